@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/config"
+	"github.com/passwall/passwall-server/internal/kms"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/cache"
+	"github.com/passwall/passwall-server/pkg/constants"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+// backupFormat is the import format of the encrypted Passwall backups
+const backupFormat = "backup"
+
+// adminCommand is a subcommand of passwall-server admin
+type adminCommand struct {
+	usage string
+	run   func(s storage.Store, args []string) error
+}
+
+// adminCommands are the recovery paths for operators when the API is unusable
+var adminCommands = map[string]adminCommand{
+	"create-user":                {"-email EMAIL [-name NAME]", adminCreateUser},
+	"disable-user":               {"-email EMAIL [-enable]", adminDisableUser},
+	"reset-master-password-flag": {"-email EMAIL [-clear]", adminResetMasterPasswordFlag},
+	"rotate-keys":                {"", adminRotateKeys},
+	"kms-wrap":                   {"", adminKMSWrap},
+	"export-backup":              {"-email EMAIL -out FILE", adminExportBackup},
+	"import":                     {"-email EMAIL -file FILE -format FORMAT [-skip-duplicates] [-dry-run]", adminImport},
+}
+
+// adminKeyring is the passphrase wrapped by the key management service, it is
+// nil without a service
+var adminKeyring *kms.Keyring
+
+// runAdmin runs the admin subcommand of the arguments and returns the exit code
+func runAdmin(args []string) int {
+	if len(args) == 0 {
+		adminUsage()
+		return 2
+	}
+	command, ok := adminCommands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown admin command %q\n", args[0])
+		adminUsage()
+		return 2
+	}
+
+	// File flags are relative to the directory the command is run in
+	workDir, _ = os.Getwd()
+	if err := os.Chdir(filepath.Dir(appFilePath())); err != nil {
+		logger.Fatalf("os.Chdir failed error: %v", err)
+	}
+	cfg, err := config.Init(constants.ConfigPath, constants.ConfigName)
+	if err != nil {
+		logger.Fatalf("config.Init: %s", err)
+	}
+	if err := logger.SetLevel(cfg.Server.LogLevel); err != nil {
+		logger.Errorf("invalid log level %q, using info: %v", cfg.Server.LogLevel, err)
+	}
+
+	// kms-wrap wraps the passphrase, the other commands use the wrapped one
+	if args[0] == "kms-wrap" {
+		adminKeyring, err = kms.New(cfg.KMS, cfg.HSM)
+	} else {
+		adminKeyring, err = unwrapPassphrase(cfg)
+	}
+	if err != nil {
+		logger.Fatalf("kms: %v", err)
+	}
+
+	s := openStore(cfg)
+	app.SetDataKeyStore(s)
+	// Users changed by the commands are removed from the cache the servers share
+	if c, err := cache.New(cfg.Cache.Driver, cfg.Cache.Address, cfg.Cache.Password, cfg.Cache.DB); err == nil {
+		s.CacheUsers(c, time.Duration(cfg.Cache.UserTTL)*time.Second)
+	} else {
+		logger.Errorf("cache.New: %v, cached users are kept until they expire", err)
+	}
+	if err := app.CheckSchemaVersions(s); err != nil {
+		logger.Fatalf("app.CheckSchemaVersions: %v", err)
+	}
+	if err := app.RunMigrationCommand(s, "up", 0, io.Discard); err != nil {
+		logger.Fatalf("migrate up: %v", err)
+	}
+
+	if err := command.run(s, args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+func adminUsage() {
+	names := make([]string, 0, len(adminCommands))
+	for name := range adminCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "usage: passwall-server admin COMMAND [flags]")
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s %s\n", name, adminCommands[name].usage)
+	}
+}
+
+// adminFlags parses the flags of the command, email is required by every command having it
+func adminFlags(name string, args []string, define func(f *flag.FlagSet)) error {
+	f := flag.NewFlagSet("admin "+name, flag.ContinueOnError)
+	define(f)
+	if err := f.Parse(args); err != nil {
+		return err
+	}
+	if email := f.Lookup("email"); email != nil && email.Value.String() == "" {
+		return errors.New("-email is required")
+	}
+	return nil
+}
+
+func adminCreateUser(s storage.Store, args []string) error {
+	var email, name string
+	if err := adminFlags("create-user", args, func(f *flag.FlagSet) {
+		f.StringVar(&email, "email", "", "email address of the user")
+		f.StringVar(&name, "name", "", "name of the user")
+	}); err != nil {
+		return err
+	}
+
+	password := prompt("Enter Master Password: ")
+	if password == "" {
+		return errors.New("master password is required")
+	}
+
+	// Clients send the SHA256 of the master password like passwall-cli
+	user, err := app.CreateUser(s, &model.UserDTO{
+		Name:           name,
+		Email:          email,
+		MasterPassword: fmt.Sprintf("%x", sha256.Sum256([]byte(password))),
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("User %s is created with schema %s\n", user.Email, user.Schema)
+	return nil
+}
+
+func adminDisableUser(s storage.Store, args []string) error {
+	var email string
+	var enable bool
+	if err := adminFlags("disable-user", args, func(f *flag.FlagSet) {
+		f.StringVar(&email, "email", "", "email address of the user")
+		f.BoolVar(&enable, "enable", false, "enable the disabled user again")
+	}); err != nil {
+		return err
+	}
+
+	if enable {
+		if _, err := app.EnableUser(s, email); err != nil {
+			return err
+		}
+		fmt.Printf("User %s is enabled\n", email)
+		return nil
+	}
+	if _, err := app.DisableUser(s, email); err != nil {
+		return err
+	}
+	fmt.Printf("User %s is disabled and signed out\n", email)
+	return nil
+}
+
+func adminResetMasterPasswordFlag(s storage.Store, args []string) error {
+	var email string
+	var clear bool
+	if err := adminFlags("reset-master-password-flag", args, func(f *flag.FlagSet) {
+		f.StringVar(&email, "email", "", "email address of the user")
+		f.BoolVar(&clear, "clear", false, "clear the flag instead of setting it")
+	}); err != nil {
+		return err
+	}
+
+	if _, err := app.SetMasterPasswordReset(s, email, !clear); err != nil {
+		return err
+	}
+	if clear {
+		fmt.Printf("User %s isn't asked to change the master password anymore\n", email)
+	} else {
+		fmt.Printf("User %s is asked to change the master password after signing in\n", email)
+	}
+	return nil
+}
+
+func adminRotateKeys(s storage.Store, args []string) error {
+	if err := adminFlags("rotate-keys", args, func(f *flag.FlagSet) {}); err != nil {
+		return err
+	}
+
+	fmt.Println("Stop the servers before the rotation, they can't decrypt the rotated records.")
+	passphrase := prompt("Enter New Passphrase: ")
+	if passphrase != prompt("Repeat New Passphrase: ") {
+		return errors.New("passphrases don't match")
+	}
+
+	updated, err := app.RotatePassphrase(s, config.Current().Server.Passphrase, passphrase)
+	if err != nil {
+		return fmt.Errorf("%w, %d records are updated, run the rotation again with the same passphrase", err, updated)
+	}
+	if adminKeyring != nil {
+		if err := adminKeyring.Wrap(passphrase); err != nil {
+			return fmt.Errorf("%w, %d records are encrypted with the new passphrase, wrap it with kms-wrap", err, updated)
+		}
+		fmt.Printf("%d records are encrypted with the new passphrase, it is wrapped to %s\n", updated, config.Current().KMS.WrappedFile)
+		return nil
+	}
+	fmt.Printf("%d records are encrypted with the new passphrase, set %s to it before starting the servers\n",
+		updated, config.EnvName("server.passphrase"))
+	return nil
+}
+
+func adminKMSWrap(s storage.Store, args []string) error {
+	if err := adminFlags("kms-wrap", args, func(f *flag.FlagSet) {}); err != nil {
+		return err
+	}
+	if adminKeyring == nil {
+		return errors.New("kms.provider isn't configured")
+	}
+
+	passphrase := prompt("Enter Passphrase: ")
+	if passphrase == "" {
+		return errors.New("passphrase is required")
+	}
+	// Wrapping another passphrase would leave the vaults undecryptable
+	if err := app.CheckPassphrase(s, passphrase); err != nil {
+		return err
+	}
+
+	if err := adminKeyring.Wrap(passphrase); err != nil {
+		return err
+	}
+	fmt.Printf("Passphrase is wrapped to %s, remove %s from the configuration\n",
+		config.Current().KMS.WrappedFile, config.EnvName("server.passphrase"))
+	return nil
+}
+
+func adminExportBackup(s storage.Store, args []string) error {
+	var email, out string
+	if err := adminFlags("export-backup", args, func(f *flag.FlagSet) {
+		f.StringVar(&email, "email", "", "email address of the user")
+		f.StringVar(&out, "out", "", "file the encrypted backup is written to")
+	}); err != nil {
+		return err
+	}
+	if out == "" {
+		return errors.New("-out is required")
+	}
+
+	user, err := s.Users().FindByEmail(email)
+	if err != nil {
+		return fmt.Errorf("user %s: %w", email, err)
+	}
+	password := prompt("Enter Backup Password: ")
+
+	backup, err := app.BuildVaultBackup(s, user.Schema)
+	if err != nil {
+		return err
+	}
+	data, err := app.EncryptVaultBackup(backup, password)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(workPath(out), data, 0600); err != nil {
+		return err
+	}
+	fmt.Printf("Vault of %s is written to %s\n", email, out)
+	return nil
+}
+
+func adminImport(s storage.Store, args []string) error {
+	var email, file, format string
+	var opts model.ImportOptions
+	if err := adminFlags("import", args, func(f *flag.FlagSet) {
+		f.StringVar(&email, "email", "", "email address of the user")
+		f.StringVar(&file, "file", "", "export file of a password manager or a Passwall backup")
+		f.StringVar(&format, "format", backupFormat, "format of the file, backup or an import format like lastpass, keepass and csv")
+		f.BoolVar(&opts.SkipDuplicates, "skip-duplicates", false, "skip the logins having the URL and username of an existing login")
+		f.BoolVar(&opts.DryRun, "dry-run", false, "validate the items without saving them")
+	}); err != nil {
+		return err
+	}
+	if file == "" {
+		return errors.New("-file is required")
+	}
+
+	user, err := s.Users().FindByEmail(email)
+	if err != nil {
+		return fmt.Errorf("user %s: %w", email, err)
+	}
+	data, err := os.ReadFile(workPath(file))
+	if err != nil {
+		return err
+	}
+
+	var summary *model.ImportSummary
+	if format == backupFormat {
+		backup, err := app.DecryptVaultBackup(data, prompt("Enter Backup Password: "))
+		if err != nil {
+			return err
+		}
+		summary, err = app.RestoreVaultBackup(s, backup, user.Schema)
+		if err != nil {
+			return err
+		}
+	} else {
+		if format == "keepass" {
+			opts.Password = prompt("Enter KeePass Password: ")
+		}
+		items, err := app.ParseImport(format, data, opts)
+		if err != nil {
+			return err
+		}
+		summary, err = app.SaveImport(s, items, user.Schema, opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Created %d, skipped %d, failed %d items\n", summary.Created, summary.Skipped, summary.Failed)
+	for _, message := range summary.Errors {
+		fmt.Println("  " + message)
+	}
+	return nil
+}
+
+var (
+	stdin = bufio.NewReader(os.Stdin)
+
+	// workDir is the directory the admin command is run in
+	workDir string
+)
+
+// workPath returns the path relative to the directory the command is run in
+func workPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(workDir, path)
+}
+
+// prompt asks for a value on the terminal
+func prompt(label string) string {
+	fmt.Print(label)
+	value, _ := stdin.ReadString('\n')
+	return strings.TrimSpace(value)
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
@@ -9,14 +11,35 @@ import (
 
 	"github.com/passwall/passwall-server/internal/app"
 	"github.com/passwall/passwall-server/internal/config"
+	"github.com/passwall/passwall-server/internal/geoip"
+	"github.com/passwall/passwall-server/internal/hsm"
+	"github.com/passwall/passwall-server/internal/kms"
+	"github.com/passwall/passwall-server/internal/license"
 	"github.com/passwall/passwall-server/internal/router"
+	"github.com/passwall/passwall-server/internal/siem"
+	"github.com/passwall/passwall-server/internal/sms"
 	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/internal/storage/tenancy"
 	"github.com/passwall/passwall-server/pkg/buildvars"
+	"github.com/passwall/passwall-server/pkg/cache"
 	"github.com/passwall/passwall-server/pkg/constants"
 	"github.com/passwall/passwall-server/pkg/logger"
+	"github.com/spf13/viper"
 )
 
 func main() {
+	// Administrative commands work on the storage directly, without the API
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		os.Exit(runAdmin(os.Args[2:]))
+	}
+
+	migrate := flag.String("migrate", "", "run the database migrations (up, down or status) and exit")
+	steps := flag.Int("steps", 1, "number of the migrations reverted by -migrate down")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with the secrets redacted and exit")
+	copyToPostgres := flag.Bool("copy-to-postgres", false, "copy the SQLite database to the Postgres database of the PW_DB_* variables and exit")
+	copyToRows := flag.Bool("copy-to-rows", false, "copy the vault schemas to the shared tables of row tenancy and exit")
+	flag.Parse()
+
 	// Set current working directory to make logger and config use the application dir
 	if err := os.Chdir(filepath.Dir(appFilePath())); err != nil {
 		logger.Fatalf("os.Chdir failed error: %v", err)
@@ -28,23 +51,138 @@ func main() {
 	if err != nil {
 		logger.Fatalf("config.Init: %s", err)
 	}
+	if err := logger.SetLevel(cfg.Server.LogLevel); err != nil {
+		logger.Errorf("invalid log level %q, using info: %v", cfg.Server.LogLevel, err)
+	}
 
-	db, err := storage.DBConn(&cfg.Database)
+	if *printConfig {
+		for _, line := range config.Report() {
+			fmt.Println(line)
+		}
+		return
+	}
+	for _, line := range config.Report() {
+		logger.Infof("config %s", line)
+	}
+
+	// The passphrase is unwrapped with the key management service instead
+	keyring, err := unwrapPassphrase(cfg)
 	if err != nil {
-		logger.Fatalf("storage.DBConn: %s", err)
+		logger.Fatalf("kms: %v", err)
+	}
+	if keyring != nil {
+		keyring.Watch(time.Duration(cfg.KMS.RewrapInterval) * time.Hour)
+	}
+	// Tokens are signed with the key of the HSM instead of server.secret
+	if err := setSigningKey(cfg); err != nil {
+		logger.Fatalf("hsm: %v", err)
 	}
 
-	s := storage.New(db)
+	s := openStore(cfg)
+	// Encrypted fields are decrypted with the data keys of the store and its regions
+	app.SetDataKeyStore(s)
 
+	if *copyToPostgres {
+		if err := copyDatabase(s, cfg.Database); err != nil {
+			logger.Fatalf("copy-to-postgres: %v", err)
+		}
+		return
+	}
+
+	if *copyToRows {
+		if err := copyVaults(s); err != nil {
+			logger.Fatalf("copy-to-rows: %v", err)
+		}
+		return
+	}
+
+	if *migrate != "" {
+		if err := app.RunMigrationCommand(s, *migrate, *steps, os.Stdout); err != nil {
+			logger.Fatalf("migrate %s: %v", *migrate, err)
+		}
+		return
+	}
+
+	// Refuse to run on databases migrated by a newer version
+	if err := app.CheckSchemaVersions(s); err != nil {
+		logger.Fatalf("app.CheckSchemaVersions: %v", err)
+	}
 	app.MigrateSystemTables(s)
+	app.MigrateVaults(s)
+	// Indexes built with another index key don't find anything
+	if err := app.RebuildSearchIndexes(s); err != nil {
+		logger.Errorf("app.RebuildSearchIndexes: %v", err)
+	}
+	app.StartCronJobs(s)
+	app.StartEmailWorker(s)
+
+	c, err := cache.New(cfg.Cache.Driver, cfg.Cache.Address, cfg.Cache.Password, cfg.Cache.DB)
+	if err != nil {
+		logger.Fatalf("cache.New: %s", err)
+	}
+	s.CacheUsers(c, time.Duration(cfg.Cache.UserTTL)*time.Second)
 
+	// Audit events are streamed to the SIEM sinks besides the database
+	sinks, err := siem.New(cfg.Audit)
+	if err != nil {
+		logger.Fatalf("siem.New: %s", err)
+	}
+	if len(sinks) > 0 {
+		app.SetAuditForwarder(siem.NewForwarder(sinks))
+	}
+
+	// Security events are located by their IP when a GeoIP database is given
+	if cfg.GeoIP.DBPath != "" {
+		locator, err := geoip.Open(cfg.GeoIP.DBPath)
+		if err != nil {
+			logger.Fatalf("geoip.Open: %s", err)
+		}
+		defer locator.Close()
+		app.SetGeoLocator(locator)
+	}
+
+	// Verification codes are sent by SMS too when a provider is configured
+	sender, err := sms.New(cfg.SMS)
+	if err != nil {
+		logger.Fatalf("sms.New: %s", err)
+	}
+	if sender != nil {
+		app.SetSMSSender(sender)
+	}
+
+	// Air-gapped servers unlock the pro features with an offline license
+	lic, err := license.Load(cfg.License, buildvars.LicensePublicKey)
+	if err != nil {
+		logger.Fatalf("license.Load: %s", err)
+	}
+	if lic != nil {
+		if lic.Expired(time.Now()) {
+			logger.Warnf("license %s of %s expired at %s", lic.ID, lic.Licensee, lic.ExpiresAt.Format(time.RFC3339))
+		}
+		app.SetLicense(lic)
+	}
+
+	// Audit events are checked for impossible travel, mass exports and deletions
+	app.StartAnomalyDetection(s, c)
+
+	// Log level, rate limit, CORS origins and email are reloaded without a restart
+	if err := config.Watch(applyConfig); err != nil {
+		logger.Errorf("config.Watch: %v, reload the configuration with a restart", err)
+	}
+
+	if cfg.Server.DebugAddr != "" {
+		go serveDebug(cfg.Server.DebugAddr, s, c)
+	}
+
+	// Header timeout cuts the slowloris clients before the body is read
 	srv := &http.Server{
-		MaxHeaderBytes: 10, // 10 MB
-		Addr:           ":" + cfg.Server.Port,
-		WriteTimeout:   time.Second * time.Duration(cfg.Server.Timeout),
-		ReadTimeout:    time.Second * time.Duration(cfg.Server.Timeout),
-		IdleTimeout:    time.Second * 60,
-		Handler:        router.New(s),
+		MaxHeaderBytes:    http.DefaultMaxHeaderBytes,
+		Addr:              ":" + cfg.Server.Port,
+		ReadHeaderTimeout: time.Second * time.Duration(cfg.Server.ReadHeaderTimeout),
+		WriteTimeout:      time.Second * time.Duration(cfg.Server.Timeout),
+		ReadTimeout:       time.Second * time.Duration(cfg.Server.Timeout),
+		IdleTimeout:       time.Second * time.Duration(cfg.Server.IdleTimeout),
+		Handler:           router.New(s, c),
 	}
 
 	msg := fmt.Sprintf("Passwall Server is up and running on '%s' in '%s' mode", cfg.Server.Port, cfg.Server.Env)
@@ -55,6 +193,134 @@ func main() {
 	}
 }
 
+// openStore connects to the database and the data residency regions of the configuration
+func openStore(cfg *config.Configuration) *storage.Database {
+	if err := tenancy.SetMode(cfg.Database.Tenancy); err != nil {
+		logger.Fatalf("tenancy.SetMode: %s", err)
+	}
+	db, err := storage.DBConn(&cfg.Database)
+	if err != nil {
+		logger.Fatalf("storage.DBConn: %s", err)
+	}
+
+	s := storage.New(db)
+
+	// Connect to data residency regions
+	for name, regionCfg := range cfg.Regions {
+		regionCfg := regionCfg
+		regionDB, err := storage.DBConn(&regionCfg)
+		if err != nil {
+			logger.Fatalf("storage.DBConn region %s: %s", name, err)
+		}
+		s.AddRegion(name, storage.New(regionDB))
+	}
+	return s
+}
+
+// unwrapPassphrase replaces the passphrase of the configuration with the one
+// wrapped by the key management service, the keyring is nil without a service
+func unwrapPassphrase(cfg *config.Configuration) (*kms.Keyring, error) {
+	keyring, err := kms.New(cfg.KMS, cfg.HSM)
+	if err != nil || keyring == nil {
+		return nil, err
+	}
+	passphrase, err := keyring.Unwrap()
+	if errors.Is(err, os.ErrNotExist) {
+		return keyring, fmt.Errorf("%s is missing, wrap the passphrase with passwall-server admin kms-wrap", cfg.KMS.WrappedFile)
+	}
+	if err != nil {
+		return keyring, err
+	}
+
+	cfg.Server.Passphrase = passphrase
+	viper.Set("server.passphrase", passphrase)
+	return keyring, nil
+}
+
+// setSigningKey signs the tokens with the configured key of the HSM, the key
+// stays on the token and only its public key is read
+func setSigningKey(cfg *config.Configuration) error {
+	if cfg.HSM.SigningKey == "" {
+		return nil
+	}
+	token, err := hsm.Open(cfg.HSM)
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return errors.New("hsm.library is required for hsm.signingKey")
+	}
+	key, err := token.SigningKey(cfg.HSM.SigningKey)
+	if err != nil {
+		return err
+	}
+	app.SetSigningKey(key)
+	return nil
+}
+
+// serveDebug serves the profiles on the debug listener. It has no write timeout,
+// CPU profiles and traces take as long as the operator asks.
+func serveDebug(addr string, s storage.Store, c cache.Cache) {
+	srv := &http.Server{
+		Addr:              addr,
+		ReadHeaderTimeout: 5 * time.Second,
+		Handler:           router.NewDebug(s, c),
+	}
+	logger.Infof("debug endpoints are served on %s", addr)
+	if err := srv.ListenAndServe(); err != nil {
+		logger.Errorf("debug listener failed: %v", err)
+	}
+}
+
+// applyConfig applies the reloaded configuration, the settings which aren't
+// read on every use are changed here
+func applyConfig(cfg *config.Configuration, err error) {
+	if err != nil {
+		logger.Errorf("config reload failed, keeping the current configuration: %v", err)
+		return
+	}
+	if err := logger.SetLevel(cfg.Server.LogLevel); err != nil {
+		logger.Errorf("invalid log level %q: %v", cfg.Server.LogLevel, err)
+	}
+	logger.Infof("configuration is reloaded")
+}
+
+// copyDatabase migrates the SQLite database to the latest version and copies it
+// to the Postgres database of the same configuration
+func copyDatabase(s *storage.Database, cfg config.DatabaseConfiguration) error {
+	if err := app.RunMigrationCommand(s, "up", 0, os.Stdout); err != nil {
+		return err
+	}
+
+	cfg.Driver = "postgres"
+	db, err := storage.DBConn(&cfg)
+	if err != nil {
+		return err
+	}
+	if err := s.CopyToPostgres(storage.New(db)); err != nil {
+		return err
+	}
+
+	fmt.Printf("Database is copied to %s on %s, set PW_DB_DRIVER to postgres to use it\n", cfg.Name, cfg.Host)
+	return nil
+}
+
+// copyVaults migrates the database to the latest version and copies the vault
+// schemas to the shared tables of row tenancy
+func copyVaults(s *storage.Database) error {
+	if err := app.RunMigrationCommand(s, "up", 0, os.Stdout); err != nil {
+		return err
+	}
+
+	copied, err := app.CopyVaultsToRows(s)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d vaults are copied to row tenancy. Run again until none is copied, the last time in maintenance mode, then set PW_DB_TENANCY to row.\n", copied)
+	return nil
+}
+
 func logStartupInfo() {
 	args := os.Args
 	if args == nil {
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// activityDefaultPeriod is the period of the activity feed when from isn't given
+const activityDefaultPeriod = 7 * 24 * time.Hour
+
+// FindActivity lists the newest instance level events for the admins: signups,
+// deletions, subscription changes and failed signin spikes
+func FindActivity(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		period, limit, types, err := ParseActivityOptions(r, time.Now())
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		events, err := app.FindActivity(s, period, limit, types)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, events)
+	}
+}
+
+// ParseActivityOptions parses the from and to times in RFC3339, the limit and
+// the comma separated types of the activity feed. The feed is of the last week
+// unless from is given, older pages are asked with to.
+func ParseActivityOptions(r *http.Request, now time.Time) (model.AuditPeriod, int, []string, error) {
+	to := now
+	if v := r.FormValue("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return model.AuditPeriod{}, 0, nil, errors.New("invalid to value, RFC3339 expected")
+		}
+		to = t
+	}
+	from := to.Add(-activityDefaultPeriod)
+	if v := r.FormValue("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return model.AuditPeriod{}, 0, nil, errors.New("invalid from value, RFC3339 expected")
+		}
+		from = t
+	}
+
+	limit := defaultPageLimit
+	if v := r.FormValue("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return model.AuditPeriod{}, 0, nil, errors.New("invalid limit value")
+		}
+		limit = n
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	types := []string{}
+	if v := r.FormValue("type"); v != "" {
+		known := app.ActivityTypes()
+		for _, t := range strings.Split(v, ",") {
+			if app.FindIndex(known, t) < 0 {
+				return model.AuditPeriod{}, 0, nil, errors.New("invalid type " + t + ", use one of " + strings.Join(known, ", "))
+			}
+			types = append(types, t)
+		}
+	}
+
+	return model.AuditPeriod{From: &from, To: &to}, limit, types, nil
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseActivityOptions(t *testing.T) {
+	now := time.Date(2026, 3, 8, 12, 0, 0, 0, time.UTC)
+
+	r := httptest.NewRequest("GET", "/api/admin/events", nil)
+	period, limit, types, err := ParseActivityOptions(r, now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, -7), *period.From)
+	assert.Equal(t, now, *period.To)
+	assert.Equal(t, defaultPageLimit, limit)
+	assert.Empty(t, types)
+
+	r = httptest.NewRequest("GET", "/api/admin/events?to=2026-02-01T00:00:00Z&limit=5000&type=user.signed_up,signin.failed_spike", nil)
+	period, limit, types, err = ParseActivityOptions(r, now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 1, 25, 0, 0, 0, 0, time.UTC), *period.From)
+	assert.Equal(t, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), *period.To)
+	assert.Equal(t, maxListLimit, limit)
+	assert.Equal(t, []string{"user.signed_up", "signin.failed_spike"}, types)
+
+	for _, query := range []string{"limit=0", "from=yesterday", "to=now", "type=item.created"} {
+		_, _, _, err := ParseActivityOptions(httptest.NewRequest("GET", "/api/admin/events?"+query, nil), now)
+		assert.Error(t, err, query)
+	}
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/passwall/passwall-server/internal/storage"
+)
+
+// alertSortFields are the fields security alerts can be sorted by
+var alertSortFields = []string{"id", "created_at", "type"}
+
+// FindAlerts lists the security alerts of the user of the token
+func FindAlerts(s storage.Store) http.HandlerFunc {
+	return findAlerts(s, false)
+}
+
+// FindAllAlerts lists the security alerts of every user, the user_id query
+// param filters them
+func FindAllAlerts(s storage.Store) http.HandlerFunc {
+	return findAlerts(s, true)
+}
+
+func findAlerts(s storage.Store, instance bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		opts, err := ParseListOptions(r, alertSortFields)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if r.FormValue("sort") == "" {
+			opts.Sort = "created_at"
+		}
+		if opts.Limit == 0 {
+			opts.Limit = defaultPageLimit
+		}
+		if v := r.FormValue("type"); v != "" {
+			opts.Filters["type"] = v
+		}
+		if v := r.FormValue("user_id"); v != "" && instance {
+			id, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				RespondWithError(w, http.StatusBadRequest, "invalid user_id value")
+				return
+			}
+			opts.Filters["user_id"] = uint(id)
+		}
+		if !instance {
+			opts.Filters["user_id"], _ = r.Context().Value("user_id").(uint)
+		}
+
+		alerts, total, err := s.Alerts().FindAll(opts)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, NewListResponse(alerts, total, opts))
+	}
+}
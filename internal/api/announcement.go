@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// FindActiveAnnouncements returns the announcements shown to the users now
+func FindActiveAnnouncements(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		announcements, err := s.Announcements().FindActive(time.Now())
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		RespondWithJSON(w, http.StatusOK, announcements)
+	}
+}
+
+// FindAllAnnouncements returns every announcement including the past and the
+// scheduled ones
+func FindAllAnnouncements(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		announcements, err := s.Announcements().All()
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		RespondWithJSON(w, http.StatusOK, announcements)
+	}
+}
+
+// CreateAnnouncement publishes an announcement to every user
+func CreateAnnouncement(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dto, ok := decodeAnnouncementDTO(w, r)
+		if !ok {
+			return
+		}
+
+		announcement, err := app.CreateAnnouncement(s, dto, time.Now())
+		if err != nil {
+			RespondWithError(w, announcementErrorStatus(err), err.Error())
+			return
+		}
+		RespondWithJSON(w, http.StatusOK, announcement)
+	}
+}
+
+// UpdateAnnouncement changes an announcement
+func UpdateAnnouncement(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		announcement, ok := findAnnouncement(s, w, r)
+		if !ok {
+			return
+		}
+		dto, ok := decodeAnnouncementDTO(w, r)
+		if !ok {
+			return
+		}
+
+		announcement, err := app.UpdateAnnouncement(s, announcement, dto, time.Now())
+		if err != nil {
+			RespondWithError(w, announcementErrorStatus(err), err.Error())
+			return
+		}
+		RespondWithJSON(w, http.StatusOK, announcement)
+	}
+}
+
+// DeleteAnnouncement removes an announcement
+func DeleteAnnouncement(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		announcement, ok := findAnnouncement(s, w, r)
+		if !ok {
+			return
+		}
+
+		if err := s.Announcements().Delete(announcement.ID); err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		response := model.Response{
+			Code:    http.StatusOK,
+			Status:  Success,
+			Message: "Announcement deleted successfully!",
+		}
+		RespondWithJSON(w, http.StatusOK, response)
+	}
+}
+
+// findAnnouncement finds the announcement of the route, the response is sent
+// when it can't be found
+func findAnnouncement(s storage.Store, w http.ResponseWriter, r *http.Request) (*model.Announcement, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+
+	announcement, err := app.FindAnnouncement(s, uint(id))
+	if err != nil {
+		RespondWithError(w, http.StatusNotFound, err.Error())
+		return nil, false
+	}
+	return announcement, true
+}
+
+// decodeAnnouncementDTO decodes and validates the announcement payload, the
+// response is sent when it is invalid
+func decodeAnnouncementDTO(w http.ResponseWriter, r *http.Request) (*model.AnnouncementDTO, bool) {
+	dto := new(model.AnnouncementDTO)
+	if err := json.NewDecoder(r.Body).Decode(dto); err != nil {
+		RespondWithError(w, requestErrorStatus(err), InvalidJSON)
+		return nil, false
+	}
+	defer r.Body.Close()
+
+	if err := app.PayloadValidator(dto); err != nil {
+		errs := GetErrors(err.(validator.ValidationErrors))
+		RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+		return nil, false
+	}
+	return dto, true
+}
+
+// announcementErrorStatus is the response status of the announcement errors
+func announcementErrorStatus(err error) int {
+	if err == app.ErrAnnouncementPeriod {
+		return http.StatusBadRequest
+	}
+	return updateErrorStatus(err)
+}
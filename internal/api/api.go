@@ -1,9 +1,39 @@
 package api
 
 import (
+	"fmt"
+	"math/rand"
+	"strconv"
 	"time"
-
-	"github.com/patrickmn/go-cache"
 )
 
-var c = cache.New(time.Minute*5, time.Minute*10)
\ No newline at end of file
+// codeExpiration is how long the verification codes and the verified emails are kept
+const codeExpiration = 5 * time.Minute
+
+// verificationKey is the cache key of the verification code of the email
+func verificationKey(email string) string {
+	return "verification:" + email
+}
+
+// signupPhoneKey is the cache key of the phone the signup code of the email is sent to
+func signupPhoneKey(email string) string {
+	return "verification-phone:" + email
+}
+
+// phoneVerificationKey is the cache key of the phone number a user verifies and its code
+func phoneVerificationKey(userID uint) string {
+	return fmt.Sprintf("phone-verification:%d", userID)
+}
+
+// signinCodeKey is the cache key of the two-factor signin code of the email
+func signinCodeKey(email string) string {
+	return "signin-code:" + email
+}
+
+// generateCode returns a random 6 digit verification code
+func generateCode() string {
+	rand.Seed(time.Now().UnixNano())
+	min := 100000
+	max := 999999
+	return strconv.Itoa(rand.Intn(max-min+1) + min)
+}
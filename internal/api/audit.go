@@ -0,0 +1,151 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+// auditSortFields are the fields audit events can be sorted by
+var auditSortFields = []string{"id", "created_at", "action", "item_type"}
+
+// FindAuditEvents lists the audit events of the user of the token
+func FindAuditEvents(s storage.Store) http.HandlerFunc {
+	return findAuditEvents(s, false)
+}
+
+// FindAllAuditEvents lists the audit events of every user, the user_id query
+// param filters them by actor
+func FindAllAuditEvents(s storage.Store) http.HandlerFunc {
+	return findAuditEvents(s, true)
+}
+
+func findAuditEvents(s storage.Store, instance bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		opts, period, err := ParseAuditOptions(r)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if !instance {
+			opts.Filters["user_id"], _ = r.Context().Value("user_id").(uint)
+		}
+
+		events, total, err := s.AuditEvents().FindAll(opts, period)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, NewListResponse(events, total, opts))
+	}
+}
+
+// ParseAuditOptions parses the list options and the filters of the audit log:
+// user_id, action, item_type, item_id, impersonator_id, principal and the from and to times in RFC3339.
+// Newest events come first unless another sort is asked.
+func ParseAuditOptions(r *http.Request) (*model.ListOptions, model.AuditPeriod, error) {
+	period := model.AuditPeriod{}
+	opts, err := ParseListOptions(r, auditSortFields)
+	if err != nil {
+		return nil, period, err
+	}
+	if r.FormValue("sort") == "" {
+		opts.Sort = "created_at"
+	}
+	if opts.Limit == 0 {
+		opts.Limit = defaultPageLimit
+	}
+	// Audit events are never updated
+	opts.UpdatedSince = nil
+
+	for _, name := range []string{"user_id", "item_id", "impersonator_id"} {
+		if v := r.FormValue(name); v != "" {
+			id, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return nil, period, errors.New("invalid " + name + " value")
+			}
+			opts.Filters[name] = uint(id)
+		}
+	}
+	for _, name := range []string{"action", "item_type", "principal"} {
+		if v := r.FormValue(name); v != "" {
+			opts.Filters[name] = v
+		}
+	}
+
+	for name, dest := range map[string]**time.Time{"from": &period.From, "to": &period.To} {
+		if v := r.FormValue(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return nil, period, errors.New("invalid " + name + " value, RFC3339 expected")
+			}
+			*dest = &t
+		}
+	}
+
+	return opts, period, nil
+}
+
+// NewAuditEvent returns the audit event of the action of the request, the actor
+// is the user of the token and the admin impersonating the user is kept too
+func NewAuditEvent(r *http.Request, action, itemType string, itemID uint) *model.AuditEvent {
+	userID, _ := r.Context().Value("user_id").(uint)
+	impersonatorID, _ := r.Context().Value("impersonator_id").(uint)
+	return &model.AuditEvent{
+		UserID:         userID,
+		Action:         action,
+		ItemType:       itemType,
+		ItemID:         itemID,
+		IP:             RequestIP(r),
+		UserAgent:      r.UserAgent(),
+		ImpersonatorID: impersonatorID,
+		Principal:      RequestPrincipal(r),
+	}
+}
+
+// RequestPrincipal returns the principal the changes of the request are
+// attributed to, the admin impersonating the user or the user. It is empty
+// for the requests without a user.
+func RequestPrincipal(r *http.Request) string {
+	if id, ok := r.Context().Value("impersonator_id").(uint); ok {
+		return app.Principal(app.PrincipalAdmin, id)
+	}
+	if id, ok := r.Context().Value("user_id").(uint); ok {
+		return app.Principal(app.PrincipalUser, id)
+	}
+	return ""
+}
+
+// RecordAuditEvent records the event of an operation which is done already,
+// the response can't be failed by then so errors are logged
+func RecordAuditEvent(s storage.Store, r *http.Request, event *model.AuditEvent) {
+	if err := app.RecordAuditEvent(s, event); err != nil {
+		logger.WithContext(r.Context()).Errorf("can't record audit event %s of user %d error: %v", event.Action, event.UserID, err)
+	}
+}
+
+// recordUserEvent records the action of the user on the own account, the
+// requests of the auth routes have no user in their context
+func recordUserEvent(s storage.Store, r *http.Request, action string, user *model.User) {
+	event := NewAuditEvent(r, action, "users", user.ID)
+	event.UserID = user.ID
+	RecordAuditEvent(s, r, event)
+}
+
+// recordSigninFailed records the failed signin of the email, the user is
+// found by the email when the master password is wrong
+func recordSigninFailed(s storage.Store, r *http.Request, email string) {
+	event := NewAuditEvent(r, app.AuditActionSigninFailed, "users", 0)
+	if user, err := s.Users().FindByEmail(email); err == nil {
+		event.UserID = user.ID
+		event.ItemID = user.ID
+	}
+	RecordAuditEvent(s, r, event)
+}
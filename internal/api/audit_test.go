@@ -0,0 +1,47 @@
+package api
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAuditOptions(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/audit", nil)
+	opts, period, err := ParseAuditOptions(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "created_at", opts.Sort)
+	assert.Equal(t, "desc", opts.Direction)
+	assert.Equal(t, defaultPageLimit, opts.Limit)
+	assert.Empty(t, opts.Filters)
+	assert.Nil(t, period.From)
+	assert.Nil(t, period.To)
+
+	r = httptest.NewRequest("GET", "/api/audit?user_id=3&action=view&item_type=logins&item_id=7&from=2026-01-01T00:00:00Z&to=2026-02-01T00:00:00Z&sort=id&limit=10", nil)
+	opts, period, err = ParseAuditOptions(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "id", opts.Sort)
+	assert.Equal(t, 10, opts.Limit)
+	assert.Equal(t, map[string]interface{}{"user_id": uint(3), "action": "view", "item_type": "logins", "item_id": uint(7)}, opts.Filters)
+	assert.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), *period.From)
+	assert.Equal(t, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), *period.To)
+
+	for _, query := range []string{"user_id=me", "item_id=-1", "from=yesterday", "sort=ip"} {
+		_, _, err := ParseAuditOptions(httptest.NewRequest("GET", "/api/audit?"+query, nil))
+		assert.Error(t, err, query)
+	}
+}
+
+func TestRequestPrincipal(t *testing.T) {
+	r := httptest.NewRequest("POST", "/api/notes", nil)
+	assert.Empty(t, RequestPrincipal(r))
+
+	ctx := context.WithValue(r.Context(), "user_id", uint(4))
+	assert.Equal(t, "user:4", RequestPrincipal(r.WithContext(ctx)))
+
+	ctx = context.WithValue(ctx, "impersonator_id", uint(1))
+	assert.Equal(t, "admin:1", RequestPrincipal(r.WithContext(ctx)))
+}
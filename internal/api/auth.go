@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/golang-jwt/jwt/v4"
@@ -12,8 +13,12 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/billing"
+	"github.com/passwall/passwall-server/internal/config"
+	"github.com/passwall/passwall-server/internal/i18n"
 	"github.com/passwall/passwall-server/internal/storage"
 	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/cache"
 	"github.com/passwall/passwall-server/pkg/constants"
 	"github.com/passwall/passwall-server/pkg/cookie"
 	"github.com/passwall/passwall-server/pkg/logger"
@@ -21,20 +26,19 @@ import (
 )
 
 var (
-	userLoginErr         = "User email or master password is wrong."
-	invalidUser          = "Invalid user"
-	invalidToken         = "Token is expired or not valid!"
-	noToken              = "Token could not found! "
-	tokenCreateErr       = "Token could not be created"
-	signupSuccess        = "User created successfully"
-	signoutSuccess       = "User signed out successfully"
-	codeSuccess          = "Code created successfully"
-	subscriptionTypePro  = "pro"
-	subscriptionTypeFree = "free"
+	userLoginErr   = "User email or master password is wrong."
+	invalidUser    = "Invalid user"
+	userDisabled   = "User is disabled, contact the administrator"
+	invalidToken   = "Token is expired or not valid!"
+	noToken        = "Token could not found! "
+	tokenCreateErr = "Token could not be created"
+	signupSuccess  = "User created successfully"
+	signoutSuccess = "User signed out successfully"
+	codeSuccess    = "Code created successfully"
 )
 
 // Signin ...
-func Signin(s storage.Store) http.HandlerFunc {
+func Signin(s storage.Store, c cache.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var loginDTO model.AuthLoginDTO
 
@@ -45,7 +49,7 @@ func Signin(s storage.Store) http.HandlerFunc {
 		}
 		defer func() {
 			if err := r.Body.Close(); err != nil {
-				logger.Errorf("Failed to close body error: %v", err)
+				logger.WithContext(r.Context()).Errorf("Failed to close body error: %v", err)
 			}
 		}()
 
@@ -60,17 +64,59 @@ func Signin(s storage.Store) http.HandlerFunc {
 		// Check if user exist in database and credentials are true
 		user, err := s.Users().FindByCredentials(loginDTO.Email, loginDTO.MasterPassword)
 		if err != nil {
+			recordSigninFailed(s, r, loginDTO.Email)
 			RespondWithError(w, http.StatusUnauthorized, userLoginErr)
 			return
 		}
 
-		sType := subscriptionTypeFree
-		if isPro(user.UUID) {
-			sType = subscriptionTypePro
+		// Users disabled by an administrator can't sign in
+		if user.DisabledAt != nil {
+			recordSigninFailed(s, r, loginDTO.Email)
+			RespondWithError(w, http.StatusForbidden, userDisabled)
+			return
+		}
+
+		// The security policy may ask a code sent to the user besides the master password
+		policy, err := app.FindSecurityPolicy(s)
+		if err != nil {
+			logger.WithContext(r.Context()).Errorf("can't find security policy error: %v", err)
+			RespondWithError(w, http.StatusInternalServerError, "Server error!")
+			return
+		}
+		// Trusted devices skip the code, never the master password
+		trustDevice := false
+		if policy.RequireTwoFactor && !app.TrustedDeviceValid(s, user, loginDTO.DeviceID, loginDTO.DeviceToken, time.Now()) {
+			if !checkSigninCode(s, c, w, r, user, loginDTO.Code) {
+				return
+			}
+			trustDevice = loginDTO.TrustDevice
+		}
+
+		// Master passwords hashed with a lower cost than the policy asks are
+		// upgraded while the password is known, the signin goes on without it
+		if upgraded, err := app.UpgradeKDF(s, policy, user, loginDTO.MasterPassword); err == nil {
+			user = upgraded
+		} else {
+			logger.WithContext(r.Context()).Errorf("can't upgrade kdf of user %d error: %v", user.ID, err)
+		}
+
+		// Subscriptions of the other providers are updated by their webhooks,
+		// users in trial keep it until they subscribe or it ends and paying
+		// users are past due in the grace period when it can't be confirmed.
+		// Licensed servers don't call the billing provider.
+		now := time.Now()
+		if config.Current().Billing.Provider == billing.ProviderRevenueCat && !app.Licensed(now) {
+			sType := app.LapsedSubscriptionType(user, now)
+			if isPro(user.UUID) {
+				sType = app.SubscriptionTypePro
+			} else if app.InTrial(user, now) {
+				sType = app.SubscriptionTypeTrial
+			}
+			app.UpdateSubscription(s, user, sType)
 		}
 
 		// token is necessary for Passwall Extension
-		token, err := app.CreateToken(user)
+		token, err := app.CreateToken(user, policy, loginDTO.Client)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, tokenCreateErr)
 			return
@@ -87,10 +133,38 @@ func Signin(s storage.Store) http.HandlerFunc {
 		authLoginResponse := model.AuthLoginResponse{
 			AccessToken:  token.AccessToken,
 			RefreshToken: token.RefreshToken,
-			Type:         sType,
+			Type:         app.SubscriptionAccess(user, now),
+			Trial:        app.FindTrialStatus(user, now),
 			UserDTO:      model.ToUserDTO(user),
 		}
 
+		// The signin goes on without a device token when it can't be saved
+		if trustDevice {
+			device, deviceToken, err := app.TrustDevice(s, user, loginDTO.DeviceID, r.UserAgent(), now)
+			if err != nil {
+				logger.WithContext(r.Context()).Errorf("can't trust device of user %d error: %v", user.ID, err)
+			} else {
+				authLoginResponse.DeviceToken = deviceToken
+				event := NewAuditEvent(r, app.AuditActionTrustDevice, "trusted_devices", device.ID)
+				event.UserID = user.ID
+				RecordAuditEvent(s, r, event)
+			}
+		}
+
+		// Devices are told apart by their user agent
+		newDevice := app.IsNewDevice(s, user.ID, r.UserAgent())
+		event := NewAuditEvent(r, app.AuditActionSignin, "users", user.ID)
+		event.UserID = user.ID
+		RecordAuditEvent(s, r, event)
+		if newDevice {
+			app.DispatchWebhookEvent(s, app.WebhookEventNewDeviceSignin, user.ID, map[string]string{
+				"ip":         event.IP,
+				"user_agent": event.UserAgent,
+				"country":    event.Country,
+				"city":       event.City,
+			})
+		}
+
 		// cookie is necessary for Passwall Desktop
 		newCookie := cookie.Create(constants.CookieName, token.AccessToken, token.AtExpiresTime)
 
@@ -98,9 +172,90 @@ func Signin(s storage.Store) http.HandlerFunc {
 	}
 }
 
-// Signout ...
-func Signout() http.HandlerFunc {
+// checkSigninCode sends a signin code to the verified phone of the user, or to
+// the email when there isn't one, for the logins without a code and checks the
+// code otherwise. A wrong code is deleted so codes can't be guessed. It responds
+// and returns false when the signin can't go on.
+func checkSigninCode(s storage.Store, c cache.Cache, w http.ResponseWriter, r *http.Request, user *model.User, code string) bool {
+	key := signinCodeKey(user.Email)
+	if code == "" {
+		code = generateCode()
+		if err := c.Set(key, code, codeExpiration); err != nil {
+			logger.WithContext(r.Context()).Errorf("can't save signin code of user %d error: %v", user.ID, err)
+			RespondWithError(w, http.StatusInternalServerError, "Server error!")
+			return false
+		}
+
+		if user.PhoneVerifiedAt != nil && user.Phone != "" {
+			locale := user.Locale
+			if locale == "" {
+				locale = Locale(w)
+			}
+			if err := app.SendSMSCode(locale, user.Phone, code); err != nil {
+				respondSMSError(w, r, err)
+				return false
+			}
+		} else if err := app.SendUserMail(s, user, app.EmailVerification, map[string]interface{}{"Code": code}); err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "Couldn't send email")
+			return false
+		}
+		RespondWithError(w, http.StatusUnauthorized, app.ErrTwoFactorRequired.Error())
+		return false
+	}
+
+	expected, found, err := c.Get(key)
+	if err != nil {
+		logger.WithContext(r.Context()).Errorf("can't get signin code of user %d error: %v", user.ID, err)
+		RespondWithError(w, http.StatusInternalServerError, "Server error!")
+		return false
+	}
+	if err := c.Delete(key); err != nil {
+		logger.WithContext(r.Context()).Errorf("can't delete signin code of user %d error: %v", user.ID, err)
+	}
+	if !found || expected != code {
+		recordSigninFailed(s, r, user.Email)
+		RespondWithError(w, http.StatusUnauthorized, app.ErrInvalidTwoFactorCode.Error())
+		return false
+	}
+	return true
+}
+
+// Signout revokes the access token of the request and the refresh token of
+// the body, so they can't be used until they expire
+func Signout(s storage.Store, c cache.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		var signedOut string
+		tokens := []string{token.Find(r)}
+		if r.ContentLength > 0 {
+			tokens = append(tokens, token.ExtractRefreshToken(r))
+		}
+		for _, tokenStr := range tokens {
+			if tokenStr == "" {
+				continue
+			}
+			t, err := app.TokenValid(tokenStr)
+			if err != nil {
+				continue
+			}
+			claims, _ := t.Claims.(jwt.MapClaims)
+			if err := app.RevokeToken(c, claims); err != nil {
+				logger.WithContext(r.Context()).Errorf("can't revoke token error: %v", err)
+				RespondWithError(w, http.StatusInternalServerError, "Server error!")
+				return
+			}
+			uuid, _ := claims["uuid"].(string)
+			s.Tokens().DeleteByUUID(uuid)
+			signedOut, _ = claims["user_uuid"].(string)
+		}
+
+		if signedOut != "" {
+			if user, err := s.Users().FindByUUID(signedOut); err == nil {
+				event := NewAuditEvent(r, app.AuditActionSignout, "users", user.ID)
+				event.UserID = user.ID
+				RecordAuditEvent(s, r, event)
+			}
+		}
+
 		deletedCookie := cookie.Delete(constants.CookieName)
 
 		response := model.Response{
@@ -113,7 +268,7 @@ func Signout() http.HandlerFunc {
 }
 
 // RefreshToken ...
-func RefreshToken(s storage.Store) http.HandlerFunc {
+func RefreshToken(s storage.Store, c cache.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		refreshToken := token.ExtractRefreshToken(r)
@@ -130,7 +285,26 @@ func RefreshToken(s storage.Store) http.HandlerFunc {
 		}
 
 		claims := token.Claims.(jwt.MapClaims)
-		uuid := claims["uuid"].(string)
+		uuid, _ := claims["uuid"].(string)
+
+		// Only the refresh tokens of the sessions are refreshed, support and
+		// scoped tokens can't become full sessions
+		if !app.RefreshTokenClaims(claims) {
+			RespondWithError(w, http.StatusUnauthorized, invalidToken)
+			return
+		}
+
+		// Revoked tokens may still be in the db of another instance for a moment
+		revoked, err := app.IsTokenRevoked(c, claims)
+		if err != nil {
+			logger.WithContext(r.Context()).Errorf("can't check revoked token error: %v", err)
+			RespondWithError(w, http.StatusInternalServerError, "Server error!")
+			return
+		}
+		if revoked {
+			RespondWithError(w, http.StatusUnauthorized, invalidToken)
+			return
+		}
 
 		// Get token details from db by User UUID
 		_, err = s.Tokens().FindByUUID(uuid)
@@ -148,16 +322,45 @@ func RefreshToken(s storage.Store) http.HandlerFunc {
 			RespondWithError(w, http.StatusUnauthorized, invalidUser)
 			return
 		}
+		if user.DisabledAt != nil {
+			RespondWithError(w, http.StatusForbidden, userDisabled)
+			return
+		}
+		if !app.TokenEpochValid(user, claims) {
+			RespondWithError(w, http.StatusUnauthorized, invalidToken)
+			return
+		}
 
-		//create token
-		newtoken, err := app.CreateToken(user)
+		// Sessions can't be refreshed beyond the lifetime of the security policy
+		policy, err := app.FindSecurityPolicy(s)
+		if err != nil {
+			logger.WithContext(r.Context()).Errorf("can't find security policy error: %v", err)
+			RespondWithError(w, http.StatusInternalServerError, "Server error!")
+			return
+		}
+		now := time.Now()
+		if app.SessionExpired(policy, claims, now) {
+			RespondWithError(w, http.StatusUnauthorized, app.ErrSessionExpired.Error())
+			return
+		}
+		authTime, ok := app.SessionStartedAt(claims)
+		if !ok {
+			authTime = now
+		}
+
+		//create token, the session keeps its client and signin time
+		newtoken, err := app.CreateSessionToken(user, policy, app.SessionClient(claims), authTime)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, tokenCreateErr)
 			return
 		}
 
-		//delete tokens from db
+		//delete tokens from db, the used refresh token can't be used again
 		s.Tokens().DeleteByUUID(userUUID)
+		s.Tokens().DeleteByUUID(uuid)
+		if err := app.RevokeToken(c, claims); err != nil {
+			logger.WithContext(r.Context()).Errorf("can't revoke refresh token error: %v", err)
+		}
 
 		//create tokens on db
 		s.Tokens().Create(int(user.ID), newtoken.AtUUID, newtoken.AccessToken, newtoken.AtExpiresTime)
@@ -166,6 +369,7 @@ func RefreshToken(s storage.Store) http.HandlerFunc {
 		authLoginResponse := model.AuthLoginResponse{
 			AccessToken:  newtoken.AccessToken,
 			RefreshToken: newtoken.RefreshToken,
+			Trial:        app.FindTrialStatus(user, now),
 			UserDTO:      model.ToUserDTO(user),
 		}
 
@@ -176,6 +380,80 @@ func RefreshToken(s storage.Store) http.HandlerFunc {
 	}
 }
 
+// RevokeAllSessions signs out every other session of the user of the access
+// token at once, the session asking it gets new tokens and the user is told by email
+func RevokeAllSessions(s storage.Store, c cache.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t, err := app.TokenValid(token.Find(r))
+		if err != nil {
+			RespondWithError(w, http.StatusUnauthorized, invalidToken)
+			return
+		}
+		claims := t.Claims.(jwt.MapClaims)
+
+		revoked, err := app.IsTokenRevoked(c, claims)
+		if err != nil {
+			logger.WithContext(r.Context()).Errorf("can't check revoked token error: %v", err)
+			RespondWithError(w, http.StatusInternalServerError, "Server error!")
+			return
+		}
+		if revoked {
+			RespondWithError(w, http.StatusUnauthorized, invalidToken)
+			return
+		}
+
+		userUUID, _ := claims["user_uuid"].(string)
+		user, err := s.Users().FindByUUID(userUUID)
+		if err != nil || !app.TokenEpochValid(user, claims) {
+			RespondWithError(w, http.StatusUnauthorized, invalidToken)
+			return
+		}
+		if user.DisabledAt != nil {
+			RespondWithError(w, http.StatusForbidden, userDisabled)
+			return
+		}
+
+		// Only the sessions of the user do it, scoped tokens without the
+		// account scope and support sessions can't sign the user out
+		scopes, _ := app.ScopesFromClaims(claims)
+		if _, impersonated := app.ImpersonatorFromClaims(claims); impersonated || app.ScopedToken(claims) && !app.HasScope(scopes, app.ScopeAccount) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		policy, err := app.FindSecurityPolicy(s)
+		if err != nil {
+			logger.WithContext(r.Context()).Errorf("can't find security policy error: %v", err)
+			RespondWithError(w, http.StatusInternalServerError, "Server error!")
+			return
+		}
+		now := time.Now()
+		if app.SessionExpired(policy, claims, now) {
+			RespondWithError(w, http.StatusUnauthorized, app.ErrSessionExpired.Error())
+			return
+		}
+
+		newtoken, err := app.RevokeAllSessions(s, user, policy, claims, RequestIP(r), now)
+		if err != nil {
+			logger.WithContext(r.Context()).Errorf("can't revoke sessions of user %d error: %v", user.ID, err)
+			RespondWithError(w, http.StatusInternalServerError, "Server error!")
+			return
+		}
+
+		event := NewAuditEvent(r, app.AuditActionRevokeAll, "users", user.ID)
+		event.UserID = user.ID
+		RecordAuditEvent(s, r, event)
+
+		authLoginResponse := model.AuthLoginResponse{
+			AccessToken:  newtoken.AccessToken,
+			RefreshToken: newtoken.RefreshToken,
+			UserDTO:      model.ToUserDTO(user),
+		}
+		newCookie := cookie.Create(constants.CookieName, newtoken.AccessToken, newtoken.AtExpiresTime)
+		RespondWithCookie(w, http.StatusOK, newCookie, authLoginResponse)
+	}
+}
+
 // CheckToken ...
 func CheckToken(s storage.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -213,28 +491,23 @@ func CheckToken(s storage.Store) http.HandlerFunc {
 	}
 }
 
-func notifyAdminEmail(user *model.User) {
-	subject := "PassWall New User Subscription"
-	body := "PassWall has new a user. User details:\n\n"
-	body += "Name: " + user.Name + "\n"
-	body += "Email: " + user.Email + "\n"
+func notifyAdminEmail(s storage.Store, user *model.User) {
 	app.SendMail(
+		s,
+		i18n.DefaultLocale,
 		viper.GetString("email.fromName"),
 		viper.GetString("email.fromEmail"),
-		subject,
-		body)
+		app.EmailNewUser,
+		map[string]interface{}{"Name": user.Name, "Email": user.Email})
 }
 
-func isMailVerified(email string) error {
-	cachedEmail, found := c.Get(email)
-	if !found {
-		err := fmt.Errorf("can't find email %q in cache", email)
+func isMailVerified(c cache.Cache, email string) error {
+	verified, found, err := c.Get(verificationKey(email))
+	if err != nil {
 		return err
 	}
-
-	verified, ok := cachedEmail.(string)
-	if !ok {
-		err := fmt.Errorf("can't convert cached email data %v to string", verified)
+	if !found {
+		err := fmt.Errorf("can't find email %q in cache", email)
 		return err
 	}
 
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/internal/storage/migration"
+	"github.com/passwall/passwall-server/internal/storage/sqlite"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/cache"
+)
+
+// refreshTestStore returns a store with a user and sets the token lifetimes
+func refreshTestStore(t *testing.T) (*storage.Database, *model.User) {
+	for key, value := range map[string]string{
+		"server.accessTokenExpireDuration":  "30m",
+		"server.refreshTokenExpireDuration": "15d",
+	} {
+		previous := viper.GetString(key)
+		t.Cleanup(func() { viper.Set(key, previous) })
+		viper.Set(key, value)
+	}
+
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "passwall.db"), &gorm.Config{})
+	require.NoError(t, err)
+	s := storage.New(db)
+	require.NoError(t, s.Migrations().Up(migration.SetSystem, ""))
+
+	user, err := s.Users().Create(&model.User{UUID: uuid.NewV4(), Email: "refresh@passwall.io", Role: "Admin"})
+	require.NoError(t, err)
+	return s, user
+}
+
+// refresh posts the token to the refresh endpoint and returns the status
+func refresh(s storage.Store, c cache.Cache, refreshToken string) int {
+	r := httptest.NewRequest(http.MethodPost, "/auth/refresh", strings.NewReader(`{"refresh_token": "`+refreshToken+`"}`))
+	w := httptest.NewRecorder()
+	RefreshToken(s, c)(w, r)
+	return w.Code
+}
+
+func TestRefreshToken(t *testing.T) {
+	s, user := refreshTestStore(t)
+	c := cache.NewMemory()
+
+	session, err := app.CreateToken(user, model.SecurityPolicy{}, "")
+	require.NoError(t, err)
+	s.Tokens().Create(int(user.ID), session.AtUUID, session.AccessToken, session.AtExpiresTime)
+	s.Tokens().Create(int(user.ID), session.RtUUID, session.RefreshToken, session.RtExpiresTime)
+
+	// Access tokens are saved too but they aren't refresh tokens
+	assert.Equal(t, http.StatusUnauthorized, refresh(s, c, session.AccessToken))
+	assert.Equal(t, http.StatusOK, refresh(s, c, session.RefreshToken))
+	// The used refresh token can't be used again
+	assert.Equal(t, http.StatusUnauthorized, refresh(s, c, session.RefreshToken))
+}
+
+func TestRefreshSupportToken(t *testing.T) {
+	s, user := refreshTestStore(t)
+	c := cache.NewMemory()
+
+	// Support tokens stay support sessions, they can't become full sessions
+	now := time.Now()
+	admin := &model.User{ID: 99, Role: "Admin"}
+	support, err := app.CreateSupportToken(user, admin, now.Add(time.Hour), now)
+	require.NoError(t, err)
+	s.Tokens().Create(int(user.ID), support.AtUUID, support.AccessToken, support.AtExpiresTime)
+	assert.Equal(t, http.StatusUnauthorized, refresh(s, c, support.AccessToken))
+}
+
+func TestRefreshScopedToken(t *testing.T) {
+	s, user := refreshTestStore(t)
+	c := cache.NewMemory()
+
+	// Scoped tokens keep their scopes, they can't become full sessions
+	dto := model.ScopedTokenDTO{Scopes: []string{app.ScopeVaultRead}, ExpiresIn: 1}
+	scoped, err := app.CreateScopedToken(s, user, []string{app.ScopeVaultRead, app.ScopeAccount}, dto, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, refresh(s, c, scoped.AccessToken))
+}
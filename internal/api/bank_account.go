@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -16,15 +17,21 @@ const (
 	bankAccountDeleteSuccess = "BankAccount deleted successfully!"
 )
 
+// bankAccountSortFields are the fields bank accounts can be sorted by
+var bankAccountSortFields = []string{"id", "created_at", "updated_at", "bank_name", "bank_code"}
+
 // FindAllBankAccounts finds all bank accounts
 func FindAllBankAccounts(s storage.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var err error
-		var bankAccountList []model.BankAccount
+		opts, err := ParseListOptions(r, bankAccountSortFields)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 
 		// Get all bank accounts from db
 		schema := r.Context().Value("schema").(string)
-		bankAccountList, err = s.BankAccounts().All(schema)
+		bankAccountList, total, err := s.BankAccounts().FindAll(opts, schema)
 		if err != nil {
 			RespondWithError(w, http.StatusNotFound, err.Error())
 			return
@@ -32,7 +39,7 @@ func FindAllBankAccounts(s storage.Store) http.HandlerFunc {
 
 		// Decrypt server side encrypted fields
 		for i := range bankAccountList {
-			uBankAccount, err := app.DecryptModel(&bankAccountList[i])
+			uBankAccount, err := app.DecryptModel(&bankAccountList[i], schema)
 			if err != nil {
 				RespondWithError(w, http.StatusInternalServerError, err.Error())
 				return
@@ -40,7 +47,7 @@ func FindAllBankAccounts(s storage.Store) http.HandlerFunc {
 			bankAccountList[i] = *uBankAccount.(*model.BankAccount)
 		}
 
-		RespondWithJSON(w, http.StatusOK, bankAccountList)
+		RespondWithJSON(w, http.StatusOK, NewListResponse(bankAccountList, total, opts))
 	}
 }
 
@@ -64,7 +71,7 @@ func FindBankAccountByID(s storage.Store) http.HandlerFunc {
 		}
 
 		// Decrypt server side encrypted fields
-		uBankAccount, err := app.DecryptModel(bankAccount)
+		uBankAccount, err := app.DecryptModel(bankAccount, schema)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -73,7 +80,14 @@ func FindBankAccountByID(s storage.Store) http.HandlerFunc {
 		// Create DTO
 		bankAccountDTO := model.ToBankAccountDTO(uBankAccount.(*model.BankAccount))
 
-		RespondWithJSON(w, http.StatusOK, bankAccountDTO)
+		// Add related items if requested
+		response, err := expandRelated(s, r, app.ItemTypeBankAccount, bankAccount.ID, bankAccountDTO)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, response)
 	}
 }
 
@@ -93,12 +107,12 @@ func CreateBankAccount(s storage.Store) http.HandlerFunc {
 		schema := r.Context().Value("schema").(string)
 		createdBankAccount, err := app.CreateBankAccount(s, &bankAccountDTO, schema)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+			RespondWithError(w, updateErrorStatus(err), err.Error())
 			return
 		}
 
 		// Decrypt server side encrypted fields
-		decBankAccount, err := app.DecryptModel(createdBankAccount)
+		decBankAccount, err := app.DecryptModel(createdBankAccount, schema)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -140,13 +154,23 @@ func UpdateBankAccount(s storage.Store) http.HandlerFunc {
 
 		// Update login
 		updatedBankAccount, err := app.UpdateBankAccount(s, bankAccount, &bankAccountDTO, schema)
+		if errors.Is(err, app.ErrRevisionConflict) {
+			// Update is kept as a conflicted copy instead of overwriting the newer revision
+			conflict, err := app.CreateBankAccountConflict(s, bankAccount, &bankAccountDTO, schema)
+			if err != nil {
+				RespondWithError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			RespondWithJSON(w, http.StatusConflict, conflict)
+			return
+		}
 		if err != nil {
-			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			RespondWithError(w, updateErrorStatus(err), err.Error())
 			return
 		}
 
 		// Decrypt server side encrypted fields
-		decBankAccount, err := app.DecryptModel(updatedBankAccount)
+		decBankAccount, err := app.DecryptModel(updatedBankAccount, schema)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -181,8 +205,11 @@ func BulkUpdateBankAccounts(s storage.Store) http.HandlerFunc {
 
 			// Update bankAccount
 			_, err = app.UpdateBankAccount(s, bankAccount, &bankAccountDTO, schema)
+			if errors.Is(err, app.ErrRevisionConflict) {
+				_, err = app.CreateBankAccountConflict(s, bankAccount, &bankAccountDTO, schema)
+			}
 			if err != nil {
-				RespondWithError(w, http.StatusInternalServerError, err.Error())
+				RespondWithError(w, updateErrorStatus(err), err.Error())
 				return
 			}
 		}
@@ -218,6 +245,7 @@ func DeleteBankAccount(s storage.Store) http.HandlerFunc {
 			RespondWithError(w, http.StatusNotFound, err.Error())
 			return
 		}
+		s.Relations().DeleteByItem(app.ItemTypeBankAccount, bankAccount.ID, schema)
 
 		response := model.Response{
 			Code:    http.StatusOK,
@@ -227,3 +255,46 @@ func DeleteBankAccount(s storage.Store) http.HandlerFunc {
 		RespondWithJSON(w, http.StatusOK, response)
 	}
 }
+
+// BatchBankAccounts creates, updates and deletes bank accounts in a single request
+func BatchBankAccounts(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		runBatch(w, r, batchHandlers{
+			create: func(raw json.RawMessage, schema string) (uint, error) {
+				var bankAccountDTO model.BankAccountDTO
+				if err := json.Unmarshal(raw, &bankAccountDTO); err != nil {
+					return 0, err
+				}
+				createdBankAccount, err := app.CreateBankAccount(s, &bankAccountDTO, schema)
+				if err != nil {
+					return 0, err
+				}
+				return createdBankAccount.ID, nil
+			},
+			update: func(raw json.RawMessage, schema string) (uint, error) {
+				var bankAccountDTO model.BankAccountDTO
+				if err := json.Unmarshal(raw, &bankAccountDTO); err != nil {
+					return 0, err
+				}
+				bankAccount, err := s.BankAccounts().FindByID(bankAccountDTO.ID, schema)
+				if err != nil {
+					return bankAccountDTO.ID, err
+				}
+				if _, err := app.UpdateBankAccount(s, bankAccount, &bankAccountDTO, schema); err != nil {
+					return bankAccount.ID, err
+				}
+				return bankAccount.ID, nil
+			},
+			delete: func(id uint, schema string) error {
+				bankAccount, err := s.BankAccounts().FindByID(id, schema)
+				if err != nil {
+					return err
+				}
+				if err := s.BankAccounts().Delete(bankAccount.ID, schema); err != nil {
+					return err
+				}
+				return s.Relations().DeleteByItem(app.ItemTypeBankAccount, bankAccount.ID, schema)
+			},
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/spf13/viper"
+)
+
+// batchHandlers are the item type specific operations of a batch.
+// Create and update return the id of the stored item.
+type batchHandlers struct {
+	create func(raw json.RawMessage, schema string) (uint, error)
+	update func(raw json.RawMessage, schema string) (uint, error)
+	delete func(id uint, schema string) error
+}
+
+// runBatch decodes the batch request and runs every operation on its own,
+// so a failing item doesn't stop the rest of the batch
+func runBatch(w http.ResponseWriter, r *http.Request, handlers batchHandlers) {
+	var batch model.BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+		return
+	}
+	defer r.Body.Close()
+
+	limit := viper.GetInt("server.batchLimit")
+	if batch.Size() == 0 || batch.Size() > limit {
+		RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Batch must have between 1 and %d operations", limit))
+		return
+	}
+
+	schema := r.Context().Value("schema").(string)
+	response := model.BatchResponse{Results: []model.BatchResult{}}
+
+	add := func(operation string, index int, id uint, err error) {
+		result := model.BatchResult{Operation: operation, Index: index, ID: id, Status: Success}
+		if err != nil {
+			result.Status = "Error"
+			result.Error = err.Error()
+			response.Failed++
+		} else {
+			response.Succeeded++
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	for i, raw := range batch.Create {
+		id, err := handlers.create(raw, schema)
+		add(model.BatchCreate, i, id, err)
+	}
+	for i, raw := range batch.Update {
+		id, err := handlers.update(raw, schema)
+		add(model.BatchUpdate, i, id, err)
+	}
+	for i, id := range batch.Delete {
+		add(model.BatchDelete, i, id, handlers.delete(id, schema))
+	}
+
+	RespondWithJSON(w, http.StatusOK, response)
+}
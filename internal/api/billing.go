@@ -0,0 +1,101 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/billing"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+// maxWebhookSize limits the body of the payment provider webhooks
+const maxWebhookSize = 1 << 20
+
+var (
+	subscriptionUpdated = "Subscription updated successfully"
+	webhookIgnored      = "Webhook ignored"
+)
+
+// BillingWebhook updates the subscription of the user with the webhook of the
+// payment provider. Providers retry the webhooks which fail, so the ones of
+// other events and unknown customers are acknowledged and ignored.
+func BillingWebhook(s storage.Store, provider billing.Provider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookSize))
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+			return
+		}
+		defer r.Body.Close()
+
+		event, err := provider.Parse(r.Header, body)
+		if errors.Is(err, billing.ErrInvalidSignature) {
+			RespondWithError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		if errors.Is(err, billing.ErrIgnoredEvent) {
+			RespondWithJSON(w, http.StatusOK, model.Response{Code: http.StatusOK, Status: Success, Message: webhookIgnored})
+			return
+		}
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+			return
+		}
+
+		// Team checkouts pass the organization whose seats they pay for
+		if event.OrganizationID != 0 {
+			if err := app.UpdateTeamSubscription(s, event); err != nil {
+				logger.WithContext(r.Context()).Warnf("can't update organization %d of billing event %s error: %v", event.OrganizationID, event.Name, err)
+			}
+		}
+
+		user, err := findSubscriber(s, event)
+		if err != nil {
+			logger.WithContext(r.Context()).Warnf("can't find user of billing event %s error: %v", event.Name, err)
+			RespondWithJSON(w, http.StatusOK, model.Response{Code: http.StatusOK, Status: Success, Message: webhookIgnored})
+			return
+		}
+
+		// Ending subscriptions don't end the trial of the user, failed
+		// payments keep the pro features in the grace period
+		now := time.Now()
+		subscriptionType := app.SubscriptionTypeFree
+		switch {
+		case event.Active:
+			subscriptionType = app.SubscriptionTypePro
+		case app.InTrial(user, now):
+			subscriptionType = app.SubscriptionTypeTrial
+		case event.PastDue:
+			subscriptionType = app.LapsedSubscriptionType(user, now)
+		}
+		app.UpdateSubscription(s, user, subscriptionType)
+
+		response := model.Response{
+			Code:    http.StatusOK,
+			Status:  Success,
+			Message: subscriptionUpdated,
+		}
+		RespondWithJSON(w, http.StatusOK, response)
+	}
+}
+
+// findSubscriber finds the user of the event by the UUID passed to the
+// checkout, or by the email of the customer. Users who changed their email
+// are found by the one they subscribed with before the user who took it.
+func findSubscriber(s storage.Store, event *billing.Event) (*model.User, error) {
+	if event.UserUUID != "" {
+		return s.Users().FindByUUID(event.UserUUID)
+	}
+	if event.Email == "" {
+		return nil, errors.New("event has neither a user UUID nor an email")
+	}
+	if user, err := s.Users().FindByBillingEmail(event.Email); err == nil {
+		return user, nil
+	}
+	return s.Users().FindByEmail(event.Email)
+}
@@ -0,0 +1,21 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/passwall/passwall-server/internal/storage"
+)
+
+// FindAllBreaches finds the known breaches involving the email addresses of the user
+func FindAllBreaches(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value("user_id").(uint)
+		breaches, err := s.Breaches().FindByUserID(userID)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, breaches)
+	}
+}
@@ -0,0 +1,405 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+const (
+	collectionDeleteSuccess       = "Collection deleted successfully!"
+	collectionItemRemoveSuccess   = "Item removed from collection successfully!"
+	collectionMemberRemoveSuccess = "Member removed from collection successfully!"
+)
+
+// FindAllCollections finds all collections of an organization, members find
+// the collections they are given
+func FindAllCollections(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org, member, ok := findOrganization(s, w, r)
+		if !ok {
+			return
+		}
+
+		var collections []model.Collection
+		var err error
+		if app.CanEditOrganizationVault(member.Role) {
+			collections, err = s.Collections().All(org.ID)
+		} else {
+			collections, err = s.Collections().FindByMember(org.ID, member.UserID)
+		}
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		collectionDTOs := make([]*model.CollectionDTO, len(collections))
+		for i := range collections {
+			collectionDTOs[i] = model.ToCollectionDTO(&collections[i])
+		}
+
+		RespondWithJSON(w, http.StatusOK, collectionDTOs)
+	}
+}
+
+// CreateCollection creates a collection in an organization
+func CreateCollection(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org, member, ok := findOrganization(s, w, r)
+		if !ok {
+			return
+		}
+
+		if !app.CanManageOrganization(member.Role) {
+			RespondWithError(w, http.StatusForbidden, notOrganizationManager)
+			return
+		}
+
+		var collectionDTO model.CollectionDTO
+		if err := json.NewDecoder(r.Body).Decode(&collectionDTO); err != nil {
+			RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := app.PayloadValidator(collectionDTO); err != nil {
+			errs := GetErrors(err.(validator.ValidationErrors))
+			RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+			return
+		}
+
+		collection := model.ToCollection(&collectionDTO)
+		collection.OrganizationID = org.ID
+		createdCollection, err := s.Collections().Create(collection)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, model.ToCollectionDTO(createdCollection))
+	}
+}
+
+// UpdateCollection updates a collection of an organization
+func UpdateCollection(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collection, member, ok := findCollection(s, w, r)
+		if !ok {
+			return
+		}
+
+		if !app.CanManageOrganization(member.Role) {
+			RespondWithError(w, http.StatusForbidden, notOrganizationManager)
+			return
+		}
+
+		var collectionDTO model.CollectionDTO
+		if err := json.NewDecoder(r.Body).Decode(&collectionDTO); err != nil {
+			RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := app.PayloadValidator(collectionDTO); err != nil {
+			errs := GetErrors(err.(validator.ValidationErrors))
+			RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+			return
+		}
+
+		collection.Name = collectionDTO.Name
+		collection.MaxPasswordAge = collectionDTO.MaxPasswordAge
+		updatedCollection, err := s.Collections().Update(collection)
+		if err != nil {
+			RespondWithError(w, updateErrorStatus(err), err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, model.ToCollectionDTO(updatedCollection))
+	}
+}
+
+// DeleteCollection deletes a collection of an organization
+func DeleteCollection(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collection, member, ok := findCollection(s, w, r)
+		if !ok {
+			return
+		}
+
+		if !app.CanManageOrganization(member.Role) {
+			RespondWithError(w, http.StatusForbidden, notOrganizationManager)
+			return
+		}
+
+		if err := s.Collections().Delete(collection.ID); err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		response := model.Response{
+			Code:    http.StatusOK,
+			Status:  Success,
+			Message: collectionDeleteSuccess,
+		}
+		RespondWithJSON(w, http.StatusOK, response)
+	}
+}
+
+// FindCollectionItems finds all items of a collection
+func FindCollectionItems(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collection, member, ok := findCollection(s, w, r)
+		if !ok {
+			return
+		}
+
+		// Members only read the collections they are given
+		if !app.CanEditOrganizationVault(member.Role) {
+			given, err := s.Collections().IsMember(collection.ID, member.UserID)
+			if err != nil || !given {
+				RespondWithError(w, http.StatusForbidden, notCollectionMember)
+				return
+			}
+		}
+
+		items, err := s.Collections().Items(collection.ID)
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, items)
+	}
+}
+
+// AddCollectionItem adds an item of the organization vault to a collection
+func AddCollectionItem(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collection, member, ok := findCollection(s, w, r)
+		if !ok {
+			return
+		}
+
+		if !app.CanEditOrganizationVault(member.Role) {
+			RespondWithError(w, http.StatusForbidden, notOrganizationEditor)
+			return
+		}
+
+		var itemDTO model.CollectionItemDTO
+		if err := json.NewDecoder(r.Body).Decode(&itemDTO); err != nil {
+			RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := app.PayloadValidator(itemDTO); err != nil {
+			errs := GetErrors(err.(validator.ValidationErrors))
+			RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+			return
+		}
+
+		// Only items of the organization vault can be added to its collections
+		org, err := s.Organizations().FindByID(collection.OrganizationID)
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		vault, err := s.Region(org.Region)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if err := app.ItemExists(vault, itemDTO.ItemType, itemDTO.ItemID, org.Schema); err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		// Shares are limited by the plan of the organization owner
+		owner, err := s.Users().FindByID(org.OwnerID)
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		shares, err := app.CountShares(s, org.ID)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if err := app.CheckQuota(owner, app.QuotaShares, shares); err != nil {
+			RespondWithQuotaError(w, err)
+			return
+		}
+
+		item := model.ToCollectionItem(&itemDTO)
+		item.CollectionID = collection.ID
+		createdItem, err := s.Collections().AddItem(item)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, createdItem)
+	}
+}
+
+// RemoveCollectionItem removes an item from a collection
+func RemoveCollectionItem(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collection, member, ok := findCollection(s, w, r)
+		if !ok {
+			return
+		}
+
+		if !app.CanEditOrganizationVault(member.Role) {
+			RespondWithError(w, http.StatusForbidden, notOrganizationEditor)
+			return
+		}
+
+		vars := mux.Vars(r)
+		itemID, err := strconv.Atoi(vars["item_id"])
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if err := s.Collections().RemoveItem(collection.ID, vars["item_type"], uint(itemID)); err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		response := model.Response{
+			Code:    http.StatusOK,
+			Status:  Success,
+			Message: collectionItemRemoveSuccess,
+		}
+		RespondWithJSON(w, http.StatusOK, response)
+	}
+}
+
+// FindCollectionMembers finds the members given a collection
+func FindCollectionMembers(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collection, member, ok := findCollection(s, w, r)
+		if !ok {
+			return
+		}
+
+		if !app.CanManageOrganization(member.Role) {
+			RespondWithError(w, http.StatusForbidden, notOrganizationManager)
+			return
+		}
+
+		members, err := s.Collections().Members(collection.ID)
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, members)
+	}
+}
+
+// AddCollectionMember gives an accepted member of the organization the items of a collection
+func AddCollectionMember(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collection, member, ok := findCollection(s, w, r)
+		if !ok {
+			return
+		}
+
+		if !app.CanManageOrganization(member.Role) {
+			RespondWithError(w, http.StatusForbidden, notOrganizationManager)
+			return
+		}
+
+		var memberDTO model.CollectionMemberDTO
+		if err := json.NewDecoder(r.Body).Decode(&memberDTO); err != nil {
+			RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := app.PayloadValidator(memberDTO); err != nil {
+			errs := GetErrors(err.(validator.ValidationErrors))
+			RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+			return
+		}
+
+		given, err := s.Organizations().FindMember(collection.OrganizationID, memberDTO.UserID)
+		if err != nil || given.Status != model.MemberStatusAccepted {
+			RespondWithError(w, http.StatusNotFound, notOrganizationMember)
+			return
+		}
+
+		createdMember, err := s.Collections().AddMember(&model.CollectionMember{CollectionID: collection.ID, UserID: given.UserID})
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, createdMember)
+	}
+}
+
+// RemoveCollectionMember takes a collection back from a member of the organization
+func RemoveCollectionMember(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collection, member, ok := findCollection(s, w, r)
+		if !ok {
+			return
+		}
+
+		if !app.CanManageOrganization(member.Role) {
+			RespondWithError(w, http.StatusForbidden, notOrganizationManager)
+			return
+		}
+
+		userID, err := strconv.Atoi(mux.Vars(r)["user_id"])
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if err := s.Collections().RemoveMember(collection.ID, uint(userID)); err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		response := model.Response{
+			Code:    http.StatusOK,
+			Status:  Success,
+			Message: collectionMemberRemoveSuccess,
+		}
+		RespondWithJSON(w, http.StatusOK, response)
+	}
+}
+
+// findCollection finds the collection defined by collection_id in route
+// after checking the organization membership of the user
+func findCollection(s storage.Store, w http.ResponseWriter, r *http.Request) (*model.Collection, *model.OrganizationMember, bool) {
+	org, member, ok := findOrganization(s, w, r)
+	if !ok {
+		return nil, nil, false
+	}
+
+	collectionID, err := strconv.Atoi(mux.Vars(r)["collection_id"])
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return nil, nil, false
+	}
+
+	collection, err := s.Collections().FindByID(uint(collectionID), org.ID)
+	if err != nil {
+		RespondWithError(w, http.StatusNotFound, err.Error())
+		return nil, nil, false
+	}
+
+	return collection, member, true
+}
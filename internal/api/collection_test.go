@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/internal/storage/migration"
+	"github.com/passwall/passwall-server/internal/storage/sqlite"
+	"github.com/passwall/passwall-server/model"
+)
+
+func TestCollectionRoles(t *testing.T) {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "passwall.db"), &gorm.Config{})
+	require.NoError(t, err)
+	s := storage.New(db)
+	require.NoError(t, s.Migrations().Up(migration.SetSystem, ""))
+
+	org, err := s.Organizations().Create(&model.Organization{Name: "Team", OwnerID: 1})
+	require.NoError(t, err)
+	for userID, role := range map[uint]string{1: model.OrganizationRoleAdmin, 2: model.OrganizationRoleMember} {
+		_, err := s.Organizations().SaveMember(&model.OrganizationMember{OrganizationID: org.ID, UserID: userID, Role: role, Status: model.MemberStatusAccepted})
+		require.NoError(t, err)
+	}
+	given, err := s.Collections().Create(&model.Collection{OrganizationID: org.ID, Name: "Given"})
+	require.NoError(t, err)
+	_, err = s.Collections().Create(&model.Collection{OrganizationID: org.ID, Name: "Other"})
+	require.NoError(t, err)
+	_, err = s.Collections().AddMember(&model.CollectionMember{CollectionID: given.ID, UserID: 2})
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/organizations/{id:[0-9]+}/collections", FindAllCollections(s)).Methods(http.MethodGet)
+	router.HandleFunc("/organizations/{id:[0-9]+}/collections/{collection_id:[0-9]+}/items", FindCollectionItems(s)).Methods(http.MethodGet)
+	router.HandleFunc("/organizations/{id:[0-9]+}/collections/{collection_id:[0-9]+}/items", AddCollectionItem(s)).Methods(http.MethodPost)
+	serve := func(userID uint, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), "user_id", userID))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Admins find every collection, members the ones they are given
+	for userID, expected := range map[uint][]string{1: {"Given", "Other"}, 2: {"Given"}} {
+		w := serve(userID, http.MethodGet, "/organizations/1/collections", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var collections []model.CollectionDTO
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &collections))
+		names := []string{}
+		for _, collection := range collections {
+			names = append(names, collection.Name)
+		}
+		assert.Equal(t, expected, names)
+	}
+
+	assert.Equal(t, http.StatusOK, serve(2, http.MethodGet, "/organizations/1/collections/1/items", "").Code)
+	assert.Equal(t, http.StatusForbidden, serve(2, http.MethodGet, "/organizations/1/collections/2/items", "").Code)
+	assert.Equal(t, http.StatusOK, serve(1, http.MethodGet, "/organizations/1/collections/2/items", "").Code)
+
+	// Members can't change the collections
+	assert.Equal(t, http.StatusForbidden, serve(2, http.MethodPost, "/organizations/1/collections/1/items", `{"item_type": "login", "item_id": 1}`).Code)
+}
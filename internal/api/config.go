@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// FindClientConfig returns the configuration of the server the clients need
+func FindClientConfig(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		policy, err := app.FindSecurityPolicy(s)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		config := model.ClientConfig{
+			License:         app.FindLicenseStatus(time.Now()),
+			Policy:          policy,
+			ExportPublicKey: app.ExportPublicKey(),
+		}
+		if app.ZeroKnowledge() {
+			config.Encryption = model.EncryptionMode{ZeroKnowledge: true, CiphertextFormat: app.ClientCiphertextFormat}
+		}
+
+		RespondWithJSON(w, http.StatusOK, config)
+	}
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+const (
+	conflictResolveSuccess = "Conflict resolved successfully!"
+)
+
+// FindAllConflicts finds the items having conflicted copies from outdated updates
+func FindAllConflicts(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		schema := r.Context().Value("schema").(string)
+		conflicts, err := app.FindAllConflicts(s, schema)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, conflicts)
+	}
+}
+
+// ResolveConflict keeps the original item, the conflicted copy or both
+func ResolveConflict(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var resolveDTO model.ResolveConflictDTO
+		if err := json.NewDecoder(r.Body).Decode(&resolveDTO); err != nil {
+			RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := app.PayloadValidator(resolveDTO); err != nil {
+			errs := GetErrors(err.(validator.ValidationErrors))
+			RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+			return
+		}
+
+		schema := r.Context().Value("schema").(string)
+		err = app.ResolveConflict(s, uint(id), resolveDTO.Keep, schema)
+		if err == app.ErrConflictNotFound {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		response := model.Response{
+			Code:    http.StatusOK,
+			Status:  Success,
+			Message: conflictResolveSuccess,
+		}
+		RespondWithJSON(w, http.StatusOK, response)
+	}
+}
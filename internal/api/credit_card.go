@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -21,15 +22,21 @@ const (
 	Success = "Success"
 )
 
+// creditCardSortFields are the fields credit cards can be sorted by
+var creditCardSortFields = []string{"id", "created_at", "updated_at", "card_name"}
+
 // FindAllCreditCards finds all credid carts
 func FindAllCreditCards(s storage.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var err error
-		var creditCardList []model.CreditCard
+		opts, err := ParseListOptions(r, creditCardSortFields)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 
 		// Get all credit cards from db
 		schema := r.Context().Value("schema").(string)
-		creditCardList, err = s.CreditCards().All(schema)
+		creditCardList, total, err := s.CreditCards().FindAll(opts, schema)
 		if err != nil {
 			RespondWithError(w, http.StatusNotFound, err.Error())
 			return
@@ -37,7 +44,7 @@ func FindAllCreditCards(s storage.Store) http.HandlerFunc {
 
 		// Decrypt server side encrypted fields
 		for i := range creditCardList {
-			uCreditCard, err := app.DecryptModel(&creditCardList[i])
+			uCreditCard, err := app.DecryptModel(&creditCardList[i], schema)
 			if err != nil {
 				RespondWithError(w, http.StatusInternalServerError, err.Error())
 				return
@@ -45,7 +52,7 @@ func FindAllCreditCards(s storage.Store) http.HandlerFunc {
 			creditCardList[i] = *uCreditCard.(*model.CreditCard)
 		}
 
-		RespondWithJSON(w, http.StatusOK, creditCardList)
+		RespondWithJSON(w, http.StatusOK, NewListResponse(creditCardList, total, opts))
 	}
 }
 
@@ -70,7 +77,7 @@ func FindCreditCardByID(s storage.Store) http.HandlerFunc {
 		}
 
 		// Decrypt server side encrypted fields
-		uCreditCard, err := app.DecryptModel(creditCard)
+		uCreditCard, err := app.DecryptModel(creditCard, schema)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -79,7 +86,14 @@ func FindCreditCardByID(s storage.Store) http.HandlerFunc {
 		// Create DTO
 		creditCardDTO := model.ToCreditCardDTO(uCreditCard.(*model.CreditCard))
 
-		RespondWithJSON(w, http.StatusOK, creditCardDTO)
+		// Add related items if requested
+		response, err := expandRelated(s, r, app.ItemTypeCreditCard, creditCard.ID, creditCardDTO)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, response)
 	}
 }
 
@@ -99,12 +113,12 @@ func CreateCreditCard(s storage.Store) http.HandlerFunc {
 		schema := r.Context().Value("schema").(string)
 		createdCreditCard, err := app.CreateCreditCard(s, &creditCardDTO, schema)
 		if err != nil {
-			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			RespondWithError(w, updateErrorStatus(err), err.Error())
 			return
 		}
 
 		// Decrypt server side encrypted fields
-		decCreditCard, err := app.DecryptModel(createdCreditCard)
+		decCreditCard, err := app.DecryptModel(createdCreditCard, schema)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -146,13 +160,23 @@ func UpdateCreditCard(s storage.Store) http.HandlerFunc {
 
 		// Update credit card
 		updatedCreditCard, err := app.UpdateCreditCard(s, creditCard, &creditCardDTO, schema)
+		if errors.Is(err, app.ErrRevisionConflict) {
+			// Update is kept as a conflicted copy instead of overwriting the newer revision
+			conflict, err := app.CreateCreditCardConflict(s, creditCard, &creditCardDTO, schema)
+			if err != nil {
+				RespondWithError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			RespondWithJSON(w, http.StatusConflict, conflict)
+			return
+		}
 		if err != nil {
-			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			RespondWithError(w, updateErrorStatus(err), err.Error())
 			return
 		}
 
 		// Decrypt server side encrypted fields
-		decCreditCard, err := app.DecryptModel(updatedCreditCard)
+		decCreditCard, err := app.DecryptModel(updatedCreditCard, schema)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -186,8 +210,11 @@ func BulkUpdateCreditCards(s storage.Store) http.HandlerFunc {
 
 			// Update creditCard
 			_, err = app.UpdateCreditCard(s, creditCard, &creditCardDTO, schema)
+			if errors.Is(err, app.ErrRevisionConflict) {
+				_, err = app.CreateCreditCardConflict(s, creditCard, &creditCardDTO, schema)
+			}
 			if err != nil {
-				RespondWithError(w, http.StatusInternalServerError, err.Error())
+				RespondWithError(w, updateErrorStatus(err), err.Error())
 				return
 			}
 		}
@@ -223,6 +250,7 @@ func DeleteCreditCard(s storage.Store) http.HandlerFunc {
 			RespondWithError(w, http.StatusNotFound, err.Error())
 			return
 		}
+		s.Relations().DeleteByItem(app.ItemTypeCreditCard, creditCard.ID, schema)
 
 		response := model.Response{
 			Code:    http.StatusOK,
@@ -232,3 +260,46 @@ func DeleteCreditCard(s storage.Store) http.HandlerFunc {
 		RespondWithJSON(w, http.StatusOK, response)
 	}
 }
+
+// BatchCreditCards creates, updates and deletes credit cards in a single request
+func BatchCreditCards(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		runBatch(w, r, batchHandlers{
+			create: func(raw json.RawMessage, schema string) (uint, error) {
+				var creditCardDTO model.CreditCardDTO
+				if err := json.Unmarshal(raw, &creditCardDTO); err != nil {
+					return 0, err
+				}
+				createdCreditCard, err := app.CreateCreditCard(s, &creditCardDTO, schema)
+				if err != nil {
+					return 0, err
+				}
+				return createdCreditCard.ID, nil
+			},
+			update: func(raw json.RawMessage, schema string) (uint, error) {
+				var creditCardDTO model.CreditCardDTO
+				if err := json.Unmarshal(raw, &creditCardDTO); err != nil {
+					return 0, err
+				}
+				creditCard, err := s.CreditCards().FindByID(creditCardDTO.ID, schema)
+				if err != nil {
+					return creditCardDTO.ID, err
+				}
+				if _, err := app.UpdateCreditCard(s, creditCard, &creditCardDTO, schema); err != nil {
+					return creditCard.ID, err
+				}
+				return creditCard.ID, nil
+			},
+			delete: func(id uint, schema string) error {
+				creditCard, err := s.CreditCards().FindByID(id, schema)
+				if err != nil {
+					return err
+				}
+				if err := s.CreditCards().Delete(creditCard.ID, schema); err != nil {
+					return err
+				}
+				return s.Relations().DeleteByItem(app.ItemTypeCreditCard, creditCard.ID, schema)
+			},
+		})
+	}
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -12,14 +13,20 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// emailSortFields are the fields emails can be sorted by
+var emailSortFields = []string{"id", "created_at", "updated_at", "title"}
+
 // FindAllEmails ...
 func FindAllEmails(s storage.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var err error
-		var emailList []model.Email
+		opts, err := ParseListOptions(r, emailSortFields)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 
 		schema := r.Context().Value("schema").(string)
-		emailList, err = s.Emails().All(schema)
+		emailList, total, err := s.Emails().FindAll(opts, schema)
 		if err != nil {
 			RespondWithError(w, http.StatusNotFound, err.Error())
 			return
@@ -27,7 +34,7 @@ func FindAllEmails(s storage.Store) http.HandlerFunc {
 
 		// Decrypt server side encrypted fields
 		for i := range emailList {
-			decEmail, err := app.DecryptModel(&emailList[i])
+			decEmail, err := app.DecryptModel(&emailList[i], schema)
 			if err != nil {
 				RespondWithError(w, http.StatusInternalServerError, err.Error())
 				return
@@ -35,7 +42,7 @@ func FindAllEmails(s storage.Store) http.HandlerFunc {
 			emailList[i] = *decEmail.(*model.Email)
 		}
 
-		RespondWithJSON(w, http.StatusOK, emailList)
+		RespondWithJSON(w, http.StatusOK, NewListResponse(emailList, total, opts))
 	}
 }
 
@@ -58,7 +65,7 @@ func FindEmailByID(s storage.Store) http.HandlerFunc {
 		}
 
 		// Decrypt server side encrypted fields
-		decEmail, err := app.DecryptModel(email)
+		decEmail, err := app.DecryptModel(email, schema)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -66,7 +73,14 @@ func FindEmailByID(s storage.Store) http.HandlerFunc {
 
 		emailDTO := model.ToEmailDTO(decEmail.(*model.Email))
 
-		RespondWithJSON(w, http.StatusOK, emailDTO)
+		// Add related items if requested
+		response, err := expandRelated(s, r, app.ItemTypeEmail, email.ID, emailDTO)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, response)
 	}
 }
 
@@ -86,12 +100,12 @@ func CreateEmail(s storage.Store) http.HandlerFunc {
 		schema := r.Context().Value("schema").(string)
 		createdEmail, err := app.CreateEmail(s, &emailDTO, schema)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+			RespondWithError(w, updateErrorStatus(err), err.Error())
 			return
 		}
 
 		// Decrypt server side encrypted fields
-		decEmail, err := app.DecryptModel(createdEmail)
+		decEmail, err := app.DecryptModel(createdEmail, schema)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -133,13 +147,23 @@ func UpdateEmail(s storage.Store) http.HandlerFunc {
 
 		// Update email
 		updatedEmail, err := app.UpdateEmail(s, email, &emailDTO, schema)
+		if errors.Is(err, app.ErrRevisionConflict) {
+			// Update is kept as a conflicted copy instead of overwriting the newer revision
+			conflict, err := app.CreateEmailConflict(s, email, &emailDTO, schema)
+			if err != nil {
+				RespondWithError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			RespondWithJSON(w, http.StatusConflict, conflict)
+			return
+		}
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+			RespondWithError(w, updateErrorStatus(err), err.Error())
 			return
 		}
 
 		// Decrypt server side encrypted fields
-		decEmail, err := app.DecryptModel(updatedEmail)
+		decEmail, err := app.DecryptModel(updatedEmail, schema)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -175,8 +199,11 @@ func BulkUpdateEmails(s storage.Store) http.HandlerFunc {
 
 			// Update email
 			_, err = app.UpdateEmail(s, email, &emailDTO, schema)
+			if errors.Is(err, app.ErrRevisionConflict) {
+				_, err = app.CreateEmailConflict(s, email, &emailDTO, schema)
+			}
 			if err != nil {
-				RespondWithError(w, http.StatusInternalServerError, err.Error())
+				RespondWithError(w, updateErrorStatus(err), err.Error())
 				return
 			}
 		}
@@ -212,6 +239,7 @@ func DeleteEmail(s storage.Store) http.HandlerFunc {
 			RespondWithError(w, http.StatusNotFound, err.Error())
 			return
 		}
+		s.Relations().DeleteByItem(app.ItemTypeEmail, email.ID, schema)
 
 		response := model.Response{
 			Code:    http.StatusOK,
@@ -221,3 +249,46 @@ func DeleteEmail(s storage.Store) http.HandlerFunc {
 		RespondWithJSON(w, http.StatusOK, response)
 	}
 }
+
+// BatchEmails creates, updates and deletes emails in a single request
+func BatchEmails(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		runBatch(w, r, batchHandlers{
+			create: func(raw json.RawMessage, schema string) (uint, error) {
+				var emailDTO model.EmailDTO
+				if err := json.Unmarshal(raw, &emailDTO); err != nil {
+					return 0, err
+				}
+				createdEmail, err := app.CreateEmail(s, &emailDTO, schema)
+				if err != nil {
+					return 0, err
+				}
+				return createdEmail.ID, nil
+			},
+			update: func(raw json.RawMessage, schema string) (uint, error) {
+				var emailDTO model.EmailDTO
+				if err := json.Unmarshal(raw, &emailDTO); err != nil {
+					return 0, err
+				}
+				email, err := s.Emails().FindByID(emailDTO.ID, schema)
+				if err != nil {
+					return emailDTO.ID, err
+				}
+				if _, err := app.UpdateEmail(s, email, &emailDTO, schema); err != nil {
+					return email.ID, err
+				}
+				return email.ID, nil
+			},
+			delete: func(id uint, schema string) error {
+				email, err := s.Emails().FindByID(id, schema)
+				if err != nil {
+					return err
+				}
+				if err := s.Emails().Delete(email.ID, schema); err != nil {
+					return err
+				}
+				return s.Relations().DeleteByItem(app.ItemTypeEmail, email.ID, schema)
+			},
+		})
+	}
+}
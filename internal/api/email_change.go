@@ -0,0 +1,169 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/cache"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+var (
+	emailChangeRequested = "Codes sent to the new and the current email successfully"
+	emailChangeWaiting   = "Email change is waiting for the other email"
+	emailChangeAborted   = "Email change aborted successfully"
+)
+
+// RequestEmailChange sends a code to the new email of the user and a link to
+// approve or abort the change to the current one
+func RequestEmailChange(s storage.Store, c cache.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var dto model.EmailChangeDTO
+		if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+			RespondWithError(w, http.StatusUnprocessableEntity, InvalidJSON)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := app.PayloadValidator(dto); err != nil {
+			errs := GetErrors(err.(validator.ValidationErrors))
+			RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+			return
+		}
+
+		userID := r.Context().Value("user_id").(uint)
+		user, err := s.Users().FindByID(userID)
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		if err := app.RequestEmailChange(s, c, user, dto.Email, time.Now()); err != nil {
+			logger.WithContext(r.Context()).Errorf("can't request email change of user %d error: %v", user.ID, err)
+			RespondWithError(w, emailChangeErrorStatus(err), err.Error())
+			return
+		}
+
+		response := model.Response{
+			Code:    http.StatusOK,
+			Status:  Success,
+			Message: emailChangeRequested,
+		}
+		RespondWithJSON(w, http.StatusOK, response)
+	}
+}
+
+// ConfirmEmailChange checks the code sent to the new email, the email of the
+// user changes when the current email approved it too
+func ConfirmEmailChange(s storage.Store, c cache.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var dto model.EmailChangeCodeDTO
+		if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+			RespondWithError(w, http.StatusUnprocessableEntity, InvalidJSON)
+			return
+		}
+		defer r.Body.Close()
+
+		userID := r.Context().Value("user_id").(uint)
+		user, err := s.Users().FindByID(userID)
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		user, changed, err := app.ConfirmEmailChange(s, c, user, dto.Code, time.Now())
+		if err != nil {
+			RespondWithError(w, emailChangeErrorStatus(err), err.Error())
+			return
+		}
+		respondEmailChange(s, w, r, user, changed)
+	}
+}
+
+// ApproveEmailChange approves the email change of the link sent to the
+// current email, the email of the user changes when the new email confirmed
+// it too
+func ApproveEmailChange(s storage.Store, c cache.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var dto model.EmailChangeTokenDTO
+		if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+			RespondWithError(w, http.StatusUnprocessableEntity, InvalidJSON)
+			return
+		}
+		defer r.Body.Close()
+
+		user, changed, err := app.ApproveEmailChange(s, c, dto.Token, time.Now())
+		if err != nil {
+			RespondWithError(w, emailChangeErrorStatus(err), err.Error())
+			return
+		}
+		respondEmailChange(s, w, r, user, changed)
+	}
+}
+
+// AbortEmailChange ends the email change of the link sent to the current email
+func AbortEmailChange(s storage.Store, c cache.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var dto model.EmailChangeTokenDTO
+		if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+			RespondWithError(w, http.StatusUnprocessableEntity, InvalidJSON)
+			return
+		}
+		defer r.Body.Close()
+
+		user, err := app.AbortEmailChange(s, c, dto.Token)
+		if err != nil {
+			RespondWithError(w, emailChangeErrorStatus(err), err.Error())
+			return
+		}
+
+		event := NewAuditEvent(r, app.AuditActionAbortEmail, "users", user.ID)
+		event.UserID = user.ID
+		RecordAuditEvent(s, r, event)
+
+		response := model.Response{
+			Code:    http.StatusOK,
+			Status:  Success,
+			Message: emailChangeAborted,
+		}
+		RespondWithJSON(w, http.StatusOK, response)
+	}
+}
+
+// respondEmailChange responds the user with the new email when it changed, or
+// that the change waits for the other email
+func respondEmailChange(s storage.Store, w http.ResponseWriter, r *http.Request, user *model.User, changed bool) {
+	if !changed {
+		response := model.Response{
+			Code:    http.StatusAccepted,
+			Status:  Success,
+			Message: emailChangeWaiting,
+		}
+		RespondWithJSON(w, http.StatusAccepted, response)
+		return
+	}
+
+	event := NewAuditEvent(r, app.AuditActionChangeEmail, "users", user.ID)
+	event.UserID = user.ID
+	RecordAuditEvent(s, r, event)
+
+	RespondWithJSON(w, http.StatusOK, model.ToUserDTO(user))
+}
+
+// emailChangeErrorStatus returns the response status of the email change errors
+func emailChangeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, app.ErrEmailTaken):
+		return http.StatusConflict
+	case errors.Is(err, app.ErrNoEmailChange), errors.Is(err, app.ErrInvalidEmailChange):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
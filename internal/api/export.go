@@ -26,7 +26,7 @@ func getLogins(s storage.Store, w http.ResponseWriter, r *http.Request) [][]stri
 
 	// Decrypt server side encrypted fields
 	for i := range loginList {
-		uLogin, err := app.DecryptModel(&loginList[i])
+		uLogin, err := app.DecryptModel(&loginList[i], schema)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return nil
@@ -58,7 +58,7 @@ func getBankAccounts(s storage.Store, w http.ResponseWriter, r *http.Request) []
 
 	// Decrypt server side encrypted fields
 	for i := range bankAccountList {
-		uBankAccount, err := app.DecryptModel(&bankAccountList[i])
+		uBankAccount, err := app.DecryptModel(&bankAccountList[i], schema)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return nil
@@ -93,7 +93,7 @@ func getCreditCards(s storage.Store, w http.ResponseWriter, r *http.Request) [][
 
 	// Decrypt server side encrypted fields
 	for i := range creditCardList {
-		uCreditCard, err := app.DecryptModel(&creditCardList[i])
+		uCreditCard, err := app.DecryptModel(&creditCardList[i], schema)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return nil
@@ -128,7 +128,7 @@ func getEmails(s storage.Store, w http.ResponseWriter, r *http.Request) [][]stri
 
 	// Decrypt server side encrypted fields
 	for i := range emailList {
-		decEmail, err := app.DecryptModel(&emailList[i])
+		decEmail, err := app.DecryptModel(&emailList[i], schema)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return nil
@@ -160,7 +160,7 @@ func getNotes(s storage.Store, w http.ResponseWriter, r *http.Request) [][]strin
 
 	// Decrypt server side encrypted fields
 	for i := range noteList {
-		uNote, err := app.DecryptModel(&noteList[i])
+		uNote, err := app.DecryptModel(&noteList[i], schema)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return nil
@@ -192,7 +192,7 @@ func getServers(s storage.Store, w http.ResponseWriter, r *http.Request) [][]str
 
 	// Decrypt server side encrypted fields
 	for i := range serverList {
-		decServer, err := app.DecryptModel(&serverList[i])
+		decServer, err := app.DecryptModel(&serverList[i], schema)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return nil
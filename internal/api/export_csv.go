@@ -0,0 +1,61 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+// ExportCSV streams the decrypted items of the type in the path as a CSV file,
+// its signed manifest is sent in the trailers once the file is complete
+func ExportCSV(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		itemType := mux.Vars(r)["type"]
+		if !app.IsCSVExportType(itemType) {
+			RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("%s items can't be exported", itemType))
+			return
+		}
+
+		schema := r.Context().Value("schema").(string)
+		filename := fmt.Sprintf("passwall-%s-%s.csv", itemType, time.Now().Format("2006-01-02"))
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Trailer", exportManifestHeader+", "+exportSignatureHeader)
+		w.WriteHeader(http.StatusOK)
+
+		// Status is already sent, the client gets a truncated file without
+		// the manifest on errors
+		digest := app.NewExportDigest(app.ExportTypeCSV, itemType)
+		if err := app.WriteCSVExport(s, itemType, schema, flushWriter{io.MultiWriter(w, digest), w}); err != nil {
+			logger.WithContext(r.Context()).Errorf("Error while exporting %s items of schema %s: %v", itemType, schema, err)
+			return
+		}
+		manifest, signature, err := digest.Sign(0)
+		if err != nil {
+			logger.WithContext(r.Context()).Errorf("Error while signing %s export of schema %s: %v", itemType, schema, err)
+			return
+		}
+		setExportSignature(w.Header(), manifest, signature)
+	}
+}
+
+// flushWriter keeps flushing the pages to the client while the export is
+// written through another writer
+type flushWriter struct {
+	io.Writer
+	w http.ResponseWriter
+}
+
+// Flush sends the written pages to the client
+func (f flushWriter) Flush() {
+	if flusher, ok := f.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
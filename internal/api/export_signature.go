@@ -0,0 +1,46 @@
+package api
+
+import (
+	"encoding/base64"
+	"net/http"
+
+	"github.com/passwall/passwall-server/internal/app"
+)
+
+const (
+	// exportManifestHeader and exportSignatureHeader carry the base64 signed
+	// manifest of the CSV and KeePass exports
+	exportManifestHeader  = "X-Export-Manifest"
+	exportSignatureHeader = "X-Export-Signature"
+)
+
+// setExportSignature sets the manifest and signature of the export in the
+// headers, or in the trailers when they are declared before the body
+func setExportSignature(header http.Header, manifest, signature []byte) {
+	header.Set(exportManifestHeader, base64.StdEncoding.EncodeToString(manifest))
+	header.Set(exportSignatureHeader, base64.StdEncoding.EncodeToString(signature))
+}
+
+// verifyImportSignature checks the imported file against the manifest sent in
+// the export headers or the "manifest" and "signature" form fields. Files
+// without a manifest are imported like the exports of other password managers.
+func verifyImportSignature(r *http.Request, data []byte) error {
+	encodedManifest, encodedSignature := r.Header.Get(exportManifestHeader), r.Header.Get(exportSignatureHeader)
+	if encodedManifest == "" {
+		encodedManifest, encodedSignature = r.FormValue("manifest"), r.FormValue("signature")
+	}
+	if encodedManifest == "" {
+		return nil
+	}
+
+	manifest, err := base64.StdEncoding.DecodeString(encodedManifest)
+	if err != nil {
+		return app.ErrExportSignature
+	}
+	signature, err := base64.StdEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return app.ErrExportSignature
+	}
+	_, err = app.VerifyExport(manifest, signature, data)
+	return err
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+const (
+	folderDeleteSuccess = "Folder deleted successfully!"
+)
+
+// FindAllFolders finds all folders of the vault
+func FindAllFolders(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		schema := r.Context().Value("schema").(string)
+		folders, err := s.Folders().All(schema)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		folderDTOs := make([]*model.FolderDTO, len(folders))
+		for i := range folders {
+			folderDTOs[i] = model.ToFolderDTO(&folders[i])
+		}
+
+		RespondWithJSON(w, http.StatusOK, folderDTOs)
+	}
+}
+
+// CreateFolder creates a folder
+func CreateFolder(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var folderDTO model.FolderDTO
+		if err := json.NewDecoder(r.Body).Decode(&folderDTO); err != nil {
+			RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := app.PayloadValidator(folderDTO); err != nil {
+			errs := GetErrors(err.(validator.ValidationErrors))
+			RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+			return
+		}
+
+		schema := r.Context().Value("schema").(string)
+		folder, err := app.CreateFolder(s, &folderDTO, schema)
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, model.ToFolderDTO(folder))
+	}
+}
+
+// UpdateFolder renames or moves a folder
+func UpdateFolder(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var folderDTO model.FolderDTO
+		if err := json.NewDecoder(r.Body).Decode(&folderDTO); err != nil {
+			RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := app.PayloadValidator(folderDTO); err != nil {
+			errs := GetErrors(err.(validator.ValidationErrors))
+			RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+			return
+		}
+
+		schema := r.Context().Value("schema").(string)
+		folder, err := s.Folders().FindByID(uint(id), schema)
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		updatedFolder, err := app.UpdateFolder(s, folder, &folderDTO, schema)
+		if err == app.ErrFolderCycle {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, model.ToFolderDTO(updatedFolder))
+	}
+}
+
+// DeleteFolder deletes a folder, its items and subfolders are moved to the parent folder
+func DeleteFolder(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		schema := r.Context().Value("schema").(string)
+		folder, err := s.Folders().FindByID(uint(id), schema)
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		if err := s.Folders().Delete(folder.ID, schema); err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		response := model.Response{
+			Code:    http.StatusOK,
+			Status:  Success,
+			Message: folderDeleteSuccess,
+		}
+		RespondWithJSON(w, http.StatusOK, response)
+	}
+}
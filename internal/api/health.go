@@ -3,7 +3,10 @@ package api
 import (
 	"net/http"
 
+	"github.com/passwall/passwall-server/internal/app"
 	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/cache"
 )
 
 var (
@@ -70,3 +73,23 @@ func getStatus(state int, err error) *HealthProp {
 		Err:        err,
 	}
 }
+
+// Liveness reports that the process is alive, it doesn't check the dependencies
+// so that a broken database doesn't make the orchestrator restart the server
+func Liveness(w http.ResponseWriter, r *http.Request) {
+	RespondWithJSON(w, http.StatusOK, model.ComponentStatus{Status: app.HealthStatusUp})
+}
+
+// Readiness reports the state of the dependencies, the server answers with
+// 503 until all of them are up
+func Readiness(s storage.Store, c cache.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		readiness := app.CheckReadiness(s, c)
+
+		status := http.StatusOK
+		if readiness.Status != app.HealthStatusUp {
+			status = http.StatusServiceUnavailable
+		}
+		RespondWithJSON(w, status, readiness)
+	}
+}
@@ -2,11 +2,16 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/passwall/passwall-server/internal/app"
 	"github.com/passwall/passwall-server/model"
@@ -36,6 +41,89 @@ func SetArgs(r *http.Request, fields []string) (map[string]string, map[string]in
 	return argsStr, argsInt
 }
 
+// maxListLimit is the maximum number of items returned by list endpoints in one page
+const maxListLimit = 1000
+
+// ParseListOptions parses the pagination, sorting and filtering query params of item
+// list endpoints: limit, offset, cursor, sort, direction, search and updated_since
+func ParseListOptions(r *http.Request, sortFields []string) (*model.ListOptions, error) {
+	opts := &model.ListOptions{
+		Sort:      "updated_at",
+		Direction: "desc",
+		Search:    strings.TrimSpace(r.FormValue("search")),
+		Filters:   map[string]interface{}{},
+	}
+
+	for name, dest := range map[string]*int{"limit": &opts.Limit, "offset": &opts.Offset} {
+		v := r.FormValue(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid %s value", name)
+		}
+		*dest = n
+	}
+	if opts.Limit > maxListLimit {
+		opts.Limit = maxListLimit
+	}
+
+	if v := r.FormValue("cursor"); v != "" {
+		cursor, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor value")
+		}
+		opts.Cursor = uint(cursor)
+	}
+
+	if v := r.FormValue("sort"); v != "" {
+		if !include(sortFields, ToSnakeCase(v)) {
+			return nil, fmt.Errorf("invalid sort field, allowed fields: %s", strings.Join(sortFields, ", "))
+		}
+		opts.Sort = ToSnakeCase(v)
+	}
+
+	if v := r.FormValue("direction"); v != "" {
+		v = strings.ToLower(v)
+		if !include([]string{"asc", "desc"}, v) {
+			return nil, fmt.Errorf("invalid direction value")
+		}
+		opts.Direction = v
+	}
+
+	if v := r.FormValue("updated_since"); v != "" {
+		updatedSince, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid updated_since value, RFC3339 expected")
+		}
+		opts.UpdatedSince = &updatedSince
+	}
+
+	return opts, nil
+}
+
+// NewListResponse creates the response envelope of list endpoints. Items must be
+// a slice of models with an ID field. Next cursor is set when the page is full
+// so clients can ask for the rest.
+func NewListResponse(items interface{}, total int64, opts *model.ListOptions) model.ListResponse {
+	response := model.ListResponse{
+		Items:  items,
+		Total:  total,
+		Limit:  opts.Limit,
+		Offset: opts.Offset,
+	}
+
+	list := reflect.ValueOf(items)
+	if opts.Limit > 0 && list.Kind() == reflect.Slice && list.Len() == opts.Limit {
+		if id := reflect.Indirect(list.Index(list.Len() - 1)).FieldByName("ID"); id.IsValid() {
+			response.NextCursor = uint(id.Uint())
+		}
+	}
+
+	return response
+}
+
 // Offset returns the starting number of result for pagination
 func setOffset(offset string) int {
 	offsetInt, err := strconv.Atoi(offset)
@@ -129,3 +217,39 @@ func ToBody(r *http.Request, env, transmissionKey string) error {
 
 	return nil
 }
+
+// updateErrorStatus returns the response status for the errors of creates and
+// updates, items and records changed by another request meanwhile get 409
+func updateErrorStatus(err error) int {
+	var versionErr *app.VersionConflictError
+	switch {
+	case errors.Is(err, app.ErrRevisionConflict), errors.As(err, &versionErr):
+		return http.StatusConflict
+	case errors.Is(err, app.ErrRevisionRequired), errors.Is(err, app.ErrNotClientEncrypted):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// requestErrorStatus returns the response status for the errors of reading the
+// request body, bodies over the limit of the router get 413
+func requestErrorStatus(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// RequestIP returns the client IP of the request, the first address of
+// X-Forwarded-For is used behind proxies
+func RequestIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
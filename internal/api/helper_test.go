@@ -0,0 +1,79 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseListOptions(t *testing.T) {
+	fields := []string{"id", "updated_at", "title"}
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr bool
+		check   func(opts *model.ListOptions)
+	}{
+		{name: "Defaults", query: "", check: func(opts *model.ListOptions) {
+			assert.Equal(t, "updated_at", opts.Sort)
+			assert.Equal(t, "desc", opts.Direction)
+			assert.Equal(t, 0, opts.Limit)
+		}},
+		{name: "Paging and sorting", query: "limit=20&offset=40&sort=title&direction=ASC&search=git", check: func(opts *model.ListOptions) {
+			assert.Equal(t, 20, opts.Limit)
+			assert.Equal(t, 40, opts.Offset)
+			assert.Equal(t, "title", opts.Sort)
+			assert.Equal(t, "asc", opts.Direction)
+			assert.Equal(t, "git", opts.Search)
+		}},
+		{name: "Limit is capped", query: "limit=5000", check: func(opts *model.ListOptions) {
+			assert.Equal(t, maxListLimit, opts.Limit)
+		}},
+		{name: "Cursor and updated since", query: "cursor=15&updated_since=2021-01-02T15:04:05Z", check: func(opts *model.ListOptions) {
+			assert.Equal(t, uint(15), opts.Cursor)
+			assert.Equal(t, 2021, opts.UpdatedSince.Year())
+		}},
+		{name: "Unknown sort field", query: "sort=password", wantErr: true},
+		{name: "Invalid direction", query: "direction=up", wantErr: true},
+		{name: "Negative limit", query: "limit=-1", wantErr: true},
+		{name: "Invalid updated since", query: "updated_since=yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/logins?"+tt.query, nil)
+			opts, err := ParseListOptions(r, fields)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseListOptions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.check != nil {
+				tt.check(opts)
+			}
+		})
+	}
+}
+
+func TestNewListResponse(t *testing.T) {
+	logins := []model.Login{{ID: 3}, {ID: 7}}
+
+	response := NewListResponse(logins, 10, &model.ListOptions{Limit: 2})
+	assert.Equal(t, uint(7), response.NextCursor)
+	assert.Equal(t, int64(10), response.Total)
+
+	response = NewListResponse(logins, 2, &model.ListOptions{Limit: 5})
+	assert.Equal(t, uint(0), response.NextCursor)
+}
+
+func TestRequestErrorStatus(t *testing.T) {
+	_, err := io.ReadAll(http.MaxBytesReader(httptest.NewRecorder(), io.NopCloser(strings.NewReader("too large")), 3))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, requestErrorStatus(err))
+	assert.Equal(t, http.StatusBadRequest, requestErrorStatus(errors.New("invalid json")))
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// FindJobByID returns the status and progress of a background job of the user
+func FindJobByID(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		userID := r.Context().Value("user_id").(uint)
+		job, err := app.FindJob(s, uint(id), userID)
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, model.ToJobDTO(job))
+	}
+}
+
+// CancelJob stops a background job of the user
+func CancelJob(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		userID := r.Context().Value("user_id").(uint)
+		job, err := app.CancelJob(s, uint(id), userID)
+		if err == app.ErrJobNotFound {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if err == app.ErrJobFinished {
+			RespondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, model.ToJobDTO(job))
+	}
+}
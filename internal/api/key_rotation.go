@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// StartKeyRotation encrypts the vaults with new data keys in the background
+// and returns the job to follow its progress, the body is optional
+func StartKeyRotation(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var rotationDTO model.KeyRotationDTO
+		if err := json.NewDecoder(r.Body).Decode(&rotationDTO); err != nil && err != io.EOF {
+			RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := app.PayloadValidator(rotationDTO); err != nil {
+			errs := GetErrors(err.(validator.ValidationErrors))
+			RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+			return
+		}
+
+		userID := r.Context().Value("user_id").(uint)
+		job, err := app.StartKeyRotationJob(s, userID, rotationDTO.ItemsPerSecond)
+		if err == app.ErrKeyRotationRunning {
+			RespondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RecordAuditEvent(s, r, NewAuditEvent(r, app.AuditActionKeyRotation, "jobs", job.ID))
+		RespondWithJSON(w, http.StatusAccepted, model.ToJobDTO(job))
+	}
+}
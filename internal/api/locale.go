@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/passwall/passwall-server/internal/i18n"
+	"github.com/passwall/passwall-server/model"
+)
+
+// localeHeader tells the language of the messages in the response, the
+// responses are written in the locale it has
+const localeHeader = "Content-Language"
+
+// SetLocale sets the language of the messages in the response
+func SetLocale(w http.ResponseWriter, locale string) {
+	w.Header().Set(localeHeader, locale)
+}
+
+// Locale returns the language of the messages in the response, it is the
+// preference of the user or the one of the Accept-Language header
+func Locale(w http.ResponseWriter) string {
+	if locale := w.Header().Get(localeHeader); locale != "" {
+		return locale
+	}
+	return i18n.DefaultLocale
+}
+
+// localize translates the message to the language of the response
+func localize(w http.ResponseWriter, message string) string {
+	return i18n.Translate(Locale(w), message)
+}
+
+// localizeResponse translates the message of the success responses
+func localizeResponse(w http.ResponseWriter, payload interface{}) interface{} {
+	switch response := payload.(type) {
+	case model.Response:
+		response.Message = localize(w, response.Message)
+		return response
+	case *model.Response:
+		localized := *response
+		localized.Message = localize(w, response.Message)
+		return &localized
+	}
+	return payload
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRespondWithLocale(t *testing.T) {
+	w := httptest.NewRecorder()
+	SetLocale(w, "tr")
+	RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+
+	var errResponse ErrorResponseDTO
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&errResponse))
+	assert.Equal(t, "Geçersiz istek içeriği", errResponse.Message)
+	assert.Equal(t, "tr", w.Header().Get("Content-Language"))
+
+	w = httptest.NewRecorder()
+	SetLocale(w, "tr")
+	RespondWithJSON(w, http.StatusOK, model.Response{Code: http.StatusOK, Status: Success, Message: signupSuccess})
+
+	var response model.Response
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, "Kullanıcı başarıyla oluşturuldu", response.Message)
+	assert.Equal(t, Success, response.Status)
+
+	// Responses without a locale are in English
+	w = httptest.NewRecorder()
+	RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&errResponse))
+	assert.Equal(t, InvalidRequestPayload, errResponse.Message)
+}
@@ -2,9 +2,11 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/passwall/passwall-server/internal/app"
 	"github.com/passwall/passwall-server/internal/storage"
 	"github.com/passwall/passwall-server/model"
@@ -16,14 +18,31 @@ const (
 	loginDeleteSuccess = "Login deleted successfully!"
 )
 
+// loginSortFields are the fields logins can be sorted by
+var loginSortFields = []string{"id", "created_at", "updated_at", "title", "url"}
+
 // FindAllLogins finds all logins
 func FindAllLogins(s storage.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var err error
-		var loginList []model.Login
+		opts, err := ParseListOptions(r, loginSortFields)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// Filter by password expiry if requested
+		if v := r.FormValue("expired"); v != "" {
+			expired, err := strconv.ParseBool(v)
+			if err != nil {
+				RespondWithError(w, http.StatusBadRequest, "Invalid expired value")
+				return
+			}
+			opts.Filters["expired"] = expired
+		}
+
 		// Get all logins from db
 		schema := r.Context().Value("schema").(string)
-		loginList, err = s.Logins().All(schema)
+		loginList, total, err := s.Logins().FindAll(opts, schema)
 		if err != nil {
 			RespondWithError(w, http.StatusNotFound, err.Error())
 			return
@@ -31,7 +50,7 @@ func FindAllLogins(s storage.Store) http.HandlerFunc {
 
 		// Decrypt server side encrypted fields
 		for i := range loginList {
-			uLogin, err := app.DecryptModel(&loginList[i])
+			uLogin, err := app.DecryptModel(&loginList[i], schema)
 			if err != nil {
 				RespondWithError(w, http.StatusInternalServerError, err.Error())
 				return
@@ -39,6 +58,20 @@ func FindAllLogins(s storage.Store) http.HandlerFunc {
 			loginList[i] = *uLogin.(*model.Login)
 		}
 
+		RespondWithJSON(w, http.StatusOK, NewListResponse(loginList, total, opts))
+	}
+}
+
+// SearchLogins finds the logins matching the search query
+func SearchLogins(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		schema := r.Context().Value("schema").(string)
+		loginList, err := app.SearchLogins(s, r.FormValue("q"), schema)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
 		RespondWithJSON(w, http.StatusOK, loginList)
 	}
 }
@@ -63,7 +96,7 @@ func FindLoginsByID(s storage.Store) http.HandlerFunc {
 		}
 
 		// Decrypt server side encrypted fields
-		uLogin, err := app.DecryptModel(login)
+		uLogin, err := app.DecryptModel(login, schema)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -72,7 +105,14 @@ func FindLoginsByID(s storage.Store) http.HandlerFunc {
 		// Create DTO
 		loginDTO := model.ToLoginDTO(uLogin.(*model.Login))
 
-		RespondWithJSON(w, http.StatusOK, loginDTO)
+		// Add related items if requested
+		response, err := expandRelated(s, r, app.ItemTypeLogin, login.ID, loginDTO)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, response)
 	}
 }
 
@@ -92,12 +132,12 @@ func CreateLogin(s storage.Store) http.HandlerFunc {
 		schema := r.Context().Value("schema").(string)
 		createdLogin, err := app.CreateLogin(s, &loginDTO, schema)
 		if err != nil {
-			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			RespondWithError(w, updateErrorStatus(err), err.Error())
 			return
 		}
 
 		// Decrypt server side encrypted fields
-		decLogin, err := app.DecryptModel(createdLogin)
+		decLogin, err := app.DecryptModel(createdLogin, schema)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -139,13 +179,23 @@ func UpdateLogin(s storage.Store) http.HandlerFunc {
 
 		// Update login
 		updatedLogin, err := app.UpdateLogin(s, login, &loginDTO, schema)
+		if errors.Is(err, app.ErrRevisionConflict) {
+			// Update is kept as a conflicted copy instead of overwriting the newer revision
+			conflict, err := app.CreateLoginConflict(s, login, &loginDTO, schema)
+			if err != nil {
+				RespondWithError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			RespondWithJSON(w, http.StatusConflict, conflict)
+			return
+		}
 		if err != nil {
-			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			RespondWithError(w, updateErrorStatus(err), err.Error())
 			return
 		}
 
 		// Decrypt server side encrypted fields
-		decLogin, err := app.DecryptModel(updatedLogin)
+		decLogin, err := app.DecryptModel(updatedLogin, schema)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -180,8 +230,11 @@ func BulkUpdateLogins(s storage.Store) http.HandlerFunc {
 
 			// Update login
 			_, err = app.UpdateLogin(s, login, &loginDTO, schema)
+			if errors.Is(err, app.ErrRevisionConflict) {
+				_, err = app.CreateLoginConflict(s, login, &loginDTO, schema)
+			}
 			if err != nil {
-				RespondWithError(w, http.StatusInternalServerError, err.Error())
+				RespondWithError(w, updateErrorStatus(err), err.Error())
 				return
 			}
 		}
@@ -219,6 +272,7 @@ func DeleteLogin(s storage.Store) http.HandlerFunc {
 			RespondWithError(w, http.StatusNotFound, err.Error())
 			return
 		}
+		s.Relations().DeleteByItem(app.ItemTypeLogin, login.ID, schema)
 
 		// Generate response
 		response := model.Response{
@@ -242,3 +296,119 @@ func TestLogin(s storage.Store) http.HandlerFunc {
 		RespondWithJSON(w, http.StatusOK, response)
 	}
 }
+
+// FindDuplicateLogins finds the logins with the same URL and username
+func FindDuplicateLogins(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		schema := r.Context().Value("schema").(string)
+		groups, err := app.FindDuplicateLogins(s, schema)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, groups)
+	}
+}
+
+// MergeLogins merges duplicate logins into the target login
+func MergeLogins(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var mergeDTO model.MergeLoginsDTO
+		if err := json.NewDecoder(r.Body).Decode(&mergeDTO); err != nil {
+			RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := app.PayloadValidator(mergeDTO); err != nil {
+			errs := GetErrors(err.(validator.ValidationErrors))
+			RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+			return
+		}
+
+		schema := r.Context().Value("schema").(string)
+		mergedLogin, err := app.MergeLogins(s, &mergeDTO, schema)
+		if err == app.ErrMergeIntoItself {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		// Decrypt server side encrypted fields
+		decLogin, err := app.DecryptModel(mergedLogin, schema)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, model.ToLoginDTO(decLogin.(*model.Login)))
+	}
+}
+
+// FindLoginHistories finds the previous versions of a login
+func FindLoginHistories(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		schema := r.Context().Value("schema").(string)
+		histories, err := app.FindLoginHistories(s, uint(id), schema)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, histories)
+	}
+}
+
+// BatchLogins creates, updates and deletes logins in a single request
+func BatchLogins(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		runBatch(w, r, batchHandlers{
+			create: func(raw json.RawMessage, schema string) (uint, error) {
+				var loginDTO model.LoginDTO
+				if err := json.Unmarshal(raw, &loginDTO); err != nil {
+					return 0, err
+				}
+				createdLogin, err := app.CreateLogin(s, &loginDTO, schema)
+				if err != nil {
+					return 0, err
+				}
+				return createdLogin.ID, nil
+			},
+			update: func(raw json.RawMessage, schema string) (uint, error) {
+				var loginDTO model.LoginDTO
+				if err := json.Unmarshal(raw, &loginDTO); err != nil {
+					return 0, err
+				}
+				login, err := s.Logins().FindByID(loginDTO.ID, schema)
+				if err != nil {
+					return loginDTO.ID, err
+				}
+				if _, err := app.UpdateLogin(s, login, &loginDTO, schema); err != nil {
+					return login.ID, err
+				}
+				return login.ID, nil
+			},
+			delete: func(id uint, schema string) error {
+				login, err := s.Logins().FindByID(id, schema)
+				if err != nil {
+					return err
+				}
+				if err := s.Logins().Delete(login.ID, schema); err != nil {
+					return err
+				}
+				return s.Relations().DeleteByItem(app.ItemTypeLogin, login.ID, schema)
+			},
+		})
+	}
+}
@@ -44,7 +44,6 @@ func (suite *TestSuiteEnv) TestFindAllLogins() {
 		ID:        1,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
-		DeletedAt: nil,
 		Title:     "Dummy Title",
 		URL:       "http://dummy.com",
 		Username:  "dummyuser",
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// FindMaintenance returns the maintenance mode of the server
+func FindMaintenance(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		maintenance, err := app.FindMaintenance(s)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		RespondWithJSON(w, http.StatusOK, maintenance)
+	}
+}
+
+// UpdateMaintenance switches the maintenance mode of the server
+func UpdateMaintenance(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var maintenanceDTO model.MaintenanceDTO
+		if err := json.NewDecoder(r.Body).Decode(&maintenanceDTO); err != nil {
+			RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+			return
+		}
+		defer r.Body.Close()
+
+		maintenance, err := app.SetMaintenance(s, maintenanceDTO.Enabled)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		RespondWithJSON(w, http.StatusOK, maintenance)
+	}
+}
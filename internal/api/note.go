@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -16,14 +17,21 @@ const (
 	noteDeleteSuccess = "Note deleted successfully!"
 )
 
+// noteSortFields are the fields notes can be sorted by
+var noteSortFields = []string{"id", "created_at", "updated_at", "title"}
+
 // FindAllNotes finds all notes
 func FindAllNotes(s storage.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var err error
-		var noteList []model.Note
+		opts, err := ParseListOptions(r, noteSortFields)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
 		// Get all notes from db
 		schema := r.Context().Value("schema").(string)
-		noteList, err = s.Notes().All(schema)
+		noteList, total, err := s.Notes().FindAll(opts, schema)
 		if err != nil {
 			RespondWithError(w, http.StatusNotFound, err.Error())
 			return
@@ -31,7 +39,7 @@ func FindAllNotes(s storage.Store) http.HandlerFunc {
 
 		// Decrypt server side encrypted fields
 		for i := range noteList {
-			uNote, err := app.DecryptModel(&noteList[i])
+			uNote, err := app.DecryptModel(&noteList[i], schema)
 			if err != nil {
 				RespondWithError(w, http.StatusInternalServerError, err.Error())
 				return
@@ -39,7 +47,7 @@ func FindAllNotes(s storage.Store) http.HandlerFunc {
 			noteList[i] = *uNote.(*model.Note)
 		}
 
-		RespondWithJSON(w, http.StatusOK, noteList)
+		RespondWithJSON(w, http.StatusOK, NewListResponse(noteList, total, opts))
 	}
 }
 
@@ -63,7 +71,7 @@ func FindNoteByID(s storage.Store) http.HandlerFunc {
 		}
 
 		// Decrypt server side encrypted fields
-		uNote, err := app.DecryptModel(note)
+		uNote, err := app.DecryptModel(note, schema)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -72,7 +80,14 @@ func FindNoteByID(s storage.Store) http.HandlerFunc {
 		// Create DTO
 		noteDTO := model.ToNoteDTO(uNote.(*model.Note))
 
-		RespondWithJSON(w, http.StatusOK, noteDTO)
+		// Add related items if requested
+		response, err := expandRelated(s, r, app.ItemTypeNote, note.ID, noteDTO)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, response)
 	}
 }
 
@@ -92,12 +107,12 @@ func CreateNote(s storage.Store) http.HandlerFunc {
 		schema := r.Context().Value("schema").(string)
 		createdNote, err := app.CreateNote(s, &noteDTO, schema)
 		if err != nil {
-			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			RespondWithError(w, updateErrorStatus(err), err.Error())
 			return
 		}
 
 		// Decrypt server side encrypted fields
-		decNote, err := app.DecryptModel(createdNote)
+		decNote, err := app.DecryptModel(createdNote, schema)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -139,13 +154,23 @@ func UpdateNote(s storage.Store) http.HandlerFunc {
 
 		// Update note
 		updatedNote, err := app.UpdateNote(s, note, &noteDTO, schema)
+		if errors.Is(err, app.ErrRevisionConflict) {
+			// Update is kept as a conflicted copy instead of overwriting the newer revision
+			conflict, err := app.CreateNoteConflict(s, note, &noteDTO, schema)
+			if err != nil {
+				RespondWithError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			RespondWithJSON(w, http.StatusConflict, conflict)
+			return
+		}
 		if err != nil {
-			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			RespondWithError(w, updateErrorStatus(err), err.Error())
 			return
 		}
 
 		// Decrypt server side encrypted fields
-		decNote, err := app.DecryptModel(updatedNote)
+		decNote, err := app.DecryptModel(updatedNote, schema)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -180,8 +205,11 @@ func BulkUpdateNotes(s storage.Store) http.HandlerFunc {
 
 			// Update note
 			_, err = app.UpdateNote(s, note, &noteDTO, schema)
+			if errors.Is(err, app.ErrRevisionConflict) {
+				_, err = app.CreateNoteConflict(s, note, &noteDTO, schema)
+			}
 			if err != nil {
-				RespondWithError(w, http.StatusInternalServerError, err.Error())
+				RespondWithError(w, updateErrorStatus(err), err.Error())
 				return
 			}
 		}
@@ -217,6 +245,7 @@ func DeleteNote(s storage.Store) http.HandlerFunc {
 			RespondWithError(w, http.StatusNotFound, err.Error())
 			return
 		}
+		s.Relations().DeleteByItem(app.ItemTypeNote, note.ID, schema)
 
 		response := model.Response{
 			Code:    http.StatusOK,
@@ -226,3 +255,46 @@ func DeleteNote(s storage.Store) http.HandlerFunc {
 		RespondWithJSON(w, http.StatusOK, response)
 	}
 }
+
+// BatchNotes creates, updates and deletes notes in a single request
+func BatchNotes(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		runBatch(w, r, batchHandlers{
+			create: func(raw json.RawMessage, schema string) (uint, error) {
+				var noteDTO model.NoteDTO
+				if err := json.Unmarshal(raw, &noteDTO); err != nil {
+					return 0, err
+				}
+				createdNote, err := app.CreateNote(s, &noteDTO, schema)
+				if err != nil {
+					return 0, err
+				}
+				return createdNote.ID, nil
+			},
+			update: func(raw json.RawMessage, schema string) (uint, error) {
+				var noteDTO model.NoteDTO
+				if err := json.Unmarshal(raw, &noteDTO); err != nil {
+					return 0, err
+				}
+				note, err := s.Notes().FindByID(noteDTO.ID, schema)
+				if err != nil {
+					return noteDTO.ID, err
+				}
+				if _, err := app.UpdateNote(s, note, &noteDTO, schema); err != nil {
+					return note.ID, err
+				}
+				return note.ID, nil
+			},
+			delete: func(id uint, schema string) error {
+				note, err := s.Notes().FindByID(id, schema)
+				if err != nil {
+					return err
+				}
+				if err := s.Notes().Delete(note.ID, schema); err != nil {
+					return err
+				}
+				return s.Relations().DeleteByItem(app.ItemTypeNote, note.ID, schema)
+			},
+		})
+	}
+}
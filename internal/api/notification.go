@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+const (
+	// notificationPollInterval is how often the vault is checked for changes
+	notificationPollInterval = 5 * time.Second
+	// notificationHeartbeatInterval keeps idle streams open through proxies
+	notificationHeartbeatInterval = 15 * time.Second
+	// notificationRetry is the reconnection delay suggested to the clients in milliseconds
+	notificationRetry = 5000
+)
+
+// NotificationStream sends the changes of the vault as Server-Sent Events for the
+// clients which can't use WebSockets. Event IDs are sync revisions, so reconnecting
+// clients get the changes they missed since the Last-Event-ID header or the
+// last_event_id query param of EventSource polyfills. Streams closed by the
+// server write timeout are resumed the same way. An account event tells the
+// session when the email of the account changes.
+func NotificationStream(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			RespondWithError(w, http.StatusInternalServerError, "Streaming is not supported")
+			return
+		}
+
+		lastEventID := r.Header.Get("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = r.URL.Query().Get("last_event_id")
+		}
+		since := time.Now()
+		if lastEventID != "" {
+			var err error
+			if since, err = app.ParseSyncSince(lastEventID); err != nil {
+				RespondWithError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "retry: %d\n\n", notificationRetry)
+		flusher.Flush()
+
+		schema := r.Context().Value("schema").(string)
+		userUUID, _ := r.Context().Value("uuid").(string)
+		email := accountEmail(s, userUUID)
+		poll := time.NewTicker(notificationPollInterval)
+		defer poll.Stop()
+		heartbeat := time.NewTicker(notificationHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		// Changes are checked right away for the resuming clients
+		check := make(chan struct{}, 1)
+		check <- struct{}{}
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-heartbeat.C:
+				io.WriteString(w, ": heartbeat\n\n")
+				flusher.Flush()
+				continue
+			case <-poll.C:
+			case <-check:
+			}
+
+			notification, err := app.VaultChanges(s, since, schema)
+			if err != nil {
+				logger.WithContext(r.Context()).Errorf("Error while checking vault changes of schema %s: %v", schema, err)
+				continue
+			}
+			since = app.RevisionTime(notification.Revision)
+
+			// Sessions are told when the email of the account changes
+			if changed := accountEmail(s, userUUID); changed != "" && changed != email {
+				email = changed
+				data, _ := json.Marshal(map[string]string{"email": email})
+				io.WriteString(w, formatSSEEvent(fmt.Sprint(notification.Revision), "account", data))
+				flusher.Flush()
+			}
+			if len(notification.Changes) == 0 {
+				continue
+			}
+
+			data, err := json.Marshal(notification)
+			if err != nil {
+				logger.WithContext(r.Context()).Errorf("Error while encoding vault changes: %v", err)
+				continue
+			}
+			io.WriteString(w, formatSSEEvent(fmt.Sprint(notification.Revision), "change", data))
+			flusher.Flush()
+		}
+	}
+}
+
+// accountEmail returns the email of the user of the stream, empty when the
+// user can't be found
+func accountEmail(s storage.Store, userUUID string) string {
+	user, err := s.Users().FindCachedByUUID(userUUID)
+	if err != nil {
+		return ""
+	}
+	return user.Email
+}
+
+// formatSSEEvent formats an event of the Server-Sent Events stream. Every line
+// of the data gets its own data field.
+func formatSSEEvent(id, event string, data []byte) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "id: %s\n", id)
+	fmt.Fprintf(&b, "event: %s\n", event)
+	for _, line := range strings.Split(string(data), "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
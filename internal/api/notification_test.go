@@ -0,0 +1,24 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatSSEEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{name: "Single line", data: `{"revision":1}`, expected: "id: 1\nevent: change\ndata: {\"revision\":1}\n\n"},
+		{name: "Multi line", data: "a\nb", expected: "id: 1\nevent: change\ndata: a\ndata: b\n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, formatSSEEvent("1", "change", []byte(tt.data)))
+		})
+	}
+}
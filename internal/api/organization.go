@@ -0,0 +1,348 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/billing"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+const (
+	organizationDeleteSuccess = "Organization deleted successfully!"
+	memberDeleteSuccess       = "Member removed successfully!"
+	notOrganizationMember     = "You are not a member of this organization"
+	notOrganizationManager    = "You are not allowed to manage this organization"
+	notOrganizationEditor     = "You are not allowed to change the vault of this organization"
+	notCollectionMember       = "You are not a member of this collection"
+)
+
+// FindAllOrganizations finds all organizations of the user
+func FindAllOrganizations(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value("user_id").(uint)
+		orgs, err := s.Organizations().FindByUserID(userID)
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		orgDTOs := make([]*model.OrganizationDTO, len(orgs))
+		for i := range orgs {
+			role := ""
+			if member, err := s.Organizations().FindMember(orgs[i].ID, userID); err == nil {
+				role = member.Role
+			}
+			orgDTOs[i] = model.ToOrganizationDTO(&orgs[i], role)
+		}
+
+		RespondWithJSON(w, http.StatusOK, orgDTOs)
+	}
+}
+
+// FindOrganizationByID finds an organization of the user by id
+func FindOrganizationByID(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org, member, ok := findOrganization(s, w, r)
+		if !ok {
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, model.ToOrganizationDTO(org, member.Role))
+	}
+}
+
+// CreateOrganization creates an organization owned by the user
+func CreateOrganization(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var orgDTO model.OrganizationDTO
+		if err := json.NewDecoder(r.Body).Decode(&orgDTO); err != nil {
+			RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := app.PayloadValidator(orgDTO); err != nil {
+			errs := GetErrors(err.(validator.ValidationErrors))
+			RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+			return
+		}
+
+		user, err := s.Users().FindByUUID(r.Context().Value("uuid").(string))
+		if err != nil {
+			RespondWithError(w, http.StatusUnauthorized, invalidUser)
+			return
+		}
+
+		org, err := app.CreateOrganization(s, &orgDTO, user)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, model.ToOrganizationDTO(org, model.OrganizationRoleOwner))
+	}
+}
+
+// UpdateOrganization updates an organization
+func UpdateOrganization(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org, member, ok := findOrganization(s, w, r)
+		if !ok {
+			return
+		}
+
+		if !app.CanManageOrganization(member.Role) {
+			RespondWithError(w, http.StatusForbidden, notOrganizationManager)
+			return
+		}
+
+		var orgDTO model.OrganizationDTO
+		if err := json.NewDecoder(r.Body).Decode(&orgDTO); err != nil {
+			RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := app.PayloadValidator(orgDTO); err != nil {
+			errs := GetErrors(err.(validator.ValidationErrors))
+			RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+			return
+		}
+
+		org.Name = orgDTO.Name
+		updatedOrg, err := s.Organizations().Update(org)
+		if err != nil {
+			RespondWithError(w, updateErrorStatus(err), err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, model.ToOrganizationDTO(updatedOrg, member.Role))
+	}
+}
+
+// DeleteOrganization deletes an organization and its vault
+func DeleteOrganization(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org, member, ok := findOrganization(s, w, r)
+		if !ok {
+			return
+		}
+
+		// Only the owner can delete the organization
+		if member.Role != model.OrganizationRoleOwner {
+			RespondWithError(w, http.StatusForbidden, notOrganizationManager)
+			return
+		}
+
+		if err := app.DeleteOrganization(s, org); err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		response := model.Response{
+			Code:    http.StatusOK,
+			Status:  Success,
+			Message: organizationDeleteSuccess,
+		}
+		RespondWithJSON(w, http.StatusOK, response)
+	}
+}
+
+// FindAllRegions lists the data residency regions organizations can be pinned to
+func FindAllRegions(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		RespondWithJSON(w, http.StatusOK, s.RegionNames())
+	}
+}
+
+// FindOrganizationMembers finds all members and invitations of an organization
+func FindOrganizationMembers(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org, _, ok := findOrganization(s, w, r)
+		if !ok {
+			return
+		}
+
+		members, err := s.Organizations().Members(org.ID)
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, members)
+	}
+}
+
+// InviteMember invites a user to an organization
+func InviteMember(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org, member, ok := findOrganization(s, w, r)
+		if !ok {
+			return
+		}
+
+		if !app.CanManageOrganization(member.Role) {
+			RespondWithError(w, http.StatusForbidden, notOrganizationManager)
+			return
+		}
+
+		var invitationDTO model.InvitationDTO
+		if err := json.NewDecoder(r.Body).Decode(&invitationDTO); err != nil {
+			RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := app.PayloadValidator(invitationDTO); err != nil {
+			errs := GetErrors(err.(validator.ValidationErrors))
+			RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+			return
+		}
+
+		invited, err := app.InviteMember(s, org, &invitationDTO)
+		if errors.Is(err, app.ErrNoSeats) {
+			RespondWithError(w, http.StatusPaymentRequired, err.Error())
+			return
+		}
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, invited)
+	}
+}
+
+// AcceptInvitation accepts the invitation of the user to an organization
+func AcceptInvitation(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		userID := r.Context().Value("user_id").(uint)
+		member, err := app.AcceptInvitation(s, uint(id), userID)
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, member)
+	}
+}
+
+// DeleteMember removes a member from an organization. Members can remove themselves.
+func DeleteMember(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org, member, ok := findOrganization(s, w, r)
+		if !ok {
+			return
+		}
+
+		userID, err := strconv.Atoi(mux.Vars(r)["user_id"])
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if uint(userID) != member.UserID && !app.CanManageOrganization(member.Role) {
+			RespondWithError(w, http.StatusForbidden, notOrganizationManager)
+			return
+		}
+
+		// Owner can't be removed from the organization
+		if uint(userID) == org.OwnerID {
+			RespondWithError(w, http.StatusBadRequest, "Organization owner can't be removed")
+			return
+		}
+
+		if err := s.Organizations().DeleteMember(org.ID, uint(userID)); err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		response := model.Response{
+			Code:    http.StatusOK,
+			Status:  Success,
+			Message: memberDeleteSuccess,
+		}
+		RespondWithJSON(w, http.StatusOK, response)
+	}
+}
+
+// UpdateOrganizationSeats changes the seats of the team subscription of an
+// organization at the billing provider
+func UpdateOrganizationSeats(s storage.Store, provider billing.Provider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org, member, ok := findOrganization(s, w, r)
+		if !ok {
+			return
+		}
+
+		// Only the owner pays for the seats
+		if member.Role != model.OrganizationRoleOwner {
+			RespondWithError(w, http.StatusForbidden, notOrganizationManager)
+			return
+		}
+
+		var seatsDTO model.SeatsDTO
+		if err := json.NewDecoder(r.Body).Decode(&seatsDTO); err != nil {
+			RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := app.PayloadValidator(seatsDTO); err != nil {
+			errs := GetErrors(err.(validator.ValidationErrors))
+			RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+			return
+		}
+
+		updatedOrg, err := app.UpdateSeats(s, provider, org, seatsDTO.Seats)
+		if errors.Is(err, app.ErrNoTeamSubscription) || errors.Is(err, app.ErrTooFewSeats) {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			RespondWithError(w, http.StatusBadGateway, "Couldn't update seats at the billing provider")
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, model.ToOrganizationDTO(updatedOrg, member.Role))
+	}
+}
+
+// findOrganization finds the organization defined by id in route and the membership
+// of the user. It responds with an error and returns false if the user is not an accepted member.
+func findOrganization(s storage.Store, w http.ResponseWriter, r *http.Request) (*model.Organization, *model.OrganizationMember, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return nil, nil, false
+	}
+
+	userID := r.Context().Value("user_id").(uint)
+	member, err := s.Organizations().FindMember(uint(id), userID)
+	if err != nil || member.Status != model.MemberStatusAccepted {
+		RespondWithError(w, http.StatusForbidden, notOrganizationMember)
+		return nil, nil, false
+	}
+
+	org, err := s.Organizations().FindByID(uint(id))
+	if err != nil {
+		RespondWithError(w, http.StatusNotFound, err.Error())
+		return nil, nil, false
+	}
+
+	return org, member, true
+}
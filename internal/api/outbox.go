@@ -0,0 +1,93 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// outboxSortFields are the fields outbox emails can be sorted by
+var outboxSortFields = []string{"id", "created_at", "updated_at", "status", "attempts"}
+
+// FindOutboxEmails lists the emails waiting in the outbox, the status query
+// param filters the pending or the dead ones
+func FindOutboxEmails(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		opts, err := ParseListOptions(r, outboxSortFields)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if r.FormValue("sort") == "" {
+			opts.Sort = "created_at"
+		}
+		if opts.Limit == 0 {
+			opts.Limit = defaultPageLimit
+		}
+		if v := r.FormValue("status"); v != "" {
+			opts.Filters["status"] = v
+		}
+
+		emails, total, err := s.Outbox().FindAll(opts)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, NewListResponse(emails, total, opts))
+	}
+}
+
+// RetryOutboxEmail queues a dead email again
+func RetryOutboxEmail(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		email, err := app.RetryEmail(s, uint(id))
+		if err == app.ErrOutboxEmailNotFound {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, email)
+	}
+}
+
+// DeleteOutboxEmail removes an email from the outbox without sending it
+func DeleteOutboxEmail(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if _, err := s.Outbox().FindByID(uint(id)); err != nil {
+			RespondWithError(w, http.StatusNotFound, app.ErrOutboxEmailNotFound.Error())
+			return
+		}
+		if err := s.Outbox().Delete(uint(id)); err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		response := model.Response{
+			Code:    http.StatusOK,
+			Status:  Success,
+			Message: "Email deleted successfully!",
+		}
+		RespondWithJSON(w, http.StatusOK, response)
+	}
+}
@@ -1,8 +1,11 @@
 package api
 
 import (
+	"encoding/json"
+	"io"
 	"net/http"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/passwall/passwall-server/internal/app"
 	"github.com/passwall/passwall-server/model"
 	"github.com/spf13/viper"
@@ -46,3 +49,58 @@ func GeneratePassword(w http.ResponseWriter, r *http.Request) {
 	}
 	RespondWithJSON(w, http.StatusOK, response)
 }
+
+// GeneratePassphrase generates new diceware passphrase
+func GeneratePassphrase(w http.ResponseWriter, r *http.Request) {
+	var passphraseDTO model.PassphraseDTO
+
+	// Empty body means default options
+	if err := json.NewDecoder(r.Body).Decode(&passphraseDTO); err != nil && err != io.EOF {
+		RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := app.PayloadValidator(passphraseDTO); err != nil {
+		errs := GetErrors(err.(validator.ValidationErrors))
+		RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+		return
+	}
+
+	passphrase, err := app.GeneratePassphrase(&passphraseDTO)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := model.Response{
+		Code:    http.StatusOK,
+		Status:  Success,
+		Message: passphrase,
+	}
+	RespondWithJSON(w, http.StatusOK, response)
+}
+
+// CheckBreachedPassword checks the password against known breaches
+func CheckBreachedPassword(w http.ResponseWriter, r *http.Request) {
+	var breachCheckDTO model.BreachCheckDTO
+	if err := json.NewDecoder(r.Body).Decode(&breachCheckDTO); err != nil {
+		RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := app.PayloadValidator(breachCheckDTO); err != nil {
+		errs := GetErrors(err.(validator.ValidationErrors))
+		RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+		return
+	}
+
+	result, err := app.CheckBreachedPassword(&breachCheckDTO)
+	if err != nil {
+		RespondWithError(w, http.StatusBadGateway, "Couldn't check breached password")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, result)
+}
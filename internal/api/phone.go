@@ -0,0 +1,129 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/cache"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+var (
+	phoneCodeSuccess = "Code sent to the phone successfully"
+	phoneRemoved     = "Phone removed successfully"
+)
+
+// CreatePhoneCode sends a code to the phone number the user registers
+func CreatePhoneCode(s storage.Store, c cache.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value("user_id").(uint)
+
+		var dto model.PhoneDTO
+		if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+			RespondWithError(w, http.StatusUnprocessableEntity, InvalidJSON)
+			return
+		}
+		defer r.Body.Close()
+
+		user, err := s.Users().FindByID(userID)
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		code := generateCode()
+		if err := c.Set(phoneVerificationKey(user.ID), dto.Phone+":"+code, codeExpiration); err != nil {
+			logger.WithContext(r.Context()).Errorf("can't save phone code of user %d error: %v", user.ID, err)
+			RespondWithError(w, http.StatusInternalServerError, "Server error!")
+			return
+		}
+
+		locale := user.Locale
+		if locale == "" {
+			locale = Locale(w)
+		}
+		if err := app.SendSMSCode(locale, dto.Phone, code); err != nil {
+			respondSMSError(w, r, err)
+			return
+		}
+
+		response := model.Response{
+			Code:    http.StatusOK,
+			Status:  Success,
+			Message: phoneCodeSuccess,
+		}
+		RespondWithJSON(w, http.StatusOK, response)
+	}
+}
+
+// VerifyPhone saves the phone number of the user when the code matches
+func VerifyPhone(s storage.Store, c cache.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value("user_id").(uint)
+
+		var dto model.PhoneCodeDTO
+		if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+			RespondWithError(w, http.StatusUnprocessableEntity, InvalidJSON)
+			return
+		}
+		defer r.Body.Close()
+
+		value, found, err := c.Get(phoneVerificationKey(userID))
+		if err != nil {
+			logger.WithContext(r.Context()).Errorf("can't get phone code of user %d error: %v", userID, err)
+			RespondWithError(w, http.StatusInternalServerError, "Server error!")
+			return
+		}
+		if !found {
+			RespondWithError(w, http.StatusBadRequest, "Code couldn't found!")
+			return
+		}
+		phone, code, _ := strings.Cut(value, ":")
+		if dto.Code == "" || dto.Code != code {
+			RespondWithError(w, http.StatusBadRequest, "Code doesn't match!")
+			return
+		}
+
+		user, err := s.Users().FindByID(userID)
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		user, err = app.SetUserPhone(s, user, phone)
+		if err != nil {
+			RespondWithError(w, updateErrorStatus(err), err.Error())
+			return
+		}
+		c.Delete(phoneVerificationKey(userID))
+
+		RespondWithJSON(w, http.StatusOK, model.ToUserDTO(user))
+	}
+}
+
+// DeletePhone removes the phone number of the user, the codes are sent by email again
+func DeletePhone(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value("user_id").(uint)
+
+		user, err := s.Users().FindByID(userID)
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if _, err := app.SetUserPhone(s, user, ""); err != nil {
+			RespondWithError(w, updateErrorStatus(err), err.Error())
+			return
+		}
+
+		response := model.Response{
+			Code:    http.StatusOK,
+			Status:  Success,
+			Message: phoneRemoved,
+		}
+		RespondWithJSON(w, http.StatusOK, response)
+	}
+}
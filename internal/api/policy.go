@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+// FindSecurityPolicy returns the security policy of the instance
+func FindSecurityPolicy(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		policy, err := app.FindSecurityPolicy(s)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		RespondWithJSON(w, http.StatusOK, policy)
+	}
+}
+
+// UpdateSecurityPolicy replaces the security policy of the instance
+func UpdateSecurityPolicy(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var policy model.SecurityPolicy
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := app.PayloadValidator(policy); err != nil {
+			errs := GetErrors(err.(validator.ValidationErrors))
+			RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+			return
+		}
+		// Sliding sessions still end at the max lifetime
+		if policy.SessionIdleTimeout > 0 && policy.SessionMaxLifetime == 0 {
+			RespondWithError(w, http.StatusBadRequest, app.ErrSessionLifetimeRequired.Error())
+			return
+		}
+
+		if err := app.UpdateSecurityPolicy(s, policy); err != nil {
+			logger.WithContext(r.Context()).Errorf("can't update security policy error: %v", err)
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		RespondWithJSON(w, http.StatusOK, policy)
+	}
+}
+
+// checkMasterPasswordPolicy responds with the reason when the master password
+// doesn't meet the security policy, it returns false then
+func checkMasterPasswordPolicy(s storage.Store, w http.ResponseWriter, r *http.Request, masterPassword string) bool {
+	policy, err := app.FindSecurityPolicy(s)
+	if err != nil {
+		logger.WithContext(r.Context()).Errorf("can't find security policy error: %v", err)
+		RespondWithError(w, http.StatusInternalServerError, "Server error!")
+		return false
+	}
+	if err := app.CheckMasterPasswordPolicy(policy, masterPassword); err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+const (
+	relationDeleteSuccess = "Relation deleted successfully!"
+)
+
+// FindRelatedItems finds the items linked to the item given in query params
+func FindRelatedItems(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		itemID, err := strconv.Atoi(r.FormValue("item_id"))
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, "Invalid item_id value")
+			return
+		}
+
+		schema := r.Context().Value("schema").(string)
+		related, err := app.FindRelatedItems(s, r.FormValue("item_type"), uint(itemID), schema)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, related)
+	}
+}
+
+// CreateRelation links two items
+func CreateRelation(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var relationDTO model.ItemRelationDTO
+		if err := json.NewDecoder(r.Body).Decode(&relationDTO); err != nil {
+			RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := app.PayloadValidator(relationDTO); err != nil {
+			errs := GetErrors(err.(validator.ValidationErrors))
+			RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+			return
+		}
+
+		schema := r.Context().Value("schema").(string)
+		relation, err := app.CreateRelation(s, &relationDTO, schema)
+		if err == app.ErrSelfRelation {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, relation)
+	}
+}
+
+// DeleteRelation removes a link between two items
+func DeleteRelation(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		schema := r.Context().Value("schema").(string)
+		relation, err := s.Relations().FindByID(uint(id), schema)
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		if err := s.Relations().Delete(relation.ID, schema); err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		response := model.Response{
+			Code:    http.StatusOK,
+			Status:  Success,
+			Message: relationDeleteSuccess,
+		}
+		RespondWithJSON(w, http.StatusOK, response)
+	}
+}
+
+// expandRelated adds the related items to the item DTO when the request has
+// expand=related query param, otherwise the DTO is returned as it is
+func expandRelated(s storage.Store, r *http.Request, itemType string, itemID uint, dto interface{}) (interface{}, error) {
+	if r.FormValue("expand") != "related" {
+		return dto, nil
+	}
+
+	schema := r.Context().Value("schema").(string)
+	related, err := app.FindRelatedItems(s, itemType, itemID, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(dto)
+	if err != nil {
+		return nil, err
+	}
+	expanded := map[string]interface{}{}
+	if err := json.Unmarshal(data, &expanded); err != nil {
+		return nil, err
+	}
+	expanded["related"] = related
+
+	return expanded, nil
+}
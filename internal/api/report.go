@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/spf13/viper"
+)
+
+// FindReusedPasswords reports the logins sharing the same password
+func FindReusedPasswords(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		schema := r.Context().Value("schema").(string)
+		groups, err := app.FindReusedPasswords(s, schema)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, groups)
+	}
+}
+
+// FindReusedPasswordsByHashes reports the logins sharing the same password
+// regarding to the hashes computed by the client
+func FindReusedPasswordsByHashes(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var hashesDTO model.PasswordHashesDTO
+		if err := json.NewDecoder(r.Body).Decode(&hashesDTO); err != nil {
+			RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := app.PayloadValidator(hashesDTO); err != nil {
+			errs := GetErrors(err.(validator.ValidationErrors))
+			RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+			return
+		}
+
+		schema := r.Context().Value("schema").(string)
+		groups, err := app.FindReusedPasswordsByHashes(s, &hashesDTO, schema)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, groups)
+	}
+}
+
+// FindPasswordReport reports the logins with weak or old passwords
+func FindPasswordReport(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		maxAge := viper.GetInt("server.passwordMaxAge")
+		if v := r.FormValue("max_age"); v != "" {
+			days, err := strconv.Atoi(v)
+			if err != nil || days < 0 {
+				RespondWithError(w, http.StatusBadRequest, "Invalid max_age value")
+				return
+			}
+			maxAge = days
+		}
+
+		schema := r.Context().Value("schema").(string)
+		report, err := app.FindPasswordReport(s, schema, maxAge)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, report)
+	}
+}
@@ -26,17 +26,17 @@ type fieldError struct {
 
 // RespondWithError ...
 func RespondWithError(w http.ResponseWriter, code int, message string) {
-	RespondWithJSON(w, code, ErrorResponseDTO{Code: code, Status: "Error", Message: message})
+	RespondWithJSON(w, code, ErrorResponseDTO{Code: code, Status: "Error", Message: localize(w, message)})
 }
 
 // RespondWithErrors ...
 func RespondWithErrors(w http.ResponseWriter, code int, message string, errors []string) {
-	RespondWithJSON(w, code, ErrorResponseDTO{Code: code, Status: "Error", Message: message, Errors: errors})
+	RespondWithJSON(w, code, ErrorResponseDTO{Code: code, Status: "Error", Message: localize(w, message), Errors: errors})
 }
 
 // RespondWithJSON write json
 func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
-	response, _ := json.Marshal(payload)
+	response, _ := json.Marshal(localizeResponse(w, payload))
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
 	w.Write(response)
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// CreateScopedToken creates an access token limited to the scopes of the
+// payload, like a read-only token of a monitoring script
+func CreateScopedToken(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var tokenDTO model.ScopedTokenDTO
+		if err := json.NewDecoder(r.Body).Decode(&tokenDTO); err != nil {
+			RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := app.PayloadValidator(tokenDTO); err != nil {
+			errs := GetErrors(err.(validator.ValidationErrors))
+			RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+			return
+		}
+
+		userID := r.Context().Value("user_id").(uint)
+		user, err := s.Users().FindByID(userID)
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		scopes, _ := r.Context().Value("scopes").([]string)
+		td, err := app.CreateScopedToken(s, user, scopes, tokenDTO, time.Now())
+		if errors.Is(err, app.ErrScopeNotGranted) {
+			RespondWithError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RecordAuditEvent(s, r, NewAuditEvent(r, app.AuditActionCreateToken, "tokens", user.ID))
+		RespondWithJSON(w, http.StatusCreated, model.ScopedTokenResponse{
+			AccessToken: td.AccessToken,
+			Scopes:      tokenDTO.Scopes,
+			ExpiresAt:   td.AtExpiresTime,
+		})
+	}
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -17,15 +18,21 @@ const (
 	ServerDeleteSuccess = "Server deleted successfully!"
 )
 
+// serverSortFields are the fields servers can be sorted by
+var serverSortFields = []string{"id", "created_at", "updated_at", "title", "url"}
+
 // FindAllServers ...
 func FindAllServers(s storage.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var err error
-		var serverList []model.Server
+		opts, err := ParseListOptions(r, serverSortFields)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 
 		// Get all servers from db
 		schema := r.Context().Value("schema").(string)
-		serverList, err = s.Servers().All(schema)
+		serverList, total, err := s.Servers().FindAll(opts, schema)
 		if err != nil {
 			RespondWithError(w, http.StatusNotFound, err.Error())
 			return
@@ -33,7 +40,7 @@ func FindAllServers(s storage.Store) http.HandlerFunc {
 
 		// Decrypt server side encrypted fields
 		for i := range serverList {
-			decServer, err := app.DecryptModel(&serverList[i])
+			decServer, err := app.DecryptModel(&serverList[i], schema)
 			if err != nil {
 				RespondWithError(w, http.StatusInternalServerError, err.Error())
 				return
@@ -41,7 +48,7 @@ func FindAllServers(s storage.Store) http.HandlerFunc {
 			serverList[i] = *decServer.(*model.Server)
 		}
 
-		RespondWithJSON(w, http.StatusOK, serverList)
+		RespondWithJSON(w, http.StatusOK, NewListResponse(serverList, total, opts))
 	}
 }
 
@@ -65,7 +72,7 @@ func FindServerByID(s storage.Store) http.HandlerFunc {
 		}
 
 		// Decrypt server side encrypted fields
-		decServer, err := app.DecryptModel(server)
+		decServer, err := app.DecryptModel(server, schema)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -73,7 +80,14 @@ func FindServerByID(s storage.Store) http.HandlerFunc {
 
 		serverDTO := model.ToServerDTO(decServer.(*model.Server))
 
-		RespondWithJSON(w, http.StatusOK, serverDTO)
+		// Add related items if requested
+		response, err := expandRelated(s, r, app.ItemTypeServer, server.ID, serverDTO)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, response)
 	}
 }
 
@@ -93,11 +107,11 @@ func CreateServer(s storage.Store) http.HandlerFunc {
 		schema := r.Context().Value("schema").(string)
 		createdServer, err := app.CreateServer(s, &serverDTO, schema)
 		if err != nil {
-			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			RespondWithError(w, updateErrorStatus(err), err.Error())
 			return
 		}
 		// Decrypt server side encrypted fields
-		decServer, err := app.DecryptModel(createdServer)
+		decServer, err := app.DecryptModel(createdServer, schema)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -139,13 +153,23 @@ func UpdateServer(s storage.Store) http.HandlerFunc {
 
 		// Update server
 		updatedServer, err := app.UpdateServer(s, server, &serverDTO, schema)
+		if errors.Is(err, app.ErrRevisionConflict) {
+			// Update is kept as a conflicted copy instead of overwriting the newer revision
+			conflict, err := app.CreateServerConflict(s, server, &serverDTO, schema)
+			if err != nil {
+				RespondWithError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			RespondWithJSON(w, http.StatusConflict, conflict)
+			return
+		}
 		if err != nil {
-			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			RespondWithError(w, updateErrorStatus(err), err.Error())
 			return
 		}
 
 		// Decrypt server side encrypted fields
-		decServer, err := app.DecryptModel(updatedServer)
+		decServer, err := app.DecryptModel(updatedServer, schema)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -180,8 +204,11 @@ func BulkUpdateServers(s storage.Store) http.HandlerFunc {
 
 			// Update server
 			_, err = app.UpdateServer(s, server, &serverDTO, schema)
+			if errors.Is(err, app.ErrRevisionConflict) {
+				_, err = app.CreateServerConflict(s, server, &serverDTO, schema)
+			}
 			if err != nil {
-				RespondWithError(w, http.StatusInternalServerError, err.Error())
+				RespondWithError(w, updateErrorStatus(err), err.Error())
 				return
 			}
 		}
@@ -217,6 +244,7 @@ func DeleteServer(s storage.Store) http.HandlerFunc {
 			RespondWithError(w, http.StatusNotFound, err.Error())
 			return
 		}
+		s.Relations().DeleteByItem(app.ItemTypeServer, server.ID, schema)
 
 		response := model.Response{
 			Code:    http.StatusOK,
@@ -226,3 +254,46 @@ func DeleteServer(s storage.Store) http.HandlerFunc {
 		RespondWithJSON(w, http.StatusOK, response)
 	}
 }
+
+// BatchServers creates, updates and deletes servers in a single request
+func BatchServers(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		runBatch(w, r, batchHandlers{
+			create: func(raw json.RawMessage, schema string) (uint, error) {
+				var serverDTO model.ServerDTO
+				if err := json.Unmarshal(raw, &serverDTO); err != nil {
+					return 0, err
+				}
+				createdServer, err := app.CreateServer(s, &serverDTO, schema)
+				if err != nil {
+					return 0, err
+				}
+				return createdServer.ID, nil
+			},
+			update: func(raw json.RawMessage, schema string) (uint, error) {
+				var serverDTO model.ServerDTO
+				if err := json.Unmarshal(raw, &serverDTO); err != nil {
+					return 0, err
+				}
+				server, err := s.Servers().FindByID(serverDTO.ID, schema)
+				if err != nil {
+					return serverDTO.ID, err
+				}
+				if _, err := app.UpdateServer(s, server, &serverDTO, schema); err != nil {
+					return server.ID, err
+				}
+				return server.ID, nil
+			},
+			delete: func(id uint, schema string) error {
+				server, err := s.Servers().FindByID(id, schema)
+				if err != nil {
+					return err
+				}
+				if err := s.Servers().Delete(server.ID, schema); err != nil {
+					return err
+				}
+				return s.Relations().DeleteByItem(app.ItemTypeServer, server.ID, schema)
+			},
+		})
+	}
+}
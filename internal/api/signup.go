@@ -2,26 +2,26 @@ package api
 
 import (
 	"encoding/json"
-	"math/rand"
+	"fmt"
 	"net/http"
-	"strconv"
-	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
 	"github.com/passwall/passwall-server/internal/app"
 	"github.com/passwall/passwall-server/internal/storage"
 	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/cache"
 	"github.com/passwall/passwall-server/pkg/logger"
-	"github.com/patrickmn/go-cache"
 )
 
 var (
-	verifySuccess = "Email verified successfully"
+	verifySuccess     = "Email verified successfully"
+	deletionScheduled = "User deletion scheduled successfully!"
+	deletionCancelled = "User deletion cancelled successfully!"
 )
 
 // Signup ...
-func Signup(s storage.Store) http.HandlerFunc {
+func Signup(s storage.Store, c cache.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// 1. Decode request body to userDTO object
 		userSignup := new(model.UserSignup)
@@ -33,8 +33,8 @@ func Signup(s storage.Store) http.HandlerFunc {
 		defer r.Body.Close()
 
 		// 2. Check if email is verified
-		if err := isMailVerified(userSignup.Email); err != nil {
-			logger.Errorf("email %s is not verified error %v\n", userSignup.Email, err)
+		if err := isMailVerified(c, userSignup.Email); err != nil {
+			logger.WithContext(r.Context()).Errorf("email %s is not verified error %v", userSignup.Email, err)
 			RespondWithError(w, http.StatusUnauthorized, "Email is not verified")
 			return
 		}
@@ -47,6 +47,11 @@ func Signup(s storage.Store) http.HandlerFunc {
 			return
 		}
 
+		// 3. Check the master password meets the security policy
+		if !checkMasterPasswordPolicy(s, w, r, userSignup.MasterPassword) {
+			return
+		}
+
 		// 4. Check if user exist in database
 		userDTO := model.ConvertUserDTO(userSignup)
 		_, err = s.Users().FindByEmail(userDTO.Email)
@@ -55,15 +60,39 @@ func Signup(s storage.Store) http.HandlerFunc {
 			return
 		}
 
-		// 5. Create new user
+		// 5. Create new user, its language is the one of the signup when it isn't chosen
+		if userDTO.Locale == "" {
+			userDTO.Locale = Locale(w)
+		}
 		createdUser, err := app.CreateUser(s, userDTO)
 		if err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 
-		// 6. Send email to admin about new user subscription
-		notifyAdminEmail(createdUser)
+		// 6. Save the phone the signup code was sent to as verified
+		if phone, found, _ := c.Get(signupPhoneKey(createdUser.Email)); found && phone != "" {
+			if updated, err := app.SetUserPhone(s, createdUser, phone); err == nil {
+				createdUser = updated
+			} else {
+				logger.WithContext(r.Context()).Errorf("can't save phone of user %d error: %v", createdUser.ID, err)
+			}
+		}
+
+		// New users get the pro features for the trial days of the configuration
+		if user, err := app.StartTrial(s, createdUser); err == nil {
+			createdUser = user
+		} else {
+			logger.WithContext(r.Context()).Errorf("can't start trial of user %d error: %v", createdUser.ID, err)
+		}
+
+		// 7. Send email to admin about new user subscription
+		notifyAdminEmail(s, createdUser)
+		recordUserEvent(s, r, app.AuditActionSignup, createdUser)
+		app.DispatchWebhookEvent(s, app.WebhookEventUserSignedUp, createdUser.ID, map[string]string{
+			"name":  createdUser.Name,
+			"email": createdUser.Email,
+		})
 
 		// Return success message
 		response := model.Response{
@@ -76,7 +105,7 @@ func Signup(s storage.Store) http.HandlerFunc {
 }
 
 // Create email verification code
-func CreateCode(s storage.Store) http.HandlerFunc {
+func CreateCode(s storage.Store, c cache.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// 1. Decode json to email
 		var signup model.AuthEmail
@@ -85,30 +114,47 @@ func CreateCode(s storage.Store) http.HandlerFunc {
 			return
 		}
 
+		if err := checkChannel(signup.Channel); err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
 		// 2. Check if user exist in database
 		_, err := s.Users().FindByEmail(signup.Email)
 		if err == nil {
-			logger.Errorf("email %s already exist in database\n", signup.Email)
+			logger.WithContext(r.Context()).Errorf("email %s already exist in database", signup.Email)
 			RespondWithError(w, http.StatusBadRequest, "User couldn't created!")
 			return
 		}
 
 		// 2. Generate a random code
-		rand.Seed(time.Now().Unix())
-		min := 100000
-		max := 999999
-		code := strconv.Itoa(rand.Intn(max-min+1) + min)
+		code := generateCode()
 
-		logger.Infof("verification code %s generated for email %s\n", code, signup.Email)
+		logger.WithContext(r.Context()).Debugf("verification code generated")
 
 		// 3. Save code in cache
-		c.Set(signup.Email, code, cache.DefaultExpiration)
+		if err := c.Set(verificationKey(signup.Email), code, codeExpiration); err != nil {
+			logger.WithContext(r.Context()).Errorf("can't save code of email %s error: %v", signup.Email, err)
+			RespondWithError(w, http.StatusInternalServerError, "Server error!")
+			return
+		}
+
+		// 4. Send the code by SMS when it is asked, the phone is verified with the email
+		if signup.Channel == app.ChannelSMS {
+			if err := app.SendSMSCode(Locale(w), signup.Phone, code); err != nil {
+				respondSMSError(w, r, err)
+				return
+			}
+			if err := c.Set(signupPhoneKey(signup.Email), signup.Phone, codeExpiration); err != nil {
+				logger.WithContext(r.Context()).Errorf("can't save phone of email %s error: %v", signup.Email, err)
+			}
+			RespondWithJSON(w, http.StatusOK, model.Response{Code: http.StatusOK, Status: Success, Message: codeSuccess})
+			return
+		}
 
-		// 4. Send verification email to user
-		subject := "Passwall Email Verification"
-		body := "Passwall verification code: " + code
-		if err = app.SendMail("Passwall Verification Code", signup.Email, subject, body); err != nil {
-			logger.Errorf("can't send email to %s error: %v\n", signup.Email, err)
+		// 5. Send verification email to user
+		if err = app.SendMail(s, Locale(w), "Passwall Verification Code", signup.Email, app.EmailVerification, map[string]interface{}{"Code": code}); err != nil {
+			logger.WithContext(r.Context()).Errorf("can't send email to %s error: %v", signup.Email, err)
 			RespondWithError(w, http.StatusBadRequest, "Couldn't send email")
 			return
 		}
@@ -124,7 +170,7 @@ func CreateCode(s storage.Store) http.HandlerFunc {
 }
 
 // Create user deletion code
-func CreateDeleteCode(s storage.Store) http.HandlerFunc {
+func CreateDeleteCode(s storage.Store, c cache.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// 1. Decode json to email
 		var signup model.AuthEmail
@@ -133,30 +179,50 @@ func CreateDeleteCode(s storage.Store) http.HandlerFunc {
 			return
 		}
 
+		if err := checkChannel(signup.Channel); err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
 		// 2. Check if user exist in database
-		_, err := s.Users().FindByEmail(signup.Email)
+		user, err := s.Users().FindByEmail(signup.Email)
 		if err != nil {
-			logger.Errorf("email %s does not exist in database error %v\n", signup.Email, err)
+			logger.WithContext(r.Context()).Errorf("email %s does not exist in database error %v", signup.Email, err)
 			RespondWithError(w, http.StatusBadRequest, "User couldn't be found!")
 			return
 		}
+		if signup.Channel == app.ChannelSMS && user.PhoneVerifiedAt == nil {
+			RespondWithError(w, http.StatusBadRequest, app.ErrPhoneNotVerified.Error())
+			return
+		}
 
 		// 2. Generate a random code
-		rand.Seed(time.Now().Unix())
-		min := 100000
-		max := 999999
-		code := strconv.Itoa(rand.Intn(max-min+1) + min)
+		code := generateCode()
 
-		logger.Infof("deletion code %s generated for email %s\n", code, signup.Email)
+		logger.WithContext(r.Context()).Debugf("deletion code generated for user %d", user.ID)
 
 		// 3. Save code in cache
-		c.Set(signup.Email, code, cache.DefaultExpiration)
+		if err := c.Set(verificationKey(signup.Email), code, codeExpiration); err != nil {
+			logger.WithContext(r.Context()).Errorf("can't save code of email %s error: %v", signup.Email, err)
+			RespondWithError(w, http.StatusInternalServerError, "Server error!")
+			return
+		}
 
-		// 4. Send verification email to user
-		subject := "PassWall User Deletion Verification"
-		body := "PassWall user deletion code: " + code + "<br><br>If you didn't request this code to delete your PassWall account, you can safely ignore it."
-		if err = app.SendMail("PassWall user deletion Code", signup.Email, subject, body); err != nil {
-			logger.Errorf("can't send email to %s error: %v\n", signup.Email, err)
+		// 4. Send the code to the verified phone or the email of the user
+		locale := user.Locale
+		if locale == "" {
+			locale = Locale(w)
+		}
+		if signup.Channel == app.ChannelSMS {
+			if err := app.SendSMSCode(locale, user.Phone, code); err != nil {
+				respondSMSError(w, r, err)
+				return
+			}
+			RespondWithJSON(w, http.StatusOK, model.Response{Code: http.StatusOK, Status: Success, Message: codeSuccess})
+			return
+		}
+		if err = app.SendMail(s, locale, "PassWall user deletion Code", signup.Email, app.EmailDeletionCode, map[string]interface{}{"Code": code}); err != nil {
+			logger.WithContext(r.Context()).Errorf("can't send email to %s error: %v", signup.Email, err)
 			RespondWithError(w, http.StatusBadRequest, "Couldn't send email")
 			return
 		}
@@ -172,20 +238,19 @@ func CreateDeleteCode(s storage.Store) http.HandlerFunc {
 }
 
 // Verify Email
-func VerifyCode() http.HandlerFunc {
+func VerifyCode(c cache.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userCode := mux.Vars(r)["code"]
 		email := r.FormValue("email")
 
-		code, ok := c.Get(email)
-		if !ok {
-			RespondWithError(w, http.StatusBadRequest, "Code couldn't found!")
+		confirmationCode, ok, err := c.Get(verificationKey(email))
+		if err != nil {
+			logger.WithContext(r.Context()).Errorf("can't get code of email %s error: %v", email, err)
+			RespondWithError(w, http.StatusInternalServerError, "Server error!")
 			return
 		}
-
-		confirmationCode, ok := code.(string)
 		if !ok {
-			RespondWithError(w, http.StatusInternalServerError, "Server error!")
+			RespondWithError(w, http.StatusBadRequest, "Code couldn't found!")
 			return
 		}
 
@@ -194,7 +259,11 @@ func VerifyCode() http.HandlerFunc {
 			return
 		}
 
-		c.Set(email, "verified", cache.DefaultExpiration)
+		if err := c.Set(verificationKey(email), "verified", codeExpiration); err != nil {
+			logger.WithContext(r.Context()).Errorf("can't save verification of email %s error: %v", email, err)
+			RespondWithError(w, http.StatusInternalServerError, "Server error!")
+			return
+		}
 
 		response := model.Response{
 			Code:    http.StatusOK,
@@ -206,7 +275,7 @@ func VerifyCode() http.HandlerFunc {
 	}
 }
 
-func RecoverDelete(s storage.Store) http.HandlerFunc {
+func RecoverDelete(s storage.Store, c cache.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Get route variables
 		vars := mux.Vars(r)
@@ -214,8 +283,8 @@ func RecoverDelete(s storage.Store) http.HandlerFunc {
 		email := vars["email"]
 
 		// Check if email is verified
-		if err := isMailVerified(email); err != nil {
-			logger.Errorf("email %s is not verified error %v\n", email, err)
+		if err := isMailVerified(c, email); err != nil {
+			logger.WithContext(r.Context()).Errorf("email %s is not verified error %v", email, err)
 			RespondWithError(w, http.StatusUnauthorized, "Email is not verified")
 			return
 		}
@@ -227,18 +296,84 @@ func RecoverDelete(s storage.Store) http.HandlerFunc {
 			return
 		}
 
-		// Delete user
-		err = s.Users().Delete(user.ID, user.Schema)
+		// Deactivate user and schedule the deletion
+		err = app.ScheduleUserDeletion(s, user)
 		if err != nil {
-			RespondWithError(w, http.StatusNotFound, err.Error())
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
+		recordUserEvent(s, r, app.AuditActionDeletion, user)
 
 		response := model.Response{
 			Code:    http.StatusOK,
 			Status:  "Success",
-			Message: "User deleted successfully!",
+			Message: deletionScheduled,
+		}
+		RespondWithJSON(w, http.StatusOK, response)
+	}
+}
+
+// CancelDeletion reactivates a user whose deletion is scheduled
+func CancelDeletion(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var loginDTO model.AuthLoginDTO
+		if err := json.NewDecoder(r.Body).Decode(&loginDTO); err != nil {
+			RespondWithError(w, http.StatusUnprocessableEntity, InvalidJSON)
+			return
+		}
+		defer r.Body.Close()
+
+		// Run validator according to model.AuthLoginDTO validator tags
+		err := app.PayloadValidator(loginDTO)
+		if err != nil {
+			errs := GetErrors(err.(validator.ValidationErrors))
+			RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+			return
+		}
+
+		// Check if user exist in database and credentials are true
+		user, err := s.Users().FindByCredentials(loginDTO.Email, loginDTO.MasterPassword)
+		if err != nil {
+			RespondWithError(w, http.StatusUnauthorized, userLoginErr)
+			return
+		}
+
+		err = app.CancelUserDeletion(s, user)
+		if err == app.ErrDeletionNotScheduled {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		recordUserEvent(s, r, app.AuditActionCancelDelete, user)
+
+		response := model.Response{
+			Code:    http.StatusOK,
+			Status:  Success,
+			Message: deletionCancelled,
 		}
 		RespondWithJSON(w, http.StatusOK, response)
 	}
 }
+
+// checkChannel checks the channel the code is asked to be sent by
+func checkChannel(channel string) error {
+	switch channel {
+	case "", app.ChannelEmail, app.ChannelSMS:
+		return nil
+	}
+	return fmt.Errorf("channel %q is invalid, use email or sms", channel)
+}
+
+// respondSMSError responds with the reason the code couldn't be sent by SMS
+func respondSMSError(w http.ResponseWriter, r *http.Request, err error) {
+	logger.WithContext(r.Context()).Errorf("can't send SMS error: %v", err)
+	switch err {
+	case app.ErrSMSDisabled, app.ErrInvalidPhone:
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+	default:
+		RespondWithError(w, http.StatusBadRequest, "Couldn't send SMS")
+	}
+}
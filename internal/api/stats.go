@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+)
+
+// defaultStatsDays is the days of signups returned without the days parameter
+const defaultStatsDays = 30
+
+// FindInstanceStats returns the statistics of the instance, signups are
+// returned for the days query parameter
+func FindInstanceStats(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		days := defaultStatsDays
+		if value := r.URL.Query().Get("days"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > app.StatsMaxDays {
+				RespondWithError(w, http.StatusBadRequest, "days must be between 1 and 365")
+				return
+			}
+			days = n
+		}
+
+		stats, err := app.FindInstanceStats(s, time.Now(), days)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		RespondWithJSON(w, http.StatusOK, stats)
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// grantFunc changes the pro grant of the user with the email
+type grantFunc func(s storage.Store, dto *model.SubscriptionGrantDTO, now time.Time) (*model.User, error)
+
+// GrantSubscription grants the pro features to a user
+func GrantSubscription(s storage.Store) http.HandlerFunc {
+	return subscriptionGrant(s, app.AuditActionGrant, func(s storage.Store, dto *model.SubscriptionGrantDTO, now time.Time) (*model.User, error) {
+		return app.GrantSubscription(s, dto.Email, dto.Until, now)
+	})
+}
+
+// ExtendSubscription changes the end of the pro grant of a user
+func ExtendSubscription(s storage.Store) http.HandlerFunc {
+	return subscriptionGrant(s, app.AuditActionExtendGrant, func(s storage.Store, dto *model.SubscriptionGrantDTO, now time.Time) (*model.User, error) {
+		return app.ExtendSubscription(s, dto.Email, dto.Until, now)
+	})
+}
+
+// RevokeSubscription ends the pro grant of a user
+func RevokeSubscription(s storage.Store) http.HandlerFunc {
+	return subscriptionGrant(s, app.AuditActionRevokeGrant, func(s storage.Store, dto *model.SubscriptionGrantDTO, now time.Time) (*model.User, error) {
+		return app.RevokeSubscription(s, dto.Email)
+	})
+}
+
+// subscriptionGrant changes the grant of the user in the request body and
+// records the change to the audit log with the admin as the actor
+func subscriptionGrant(s storage.Store, action string, change grantFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var grantDTO model.SubscriptionGrantDTO
+		if err := json.NewDecoder(r.Body).Decode(&grantDTO); err != nil {
+			RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := app.PayloadValidator(grantDTO); err != nil {
+			errs := GetErrors(err.(validator.ValidationErrors))
+			RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+			return
+		}
+
+		now := time.Now()
+		user, err := change(s, &grantDTO, now)
+		if errors.Is(err, app.ErrAlreadyGranted) || errors.Is(err, app.ErrNotGranted) || errors.Is(err, app.ErrGrantEnded) {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		RecordAuditEvent(s, r, NewAuditEvent(r, action, "users", user.ID))
+
+		RespondWithJSON(w, http.StatusOK, model.SubscriptionGrant{
+			Email:            user.Email,
+			SubscriptionType: app.SubscriptionAccess(user, now),
+			GrantedAt:        user.SubscriptionGrantedAt,
+			GrantedUntil:     user.SubscriptionGrantedUntil,
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/cache"
+)
+
+// GrantSupportConsent gives consent to the admins to impersonate the user of
+// the token for the minutes of the payload and returns the consent token
+func GrantSupportConsent(s storage.Store, c cache.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var consentDTO model.SupportConsentDTO
+		if err := json.NewDecoder(r.Body).Decode(&consentDTO); err != nil && err != io.EOF {
+			RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := app.PayloadValidator(consentDTO); err != nil {
+			errs := GetErrors(err.(validator.ValidationErrors))
+			RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+			return
+		}
+
+		userID := r.Context().Value("user_id").(uint)
+		user, err := s.Users().FindByID(userID)
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		duration := time.Duration(consentDTO.Minutes) * time.Minute
+		consent, err := app.GrantSupportConsent(c, user, duration, time.Now())
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RecordAuditEvent(s, r, NewAuditEvent(r, app.AuditActionGrantSupport, "users", user.ID))
+		RespondWithJSON(w, http.StatusOK, consent)
+	}
+}
+
+// RevokeSupportConsent takes the consent of the user of the token back, the
+// support sessions of the admins end right away
+func RevokeSupportConsent(s storage.Store, c cache.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value("user_id").(uint)
+		if err := app.RevokeSupportConsent(c, userID); err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RecordAuditEvent(s, r, NewAuditEvent(r, app.AuditActionRevokeSupport, "users", userID))
+		response := model.Response{
+			Code:    http.StatusOK,
+			Status:  Success,
+			Message: "Support consent revoked successfully!",
+		}
+		RespondWithJSON(w, http.StatusOK, response)
+	}
+}
+
+// Impersonate creates the token of the support session of the admin as the
+// user of the consent token. The session can't read the vault or change
+// anything and its every request is in the audit log of the user.
+func Impersonate(s storage.Store, c cache.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var impersonateDTO model.ImpersonateDTO
+		if err := json.NewDecoder(r.Body).Decode(&impersonateDTO); err != nil {
+			RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := app.PayloadValidator(impersonateDTO); err != nil {
+			errs := GetErrors(err.(validator.ValidationErrors))
+			RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+			return
+		}
+
+		adminID := r.Context().Value("user_id").(uint)
+		admin, err := s.Users().FindByID(adminID)
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		user, td, err := app.Impersonate(s, c, admin, impersonateDTO.Email, impersonateDTO.ConsentToken, time.Now())
+		if errors.Is(err, app.ErrNoSupportConsent) {
+			RespondWithError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		event := NewAuditEvent(r, app.AuditActionImpersonate, "users", user.ID)
+		event.ImpersonatorID = admin.ID
+		RecordAuditEvent(s, r, event)
+
+		userDTO := model.ToUserDTOTable(*user)
+		RespondWithJSON(w, http.StatusOK, model.ImpersonateResponse{
+			AccessToken: td.AccessToken,
+			ExpiresAt:   td.AtExpiresTime,
+			User:        &userDTO,
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+)
+
+// Sync returns the items created, updated or deleted since the revision or
+// timestamp in the since query param. Without since the whole vault is returned.
+func Sync(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		since, err := app.ParseSyncSince(r.URL.Query().Get("since"))
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		schema := r.Context().Value("schema").(string)
+		response, err := app.Sync(s, since, schema)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, response)
+	}
+}
+
+// FullSync returns the profile of the user and the whole vault in one response
+// for the first sync of the clients. User is read from the system store.
+func FullSync(system storage.Store) func(storage.Store) http.HandlerFunc {
+	return func(s storage.Store) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			userID := r.Context().Value("user_id").(uint)
+			user, err := system.Users().FindByID(userID)
+			if err != nil {
+				RespondWithError(w, http.StatusNotFound, err.Error())
+				return
+			}
+
+			schema := r.Context().Value("schema").(string)
+			response, err := app.FullSync(s, user, schema)
+			if err != nil {
+				RespondWithError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+
+			RespondWithJSON(w, http.StatusOK, response)
+		}
+	}
+}
+
+// FindVaultRevision returns the revision counter of the vault, clients compare
+// it with the revision of their last sync to decide whether to sync
+func FindVaultRevision(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		schema := r.Context().Value("schema").(string)
+		revision, err := app.FindVaultRevision(s, schema)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, revision)
+	}
+}
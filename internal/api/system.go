@@ -2,9 +2,11 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/gorilla/mux"
@@ -129,25 +131,54 @@ func Language(s storage.Store) http.HandlerFunc {
 	}
 }
 
-// Import ...
+// Import imports the JSON login list or, when format query param is given, the
+// export file of another password manager sent as "file" form field or request body.
+// With dry_run query param the file is only validated and the summary is returned.
 func Import(s storage.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		schema := r.Context().Value("schema").(string)
+
+		// Dry runs of the JSON login list are previewed like the other formats
+		format := r.URL.Query().Get("format")
+		dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+		if format == "" && dryRun {
+			format = "json"
+		}
+
+		if format != "" {
+			items, opts, err := parseImportRequest(w, r, format)
+			if err != nil {
+				RespondWithError(w, requestErrorStatus(err), err.Error())
+				return
+			}
+			opts.DryRun = dryRun
+
+			summary, err := app.SaveImport(s, items, schema, opts)
+			if err != nil {
+				RespondWithError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+
+			RespondWithJSON(w, http.StatusOK, summary)
+			return
+		}
+
 		var payloadList []model.LoginDTO
 
 		decoder := json.NewDecoder(r.Body)
 		if err := decoder.Decode(&payloadList); err != nil {
 			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
 		}
 		defer r.Body.Close()
 
-		for _, loginDTO := range payloadList {
-			// Add new login to db
-			schema := r.Context().Value("schema").(string)
-			_, err := app.CreateLogin(s, &loginDTO, schema)
-			if err != nil {
-				RespondWithError(w, http.StatusInternalServerError, err.Error())
-				return
-			}
+		// Logins of the list are imported all together or not at all
+		err := s.WithTransaction(func(tx storage.Store) error {
+			return app.CreateLogins(tx, payloadList, schema)
+		})
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
 		}
 
 		response := model.Response{
@@ -159,6 +190,79 @@ func Import(s storage.Store) http.HandlerFunc {
 	}
 }
 
+// ImportJob starts importing the export file like Import does, but saves the items
+// in the background and responds with the job whose progress is followed on /jobs/{id}
+func ImportJob(system storage.Store) func(storage.Store) http.HandlerFunc {
+	return func(s storage.Store) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			schema := r.Context().Value("schema").(string)
+			userID := r.Context().Value("user_id").(uint)
+
+			format := r.URL.Query().Get("format")
+			if format == "" {
+				format = "json"
+			}
+			items, opts, err := parseImportRequest(w, r, format)
+			if err != nil {
+				RespondWithError(w, requestErrorStatus(err), err.Error())
+				return
+			}
+
+			job, err := app.StartImportJob(system, s, userID, items, schema, opts)
+			if err != nil {
+				RespondWithError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+
+			RespondWithJSON(w, http.StatusAccepted, model.ToJobDTO(job))
+		}
+	}
+}
+
+// parseImportRequest reads the import file and the options of the request and
+// parses the file in the given format
+func parseImportRequest(w http.ResponseWriter, r *http.Request, format string) ([]model.ImportItem, model.ImportOptions, error) {
+	data, err := readImportFile(w, r)
+	if err != nil {
+		return nil, model.ImportOptions{}, err
+	}
+	// Signed Passwall exports are checked before anything is parsed
+	if err := verifyImportSignature(r, data); err != nil {
+		return nil, model.ImportOptions{}, err
+	}
+
+	skipDuplicates, _ := strconv.ParseBool(r.FormValue("skip_duplicates"))
+	opts := model.ImportOptions{
+		Password:       r.FormValue("password"),
+		SkipDuplicates: skipDuplicates,
+	}
+	if mapping := r.FormValue("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &opts.Mapping); err != nil {
+			return nil, opts, errors.New("invalid mapping value")
+		}
+	}
+
+	items, err := app.ParseImport(format, data, opts)
+	return items, opts, err
+}
+
+// readImportFile reads the uploaded file of multipart requests or the whole body,
+// the size of the body is limited by the router
+func readImportFile(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	defer r.Body.Close()
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return ioutil.ReadAll(file)
+	}
+
+	return ioutil.ReadAll(r.Body)
+}
+
 // Export exports all data as CSV file
 func Export(s storage.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -177,37 +281,37 @@ func Export(s storage.Store) http.HandlerFunc {
 		schema := r.Context().Value("schema").(string)
 
 		if l, err := app.FindAllLogins(s, schema); err != nil {
-			logger.Errorf("Error while getting logins: %v", err)
+			logger.WithContext(r.Context()).Errorf("Error while getting logins: %v", err)
 		} else {
 			allRecords.Logins = l
 		}
 
 		if ba, err := app.FindAllBankAccounts(s, schema); err != nil {
-			logger.Errorf("Error while getting logins: %v", err)
+			logger.WithContext(r.Context()).Errorf("Error while getting logins: %v", err)
 		} else {
 			allRecords.BankAccounts = ba
 		}
 
 		if cc, err := app.FindAllCreditCards(s, schema); err != nil {
-			logger.Errorf("Error while getting logins: %v", err)
+			logger.WithContext(r.Context()).Errorf("Error while getting logins: %v", err)
 		} else {
 			allRecords.CreditCards = cc
 		}
 
 		if nt, err := app.FindAllNotes(s, schema); err != nil {
-			logger.Errorf("Error while getting logins: %v", err)
+			logger.WithContext(r.Context()).Errorf("Error while getting logins: %v", err)
 		} else {
 			allRecords.Notes = nt
 		}
 
 		if sr, err := app.FindAllServers(s, schema); err != nil {
-			logger.Errorf("Error while getting logins: %v", err)
+			logger.WithContext(r.Context()).Errorf("Error while getting logins: %v", err)
 		} else {
 			allRecords.Servers = sr
 		}
 
 		if em, err := app.FindAllEmails(s, schema); err != nil {
-			logger.Errorf("Error while getting logins: %v", err)
+			logger.WithContext(r.Context()).Errorf("Error while getting logins: %v", err)
 		} else {
 			allRecords.Emails = em
 		}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/internal/storage/trash"
+	"github.com/passwall/passwall-server/model"
+)
+
+// FindDeletedRecords lists the deleted records of the table which aren't purged
+// yet. The vault tables need the email query param of the user or the
+// organization query param.
+func FindDeletedRecords(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vault, err := trashVault(r)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		records, err := app.FindDeletedRecords(s, mux.Vars(r)["table"], vault)
+		if err != nil {
+			RespondWithError(w, trashErrorStatus(err), err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, records)
+	}
+}
+
+// RestoreDeletedRecord brings the deleted record of the table back
+func RestoreDeletedRecord(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		table := mux.Vars(r)["table"]
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		vault, err := trashVault(r)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if err := app.RestoreDeletedRecord(s, table, uint(id), vault); err != nil {
+			RespondWithError(w, trashErrorStatus(err), err.Error())
+			return
+		}
+		RecordAuditEvent(s, r, NewAuditEvent(r, app.AuditActionRestore, table, uint(id)))
+
+		response := model.Response{
+			Code:    http.StatusOK,
+			Status:  Success,
+			Message: "Record restored successfully!",
+		}
+		RespondWithJSON(w, http.StatusOK, response)
+	}
+}
+
+// trashVault returns the vault of the email or the organization query param
+func trashVault(r *http.Request) (app.TrashVault, error) {
+	vault := app.TrashVault{Email: r.FormValue("email")}
+	if v := r.FormValue("organization"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return vault, err
+		}
+		vault.OrganizationID = uint(id)
+	}
+	return vault, nil
+}
+
+// trashErrorStatus returns the status of the errors of the deleted records
+func trashErrorStatus(err error) int {
+	switch err {
+	case trash.ErrUnknownTable, app.ErrDeletedRecordNotFound, app.ErrVaultNotFound:
+		return http.StatusNotFound
+	case app.ErrVaultRequired:
+		return http.StatusBadRequest
+	case app.ErrEmailTaken:
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// FindTrustedDevices lists the devices of the user of the token which skip the
+// two-factor code at signin
+func FindTrustedDevices(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := r.Context().Value("user_id").(uint)
+		devices, err := s.TrustedDevices().FindByUserID(userID, time.Now())
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		RespondWithJSON(w, http.StatusOK, devices)
+	}
+}
+
+// RevokeTrustedDevice forgets a trusted device of the user of the token, its
+// next signin asks the two-factor code again
+func RevokeTrustedDevice(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := r.Context().Value("user_id").(uint)
+		device, err := s.TrustedDevices().Delete(userID, mux.Vars(r)["uuid"])
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		event := NewAuditEvent(r, app.AuditActionRevokeDevice, "trusted_devices", device.ID)
+		event.UserID = userID
+		RecordAuditEvent(s, r, event)
+
+		response := model.Response{
+			Code:    http.StatusOK,
+			Status:  Success,
+			Message: "Trusted device revoked successfully!",
+		}
+		RespondWithJSON(w, http.StatusOK, response)
+	}
+}
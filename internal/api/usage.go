@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// RespondWithQuotaError responds 402 when subscribing allows more of the
+// resource and 403 when it doesn't, it returns false for the other errors
+func RespondWithQuotaError(w http.ResponseWriter, err error) bool {
+	var quotaErr *app.QuotaError
+	if !errors.As(err, &quotaErr) {
+		return false
+	}
+
+	status := http.StatusForbidden
+	if quotaErr.Upgradable {
+		status = http.StatusPaymentRequired
+	}
+	RespondWithError(w, status, quotaErr.Error())
+	return true
+}
+
+// FindUsage returns the use of the plan quotas of the user
+func FindUsage(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value("user_id").(uint)
+
+		user, err := s.Users().FindByID(userID)
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		usage, err := app.FindUsage(s, user)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, usage)
+	}
+}
+
+// UpdateUserQuota changes the item quota of the user, it returns the usage of
+// the user with the new quota
+func UpdateUserQuota(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var quotaDTO model.UserQuotaDTO
+		if err := json.NewDecoder(r.Body).Decode(&quotaDTO); err != nil {
+			RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := app.PayloadValidator(quotaDTO); err != nil {
+			errs := GetErrors(err.(validator.ValidationErrors))
+			RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+			return
+		}
+
+		user, err := s.Users().FindByID(uint(id))
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		user, err = app.SetUserQuota(s, user, quotaDTO.MaxItems)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		usage, err := app.FindUsage(s, user)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		RespondWithJSON(w, http.StatusOK, usage)
+	}
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRespondWithQuotaError(t *testing.T) {
+	w := httptest.NewRecorder()
+	assert.True(t, RespondWithQuotaError(w, &app.QuotaError{Resource: app.QuotaItems, Limit: 50, Upgradable: true}))
+	assert.Equal(t, http.StatusPaymentRequired, w.Code)
+
+	var errResponse ErrorResponseDTO
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&errResponse))
+	assert.Equal(t, "your plan allows 50 items, upgrade to add more", errResponse.Message)
+
+	w = httptest.NewRecorder()
+	assert.True(t, RespondWithQuotaError(w, &app.QuotaError{Resource: app.QuotaShares, Limit: 500}))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	assert.False(t, RespondWithQuotaError(httptest.NewRecorder(), errors.New("database is down")))
+}
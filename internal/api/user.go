@@ -2,8 +2,10 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 
@@ -30,6 +32,14 @@ func FindAllUsers(s storage.Store) http.HandlerFunc {
 		}
 
 		usersDTOs := model.ToUserDTOs(users)
+		for i := range users {
+			usage, err := app.FindUsage(s, &users[i])
+			if err != nil {
+				RespondWithError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			usersDTOs[i].Usage = usage
+		}
 
 		// users = app.DecryptUserPasswords(users)
 		RespondWithJSON(w, http.StatusOK, usersDTOs)
@@ -79,6 +89,11 @@ func CreateUser(s storage.Store) http.HandlerFunc {
 			return
 		}
 
+		// Admins are held to the security policy too
+		if !checkMasterPasswordPolicy(s, w, r, userDTO.MasterPassword) {
+			return
+		}
+
 		// 3. Check if user exist in database
 		_, err := s.Users().FindByEmail(userDTO.Email)
 		if err == nil {
@@ -143,8 +158,12 @@ func UpdateUser(s storage.Store) http.HandlerFunc {
 		// Update user
 
 		updatedUser, err := app.UpdateUser(s, user, &userDTO, isAuthorized)
+		if err == app.ErrUnsupportedLocale {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		if err != nil {
-			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			RespondWithError(w, updateErrorStatus(err), err.Error())
 			return
 		}
 
@@ -180,7 +199,7 @@ func Migrate(s storage.Store) http.HandlerFunc {
 
 		updatedUser, err := app.UpdateUser(s, user, userDTO, isAuthorized)
 		if err != nil {
-			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			RespondWithError(w, updateErrorStatus(err), err.Error())
 			return
 		}
 
@@ -209,6 +228,9 @@ func DeleteUser(s storage.Store) http.HandlerFunc {
 			RespondWithError(w, http.StatusNotFound, err.Error())
 			return
 		}
+		s.Webhooks().DeleteByUserID(user.ID)
+		s.Alerts().DeleteByUserID(user.ID)
+		s.TrustedDevices().DeleteByUserID(user.ID)
 
 		response := model.Response{
 			Code:    http.StatusOK,
@@ -245,6 +267,10 @@ func ChangeMasterPassword(s storage.Store) http.HandlerFunc {
 			return
 		}
 
+		if !checkMasterPasswordPolicy(s, w, r, newPass) {
+			return
+		}
+
 		user, err := s.Users().FindByCredentials(email, oldPass)
 		if err != nil {
 			RespondWithError(w, http.StatusUnauthorized, userLoginErr)
@@ -258,7 +284,7 @@ func ChangeMasterPassword(s storage.Store) http.HandlerFunc {
 
 		_, err = app.ChangeMasterPassword(s, user, newPass)
 		if err != nil {
-			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			RespondWithError(w, updateErrorStatus(err), err.Error())
 			return
 		}
 
@@ -307,3 +333,63 @@ func CheckCredentials(s storage.Store) http.HandlerFunc {
 		RespondWithJSON(w, http.StatusOK, response)
 	}
 }
+
+// Reauthenticate confirms the master password of the token owner before sensitive operations
+func Reauthenticate(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value("user_id").(uint)
+
+		var dto model.ReauthenticateDTO
+		if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+			RespondWithError(w, http.StatusUnprocessableEntity, InvalidJSON)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := app.PayloadValidator(dto); err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		user, err := app.Reauthenticate(s, userID, dto.MasterPassword)
+		if err != nil {
+			RespondWithError(w, http.StatusUnauthorized, userLoginErr)
+			return
+		}
+
+		response := model.Response{
+			Code:    http.StatusOK,
+			Status:  Success,
+			Message: fmt.Sprintf("Master password confirmed until %s", user.ReauthenticatedAt.Add(app.StepUpWindow()).Format(time.RFC3339)),
+		}
+		RespondWithJSON(w, http.StatusOK, response)
+	}
+}
+
+// ForcePasswordChange asks a user to change the master password, the user
+// signs in but can't use the vault until it is changed
+func ForcePasswordChange(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var dto model.ForcePasswordChangeDTO
+		if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+			RespondWithError(w, http.StatusBadRequest, InvalidRequestPayload)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := app.PayloadValidator(dto); err != nil {
+			errs := GetErrors(err.(validator.ValidationErrors))
+			RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+			return
+		}
+
+		user, err := app.SetMasterPasswordReset(s, dto.Email, dto.ForcePasswordChange)
+		if err != nil {
+			RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		RecordAuditEvent(s, r, NewAuditEvent(r, app.AuditActionForceChange, "users", user.ID))
+		RespondWithJSON(w, http.StatusOK, model.ToUserDTO(user))
+	}
+}
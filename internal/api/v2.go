@@ -0,0 +1,285 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+const (
+	// problemContentType is the content type of the v2 error responses
+	problemContentType = "application/problem+json"
+	// defaultPageLimit is the page size of the v2 lists without a limit
+	defaultPageLimit = 50
+)
+
+var (
+	errInvalidCursor = errors.New("invalid cursor value")
+	errInvalidLimit  = errors.New("invalid limit value")
+)
+
+// ProblemDTO is the error response of the v2 API, the problem details of RFC 7807
+type ProblemDTO struct {
+	Type      string   `json:"type"`
+	Title     string   `json:"title"`
+	Status    int      `json:"status"`
+	Detail    string   `json:"detail,omitempty"`
+	RequestID string   `json:"request_id,omitempty"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// PageDTO is the list response of the v2 API
+type PageDTO struct {
+	Data       interface{}   `json:"data"`
+	Pagination PaginationDTO `json:"pagination"`
+}
+
+// PaginationDTO tells where the page is in the list, the next page is asked
+// with the next cursor until it is empty
+type PaginationDTO struct {
+	Limit      int    `json:"limit"`
+	Total      int64  `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// RespondWithProblem writes the v2 error response of the status
+func RespondWithProblem(w http.ResponseWriter, r *http.Request, status int, detail string, errs ...string) {
+	problem := ProblemDTO{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    localize(w, detail),
+		RequestID: logger.RequestID(r.Context()),
+		Errors:    errs,
+	}
+	response, _ := json.Marshal(problem)
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// ProblemNotFound is the response of the unknown v2 endpoints
+func ProblemNotFound(w http.ResponseWriter, r *http.Request) {
+	RespondWithProblem(w, r, http.StatusNotFound, "endpoint not found")
+}
+
+// ParsePageOptions parses the limit, cursor, search and updated_since query
+// params of the v2 lists. Pages always walk the items in id order, so a cursor
+// stays valid while the items change.
+func ParsePageOptions(r *http.Request) (*model.ListOptions, error) {
+	opts := &model.ListOptions{
+		Limit:     defaultPageLimit,
+		Sort:      "id",
+		Direction: "asc",
+		Search:    strings.TrimSpace(r.FormValue("search")),
+		Filters:   map[string]interface{}{},
+	}
+
+	if v := r.FormValue("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return nil, errInvalidLimit
+		}
+		opts.Limit = limit
+	}
+	if opts.Limit > maxListLimit {
+		opts.Limit = maxListLimit
+	}
+
+	if v := r.FormValue("cursor"); v != "" {
+		cursor, err := decodeCursor(v)
+		if err != nil {
+			return nil, err
+		}
+		opts.Cursor = cursor
+	}
+
+	if v := r.FormValue("updated_since"); v != "" {
+		updatedSince, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, errors.New("invalid updated_since value, RFC3339 expected")
+		}
+		opts.UpdatedSince = &updatedSince
+	}
+
+	return opts, nil
+}
+
+// NewPage creates the v2 list response. Items must be a slice of models with an
+// ID field, one item more than the limit is asked to know if a next page exists.
+func NewPage(items interface{}, total int64, limit int) PageDTO {
+	page := PageDTO{Data: items, Pagination: PaginationDTO{Limit: limit, Total: total}}
+
+	list := reflect.ValueOf(items)
+	if list.Kind() == reflect.Slice && list.Len() > limit {
+		last := reflect.Indirect(list.Index(limit - 1)).FieldByName("ID")
+		page.Data = list.Slice(0, limit).Interface()
+		if last.IsValid() {
+			page.Pagination.NextCursor = encodeCursor(uint(last.Uint()))
+		}
+	}
+	return page
+}
+
+// encodeCursor hides the ID behind the cursor so clients don't build cursors
+func encodeCursor(id uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("id:" + strconv.FormatUint(uint64(id), 10)))
+}
+
+func decodeCursor(cursor string) (uint, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(data), "id:") {
+		return 0, errInvalidCursor
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(string(data), "id:"), 10, 64)
+	if err != nil {
+		return 0, errInvalidCursor
+	}
+	return uint(id), nil
+}
+
+// pageFinder finds the decrypted items of the page and the total count
+type pageFinder func(s storage.Store, opts *model.ListOptions, schema string) (interface{}, int64, error)
+
+// listV2 serves the pages of the items the finder finds
+func listV2(find pageFinder) func(storage.Store) http.HandlerFunc {
+	return func(s storage.Store) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			opts, err := ParsePageOptions(r)
+			if err != nil {
+				RespondWithProblem(w, r, http.StatusBadRequest, err.Error())
+				return
+			}
+			limit := opts.Limit
+			opts.Limit++
+
+			schema := r.Context().Value("schema").(string)
+			items, total, err := find(s, opts, schema)
+			if err != nil {
+				logger.WithContext(r.Context()).Errorf("can't list items error: %v", err)
+				RespondWithProblem(w, r, http.StatusInternalServerError, "items can't be listed")
+				return
+			}
+
+			RespondWithJSON(w, http.StatusOK, NewPage(items, total, limit))
+		}
+	}
+}
+
+// decryptAll decrypts the server side encrypted fields of the slice of models
+// of the vault of the schema
+func decryptAll(items interface{}, schema string) error {
+	list := reflect.ValueOf(items)
+	for i := 0; i < list.Len(); i++ {
+		if _, err := app.DecryptModel(list.Index(i).Addr().Interface(), schema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListLoginsV2 lists the logins page by page
+var ListLoginsV2 = listV2(func(s storage.Store, opts *model.ListOptions, schema string) (interface{}, int64, error) {
+	items, total, err := s.Logins().FindAll(opts, schema)
+	if err == nil {
+		err = decryptAll(items, schema)
+	}
+	return items, total, err
+})
+
+// ListCreditCardsV2 lists the credit cards page by page
+var ListCreditCardsV2 = listV2(func(s storage.Store, opts *model.ListOptions, schema string) (interface{}, int64, error) {
+	items, total, err := s.CreditCards().FindAll(opts, schema)
+	if err == nil {
+		err = decryptAll(items, schema)
+	}
+	return items, total, err
+})
+
+// ListBankAccountsV2 lists the bank accounts page by page
+var ListBankAccountsV2 = listV2(func(s storage.Store, opts *model.ListOptions, schema string) (interface{}, int64, error) {
+	items, total, err := s.BankAccounts().FindAll(opts, schema)
+	if err == nil {
+		err = decryptAll(items, schema)
+	}
+	return items, total, err
+})
+
+// ListNotesV2 lists the notes page by page
+var ListNotesV2 = listV2(func(s storage.Store, opts *model.ListOptions, schema string) (interface{}, int64, error) {
+	items, total, err := s.Notes().FindAll(opts, schema)
+	if err == nil {
+		err = decryptAll(items, schema)
+	}
+	return items, total, err
+})
+
+// ListEmailsV2 lists the emails page by page
+var ListEmailsV2 = listV2(func(s storage.Store, opts *model.ListOptions, schema string) (interface{}, int64, error) {
+	items, total, err := s.Emails().FindAll(opts, schema)
+	if err == nil {
+		err = decryptAll(items, schema)
+	}
+	return items, total, err
+})
+
+// ListServersV2 lists the servers page by page
+var ListServersV2 = listV2(func(s storage.Store, opts *model.ListOptions, schema string) (interface{}, int64, error) {
+	items, total, err := s.Servers().FindAll(opts, schema)
+	if err == nil {
+		err = decryptAll(items, schema)
+	}
+	return items, total, err
+})
+
+// SyncV2 returns the changes since the revision in the since query param, the
+// revision of the response is sent as since in the next sync. Unlike v1 only
+// revisions are accepted, timestamps of the clients can't be trusted.
+func SyncV2(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		since := time.Time{}
+		if v := r.URL.Query().Get("since"); v != "" {
+			revision, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || revision < 0 {
+				RespondWithProblem(w, r, http.StatusBadRequest, "invalid since value, revision of the last sync expected")
+				return
+			}
+			since = app.RevisionTime(revision)
+		}
+
+		schema := r.Context().Value("schema").(string)
+		response, err := app.Sync(s, since, schema)
+		if err != nil {
+			logger.WithContext(r.Context()).Errorf("can't sync error: %v", err)
+			RespondWithProblem(w, r, http.StatusInternalServerError, "vault can't be synced")
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, response)
+	}
+}
+
+// FindVaultRevisionV2 returns the revision counter of the vault
+func FindVaultRevisionV2(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		schema := r.Context().Value("schema").(string)
+		revision, err := app.FindVaultRevision(s, schema)
+		if err != nil {
+			logger.WithContext(r.Context()).Errorf("can't find vault revision error: %v", err)
+			RespondWithProblem(w, r, http.StatusInternalServerError, "vault revision can't be found")
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, revision)
+	}
+}
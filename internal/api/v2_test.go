@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePageOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		wantErr bool
+		check   func(opts *model.ListOptions)
+	}{
+		{name: "Defaults", query: "", check: func(opts *model.ListOptions) {
+			assert.Equal(t, defaultPageLimit, opts.Limit)
+			assert.Equal(t, "id", opts.Sort)
+			assert.Equal(t, "asc", opts.Direction)
+			assert.Equal(t, uint(0), opts.Cursor)
+		}},
+		{name: "Cursor and limit", query: "limit=20&cursor=" + encodeCursor(15) + "&search=git", check: func(opts *model.ListOptions) {
+			assert.Equal(t, 20, opts.Limit)
+			assert.Equal(t, uint(15), opts.Cursor)
+			assert.Equal(t, "git", opts.Search)
+		}},
+		{name: "Limit is capped", query: "limit=5000", check: func(opts *model.ListOptions) {
+			assert.Equal(t, maxListLimit, opts.Limit)
+		}},
+		{name: "Zero limit", query: "limit=0", wantErr: true},
+		{name: "Plain ID cursor", query: "cursor=15", wantErr: true},
+		{name: "Invalid updated since", query: "updated_since=yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/v2/logins?"+tt.query, nil)
+			opts, err := ParsePageOptions(r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParsePageOptions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.check != nil {
+				tt.check(opts)
+			}
+		})
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	logins := []model.Login{{ID: 3}, {ID: 7}, {ID: 9}}
+
+	page := NewPage(logins, 10, 2)
+	assert.Equal(t, []model.Login{{ID: 3}, {ID: 7}}, page.Data)
+	assert.Equal(t, encodeCursor(7), page.Pagination.NextCursor)
+	assert.Equal(t, int64(10), page.Pagination.Total)
+
+	// Last page has no next cursor even when it is full
+	page = NewPage(logins, 3, 3)
+	assert.Equal(t, logins, page.Data)
+	assert.Empty(t, page.Pagination.NextCursor)
+}
+
+func TestCursor(t *testing.T) {
+	id, err := decodeCursor(encodeCursor(42))
+	assert.NoError(t, err)
+	assert.Equal(t, uint(42), id)
+
+	_, err = decodeCursor("not-a-cursor")
+	assert.ErrorIs(t, err, errInvalidCursor)
+}
+
+func TestRespondWithProblem(t *testing.T) {
+	w := httptest.NewRecorder()
+	RespondWithProblem(w, httptest.NewRequest("GET", "/api/v2/sync", nil), http.StatusBadRequest, "invalid since value")
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+
+	var problem ProblemDTO
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, "Bad Request", problem.Title)
+	assert.Equal(t, http.StatusBadRequest, problem.Status)
+	assert.Equal(t, "invalid since value", problem.Detail)
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+)
+
+const (
+	// backupPasswordHeader carries the password of the backup, so it isn't logged with the URL
+	backupPasswordHeader = "X-Backup-Password"
+)
+
+// ExportBackup exports the whole vault as a password protected backup file
+func ExportBackup(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		password := r.Header.Get(backupPasswordHeader)
+		if password == "" {
+			RespondWithError(w, http.StatusBadRequest, app.ErrImportPasswordRequired.Error())
+			return
+		}
+
+		schema := r.Context().Value("schema").(string)
+		backup, err := app.BuildVaultBackup(s, schema)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		data, err := app.EncryptVaultBackup(backup, password)
+		if err == app.ErrShortBackupPassword {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		filename := fmt.Sprintf("passwall-%s.pwbackup", time.Now().Format("2006-01-02"))
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	}
+}
+
+// RestoreBackup adds the content of a backup file to the vault. The file is sent
+// as "file" form field or request body with its password in the backup password header
+// or "password" form field.
+func RestoreBackup(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, err := readImportFile(w, r)
+		if err != nil {
+			RespondWithError(w, requestErrorStatus(err), err.Error())
+			return
+		}
+
+		password := r.Header.Get(backupPasswordHeader)
+		if password == "" {
+			password = r.FormValue("password")
+		}
+		if password == "" {
+			RespondWithError(w, http.StatusBadRequest, app.ErrImportPasswordRequired.Error())
+			return
+		}
+
+		backup, err := app.DecryptVaultBackup(data, password)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		schema := r.Context().Value("schema").(string)
+		summary, err := app.RestoreVaultBackup(s, backup, schema)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, summary)
+	}
+}
+
+// ExportKeePass exports the whole vault as a KDBX 4 database encrypted with the
+// password in the backup password header
+func ExportKeePass(s storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		password := r.Header.Get(backupPasswordHeader)
+		if password == "" {
+			RespondWithError(w, http.StatusBadRequest, app.ErrImportPasswordRequired.Error())
+			return
+		}
+
+		schema := r.Context().Value("schema").(string)
+		backup, err := app.BuildVaultBackup(s, schema)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		buf := new(bytes.Buffer)
+		err = app.ExportKeePass(backup, password, buf)
+		if err == app.ErrShortBackupPassword {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		manifest, signature, err := app.SignExport(app.ExportTypeKeePass, "", app.VaultBackupItems(backup), buf.Bytes())
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		filename := fmt.Sprintf("passwall-%s.kdbx", time.Now().Format("2006-01-02"))
+		setExportSignature(w.Header(), manifest, signature)
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
+	}
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// webhookDeliverySortFields are the fields webhook deliveries can be sorted by
+var webhookDeliverySortFields = []string{"id", "created_at", "updated_at", "status", "attempts"}
+
+// FindAllWebhooks lists the webhooks of the user of the token
+func FindAllWebhooks(s storage.Store) http.HandlerFunc {
+	return findAllWebhooks(s, false)
+}
+
+// FindAllAdminWebhooks lists the webhooks receiving the events of every user
+func FindAllAdminWebhooks(s storage.Store) http.HandlerFunc {
+	return findAllWebhooks(s, true)
+}
+
+// CreateWebhook registers a webhook of the user of the token, the response
+// has the signing secret which isn't shown again
+func CreateWebhook(s storage.Store) http.HandlerFunc {
+	return createWebhook(s, false)
+}
+
+// CreateAdminWebhook registers a webhook receiving the events of every user
+func CreateAdminWebhook(s storage.Store) http.HandlerFunc {
+	return createWebhook(s, true)
+}
+
+// UpdateWebhook changes a webhook of the user of the token
+func UpdateWebhook(s storage.Store) http.HandlerFunc {
+	return updateWebhook(s, false)
+}
+
+// UpdateAdminWebhook changes an admin webhook
+func UpdateAdminWebhook(s storage.Store) http.HandlerFunc {
+	return updateWebhook(s, true)
+}
+
+// DeleteWebhook removes a webhook of the user of the token and its deliveries
+func DeleteWebhook(s storage.Store) http.HandlerFunc {
+	return deleteWebhook(s, false)
+}
+
+// DeleteAdminWebhook removes an admin webhook and its deliveries
+func DeleteAdminWebhook(s storage.Store) http.HandlerFunc {
+	return deleteWebhook(s, true)
+}
+
+// FindWebhookDeliveries lists the delivery log of a webhook of the user of the token
+func FindWebhookDeliveries(s storage.Store) http.HandlerFunc {
+	return findWebhookDeliveries(s, false)
+}
+
+// FindAdminWebhookDeliveries lists the delivery log of an admin webhook
+func FindAdminWebhookDeliveries(s storage.Store) http.HandlerFunc {
+	return findWebhookDeliveries(s, true)
+}
+
+func findAllWebhooks(s storage.Store, admin bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := r.Context().Value("user_id").(uint)
+		webhooks, err := s.Webhooks().FindAll(userID, admin)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		responses := make([]*model.WebhookResponse, len(webhooks))
+		for i := range webhooks {
+			responses[i] = model.ToWebhookResponse(&webhooks[i])
+		}
+		RespondWithJSON(w, http.StatusOK, responses)
+	}
+}
+
+func createWebhook(s storage.Store, admin bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dto, ok := decodeWebhookDTO(w, r)
+		if !ok {
+			return
+		}
+
+		userID, _ := r.Context().Value("user_id").(uint)
+		webhook, err := app.CreateWebhook(s, dto, userID, admin)
+		if err != nil {
+			RespondWithError(w, webhookErrorStatus(err), err.Error())
+			return
+		}
+
+		response := model.ToWebhookResponse(webhook)
+		response.Secret = webhook.Secret
+		RespondWithJSON(w, http.StatusOK, response)
+	}
+}
+
+func updateWebhook(s storage.Store, admin bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		webhook, ok := findWebhook(s, w, r, admin)
+		if !ok {
+			return
+		}
+		dto, ok := decodeWebhookDTO(w, r)
+		if !ok {
+			return
+		}
+
+		webhook, err := app.UpdateWebhook(s, webhook, dto)
+		if err != nil {
+			RespondWithError(w, webhookErrorStatus(err), err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, model.ToWebhookResponse(webhook))
+	}
+}
+
+func deleteWebhook(s storage.Store, admin bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		webhook, ok := findWebhook(s, w, r, admin)
+		if !ok {
+			return
+		}
+
+		if err := s.Webhooks().Delete(webhook.ID); err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		response := model.Response{
+			Code:    http.StatusOK,
+			Status:  Success,
+			Message: "Webhook deleted successfully!",
+		}
+		RespondWithJSON(w, http.StatusOK, response)
+	}
+}
+
+func findWebhookDeliveries(s storage.Store, admin bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		webhook, ok := findWebhook(s, w, r, admin)
+		if !ok {
+			return
+		}
+
+		opts, err := ParseListOptions(r, webhookDeliverySortFields)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if r.FormValue("sort") == "" {
+			opts.Sort = "created_at"
+		}
+		if opts.Limit == 0 {
+			opts.Limit = defaultPageLimit
+		}
+		if v := r.FormValue("status"); v != "" {
+			opts.Filters["status"] = v
+		}
+
+		deliveries, total, err := s.Webhooks().FindDeliveries(webhook.ID, opts)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		RespondWithJSON(w, http.StatusOK, NewListResponse(deliveries, total, opts))
+	}
+}
+
+// findWebhook finds the webhook of the route, the response is sent when it
+// can't be found
+func findWebhook(s storage.Store, w http.ResponseWriter, r *http.Request, admin bool) (*model.Webhook, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+
+	userID, _ := r.Context().Value("user_id").(uint)
+	webhook, err := app.FindWebhook(s, uint(id), userID, admin)
+	if err != nil {
+		RespondWithError(w, http.StatusNotFound, err.Error())
+		return nil, false
+	}
+	return webhook, true
+}
+
+// decodeWebhookDTO decodes and validates the webhook payload, the response is
+// sent when it is invalid
+func decodeWebhookDTO(w http.ResponseWriter, r *http.Request) (*model.WebhookDTO, bool) {
+	dto := new(model.WebhookDTO)
+	if err := json.NewDecoder(r.Body).Decode(dto); err != nil {
+		RespondWithError(w, requestErrorStatus(err), InvalidJSON)
+		return nil, false
+	}
+	defer r.Body.Close()
+
+	if err := app.PayloadValidator(dto); err != nil {
+		errs := GetErrors(err.(validator.ValidationErrors))
+		RespondWithErrors(w, http.StatusBadRequest, InvalidRequestPayload, errs)
+		return nil, false
+	}
+	return dto, true
+}
+
+// webhookErrorStatus is the response status of the webhook validation errors
+func webhookErrorStatus(err error) int {
+	if errors.Is(err, app.ErrWebhookURL) || errors.Is(err, app.ErrWebhookEvent) {
+		return http.StatusBadRequest
+	}
+	return updateErrorStatus(err)
+}
@@ -0,0 +1,157 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/config"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// Activity feed event types
+const (
+	ActivityUserSignedUp        = "user.signed_up"
+	ActivityUserCreated         = "user.created"
+	ActivityUserDeleted         = "user.deleted"
+	ActivityDeletionScheduled   = "user.deletion_scheduled"
+	ActivityDeletionCancelled   = "user.deletion_cancelled"
+	ActivityUserPurged          = "user.purged"
+	ActivityUserImpersonated    = "user.impersonated"
+	ActivitySubscriptionChanged = "subscription.changed"
+	ActivityFailedSigninSpike   = "signin.failed_spike"
+)
+
+const (
+	// spikePeriod is the period the failed signins are counted in
+	spikePeriod = time.Hour
+	// defaultFailedSignins is the spike threshold when the configuration has none
+	defaultFailedSignins = 20
+)
+
+// activitySource is the audit action of the users table shown in the feed as the type
+type activitySource struct {
+	action       string
+	activityType string
+}
+
+// activitySources are the audit events of the feed, the spikes are found separately
+var activitySources = []activitySource{
+	{AuditActionSignup, ActivityUserSignedUp},
+	{AuditActionCreate, ActivityUserCreated},
+	{AuditActionDelete, ActivityUserDeleted},
+	{AuditActionDeletion, ActivityDeletionScheduled},
+	{AuditActionCancelDelete, ActivityDeletionCancelled},
+	{AuditActionPurge, ActivityUserPurged},
+	{AuditActionSubscription, ActivitySubscriptionChanged},
+	{AuditActionGrant, ActivitySubscriptionChanged},
+	{AuditActionExtendGrant, ActivitySubscriptionChanged},
+	{AuditActionRevokeGrant, ActivitySubscriptionChanged},
+	{AuditActionImpersonate, ActivityUserImpersonated},
+}
+
+// ActivityTypes returns the event types of the activity feed
+func ActivityTypes() []string {
+	types := []string{}
+	for _, source := range activitySources {
+		// Some types have more than one action
+		if FindIndex(types, source.activityType) < 0 {
+			types = append(types, source.activityType)
+		}
+	}
+	return append(types, ActivityFailedSigninSpike)
+}
+
+// FindActivity returns the newest events of the instance in the period, up to
+// the limit. Types limits the events to the given types, empty returns all.
+func FindActivity(s storage.Store, period model.AuditPeriod, limit int, types []string) ([]model.ActivityEvent, error) {
+	wanted := func(activityType string) bool {
+		return len(types) == 0 || FindIndex(types, activityType) >= 0
+	}
+	feed := []model.ActivityEvent{}
+
+	for _, source := range activitySources {
+		if !wanted(source.activityType) {
+			continue
+		}
+		opts := &model.ListOptions{
+			Limit:     limit,
+			Sort:      "created_at",
+			Direction: "desc",
+			Filters:   map[string]interface{}{"action": source.action, "item_type": "users"},
+		}
+		events, _, err := s.AuditEvents().FindAll(opts, period)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			feed = append(feed, model.ActivityEvent{
+				Type:      source.activityType,
+				CreatedAt: event.CreatedAt,
+				UserID:    event.ItemID,
+				ActorID:   event.UserID,
+				IP:        event.IP,
+				Country:   event.Country,
+			})
+		}
+	}
+
+	if wanted(ActivityFailedSigninSpike) {
+		failed, err := s.AuditEvents().FindByAction(AuditActionSigninFailed, period)
+		if err != nil {
+			return nil, err
+		}
+		threshold := config.Current().Anomaly.FailedSignins
+		if threshold <= 0 {
+			threshold = defaultFailedSignins
+		}
+		feed = append(feed, FailedSigninSpikes(failed, threshold)...)
+	}
+
+	sort.SliceStable(feed, func(i, j int) bool { return feed[i].CreatedAt.After(feed[j].CreatedAt) })
+	if limit > 0 && len(feed) > limit {
+		feed = feed[:limit]
+	}
+	return feed, nil
+}
+
+// FailedSigninSpikes groups the failed signins by hour and returns the hours
+// having at least threshold of them, at the start of the hour
+func FailedSigninSpikes(failed []model.AuditEvent, threshold int) []model.ActivityEvent {
+	type bucket struct {
+		count int
+		users map[uint]bool
+		ips   map[string]bool
+	}
+	buckets := map[time.Time]*bucket{}
+	starts := []time.Time{}
+
+	for _, event := range failed {
+		start := event.CreatedAt.UTC().Truncate(spikePeriod)
+		b, ok := buckets[start]
+		if !ok {
+			b = &bucket{users: map[uint]bool{}, ips: map[string]bool{}}
+			buckets[start] = b
+			starts = append(starts, start)
+		}
+		b.count++
+		b.users[event.UserID] = true
+		b.ips[event.IP] = true
+	}
+
+	spikes := []model.ActivityEvent{}
+	for _, start := range starts {
+		b := buckets[start]
+		if b.count < threshold {
+			continue
+		}
+		spikes = append(spikes, model.ActivityEvent{
+			Type:      ActivityFailedSigninSpike,
+			CreatedAt: start,
+			Count:     b.count,
+			Details:   fmt.Sprintf("%d failed signins for %d users from %d IPs in an hour", b.count, len(b.users), len(b.ips)),
+		})
+	}
+	return spikes
+}
@@ -0,0 +1,29 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailedSigninSpikes(t *testing.T) {
+	hour := time.Date(2026, 3, 8, 10, 0, 0, 0, time.UTC)
+	failed := []model.AuditEvent{}
+	for i := 0; i < 4; i++ {
+		failed = append(failed, model.AuditEvent{CreatedAt: hour.Add(time.Duration(i) * time.Minute), UserID: uint(i % 2), IP: "10.0.0.1"})
+	}
+	failed = append(failed, model.AuditEvent{CreatedAt: hour.Add(time.Hour), UserID: 1, IP: "10.0.0.2"})
+
+	spikes := FailedSigninSpikes(failed, 3)
+	if assert.Len(t, spikes, 1) {
+		assert.Equal(t, ActivityFailedSigninSpike, spikes[0].Type)
+		assert.Equal(t, hour, spikes[0].CreatedAt)
+		assert.Equal(t, 4, spikes[0].Count)
+		assert.Equal(t, "4 failed signins for 2 users from 1 IPs in an hour", spikes[0].Details)
+	}
+
+	assert.Empty(t, FailedSigninSpikes(failed, 5))
+	assert.Empty(t, FailedSigninSpikes(nil, 1))
+}
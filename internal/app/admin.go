@@ -0,0 +1,289 @@
+package app
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+var (
+	// ErrSamePassphrase represents message for rotations to the current passphrase
+	ErrSamePassphrase = errors.New("new passphrase must be different from the current one")
+	// ErrShortPassphrase represents message for weak server passphrases
+	ErrShortPassphrase = fmt.Errorf("passphrase must be at least %d characters", minSecureKeyLength)
+	// ErrPasswordChangeRequired represents message for the requests of users
+	// who must change the master password first
+	ErrPasswordChangeRequired = errors.New("master password must be changed before using the vault")
+)
+
+// DisableUser disables the user and deletes its tokens, disabled users can't
+// sign in or use the tokens they have
+func DisableUser(s storage.Store, email string) (*model.User, error) {
+	user, err := s.Users().FindByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("user %s: %w", email, err)
+	}
+	if user.DisabledAt == nil {
+		now := time.Now()
+		user.DisabledAt = &now
+	}
+	if user, err = s.Users().Update(user); err != nil {
+		return nil, err
+	}
+	s.Tokens().Delete(int(user.ID))
+	return user, nil
+}
+
+// EnableUser enables the disabled user again
+func EnableUser(s storage.Store, email string) (*model.User, error) {
+	user, err := s.Users().FindByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("user %s: %w", email, err)
+	}
+	user.DisabledAt = nil
+	return s.Users().Update(user)
+}
+
+// SetMasterPasswordReset sets whether the user is asked to change the master
+// password after signing in, the vault can't be used until it is changed.
+// Changing the master password clears it.
+func SetMasterPasswordReset(s storage.Store, email string, required bool) (*model.User, error) {
+	user, err := s.Users().FindByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("user %s: %w", email, err)
+	}
+	user.MasterPasswordReset = required
+	return s.Users().Update(user)
+}
+
+// RotatePassphrase wraps the data key of every user and organization vault
+// with the new passphrase and rebuilds the search indexes, then the process
+// uses the new passphrase. Fields still encrypted with the old passphrase are
+// moved to the data key of their vault. It returns the number of updated
+// records. Each vault is rotated in a transaction and keys and fields already
+// rotated are kept, so a rotation which failed half way can be run again.
+func RotatePassphrase(s storage.Store, oldPassphrase, newPassphrase string) (int, error) {
+	if len(newPassphrase) < minSecureKeyLength {
+		return 0, ErrShortPassphrase
+	}
+	if oldPassphrase == newPassphrase {
+		return 0, ErrSamePassphrase
+	}
+	// Only the newest data keys of the vaults are wrapped again
+	if unfinished, err := keyRotationUnfinished(s); err != nil || unfinished {
+		if err == nil {
+			err = ErrKeyRotationUnfinished
+		}
+		return 0, err
+	}
+
+	targets, err := vaultMigrationTargets(s)
+	if err != nil {
+		return 0, err
+	}
+
+	// Data keys and the indexes without an index key use the passphrase in use
+	viper.Set("server.passphrase", newPassphrase)
+
+	updated := 0
+	for _, target := range targets {
+		count := 0
+		err := target.store.WithTransaction(func(tx storage.Store) error {
+			var err error
+			count, err = rotateVault(tx, target.schema, oldPassphrase, newPassphrase)
+			return err
+		})
+		if err != nil {
+			return updated, fmt.Errorf("schema %s: %w", target.schema, err)
+		}
+		updated += count
+	}
+	return updated, saveIndexKeyID(s)
+}
+
+func rotateVault(s storage.Store, schema, oldPassphrase, newPassphrase string) (int, error) {
+	keyID, key, err := rotateDataKey(s, schema, oldPassphrase, newPassphrase)
+	if err != nil {
+		return 0, err
+	}
+	// Logins are decrypted for their indexes before the new wrap is committed
+	keepDataKey(keyID, schema, key)
+
+	updated := 0
+	err = walkVault(s, schema, func(item interface{}, update func() error) error {
+		changed, err := rotateModel(item, schema, oldPassphrase, newPassphrase, keyID, key)
+		if err != nil {
+			return err
+		}
+		// Indexes derived from the passphrase change with it even without encrypted fields
+		if login, ok := item.(*model.Login); ok {
+			indexed, err := setStoredLoginSearchIndex(login, schema)
+			if err != nil {
+				return err
+			}
+			changed = changed || indexed
+		}
+		if !changed {
+			return nil
+		}
+		if err := update(); err != nil {
+			return err
+		}
+		updated++
+		return nil
+	})
+	return updated, err
+}
+
+// walkVault calls the function with every record of the vault which has
+// encrypted fields and the function saving it, the histories of a login follow it
+func walkVault(s storage.Store, schema string, fn func(item interface{}, update func() error) error) error {
+	logins, err := s.Logins().All(schema)
+	if err != nil {
+		return err
+	}
+	for i := range logins {
+		login := &logins[i]
+		err := fn(login, func() error {
+			_, err := s.Logins().Update(login, schema)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("login %d: %w", login.ID, err)
+		}
+
+		histories, err := s.Logins().Histories(login.ID, schema)
+		if err != nil {
+			return err
+		}
+		for j := range histories {
+			history := &histories[j]
+			err := fn(history, func() error {
+				_, err := s.Logins().UpdateHistory(history, schema)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("login history %d: %w", history.ID, err)
+			}
+		}
+	}
+
+	cards, err := s.CreditCards().All(schema)
+	if err != nil {
+		return err
+	}
+	for i := range cards {
+		card := &cards[i]
+		err := fn(card, func() error {
+			_, err := s.CreditCards().Update(card, schema)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("credit card %d: %w", card.ID, err)
+		}
+	}
+
+	accounts, err := s.BankAccounts().All(schema)
+	if err != nil {
+		return err
+	}
+	for i := range accounts {
+		account := &accounts[i]
+		err := fn(account, func() error {
+			_, err := s.BankAccounts().Update(account, schema)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("bank account %d: %w", account.ID, err)
+		}
+	}
+
+	notes, err := s.Notes().All(schema)
+	if err != nil {
+		return err
+	}
+	for i := range notes {
+		note := &notes[i]
+		err := fn(note, func() error {
+			_, err := s.Notes().Update(note, schema)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("note %d: %w", note.ID, err)
+		}
+	}
+
+	emails, err := s.Emails().All(schema)
+	if err != nil {
+		return err
+	}
+	for i := range emails {
+		email := &emails[i]
+		err := fn(email, func() error {
+			_, err := s.Emails().Update(email, schema)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("email %d: %w", email.ID, err)
+		}
+	}
+
+	servers, err := s.Servers().All(schema)
+	if err != nil {
+		return err
+	}
+	for i := range servers {
+		server := &servers[i]
+		err := fn(server, func() error {
+			_, err := s.Servers().Update(server, schema)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("server %d: %w", server.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// rotateModel encrypts the fields of the struct pointer of the vault of the
+// schema still encrypted with a passphrase with the data key and reports whether a field is changed. Fields
+// encrypted with a data key or by the clients are kept. Fields which can't be decrypted with
+// either passphrase fail the rotation instead of being encrypted twice.
+func rotateModel(rawModel interface{}, schema, oldPassphrase, newPassphrase, keyID string, key []byte) (bool, error) {
+	scope := modelScope(rawModel, schema)
+	value := reflect.ValueOf(rawModel).Elem()
+	changed := false
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Tag.Get("encrypt") != "true" || value.Field(i).String() == "" || isDataKeyField(value.Field(i).String()) || IsClientCiphertext(value.Field(i).String()) {
+			continue
+		}
+
+		encrypted, err := base64.StdEncoding.DecodeString(value.Field(i).String())
+		if err != nil {
+			return false, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		decrypted, err := Decrypt(string(encrypted), oldPassphrase)
+		if err != nil {
+			if decrypted, err = Decrypt(string(encrypted), newPassphrase); err != nil {
+				return false, fmt.Errorf("field %s can't be decrypted with the current passphrase", field.Name)
+			}
+		}
+
+		rotated, err := sealField(string(decrypted), scope.withField(field), keyID, key)
+		if err != nil {
+			return false, err
+		}
+		value.Field(i).SetString(rotated)
+		changed = true
+	}
+	return changed, nil
+}
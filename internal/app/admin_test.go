@@ -0,0 +1,70 @@
+package app
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encryptedWith(t *testing.T, value, passphrase string) string {
+	encrypted, err := Encrypt(value, passphrase)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(encrypted)
+}
+
+func TestRotateModel(t *testing.T) {
+	defer SetDataKeyStore(nil)
+	oldPassphrase, newPassphrase := "old-passphrase", "new-passphrase"
+	s := newFakeDataKeyStore()
+	SetDataKeyStore(s)
+	keyID, key, err := VaultDataKey(s, "user_rotate_model")
+	require.NoError(t, err)
+	note := &model.Note{ID: 1, Title: "Wifi", Note: encryptedWith(t, "secret note", oldPassphrase)}
+
+	changed, err := rotateModel(note, "user_rotate_model", oldPassphrase, newPassphrase, keyID, key)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "Wifi", note.Title)
+	assert.True(t, isDataKeyField(note.Note))
+	decrypted := *note
+	_, err = DecryptModel(&decrypted, "user_rotate_model")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret note", decrypted.Note)
+
+	// Running the rotation again keeps the rotated fields
+	rotated := note.Note
+	changed, err = rotateModel(note, "user_rotate_model", oldPassphrase, newPassphrase, keyID, key)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, rotated, note.Note)
+
+	// Fields encrypted with the new passphrase are moved to the data key too
+	note = &model.Note{ID: 2, Note: encryptedWith(t, "secret note", newPassphrase)}
+	changed, err = rotateModel(note, "user_rotate_model", oldPassphrase, newPassphrase, keyID, key)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.True(t, isDataKeyField(note.Note))
+
+	changed, err = rotateModel(&model.Note{}, "user_rotate_model", oldPassphrase, newPassphrase, keyID, key)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestRotateModelUnknownPassphrase(t *testing.T) {
+	note := &model.Note{ID: 1, Note: encryptedWith(t, "secret note", "another-passphrase")}
+	encrypted := note.Note
+
+	_, err := rotateModel(note, "user_rotate_model", "old-passphrase", "new-passphrase", "rotate", make([]byte, dataKeyLength))
+	assert.Error(t, err)
+	assert.Equal(t, encrypted, note.Note)
+}
+
+func TestRotatePassphraseChecksPassphrase(t *testing.T) {
+	_, err := RotatePassphrase(nil, "old-passphrase", "short")
+	assert.ErrorIs(t, err, ErrShortPassphrase)
+	_, err = RotatePassphrase(nil, "old-passphrase", "old-passphrase")
+	assert.ErrorIs(t, err, ErrSamePassphrase)
+}
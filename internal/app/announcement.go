@@ -0,0 +1,67 @@
+package app
+
+import (
+	"errors"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// Severities of the announcements
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+var (
+	// ErrAnnouncementNotFound is returned for the announcements which don't exist
+	ErrAnnouncementNotFound = errors.New("announcement not found")
+	// ErrAnnouncementPeriod is returned for the announcements ending before they start
+	ErrAnnouncementPeriod = errors.New("announcement must end after it starts")
+)
+
+// CreateAnnouncement publishes the announcement, it starts at the time
+// without a start time
+func CreateAnnouncement(s storage.Store, dto *model.AnnouncementDTO, now time.Time) (*model.Announcement, error) {
+	announcement := new(model.Announcement)
+	if err := applyAnnouncementDTO(announcement, dto, now); err != nil {
+		return nil, err
+	}
+	return s.Announcements().Create(announcement)
+}
+
+// UpdateAnnouncement changes the announcement
+func UpdateAnnouncement(s storage.Store, announcement *model.Announcement, dto *model.AnnouncementDTO, now time.Time) (*model.Announcement, error) {
+	if err := applyAnnouncementDTO(announcement, dto, now); err != nil {
+		return nil, err
+	}
+	return s.Announcements().Update(announcement)
+}
+
+// FindAnnouncement finds the announcement with the ID
+func FindAnnouncement(s storage.Store, id uint) (*model.Announcement, error) {
+	announcement, err := s.Announcements().FindByID(id)
+	if err != nil {
+		return nil, ErrAnnouncementNotFound
+	}
+	return announcement, nil
+}
+
+// applyAnnouncementDTO sets the fields of the announcement from the payload
+func applyAnnouncementDTO(announcement *model.Announcement, dto *model.AnnouncementDTO, now time.Time) error {
+	startsAt := now
+	if dto.StartsAt != nil {
+		startsAt = *dto.StartsAt
+	}
+	if dto.EndsAt != nil && !dto.EndsAt.After(startsAt) {
+		return ErrAnnouncementPeriod
+	}
+
+	announcement.Message = dto.Message
+	announcement.Severity = dto.Severity
+	announcement.StartsAt = startsAt
+	announcement.EndsAt = dto.EndsAt
+	return nil
+}
@@ -0,0 +1,30 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/passwall/passwall-server/model"
+)
+
+func TestApplyAnnouncementDTO(t *testing.T) {
+	now := time.Now()
+	announcement := new(model.Announcement)
+
+	// Announcements without a start time start right away
+	dto := &model.AnnouncementDTO{Message: "Planned maintenance", Severity: SeverityWarning}
+	assert.NoError(t, applyAnnouncementDTO(announcement, dto, now))
+	assert.Equal(t, now, announcement.StartsAt)
+	assert.Nil(t, announcement.EndsAt)
+
+	startsAt, endsAt := now.Add(time.Hour), now.Add(2*time.Hour)
+	dto.StartsAt, dto.EndsAt = &startsAt, &endsAt
+	assert.NoError(t, applyAnnouncementDTO(announcement, dto, now))
+	assert.Equal(t, startsAt, announcement.StartsAt)
+	assert.Equal(t, &endsAt, announcement.EndsAt)
+
+	dto.EndsAt = &startsAt
+	assert.Equal(t, ErrAnnouncementPeriod, applyAnnouncementDTO(announcement, dto, now))
+}
@@ -0,0 +1,276 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/config"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/cache"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+// Security alert types
+const (
+	AlertImpossibleTravel = "impossible_travel"
+	AlertMassExport       = "mass_export"
+	AlertMassDeletion     = "mass_deletion"
+)
+
+const (
+	// anomalyBatch is the number of audit events checked at once
+	anomalyBatch = 500
+	// minTravelDistance ignores the signins GeoIP can't reliably tell apart, in km
+	minTravelDistance = 500
+	// earthRadius is the mean radius of the Earth in km
+	earthRadius = 6371.0
+)
+
+// exportActions are the audit actions taking the vault out of the server
+var exportActions = []string{AuditActionExport, AuditActionExportBackup, AuditActionExportKeePass, AuditActionExportCSV}
+
+// lastAnalyzedEvent is the ID of the last audit event checked for anomalies
+var lastAnalyzedEvent uint
+
+// burst is the alert type of a user whose actions are counted in the window
+type burst struct {
+	userID    uint
+	alertType string
+}
+
+// StartAnomalyDetection checks the new audit events for suspicious patterns
+// every minute. The events of the last window are checked again after a
+// restart, the alerts already raised for them aren't repeated.
+func StartAnomalyDetection(s storage.Store, c cache.Cache) {
+	window := time.Duration(config.Current().Anomaly.Window) * time.Minute
+	to := time.Now().Add(-window)
+	opts := &model.ListOptions{Limit: 1, Sort: "id", Direction: "desc", Filters: map[string]interface{}{}}
+	if events, _, err := s.AuditEvents().FindAll(opts, model.AuditPeriod{To: &to}); err == nil && len(events) > 0 {
+		lastAnalyzedEvent = events[0].ID
+	}
+
+	every(s, time.Minute, func() { DetectAnomalies(s, c) })
+}
+
+// DetectAnomalies checks the audit events recorded since the last run, it does
+// nothing when the anomaly detection is disabled
+func DetectAnomalies(s storage.Store, c cache.Cache) {
+	cfg := config.Current().Anomaly
+	if !cfg.Enabled {
+		return
+	}
+
+	for {
+		opts := &model.ListOptions{
+			Limit:     anomalyBatch,
+			Cursor:    lastAnalyzedEvent,
+			Sort:      "id",
+			Direction: "asc",
+			Filters:   map[string]interface{}{},
+		}
+		events, _, err := s.AuditEvents().FindAll(opts, model.AuditPeriod{})
+		if err != nil {
+			logger.Errorf("can't find audit events for anomaly detection error: %v", err)
+			return
+		}
+		if len(events) == 0 {
+			return
+		}
+
+		checkAnomalies(s, c, events, cfg)
+		lastAnalyzedEvent = events[len(events)-1].ID
+		if len(events) < anomalyBatch {
+			return
+		}
+	}
+}
+
+// checkAnomalies checks every signin for impossible travel, and the exports and
+// deletions of each user in the window of the last one in the events
+func checkAnomalies(s storage.Store, c cache.Cache, events []model.AuditEvent, cfg config.AnomalyConfiguration) {
+	window := time.Duration(cfg.Window) * time.Minute
+	latest := map[burst]*model.AuditEvent{}
+
+	for i := range events {
+		event := &events[i]
+		if event.UserID == 0 {
+			continue
+		}
+		switch {
+		case event.Action == AuditActionSignin:
+			checkTravel(s, c, event, cfg)
+		case FindIndex(exportActions, event.Action) >= 0:
+			latest[burst{event.UserID, AlertMassExport}] = event
+		case event.Action == AuditActionDelete:
+			latest[burst{event.UserID, AlertMassDeletion}] = event
+		}
+	}
+
+	for key, event := range latest {
+		actions, limit, noun := exportActions, cfg.MaxExports, "exports"
+		if key.alertType == AlertMassDeletion {
+			actions, limit, noun = []string{AuditActionDelete}, cfg.MaxDeletions, "deletions"
+		}
+
+		count, err := countActions(s, event.UserID, actions, event.CreatedAt.Add(-window), event.CreatedAt)
+		if err != nil || count < int64(limit) {
+			continue
+		}
+		raiseAlert(s, c, event, key.alertType, fmt.Sprintf("%d %s in %d minutes", count, noun, cfg.Window), cfg)
+	}
+}
+
+// checkTravel compares the place of the signin with the previous signin of the user
+func checkTravel(s storage.Store, c cache.Cache, event *model.AuditEvent, cfg config.AnomalyConfiguration) {
+	latitude, longitude, ok := Coordinates(event.IP)
+	if !ok {
+		return
+	}
+
+	opts := &model.ListOptions{
+		Limit:     1,
+		Sort:      "id",
+		Direction: "desc",
+		Filters:   map[string]interface{}{"user_id": event.UserID, "action": AuditActionSignin},
+	}
+	previous, _, err := s.AuditEvents().FindAll(opts, model.AuditPeriod{To: &event.CreatedAt})
+	if err != nil || len(previous) == 0 || previous[0].IP == event.IP {
+		return
+	}
+	prevLatitude, prevLongitude, ok := Coordinates(previous[0].IP)
+	if !ok {
+		return
+	}
+
+	distance := Distance(prevLatitude, prevLongitude, latitude, longitude)
+	elapsed := event.CreatedAt.Sub(previous[0].CreatedAt)
+	if !ImpossibleTravel(distance, elapsed, cfg.TravelSpeed) {
+		return
+	}
+	details := fmt.Sprintf("signed in from %s and %s, %.0f km apart, in %s",
+		place(&previous[0]), place(event), distance, elapsed.Round(time.Second))
+	raiseAlert(s, c, event, AlertImpossibleTravel, details, cfg)
+}
+
+// countActions counts the audit events of the user with the actions in the period
+func countActions(s storage.Store, userID uint, actions []string, from, to time.Time) (int64, error) {
+	// The period excludes its end, the last event must be counted
+	to = to.Add(time.Second)
+
+	var count int64
+	for _, action := range actions {
+		opts := &model.ListOptions{
+			Limit:     1,
+			Sort:      "id",
+			Direction: "asc",
+			Filters:   map[string]interface{}{"user_id": userID, "action": action},
+		}
+		_, total, err := s.AuditEvents().FindAll(opts, model.AuditPeriod{From: &from, To: &to})
+		if err != nil {
+			return 0, err
+		}
+		count += total
+	}
+	return count, nil
+}
+
+// raiseAlert stores the alert of the event and notifies the user, the sessions
+// of the user are revoked when it is configured. Users get one alert of a type
+// in a window.
+func raiseAlert(s storage.Store, c cache.Cache, event *model.AuditEvent, alertType, details string, cfg config.AnomalyConfiguration) {
+	since := time.Now().Add(-time.Duration(cfg.Window) * time.Minute)
+	exists, err := s.Alerts().ExistsSince(event.UserID, alertType, since)
+	if err != nil || exists {
+		return
+	}
+
+	alert := &model.SecurityAlert{
+		UserID:  event.UserID,
+		Type:    alertType,
+		Details: details,
+		EventID: event.ID,
+	}
+	if cfg.RevokeSessions {
+		if err := RevokeUserSessions(s, c, event.UserID); err != nil {
+			logger.Errorf("can't revoke sessions of user %d error: %v", event.UserID, err)
+		} else {
+			alert.SessionsRevoked = true
+		}
+	}
+	if cfg.ForceChange {
+		forcePasswordChange(s, event.UserID)
+	}
+	if _, err := s.Alerts().Create(alert); err != nil {
+		return
+	}
+
+	logger.Warnf("security alert %s for user %d: %s", alertType, event.UserID, details)
+	notifySecurityAlert(s, alert)
+	DispatchWebhookEvent(s, WebhookEventSecurityAlert, alert.UserID, alert)
+}
+
+// forcePasswordChange asks the user of the alert to change the master
+// password, the vault can't be used until it is changed
+func forcePasswordChange(s storage.Store, userID uint) {
+	user, err := s.Users().FindByID(userID)
+	if err != nil {
+		return
+	}
+	user.MasterPasswordReset = true
+	if _, err := s.Users().Update(user); err != nil {
+		logger.Errorf("can't force password change of user %d error: %v", userID, err)
+	}
+}
+
+func notifySecurityAlert(s storage.Store, alert *model.SecurityAlert) {
+	user, err := s.Users().FindByID(alert.UserID)
+	if err != nil {
+		return
+	}
+
+	data := map[string]interface{}{"Details": alert.Details, "SessionsRevoked": alert.SessionsRevoked}
+	if err := SendUserMail(s, user, EmailSecurityAlert, data); err != nil {
+		logger.Errorf("can't send email to %s error: %v\n", user.Email, err)
+	}
+}
+
+// ImpossibleTravel checks if going the distance in km in the elapsed time needs
+// a speed over the max speed in km/h. Short distances are never impossible since
+// GeoIP places nearby cities and mobile networks loosely.
+func ImpossibleTravel(distance float64, elapsed time.Duration, maxSpeed int) bool {
+	if distance < minTravelDistance {
+		return false
+	}
+	if elapsed <= 0 {
+		return true
+	}
+	return distance/elapsed.Hours() > float64(maxSpeed)
+}
+
+// Distance returns the great circle distance between the coordinates in km
+func Distance(latitude1, longitude1, latitude2, longitude2 float64) float64 {
+	radians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+	dLatitude := radians(latitude2 - latitude1)
+	dLongitude := radians(longitude2 - longitude1)
+
+	a := math.Sin(dLatitude/2)*math.Sin(dLatitude/2) +
+		math.Cos(radians(latitude1))*math.Cos(radians(latitude2))*math.Sin(dLongitude/2)*math.Sin(dLongitude/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+// place returns the city and the country of the event, or its IP when it wasn't located
+func place(event *model.AuditEvent) string {
+	parts := []string{}
+	for _, part := range []string{event.City, event.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return event.IP
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistance(t *testing.T) {
+	// London to Paris and New York
+	assert.InDelta(t, 344, Distance(51.5074, -0.1278, 48.8566, 2.3522), 5)
+	assert.InDelta(t, 5570, Distance(51.5074, -0.1278, 40.7128, -74.0060), 20)
+	assert.Zero(t, Distance(41.0082, 28.9784, 41.0082, 28.9784))
+}
+
+func TestImpossibleTravel(t *testing.T) {
+	tests := []struct {
+		name     string
+		distance float64
+		elapsed  time.Duration
+		expected bool
+	}{
+		{name: "Nearby cities", distance: 300, elapsed: time.Minute, expected: false},
+		{name: "Distant within minutes", distance: 5570, elapsed: 10 * time.Minute, expected: true},
+		{name: "Distant by plane", distance: 5570, elapsed: 8 * time.Hour, expected: false},
+		{name: "Distant at once", distance: 1000, elapsed: 0, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ImpossibleTravel(tt.distance, tt.elapsed, 900))
+		})
+	}
+}
+
+func TestPlace(t *testing.T) {
+	assert.Equal(t, "London, GB", place(&model.AuditEvent{IP: "81.2.69.160", City: "London", Country: "GB"}))
+	assert.Equal(t, "GB", place(&model.AuditEvent{IP: "81.2.69.160", Country: "GB"}))
+	assert.Equal(t, "81.2.69.160", place(&model.AuditEvent{IP: "81.2.69.160"}))
+}
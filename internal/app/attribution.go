@@ -0,0 +1,20 @@
+package app
+
+import (
+	"strconv"
+)
+
+// Kinds of the principals the changes of the vault rows are attributed to
+const (
+	// PrincipalUser is the user signed in to the vault
+	PrincipalUser = "user"
+	// PrincipalAdmin is the admin impersonating the user
+	PrincipalAdmin = "admin"
+	// PrincipalImport is the import job saving the items in the background
+	PrincipalImport = "import"
+)
+
+// Principal returns the principal of the kind with the ID, like user:1
+func Principal(kind string, id uint) string {
+	return kind + ":" + strconv.FormatUint(uint64(id), 10)
+}
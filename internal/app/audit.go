@@ -0,0 +1,107 @@
+package app
+
+import (
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// Audit event actions
+const (
+	AuditActionCreate        = "create"
+	AuditActionUpdate        = "update"
+	AuditActionDelete        = "delete"
+	AuditActionView          = "view"
+	AuditActionSignin        = "signin"
+	AuditActionSigninFailed  = "signin_failed"
+	AuditActionSignout       = "signout"
+	AuditActionImport        = "import"
+	AuditActionExport        = "export"
+	AuditActionExportBackup  = "export_backup"
+	AuditActionExportKeePass = "export_keepass"
+	AuditActionExportCSV     = "export_csv"
+	AuditActionShare         = "share"
+	AuditActionUnshare       = "unshare"
+	AuditActionSignup        = "signup"
+	AuditActionDeletion      = "schedule_deletion"
+	AuditActionCancelDelete  = "cancel_deletion"
+	AuditActionPurge         = "purge"
+	AuditActionSubscription  = "change_subscription"
+	AuditActionGrant         = "grant_subscription"
+	AuditActionExtendGrant   = "extend_subscription"
+	AuditActionRevokeGrant   = "revoke_subscription"
+	AuditActionForceChange   = "force_password_change"
+	AuditActionImpersonate   = "impersonate"
+	AuditActionGrantSupport  = "grant_support_consent"
+	AuditActionRevokeSupport = "revoke_support_consent"
+	AuditActionSupportAccess = "support_access"
+	AuditActionRestore       = "restore"
+	AuditActionKeyRotation   = "key_rotation"
+	AuditActionCreateToken   = "create_token"
+	AuditActionRevokeAll     = "revoke_sessions"
+	AuditActionTrustDevice   = "trust_device"
+	AuditActionRevokeDevice  = "revoke_device"
+	AuditActionChangeEmail   = "change_email"
+	AuditActionAbortEmail    = "abort_email_change"
+)
+
+// AuditForwarder passes the recorded audit events on, like to a SIEM
+type AuditForwarder interface {
+	Forward(event model.AuditEvent)
+}
+
+// auditForwarder gets the events after they are stored, nil keeps them only in the database
+var auditForwarder AuditForwarder
+
+// SetAuditForwarder sets the forwarder of the audit events, it is set at startup
+func SetAuditForwarder(forwarder AuditForwarder) {
+	auditForwarder = forwarder
+}
+
+// GeoLocator finds where the IP addresses are
+type GeoLocator interface {
+	// Locate returns the country and the city of the IP
+	Locate(ip string) (country, city string)
+	// Coordinates returns the latitude and the longitude of the IP
+	Coordinates(ip string) (latitude, longitude float64, ok bool)
+}
+
+// geoLocator annotates the audit events with their location, nil leaves them without it
+var geoLocator GeoLocator
+
+// SetGeoLocator sets the locator of the IP addresses, it is set at startup
+// when a GeoIP database is configured
+func SetGeoLocator(locator GeoLocator) {
+	geoLocator = locator
+}
+
+// Locate returns the country and the city of the IP, empty when GeoIP isn't configured
+func Locate(ip string) (country, city string) {
+	if geoLocator == nil || ip == "" {
+		return "", ""
+	}
+	return geoLocator.Locate(ip)
+}
+
+// Coordinates returns the latitude and the longitude of the IP, ok is false
+// when GeoIP isn't configured or the IP can't be located
+func Coordinates(ip string) (latitude, longitude float64, ok bool) {
+	if geoLocator == nil || ip == "" {
+		return 0, 0, false
+	}
+	return geoLocator.Coordinates(ip)
+}
+
+// RecordAuditEvent stores the audit event with the location of its IP.
+// Sensitive operations should not go on when their audit event can't be recorded.
+func RecordAuditEvent(s storage.Store, event *model.AuditEvent) error {
+	if event.Country == "" {
+		event.Country, event.City = Locate(event.IP)
+	}
+	if _, err := s.AuditEvents().Create(event); err != nil {
+		return err
+	}
+	if auditForwarder != nil {
+		auditForwarder.Forward(*event)
+	}
+	return nil
+}
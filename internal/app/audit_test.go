@@ -0,0 +1,39 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeLocation struct {
+	country, city       string
+	latitude, longitude float64
+}
+
+type fakeLocator map[string]fakeLocation
+
+func (l fakeLocator) Locate(ip string) (country, city string) {
+	return l[ip].country, l[ip].city
+}
+
+func (l fakeLocator) Coordinates(ip string) (latitude, longitude float64, ok bool) {
+	location, ok := l[ip]
+	return location.latitude, location.longitude, ok
+}
+
+func TestLocate(t *testing.T) {
+	defer SetGeoLocator(nil)
+
+	country, city := Locate("81.2.69.160")
+	assert.Empty(t, country)
+	assert.Empty(t, city)
+
+	SetGeoLocator(fakeLocator{"81.2.69.160": {country: "GB", city: "London"}})
+	country, city = Locate("81.2.69.160")
+	assert.Equal(t, "GB", country)
+	assert.Equal(t, "London", city)
+
+	country, _ = Locate("")
+	assert.Empty(t, country)
+}
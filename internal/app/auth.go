@@ -7,9 +7,11 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/passwall/passwall-server/internal/hsm"
+	"github.com/passwall/passwall-server/internal/storage"
 	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/cache"
 
-	uuid "github.com/satori/go.uuid"
 	"github.com/spf13/viper"
 )
 
@@ -20,21 +22,52 @@ var (
 	ErrUnauthorized = errors.New("unauthorized")
 )
 
+const (
+	// ScopeVaultRead allows reading vault items
+	ScopeVaultRead = "vault:read"
+	// ScopeVaultWrite allows creating, updating and deleting vault items
+	ScopeVaultWrite = "vault:write"
+	// ScopeVaultExport allows exporting the vault
+	ScopeVaultExport = "vault:export"
+	// ScopeAccount allows the account, its organizations, webhooks and tokens
+	ScopeAccount = "account"
+	// ScopeAdmin allows instance administration
+	ScopeAdmin = "admin"
+	// ScopeBilling allows managing subscriptions
+	ScopeBilling = "billing"
+	// ScopeSupport is the only scope of the admins impersonating a user
+	ScopeSupport = "support"
+)
+
+// Client types the users sign in with, claimed in the tokens of their sessions
+const (
+	ClientExtension = "extension"
+	ClientWeb       = "web"
+	ClientMobile    = "mobile"
+	ClientAPI       = "api"
+)
+
+// TokenTypeRefresh is the type claimed by the refresh tokens of the sessions,
+// the only tokens the sessions are refreshed with
+const TokenTypeRefresh = "refresh"
+
 // CreateToken ...
-func CreateToken(user *model.User) (*model.TokenDetailsDTO, error) {
+func CreateToken(user *model.User, policy model.SecurityPolicy, client string) (*model.TokenDetailsDTO, error) {
+	return CreateSessionToken(user, policy, client, time.Now())
+}
+
+// CreateSessionToken creates the tokens of the session the user signed in to
+// with the client type at the auth time, refreshed tokens keep the client and
+// the time of the signin and expire as the security policy tells
+func CreateSessionToken(user *model.User, policy model.SecurityPolicy, client string, authTime time.Time) (*model.TokenDetailsDTO, error) {
 
 	var err error
-	accessSecret := viper.GetString("server.secret")
 	td := &model.TokenDetailsDTO{}
 
-	accessTokenExpireDuration := resolveTokenExpireDuration(viper.GetString("server.accessTokenExpireDuration"))
-	refreshTokenExpireDuration := resolveTokenExpireDuration(viper.GetString("server.refreshTokenExpireDuration"))
-
-	td.AtExpiresTime = time.Now().Add(accessTokenExpireDuration)
-	td.RtExpiresTime = time.Now().Add(refreshTokenExpireDuration)
+	td.AtExpiresTime, td.RtExpiresTime = SessionExpiry(policy, client, authTime, time.Now())
 
-	td.AtUUID = uuid.NewV4()
-	td.RtUUID = uuid.NewV4()
+	td.AtUUID = NewUUID()
+	td.RtUUID = NewUUID()
 
 	//create access token
 	atClaims := jwt.MapClaims{}
@@ -47,8 +80,13 @@ func CreateToken(user *model.User) (*model.TokenDetailsDTO, error) {
 	atClaims["user_uuid"] = user.UUID.String()
 	atClaims["exp"] = td.AtExpiresTime.Unix()
 	atClaims["uuid"] = td.AtUUID.String()
-	at := jwt.NewWithClaims(jwt.SigningMethodHS256, atClaims)
-	td.AccessToken, err = at.SignedString([]byte(accessSecret))
+	atClaims["scopes"] = DefaultScopes(user)
+	atClaims["auth_time"] = authTime.Unix()
+	atClaims["epoch"] = user.TokenEpoch
+	if client != "" {
+		atClaims["client"] = client
+	}
+	td.AccessToken, err = signToken(atClaims)
 	if err != nil {
 		return nil, err
 	}
@@ -58,9 +96,14 @@ func CreateToken(user *model.User) (*model.TokenDetailsDTO, error) {
 	rtClaims["user_uuid"] = user.UUID.String()
 	rtClaims["exp"] = td.RtExpiresTime.Unix()
 	rtClaims["uuid"] = td.RtUUID.String()
+	rtClaims["auth_time"] = authTime.Unix()
+	rtClaims["epoch"] = user.TokenEpoch
+	rtClaims["type"] = TokenTypeRefresh
+	if client != "" {
+		rtClaims["client"] = client
+	}
 
-	rt := jwt.NewWithClaims(jwt.SigningMethodHS256, rtClaims)
-	td.RefreshToken, err = rt.SignedString([]byte(accessSecret))
+	td.RefreshToken, err = signToken(rtClaims)
 	if err != nil {
 		return nil, err
 	}
@@ -73,10 +116,81 @@ func accessTokenExpTime() time.Time {
 	return time.Now().Add(expirationDuration)
 }
 
+// SessionClient returns the client type the session of the token claims was
+// signed in with, empty for the tokens without one
+func SessionClient(claims jwt.MapClaims) string {
+	client, _ := claims["client"].(string)
+	return client
+}
+
+// RefreshTokenClaims checks if the token claims are of the refresh token of a
+// session of the user. Access tokens, support tokens and scoped tokens are
+// saved too but never refreshed, they would become full sessions.
+func RefreshTokenClaims(claims jwt.MapClaims) bool {
+	tokenType, _ := claims["type"].(string)
+	if tokenType != TokenTypeRefresh || ScopedToken(claims) {
+		return false
+	}
+	_, impersonated := claims["impersonator_id"]
+	return !impersonated
+}
+
+// TokenEpochValid checks if the token claims are of the current token epoch
+// of the user, tokens issued before the epoch was claimed are of epoch 0
+func TokenEpochValid(user *model.User, claims jwt.MapClaims) bool {
+	epoch, _ := claims["epoch"].(float64)
+	return int(epoch) >= user.TokenEpoch
+}
+
+// clientTokenDurations returns the token lifetimes of the client type, the
+// ones it doesn't set and unknown clients use the lifetimes of the server
+func clientTokenDurations(client string) (access, refresh time.Duration) {
+	accessConfig := viper.GetString("server.accessTokenExpireDuration")
+	refreshConfig := viper.GetString("server.refreshTokenExpireDuration")
+	if client != "" {
+		if value := viper.GetString("tokens." + client + ".accessTokenExpireDuration"); value != "" {
+			accessConfig = value
+		}
+		if value := viper.GetString("tokens." + client + ".refreshTokenExpireDuration"); value != "" {
+			refreshConfig = value
+		}
+	}
+	return resolveTokenExpireDuration(accessConfig), resolveTokenExpireDuration(refreshConfig)
+}
+
 func isAuthorized(role string) bool {
 	return role == "Admin"
 }
 
+// DefaultScopes returns the scopes granted to a regular session of the user
+func DefaultScopes(user *model.User) []string {
+	scopes := []string{ScopeVaultRead, ScopeVaultWrite, ScopeVaultExport, ScopeAccount}
+	if isAuthorized(user.Role) {
+		scopes = append(scopes, ScopeAdmin, ScopeBilling)
+	}
+	return scopes
+}
+
+// ScopesFromClaims extracts the scopes claim from token claims.
+// ok is false when the token carries no scopes claim.
+func ScopesFromClaims(claims jwt.MapClaims) (scopes []string, ok bool) {
+	raw, ok := claims["scopes"].([]interface{})
+	if !ok {
+		return nil, false
+	}
+	for _, v := range raw {
+		if scope, isStr := v.(string); isStr {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, true
+}
+
+// HasScope checks if scope exists in scopes
+func HasScope(scopes []string, scope string) bool {
+	return FindIndex(scopes, scope) >= 0
+}
+
 // TokenValid ...
 func TokenValid(bearerToken string) (*jwt.Token, error) {
 	token, err := verifyToken(bearerToken)
@@ -92,9 +206,93 @@ func TokenValid(bearerToken string) (*jwt.Token, error) {
 	return token, nil
 }
 
+// RevokeToken adds the token to the denylist until it expires, so it can't be
+// used on any server instance
+func RevokeToken(c cache.Cache, claims jwt.MapClaims) error {
+	uuid, _ := claims["uuid"].(string)
+	if uuid == "" {
+		return ErrUnauthorized
+	}
+	ttl := tokenTTL(claims, time.Now())
+	if ttl <= 0 {
+		return nil
+	}
+	return c.Set(revokedTokenKey(uuid), "revoked", ttl)
+}
+
+// RevokeUserSessions adds every token of the user to the denylist and deletes
+// them, the user has to sign in again on every device
+func RevokeUserSessions(s storage.Store, c cache.Cache, userID uint) error {
+	tokens, err := s.Tokens().FindByUserID(int(userID))
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		ttl := time.Until(token.ExpiryTime)
+		if ttl <= 0 {
+			continue
+		}
+		if err := c.Set(revokedTokenKey(token.UUID.String()), "revoked", ttl); err != nil {
+			return err
+		}
+	}
+	s.Tokens().Delete(int(userID))
+	return nil
+}
+
+// IsTokenRevoked checks if the token is in the denylist
+func IsTokenRevoked(c cache.Cache, claims jwt.MapClaims) (bool, error) {
+	uuid, _ := claims["uuid"].(string)
+	_, found, err := c.Get(revokedTokenKey(uuid))
+	return found, err
+}
+
+func revokedTokenKey(uuid string) string {
+	return "revoked-token:" + uuid
+}
+
+// tokenTTL returns how long the token is valid after now
+func tokenTTL(claims jwt.MapClaims, now time.Time) time.Duration {
+	var exp int64
+	switch value := claims["exp"].(type) {
+	case float64:
+		exp = int64(value)
+	case int64:
+		exp = value
+	default:
+		return 0
+	}
+	return time.Unix(exp, 0).Sub(now)
+}
+
+// signingKey is the key of the HSM signing the tokens with ES256 instead of
+// the HMAC of server.secret, it is set at startup
+var signingKey *hsm.SigningKey
+
+// SetSigningKey sets the key of the HSM signing the tokens, the tokens signed
+// with server.secret before aren't accepted anymore
+func SetSigningKey(key *hsm.SigningKey) {
+	signingKey = key
+}
+
+// signToken signs the claims with the key of the HSM or with server.secret
+func signToken(claims jwt.MapClaims) (string, error) {
+	if signingKey != nil {
+		return jwt.NewWithClaims(hsm.SigningMethodES256, claims).SignedString(signingKey)
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(viper.GetString("server.secret")))
+}
+
 // verifyToken verify token
 func verifyToken(tokenString string) (*jwt.Token, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		// Tokens are verified with the public key of the HSM key signing them
+		if signingKey != nil {
+			if _, ok := token.Method.(*jwt.SigningMethodECDSA); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return signingKey.Public(), nil
+		}
 		//Make sure that the token method conform to "SigningMethodHMAC"
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
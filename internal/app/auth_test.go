@@ -0,0 +1,80 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultScopes(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     string
+		expected []string
+	}{
+		{name: "Member scopes", role: "Member", expected: []string{ScopeVaultRead, ScopeVaultWrite, ScopeVaultExport, ScopeAccount}},
+		{name: "Admin scopes", role: "Admin", expected: []string{ScopeVaultRead, ScopeVaultWrite, ScopeVaultExport, ScopeAccount, ScopeAdmin, ScopeBilling}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, DefaultScopes(&model.User{Role: tt.role}))
+		})
+	}
+}
+
+func TestScopesFromClaims(t *testing.T) {
+	tests := []struct {
+		name     string
+		claims   jwt.MapClaims
+		expected []string
+		ok       bool
+	}{
+		{name: "No scopes claim", claims: jwt.MapClaims{}, expected: nil, ok: false},
+		{name: "Scopes claim", claims: jwt.MapClaims{"scopes": []interface{}{ScopeVaultRead, 42}}, expected: []string{ScopeVaultRead}, ok: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scopes, ok := ScopesFromClaims(tt.claims)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, scopes)
+			assert.Equal(t, tt.ok, HasScope(scopes, ScopeVaultRead))
+		})
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	c := cache.NewMemory()
+	now := time.Now()
+	claims := jwt.MapClaims{"uuid": "token-1", "exp": float64(now.Add(time.Minute).Unix())}
+
+	revoked, err := IsTokenRevoked(c, claims)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	assert.NoError(t, RevokeToken(c, claims))
+	revoked, err = IsTokenRevoked(c, claims)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	// Other tokens aren't affected
+	revoked, err = IsTokenRevoked(c, jwt.MapClaims{"uuid": "token-2"})
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	assert.Error(t, RevokeToken(c, jwt.MapClaims{}))
+}
+
+func TestTokenTTL(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	assert.Equal(t, 30*time.Second, tokenTTL(jwt.MapClaims{"exp": float64(1030)}, now))
+	assert.Equal(t, 30*time.Second, tokenTTL(jwt.MapClaims{"exp": int64(1030)}, now))
+	assert.True(t, tokenTTL(jwt.MapClaims{"exp": float64(900)}, now) < 0)
+	assert.Equal(t, time.Duration(0), tokenTTL(jwt.MapClaims{}, now))
+}
@@ -15,7 +15,7 @@ func FindAllBankAccounts(s storage.Store, schema string) ([]model.BankAccount, e
 
 	// Decrypt server side encrypted fields
 	for i := range list {
-		m, err := DecryptModel(&list[i])
+		m, err := DecryptModel(&list[i], schema)
 		if err != nil {
 			logger.Errorf("Error while decrypting bank account: %v", err)
 			continue
@@ -29,9 +29,12 @@ func FindAllBankAccounts(s storage.Store, schema string) ([]model.BankAccount, e
 // CreateBankAccount creates a new bank account and saves it to the store
 func CreateBankAccount(s storage.Store, dto *model.BankAccountDTO, schema string) (*model.BankAccount, error) {
 	rawModel := model.ToBankAccount(dto)
-	encModel := EncryptModel(rawModel)
+	seal, err := sealCreated(s, rawModel, schema)
+	if err != nil {
+		return nil, err
+	}
 
-	createdBankAccount, err := s.BankAccounts().Create(encModel.(*model.BankAccount), schema)
+	createdBankAccount, err := s.BankAccounts().CreateSealed(rawModel, schema, seal)
 	if err != nil {
 		return nil, err
 	}
@@ -41,8 +44,17 @@ func CreateBankAccount(s storage.Store, dto *model.BankAccountDTO, schema string
 
 // UpdateBankAccount updates the account with the dto and applies the changes in the store
 func UpdateBankAccount(s storage.Store, bankAccount *model.BankAccount, dto *model.BankAccountDTO, schema string) (*model.BankAccount, error) {
+	if err := checkRevision(dto.Revision, bankAccount.Revision); err != nil {
+		return nil, err
+	}
+
 	rawModel := model.ToBankAccount(dto)
-	encModel := EncryptModel(rawModel).(*model.BankAccount)
+	rawModel.ID = bankAccount.ID
+	encrypted, err := EncryptModel(s, rawModel, schema)
+	if err != nil {
+		return nil, err
+	}
+	encModel := encrypted.(*model.BankAccount)
 
 	bankAccount.BankName = encModel.BankName
 	bankAccount.BankCode = encModel.BankCode
@@ -51,6 +63,7 @@ func UpdateBankAccount(s storage.Store, bankAccount *model.BankAccount, dto *mod
 	bankAccount.IBAN = encModel.IBAN
 	bankAccount.Currency = encModel.Currency
 	bankAccount.Password = encModel.Password
+	bankAccount.FolderID = encModel.FolderID
 
 	updatedBankAccount, err := s.BankAccounts().Update(bankAccount, schema)
 	if err != nil {
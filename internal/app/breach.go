@@ -0,0 +1,173 @@
+package app
+
+import (
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+// CheckBreachedPassword checks the password or its SHA-1 hash against known breaches
+func CheckBreachedPassword(dto *model.BreachCheckDTO) (*model.BreachCheckResponse, error) {
+	hash := dto.Hash
+	if hash == "" {
+		hash = PasswordSHA1(dto.Password)
+	}
+
+	count, err := PwnedPasswordCount(hash)
+	if err != nil {
+		logger.Errorf("Error while checking breached password: %v", err)
+		return nil, err
+	}
+
+	return &model.BreachCheckResponse{Breached: count > 0, Count: count}, nil
+}
+
+// ScanBreachedPasswords flags the logins of the users who opted in to breach scanning
+// whose passwords appear in known breaches
+func ScanBreachedPasswords(s storage.Store) {
+	// Passwords are only known to the clients in zero-knowledge mode, they
+	// check them with the hash prefixes themselves
+	if ZeroKnowledge() {
+		return
+	}
+
+	users, err := s.Users().All()
+	if err != nil {
+		logger.Errorf("Error while finding users for breach scan: %v", err)
+		return
+	}
+
+	for _, user := range users {
+		if !user.BreachScan || user.Schema == "" {
+			continue
+		}
+		if err := scanBreachedLogins(s, user.Schema); err != nil {
+			logger.Errorf("Error while scanning breached passwords of schema %s: %v", user.Schema, err)
+		}
+	}
+}
+
+func scanBreachedLogins(s storage.Store, schema string) error {
+	loginList, err := s.Logins().All(schema)
+	if err != nil {
+		return err
+	}
+
+	for i := range loginList {
+		login := &loginList[i]
+
+		// Decrypt a copy, the stored login must stay encrypted
+		decrypted := *login
+		if _, err := DecryptModel(&decrypted, schema); err != nil || decrypted.Password == "" {
+			continue
+		}
+
+		count, err := PwnedPasswordCount(PasswordSHA1(decrypted.Password))
+		if err != nil {
+			return err
+		}
+
+		checkedAt := time.Now()
+		login.Breached = count > 0
+		login.BreachCheckedAt = &checkedAt
+		if _, err := s.Logins().Update(login, schema); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hibpRequestInterval keeps the breached account requests under the HIBP rate limit
+const hibpRequestInterval = 1600 * time.Millisecond
+
+// MonitorEmailBreaches checks the account and vault email addresses of the users
+// against the HIBP breached account API, stores the new findings and notifies the users.
+// It does nothing when the HIBP API key is not configured.
+func MonitorEmailBreaches(s storage.Store) {
+	apiKey := viper.GetString("hibp.apiKey")
+	if apiKey == "" {
+		return
+	}
+
+	users, err := s.Users().All()
+	if err != nil {
+		logger.Errorf("Error while finding users for breach monitoring: %v", err)
+		return
+	}
+
+	for i := range users {
+		user := &users[i]
+		if user.DeletionScheduledAt != nil {
+			continue
+		}
+
+		newBreaches := []model.EmailBreach{}
+		for _, email := range userEmailAddresses(s, user) {
+			breaches, err := BreachedAccount(email, apiKey)
+			time.Sleep(hibpRequestInterval)
+			if err != nil {
+				logger.Errorf("Error while checking breaches of %s: %v", email, err)
+				continue
+			}
+
+			for _, dto := range breaches {
+				exists, err := s.Breaches().Exists(user.ID, email, dto.Name)
+				if err != nil || exists {
+					continue
+				}
+
+				breach, err := s.Breaches().Create(model.ToEmailBreach(user.ID, email, &dto))
+				if err != nil {
+					continue
+				}
+				newBreaches = append(newBreaches, *breach)
+			}
+		}
+
+		if len(newBreaches) > 0 {
+			notifyEmailBreaches(s, user, newBreaches)
+		}
+	}
+}
+
+// userEmailAddresses returns the account email and the email items in the vault of the user
+func userEmailAddresses(s storage.Store, user *model.User) []string {
+	seen := map[string]bool{}
+	emails := []string{}
+
+	add := func(email string) {
+		email = strings.ToLower(strings.TrimSpace(email))
+		if email == "" || seen[email] {
+			return
+		}
+		seen[email] = true
+		emails = append(emails, email)
+	}
+
+	add(user.Email)
+
+	if user.Schema != "" && !ZeroKnowledge() {
+		emailList, err := s.Emails().All(user.Schema)
+		if err == nil {
+			for i := range emailList {
+				if _, err := DecryptModel(&emailList[i], user.Schema); err == nil {
+					add(emailList[i].Email)
+				}
+			}
+		}
+	}
+
+	return emails
+}
+
+func notifyEmailBreaches(s storage.Store, user *model.User, breaches []model.EmailBreach) {
+	if err := SendUserMail(s, user, EmailBreachAlert, map[string]interface{}{"Breaches": breaches}); err != nil {
+		logger.Errorf("can't send email to %s error: %v\n", user.Email, err)
+	}
+}
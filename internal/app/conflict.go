@@ -0,0 +1,142 @@
+package app
+
+import (
+	"errors"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// Versions of the item a conflict can be resolved with
+const (
+	ConflictKeepOriginal = "original"
+	ConflictKeepCopy     = "copy"
+	ConflictKeepBoth     = "both"
+)
+
+// conflictCopySuffix is added to the titles of the conflicted copies
+const conflictCopySuffix = " (conflicted copy)"
+
+// ErrConflictNotFound represents message for conflicts which don't exist in the vault
+var ErrConflictNotFound = errors.New("conflict couldn't be found")
+
+// CreateLoginConflict keeps the update made on an outdated revision of the
+// login as a conflicted copy instead of discarding it
+func CreateLoginConflict(s storage.Store, login *model.Login, dto *model.LoginDTO, schema string) (*model.Conflict, error) {
+	copyDTO := *dto
+	copyDTO.Title = conflictCopyTitle(dto.Title)
+	created, err := CreateLogin(s, &copyDTO, schema)
+	if err != nil {
+		return nil, err
+	}
+	return recordConflict(s, ItemTypeLogin, login.ID, created.ID, schema)
+}
+
+// CreateCreditCardConflict keeps the update made on an outdated revision of
+// the credit card as a conflicted copy instead of discarding it
+func CreateCreditCardConflict(s storage.Store, card *model.CreditCard, dto *model.CreditCardDTO, schema string) (*model.Conflict, error) {
+	copyDTO := *dto
+	copyDTO.CardName = conflictCopyTitle(dto.CardName)
+	created, err := CreateCreditCard(s, &copyDTO, schema)
+	if err != nil {
+		return nil, err
+	}
+	return recordConflict(s, ItemTypeCreditCard, card.ID, created.ID, schema)
+}
+
+// CreateBankAccountConflict keeps the update made on an outdated revision of
+// the bank account as a conflicted copy instead of discarding it
+func CreateBankAccountConflict(s storage.Store, account *model.BankAccount, dto *model.BankAccountDTO, schema string) (*model.Conflict, error) {
+	copyDTO := *dto
+	copyDTO.BankName = conflictCopyTitle(dto.BankName)
+	created, err := CreateBankAccount(s, &copyDTO, schema)
+	if err != nil {
+		return nil, err
+	}
+	return recordConflict(s, ItemTypeBankAccount, account.ID, created.ID, schema)
+}
+
+// CreateNoteConflict keeps the update made on an outdated revision of the
+// note as a conflicted copy instead of discarding it
+func CreateNoteConflict(s storage.Store, note *model.Note, dto *model.NoteDTO, schema string) (*model.Conflict, error) {
+	copyDTO := *dto
+	copyDTO.Title = conflictCopyTitle(dto.Title)
+	created, err := CreateNote(s, &copyDTO, schema)
+	if err != nil {
+		return nil, err
+	}
+	return recordConflict(s, ItemTypeNote, note.ID, created.ID, schema)
+}
+
+// CreateEmailConflict keeps the update made on an outdated revision of the
+// email as a conflicted copy instead of discarding it
+func CreateEmailConflict(s storage.Store, email *model.Email, dto *model.EmailDTO, schema string) (*model.Conflict, error) {
+	copyDTO := *dto
+	copyDTO.Title = conflictCopyTitle(dto.Title)
+	created, err := CreateEmail(s, &copyDTO, schema)
+	if err != nil {
+		return nil, err
+	}
+	return recordConflict(s, ItemTypeEmail, email.ID, created.ID, schema)
+}
+
+// CreateServerConflict keeps the update made on an outdated revision of the
+// server as a conflicted copy instead of discarding it
+func CreateServerConflict(s storage.Store, server *model.Server, dto *model.ServerDTO, schema string) (*model.Conflict, error) {
+	copyDTO := *dto
+	copyDTO.Title = conflictCopyTitle(dto.Title)
+	created, err := CreateServer(s, &copyDTO, schema)
+	if err != nil {
+		return nil, err
+	}
+	return recordConflict(s, ItemTypeServer, server.ID, created.ID, schema)
+}
+
+func recordConflict(s storage.Store, itemType string, itemID, copyID uint, schema string) (*model.Conflict, error) {
+	return s.Conflicts().Create(&model.Conflict{ItemType: itemType, ItemID: itemID, CopyID: copyID}, schema)
+}
+
+func conflictCopyTitle(title string) string {
+	return title + conflictCopySuffix
+}
+
+// FindAllConflicts finds the unresolved conflicts of the vault. Conflicts are
+// resolved by deleting the original item or the copy too, those are removed.
+func FindAllConflicts(s storage.Store, schema string) ([]model.Conflict, error) {
+	conflicts, err := s.Conflicts().All(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	unresolved := []model.Conflict{}
+	for _, conflict := range conflicts {
+		if ItemExists(s, conflict.ItemType, conflict.ItemID, schema) != nil ||
+			ItemExists(s, conflict.ItemType, conflict.CopyID, schema) != nil {
+			s.Conflicts().Delete(conflict.ID, schema)
+			continue
+		}
+		unresolved = append(unresolved, conflict)
+	}
+	return unresolved, nil
+}
+
+// ResolveConflict keeps the selected version of the item. Keeping the copy
+// deletes the original item, keeping both leaves the copy as a separate item.
+func ResolveConflict(s storage.Store, id uint, keep, schema string) error {
+	conflict, err := s.Conflicts().FindByID(id, schema)
+	if err != nil {
+		return ErrConflictNotFound
+	}
+
+	switch keep {
+	case ConflictKeepOriginal:
+		err = DeleteItem(s, conflict.ItemType, conflict.CopyID, schema)
+	case ConflictKeepCopy:
+		err = DeleteItem(s, conflict.ItemType, conflict.ItemID, schema)
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.Conflicts().Delete(conflict.ID, schema)
+}
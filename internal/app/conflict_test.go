@@ -0,0 +1,12 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConflictCopyTitle(t *testing.T) {
+	assert.Equal(t, "GitHub (conflicted copy)", conflictCopyTitle("GitHub"))
+	assert.Equal(t, " (conflicted copy)", conflictCopyTitle(""))
+}
@@ -15,7 +15,7 @@ func FindAllCreditCards(s storage.Store, schema string) ([]model.CreditCard, err
 
 	// Decrypt server side encrypted fields
 	for i := range list {
-		m, err := DecryptModel(&list[i])
+		m, err := DecryptModel(&list[i], schema)
 		if err != nil {
 			logger.Errorf("Error while decrypting credit card: %v", err)
 			continue
@@ -29,9 +29,12 @@ func FindAllCreditCards(s storage.Store, schema string) ([]model.CreditCard, err
 // CreateCreditCard creates a new credit card and saves it to the store
 func CreateCreditCard(s storage.Store, dto *model.CreditCardDTO, schema string) (*model.CreditCard, error) {
 	rawModel := model.ToCreditCard(dto)
-	encModel := EncryptModel(rawModel)
+	seal, err := sealCreated(s, rawModel, schema)
+	if err != nil {
+		return nil, err
+	}
 
-	createdCreditCard, err := s.CreditCards().Create(encModel.(*model.CreditCard), schema)
+	createdCreditCard, err := s.CreditCards().CreateSealed(rawModel, schema, seal)
 	if err != nil {
 		return nil, err
 	}
@@ -41,8 +44,17 @@ func CreateCreditCard(s storage.Store, dto *model.CreditCardDTO, schema string)
 
 // UpdateCreditCard updates the credit card with the dto and applies the changes in the store
 func UpdateCreditCard(s storage.Store, creditCard *model.CreditCard, dto *model.CreditCardDTO, schema string) (*model.CreditCard, error) {
+	if err := checkRevision(dto.Revision, creditCard.Revision); err != nil {
+		return nil, err
+	}
+
 	rawModel := model.ToCreditCard(dto)
-	encModel := EncryptModel(rawModel).(*model.CreditCard)
+	rawModel.ID = creditCard.ID
+	encrypted, err := EncryptModel(s, rawModel, schema)
+	if err != nil {
+		return nil, err
+	}
+	encModel := encrypted.(*model.CreditCard)
 
 	creditCard.CardName = encModel.CardName
 	creditCard.CardholderName = encModel.CardholderName
@@ -50,6 +62,7 @@ func UpdateCreditCard(s storage.Store, creditCard *model.CreditCard, dto *model.
 	creditCard.Number = encModel.Number
 	creditCard.VerificationNumber = encModel.VerificationNumber
 	creditCard.ExpiryDate = encModel.ExpiryDate
+	creditCard.FolderID = encModel.FolderID
 
 	updatedCreditCard, err := s.CreditCards().Update(creditCard, schema)
 	if err != nil {
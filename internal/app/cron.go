@@ -1,5 +1,11 @@
 package app
 
+import (
+	"time"
+
+	"github.com/passwall/passwall-server/internal/storage"
+)
+
 // StartCronJob ...
 /* func StartCronJob(s storage.Store) {
 	backupPeriod := viper.GetString("backup.period")
@@ -7,3 +13,32 @@ package app
 	cron.AddFunc(fmt.Sprintf("@every %s", backupPeriod), func() { BackupData(s) })
 	cron.Start()
 } */
+
+// StartCronJobs runs the periodic maintenance jobs in the background
+func StartCronJobs(s storage.Store) {
+	every(s, time.Hour, func() { PurgeDeletedUsers(s) })
+	every(s, time.Hour, func() { PurgeDeletedRecords(s, time.Now()) })
+	every(s, 24*time.Hour, func() { FlagExpiredPasswords(s) })
+	every(s, 24*time.Hour, func() { ScanBreachedPasswords(s) })
+	every(s, 24*time.Hour, func() { MonitorEmailBreaches(s) })
+	every(s, time.Minute, func() { RetryWebhookDeliveries(s) })
+	every(s, time.Hour, func() { SendSecurityDigests(s) })
+	every(s, time.Hour, func() { ExpireTrials(s) })
+	every(s, time.Hour, func() { ExpireGracePeriods(s) })
+}
+
+// every runs the job immediately and then periodically in a goroutine, the
+// runs are skipped while the server is in maintenance
+func every(s storage.Store, period time.Duration, job func()) {
+	go func() {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+
+		for {
+			if !InMaintenance(s) {
+				job()
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,446 @@
+package app
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+	gormschema "gorm.io/gorm/schema"
+)
+
+// Envelope encryption: the encrypted fields of the vault items are encrypted
+// with the data key of their vault and the data keys are stored wrapped by the
+// master key of the passphrase. Changing the passphrase wraps the data keys
+// again, the items are left as they are.
+
+// boundDataKeyPrefix starts the fields encrypted with a data key, the key ID
+// and the base64 of the nonce and the ciphertext follow it, like
+// dk2:<key id>:<data>. The ciphertext is bound to the vault, the table, the
+// record and the field it belongs to, so it can't be moved to another one.
+const boundDataKeyPrefix = "dk2:"
+
+// dataKeyPrefix starts the fields encrypted with a data key by the older
+// versions, without being bound to their record. Fields without either prefix
+// are encrypted with the passphrase by the older versions.
+const dataKeyPrefix = "dk:"
+
+// dataKeyLength is the length of the AES-256 data keys
+const dataKeyLength = 32
+
+var (
+	// ErrDataKeyNotFound represents message for fields encrypted with a data key which isn't found
+	ErrDataKeyNotFound = errors.New("data key of the encrypted field is not found")
+	// ErrInvalidEncryptedField represents message for encrypted fields which can't be parsed
+	ErrInvalidEncryptedField = errors.New("encrypted field is invalid")
+	// ErrWrongPassphrase represents message for passphrases the data keys aren't wrapped with
+	ErrWrongPassphrase = errors.New("data keys aren't wrapped with the passphrase")
+	// ErrDataKeyOfAnotherVault represents message for fields encrypted with the data key of another vault
+	ErrDataKeyOfAnotherVault = errors.New("encrypted field belongs to another vault")
+	// ErrUnboundField represents message for fields encrypted before their record has an ID
+	ErrUnboundField = errors.New("encrypted field needs the ID of its record")
+)
+
+// dataKeyStore is the store the data keys of the decrypted fields are found
+// in, with the stores of its regions. It is set at startup.
+var dataKeyStore storage.Store
+
+// SetDataKeyStore sets the store of the data keys, it is set at startup
+func SetDataKeyStore(s storage.Store) {
+	dataKeyStore = s
+}
+
+// dataKeyCacheTTL is how long the key ID of a vault is kept before it is found
+// again, so the keys created by the key rotations of other instances are used
+const dataKeyCacheTTL = time.Minute
+
+// dataKeys keeps the unwrapped data keys by key ID and the key IDs of the
+// vaults by schema. Keys created in a transaction aren't kept for their vault
+// until they are found in the database, the transaction may be rolled back.
+var dataKeys = struct {
+	sync.RWMutex
+	byID     map[string]unwrappedKey
+	bySchema map[string]vaultKey
+}{byID: map[string]unwrappedKey{}, bySchema: map[string]vaultKey{}}
+
+// unwrappedKey is a data key and the schema of the vault it belongs to
+type unwrappedKey struct {
+	key    []byte
+	schema string
+}
+
+// keepDataKey keeps the unwrapped data key of the vault by its key ID
+func keepDataKey(keyID, schema string, key []byte) {
+	dataKeys.Lock()
+	dataKeys.byID[keyID] = unwrappedKey{key: key, schema: schema}
+	dataKeys.Unlock()
+}
+
+// vaultKey is the key ID of a vault and when it was found
+type vaultKey struct {
+	keyID   string
+	foundAt time.Time
+}
+
+// VaultDataKey returns the ID and the unwrapped data key of the vault of the
+// schema, the key is created with the first item of the vault
+func VaultDataKey(s storage.Store, schema string) (string, []byte, error) {
+	dataKeys.RLock()
+	cached, ok := dataKeys.bySchema[schema]
+	unwrapped, found := dataKeys.byID[cached.keyID]
+	dataKeys.RUnlock()
+	if ok && found && time.Since(cached.foundAt) < dataKeyCacheTTL {
+		return cached.keyID, unwrapped.key, nil
+	}
+
+	// Only a vault without a key gets one, a failed lookup would replace its key
+	stored, err := s.DataKeys().FindBySchema(schema)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return createDataKey(s, schema)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("data key of schema %s: %w", schema, err)
+	}
+	key, err := unwrapDataKey(stored.WrappedKey, viper.GetString("server.passphrase"))
+	if err != nil {
+		return "", nil, fmt.Errorf("data key of schema %s: %w", schema, err)
+	}
+
+	dataKeys.Lock()
+	dataKeys.byID[stored.KeyID] = unwrappedKey{key: key, schema: schema}
+	dataKeys.bySchema[schema] = vaultKey{keyID: stored.KeyID, foundAt: time.Now()}
+	dataKeys.Unlock()
+	return stored.KeyID, key, nil
+}
+
+// createDataKey creates the data key of the vault, the one another request
+// created meanwhile is used instead
+func createDataKey(s storage.Store, schema string) (string, []byte, error) {
+	created, key, err := newDataKey(s, schema, 1)
+	if err != nil {
+		if _, err := s.DataKeys().FindBySchema(schema); err == nil {
+			return VaultDataKey(s, schema)
+		}
+		return "", nil, err
+	}
+	return created.KeyID, key, nil
+}
+
+// newDataKey stores a new data key of the version for the vault and keeps it
+// by its key ID
+func newDataKey(s storage.Store, schema string, version int) (*model.DataKey, []byte, error) {
+	key := make([]byte, dataKeyLength)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, nil, err
+	}
+	wrapped, err := wrapDataKey(key, viper.GetString("server.passphrase"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	created, err := s.DataKeys().Create(&model.DataKey{KeyID: NewUUID().String(), Schema: schema, WrappedKey: wrapped, Version: version})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keepDataKey(created.KeyID, schema, key)
+	return created, key, nil
+}
+
+// findDataKey finds the unwrapped data key of the key ID and the schema of its
+// vault in the data key store and the stores of its regions
+func findDataKey(keyID string) (unwrappedKey, error) {
+	dataKeys.RLock()
+	unwrapped, ok := dataKeys.byID[keyID]
+	dataKeys.RUnlock()
+	if ok {
+		return unwrapped, nil
+	}
+	if dataKeyStore == nil {
+		return unwrappedKey{}, ErrDataKeyNotFound
+	}
+
+	stores := []storage.Store{dataKeyStore}
+	for _, name := range dataKeyStore.RegionNames() {
+		if region, err := dataKeyStore.Region(name); err == nil {
+			stores = append(stores, region)
+		}
+	}
+	for _, s := range stores {
+		stored, err := s.DataKeys().FindByKeyID(keyID)
+		if err != nil {
+			continue
+		}
+		key, err := unwrapDataKey(stored.WrappedKey, viper.GetString("server.passphrase"))
+		if err != nil {
+			return unwrappedKey{}, fmt.Errorf("data key %s: %w", keyID, err)
+		}
+		keepDataKey(keyID, stored.Schema, key)
+		return unwrappedKey{key: key, schema: stored.Schema}, nil
+	}
+	return unwrappedKey{}, ErrDataKeyNotFound
+}
+
+// CheckPassphrase checks the data keys of the store are wrapped with the
+// passphrase, any passphrase is accepted before the first data key
+func CheckPassphrase(s storage.Store, passphrase string) error {
+	keys, err := s.DataKeys().All()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if _, err := unwrapDataKey(key.WrappedKey, passphrase); err != nil {
+			return ErrWrongPassphrase
+		}
+	}
+	return nil
+}
+
+// reencrypting are the vaults this process moved or is moving to their data key
+var reencrypting sync.Map
+
+// ReencryptVault moves the fields of the vault still encrypted with the
+// passphrase by the older versions to its data key in the background, when the
+// vault is read the first time. Vaults are moved once, a failed move is tried
+// again with the next read.
+func ReencryptVault(s storage.Store, schema string) {
+	// Vaults of zero-knowledge servers have nothing encrypted with the passphrase
+	if ZeroKnowledge() {
+		return
+	}
+	if _, started := reencrypting.LoadOrStore(schema, true); started {
+		return
+	}
+	go func() {
+		updated, err := reencryptVault(s, schema)
+		if err != nil {
+			logger.Errorf("Error while encrypting %s with its data key: %v", schema, err)
+			reencrypting.Delete(schema)
+			return
+		}
+		if updated > 0 {
+			logger.Infof("%d records of %s are encrypted with its data key", updated, schema)
+		}
+	}()
+}
+
+// reencryptVault moves the fields of the vault encrypted with the passphrase
+// to its data key in a transaction and returns the number of updated records
+func reencryptVault(s storage.Store, schema string) (int, error) {
+	stored, err := s.DataKeys().FindBySchema(schema)
+	if err == nil && stored.ReencryptedAt != nil {
+		return 0, nil
+	}
+
+	passphrase := viper.GetString("server.passphrase")
+	updated := 0
+	err = s.WithTransaction(func(tx storage.Store) error {
+		var err error
+		if updated, err = rotateVault(tx, schema, passphrase, passphrase); err != nil {
+			return err
+		}
+		return tx.DataKeys().MarkReencrypted(schema)
+	})
+	return updated, err
+}
+
+// rotateDataKey wraps the data key of the vault with the new passphrase and
+// returns it. A key already wrapped with the new passphrase is kept and the
+// vault without a key gets one, its fields encrypted with the passphrase are
+// moved to it.
+func rotateDataKey(s storage.Store, schema, oldPassphrase, newPassphrase string) (string, []byte, error) {
+	stored, err := s.DataKeys().FindBySchema(schema)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return createDataKey(s, schema)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	key, err := unwrapDataKey(stored.WrappedKey, oldPassphrase)
+	if err != nil {
+		if key, err := unwrapDataKey(stored.WrappedKey, newPassphrase); err == nil {
+			return stored.KeyID, key, nil
+		}
+		return "", nil, fmt.Errorf("data key %s can't be unwrapped with the current passphrase", stored.KeyID)
+	}
+	if oldPassphrase == newPassphrase {
+		return stored.KeyID, key, nil
+	}
+	if stored.WrappedKey, err = wrapDataKey(key, newPassphrase); err != nil {
+		return "", nil, err
+	}
+	if err := s.DataKeys().Rewrap(stored); err != nil {
+		return "", nil, err
+	}
+	return stored.KeyID, key, nil
+}
+
+// ForgetDataKey deletes the data key of the purged vault, the fields it
+// encrypted can't be decrypted anymore even from the backups
+func ForgetDataKey(s storage.Store, schema string) error {
+	dataKeys.Lock()
+	if cached, ok := dataKeys.bySchema[schema]; ok {
+		delete(dataKeys.byID, cached.keyID)
+		delete(dataKeys.bySchema, schema)
+	}
+	dataKeys.Unlock()
+	return s.DataKeys().Delete(schema)
+}
+
+// wrapDataKey encrypts the data key with the master key of the passphrase
+func wrapDataKey(key []byte, passphrase string) (string, error) {
+	wrapped, err := Encrypt(string(key), passphrase)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(wrapped), nil
+}
+
+// unwrapDataKey decrypts the data key wrapped with the master key of the passphrase
+func unwrapDataKey(wrapped, passphrase string) ([]byte, error) {
+	encrypted, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, err
+	}
+	key, err := Decrypt(string(encrypted), passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != dataKeyLength {
+		return nil, ErrInvalidEncryptedField
+	}
+	return key, nil
+}
+
+// fieldScope is the field of a record of a vault an encrypted value belongs
+// to, the ciphertext is bound to it as the additional data of AES-GCM
+type fieldScope struct {
+	schema string
+	table  string
+	id     uint
+	field  string
+}
+
+// additionalData returns the schema, the table, the ID and the field joined
+func (f fieldScope) additionalData() []byte {
+	return []byte(fmt.Sprintf("%s|%s|%d|%s", f.schema, f.table, f.id, f.field))
+}
+
+// modelScope returns the scope of the fields of the struct pointer stored in
+// the vault of the schema, the table is the one GORM names for its type
+func modelScope(rawModel interface{}, schema string) fieldScope {
+	value := reflect.ValueOf(rawModel).Elem()
+	scope := fieldScope{schema: schema, table: gormschema.NamingStrategy{}.TableName(value.Type().Name())}
+	if id := value.FieldByName("ID"); id.IsValid() && id.CanUint() {
+		scope.id = uint(id.Uint())
+	}
+	return scope
+}
+
+// withField returns the scope of the field of the struct
+func (f fieldScope) withField(field reflect.StructField) fieldScope {
+	f.field = gormschema.NamingStrategy{}.ColumnName("", field.Name)
+	return f
+}
+
+// sealField encrypts the value of a field with the data key of the key ID,
+// bound to the field of the record it belongs to
+func sealField(value string, scope fieldScope, keyID string, key []byte) (string, error) {
+	if scope.id == 0 {
+		return "", ErrUnboundField
+	}
+	gcm, err := dataKeyCipher(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), scope.additionalData())
+	return boundDataKeyPrefix + keyID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openField decrypts the field encrypted with a data key after checking the
+// key is the one of the vault of the scope. Fields of the older versions
+// aren't bound to their record, they are opened without the scope.
+func openField(value string, scope fieldScope) (string, error) {
+	bound := strings.HasPrefix(value, boundDataKeyPrefix)
+	keyID, data, ok := strings.Cut(fieldKeyData(value), ":")
+	if !ok {
+		return "", ErrInvalidEncryptedField
+	}
+	sealed, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", err
+	}
+	unwrapped, err := findDataKey(keyID)
+	if err != nil {
+		return "", err
+	}
+	if unwrapped.schema != scope.schema {
+		return "", ErrDataKeyOfAnotherVault
+	}
+	gcm, err := dataKeyCipher(unwrapped.key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", ErrInvalidEncryptedField
+	}
+	var additionalData []byte
+	if bound {
+		additionalData = scope.additionalData()
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+func dataKeyCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// isDataKeyField reports if the field is encrypted with a data key
+func isDataKeyField(value string) bool {
+	return strings.HasPrefix(value, boundDataKeyPrefix) || strings.HasPrefix(value, dataKeyPrefix)
+}
+
+// isBoundField reports if the field encrypted with a data key is bound to its record
+func isBoundField(value string) bool {
+	return strings.HasPrefix(value, boundDataKeyPrefix)
+}
+
+// fieldKeyData returns the key ID and the data of the field encrypted with a data key
+func fieldKeyData(value string) string {
+	if isBoundField(value) {
+		return strings.TrimPrefix(value, boundDataKeyPrefix)
+	}
+	return strings.TrimPrefix(value, dataKeyPrefix)
+}
+
+// fieldKeyID returns the ID of the data key the field is encrypted with
+func fieldKeyID(value string) string {
+	keyID, _, _ := strings.Cut(fieldKeyData(value), ":")
+	return keyID
+}
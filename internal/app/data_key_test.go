@@ -0,0 +1,293 @@
+package app
+
+import (
+	"encoding/base64"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/internal/storage/migration"
+	"github.com/passwall/passwall-server/internal/storage/sqlite"
+	"github.com/passwall/passwall-server/model"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type fakeDataKeys struct {
+	storage.DataKeyRepository
+	keys map[string]*model.DataKey
+	// err fails the lookups by schema
+	err error
+}
+
+func (r *fakeDataKeys) All() ([]model.DataKey, error) {
+	keys := []model.DataKey{}
+	for _, key := range r.keys {
+		keys = append(keys, *key)
+	}
+	return keys, nil
+}
+
+func (r *fakeDataKeys) FindBySchema(schema string) (*model.DataKey, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if key, ok := r.keys[schema]; ok {
+		return key, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeDataKeys) FindByKeyID(keyID string) (*model.DataKey, error) {
+	for _, key := range r.keys {
+		if key.KeyID == keyID {
+			return key, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeDataKeys) Create(key *model.DataKey) (*model.DataKey, error) {
+	r.keys[key.Schema] = key
+	return key, nil
+}
+
+func (r *fakeDataKeys) Rewrap(key *model.DataKey) error {
+	r.keys[key.Schema].WrappedKey = key.WrappedKey
+	return nil
+}
+
+func (r *fakeDataKeys) Delete(schema string) error {
+	delete(r.keys, schema)
+	return nil
+}
+
+type fakeDataKeyStore struct {
+	storage.Store
+	dataKeys *fakeDataKeys
+}
+
+func newFakeDataKeyStore() *fakeDataKeyStore {
+	return &fakeDataKeyStore{dataKeys: &fakeDataKeys{keys: map[string]*model.DataKey{}}}
+}
+
+func (s *fakeDataKeyStore) DataKeys() storage.DataKeyRepository {
+	return s.dataKeys
+}
+
+func (s *fakeDataKeyStore) RegionNames() []string {
+	return nil
+}
+
+func TestVaultDataKey(t *testing.T) {
+	s := newFakeDataKeyStore()
+
+	keyID, key, err := VaultDataKey(s, "user_data_key")
+	require.NoError(t, err)
+	assert.Len(t, key, dataKeyLength)
+
+	// The stored key is wrapped, not the key itself
+	stored := s.dataKeys.keys["user_data_key"]
+	require.NotNil(t, stored)
+	assert.Equal(t, keyID, stored.KeyID)
+	assert.NotContains(t, stored.WrappedKey, string(key))
+
+	// The vault keeps its key
+	sameID, same, err := VaultDataKey(s, "user_data_key")
+	require.NoError(t, err)
+	assert.Equal(t, keyID, sameID)
+	assert.Equal(t, key, same)
+
+	otherID, _, err := VaultDataKey(s, "user_other_data_key")
+	require.NoError(t, err)
+	assert.NotEqual(t, keyID, otherID)
+}
+
+func TestVaultDataKeyLookupError(t *testing.T) {
+	s := newFakeDataKeyStore()
+	s.dataKeys.err = errors.New("connection refused")
+
+	// Vaults whose key can't be found aren't given another key
+	_, _, err := VaultDataKey(s, "user_data_key_lookup")
+	assert.ErrorIs(t, err, s.dataKeys.err)
+	assert.Empty(t, s.dataKeys.keys)
+}
+
+func TestDataKeyFields(t *testing.T) {
+	defer SetDataKeyStore(nil)
+	s := newFakeDataKeyStore()
+	SetDataKeyStore(s)
+
+	note := &model.Note{ID: 1, Title: "Wifi", Note: "secret note"}
+	_, err := EncryptModel(s, note, "user_data_key_fields")
+	require.NoError(t, err)
+	assert.True(t, isDataKeyField(note.Note))
+
+	// Keys not in memory are found in the store
+	dataKeys.Lock()
+	delete(dataKeys.byID, s.dataKeys.keys["user_data_key_fields"].KeyID)
+	dataKeys.Unlock()
+	_, err = DecryptModel(note, "user_data_key_fields")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret note", note.Note)
+
+	// Fields encrypted with the passphrase by the older versions
+	legacy := &model.Note{Note: encryptedWith(t, "legacy note", viper.GetString("server.passphrase"))}
+	_, err = DecryptModel(legacy, "user_data_key_fields")
+	assert.NoError(t, err)
+	assert.Equal(t, "legacy note", legacy.Note)
+}
+
+func TestBoundDataKeyFields(t *testing.T) {
+	defer SetDataKeyStore(nil)
+	s := newFakeDataKeyStore()
+	SetDataKeyStore(s)
+
+	login := &model.Login{ID: 1, Username: "john", Password: "secret"}
+	_, err := EncryptModel(s, login, "user_bound_fields")
+	require.NoError(t, err)
+	assert.True(t, isBoundField(login.Password))
+
+	// Fields moved to another field, record or vault aren't opened
+	swapped := &model.Login{ID: 1, Username: login.Password}
+	_, err = DecryptModel(swapped, "user_bound_fields")
+	assert.Error(t, err)
+	moved := &model.Login{ID: 2, Password: login.Password}
+	_, err = DecryptModel(moved, "user_bound_fields")
+	assert.Error(t, err)
+	copied := &model.Login{ID: 1, Password: login.Password}
+	_, err = DecryptModel(copied, "user_other_bound_fields")
+	assert.ErrorIs(t, err, ErrDataKeyOfAnotherVault)
+
+	// Records without an ID can't be sealed
+	_, err = EncryptModel(s, &model.Note{Note: "secret note"}, "user_bound_fields")
+	assert.ErrorIs(t, err, ErrUnboundField)
+}
+
+func TestUnboundDataKeyFields(t *testing.T) {
+	defer SetDataKeyStore(nil)
+	s := newFakeDataKeyStore()
+	SetDataKeyStore(s)
+	keyID, key, err := VaultDataKey(s, "user_unbound_fields")
+	require.NoError(t, err)
+
+	// Fields of the older versions are sealed without their record
+	gcm, err := dataKeyCipher(key)
+	require.NoError(t, err)
+	nonce := make([]byte, gcm.NonceSize())
+	sealed := gcm.Seal(nonce, nonce, []byte("secret note"), nil)
+	note := &model.Note{ID: 1, Note: dataKeyPrefix + keyID + ":" + base64.StdEncoding.EncodeToString(sealed)}
+
+	decrypted := *note
+	_, err = DecryptModel(&decrypted, "user_unbound_fields")
+	require.NoError(t, err)
+	assert.Equal(t, "secret note", decrypted.Note)
+	_, err = DecryptModel(&model.Note{ID: 1, Note: note.Note}, "user_other_unbound_fields")
+	assert.ErrorIs(t, err, ErrDataKeyOfAnotherVault)
+
+	// Rekeying binds them with the same key
+	changed, err := rekeyModel(note, "user_unbound_fields", keyID, key)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.True(t, isBoundField(note.Note))
+	assert.Equal(t, keyID, fieldKeyID(note.Note))
+	_, err = DecryptModel(note, "user_unbound_fields")
+	require.NoError(t, err)
+	assert.Equal(t, "secret note", note.Note)
+}
+
+func TestForgetDataKey(t *testing.T) {
+	defer SetDataKeyStore(nil)
+	s := newFakeDataKeyStore()
+	SetDataKeyStore(s)
+
+	note := &model.Note{ID: 1, Note: "secret note"}
+	_, err := EncryptModel(s, note, "user_forget_data_key")
+	require.NoError(t, err)
+	keyID := s.dataKeys.keys["user_forget_data_key"].KeyID
+	// Keys are kept for their vault once they are found in the store
+	_, _, err = VaultDataKey(s, "user_forget_data_key")
+	require.NoError(t, err)
+
+	require.NoError(t, ForgetDataKey(s, "user_forget_data_key"))
+	dataKeys.Lock()
+	delete(dataKeys.byID, keyID)
+	dataKeys.Unlock()
+
+	_, err = DecryptModel(note, "user_forget_data_key")
+	assert.ErrorIs(t, err, ErrDataKeyNotFound)
+}
+
+func TestRotateDataKey(t *testing.T) {
+	oldPassphrase, newPassphrase := "old-passphrase", "new-passphrase"
+	s := newFakeDataKeyStore()
+	key := make([]byte, dataKeyLength)
+	wrapped, err := wrapDataKey(key, oldPassphrase)
+	require.NoError(t, err)
+	s.dataKeys.keys["user_rotate_data_key"] = &model.DataKey{KeyID: "rotate", Schema: "user_rotate_data_key", WrappedKey: wrapped}
+
+	keyID, rotated, err := rotateDataKey(s, "user_rotate_data_key", oldPassphrase, newPassphrase)
+	require.NoError(t, err)
+	assert.Equal(t, "rotate", keyID)
+	assert.Equal(t, key, rotated)
+	unwrapped, err := unwrapDataKey(s.dataKeys.keys["user_rotate_data_key"].WrappedKey, newPassphrase)
+	require.NoError(t, err)
+	assert.Equal(t, key, unwrapped)
+
+	// Running the rotation again keeps the rotated key
+	_, rotated, err = rotateDataKey(s, "user_rotate_data_key", oldPassphrase, newPassphrase)
+	assert.NoError(t, err)
+	assert.Equal(t, key, rotated)
+
+	_, _, err = rotateDataKey(s, "user_rotate_data_key", "another-passphrase", "new-passphrase-2")
+	assert.Error(t, err)
+}
+
+func TestCheckPassphrase(t *testing.T) {
+	s := newFakeDataKeyStore()
+	assert.NoError(t, CheckPassphrase(s, "any-passphrase"))
+
+	_, _, err := VaultDataKey(s, "user_check_passphrase")
+	require.NoError(t, err)
+	assert.NoError(t, CheckPassphrase(s, viper.GetString("server.passphrase")))
+	assert.ErrorIs(t, CheckPassphrase(s, "another-passphrase"), ErrWrongPassphrase)
+}
+
+func TestReencryptVault(t *testing.T) {
+	defer viper.Set("server.passphrase", viper.GetString("server.passphrase"))
+	defer SetDataKeyStore(nil)
+	viper.Set("server.passphrase", "reencrypt-passphrase")
+
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "passwall.db"), &gorm.Config{})
+	require.NoError(t, err)
+	s := storage.New(db)
+	SetDataKeyStore(s)
+	require.NoError(t, s.Migrations().Up(migration.SetSystem, ""))
+	require.NoError(t, s.Users().CreateSchema("user_reencrypt"))
+	require.NoError(t, s.Migrations().Up(migration.SetVault, "user_reencrypt"))
+
+	legacy, err := s.Notes().Create(&model.Note{Title: "Wifi", Note: encryptedWith(t, "secret note", "reencrypt-passphrase")}, "user_reencrypt")
+	require.NoError(t, err)
+
+	updated, err := reencryptVault(s, "user_reencrypt")
+	require.NoError(t, err)
+	assert.Equal(t, 1, updated)
+
+	note, err := s.Notes().FindByID(legacy.ID, "user_reencrypt")
+	require.NoError(t, err)
+	assert.True(t, isDataKeyField(note.Note))
+	_, err = DecryptModel(note, "user_reencrypt")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret note", note.Note)
+
+	// Vaults are moved once
+	stored, err := s.DataKeys().FindBySchema("user_reencrypt")
+	require.NoError(t, err)
+	assert.NotNil(t, stored.ReencryptedAt)
+	updated, err = reencryptVault(s, "user_reencrypt")
+	assert.NoError(t, err)
+	assert.Zero(t, updated)
+}
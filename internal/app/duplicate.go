@@ -0,0 +1,180 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+var (
+	// ErrMergeIntoItself represents message for merging a login into itself
+	ErrMergeIntoItself = errors.New("a login can't be merged into itself")
+)
+
+// FindDuplicateLogins finds the logins with the same domain and username.
+// Logins are compared by their blind indexes, only the duplicates are decrypted.
+func FindDuplicateLogins(s storage.Store, schema string) ([]model.DuplicateLoginGroup, error) {
+	loginList, err := s.Logins().All(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	for i := range loginList {
+		if key, ok := duplicateLoginKey(&loginList[i]); ok {
+			counts[key]++
+		}
+	}
+
+	duplicates := []model.Login{}
+	for i := range loginList {
+		if key, ok := duplicateLoginKey(&loginList[i]); ok && counts[key] > 1 {
+			if _, err := DecryptModel(&loginList[i], schema); err != nil {
+				logger.Errorf("Error while decrypting login: %v", err)
+			}
+			duplicates = append(duplicates, loginList[i])
+		}
+	}
+
+	return groupDuplicateLogins(duplicates), nil
+}
+
+// groupDuplicateLogins groups the logins by their domain and username indexes.
+// Logins without domain and username are not compared.
+func groupDuplicateLogins(logins []model.Login) []model.DuplicateLoginGroup {
+	groups := map[string]*model.DuplicateLoginGroup{}
+	order := []string{}
+
+	for i := range logins {
+		login := &logins[i]
+		key, ok := duplicateLoginKey(login)
+		if !ok {
+			continue
+		}
+
+		group, ok := groups[key]
+		if !ok {
+			group = &model.DuplicateLoginGroup{
+				URL:      loginDomain(login.URL),
+				Username: strings.ToLower(strings.TrimSpace(login.Username)),
+			}
+			groups[key] = group
+			order = append(order, key)
+		}
+		group.Items = append(group.Items, model.ToReportItem(login))
+	}
+
+	result := []model.DuplicateLoginGroup{}
+	for _, key := range order {
+		if len(groups[key].Items) > 1 {
+			result = append(result, *groups[key])
+		}
+	}
+
+	return result
+}
+
+// duplicateLoginKey returns the key logins with the same domain and username
+// share. Logins without domain and username have no key.
+func duplicateLoginKey(login *model.Login) (string, bool) {
+	if login.DomainIndex == "" && login.UsernameIndex == "" {
+		return "", false
+	}
+	return login.DomainIndex + ":" + login.UsernameIndex, true
+}
+
+// MergeLogins merges the source logins into the target login. The source logins
+// are kept in the history of the target and removed from the vault. Empty fields
+// of the target are filled from the sources.
+func MergeLogins(s storage.Store, dto *model.MergeLoginsDTO, schema string) (*model.Login, error) {
+	target, err := s.Logins().FindByID(dto.TargetID, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := []*model.Login{}
+	for _, id := range dto.SourceIDs {
+		if id == dto.TargetID {
+			return nil, ErrMergeIntoItself
+		}
+		source, err := s.Logins().FindByID(id, schema)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+
+	// Fields are bound to their record, so they are decrypted and sealed again
+	if _, err := DecryptModel(target, schema); err != nil {
+		return nil, err
+	}
+	for _, source := range sources {
+		if _, err := DecryptModel(source, schema); err != nil {
+			return nil, err
+		}
+		history := model.ToLoginHistory(source, target.ID, fmt.Sprintf("merged from login %d", source.ID))
+		seal, err := sealCreated(s, history, schema)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.Logins().CreateHistorySealed(history, schema, seal); err != nil {
+			return nil, err
+		}
+
+		if target.Username == "" {
+			target.Username = source.Username
+		}
+		if target.Password == "" {
+			target.Password = source.Password
+		}
+		if target.TOTPSecret == "" {
+			target.TOTPSecret = source.TOTPSecret
+		}
+		if target.Extra == "" {
+			target.Extra = source.Extra
+		}
+		if target.URL == "" {
+			target.URL = source.URL
+		}
+	}
+
+	SetLoginSearchIndex(target)
+	if _, err := EncryptModel(s, target, schema); err != nil {
+		return nil, err
+	}
+	mergedLogin, err := s.Logins().Update(target, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, source := range sources {
+		if err := s.Logins().Delete(source.ID, schema); err != nil {
+			logger.Errorf("Error while deleting merged login %d: %v", source.ID, err)
+			return nil, err
+		}
+		s.Relations().DeleteByItem(ItemTypeLogin, source.ID, schema)
+	}
+
+	return mergedLogin, nil
+}
+
+// FindLoginHistories finds the previous versions of the login
+func FindLoginHistories(s storage.Store, loginID uint, schema string) ([]model.LoginHistory, error) {
+	histories, err := s.Logins().Histories(loginID, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decrypt server side encrypted fields
+	for i := range histories {
+		if _, err := DecryptModel(&histories[i], schema); err != nil {
+			logger.Errorf("Error while decrypting login history: %v", err)
+		}
+	}
+
+	return histories, nil
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginDomain(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{url: "", expected: ""},
+		{url: "https://Example.com/", expected: "example.com"},
+		{url: "example.com", expected: "example.com"},
+		{url: "http://www.example.com/login/", expected: "example.com"},
+		{url: "https://example.com:8443/app", expected: "example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			assert.Equal(t, tt.expected, loginDomain(tt.url))
+		})
+	}
+}
+
+func TestGroupDuplicateLogins(t *testing.T) {
+	logins := []model.Login{
+		{ID: 1, URL: "https://github.com", Username: "passwall"},
+		{ID: 2, URL: "github.com/", Username: "PassWall"},
+		{ID: 3, URL: "https://github.com", Username: "other"},
+		{ID: 4, URL: "", Username: ""},
+		{ID: 5, URL: "", Username: ""},
+		{ID: 6, URL: "http://www.github.com/login", Username: "passwall"},
+	}
+	for i := range logins {
+		SetLoginSearchIndex(&logins[i])
+	}
+
+	groups := groupDuplicateLogins(logins)
+	assert.Len(t, groups, 1)
+	assert.Equal(t, "github.com", groups[0].URL)
+	assert.Equal(t, "passwall", groups[0].Username)
+
+	ids := []uint{}
+	for _, item := range groups[0].Items {
+		ids = append(ids, item.ID)
+	}
+	assert.Equal(t, []uint{1, 2, 6}, ids)
+}
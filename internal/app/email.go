@@ -15,7 +15,7 @@ func FindAllEmails(s storage.Store, schema string) ([]model.Email, error) {
 
 	// Decrypt server side encrypted fields
 	for i := range list {
-		m, err := DecryptModel(&list[i])
+		m, err := DecryptModel(&list[i], schema)
 		if err != nil {
 			logger.Errorf("Error while decrypting credit card: %v", err)
 			continue
@@ -29,9 +29,12 @@ func FindAllEmails(s storage.Store, schema string) ([]model.Email, error) {
 // CreateEmail creates a new bank account and saves it to the store
 func CreateEmail(s storage.Store, dto *model.EmailDTO, schema string) (*model.Email, error) {
 	rawModel := model.ToEmail(dto)
-	encModel := EncryptModel(rawModel)
+	seal, err := sealCreated(s, rawModel, schema)
+	if err != nil {
+		return nil, err
+	}
 
-	createdEmail, err := s.Emails().Create(encModel.(*model.Email), schema)
+	createdEmail, err := s.Emails().CreateSealed(rawModel, schema, seal)
 	if err != nil {
 		return nil, err
 	}
@@ -41,12 +44,22 @@ func CreateEmail(s storage.Store, dto *model.EmailDTO, schema string) (*model.Em
 
 // UpdateEmail updates the account with the dto and applies the changes in the store
 func UpdateEmail(s storage.Store, email *model.Email, dto *model.EmailDTO, schema string) (*model.Email, error) {
+	if err := checkRevision(dto.Revision, email.Revision); err != nil {
+		return nil, err
+	}
+
 	rawModel := model.ToEmail(dto)
-	encModel := EncryptModel(rawModel).(*model.Email)
+	rawModel.ID = email.ID
+	encrypted, err := EncryptModel(s, rawModel, schema)
+	if err != nil {
+		return nil, err
+	}
+	encModel := encrypted.(*model.Email)
 
 	email.Title = encModel.Title
 	email.Email = encModel.Email
 	email.Password = encModel.Password
+	email.FolderID = encModel.FolderID
 
 	updatedEmail, err := s.Emails().Update(email, schema)
 	if err != nil {
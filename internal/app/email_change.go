@@ -0,0 +1,269 @@
+package app
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/cache"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+// EmailChangeExpiration is how long an email change waits for the code of the
+// new email and the approval of the current one
+const EmailChangeExpiration = time.Hour
+
+var (
+	// ErrNoEmailChange is returned when the user hasn't asked to change the email or it is expired
+	ErrNoEmailChange = errors.New("email change isn't requested or it is expired")
+	// ErrInvalidEmailChange is returned for wrong codes and links of the email changes
+	ErrInvalidEmailChange = errors.New("email change code or link is invalid")
+)
+
+// Steps of an email change, the code of the new email confirms it and the
+// link of the current one approves it
+const (
+	emailChangeConfirmed = "confirmed"
+	emailChangeApproved  = "approved"
+)
+
+// emailChange is the email change of a user waiting in the cache, the new
+// email confirms it with the code and the current one approves it with the
+// token of the link
+type emailChange struct {
+	Email     string    `json:"email"`
+	Code      string    `json:"code"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RequestEmailChange starts changing the email of the user, the new email gets
+// a code and the current one a link to approve or abort the change. The email
+// changes once both are done, an earlier request of the user is replaced.
+func RequestEmailChange(s storage.Store, c cache.Cache, user *model.User, email string, now time.Time) error {
+	if _, err := s.Users().FindByEmail(email); err == nil {
+		return ErrEmailTaken
+	}
+
+	code, err := rand.Int(rand.Reader, big.NewInt(900000))
+	if err != nil {
+		return err
+	}
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return err
+	}
+	change := &emailChange{
+		Email:     email,
+		Code:      strconv.FormatInt(code.Int64()+100000, 10),
+		Token:     hex.EncodeToString(random),
+		ExpiresAt: now.Add(EmailChangeExpiration),
+	}
+
+	if previous, err := findEmailChange(c, user.ID); err == nil {
+		c.Delete(emailChangeTokenKey(previous.Token))
+	}
+	if err := saveEmailChange(c, user.ID, change, now); err != nil {
+		return err
+	}
+	if err := c.Set(emailChangeTokenKey(change.Token), strconv.FormatUint(uint64(user.ID), 10), EmailChangeExpiration); err != nil {
+		return err
+	}
+
+	if err := SendMail(s, user.Locale, user.Name, email, EmailChangeCode, map[string]interface{}{"Code": change.Code}); err != nil {
+		return err
+	}
+	return SendUserMail(s, user, EmailChangeApproval, map[string]interface{}{"Email": email, "Token": change.Token})
+}
+
+// ConfirmEmailChange checks the code sent to the new email of the user, a
+// wrong code ends the change so codes can't be guessed. The returned user has
+// the new email when the current one approved the change too.
+func ConfirmEmailChange(s storage.Store, c cache.Cache, user *model.User, code string, now time.Time) (*model.User, bool, error) {
+	change, err := findEmailChange(c, user.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	if code == "" || subtle.ConstantTimeCompare([]byte(code), []byte(change.Code)) != 1 {
+		deleteEmailChange(c, user.ID, change)
+		return nil, false, ErrInvalidEmailChange
+	}
+
+	return updateEmailChange(s, c, user, change, emailChangeConfirmed, now)
+}
+
+// ApproveEmailChange approves the email change of the token sent to the
+// current email. The returned user has the new email when the new one
+// confirmed the change too.
+func ApproveEmailChange(s storage.Store, c cache.Cache, token string, now time.Time) (*model.User, bool, error) {
+	user, change, err := findEmailChangeByToken(s, c, token)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return updateEmailChange(s, c, user, change, emailChangeApproved, now)
+}
+
+// AbortEmailChange ends the email change of the token sent to the current
+// email, the email of the user stays the same
+func AbortEmailChange(s storage.Store, c cache.Cache, token string) (*model.User, error) {
+	user, change, err := findEmailChangeByToken(s, c, token)
+	if err != nil {
+		return nil, err
+	}
+	deleteEmailChange(c, user.ID, change)
+	return user, nil
+}
+
+// updateEmailChange counts the step of the email change and changes the email
+// of the user once it is confirmed and approved. Both sides may arrive at
+// once, so the steps are counted by the cache and the one counted second
+// changes the email.
+func updateEmailChange(s storage.Store, c cache.Cache, user *model.User, change *emailChange, step string, now time.Time) (*model.User, bool, error) {
+	ttl := change.ExpiresAt.Sub(now)
+	if ttl <= 0 {
+		return nil, false, ErrNoEmailChange
+	}
+	// Repeated steps aren't counted again
+	count, err := c.Incr(emailChangeStepKey(change.Token, step), ttl)
+	if err != nil {
+		return nil, false, err
+	}
+	if count > 1 {
+		return user, false, nil
+	}
+	if count, err = c.Incr(emailChangeStepsKey(change.Token), ttl); err != nil {
+		return nil, false, err
+	}
+	if count < 2 {
+		return user, false, nil
+	}
+
+	oldEmail := user.Email
+	err = s.WithTransaction(func(tx storage.Store) error {
+		if _, err := tx.Users().FindByEmail(change.Email); err == nil {
+			return ErrEmailTaken
+		}
+		// Subscription events of the providers knowing the user by the
+		// email it subscribed with still find it
+		if user.BillingEmail == "" {
+			user.BillingEmail = oldEmail
+		}
+		user.Email = change.Email
+		user.EmailVerifiedAt = now
+		updated, err := tx.Users().Update(user)
+		if err != nil {
+			return err
+		}
+		user = updated
+		return nil
+	})
+	deleteEmailChange(c, user.ID, change)
+	if err != nil {
+		return nil, false, err
+	}
+
+	data := map[string]interface{}{"OldEmail": oldEmail, "Email": user.Email, "Time": now.UTC().Format("2006-01-02 15:04 UTC")}
+	for _, email := range []string{oldEmail, user.Email} {
+		if err := SendMail(s, user.Locale, user.Name, email, EmailChanged, data); err != nil {
+			logger.Errorf("can't send email to %s error: %v\n", email, err)
+		}
+	}
+	DispatchWebhookEvent(s, WebhookEventEmailChanged, user.ID, map[string]string{"old_email": oldEmail, "email": user.Email})
+	return user, true, nil
+}
+
+// findEmailChangeByToken finds the user and the email change of the token
+func findEmailChangeByToken(s storage.Store, c cache.Cache, token string) (*model.User, *emailChange, error) {
+	value, found, err := c.Get(emailChangeTokenKey(token))
+	if err != nil {
+		return nil, nil, err
+	}
+	if !found || token == "" {
+		return nil, nil, ErrNoEmailChange
+	}
+	userID, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, nil, ErrNoEmailChange
+	}
+	change, err := findEmailChange(c, uint(userID))
+	if err != nil {
+		return nil, nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(change.Token)) != 1 {
+		return nil, nil, ErrInvalidEmailChange
+	}
+	user, err := s.Users().FindByID(uint(userID))
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, change, nil
+}
+
+// findEmailChange returns the email change of the user waiting in the cache
+func findEmailChange(c cache.Cache, userID uint) (*emailChange, error) {
+	value, found, err := c.Get(emailChangeKey(userID))
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrNoEmailChange
+	}
+	change := new(emailChange)
+	if err := json.Unmarshal([]byte(value), change); err != nil {
+		return nil, ErrNoEmailChange
+	}
+	return change, nil
+}
+
+// saveEmailChange saves the email change of the user until it expires
+func saveEmailChange(c cache.Cache, userID uint, change *emailChange, now time.Time) error {
+	ttl := change.ExpiresAt.Sub(now)
+	if ttl <= 0 {
+		return ErrNoEmailChange
+	}
+	value, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	return c.Set(emailChangeKey(userID), string(value), ttl)
+}
+
+// deleteEmailChange ends the email change of the user
+func deleteEmailChange(c cache.Cache, userID uint, change *emailChange) {
+	if err := c.Delete(emailChangeKey(userID)); err != nil {
+		logger.Errorf("can't delete email change of user %d error: %v", userID, err)
+	}
+	if err := c.Delete(emailChangeTokenKey(change.Token)); err != nil {
+		logger.Errorf("can't delete email change token of user %d error: %v", userID, err)
+	}
+	for _, key := range []string{emailChangeStepKey(change.Token, emailChangeConfirmed), emailChangeStepKey(change.Token, emailChangeApproved), emailChangeStepsKey(change.Token)} {
+		if err := c.Delete(key); err != nil {
+			logger.Errorf("can't delete email change steps of user %d error: %v", userID, err)
+		}
+	}
+}
+
+func emailChangeKey(userID uint) string {
+	return fmt.Sprintf("email-change:%d", userID)
+}
+
+func emailChangeTokenKey(token string) string {
+	return "email-change-token:" + token
+}
+
+func emailChangeStepKey(token, step string) string {
+	return "email-change-step:" + token + ":" + step
+}
+
+func emailChangeStepsKey(token string) string {
+	return "email-change-steps:" + token
+}
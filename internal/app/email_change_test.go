@@ -0,0 +1,139 @@
+package app
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/internal/storage/migration"
+	"github.com/passwall/passwall-server/internal/storage/sqlite"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/cache"
+)
+
+func TestEmailChange(t *testing.T) {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "passwall.db"), &gorm.Config{})
+	require.NoError(t, err)
+	s := storage.New(db)
+	require.NoError(t, s.Migrations().Up(migration.SetSystem, ""))
+	c := cache.NewMemory()
+
+	user, err := s.Users().Create(&model.User{UUID: uuid.NewV4(), Email: "old@passwall.io", Role: "Member"})
+	require.NoError(t, err)
+	_, err = s.Users().Create(&model.User{UUID: uuid.NewV4(), Email: "taken@passwall.io", Role: "Member"})
+	require.NoError(t, err)
+
+	now := time.Now()
+	assert.ErrorIs(t, RequestEmailChange(s, c, user, "taken@passwall.io", now), ErrEmailTaken)
+
+	// The new email gets the code and the current one the link
+	require.NoError(t, RequestEmailChange(s, c, user, "new@passwall.io", now))
+	pending, err := s.Outbox().Count(EmailStatusPending, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), pending)
+
+	// A wrong code ends the change
+	_, _, err = ConfirmEmailChange(s, c, user, "000000", now)
+	assert.ErrorIs(t, err, ErrInvalidEmailChange)
+	_, _, err = ConfirmEmailChange(s, c, user, "000000", now)
+	assert.ErrorIs(t, err, ErrNoEmailChange)
+
+	// The email changes once both emails agree, in any order
+	require.NoError(t, RequestEmailChange(s, c, user, "new@passwall.io", now))
+	change, err := findEmailChange(c, user.ID)
+	require.NoError(t, err)
+	_, changed, err := ApproveEmailChange(s, c, change.Token, now)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	user, changed, err = ConfirmEmailChange(s, c, user, change.Code, now)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "new@passwall.io", user.Email)
+	_, err = findEmailChange(c, user.ID)
+	assert.ErrorIs(t, err, ErrNoEmailChange)
+
+	// Subscription events of the old email still find the user
+	subscriber, err := s.Users().FindByBillingEmail("old@passwall.io")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, subscriber.ID)
+
+	// Aborted changes can't be approved anymore
+	require.NoError(t, RequestEmailChange(s, c, user, "other@passwall.io", now))
+	change, err = findEmailChange(c, user.ID)
+	require.NoError(t, err)
+	_, err = AbortEmailChange(s, c, change.Token)
+	require.NoError(t, err)
+	_, _, err = ApproveEmailChange(s, c, change.Token, now)
+	assert.ErrorIs(t, err, ErrNoEmailChange)
+
+	user, err = s.Users().FindByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "new@passwall.io", user.Email)
+	assert.Equal(t, "old@passwall.io", user.BillingEmail)
+}
+
+func TestEmailChangeAtOnce(t *testing.T) {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "passwall.db"), &gorm.Config{})
+	require.NoError(t, err)
+	s := storage.New(db)
+	require.NoError(t, s.Migrations().Up(migration.SetSystem, ""))
+	c := cache.NewMemory()
+
+	user, err := s.Users().Create(&model.User{UUID: uuid.NewV4(), Email: "old@passwall.io", Role: "Member"})
+	require.NoError(t, err)
+	now := time.Now()
+	require.NoError(t, RequestEmailChange(s, c, user, "new@passwall.io", now))
+	change, err := findEmailChange(c, user.ID)
+	require.NoError(t, err)
+
+	// Repeated steps don't complete the change
+	_, changed, err := ApproveEmailChange(s, c, change.Token, now)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	_, changed, err = ApproveEmailChange(s, c, change.Token, now)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	// The code and the link arriving together change the email once
+	require.NoError(t, RequestEmailChange(s, c, user, "new@passwall.io", now))
+	change, err = findEmailChange(c, user.ID)
+	require.NoError(t, err)
+	results := make(chan bool, 2)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, changed, err := ConfirmEmailChange(s, c, user, change.Code, now)
+		assert.NoError(t, err)
+		results <- changed
+	}()
+	go func() {
+		defer wg.Done()
+		_, changed, err := ApproveEmailChange(s, c, change.Token, now)
+		if err != nil {
+			// The change may be done before the link finds it
+			assert.ErrorIs(t, err, ErrNoEmailChange)
+		}
+		results <- changed
+	}()
+	wg.Wait()
+	close(results)
+	completed := 0
+	for changed := range results {
+		if changed {
+			completed++
+		}
+	}
+	assert.Equal(t, 1, completed)
+
+	user, err = s.Users().FindByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "new@passwall.io", user.Email)
+}
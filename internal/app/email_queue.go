@@ -0,0 +1,159 @@
+package app
+
+import (
+	"errors"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+// Outbox email statuses
+const (
+	EmailStatusPending = "pending"
+	EmailStatusDead    = "dead"
+)
+
+const (
+	// emailMaxAttempts is the number of attempts before an email is dead
+	emailMaxAttempts = 8
+	// emailFirstRetry is the wait after the first failed attempt, it doubles
+	// after every attempt
+	emailFirstRetry = 30 * time.Second
+	// emailPollPeriod is the period the worker looks for due retries
+	emailPollPeriod = 15 * time.Second
+	// emailBatch is the number of emails sent at once
+	emailBatch = 50
+)
+
+// ErrOutboxEmailNotFound represents message for outbox emails which don't exist
+var ErrOutboxEmailNotFound = errors.New("email not found")
+
+// mailer sends the emails of the outbox, tests replace it
+var mailer = sendSMTP
+
+// emailQueued wakes the worker up when an email is queued
+var emailQueued = make(chan struct{}, 1)
+
+// QueueEmail stores the rendered email in the outbox to be sent right away by
+// the email worker
+func QueueEmail(s storage.Store, toName, toEmail, template string, email *Email) error {
+	now := time.Now()
+	_, err := s.Outbox().Create(&model.OutboxEmail{
+		ToName:        toName,
+		ToEmail:       toEmail,
+		Template:      template,
+		Subject:       email.Subject,
+		Text:          email.Text,
+		HTML:          email.HTML,
+		Status:        EmailStatusPending,
+		NextAttemptAt: &now,
+	})
+	if err != nil {
+		return err
+	}
+
+	wakeEmailWorker()
+	return nil
+}
+
+// StartEmailWorker sends the queued emails in the background as soon as they
+// are queued, and retries the failed ones when they are due
+func StartEmailWorker(s storage.Store) {
+	go func() {
+		ticker := time.NewTicker(emailPollPeriod)
+		defer ticker.Stop()
+
+		for {
+			SendQueuedEmails(s)
+			select {
+			case <-ticker.C:
+			case <-emailQueued:
+			}
+		}
+	}()
+}
+
+// SendQueuedEmails sends the pending emails whose attempt is due
+func SendQueuedEmails(s storage.Store) {
+	for {
+		emails, err := s.Outbox().FindDue(time.Now(), EmailStatusPending, emailBatch)
+		if err != nil || len(emails) == 0 {
+			return
+		}
+
+		for i := range emails {
+			sendQueuedEmail(s, &emails[i])
+		}
+		if len(emails) < emailBatch {
+			return
+		}
+	}
+}
+
+// sendQueuedEmail makes an attempt of the email, sent emails are removed from
+// the outbox and failed ones are retried with an exponential backoff until
+// they are dead
+func sendQueuedEmail(s storage.Store, email *model.OutboxEmail) {
+	email.Attempts++
+	err := mailer(email)
+	if err == nil {
+		if err := s.Outbox().Delete(email.ID); err != nil {
+			logger.Errorf("can't remove sent email %d error: %v", email.ID, err)
+		}
+		return
+	}
+
+	logger.Errorf("Failed to send email to '%s' error: %v", email.ToEmail, err)
+	email.Error = err.Error()
+	if email.Attempts >= emailMaxAttempts {
+		email.Status = EmailStatusDead
+		email.NextAttemptAt = nil
+	} else {
+		next := time.Now().Add(EmailRetryDelay(email.Attempts))
+		email.NextAttemptAt = &next
+	}
+
+	if _, err := s.Outbox().Update(email); err != nil {
+		logger.Errorf("can't update outbox email %d error: %v", email.ID, err)
+	}
+}
+
+// RetryEmail queues a dead email again with a fresh set of attempts
+func RetryEmail(s storage.Store, id uint) (*model.OutboxEmail, error) {
+	email, err := s.Outbox().FindByID(id)
+	if err != nil {
+		return nil, ErrOutboxEmailNotFound
+	}
+
+	now := time.Now()
+	email.Status = EmailStatusPending
+	email.Attempts = 0
+	email.Error = ""
+	email.NextAttemptAt = &now
+	if _, err := s.Outbox().Update(email); err != nil {
+		return nil, err
+	}
+
+	wakeEmailWorker()
+	return email, nil
+}
+
+// wakeEmailWorker tells the worker there are emails to send, it doesn't wait
+// when the worker is busy since it checks the outbox again before sleeping
+func wakeEmailWorker() {
+	select {
+	case emailQueued <- struct{}{}:
+	default:
+	}
+}
+
+// EmailRetryDelay is the wait before the next attempt after the given number
+// of failed attempts
+func EmailRetryDelay(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	return emailFirstRetry << (attempts - 1)
+}
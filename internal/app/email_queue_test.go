@@ -0,0 +1,79 @@
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeOutbox struct {
+	storage.OutboxRepository
+	emails map[uint]*model.OutboxEmail
+}
+
+func (o *fakeOutbox) Update(email *model.OutboxEmail) (*model.OutboxEmail, error) {
+	o.emails[email.ID] = email
+	return email, nil
+}
+
+func (o *fakeOutbox) Delete(id uint) error {
+	delete(o.emails, id)
+	return nil
+}
+
+type fakeOutboxStore struct {
+	storage.Store
+	outbox *fakeOutbox
+}
+
+func (s *fakeOutboxStore) Outbox() storage.OutboxRepository {
+	return s.outbox
+}
+
+func TestSendQueuedEmail(t *testing.T) {
+	defer func() { mailer = sendSMTP }()
+	s := &fakeOutboxStore{outbox: &fakeOutbox{emails: map[uint]*model.OutboxEmail{}}}
+	email := &model.OutboxEmail{ID: 1, ToEmail: "jane@passwall.io", Status: EmailStatusPending}
+	s.outbox.emails[1] = email
+
+	mailer = func(*model.OutboxEmail) error { return errors.New("connection refused") }
+	before := time.Now()
+	sendQueuedEmail(s, email)
+	assert.Equal(t, EmailStatusPending, email.Status)
+	assert.Equal(t, 1, email.Attempts)
+	assert.Equal(t, "connection refused", email.Error)
+	if assert.NotNil(t, email.NextAttemptAt) {
+		assert.False(t, email.NextAttemptAt.Before(before.Add(emailFirstRetry)))
+	}
+
+	// Emails failing every attempt are dead
+	email.Attempts = emailMaxAttempts - 1
+	sendQueuedEmail(s, email)
+	assert.Equal(t, EmailStatusDead, email.Status)
+	assert.Nil(t, email.NextAttemptAt)
+
+	// Sent emails leave the outbox
+	mailer = func(*model.OutboxEmail) error { return nil }
+	sendQueuedEmail(s, email)
+	assert.Empty(t, s.outbox.emails)
+}
+
+func TestEmailRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{attempts: 0, expected: 30 * time.Second},
+		{attempts: 1, expected: 30 * time.Second},
+		{attempts: 2, expected: time.Minute},
+		{attempts: 7, expected: 32 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, EmailRetryDelay(tt.attempts))
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"github.com/Luzifer/go-openssl/v4"
+	"github.com/passwall/passwall-server/internal/storage"
 	"github.com/passwall/passwall-server/pkg/logger"
 	"github.com/spf13/viper"
 	"golang.org/x/crypto/bcrypt"
@@ -165,8 +166,25 @@ func DecryptFile(filename string, passphrase string) ([]byte, error) {
 	return decrypted, err
 }
 
-// EncryptModel encrypts struct pointer according to struct tags
-func EncryptModel(rawModel interface{}) interface{} {
+// EncryptModel encrypts struct pointer according to struct tags with the data
+// key of the vault of the schema, bound to the fields of the record of its ID.
+// In zero-knowledge mode the fields are encrypted by the clients, so their
+// structure is checked only.
+func EncryptModel(s storage.Store, rawModel interface{}, schema string) (interface{}, error) {
+	if ZeroKnowledge() {
+		if err := checkClientEncrypted(rawModel); err != nil {
+			return nil, err
+		}
+		return rawModel, nil
+	}
+
+	keyID, key, err := VaultDataKey(s, schema)
+	if err != nil {
+		logger.Errorf("Error while finding data key of %s: %s", schema, err.Error())
+		return nil, err
+	}
+
+	scope := modelScope(rawModel, schema)
 	num := reflect.ValueOf(rawModel).Elem().NumField()
 
 	var tagVal string
@@ -176,21 +194,70 @@ func EncryptModel(rawModel interface{}) interface{} {
 		value := reflect.ValueOf(rawModel).Elem().Field(i).String()
 
 		if tagVal == "true" {
-			encrypted, err := Encrypt(value, viper.GetString("server.passphrase"))
+			value, err = sealField(value, scope.withField(reflect.TypeOf(rawModel).Elem().Field(i)), keyID, key)
 			if err != nil {
 				logger.Errorf("Error while encrypting: %s", err.Error())
+				return nil, err
 			}
-
-			value = base64.StdEncoding.EncodeToString(encrypted)
 			reflect.ValueOf(rawModel).Elem().Field(i).SetString(value)
 		}
 	}
 
-	return rawModel
+	return rawModel, nil
 }
 
-// DecryptModel decrypts struct pointer according to struct tags
-func DecryptModel(rawModel interface{}) (interface{}, error) {
+// sealCreated checks the struct pointer to be created in the vault of the
+// schema and clears its fields to encrypt, the returned function encrypts
+// them bound to the record once it has its ID. In zero-knowledge mode the
+// fields are kept as the clients encrypted them.
+func sealCreated[T any](s storage.Store, rawModel *T, schema string) (func(*T) (map[string]interface{}, error), error) {
+	if ZeroKnowledge() {
+		return nil, checkClientEncrypted(rawModel)
+	}
+
+	keyID, key, err := VaultDataKey(s, schema)
+	if err != nil {
+		logger.Errorf("Error while finding data key of %s: %s", schema, err.Error())
+		return nil, err
+	}
+
+	value := reflect.ValueOf(rawModel).Elem()
+	plain := map[int]string{}
+	for i := 0; i < value.NumField(); i++ {
+		if value.Type().Field(i).Tag.Get("encrypt") == "true" {
+			plain[i] = value.Field(i).String()
+			value.Field(i).SetString("")
+		}
+	}
+
+	return func(created *T) (map[string]interface{}, error) {
+		scope := modelScope(created, schema)
+		value := reflect.ValueOf(created).Elem()
+		columns := map[string]interface{}{}
+		for i, decrypted := range plain {
+			field := scope.withField(value.Type().Field(i))
+			sealed, err := sealField(decrypted, field, keyID, key)
+			if err != nil {
+				logger.Errorf("Error while encrypting: %s", err.Error())
+				return nil, err
+			}
+			value.Field(i).SetString(sealed)
+			columns[field.field] = sealed
+		}
+		return columns, nil
+	}, nil
+}
+
+// DecryptModel decrypts struct pointer of the vault of the schema according to
+// struct tags. Fields encrypted with the passphrase by the older versions are
+// decrypted too, the ones of the clients in zero-knowledge mode are returned
+// as they are.
+func DecryptModel(rawModel interface{}, schema string) (interface{}, error) {
+	if ZeroKnowledge() {
+		return rawModel, nil
+	}
+
+	scope := modelScope(rawModel, schema)
 	num := reflect.ValueOf(rawModel).Elem().NumField()
 
 	var tagVal string
@@ -199,6 +266,16 @@ func DecryptModel(rawModel interface{}) (interface{}, error) {
 		tagVal = reflect.TypeOf(rawModel).Elem().Field(i).Tag.Get("encrypt")
 		value := reflect.ValueOf(rawModel).Elem().Field(i).String()
 
+		if tagVal == "true" && isDataKeyField(value) {
+			decrypted, err := openField(value, scope.withField(reflect.TypeOf(rawModel).Elem().Field(i)))
+			if err != nil {
+				logger.Errorf("Error while decrypting: %s", err.Error())
+				lastErr = err
+			}
+			reflect.ValueOf(rawModel).Elem().Field(i).SetString(decrypted)
+			continue
+		}
+
 		if tagVal == "true" && value != "" {
 			valueByte, err := base64.StdEncoding.DecodeString(value)
 			if err != nil {
@@ -65,16 +65,19 @@ func TestEncryptModel(t *testing.T) {
 		ID:        uint(1),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
-		DeletedAt: nil,
 		Title:     "Baslik",
 		URL:       "yakuter.com",
 		Username:  "yakuter",
 		Password:  "123456",
 	}
 
-	encLogin := EncryptModel(login)
+	s := newFakeDataKeyStore()
+	encLogin, err := EncryptModel(s, login, "user_encrypt_model")
+	if err != nil {
+		t.Error(err)
+	}
 
-	decLogin, err := DecryptModel(encLogin)
+	decLogin, err := DecryptModel(encLogin, "user_encrypt_model")
 
 	if err != nil {
 		t.Error(err)
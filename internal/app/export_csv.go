@@ -0,0 +1,191 @@
+package app
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// csvExportPageSize is the number of items read at once while streaming CSV exports
+const csvExportPageSize = 500
+
+// csvExport is the CSV layout of an item type
+type csvExport struct {
+	header []string
+	// rows finds the decrypted page of items and returns their rows and the last ID
+	rows func(s storage.Store, opts *model.ListOptions, schema string) ([][]string, uint, error)
+}
+
+var csvExports = map[string]csvExport{
+	ItemTypeLogin: {
+		header: []string{"Title", "URL", "Username", "Password", "TOTP", "Extra"},
+		rows: func(s storage.Store, opts *model.ListOptions, schema string) ([][]string, uint, error) {
+			items, _, err := s.Logins().FindAll(opts, schema)
+			if err != nil {
+				return nil, 0, err
+			}
+			rows := make([][]string, len(items))
+			var last uint
+			for i := range items {
+				if _, err := DecryptModel(&items[i], schema); err != nil {
+					return nil, 0, err
+				}
+				rows[i], last = loginCSVRow(&items[i]), items[i].ID
+			}
+			return rows, last, nil
+		},
+	},
+	ItemTypeCreditCard: {
+		header: []string{"Card Name", "Cardholder Name", "Type", "Number", "Verification Number", "Expiry Date"},
+		rows: func(s storage.Store, opts *model.ListOptions, schema string) ([][]string, uint, error) {
+			items, _, err := s.CreditCards().FindAll(opts, schema)
+			if err != nil {
+				return nil, 0, err
+			}
+			rows := make([][]string, len(items))
+			var last uint
+			for i := range items {
+				if _, err := DecryptModel(&items[i], schema); err != nil {
+					return nil, 0, err
+				}
+				rows[i], last = creditCardCSVRow(&items[i]), items[i].ID
+			}
+			return rows, last, nil
+		},
+	},
+	ItemTypeBankAccount: {
+		header: []string{"Bank Name", "Bank Code", "Account Name", "Account Number", "IBAN", "Currency", "Password"},
+		rows: func(s storage.Store, opts *model.ListOptions, schema string) ([][]string, uint, error) {
+			items, _, err := s.BankAccounts().FindAll(opts, schema)
+			if err != nil {
+				return nil, 0, err
+			}
+			rows := make([][]string, len(items))
+			var last uint
+			for i := range items {
+				if _, err := DecryptModel(&items[i], schema); err != nil {
+					return nil, 0, err
+				}
+				rows[i], last = bankAccountCSVRow(&items[i]), items[i].ID
+			}
+			return rows, last, nil
+		},
+	},
+	ItemTypeNote: {
+		header: []string{"Title", "Note"},
+		rows: func(s storage.Store, opts *model.ListOptions, schema string) ([][]string, uint, error) {
+			items, _, err := s.Notes().FindAll(opts, schema)
+			if err != nil {
+				return nil, 0, err
+			}
+			rows := make([][]string, len(items))
+			var last uint
+			for i := range items {
+				if _, err := DecryptModel(&items[i], schema); err != nil {
+					return nil, 0, err
+				}
+				rows[i], last = []string{items[i].Title, items[i].Note}, items[i].ID
+			}
+			return rows, last, nil
+		},
+	},
+	ItemTypeEmail: {
+		header: []string{"Title", "Email", "Password"},
+		rows: func(s storage.Store, opts *model.ListOptions, schema string) ([][]string, uint, error) {
+			items, _, err := s.Emails().FindAll(opts, schema)
+			if err != nil {
+				return nil, 0, err
+			}
+			rows := make([][]string, len(items))
+			var last uint
+			for i := range items {
+				if _, err := DecryptModel(&items[i], schema); err != nil {
+					return nil, 0, err
+				}
+				rows[i], last = []string{items[i].Title, items[i].Email, items[i].Password}, items[i].ID
+			}
+			return rows, last, nil
+		},
+	},
+	ItemTypeServer: {
+		header: []string{"Title", "IP", "Username", "Password", "URL", "Hosting Username",
+			"Hosting Password", "Admin Username", "Admin Password", "Extra"},
+		rows: func(s storage.Store, opts *model.ListOptions, schema string) ([][]string, uint, error) {
+			items, _, err := s.Servers().FindAll(opts, schema)
+			if err != nil {
+				return nil, 0, err
+			}
+			rows := make([][]string, len(items))
+			var last uint
+			for i := range items {
+				if _, err := DecryptModel(&items[i], schema); err != nil {
+					return nil, 0, err
+				}
+				rows[i], last = serverCSVRow(&items[i]), items[i].ID
+			}
+			return rows, last, nil
+		},
+	},
+}
+
+// IsCSVExportType checks if the items of the type can be exported as CSV
+func IsCSVExportType(itemType string) bool {
+	_, ok := csvExports[itemType]
+	return ok
+}
+
+// WriteCSVExport streams the decrypted items of the type to out as CSV. Items
+// are read page by page in ID order, so the whole vault is never held in memory.
+func WriteCSVExport(s storage.Store, itemType, schema string, out io.Writer) error {
+	export, ok := csvExports[itemType]
+	if !ok {
+		return fmt.Errorf("unknown item type %q", itemType)
+	}
+
+	writer := csv.NewWriter(out)
+	if err := writer.Write(export.header); err != nil {
+		return err
+	}
+
+	opts := &model.ListOptions{Limit: csvExportPageSize, Sort: "id", Direction: "asc"}
+	for {
+		rows, last, err := export.rows(s, opts, schema)
+		if err != nil {
+			return err
+		}
+		if err := writer.WriteAll(rows); err != nil {
+			return err
+		}
+		// Pages are sent to the client as soon as they are written
+		if flusher, ok := out.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		if len(rows) < csvExportPageSize {
+			return nil
+		}
+		opts.Cursor = last
+	}
+}
+
+func loginCSVRow(login *model.Login) []string {
+	return []string{login.Title, login.URL, login.Username, login.Password, login.TOTPSecret, login.Extra}
+}
+
+func creditCardCSVRow(card *model.CreditCard) []string {
+	return []string{card.CardName, card.CardholderName, card.Type, card.Number, card.VerificationNumber, card.ExpiryDate}
+}
+
+func bankAccountCSVRow(account *model.BankAccount) []string {
+	return []string{account.BankName, account.BankCode, account.AccountName, account.AccountNumber,
+		account.IBAN, account.Currency, account.Password}
+}
+
+func serverCSVRow(server *model.Server) []string {
+	return []string{server.Title, server.IP, server.Username, server.Password, server.URL,
+		server.HostingUsername, server.HostingPassword, server.AdminUsername, server.AdminPassword,
+		server.Extra}
+}
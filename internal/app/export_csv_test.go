@@ -0,0 +1,13 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteCSVExportUnknownType(t *testing.T) {
+	assert.True(t, IsCSVExportType(ItemTypeServer))
+	assert.False(t, IsCSVExportType("collection"))
+	assert.Error(t, WriteCSVExport(nil, "collection", "user1", nil))
+}
@@ -0,0 +1,176 @@
+package app
+
+import (
+	"io"
+	"time"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/tobischo/gokeepasslib/v3"
+	w "github.com/tobischo/gokeepasslib/v3/wrappers"
+)
+
+// keePassRootGroup is the name of the root group of the exported databases
+const keePassRootGroup = "Passwall"
+
+// keePassDefaultFields are the strings every KeePass entry has
+var keePassDefaultFields = map[string]bool{
+	"Title": true, "UserName": true, "Password": true, "URL": true, "Notes": true,
+}
+
+// keePassItem is a vault item converted to KeePass entry values
+type keePassItem struct {
+	folderID  *uint
+	createdAt time.Time
+	updatedAt time.Time
+	values    []gokeepasslib.ValueData
+}
+
+// ExportKeePass writes the vault backup as a KDBX 4 database encrypted with
+// the password. Folders become groups, the fields without a standard KeePass
+// place are written as custom strings.
+func ExportKeePass(backup *model.VaultBackup, password string, out io.Writer) error {
+	if len(password) < minSecureKeyLength {
+		return ErrShortBackupPassword
+	}
+
+	db := gokeepasslib.NewDatabase(gokeepasslib.WithDatabaseKDBXVersion4())
+	db.Credentials = gokeepasslib.NewPasswordCredentials(password)
+	db.Content.Meta.DatabaseName = keePassRootGroup
+	db.Content.Root.Groups = []gokeepasslib.Group{buildKeePassGroups(backup)}
+
+	if err := db.LockProtectedEntries(); err != nil {
+		return err
+	}
+	return gokeepasslib.NewEncoder(out).Encode(db)
+}
+
+// buildKeePassGroups builds the root group with the folder tree and the items
+func buildKeePassGroups(backup *model.VaultBackup) gokeepasslib.Group {
+	known := map[uint]bool{}
+	for _, folder := range backup.Folders {
+		known[folder.ID] = true
+	}
+
+	// Items and subfolders by folder, 0 is the root group
+	entries := map[uint][]gokeepasslib.Entry{}
+	for _, item := range keePassItems(backup) {
+		var folderID uint
+		if item.folderID != nil && known[*item.folderID] {
+			folderID = *item.folderID
+		}
+
+		entry := gokeepasslib.NewEntry(gokeepasslib.WithEntryFormattedTime(false))
+		entry.Times.CreationTime = &w.TimeWrapper{Time: item.createdAt.UTC()}
+		entry.Times.LastModificationTime = &w.TimeWrapper{Time: item.updatedAt.UTC()}
+		entry.Values = item.values
+		entries[folderID] = append(entries[folderID], entry)
+	}
+
+	children := map[uint][]model.Folder{}
+	for _, folder := range sortFoldersByParent(backup.Folders) {
+		var parentID uint
+		if folder.ParentID != nil {
+			parentID = *folder.ParentID
+		}
+		children[parentID] = append(children[parentID], folder)
+	}
+
+	var build func(id uint, name string, visited map[uint]bool) gokeepasslib.Group
+	build = func(id uint, name string, visited map[uint]bool) gokeepasslib.Group {
+		visited[id] = true
+		group := gokeepasslib.NewGroup(gokeepasslib.WithGroupFormattedTime(false))
+		group.Name = name
+		group.Entries = entries[id]
+		for _, child := range children[id] {
+			if !visited[child.ID] {
+				group.Groups = append(group.Groups, build(child.ID, child.Name, visited))
+			}
+		}
+		return group
+	}
+
+	return build(0, keePassRootGroup, map[uint]bool{})
+}
+
+// keePassItems converts all the items of the backup to KeePass entry values
+func keePassItems(backup *model.VaultBackup) []keePassItem {
+	items := []keePassItem{}
+	add := func(folderID *uint, createdAt, updatedAt time.Time, values ...gokeepasslib.ValueData) {
+		// Empty custom strings are left out, the standard ones are always there
+		item := keePassItem{folderID: folderID, createdAt: createdAt, updatedAt: updatedAt}
+		for _, value := range values {
+			if value.Value.Content != "" || keePassDefaultFields[value.Key] {
+				item.values = append(item.values, value)
+			}
+		}
+		items = append(items, item)
+	}
+
+	for _, login := range backup.Logins {
+		add(login.FolderID, login.CreatedAt, login.UpdatedAt,
+			keePassValue("Title", login.Title, false),
+			keePassValue("UserName", login.Username, false),
+			keePassValue("Password", login.Password, true),
+			keePassValue("URL", login.URL, false),
+			keePassValue("Notes", login.Extra, false),
+			keePassValue("otp", login.TOTPSecret, true),
+		)
+	}
+	for _, card := range backup.CreditCards {
+		add(card.FolderID, card.CreatedAt, card.UpdatedAt,
+			keePassValue("Title", card.CardName, false),
+			keePassValue("UserName", card.CardholderName, false),
+			keePassValue("Password", card.VerificationNumber, true),
+			keePassValue("Card Type", card.Type, false),
+			keePassValue("Card Number", card.Number, true),
+			keePassValue("Expiry Date", card.ExpiryDate, false),
+		)
+	}
+	for _, account := range backup.BankAccounts {
+		add(account.FolderID, account.CreatedAt, account.UpdatedAt,
+			keePassValue("Title", account.BankName, false),
+			keePassValue("UserName", account.AccountName, false),
+			keePassValue("Password", account.Password, true),
+			keePassValue("Bank Code", account.BankCode, false),
+			keePassValue("Account Number", account.AccountNumber, true),
+			keePassValue("IBAN", account.IBAN, true),
+			keePassValue("Currency", account.Currency, false),
+		)
+	}
+	for _, note := range backup.Notes {
+		add(note.FolderID, note.CreatedAt, note.UpdatedAt,
+			keePassValue("Title", note.Title, false),
+			keePassValue("Notes", note.Note, true),
+		)
+	}
+	for _, email := range backup.Emails {
+		add(email.FolderID, email.CreatedAt, email.UpdatedAt,
+			keePassValue("Title", email.Title, false),
+			keePassValue("UserName", email.Email, false),
+			keePassValue("Password", email.Password, true),
+		)
+	}
+	for _, server := range backup.Servers {
+		add(server.FolderID, server.CreatedAt, server.UpdatedAt,
+			keePassValue("Title", server.Title, false),
+			keePassValue("UserName", server.Username, false),
+			keePassValue("Password", server.Password, true),
+			keePassValue("URL", server.URL, false),
+			keePassValue("Notes", server.Extra, false),
+			keePassValue("IP", server.IP, false),
+			keePassValue("Hosting Username", server.HostingUsername, false),
+			keePassValue("Hosting Password", server.HostingPassword, true),
+			keePassValue("Admin Username", server.AdminUsername, false),
+			keePassValue("Admin Password", server.AdminPassword, true),
+		)
+	}
+
+	return items
+}
+
+func keePassValue(key, value string, protected bool) gokeepasslib.ValueData {
+	return gokeepasslib.ValueData{
+		Key:   key,
+		Value: gokeepasslib.V{Content: value, Protected: w.NewBoolWrapper(protected)},
+	}
+}
@@ -0,0 +1,43 @@
+package app
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportKeePass(t *testing.T) {
+	work, dev := uint(1), uint(2)
+	backup := &model.VaultBackup{
+		Folders: []model.Folder{
+			{ID: dev, Name: "Dev", ParentID: &work},
+			{ID: work, Name: "Work"},
+		},
+		Logins: []model.Login{
+			{Title: "GitHub", URL: "https://github.com", Username: "octo", Password: "secret", TOTPSecret: "JBSWY3DP", FolderID: &dev},
+		},
+		Servers: []model.Server{
+			{Title: "Box", IP: "10.0.0.1", Password: "toor"},
+		},
+	}
+
+	assert.Equal(t, ErrShortBackupPassword, ExportKeePass(backup, "short", new(bytes.Buffer)))
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, ExportKeePass(backup, "correct horse", buf))
+
+	// Exported database is read back by the KeePass importer
+	items, err := ParseKeePass(buf.Bytes(), model.ImportOptions{Password: "correct horse"})
+	assert.NoError(t, err)
+	if assert.Len(t, items, 2) {
+		assert.Equal(t, "Work/Dev", items[1].Folder)
+		assert.Equal(t, "secret", items[1].Login.Password)
+		assert.Equal(t, "JBSWY3DP", items[1].Login.TOTPSecret)
+
+		assert.Empty(t, items[0].Folder)
+		assert.Equal(t, "toor", items[0].Login.Password)
+		assert.Equal(t, "IP: 10.0.0.1", items[0].Login.Extra)
+	}
+}
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/passwall/passwall-server/model"
+)
+
+const (
+	exportManifestFormat  = "passwall-export"
+	exportManifestVersion = 1
+
+	// ExportTypeBackup is the type of the encrypted vault backups
+	ExportTypeBackup = "backup"
+	// ExportTypeCSV is the type of the CSV exports of an item type
+	ExportTypeCSV = "csv"
+	// ExportTypeKeePass is the type of the KDBX exports
+	ExportTypeKeePass = "kdbx"
+)
+
+var (
+	// ErrExportSignature represents message for export manifests without a valid signature
+	ErrExportSignature = errors.New("export signature is missing or invalid")
+	// ErrExportTampered represents message for export files not matching their manifest
+	ErrExportTampered = errors.New("export file is truncated or changed")
+)
+
+// ExportDigest hashes an export while it is written, so streamed exports are
+// signed once they are complete
+type ExportDigest struct {
+	exportType string
+	itemType   string
+	hash       hash.Hash
+	size       int64
+}
+
+// NewExportDigest returns the digest of an export of the type
+func NewExportDigest(exportType, itemType string) *ExportDigest {
+	return &ExportDigest{exportType: exportType, itemType: itemType, hash: sha256.New()}
+}
+
+// Write adds the written part of the export to the digest
+func (d *ExportDigest) Write(p []byte) (int, error) {
+	d.size += int64(len(p))
+	return d.hash.Write(p)
+}
+
+// Sign returns the manifest of the written export and its signature
+func (d *ExportDigest) Sign(items int) (manifest, signature []byte, err error) {
+	key := exportSigningKey()
+	manifest, err = json.Marshal(model.ExportManifest{
+		Format:    exportManifestFormat,
+		Version:   exportManifestVersion,
+		Type:      d.exportType,
+		ItemType:  d.itemType,
+		Items:     items,
+		Size:      d.size,
+		SHA256:    hex.EncodeToString(d.hash.Sum(nil)),
+		KeyID:     exportKeyID(key.Public().(ed25519.PublicKey)),
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return manifest, ed25519.Sign(key, manifest), nil
+}
+
+// SignExport returns the signed manifest of the whole export
+func SignExport(exportType, itemType string, items int, data []byte) (manifest, signature []byte, err error) {
+	digest := NewExportDigest(exportType, itemType)
+	digest.Write(data)
+	return digest.Sign(items)
+}
+
+// VerifyExport checks the signature of the manifest with the key of the server
+// or a trusted one, then checks the size and hash of the export against it.
+// It is called before anything of the export is written to the vault.
+func VerifyExport(manifestJSON, signature, data []byte) (*model.ExportManifest, error) {
+	manifest := new(model.ExportManifest)
+	if err := json.Unmarshal(manifestJSON, manifest); err != nil || manifest.Format != exportManifestFormat {
+		return nil, ErrExportSignature
+	}
+	if manifest.Version > exportManifestVersion {
+		return nil, fmt.Errorf("unsupported export manifest version %d", manifest.Version)
+	}
+
+	key, ok := exportVerifyingKeys()[manifest.KeyID]
+	if !ok || !ed25519.Verify(key, manifestJSON, signature) {
+		return nil, ErrExportSignature
+	}
+
+	sum := sha256.Sum256(data)
+	if int64(len(data)) != manifest.Size || hex.EncodeToString(sum[:]) != manifest.SHA256 {
+		return nil, ErrExportTampered
+	}
+	return manifest, nil
+}
+
+// ExportPublicKey returns the base64 public key the exports of the server are
+// verified with
+func ExportPublicKey() string {
+	return base64.StdEncoding.EncodeToString(exportSigningKey().Public().(ed25519.PublicKey))
+}
+
+// exportSigningKey returns the configured signing key of the exports, servers
+// without one derive it from the secret
+func exportSigningKey() ed25519.PrivateKey {
+	if seed, err := base64.StdEncoding.DecodeString(viper.GetString("server.exportSigningKey")); err == nil && len(seed) == ed25519.SeedSize {
+		return ed25519.NewKeyFromSeed(seed)
+	}
+	mac := hmac.New(sha256.New, []byte(viper.GetString("server.secret")))
+	mac.Write([]byte("passwall export signing key"))
+	return ed25519.NewKeyFromSeed(mac.Sum(nil))
+}
+
+// exportVerifyingKeys returns the key of the server and the trusted keys by ID
+func exportVerifyingKeys() map[string]ed25519.PublicKey {
+	public := exportSigningKey().Public().(ed25519.PublicKey)
+	keys := map[string]ed25519.PublicKey{exportKeyID(public): public}
+	for _, trusted := range viper.GetStringSlice("server.exportTrustedKeys") {
+		key, err := base64.StdEncoding.DecodeString(trusted)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			continue
+		}
+		keys[exportKeyID(key)] = key
+	}
+	return keys
+}
+
+// exportKeyID identifies the public key in the manifests
+func exportKeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
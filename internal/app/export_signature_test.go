@@ -0,0 +1,60 @@
+package app
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyExport(t *testing.T) {
+	data := []byte("title,url,username,password\nMail,https://mail.io,john,secret\n")
+	manifest, signature, err := SignExport(ExportTypeCSV, ItemTypeLogin, 1, data)
+	require.NoError(t, err)
+
+	verified, err := VerifyExport(manifest, signature, data)
+	require.NoError(t, err)
+	assert.Equal(t, ExportTypeCSV, verified.Type)
+	assert.Equal(t, ItemTypeLogin, verified.ItemType)
+	assert.Equal(t, int64(len(data)), verified.Size)
+
+	_, err = VerifyExport(manifest, signature, data[:len(data)-10])
+	assert.Equal(t, ErrExportTampered, err)
+	_, err = VerifyExport(manifest, signature, append([]byte("Bank,https://bank.io,john,1234\n"), data...))
+	assert.Equal(t, ErrExportTampered, err)
+
+	// Changed manifests don't match the signature
+	changed := bytes.Replace(manifest, []byte(`"items":1`), []byte(`"items":2`), 1)
+	_, err = VerifyExport(changed, signature, data)
+	assert.Equal(t, ErrExportSignature, err)
+	_, err = VerifyExport(manifest, nil, data)
+	assert.Equal(t, ErrExportSignature, err)
+}
+
+func TestVerifyExportTrustedKeys(t *testing.T) {
+	defer viper.Set("server.exportSigningKey", viper.GetString("server.exportSigningKey"))
+	defer viper.Set("server.exportTrustedKeys", viper.GetStringSlice("server.exportTrustedKeys"))
+
+	data := []byte("exported on another server")
+	seed := make([]byte, ed25519.SeedSize)
+	seed[0] = 1
+	viper.Set("server.exportSigningKey", base64.StdEncoding.EncodeToString(seed))
+	manifest, signature, err := SignExport(ExportTypeKeePass, "", 3, data)
+	require.NoError(t, err)
+	otherKey := ExportPublicKey()
+
+	// Exports of other keys are accepted only when their key is trusted
+	viper.Set("server.exportSigningKey", "")
+	viper.Set("server.exportTrustedKeys", []string{})
+	_, err = VerifyExport(manifest, signature, data)
+	assert.Equal(t, ErrExportSignature, err)
+
+	viper.Set("server.exportTrustedKeys", []string{otherKey})
+	verified, err := VerifyExport(manifest, signature, data)
+	require.NoError(t, err)
+	assert.Equal(t, 3, verified.Items)
+}
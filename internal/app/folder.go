@@ -0,0 +1,74 @@
+package app
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+var (
+	// ErrFolderCycle represents message for moving a folder under itself
+	ErrFolderCycle = errors.New("a folder can't be moved under itself")
+)
+
+// CreateFolder creates a folder under the parent folder if given
+func CreateFolder(s storage.Store, dto *model.FolderDTO, schema string) (*model.Folder, error) {
+	if dto.ParentID != nil {
+		if _, err := s.Folders().FindByID(*dto.ParentID, schema); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.Folders().Create(model.ToFolder(dto), schema)
+}
+
+// UpdateFolder renames or moves the folder
+func UpdateFolder(s storage.Store, folder *model.Folder, dto *model.FolderDTO, schema string) (*model.Folder, error) {
+	// Walk up from the new parent to be sure the folder isn't one of its ancestors
+	for parentID := dto.ParentID; parentID != nil; {
+		if *parentID == folder.ID {
+			return nil, ErrFolderCycle
+		}
+		parent, err := s.Folders().FindByID(*parentID, schema)
+		if err != nil {
+			return nil, err
+		}
+		parentID = parent.ParentID
+	}
+
+	folder.Name = dto.Name
+	folder.ParentID = dto.ParentID
+	return s.Folders().Update(folder, schema)
+}
+
+// EnsureFolderPath finds or creates the folders of the path like "Work/Servers"
+// and returns the ID of the last one. Empty path returns nil.
+func EnsureFolderPath(s storage.Store, path, schema string) (*uint, error) {
+	var parentID *uint
+	for _, name := range SplitFolderPath(path) {
+		folder, err := s.Folders().FindByName(name, parentID, schema)
+		if err != nil {
+			folder, err = s.Folders().Create(&model.Folder{Name: name, ParentID: parentID}, schema)
+			if err != nil {
+				return nil, err
+			}
+		}
+		id := folder.ID
+		parentID = &id
+	}
+
+	return parentID, nil
+}
+
+// SplitFolderPath splits the folder path by slashes and backslashes
+func SplitFolderPath(path string) []string {
+	names := []string{}
+	for _, name := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
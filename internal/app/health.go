@@ -0,0 +1,80 @@
+package app
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/passwall/passwall-server/internal/config"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/cache"
+)
+
+// Health statuses of the server and its components
+const (
+	HealthStatusUp   = "up"
+	HealthStatusDown = "down"
+)
+
+// CheckReadiness checks the databases of the server and its regions, the
+// cache, the startup migrations and the SMTP configuration
+func CheckReadiness(s storage.Store, c cache.Cache) *model.Readiness {
+	email := config.Current().Email
+	components := map[string]model.ComponentStatus{
+		"database":   componentStatus(s.Ping()),
+		"cache":      componentStatus(c.Ping()),
+		"migrations": componentStatus(checkMigrations()),
+		"smtp":       componentStatus(checkSMTPConfig(email.Host, email.Port, email.FromEmail)),
+	}
+	for _, name := range s.RegionNames() {
+		region, err := s.Region(name)
+		if err == nil {
+			err = region.Ping()
+		}
+		components["database."+name] = componentStatus(err)
+	}
+
+	return &model.Readiness{Status: readinessStatus(components), Components: components}
+}
+
+func checkMigrations() error {
+	done, failed := MigrationStatus()
+	if !done {
+		return errors.New("migrations are running")
+	}
+	if len(failed) > 0 {
+		return errors.New(strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+func checkSMTPConfig(host, port, from string) error {
+	if host == "" {
+		return errors.New("email host is not set")
+	}
+	if _, err := strconv.Atoi(port); err != nil {
+		return errors.New("email port is not a number")
+	}
+	if from == "" {
+		return errors.New("email from address is not set")
+	}
+	return nil
+}
+
+func componentStatus(err error) model.ComponentStatus {
+	if err != nil {
+		return model.ComponentStatus{Status: HealthStatusDown, Error: err.Error()}
+	}
+	return model.ComponentStatus{Status: HealthStatusUp}
+}
+
+// readinessStatus is up when all the components are up
+func readinessStatus(components map[string]model.ComponentStatus) string {
+	for _, component := range components {
+		if component.Status != HealthStatusUp {
+			return HealthStatusDown
+		}
+	}
+	return HealthStatusUp
+}
@@ -0,0 +1,38 @@
+package app
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckSMTPConfig(t *testing.T) {
+	tests := []struct {
+		name             string
+		host, port, from string
+		wantErr          bool
+	}{
+		{name: "Configured", host: "smtp.passwall.io", port: "587", from: "hello@passwall.io"},
+		{name: "No host", port: "587", from: "hello@passwall.io", wantErr: true},
+		{name: "Invalid port", host: "smtp.passwall.io", port: "smtp", from: "hello@passwall.io", wantErr: true},
+		{name: "No from address", host: "smtp.passwall.io", port: "25", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSMTPConfig(tt.host, tt.port, tt.from)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestReadinessStatus(t *testing.T) {
+	up := componentStatus(nil)
+	down := componentStatus(errors.New("connection refused"))
+	assert.Equal(t, "connection refused", down.Error)
+
+	assert.Equal(t, HealthStatusUp, readinessStatus(map[string]model.ComponentStatus{"database": up, "smtp": up}))
+	assert.Equal(t, HealthStatusDown, readinessStatus(map[string]model.ComponentStatus{"database": down, "smtp": up}))
+}
@@ -0,0 +1,123 @@
+package app
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/passwall/passwall-server/model"
+)
+
+const (
+	hibpRangeURL           = "https://api.pwnedpasswords.com/range/"
+	hibpBreachedAccountURL = "https://haveibeenpwned.com/api/v3/breachedaccount/"
+	hibpUserAgent          = "passwall-server"
+)
+
+var hibpClient = &http.Client{Timeout: 10 * time.Second}
+
+// PasswordSHA1 returns the upper case hex SHA-1 hash of the password as used by HIBP
+func PasswordSHA1(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// PwnedPasswordCount returns how many times the password with the given SHA-1 hash
+// appears in known breaches. Only the first five characters of the hash are sent
+// to the HIBP range API (k-anonymity).
+func PwnedPasswordCount(hash string) (int, error) {
+	hash = strings.ToUpper(hash)
+	if len(hash) != 40 {
+		return 0, fmt.Errorf("invalid SHA-1 hash")
+	}
+
+	suffixes, err := pwnedRange(hash[:5])
+	if err != nil {
+		return 0, err
+	}
+
+	return suffixes[hash[5:]], nil
+}
+
+// pwnedRange fetches the hash suffixes of the given prefix from the HIBP range API
+func pwnedRange(prefix string) (map[string]int, error) {
+	req, err := http.NewRequest(http.MethodGet, hibpRangeURL+prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("User-Agent", hibpUserAgent)
+	req.Header.Add("Add-Padding", "true")
+
+	res, err := hibpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("hibp range api returned status %d", res.StatusCode)
+	}
+
+	return parsePwnedRange(res.Body)
+}
+
+// parsePwnedRange parses the "SUFFIX:COUNT" lines of a range API response.
+// Padding entries with zero count are skipped.
+func parsePwnedRange(r io.Reader) (map[string]int, error) {
+	suffixes := make(map[string]int)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.SplitN(strings.TrimSpace(scanner.Text()), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		count, err := strconv.Atoi(parts[1])
+		if err != nil || count == 0 {
+			continue
+		}
+		suffixes[strings.ToUpper(parts[0])] = count
+	}
+
+	return suffixes, scanner.Err()
+}
+
+// BreachedAccount returns the breaches the email address appears in.
+// The HIBP breached account API requires an API key.
+func BreachedAccount(email, apiKey string) ([]model.BreachDTO, error) {
+	req, err := http.NewRequest(http.MethodGet, hibpBreachedAccountURL+url.PathEscape(email)+"?truncateResponse=false", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("User-Agent", hibpUserAgent)
+	req.Header.Add("hibp-api-key", apiKey)
+
+	res, err := hibpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	// Not found means the account is not in any breach
+	if res.StatusCode == http.StatusNotFound {
+		return []model.BreachDTO{}, nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("hibp breached account api returned status %d", res.StatusCode)
+	}
+
+	breaches := []model.BreachDTO{}
+	if err := json.NewDecoder(res.Body).Decode(&breaches); err != nil {
+		return nil, err
+	}
+
+	return breaches, nil
+}
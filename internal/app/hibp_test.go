@@ -0,0 +1,31 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordSHA1(t *testing.T) {
+	assert.Equal(t, "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8", PasswordSHA1("password"))
+}
+
+func TestParsePwnedRange(t *testing.T) {
+	body := "1E4C9B93F3F0682250B6CF8331B7EE68FD8:3730471\r\n" +
+		"011053FD0102E94D6AE2F8B83D76FAF94F6:0\r\n" +
+		"invalid line\r\n" +
+		"012A7CA357541F0AC487871FEEC1891C49C:2\r\n"
+
+	suffixes, err := parsePwnedRange(strings.NewReader(body))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{
+		"1E4C9B93F3F0682250B6CF8331B7EE68FD8": 3730471,
+		"012A7CA357541F0AC487871FEEC1891C49C": 2,
+	}, suffixes)
+}
+
+func TestPwnedPasswordCountInvalidHash(t *testing.T) {
+	_, err := PwnedPasswordCount("5BAA6")
+	assert.Error(t, err)
+}
@@ -1,5 +1,316 @@
 package app
 
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+var (
+	// ErrUnsupportedImportFormat represents message for unknown import formats
+	ErrUnsupportedImportFormat = errors.New("unsupported import format")
+)
+
+// importParsers are the parsers of the supported export formats
+var importParsers = map[string]func(data []byte, opts model.ImportOptions) ([]model.ImportItem, error){
+	"lastpass": ParseLastPassCSV,
+	"1pux":     Parse1PUX,
+	"keepass":  ParseKeePass,
+	"browser":  ParseBrowserCSV,
+	"chrome":   ParseBrowserCSV,
+	"edge":     ParseBrowserCSV,
+	"firefox":  ParseBrowserCSV,
+	"dashlane": ParseDashlaneCSV,
+	"csv":      ParseMappedCSV,
+	"json":     ParseJSONLogins,
+}
+
+// ParseImport parses the export file of another password manager
+func ParseImport(format string, data []byte, opts model.ImportOptions) ([]model.ImportItem, error) {
+	parse, ok := importParsers[strings.ToLower(format)]
+	if !ok {
+		return nil, ErrUnsupportedImportFormat
+	}
+	return parse(data, opts)
+}
+
+// ParseJSONLogins parses the login list the import endpoint accepts as JSON
+func ParseJSONLogins(data []byte, _ model.ImportOptions) ([]model.ImportItem, error) {
+	var logins []model.LoginDTO
+	if err := json.Unmarshal(data, &logins); err != nil {
+		return nil, err
+	}
+
+	items := make([]model.ImportItem, len(logins))
+	for i := range logins {
+		items[i] = model.ImportItem{Login: &logins[i]}
+	}
+	return items, nil
+}
+
+// SaveImport creates the folders and the items parsed from an export file.
+// Failing items don't stop the import, they are reported in the summary. Logins
+// with the domain and username of an existing one are skipped if the options say so.
+// Dry runs only validate the items and report what would be imported.
+func SaveImport(s storage.Store, items []model.ImportItem, schema string, opts model.ImportOptions) (*model.ImportSummary, error) {
+	return saveImport(context.Background(), s, items, schema, opts, nil)
+}
+
+// saveImport saves the items until the context is canceled, progress is called
+// with the number of processed items after each item. The summary of the items
+// processed so far is returned with the context error on cancellation.
+func saveImport(ctx context.Context, s storage.Store, items []model.ImportItem, schema string, opts model.ImportOptions, progress func(processed int)) (*model.ImportSummary, error) {
+	summary := &model.ImportSummary{DryRun: opts.DryRun, Types: map[string]int{}}
+	folderIDs := map[string]*uint{}
+
+	// Keys of the logins in the vault and the ones imported so far
+	loginKeys := map[string]bool{}
+	if opts.SkipDuplicates || opts.DryRun {
+		logins, err := s.Logins().All(schema)
+		if err != nil {
+			return nil, err
+		}
+		for i := range logins {
+			if key, ok := duplicateLoginKey(&logins[i]); ok {
+				loginKeys[key] = true
+			}
+		}
+	}
+
+	for i, item := range items {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+		if progress != nil && i > 0 {
+			progress(i)
+		}
+
+		fail := func(err error) {
+			summary.Failed++
+			summary.Errors = append(summary.Errors, fmt.Sprintf("item %d: %v", i+1, err))
+		}
+
+		itemType := importItemType(item)
+		if err := validateImportItem(item); err != nil {
+			fail(err)
+			continue
+		}
+		summary.Types[itemType]++
+
+		if item.Login != nil {
+			indexed := model.ToLogin(item.Login)
+			SetLoginSearchIndex(indexed)
+			if key, ok := duplicateLoginKey(indexed); ok {
+				if loginKeys[key] {
+					summary.Duplicates++
+					if opts.SkipDuplicates {
+						summary.Skipped++
+						continue
+					}
+				}
+				loginKeys[key] = true
+			}
+		}
+
+		path := strings.Join(SplitFolderPath(item.Folder), "/")
+		if opts.DryRun {
+			if _, ok := folderIDs[path]; !ok && path != "" {
+				folderIDs[path] = nil
+				summary.Folders = append(summary.Folders, path)
+			}
+			summary.Created++
+			continue
+		}
+
+		// Folders of a failing item aren't left behind
+		err := s.WithTransaction(func(tx storage.Store) error {
+			folderID, ok := folderIDs[path]
+			if !ok {
+				id, err := EnsureFolderPath(tx, path, schema)
+				if err != nil {
+					return err
+				}
+				folderID = id
+			}
+			if err := saveImportItem(tx, item, folderID, schema); err != nil {
+				return err
+			}
+			folderIDs[path] = folderID
+			return nil
+		})
+		if err != nil {
+			fail(err)
+			continue
+		}
+		summary.Created++
+	}
+
+	if progress != nil {
+		progress(len(items))
+	}
+	return summary, nil
+}
+
+// importItemType returns the type of the item set in the import item
+func importItemType(item model.ImportItem) string {
+	switch {
+	case item.Login != nil:
+		return ItemTypeLogin
+	case item.CreditCard != nil:
+		return ItemTypeCreditCard
+	case item.BankAccount != nil:
+		return ItemTypeBankAccount
+	case item.Note != nil:
+		return ItemTypeNote
+	case item.Email != nil:
+		return ItemTypeEmail
+	case item.Server != nil:
+		return ItemTypeServer
+	}
+	return ""
+}
+
+// validateImportItem checks the item has the fields to be found in the vault later
+func validateImportItem(item model.ImportItem) error {
+	var empty bool
+	switch importItemType(item) {
+	case ItemTypeLogin:
+		empty = item.Login.Title == "" && item.Login.URL == "" && item.Login.Username == "" && item.Login.Password == ""
+		if err := PayloadValidator(item.Login); err != nil {
+			return err
+		}
+	case ItemTypeCreditCard:
+		empty = item.CreditCard.CardName == "" && item.CreditCard.Number == ""
+	case ItemTypeBankAccount:
+		empty = item.BankAccount.BankName == "" && item.BankAccount.AccountNumber == "" && item.BankAccount.IBAN == ""
+	case ItemTypeNote:
+		empty = item.Note.Title == "" && item.Note.Note == ""
+	case ItemTypeEmail:
+		empty = item.Email.Title == "" && item.Email.Email == ""
+	case ItemTypeServer:
+		empty = item.Server.Title == "" && item.Server.IP == "" && item.Server.URL == ""
+	default:
+		empty = true
+	}
+
+	if empty {
+		return errors.New("empty item")
+	}
+	return nil
+}
+
+func saveImportItem(s storage.Store, item model.ImportItem, folderID *uint, schema string) error {
+	var err error
+	switch importItemType(item) {
+	case ItemTypeLogin:
+		item.Login.FolderID = folderID
+		_, err = CreateLogin(s, item.Login, schema)
+	case ItemTypeCreditCard:
+		item.CreditCard.FolderID = folderID
+		_, err = CreateCreditCard(s, item.CreditCard, schema)
+	case ItemTypeBankAccount:
+		item.BankAccount.FolderID = folderID
+		_, err = CreateBankAccount(s, item.BankAccount, schema)
+	case ItemTypeNote:
+		item.Note.FolderID = folderID
+		_, err = CreateNote(s, item.Note, schema)
+	case ItemTypeEmail:
+		item.Email.FolderID = folderID
+		_, err = CreateEmail(s, item.Email, schema)
+	case ItemTypeServer:
+		item.Server.FolderID = folderID
+		_, err = CreateServer(s, item.Server, schema)
+	}
+	return err
+}
+
+// importFields are the named fields of an imported item in order. The fields
+// having a place in the item are taken, the rest are kept as text.
+type importFields []importField
+
+type importField struct {
+	key   string
+	label string
+	value string
+}
+
+// add appends the field, empty values are ignored
+func (f *importFields) add(key, label, value string) {
+	if value == "" {
+		return
+	}
+	*f = append(*f, importField{key: key, label: label, value: value})
+}
+
+// take returns the value of the field and removes it
+func (f *importFields) take(key string) string {
+	for i, field := range *f {
+		if field.key == key {
+			*f = append((*f)[:i], (*f)[i+1:]...)
+			return field.value
+		}
+	}
+	return ""
+}
+
+// text formats the remaining fields as "Label: Value" lines followed by the notes
+func (f importFields) text(notes string) string {
+	lines := []string{}
+	for _, field := range f {
+		lines = append(lines, field.label+": "+field.value)
+	}
+	if notes != "" {
+		lines = append(lines, notes)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// withNotesItem returns the item with a note keeping the text which has no place
+// in the item, the note is left out when the text is empty
+func withNotesItem(item model.ImportItem, title, text string) []model.ImportItem {
+	items := []model.ImportItem{item}
+	if text != "" {
+		items = append(items, model.ImportItem{
+			Folder: item.Folder,
+			Note:   &model.NoteDTO{Title: title + " (notes)", Note: text},
+		})
+	}
+	return items
+}
+
+// readCSV reads all the records of a CSV file, the byte order mark is ignored
+func readCSV(data []byte) ([][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	return reader.ReadAll()
+}
+
+// csvColumns maps the lowercased column names of the header to their indexes
+func csvColumns(header []string) map[string]int {
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	return columns
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
 // InsertValues ...
 /* func InsertValues(s storage.Store, url, username, password string, file *os.File) error {
 	var urlIndex, usernameIndex, passwordIndex int
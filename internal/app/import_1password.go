@@ -0,0 +1,288 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/passwall/passwall-server/model"
+)
+
+// 1Password item categories
+const (
+	onePasswordLogin       = "001"
+	onePasswordCreditCard  = "002"
+	onePasswordPassword    = "005"
+	onePasswordBankAccount = "101"
+	onePasswordDatabase    = "102"
+	onePasswordServer      = "110"
+	onePasswordEmail       = "111"
+)
+
+type onePasswordExport struct {
+	Accounts []struct {
+		Vaults []struct {
+			Attrs struct {
+				Name string `json:"name"`
+			} `json:"attrs"`
+			Items []onePasswordItem `json:"items"`
+		} `json:"vaults"`
+	} `json:"accounts"`
+}
+
+type onePasswordItem struct {
+	CategoryUUID string `json:"categoryUuid"`
+	State        string `json:"state"`
+	Details      struct {
+		LoginFields []struct {
+			Value       string `json:"value"`
+			Designation string `json:"designation"`
+		} `json:"loginFields"`
+		NotesPlain string `json:"notesPlain"`
+		Password   string `json:"password"`
+		Sections   []struct {
+			Title  string `json:"title"`
+			Fields []struct {
+				Title string                     `json:"title"`
+				ID    string                     `json:"id"`
+				Value map[string]json.RawMessage `json:"value"`
+			} `json:"fields"`
+		} `json:"sections"`
+	} `json:"details"`
+	Overview struct {
+		Title string `json:"title"`
+		URL   string `json:"url"`
+		URLs  []struct {
+			URL string `json:"url"`
+		} `json:"urls"`
+	} `json:"overview"`
+}
+
+// Parse1PUX parses the 1PUX export of 1Password which is a zip file having the
+// items in export.data file. Vaults become folders, logins, credit cards, bank
+// accounts, email accounts, servers and databases are mapped to their item types
+// and the other categories become notes. Section fields without a place in the
+// item are kept in the extra field or in a separate note.
+func Parse1PUX(data []byte, _ model.ImportOptions) ([]model.ImportItem, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid 1PUX file: %v", err)
+	}
+
+	var content []byte
+	for _, file := range archive.File {
+		if file.Name != "export.data" {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err = ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if content == nil {
+		return nil, errors.New("export.data couldn't be found in 1PUX file")
+	}
+
+	var export onePasswordExport
+	if err := json.Unmarshal(content, &export); err != nil {
+		return nil, fmt.Errorf("invalid 1PUX export data: %v", err)
+	}
+
+	items := []model.ImportItem{}
+	for _, account := range export.Accounts {
+		for _, vault := range account.Vaults {
+			for _, item := range vault.Items {
+				if item.State == "trashed" {
+					continue
+				}
+				items = append(items, parse1PasswordItem(item, vault.Attrs.Name)...)
+			}
+		}
+	}
+
+	return items, nil
+}
+
+func parse1PasswordItem(item onePasswordItem, folder string) []model.ImportItem {
+	title := item.Overview.Title
+	notes := item.Details.NotesPlain
+	fields, totp := onePasswordFields(item)
+	result := model.ImportItem{Folder: folder}
+
+	switch item.CategoryUUID {
+	case onePasswordLogin, onePasswordPassword:
+		login := &model.LoginDTO{
+			Title:      title,
+			URL:        NormalizeImportURL(item.Overview.URL),
+			TOTPSecret: totp,
+			Password:   item.Details.Password,
+		}
+		for _, field := range item.Details.LoginFields {
+			switch field.Designation {
+			case "username":
+				login.Username = field.Value
+			case "password":
+				login.Password = field.Value
+			}
+		}
+		for _, u := range item.Overview.URLs {
+			if u := NormalizeImportURL(u.URL); u != login.URL {
+				fields.add("url", "URL", u)
+			}
+		}
+		login.Extra = fields.text(notes)
+		result.Login = login
+		return []model.ImportItem{result}
+	case onePasswordCreditCard:
+		result.CreditCard = &model.CreditCardDTO{
+			CardName:           title,
+			CardholderName:     fields.take("cardholder"),
+			Type:               fields.take("type"),
+			Number:             fields.take("ccnum"),
+			VerificationNumber: fields.take("cvv"),
+			ExpiryDate:         fields.take("expiry"),
+		}
+	case onePasswordBankAccount:
+		bankCode := fields.take("swift")
+		if bankCode == "" {
+			bankCode = fields.take("routingNo")
+		}
+		result.BankAccount = &model.BankAccountDTO{
+			BankName:      firstNonEmpty(fields.take("bankName"), title),
+			BankCode:      bankCode,
+			AccountName:   firstNonEmpty(fields.take("owner"), title),
+			AccountNumber: fields.take("accountNo"),
+			IBAN:          fields.take("iban"),
+			Password:      fields.take("telephonePin"),
+		}
+	case onePasswordEmail:
+		result.Email = &model.EmailDTO{
+			Title:    title,
+			Email:    fields.take("pop_username"),
+			Password: fields.take("pop_password"),
+		}
+	case onePasswordServer:
+		result.Server = &model.ServerDTO{
+			Title:         title,
+			URL:           NormalizeImportURL(fields.take("url")),
+			Username:      fields.take("username"),
+			Password:      fields.take("password"),
+			AdminUsername: fields.take("admin_console_username"),
+			AdminPassword: fields.take("admin_console_password"),
+		}
+		result.Server.Extra = fields.text(notes)
+		return []model.ImportItem{result}
+	case onePasswordDatabase:
+		result.Server = &model.ServerDTO{
+			Title:    title,
+			IP:       fields.take("hostname"),
+			Username: fields.take("username"),
+			Password: fields.take("password"),
+		}
+		result.Server.Extra = fields.text(notes)
+		return []model.ImportItem{result}
+	default:
+		if totp != "" {
+			fields.add("totp", "One-time password", totp)
+		}
+		result.Note = &model.NoteDTO{Title: title, Note: fields.text(notes)}
+		return []model.ImportItem{result}
+	}
+
+	if totp != "" {
+		fields.add("totp", "One-time password", totp)
+	}
+	return withNotesItem(result, title, fields.text(notes))
+}
+
+// onePasswordFields flattens the section fields of the item, the first TOTP
+// seed is returned separately
+func onePasswordFields(item onePasswordItem) (importFields, string) {
+	fields := importFields{}
+	var totp string
+	for _, section := range item.Details.Sections {
+		for _, field := range section.Fields {
+			kind, value := onePasswordValue(field.Value)
+			if kind == "totp" && totp == "" {
+				totp = value
+				continue
+			}
+
+			label := firstNonEmpty(field.Title, field.ID)
+			if section.Title != "" {
+				label = section.Title + " / " + label
+			}
+			fields.add(field.ID, label, value)
+		}
+	}
+	return fields, totp
+}
+
+// onePasswordValue converts the typed value of a section field to text
+func onePasswordValue(value map[string]json.RawMessage) (string, string) {
+	// Values have a single key naming their type
+	kinds := make([]string, 0, len(value))
+	for kind := range value {
+		kinds = append(kinds, kind)
+	}
+	if len(kinds) == 0 {
+		return "", ""
+	}
+	sort.Strings(kinds)
+	kind, raw := kinds[0], value[kinds[0]]
+
+	switch kind {
+	case "monthYear":
+		var monthYear int
+		if err := json.Unmarshal(raw, &monthYear); err != nil || monthYear == 0 {
+			return kind, ""
+		}
+		return kind, fmt.Sprintf("%02d/%d", monthYear%100, monthYear/100)
+	case "date":
+		var date int64
+		if err := json.Unmarshal(raw, &date); err != nil || date == 0 {
+			return kind, ""
+		}
+		return kind, time.Unix(date, 0).UTC().Format("2006-01-02")
+	case "email":
+		var email struct {
+			EmailAddress string `json:"email_address"`
+		}
+		if err := json.Unmarshal(raw, &email); err == nil && email.EmailAddress != "" {
+			return kind, email.EmailAddress
+		}
+	case "address":
+		var address map[string]string
+		if err := json.Unmarshal(raw, &address); err != nil {
+			return kind, ""
+		}
+		parts := []string{}
+		for _, key := range []string{"street", "city", "state", "zip", "country"} {
+			if address[key] != "" {
+				parts = append(parts, address[key])
+			}
+		}
+		return kind, strings.Join(parts, ", ")
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return kind, text
+	}
+	// Other values like references and SSH keys are kept as they are
+	if string(raw) == "null" {
+		return kind, ""
+	}
+	return kind, string(raw)
+}
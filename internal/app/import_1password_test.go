@@ -0,0 +1,70 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const onePasswordExportData = `{"accounts":[{"vaults":[{"attrs":{"name":"Personal"},"items":[
+{"categoryUuid":"001","state":"active",
+ "details":{"loginFields":[{"value":"octo","designation":"username"},{"value":"secret","designation":"password"}],
+  "notesPlain":"my notes",
+  "sections":[{"title":"","fields":[{"title":"one-time password","id":"TOTP_1","value":{"totp":"otpauth://totp/GitHub?secret=JBSWY3DP"}}]},
+   {"title":"Recovery","fields":[{"title":"code","id":"code","value":{"concealed":"1234-5678"}}]}]},
+ "overview":{"title":"GitHub","url":"https://GitHub.com/","urls":[{"url":"https://github.com/"},{"url":"gist.github.com"}]}},
+{"categoryUuid":"002","state":"active",
+ "details":{"sections":[{"title":"","fields":[
+  {"title":"cardholder name","id":"cardholder","value":{"string":"John Doe"}},
+  {"title":"number","id":"ccnum","value":{"creditCardNumber":"4111111111111111"}},
+  {"title":"verification number","id":"cvv","value":{"concealed":"123"}},
+  {"title":"expiry date","id":"expiry","value":{"monthYear":202512}},
+  {"title":"issuing bank","id":"bank","value":{"string":"Big Bank"}}]}]},
+ "overview":{"title":"Visa"}},
+{"categoryUuid":"003","state":"archived","details":{"notesPlain":"remember"},"overview":{"title":"Note"}},
+{"categoryUuid":"001","state":"trashed","details":{},"overview":{"title":"Deleted"}}
+]}]}]}`
+
+func zip1PUX(t *testing.T, data string) []byte {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	f, err := w.Create("export.data")
+	assert.NoError(t, err)
+	_, err = f.Write([]byte(data))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestParse1PUX(t *testing.T) {
+	items, err := Parse1PUX(zip1PUX(t, onePasswordExportData), model.ImportOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, items, 4)
+
+	login := items[0].Login
+	assert.Equal(t, "Personal", items[0].Folder)
+	assert.Equal(t, "https://github.com", login.URL)
+	assert.Equal(t, "octo", login.Username)
+	assert.Equal(t, "secret", login.Password)
+	assert.Equal(t, "otpauth://totp/GitHub?secret=JBSWY3DP", login.TOTPSecret)
+	assert.Equal(t, "Recovery / code: 1234-5678\nURL: https://gist.github.com\nmy notes", login.Extra)
+
+	card := items[1].CreditCard
+	assert.Equal(t, "John Doe", card.CardholderName)
+	assert.Equal(t, "123", card.VerificationNumber)
+	assert.Equal(t, "12/2025", card.ExpiryDate)
+	assert.Equal(t, "issuing bank: Big Bank", items[2].Note.Note)
+
+	assert.Equal(t, "remember", items[3].Note.Note)
+}
+
+func TestParse1PUXInvalid(t *testing.T) {
+	_, err := Parse1PUX([]byte("not a zip"), model.ImportOptions{})
+	assert.Error(t, err)
+
+	_, err = Parse1PUX(zip1PUX(t, "{"), model.ImportOptions{})
+	assert.Error(t, err)
+}
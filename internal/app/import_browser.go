@@ -0,0 +1,71 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/passwall/passwall-server/model"
+)
+
+// ParseBrowserCSV parses the password CSV exports of Chrome, Edge, Firefox and
+// Safari. Columns are found by their names, so the column order of the browsers
+// doesn't matter. Logins without a name are titled with the host of their URL.
+func ParseBrowserCSV(data []byte, _ model.ImportOptions) ([]model.ImportItem, error) {
+	records, err := readCSV(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("empty browser export")
+	}
+
+	columns := csvColumns(records[0])
+	for _, name := range []string{"url", "username", "password"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%s column couldn't be found in browser export", name)
+		}
+	}
+
+	items := []model.ImportItem{}
+	for _, record := range records[1:] {
+		field := func(names ...string) string {
+			for _, name := range names {
+				if i, ok := columns[name]; ok && i < len(record) && record[i] != "" {
+					return record[i]
+				}
+			}
+			return ""
+		}
+
+		// Firefox exports its internal accounts with chrome:// URLs
+		rawURL := field("url")
+		if strings.HasPrefix(rawURL, "chrome://") {
+			continue
+		}
+
+		loginURL := NormalizeImportURL(rawURL)
+		items = append(items, model.ImportItem{
+			Login: &model.LoginDTO{
+				Title:      firstNonEmpty(field("name", "title"), urlHost(loginURL)),
+				URL:        loginURL,
+				Username:   field("username"),
+				Password:   field("password"),
+				TOTPSecret: field("otpauth"),
+				Extra:      field("note", "notes"),
+			},
+		})
+	}
+
+	return items, nil
+}
+
+// urlHost returns the host of the URL without www prefix
+func urlHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
@@ -0,0 +1,51 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBrowserCSV(t *testing.T) {
+	tests := []struct {
+		name   string
+		export string
+		want   model.LoginDTO
+	}{
+		{
+			name: "Chrome",
+			export: "name,url,username,password,note\n" +
+				"GitHub,https://github.com/,octo,secret,work\n",
+			want: model.LoginDTO{Title: "GitHub", URL: "https://github.com", Username: "octo", Password: "secret", Extra: "work"},
+		},
+		{
+			name: "Firefox",
+			export: "\"url\",\"username\",\"password\",\"httpRealm\",\"formActionOrigin\",\"guid\",\"timeCreated\",\"timeLastUsed\",\"timePasswordChanged\"\n" +
+				"\"chrome://FirefoxAccounts\",\"uid\",\"{}\",\"Firefox Accounts credentials\",,\"{1}\",\"1\",\"1\",\"1\"\n" +
+				"\"https://www.example.com\",\"john\",\"pass\",,\"https://www.example.com\",\"{2}\",\"1\",\"1\",\"1\"\n",
+			want: model.LoginDTO{Title: "example.com", URL: "https://www.example.com", Username: "john", Password: "pass"},
+		},
+		{
+			name: "Safari",
+			export: "Title,URL,Username,Password,Notes,OTPAuth\n" +
+				"Mail,https://mail.example.com/,me,pw,,otpauth://totp/Mail?secret=JBSWY3DP\n",
+			want: model.LoginDTO{Title: "Mail", URL: "https://mail.example.com", Username: "me", Password: "pw", TOTPSecret: "otpauth://totp/Mail?secret=JBSWY3DP"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := ParseBrowserCSV([]byte(tt.export), model.ImportOptions{})
+			assert.NoError(t, err)
+			if assert.Len(t, items, 1) {
+				assert.Equal(t, tt.want, *items[0].Login)
+			}
+		})
+	}
+}
+
+func TestParseBrowserCSVMissingColumn(t *testing.T) {
+	_, err := ParseBrowserCSV([]byte("name,url,password\n"), model.ImportOptions{})
+	assert.Error(t, err)
+}
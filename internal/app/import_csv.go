@@ -0,0 +1,126 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/passwall/passwall-server/model"
+)
+
+// csvImportFields are the login fields a CSV column can be mapped to
+var csvImportFields = []string{"title", "url", "username", "password", "totp", "extra", "folder"}
+
+// dashlaneColumns are the candidate columns of the login fields in Dashlane
+// exports, older exports have otpSecret and newer ones have otpUrl
+var dashlaneColumns = map[string][]string{
+	"title":    {"title"},
+	"url":      {"url"},
+	"username": {"username"},
+	"password": {"password"},
+	"totp":     {"otpurl", "otpsecret"},
+	"extra":    {"note"},
+	"folder":   {"category"},
+}
+
+// ParseMappedCSV parses a CSV file with a header row into logins using the
+// field to column mapping of the options. Columns which aren't mapped are kept
+// in the extra field.
+func ParseMappedCSV(data []byte, opts model.ImportOptions) ([]model.ImportItem, error) {
+	records, err := readCSV(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("empty CSV file")
+	}
+
+	return parseMappedRecords(records, opts.Mapping)
+}
+
+// ParseDashlaneCSV parses the credentials CSV export of Dashlane
+func ParseDashlaneCSV(data []byte, _ model.ImportOptions) ([]model.ImportItem, error) {
+	records, err := readCSV(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("empty Dashlane export")
+	}
+
+	columns := csvColumns(records[0])
+	mapping := map[string]string{}
+	for field, candidates := range dashlaneColumns {
+		for _, column := range candidates {
+			if _, ok := columns[column]; ok {
+				mapping[field] = column
+				break
+			}
+		}
+	}
+
+	return parseMappedRecords(records, mapping)
+}
+
+func parseMappedRecords(records [][]string, mapping map[string]string) ([]model.ImportItem, error) {
+	if mapping["url"] == "" && mapping["username"] == "" && mapping["password"] == "" {
+		return nil, errors.New("mapping must have url, username or password field")
+	}
+
+	columns := csvColumns(records[0])
+	indexes := map[string]int{}
+	mapped := map[int]bool{}
+	for field, column := range mapping {
+		if !isCSVImportField(field) {
+			return nil, fmt.Errorf("unknown field %s in mapping", field)
+		}
+		i, ok := columns[strings.ToLower(strings.TrimSpace(column))]
+		if !ok {
+			return nil, fmt.Errorf("%s column couldn't be found in CSV file", column)
+		}
+		indexes[field] = i
+		mapped[i] = true
+	}
+
+	items := []model.ImportItem{}
+	for _, record := range records[1:] {
+		field := func(name string) string {
+			if i, ok := indexes[name]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+
+		// Keep the values of the columns which aren't mapped
+		fields := importFields{}
+		for i, value := range record {
+			if !mapped[i] && i < len(records[0]) {
+				fields.add(records[0][i], records[0][i], value)
+			}
+		}
+
+		loginURL := NormalizeImportURL(field("url"))
+		items = append(items, model.ImportItem{
+			Folder: field("folder"),
+			Login: &model.LoginDTO{
+				Title:      firstNonEmpty(field("title"), urlHost(loginURL)),
+				URL:        loginURL,
+				Username:   field("username"),
+				Password:   field("password"),
+				TOTPSecret: field("totp"),
+				Extra:      fields.text(field("extra")),
+			},
+		})
+	}
+
+	return items, nil
+}
+
+func isCSVImportField(field string) bool {
+	for _, f := range csvImportFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMappedCSV(t *testing.T) {
+	export := "Site,Login,Secret,Group,PIN\n" +
+		"example.com,john,pass,Work/Web,1234\n"
+	opts := model.ImportOptions{Mapping: map[string]string{
+		"url":      "Site",
+		"username": "login",
+		"password": "Secret",
+		"folder":   "Group",
+	}}
+
+	items, err := ParseMappedCSV([]byte(export), opts)
+	assert.NoError(t, err)
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, "Work/Web", items[0].Folder)
+		assert.Equal(t, model.LoginDTO{
+			Title:    "example.com",
+			URL:      "https://example.com",
+			Username: "john",
+			Password: "pass",
+			Extra:    "PIN: 1234",
+		}, *items[0].Login)
+	}
+}
+
+func TestParseMappedCSVInvalidMapping(t *testing.T) {
+	export := []byte("Site,Login,Secret\n")
+	tests := []map[string]string{
+		nil,
+		{"title": "Site"},
+		{"password": "Secret", "color": "Site"},
+		{"password": "Missing"},
+	}
+
+	for _, mapping := range tests {
+		_, err := ParseMappedCSV(export, model.ImportOptions{Mapping: mapping})
+		assert.Error(t, err, mapping)
+	}
+}
+
+func TestParseDashlaneCSV(t *testing.T) {
+	export := "username,username2,username3,title,password,note,url,category,otpSecret\n" +
+		"octo,octo@example.com,,GitHub,secret,work,https://github.com,Dev,JBSWY3DP\n"
+
+	items, err := ParseDashlaneCSV([]byte(export), model.ImportOptions{})
+	assert.NoError(t, err)
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, "Dev", items[0].Folder)
+		assert.Equal(t, model.LoginDTO{
+			Title:      "GitHub",
+			URL:        "https://github.com",
+			Username:   "octo",
+			Password:   "secret",
+			TOTPSecret: "JBSWY3DP",
+			Extra:      "username2: octo@example.com\nwork",
+		}, *items[0].Login)
+	}
+}
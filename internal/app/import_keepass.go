@@ -0,0 +1,110 @@
+package app
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+var (
+	// ErrImportPasswordRequired represents message for encrypted files imported without password
+	ErrImportPasswordRequired = errors.New("password is required to open the file")
+
+	// kdbxSignature is the magic number every KDBX 3 and 4 file starts with
+	kdbxSignature = []byte{0x03, 0xd9, 0xa2, 0x9a, 0x67, 0xfb, 0x4b, 0xb5}
+)
+
+// keePassStandardFields are the entry strings mapped to the login fields
+var keePassStandardFields = map[string]bool{
+	"Title": true, "UserName": true, "Password": true, "URL": true, "Notes": true,
+	"otp": true, "TOTP Seed": true, "TOTP Settings": true,
+}
+
+// ParseKeePass parses KeePass KDBX 3/4 databases opened with the password of
+// the options, or the decrypted XML export of KeePass. The group hierarchy becomes
+// folders and the custom strings of the entries are kept in the extra field.
+func ParseKeePass(data []byte, opts model.ImportOptions) ([]model.ImportItem, error) {
+	var content *gokeepasslib.DBContent
+
+	if bytes.HasPrefix(data, kdbxSignature) {
+		if opts.Password == "" {
+			return nil, ErrImportPasswordRequired
+		}
+
+		db := gokeepasslib.NewDatabase()
+		db.Credentials = gokeepasslib.NewPasswordCredentials(opts.Password)
+		if err := gokeepasslib.NewDecoder(bytes.NewReader(data)).Decode(db); err != nil {
+			return nil, fmt.Errorf("couldn't open KeePass database: %v", err)
+		}
+		if err := db.UnlockProtectedEntries(); err != nil {
+			return nil, fmt.Errorf("couldn't open KeePass database: %v", err)
+		}
+		content = db.Content
+	} else {
+		content = new(gokeepasslib.DBContent)
+		if err := xml.Unmarshal(data, content); err != nil {
+			return nil, fmt.Errorf("invalid KeePass XML export: %v", err)
+		}
+	}
+	if content.Root == nil {
+		return nil, errors.New("empty KeePass database")
+	}
+
+	var recycleBin gokeepasslib.UUID
+	if content.Meta != nil && content.Meta.RecycleBinEnabled.Bool {
+		recycleBin = content.Meta.RecycleBinUUID
+	}
+
+	items := []model.ImportItem{}
+	for _, root := range content.Root.Groups {
+		// Entries of the root group have no folder
+		items = append(items, parseKeePassGroup(root, "", recycleBin)...)
+	}
+
+	return items, nil
+}
+
+func parseKeePassGroup(group gokeepasslib.Group, folder string, recycleBin gokeepasslib.UUID) []model.ImportItem {
+	items := []model.ImportItem{}
+	for _, entry := range group.Entries {
+		items = append(items, model.ImportItem{Folder: folder, Login: parseKeePassEntry(entry)})
+	}
+
+	for _, sub := range group.Groups {
+		if sub.UUID.Compare(recycleBin) {
+			continue
+		}
+		// Slashes are folder separators, keep them out of the group names
+		name := strings.NewReplacer("/", "-", "\\", "-").Replace(sub.Name)
+		path := name
+		if folder != "" {
+			path = folder + "/" + name
+		}
+		items = append(items, parseKeePassGroup(sub, path, recycleBin)...)
+	}
+
+	return items
+}
+
+func parseKeePassEntry(entry gokeepasslib.Entry) *model.LoginDTO {
+	fields := importFields{}
+	for _, value := range entry.Values {
+		if !keePassStandardFields[value.Key] {
+			fields.add(value.Key, value.Key, value.Value.Content)
+		}
+	}
+
+	return &model.LoginDTO{
+		Title:      entry.GetTitle(),
+		URL:        NormalizeImportURL(entry.GetContent("URL")),
+		Username:   entry.GetContent("UserName"),
+		Password:   entry.GetPassword(),
+		TOTPSecret: firstNonEmpty(entry.GetContent("otp"), entry.GetContent("TOTP Seed")),
+		Extra:      fields.text(entry.GetContent("Notes")),
+	}
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/tobischo/gokeepasslib/v3"
+	w "github.com/tobischo/gokeepasslib/v3/wrappers"
+)
+
+const keePassXMLExport = `<?xml version="1.0" encoding="utf-8" standalone="yes"?>
+<KeePassFile>
+	<Meta><RecycleBinEnabled>True</RecycleBinEnabled><RecycleBinUUID>AAAAAAAAAAAAAAAAAAAAAQ==</RecycleBinUUID></Meta>
+	<Root>
+		<Group>
+			<UUID>AAAAAAAAAAAAAAAAAAAAAA==</UUID>
+			<Name>Database</Name>
+			<Entry>
+				<String><Key>Title</Key><Value>Router</Value></String>
+				<String><Key>Password</Key><Value ProtectInMemory="True">admin</Value></String>
+			</Entry>
+			<Group>
+				<UUID>AAAAAAAAAAAAAAAAAAAAAg==</UUID>
+				<Name>Work/Dev</Name>
+				<Group>
+					<UUID>AAAAAAAAAAAAAAAAAAAAAw==</UUID>
+					<Name>Git</Name>
+					<Entry>
+						<String><Key>Title</Key><Value>GitHub</Value></String>
+						<String><Key>UserName</Key><Value>octo</Value></String>
+						<String><Key>URL</Key><Value>github.com</Value></String>
+						<String><Key>otp</Key><Value>otpauth://totp/GitHub?secret=JBSWY3DP</Value></String>
+						<String><Key>Recovery Code</Key><Value>1234</Value></String>
+						<String><Key>Notes</Key><Value>my notes</Value></String>
+					</Entry>
+				</Group>
+			</Group>
+			<Group>
+				<UUID>AAAAAAAAAAAAAAAAAAAAAQ==</UUID>
+				<Name>Recycle Bin</Name>
+				<Entry><String><Key>Title</Key><Value>Deleted</Value></String></Entry>
+			</Group>
+		</Group>
+	</Root>
+</KeePassFile>`
+
+func TestParseKeePassXML(t *testing.T) {
+	items, err := ParseKeePass([]byte(keePassXMLExport), model.ImportOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, items, 2)
+
+	assert.Empty(t, items[0].Folder)
+	assert.Equal(t, "admin", items[0].Login.Password)
+
+	login := items[1].Login
+	assert.Equal(t, "Work-Dev/Git", items[1].Folder)
+	assert.Equal(t, "https://github.com", login.URL)
+	assert.Equal(t, "octo", login.Username)
+	assert.Equal(t, "otpauth://totp/GitHub?secret=JBSWY3DP", login.TOTPSecret)
+	assert.Equal(t, "Recovery Code: 1234\nmy notes", login.Extra)
+}
+
+func TestParseKeePassKDBX(t *testing.T) {
+	entry := gokeepasslib.NewEntry()
+	entry.Values = append(entry.Values,
+		gokeepasslib.ValueData{Key: "Title", Value: gokeepasslib.V{Content: "Mail"}},
+		gokeepasslib.ValueData{Key: "Password", Value: gokeepasslib.V{Content: "hunter2", Protected: w.NewBoolWrapper(true)}},
+	)
+	sub := gokeepasslib.NewGroup()
+	sub.Name = "Personal"
+	sub.Entries = append(sub.Entries, entry)
+	root := gokeepasslib.NewGroup()
+	root.Groups = append(root.Groups, sub)
+
+	db := gokeepasslib.NewDatabase()
+	db.Credentials = gokeepasslib.NewPasswordCredentials("secret")
+	db.Content.Root = &gokeepasslib.RootData{Groups: []gokeepasslib.Group{root}}
+	assert.NoError(t, db.LockProtectedEntries())
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, gokeepasslib.NewEncoder(buf).Encode(db))
+
+	_, err := ParseKeePass(buf.Bytes(), model.ImportOptions{})
+	assert.Equal(t, ErrImportPasswordRequired, err)
+
+	_, err = ParseKeePass(buf.Bytes(), model.ImportOptions{Password: "wrong"})
+	assert.Error(t, err)
+
+	items, err := ParseKeePass(buf.Bytes(), model.ImportOptions{Password: "secret"})
+	assert.NoError(t, err)
+	assert.Len(t, items, 1)
+	assert.Equal(t, "Personal", items[0].Folder)
+	assert.Equal(t, "hunter2", items[0].Login.Password)
+}
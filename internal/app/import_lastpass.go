@@ -0,0 +1,187 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/passwall/passwall-server/model"
+)
+
+// lastPassSecureNoteURL is the URL LastPass puts to the secure notes in CSV exports
+const lastPassSecureNoteURL = "http://sn"
+
+// ParseLastPassCSV parses the CSV export of LastPass. Grouping column becomes the
+// folder path, secure notes with a known template become credit cards, bank
+// accounts, emails or servers and the other secure notes become notes.
+func ParseLastPassCSV(data []byte, _ model.ImportOptions) ([]model.ImportItem, error) {
+	records, err := readCSV(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("empty LastPass export")
+	}
+
+	columns := csvColumns(records[0])
+	for _, name := range []string{"url", "username", "password", "extra", "name", "grouping"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%s column couldn't be found in LastPass export", name)
+		}
+	}
+
+	items := []model.ImportItem{}
+	for _, record := range records[1:] {
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+
+		folder := field("grouping")
+		if folder == "(none)" {
+			folder = ""
+		}
+
+		if field("url") == lastPassSecureNoteURL {
+			items = append(items, parseLastPassNote(field("name"), field("extra"), folder)...)
+			continue
+		}
+
+		items = append(items, model.ImportItem{
+			Folder: folder,
+			Login: &model.LoginDTO{
+				Title:      field("name"),
+				URL:        NormalizeImportURL(field("url")),
+				Username:   field("username"),
+				Password:   field("password"),
+				TOTPSecret: field("totp"),
+				Extra:      field("extra"),
+			},
+		})
+	}
+
+	return items, nil
+}
+
+// parseLastPassNote converts a secure note to the item of its template. The
+// template fields which have no place in the item are kept in a separate note.
+func parseLastPassNote(title, extra, folder string) []model.ImportItem {
+	noteType, fields, notes := parseLastPassNoteFields(extra)
+	item := model.ImportItem{Folder: folder}
+
+	switch noteType {
+	case "":
+		item.Note = &model.NoteDTO{Title: title, Note: extra}
+		return []model.ImportItem{item}
+	case "Credit Card":
+		item.CreditCard = &model.CreditCardDTO{
+			CardName:           title,
+			CardholderName:     fields.take("Name on Card"),
+			Type:               fields.take("Type"),
+			Number:             fields.take("Number"),
+			VerificationNumber: fields.take("Security Code"),
+			ExpiryDate:         normalizeLastPassDate(fields.take("Expiration Date")),
+		}
+	case "Bank Account":
+		bankCode := fields.take("SWIFT Code")
+		if bankCode == "" {
+			bankCode = fields.take("Routing Number")
+		}
+		item.BankAccount = &model.BankAccountDTO{
+			BankName:      firstNonEmpty(fields.take("Bank Name"), title),
+			BankCode:      bankCode,
+			AccountName:   title,
+			AccountNumber: fields.take("Account Number"),
+			IBAN:          fields.take("IBAN Number"),
+			Password:      fields.take("Pin"),
+		}
+	case "Email Account":
+		item.Email = &model.EmailDTO{
+			Title:    title,
+			Email:    fields.take("Username"),
+			Password: fields.take("Password"),
+		}
+	case "Server":
+		item.Server = &model.ServerDTO{
+			Title:    title,
+			IP:       fields.take("Hostname"),
+			Username: fields.take("Username"),
+			Password: fields.take("Password"),
+		}
+		item.Server.Extra = fields.text(notes)
+		return []model.ImportItem{item}
+	default:
+		// Templates without a matching item type are kept as readable notes
+		item.Note = &model.NoteDTO{Title: title, Note: fields.text(notes)}
+		return []model.ImportItem{item}
+	}
+
+	return withNotesItem(item, title, fields.text(notes))
+}
+
+// parseLastPassNoteFields splits a templated secure note into its type, fields
+// and free notes. Notes field is always the last one and may have multiple lines.
+func parseLastPassNoteFields(extra string) (string, importFields, string) {
+	if !strings.HasPrefix(extra, "NoteType:") {
+		return "", nil, ""
+	}
+
+	var noteType string
+	fields := importFields{}
+	lines := strings.Split(strings.ReplaceAll(extra, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key, value := parts[0], strings.TrimSpace(parts[1])
+		switch key {
+		case "NoteType":
+			noteType = value
+		case "Notes":
+			notes := strings.Join(append([]string{parts[1]}, lines[i+1:]...), "\n")
+			return noteType, fields, strings.TrimSpace(notes)
+		default:
+			fields.add(key, key, value)
+		}
+	}
+
+	return noteType, fields, ""
+}
+
+// normalizeLastPassDate converts LastPass dates like "January,2025" to "01/2025"
+func normalizeLastPassDate(value string) string {
+	date, err := time.Parse("January,2006", value)
+	if err != nil {
+		return value
+	}
+	return date.Format("01/2006")
+}
+
+// NormalizeImportURL cleans the URLs of imported logins. URLs without scheme get
+// https, scheme and host are lowercased and the trailing slash of the root is removed.
+func NormalizeImportURL(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" || rawURL == "http://" || rawURL == "https://" {
+		return ""
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if u.Path == "/" && u.RawQuery == "" && u.Fragment == "" {
+		u.Path = ""
+	}
+	return u.String()
+}
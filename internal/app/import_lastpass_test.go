@@ -0,0 +1,73 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const lastPassExport = "url,username,password,totp,extra,name,grouping,fav\n" +
+	"GitHub.com/,octo,secret,JBSWY3DP,work account,GitHub,Work\\Dev,1\n" +
+	"http://sn,,,,Just a note,Plain note,(none),0\n" +
+	"http://sn,,,,\"NoteType:Credit Card\nLanguage:en-US\nName on Card:John Doe\nType:Visa\nNumber:4111111111111111\nSecurity Code:123\nStart Date:,\nExpiration Date:January,2025\nNotes:first line\nsecond: line\",Visa,Finance,0\n" +
+	"http://sn,,,,\"NoteType:Server\nHostname:10.0.0.1\nUsername:root\nPassword:toor\nNotes:\",Box,,0\n" +
+	"http://sn,,,,\"NoteType:Wi-Fi Password\nSSID:home\nPassword:wifipass\nNotes:\",Wi-Fi,,0\n"
+
+func TestParseLastPassCSV(t *testing.T) {
+	items, err := ParseLastPassCSV([]byte(lastPassExport), model.ImportOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, items, 6)
+
+	login := items[0]
+	assert.Equal(t, "Work\\Dev", login.Folder)
+	assert.Equal(t, "https://github.com", login.Login.URL)
+	assert.Equal(t, "octo", login.Login.Username)
+	assert.Equal(t, "JBSWY3DP", login.Login.TOTPSecret)
+	assert.Equal(t, "work account", login.Login.Extra)
+
+	assert.Empty(t, items[1].Folder)
+	assert.Equal(t, "Just a note", items[1].Note.Note)
+
+	card := items[2].CreditCard
+	assert.Equal(t, "Visa", card.CardName)
+	assert.Equal(t, "John Doe", card.CardholderName)
+	assert.Equal(t, "123", card.VerificationNumber)
+	assert.Equal(t, "01/2025", card.ExpiryDate)
+	assert.Equal(t, "Visa (notes)", items[3].Note.Title)
+	assert.Equal(t, "Language: en-US\nStart Date: ,\nfirst line\nsecond: line", items[3].Note.Note)
+
+	assert.Equal(t, "10.0.0.1", items[4].Server.IP)
+	assert.Equal(t, "toor", items[4].Server.Password)
+	assert.Empty(t, items[4].Server.Extra)
+
+	assert.Equal(t, "SSID: home\nPassword: wifipass", items[5].Note.Note)
+}
+
+func TestParseLastPassCSVMissingColumn(t *testing.T) {
+	_, err := ParseLastPassCSV([]byte("url,username,password\n"), model.ImportOptions{})
+	assert.Error(t, err)
+}
+
+func TestNormalizeImportURL(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"http://", ""},
+		{" Example.COM/ ", "https://example.com"},
+		{"HTTP://Example.com/Login?next=/", "http://example.com/Login?next=/"},
+		{"https://example.com:8443/", "https://example.com:8443"},
+		{"ftp://files.example.com/pub", "ftp://files.example.com/pub"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, NormalizeImportURL(tt.in), tt.in)
+	}
+}
+
+func TestSplitFolderPath(t *testing.T) {
+	assert.Equal(t, []string{"Work", "Dev"}, SplitFolderPath("Work\\Dev"))
+	assert.Equal(t, []string{"Work", "Dev"}, SplitFolderPath(" /Work/ Dev /"))
+	assert.Empty(t, SplitFolderPath(""))
+}
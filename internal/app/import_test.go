@@ -0,0 +1,46 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateImportItem(t *testing.T) {
+	tests := []struct {
+		name  string
+		item  model.ImportItem
+		valid bool
+	}{
+		{"login", model.ImportItem{Login: &model.LoginDTO{URL: "https://example.com"}}, true},
+		{"empty login", model.ImportItem{Login: &model.LoginDTO{Extra: "only notes"}}, false},
+		{"invalid login", model.ImportItem{Login: &model.LoginDTO{Title: "Mail", MaxPasswordAge: -1}}, false},
+		{"note", model.ImportItem{Note: &model.NoteDTO{Note: "text"}}, true},
+		{"empty card", model.ImportItem{CreditCard: &model.CreditCardDTO{Type: "Visa"}}, false},
+		{"no item", model.ImportItem{Folder: "Work"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateImportItem(tt.item)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestParseJSONLogins(t *testing.T) {
+	items, err := ParseJSONLogins([]byte(`[{"title":"Mail","username":"me"}]`), model.ImportOptions{})
+	assert.NoError(t, err)
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, ItemTypeLogin, importItemType(items[0]))
+		assert.Equal(t, "me", items[0].Login.Username)
+	}
+
+	_, err = ParseJSONLogins([]byte(`{}`), model.ImportOptions{})
+	assert.Error(t, err)
+}
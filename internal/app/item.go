@@ -0,0 +1,175 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/internal/storage/query"
+	"github.com/passwall/passwall-server/model"
+)
+
+// Item types stored in a vault
+const (
+	ItemTypeLogin       = "login"
+	ItemTypeCreditCard  = "credit_card"
+	ItemTypeBankAccount = "bank_account"
+	ItemTypeNote        = "note"
+	ItemTypeEmail       = "email"
+	ItemTypeServer      = "server"
+)
+
+var (
+	// ErrRevisionRequired represents message for updates without the revision of the item
+	ErrRevisionRequired = errors.New("revision of the item is required for updates")
+	// ErrRevisionConflict represents message for updates made on an outdated copy of the item
+	ErrRevisionConflict = query.ErrRevisionConflict
+)
+
+// VersionConflictError represents message for updates of the users,
+// organizations, collections, webhooks and announcements which were changed
+// by another request after they were read
+type VersionConflictError = query.VersionConflictError
+
+// checkRevision checks if the update is made on the current revision of the item.
+// Concurrent updates of the same revision are caught by the repositories.
+func checkRevision(expected, current uint) error {
+	if expected == 0 {
+		return ErrRevisionRequired
+	}
+	if expected != current {
+		return ErrRevisionConflict
+	}
+	return nil
+}
+
+// ItemExists checks if the item with the given type and id exists in the schema
+func ItemExists(s storage.Store, itemType string, id uint, schema string) error {
+	_, err := ItemTitle(s, itemType, id, schema)
+	return err
+}
+
+// ItemTitle finds the item with the given type and id and returns its title
+func ItemTitle(s storage.Store, itemType string, id uint, schema string) (string, error) {
+	switch itemType {
+	case ItemTypeLogin:
+		item, err := s.Logins().FindByID(id, schema)
+		if err != nil {
+			return "", err
+		}
+		return item.Title, nil
+	case ItemTypeCreditCard:
+		item, err := s.CreditCards().FindByID(id, schema)
+		if err != nil {
+			return "", err
+		}
+		return item.CardName, nil
+	case ItemTypeBankAccount:
+		item, err := s.BankAccounts().FindByID(id, schema)
+		if err != nil {
+			return "", err
+		}
+		return item.BankName, nil
+	case ItemTypeNote:
+		item, err := s.Notes().FindByID(id, schema)
+		if err != nil {
+			return "", err
+		}
+		return item.Title, nil
+	case ItemTypeEmail:
+		item, err := s.Emails().FindByID(id, schema)
+		if err != nil {
+			return "", err
+		}
+		return item.Title, nil
+	case ItemTypeServer:
+		item, err := s.Servers().FindByID(id, schema)
+		if err != nil {
+			return "", err
+		}
+		return item.Title, nil
+	}
+	return "", fmt.Errorf("unknown item type %q", itemType)
+}
+
+// ItemTitles finds the items of the type with the given ids at once and
+// returns their titles by id, the missing items aren't in the map
+func ItemTitles(s storage.Store, itemType string, ids []uint, schema string) (map[uint]string, error) {
+	titles := map[uint]string{}
+	var err error
+	switch itemType {
+	case ItemTypeLogin:
+		var items []model.Login
+		if items, err = s.Logins().FindByIDs(ids, schema); err == nil {
+			for _, item := range items {
+				titles[item.ID] = item.Title
+			}
+		}
+	case ItemTypeCreditCard:
+		var items []model.CreditCard
+		if items, err = s.CreditCards().FindByIDs(ids, schema); err == nil {
+			for _, item := range items {
+				titles[item.ID] = item.CardName
+			}
+		}
+	case ItemTypeBankAccount:
+		var items []model.BankAccount
+		if items, err = s.BankAccounts().FindByIDs(ids, schema); err == nil {
+			for _, item := range items {
+				titles[item.ID] = item.BankName
+			}
+		}
+	case ItemTypeNote:
+		var items []model.Note
+		if items, err = s.Notes().FindByIDs(ids, schema); err == nil {
+			for _, item := range items {
+				titles[item.ID] = item.Title
+			}
+		}
+	case ItemTypeEmail:
+		var items []model.Email
+		if items, err = s.Emails().FindByIDs(ids, schema); err == nil {
+			for _, item := range items {
+				titles[item.ID] = item.Title
+			}
+		}
+	case ItemTypeServer:
+		var items []model.Server
+		if items, err = s.Servers().FindByIDs(ids, schema); err == nil {
+			for _, item := range items {
+				titles[item.ID] = item.Title
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown item type %q", itemType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return titles, nil
+}
+
+// DeleteItem deletes the item with the given type and id and its relations
+func DeleteItem(s storage.Store, itemType string, id uint, schema string) error {
+	var err error
+	switch itemType {
+	case ItemTypeLogin:
+		err = s.Logins().Delete(id, schema)
+	case ItemTypeCreditCard:
+		err = s.CreditCards().Delete(id, schema)
+	case ItemTypeBankAccount:
+		err = s.BankAccounts().Delete(id, schema)
+	case ItemTypeNote:
+		err = s.Notes().Delete(id, schema)
+	case ItemTypeEmail:
+		err = s.Emails().Delete(id, schema)
+	case ItemTypeServer:
+		err = s.Servers().Delete(id, schema)
+	default:
+		return fmt.Errorf("unknown item type %q", itemType)
+	}
+	if err != nil {
+		return err
+	}
+	return s.Relations().DeleteByItem(itemType, id, schema)
+}
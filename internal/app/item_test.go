@@ -0,0 +1,13 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckRevision(t *testing.T) {
+	assert.Equal(t, ErrRevisionRequired, checkRevision(0, 3))
+	assert.Equal(t, ErrRevisionConflict, checkRevision(2, 3))
+	assert.NoError(t, checkRevision(3, 3))
+}
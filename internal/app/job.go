@@ -0,0 +1,174 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+// Job types
+const (
+	JobTypeImport      = "import"
+	JobTypeKeyRotation = "key_rotation"
+)
+
+// Job statuses
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+	JobStatusCanceled  = "canceled"
+)
+
+// jobProgressInterval is the number of processed items between progress updates
+const jobProgressInterval = 50
+
+var (
+	// ErrJobNotFound represents message for jobs which don't exist or belong to another user
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobFinished represents message for canceling jobs which are already finished
+	ErrJobFinished = errors.New("job is already finished")
+)
+
+// runningJobs keeps the cancel functions of the jobs running on this server
+var runningJobs = struct {
+	sync.Mutex
+	cancels map[uint]context.CancelFunc
+}{cancels: map[uint]context.CancelFunc{}}
+
+// StartImportJob saves the parsed import items in the background and returns
+// the job to follow its progress. Jobs are stored in the system store while the
+// items are saved to the vault store. The user is emailed when the job finishes.
+func StartImportJob(system, s storage.Store, userID uint, items []model.ImportItem, schema string, opts model.ImportOptions) (*model.Job, error) {
+	job, err := system.Jobs().Create(&model.Job{
+		UserID: userID,
+		Type:   JobTypeImport,
+		Status: JobStatusQueued,
+		Total:  len(items),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runningJobs.Lock()
+	runningJobs.cancels[job.ID] = cancel
+	runningJobs.Unlock()
+
+	// The goroutine works on its own copy, the returned job is the queued state
+	queued := *job
+	go runImportJob(ctx, system, s.As(Principal(PrincipalImport, job.ID)), job, items, schema, opts)
+
+	return &queued, nil
+}
+
+func runImportJob(ctx context.Context, system, s storage.Store, job *model.Job, items []model.ImportItem, schema string, opts model.ImportOptions) {
+	defer func() {
+		runningJobs.Lock()
+		cancel := runningJobs.cancels[job.ID]
+		delete(runningJobs.cancels, job.ID)
+		runningJobs.Unlock()
+		cancel()
+	}()
+
+	job.Status = JobStatusRunning
+	updateJob(system, job)
+
+	summary, err := saveImport(ctx, s, items, schema, opts, func(processed int) {
+		job.Processed = processed
+		if processed%jobProgressInterval == 0 {
+			updateJob(system, job)
+		}
+	})
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		job.Status = JobStatusCanceled
+	case err != nil:
+		job.Status = JobStatusFailed
+		job.Error = err.Error()
+	default:
+		job.Status = JobStatusCompleted
+	}
+	if summary != nil {
+		if result, err := json.Marshal(summary); err == nil {
+			job.Result = string(result)
+		}
+	}
+	// Items are saved after the request creating the job has been answered
+	if job.Processed > 0 {
+		BumpVaultRevision(s, schema)
+	}
+	now := time.Now()
+	job.FinishedAt = &now
+	updateJob(system, job)
+
+	if job.Status != JobStatusCanceled {
+		notifyJobFinished(system, job, summary)
+	}
+}
+
+// FindJob finds the job of the user
+func FindJob(s storage.Store, id, userID uint) (*model.Job, error) {
+	job, err := s.Jobs().FindByID(id)
+	if err != nil || job.UserID != userID {
+		return nil, ErrJobNotFound
+	}
+	return job, nil
+}
+
+// CancelJob stops the job of the user. Jobs left running by a stopped server
+// are marked as canceled since nothing runs them anymore.
+func CancelJob(s storage.Store, id, userID uint) (*model.Job, error) {
+	job, err := FindJob(s, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if job.FinishedAt != nil {
+		return nil, ErrJobFinished
+	}
+
+	runningJobs.Lock()
+	cancel, running := runningJobs.cancels[job.ID]
+	runningJobs.Unlock()
+	if running {
+		// The job goroutine saves the canceled status with the partial summary
+		cancel()
+		return job, nil
+	}
+
+	now := time.Now()
+	job.Status = JobStatusCanceled
+	job.FinishedAt = &now
+	return s.Jobs().Update(job)
+}
+
+func updateJob(s storage.Store, job *model.Job) {
+	if _, err := s.Jobs().Update(job); err != nil {
+		logger.Errorf("Error while updating job %d: %v", job.ID, err)
+	}
+}
+
+func notifyJobFinished(s storage.Store, job *model.Job, summary *model.ImportSummary) {
+	user, err := s.Users().FindByID(job.UserID)
+	if err != nil {
+		logger.Errorf("Error while finding owner of job %d: %v", job.ID, err)
+		return
+	}
+
+	template, data := EmailImportCompleted, map[string]interface{}{"Summary": summary}
+	if job.Status == JobStatusFailed {
+		template, data = EmailImportFailed, map[string]interface{}{"Error": job.Error}
+	}
+
+	if err := SendUserMail(s, user, template, data); err != nil {
+		logger.Errorf("can't send email to %s error: %v\n", user.Email, err)
+	}
+}
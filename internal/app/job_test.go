@@ -0,0 +1,43 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveImportProgress(t *testing.T) {
+	// Empty items fail validation, so nothing is saved to the store
+	items := []model.ImportItem{{}, {Note: &model.NoteDTO{}}, {}}
+
+	var reported []int
+	summary, err := saveImport(context.Background(), nil, items, "user1", model.ImportOptions{}, func(processed int) {
+		reported = append(reported, processed)
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, summary.Failed)
+	assert.Equal(t, []int{1, 2, 3}, reported)
+}
+
+func TestSaveImportCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	summary, err := saveImport(ctx, nil, []model.ImportItem{{}}, "user1", model.ImportOptions{}, nil)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, summary.Failed)
+}
+
+func TestToJobDTO(t *testing.T) {
+	job := &model.Job{Status: JobStatusCompleted, Total: 8, Processed: 6, Result: `{"created":5,"failed":1}`}
+
+	dto := model.ToJobDTO(job)
+
+	assert.Equal(t, 75, dto.Progress)
+	assert.Equal(t, 5, dto.Summary.Created)
+	assert.Equal(t, 1, dto.Summary.Failed)
+}
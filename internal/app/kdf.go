@@ -0,0 +1,59 @@
+package app
+
+import (
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+// KDFBcrypt is the algorithm the master passwords are hashed with
+const KDFBcrypt = "bcrypt"
+
+// KDFCost returns the bcrypt cost the policy asks for the master passwords,
+// it is never lower than the default of bcrypt
+func KDFCost(policy model.SecurityPolicy) int {
+	if policy.KDFCost < bcrypt.DefaultCost {
+		return bcrypt.DefaultCost
+	}
+	return policy.KDFCost
+}
+
+// UpgradeKDF hashes the master password of the user again with the cost of
+// the policy when it is hashed with a lower one. It is called once the master
+// password is confirmed at signin, users hashed with a higher cost keep it.
+func UpgradeKDF(s storage.Store, policy model.SecurityPolicy, user *model.User, masterPassword string) (*model.User, error) {
+	cost := KDFCost(policy)
+	if user.KDFAlgorithm == KDFBcrypt && user.KDFCost >= cost {
+		return user, nil
+	}
+
+	if err := setMasterPassword(user, masterPassword, cost); err != nil {
+		return nil, err
+	}
+	return s.Users().Update(user)
+}
+
+// setMasterPassword hashes the master password of the user with the cost and
+// keeps the parameters on the user
+func setMasterPassword(user *model.User, masterPassword string, cost int) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(masterPassword), cost)
+	if err != nil {
+		return err
+	}
+	user.MasterPassword = string(hash)
+	user.KDFAlgorithm = KDFBcrypt
+	user.KDFCost = cost
+	return nil
+}
+
+// policyKDFCost returns the cost of the security policy of the instance, the
+// default of bcrypt is used when the policy can't be read
+func policyKDFCost(s storage.Store) int {
+	policy, err := FindSecurityPolicy(s)
+	if err != nil {
+		logger.Errorf("Error while finding security policy: %v", err)
+	}
+	return KDFCost(policy)
+}
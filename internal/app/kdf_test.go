@@ -0,0 +1,57 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/internal/storage/migration"
+	"github.com/passwall/passwall-server/internal/storage/sqlite"
+	"github.com/passwall/passwall-server/model"
+)
+
+func TestKDFCost(t *testing.T) {
+	assert.Equal(t, bcrypt.DefaultCost, KDFCost(model.SecurityPolicy{}))
+	assert.Equal(t, bcrypt.DefaultCost, KDFCost(model.SecurityPolicy{KDFCost: 4}))
+	assert.Equal(t, 12, KDFCost(model.SecurityPolicy{KDFCost: 12}))
+}
+
+func TestUpgradeKDF(t *testing.T) {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "passwall.db"), &gorm.Config{})
+	require.NoError(t, err)
+	s := storage.New(db)
+	require.NoError(t, s.Migrations().Up(migration.SetSystem, ""))
+
+	user := &model.User{Email: "kdf@passwall.io"}
+	require.NoError(t, setMasterPassword(user, "master-password", bcrypt.DefaultCost))
+	user, err = s.Users().Create(user)
+	require.NoError(t, err)
+
+	// Users already hashed with the cost of the policy aren't saved again
+	hash := user.MasterPassword
+	user, err = UpgradeKDF(s, model.SecurityPolicy{}, user, "master-password")
+	require.NoError(t, err)
+	assert.Equal(t, hash, user.MasterPassword)
+
+	policy := model.SecurityPolicy{KDFCost: bcrypt.DefaultCost + 1}
+	_, err = UpgradeKDF(s, policy, user, "master-password")
+	require.NoError(t, err)
+
+	upgraded, err := s.Users().FindByCredentials("kdf@passwall.io", "master-password")
+	require.NoError(t, err)
+	assert.Equal(t, KDFBcrypt, upgraded.KDFAlgorithm)
+	assert.Equal(t, bcrypt.DefaultCost+1, upgraded.KDFCost)
+	cost, err := bcrypt.Cost([]byte(upgraded.MasterPassword))
+	require.NoError(t, err)
+	assert.Equal(t, bcrypt.DefaultCost+1, cost)
+
+	// Lowering the policy doesn't weaken the hashes
+	kept, err := UpgradeKDF(s, model.SecurityPolicy{}, upgraded, "master-password")
+	require.NoError(t, err)
+	assert.Equal(t, upgraded.MasterPassword, kept.MasterPassword)
+}
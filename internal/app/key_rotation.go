@@ -0,0 +1,333 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+// Key rotation: every vault gets a data key of the new version first, so the
+// new fields are encrypted with it, then its records are encrypted again at
+// the rate the admin sets. Once every vault is done the older keys are
+// deleted, which is the cutover. Rotations which are canceled or fail keep
+// their version and are resumed by starting them again, the records already
+// encrypted with the new keys are skipped.
+
+const (
+	// SettingKeyVersion is the setting of the data key version of the last completed rotation
+	SettingKeyVersion = "keys.version"
+	// SettingKeyRotationVersion is the setting of the version of the unfinished rotation
+	SettingKeyRotationVersion = "keys.rotation_version"
+)
+
+// defaultKeyRotationRate is the number of records encrypted again per second
+// when the admin doesn't set it
+const defaultKeyRotationRate = 100
+
+var (
+	// ErrKeyRotationRunning represents message for starting a key rotation while one is running
+	ErrKeyRotationRunning = errors.New("a key rotation is already running")
+	// ErrKeyRotationUnfinished represents message for passphrase rotations during a key rotation
+	ErrKeyRotationUnfinished = errors.New("a key rotation is unfinished, it must be completed first")
+)
+
+// keyCutoverDelay is how long the cutover waits after the vaults got their new
+// keys, the other instances use the older keys until they find them again
+var keyCutoverDelay = dataKeyCacheTTL
+
+// keyRotation is held while a key rotation job runs on this server
+var keyRotation sync.Mutex
+
+// StartKeyRotationJob encrypts the records of every vault with new data keys
+// in the background and returns the job to follow its progress, the vaults
+// are its total. The unfinished rotation is resumed instead of starting a new
+// version.
+func StartKeyRotationJob(s storage.Store, userID uint, itemsPerSecond int) (*model.Job, error) {
+	if !keyRotation.TryLock() {
+		return nil, ErrKeyRotationRunning
+	}
+	job, err := startKeyRotationJob(s, userID, itemsPerSecond)
+	if err != nil {
+		keyRotation.Unlock()
+	}
+	return job, err
+}
+
+func startKeyRotationJob(s storage.Store, userID uint, itemsPerSecond int) (*model.Job, error) {
+	if itemsPerSecond <= 0 {
+		itemsPerSecond = defaultKeyRotationRate
+	}
+	version, err := keyRotationVersion(s)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := vaultTargets(s)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &model.KeyRotationSummary{Version: version, ItemsPerSecond: itemsPerSecond}
+	result, err := json.Marshal(summary)
+	if err != nil {
+		return nil, err
+	}
+	job, err := s.Jobs().Create(&model.Job{
+		UserID: userID,
+		Type:   JobTypeKeyRotation,
+		Status: JobStatusQueued,
+		Total:  len(targets),
+		Result: string(result),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runningJobs.Lock()
+	runningJobs.cancels[job.ID] = cancel
+	runningJobs.Unlock()
+
+	// The goroutine works on its own copy, the returned job is the queued state
+	queued := *job
+	go runKeyRotationJob(ctx, s, job, targets, summary)
+
+	return &queued, nil
+}
+
+func runKeyRotationJob(ctx context.Context, s storage.Store, job *model.Job, targets []migrationTarget, summary *model.KeyRotationSummary) {
+	defer func() {
+		runningJobs.Lock()
+		cancel := runningJobs.cancels[job.ID]
+		delete(runningJobs.cancels, job.ID)
+		runningJobs.Unlock()
+		cancel()
+		keyRotation.Unlock()
+	}()
+
+	job.Status = JobStatusRunning
+	updateJob(s, job)
+
+	err := rotateKeys(ctx, s, targets, summary, func() {
+		job.Processed++
+		setKeyRotationResult(job, summary)
+		updateJob(s, job)
+	})
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		job.Status = JobStatusCanceled
+	case err != nil:
+		job.Status = JobStatusFailed
+		job.Error = err.Error()
+		logger.Errorf("Error while rotating the data keys to version %d: %v", summary.Version, err)
+	default:
+		job.Status = JobStatusCompleted
+	}
+	setKeyRotationResult(job, summary)
+	now := time.Now()
+	job.FinishedAt = &now
+	updateJob(s, job)
+}
+
+func setKeyRotationResult(job *model.Job, summary *model.KeyRotationSummary) {
+	if result, err := json.Marshal(summary); err == nil {
+		job.Result = string(result)
+	}
+}
+
+// rotateKeys encrypts the records of the vaults with data keys of the version
+// of the summary, calling progress after each vault, then cuts over
+func rotateKeys(ctx context.Context, s storage.Store, targets []migrationTarget, summary *model.KeyRotationSummary, progress func()) error {
+	wait, stop := rateLimiter(ctx, summary.ItemsPerSecond)
+	defer stop()
+
+	for _, target := range targets {
+		count, err := rekeyVault(target.store, target.schema, summary.Version, wait)
+		summary.Reencrypted += count
+		if err != nil {
+			return fmt.Errorf("schema %s: %w", target.schema, err)
+		}
+		progress()
+	}
+
+	// Instances which found the older keys before keep using them a while
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(keyCutoverDelay):
+	}
+	return cutoverKeys(s, summary, wait)
+}
+
+// cutoverKeys encrypts the records the other instances saved with the older
+// keys meanwhile, and the ones of the vaults created since the rotation
+// started, then deletes the older keys and completes the rotation
+func cutoverKeys(s storage.Store, summary *model.KeyRotationSummary, wait func() error) error {
+	targets, err := vaultTargets(s)
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		count, err := rekeyVault(target.store, target.schema, summary.Version, wait)
+		summary.Reencrypted += count
+		if err != nil {
+			return fmt.Errorf("schema %s: %w", target.schema, err)
+		}
+		if err := target.store.DataKeys().DeleteBefore(target.schema, summary.Version); err != nil {
+			return fmt.Errorf("schema %s: %w", target.schema, err)
+		}
+		// Fields encrypted with the passphrase are moved to the new key too
+		if err := target.store.DataKeys().MarkReencrypted(target.schema); err != nil {
+			return fmt.Errorf("schema %s: %w", target.schema, err)
+		}
+	}
+
+	now := time.Now()
+	summary.CutoverAt = &now
+	return saveSettings(s, []model.Setting{
+		{Key: SettingKeyVersion, Value: strconv.Itoa(summary.Version)},
+		{Key: SettingKeyRotationVersion, Value: ""},
+	})
+}
+
+// keyRotationVersion returns the version of the unfinished rotation, or saves
+// the next version as the one of the rotation starting
+func keyRotationVersion(s storage.Store) (int, error) {
+	values, err := findSettings(s)
+	if err != nil {
+		return 0, err
+	}
+	if value := values[SettingKeyRotationVersion]; value != "" {
+		return strconv.Atoi(value)
+	}
+
+	version := 1
+	if value := values[SettingKeyVersion]; value != "" {
+		if version, err = strconv.Atoi(value); err != nil {
+			return 0, err
+		}
+	}
+	version++
+	return version, saveSettings(s, []model.Setting{{Key: SettingKeyRotationVersion, Value: strconv.Itoa(version)}})
+}
+
+// keyRotationUnfinished reports whether a key rotation is started and not cut over yet
+func keyRotationUnfinished(s storage.Store) (bool, error) {
+	values, err := findSettings(s)
+	if err != nil {
+		return false, err
+	}
+	return values[SettingKeyRotationVersion] != "", nil
+}
+
+// rekeyVault encrypts the records of the vault with its data key of the
+// version, the key is created unless the vault has it. Each updated record
+// waits for the rate limit, it returns the number of updated records.
+func rekeyVault(s storage.Store, schema string, version int, wait func() error) (int, error) {
+	keyID, key, err := versionDataKey(s, schema, version)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	err = walkVault(s, schema, func(item interface{}, update func() error) error {
+		changed, err := rekeyModel(item, schema, keyID, key)
+		if err != nil || !changed {
+			return err
+		}
+		if err := wait(); err != nil {
+			return err
+		}
+		if err := update(); err != nil {
+			return err
+		}
+		updated++
+		return nil
+	})
+	return updated, err
+}
+
+// versionDataKey returns the data key of the vault with the version, or with
+// a higher one, and creates it when the vault doesn't have it. The key is used
+// for the new fields of the vault right away.
+func versionDataKey(s storage.Store, schema string, version int) (string, []byte, error) {
+	stored, err := s.DataKeys().FindBySchema(schema)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil, err
+	}
+
+	var key []byte
+	if err == nil && stored.Version >= version {
+		if key, err = unwrapDataKey(stored.WrappedKey, viper.GetString("server.passphrase")); err != nil {
+			return "", nil, fmt.Errorf("data key %s: %w", stored.KeyID, err)
+		}
+		keepDataKey(stored.KeyID, schema, key)
+	} else if stored, key, err = newDataKey(s, schema, version); err != nil {
+		return "", nil, err
+	}
+
+	dataKeys.Lock()
+	dataKeys.bySchema[schema] = vaultKey{keyID: stored.KeyID, foundAt: time.Now()}
+	dataKeys.Unlock()
+	return stored.KeyID, key, nil
+}
+
+// rekeyModel encrypts the fields of the struct pointer of the vault of the
+// schema encrypted with another data key, not bound to their record yet, or
+// still with the passphrase, with the data key and reports whether a field is
+// changed. Fields encrypted by the clients are kept.
+func rekeyModel(rawModel interface{}, schema, keyID string, key []byte) (bool, error) {
+	passphrase := viper.GetString("server.passphrase")
+	changed, err := rotateModel(rawModel, schema, passphrase, passphrase, keyID, key)
+	if err != nil {
+		return false, err
+	}
+
+	scope := modelScope(rawModel, schema)
+	value := reflect.ValueOf(rawModel).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		current := value.Field(i).String()
+		if field.Tag.Get("encrypt") != "true" || !isDataKeyField(current) || (fieldKeyID(current) == keyID && isBoundField(current)) {
+			continue
+		}
+
+		decrypted, err := openField(current, scope.withField(field))
+		if err != nil {
+			return false, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		rekeyed, err := sealField(decrypted, scope.withField(field), keyID, key)
+		if err != nil {
+			return false, err
+		}
+		value.Field(i).SetString(rekeyed)
+		changed = true
+	}
+	return changed, nil
+}
+
+// rateLimiter returns a function waiting for the turn of the next of the
+// records allowed per second and the function stopping it
+func rateLimiter(ctx context.Context, perSecond int) (func() error, func()) {
+	ticker := time.NewTicker(time.Second / time.Duration(perSecond))
+	return func() error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			return nil
+		}
+	}, ticker.Stop
+}
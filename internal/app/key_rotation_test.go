@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/internal/storage/migration"
+	"github.com/passwall/passwall-server/internal/storage/sqlite"
+	"github.com/passwall/passwall-server/model"
+)
+
+func TestRotateKeys(t *testing.T) {
+	defer viper.Set("server.passphrase", viper.GetString("server.passphrase"))
+	defer SetDataKeyStore(nil)
+	defer func(delay time.Duration) { keyCutoverDelay = delay }(keyCutoverDelay)
+	viper.Set("server.passphrase", "key-rotation-passphrase")
+	keyCutoverDelay = 0
+
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "passwall.db"), &gorm.Config{})
+	require.NoError(t, err)
+	s := storage.New(db)
+	SetDataKeyStore(s)
+	require.NoError(t, s.Migrations().Up(migration.SetSystem, ""))
+	_, err = s.Users().Create(&model.User{Email: "rotation@passwall.io", Schema: "user_rotation"})
+	require.NoError(t, err)
+	require.NoError(t, s.Users().CreateSchema("user_rotation"))
+	require.NoError(t, s.Migrations().Up(migration.SetVault, "user_rotation"))
+	// Settings of the stores of the other tests aren't used
+	require.NoError(t, saveSettings(s, nil))
+
+	note, err := CreateNote(s, &model.NoteDTO{Title: "Wifi", Note: "secret note"}, "user_rotation")
+	require.NoError(t, err)
+	legacy, err := s.Notes().Create(&model.Note{Note: encryptedWith(t, "legacy note", "key-rotation-passphrase")}, "user_rotation")
+	require.NoError(t, err)
+	oldKey, err := s.DataKeys().FindBySchema("user_rotation")
+	require.NoError(t, err)
+
+	version, err := keyRotationVersion(s)
+	require.NoError(t, err)
+	assert.Equal(t, 2, version)
+	unfinished, err := keyRotationUnfinished(s)
+	require.NoError(t, err)
+	assert.True(t, unfinished)
+	_, err = RotatePassphrase(s, "key-rotation-passphrase", "another-key-rotation-passphrase")
+	assert.ErrorIs(t, err, ErrKeyRotationUnfinished)
+
+	// Unfinished rotations are resumed with their version
+	resumed, err := keyRotationVersion(s)
+	require.NoError(t, err)
+	assert.Equal(t, version, resumed)
+
+	targets, err := vaultTargets(s)
+	require.NoError(t, err)
+	summary := &model.KeyRotationSummary{Version: version, ItemsPerSecond: 1000}
+	vaults := 0
+	require.NoError(t, rotateKeys(context.Background(), s, targets, summary, func() { vaults++ }))
+	assert.Equal(t, 1, vaults)
+	assert.Equal(t, 2, summary.Reencrypted)
+	assert.NotNil(t, summary.CutoverAt)
+
+	// Only the new key is kept and it encrypts the records
+	newKey, err := s.DataKeys().FindBySchema("user_rotation")
+	require.NoError(t, err)
+	assert.Equal(t, version, newKey.Version)
+	_, err = s.DataKeys().FindByKeyID(oldKey.KeyID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	for id, plain := range map[uint]string{note.ID: "secret note", legacy.ID: "legacy note"} {
+		stored, err := s.Notes().FindByID(id, "user_rotation")
+		require.NoError(t, err)
+		assert.Equal(t, newKey.KeyID, fieldKeyID(stored.Note))
+		_, err = DecryptModel(stored, "user_rotation")
+		require.NoError(t, err)
+		assert.Equal(t, plain, stored.Note)
+	}
+
+	values, err := findSettings(s)
+	require.NoError(t, err)
+	assert.Equal(t, "2", values[SettingKeyVersion])
+	assert.Empty(t, values[SettingKeyRotationVersion])
+
+	// Records already encrypted with the new key are skipped
+	updated, err := rekeyVault(s, "user_rotation", version, func() error { return nil })
+	require.NoError(t, err)
+	assert.Zero(t, updated)
+}
+
+func TestRateLimiterCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	wait, stop := rateLimiter(ctx, 1)
+	defer stop()
+	assert.ErrorIs(t, wait(), context.Canceled)
+}
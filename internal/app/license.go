@@ -0,0 +1,48 @@
+package app
+
+import (
+	"time"
+
+	"github.com/passwall/passwall-server/internal/license"
+	"github.com/passwall/passwall-server/model"
+)
+
+// License statuses, servers without a license check the subscriptions
+const (
+	LicenseStatusNone    = "none"
+	LicenseStatusActive  = "active"
+	LicenseStatusExpired = "expired"
+)
+
+// serverLicense unlocks the pro features of every user, nil checks the
+// subscriptions with the billing provider
+var serverLicense *license.License
+
+// SetLicense sets the offline license of the server, it is set at startup
+// when a license is configured
+func SetLicense(l *license.License) {
+	serverLicense = l
+}
+
+// Licensed checks if the server has a license which isn't expired at the time
+func Licensed(now time.Time) bool {
+	return serverLicense != nil && !serverLicense.Expired(now)
+}
+
+// FindLicenseStatus returns the status of the license of the server at the time
+func FindLicenseStatus(now time.Time) model.LicenseStatus {
+	if serverLicense == nil {
+		return model.LicenseStatus{Status: LicenseStatusNone}
+	}
+
+	status := LicenseStatusActive
+	if serverLicense.Expired(now) {
+		status = LicenseStatusExpired
+	}
+	expiresAt := serverLicense.ExpiresAt
+	return model.LicenseStatus{
+		Status:    status,
+		Licensee:  serverLicense.Licensee,
+		ExpiresAt: &expiresAt,
+	}
+}
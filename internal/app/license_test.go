@@ -0,0 +1,30 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/passwall/passwall-server/internal/license"
+	"github.com/passwall/passwall-server/model"
+)
+
+func TestLicense(t *testing.T) {
+	defer SetLicense(nil)
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	user := &model.User{SubscriptionType: SubscriptionTypeFree}
+
+	assert.Equal(t, LicenseStatusNone, FindLicenseStatus(now).Status)
+	assert.Equal(t, SubscriptionTypeFree, SubscriptionAccess(user, now))
+
+	SetLicense(&license.License{Licensee: "Acme", ExpiresAt: now.AddDate(0, 1, 0)})
+	status := FindLicenseStatus(now)
+	assert.Equal(t, LicenseStatusActive, status.Status)
+	assert.Equal(t, "Acme", status.Licensee)
+	assert.Equal(t, SubscriptionTypePro, SubscriptionAccess(user, now), "licenses unlock the pro features")
+
+	later := now.AddDate(0, 2, 0)
+	assert.Equal(t, LicenseStatusExpired, FindLicenseStatus(later).Status)
+	assert.Equal(t, SubscriptionTypeFree, SubscriptionAccess(user, later))
+}
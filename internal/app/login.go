@@ -1,6 +1,8 @@
 package app
 
 import (
+	"time"
+
 	"github.com/passwall/passwall-server/internal/storage"
 	"github.com/passwall/passwall-server/model"
 	"github.com/passwall/passwall-server/pkg/logger"
@@ -15,7 +17,7 @@ func FindAllLogins(s storage.Store, schema string) ([]model.Login, error) {
 
 	// Decrypt server side encrypted fields
 	for i := range loginList {
-		uLogin, err := DecryptModel(&loginList[i])
+		uLogin, err := DecryptModel(&loginList[i], schema)
 		if err != nil {
 			logger.Errorf("Error while decrypting login: %v", err)
 			continue
@@ -29,9 +31,14 @@ func FindAllLogins(s storage.Store, schema string) ([]model.Login, error) {
 // CreateLogin creates a login and saves it to the store
 func CreateLogin(s storage.Store, dto *model.LoginDTO, schema string) (*model.Login, error) {
 	rawLogin := model.ToLogin(dto)
-	encLogin := EncryptModel(rawLogin)
+	rawLogin.PasswordChangedAt = passwordChangedNow()
+	SetLoginSearchIndex(rawLogin)
+	seal, err := sealCreated(s, rawLogin, schema)
+	if err != nil {
+		return nil, err
+	}
 
-	createdLogin, err := s.Logins().Create(encLogin.(*model.Login), schema)
+	createdLogin, err := s.Logins().CreateSealed(rawLogin, schema, seal)
 	if err != nil {
 		return nil, err
 	}
@@ -43,9 +50,14 @@ func CreateLogin(s storage.Store, dto *model.LoginDTO, schema string) (*model.Lo
 func CreateLogins(s storage.Store, dtos []model.LoginDTO, schema string) error {
 	for i := range dtos {
 		rawLogin := model.ToLogin(&dtos[i])
-		encLogin := EncryptModel(rawLogin)
+		rawLogin.PasswordChangedAt = passwordChangedNow()
+		SetLoginSearchIndex(rawLogin)
+		seal, err := sealCreated(s, rawLogin, schema)
+		if err != nil {
+			return err
+		}
 
-		_, err := s.Logins().Create(encLogin.(*model.Login), schema)
+		_, err = s.Logins().CreateSealed(rawLogin, schema, seal)
 		if err != nil {
 			return err
 		}
@@ -56,8 +68,25 @@ func CreateLogins(s storage.Store, dtos []model.LoginDTO, schema string) error {
 
 // UpdateLogin updates the login with the dto and applies the changes in the store
 func UpdateLogin(s storage.Store, login *model.Login, dto *model.LoginDTO, schema string) (*model.Login, error) {
+	if err := checkRevision(dto.Revision, login.Revision); err != nil {
+		return nil, err
+	}
+
+	// Keep track of password changes for the password age reports
+	current := *login
+	if _, err := DecryptModel(&current, schema); err == nil && current.Password != dto.Password {
+		login.PasswordChangedAt = passwordChangedNow()
+		login.Expired = false
+	}
+
 	rawModel := model.ToLogin(dto)
-	encModel := EncryptModel(rawModel).(*model.Login)
+	rawModel.ID = login.ID
+	SetLoginSearchIndex(rawModel)
+	encrypted, err := EncryptModel(s, rawModel, schema)
+	if err != nil {
+		return nil, err
+	}
+	encModel := encrypted.(*model.Login)
 
 	login.Title = encModel.Title
 	login.URL = encModel.URL
@@ -65,6 +94,11 @@ func UpdateLogin(s storage.Store, login *model.Login, dto *model.LoginDTO, schem
 	login.Password = encModel.Password
 	login.Extra = encModel.Extra
 	login.TOTPSecret = encModel.TOTPSecret
+	login.MaxPasswordAge = encModel.MaxPasswordAge
+	login.FolderID = encModel.FolderID
+	login.SearchIndex = encModel.SearchIndex
+	login.DomainIndex = encModel.DomainIndex
+	login.UsernameIndex = encModel.UsernameIndex
 
 	updatedLogin, err := s.Logins().Update(login, schema)
 	if err != nil {
@@ -73,3 +107,8 @@ func UpdateLogin(s storage.Store, login *model.Login, dto *model.LoginDTO, schem
 
 	return updatedLogin, nil
 }
+
+func passwordChangedNow() *time.Time {
+	now := time.Now()
+	return &now
+}
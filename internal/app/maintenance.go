@@ -0,0 +1,48 @@
+package app
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/passwall/passwall-server/internal/config"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// SettingMaintenance is the settings key of the maintenance mode admins switch
+const SettingMaintenance = "maintenance.enabled"
+
+// ErrMaintenance is returned for the changes while the server is in maintenance
+var ErrMaintenance = errors.New("server is in maintenance, changes are disabled for a while")
+
+// FindMaintenance returns the maintenance mode of the server, it is on when
+// an admin switches it on or server.maintenance is set
+func FindMaintenance(s storage.Store) (*model.Maintenance, error) {
+	values, err := findSettings(s)
+	if err != nil {
+		return nil, err
+	}
+	switched, _ := strconv.ParseBool(values[SettingMaintenance])
+	configured := config.Current().Server.Maintenance
+	return &model.Maintenance{
+		Enabled:    switched || configured,
+		Configured: configured,
+	}, nil
+}
+
+// InMaintenance checks if the server is in maintenance, the database is
+// treated as in maintenance when the settings can't be read
+func InMaintenance(s storage.Store) bool {
+	maintenance, err := FindMaintenance(s)
+	return err != nil || maintenance.Enabled
+}
+
+// SetMaintenance switches the maintenance mode of every instance, it can't be
+// switched off while server.maintenance is set
+func SetMaintenance(s storage.Store, enabled bool) (*model.Maintenance, error) {
+	err := saveSettings(s, []model.Setting{{Key: SettingMaintenance, Value: strconv.FormatBool(enabled)}})
+	if err != nil {
+		return nil, err
+	}
+	return FindMaintenance(s)
+}
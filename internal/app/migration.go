@@ -2,52 +2,273 @@ package app
 
 import (
 	"fmt"
+	"io"
+	"sync"
 
 	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/internal/storage/migration"
+	"github.com/passwall/passwall-server/internal/storage/tenancy"
+	"github.com/passwall/passwall-server/model"
 	"github.com/passwall/passwall-server/pkg/logger"
 )
 
-// MigrateSystemTables runs auto migration for the system models (Token and User),
-// will only add missing fields won't delete/change current data in the store.
+// migrationStatus is the result of the migrations run at startup
+var migrationStatus struct {
+	sync.Mutex
+	done   bool
+	failed []string
+}
+
+// migrationFailed logs and keeps the failure for the readiness checks
+func migrationFailed(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	logger.Errorf("%s", message)
+
+	migrationStatus.Lock()
+	migrationStatus.failed = append(migrationStatus.failed, message)
+	migrationStatus.Unlock()
+}
+
+// MigrationStatus returns if the startup migrations are done and their failures
+func MigrationStatus() (bool, []string) {
+	migrationStatus.Lock()
+	defer migrationStatus.Unlock()
+	return migrationStatus.done, append([]string{}, migrationStatus.failed...)
+}
+
+// migrationTarget is a schema and the store it lives in
+type migrationTarget struct {
+	store  storage.Store
+	set    string
+	schema string
+}
+
+// MigrateSystemTables applies the pending migrations of the system tables
+// (Token, User, Organization etc.) in the public schema.
 func MigrateSystemTables(s storage.Store) {
-	if err := s.Tokens().Migrate(); err != nil {
-		logger.Errorf("failed to migrate tokens: %v", err)
+	if err := s.Migrations().Up(migration.SetSystem, ""); err != nil {
+		migrationFailed("failed to migrate system tables: %v", err)
 	}
-	if err := s.Users().Migrate(); err != nil {
-		logger.Errorf("failed to migrate users: %v", err)
+}
+
+// MigrateVaults applies the pending migrations of every user and organization schema
+func MigrateVaults(s storage.Store) {
+	targets, err := vaultMigrationTargets(s)
+	if err != nil {
+		migrationFailed("%v", err)
 	}
+	for _, target := range targets {
+		if err := migrateTarget(target); err != nil {
+			migrationFailed("failed to migrate schema %s: %v", target.schema, err)
+		}
+	}
+
+	migrationStatus.Lock()
+	migrationStatus.done = true
+	migrationStatus.Unlock()
 }
 
-// MigrateUserTables runs auto migration for user models in user schema,
-// will only add missing fields won't delete/change current data in the store.
+// MigrateUserTables applies the pending vault migrations to the user or
+// organization schema and indexes the logins for search.
 func MigrateUserTables(s storage.Store, schema string) error {
 	if schema == "" {
 		return fmt.Errorf("schema is empty")
 	}
 
-	if err := s.Logins().Migrate(schema); err != nil {
-		logger.Errorf("failed to migrate logins: %v", err)
-		return err
+	// Vaults share the tables of the vaults schema in row tenancy, new vaults
+	// have nothing to migrate
+	if tenancy.Rows() && schema != tenancy.Schema {
+		return nil
 	}
-	if err := s.CreditCards().Migrate(schema); err != nil {
-		logger.Errorf("failed to migrate credit cards: %v", err)
+
+	if err := s.Migrations().Up(migration.SetVault, schema); err != nil {
+		logger.Errorf("failed to migrate vault tables: %v", err)
 		return err
 	}
-	if err := s.BankAccounts().Migrate(schema); err != nil {
-		logger.Errorf("failed to migrate bank accounts: %v", err)
-		return err
+	// Logins of the shared tables were indexed in their schemas
+	if schema == tenancy.Schema {
+		return nil
 	}
-	if err := s.Notes().Migrate(schema); err != nil {
-		logger.Errorf("failed to migrate notes: %v", err)
+	if err := IndexLogins(s, schema); err != nil {
+		logger.Errorf("failed to index logins: %v", err)
 		return err
 	}
-	if err := s.Emails().Migrate(schema); err != nil {
-		logger.Errorf("failed to migrate emails: %v", err)
+	return nil
+}
+
+// CheckSchemaVersions fails when the system tables or a vault were migrated
+// by a newer version of the server, running on them could damage the data.
+func CheckSchemaVersions(s storage.Store) error {
+	statuses, err := MigrationStatuses(s)
+	if err != nil {
 		return err
 	}
-	if err := s.Servers().Migrate(schema); err != nil {
-		logger.Errorf("failed to migrate servers: %v", err)
+	for _, status := range statuses {
+		if len(status.Unknown) > 0 {
+			return fmt.Errorf("%w: %s migrations %v of schema %q", migration.ErrUnknownVersion, status.Set, status.Unknown, status.Schema)
+		}
+	}
+	return nil
+}
+
+// MigrationStatuses returns the migration status of the system tables and every vault
+func MigrationStatuses(s storage.Store) ([]model.MigrationStatus, error) {
+	system, err := s.Migrations().Status(migration.SetSystem, "")
+	if err != nil {
+		return nil, err
+	}
+	statuses := []model.MigrationStatus{*system}
+
+	targets, err := vaultMigrationTargets(s)
+	if err != nil {
+		// Fresh databases don't have the tables listing the vaults yet
+		if system.Version == 0 {
+			return statuses, nil
+		}
+		return nil, err
+	}
+	for _, target := range targets {
+		status, err := target.store.Migrations().Status(target.set, target.schema)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, *status)
+	}
+	return statuses, nil
+}
+
+// RunMigrationCommand runs the up, down or status command of the migrate flag
+// on the system tables and every vault, the status is written to out
+func RunMigrationCommand(s storage.Store, command string, steps int, out io.Writer) error {
+	switch command {
+	case "up":
+		if err := s.Migrations().Up(migration.SetSystem, ""); err != nil {
+			return err
+		}
+		targets, err := vaultMigrationTargets(s)
+		if err != nil {
+			return err
+		}
+		for _, target := range targets {
+			if err := migrateTarget(target); err != nil {
+				return fmt.Errorf("schema %s: %v", target.schema, err)
+			}
+		}
+	case "down":
+		if steps < 1 {
+			return fmt.Errorf("steps must be at least 1")
+		}
+		// Vaults are reverted first, the system tables list them. Row
+		// migrations come after the vault ones, they are reverted before.
+		targets, err := vaultMigrationTargets(s)
+		if err != nil {
+			return err
+		}
+		for i := len(targets) - 1; i >= 0; i-- {
+			target := targets[i]
+			if err := target.store.Migrations().Down(target.set, target.schema, steps); err != nil {
+				return fmt.Errorf("schema %s: %v", target.schema, err)
+			}
+		}
+		return s.Migrations().Down(migration.SetSystem, "", steps)
+	case "status":
+	default:
+		return fmt.Errorf("unknown migrate command %q, use up, down or status", command)
+	}
+
+	statuses, err := MigrationStatuses(s)
+	if err != nil {
 		return err
 	}
+	for _, status := range statuses {
+		fmt.Fprintln(out, formatMigrationStatus(status))
+	}
 	return nil
 }
+
+func formatMigrationStatus(status model.MigrationStatus) string {
+	schema := status.Schema
+	if schema == "" {
+		schema = "public"
+	}
+	line := fmt.Sprintf("%s %s: version %d of %d", status.Set, schema, status.Version, status.Latest)
+	if len(status.Pending) > 0 {
+		line += fmt.Sprintf(", pending %v", status.Pending)
+	}
+	if len(status.Unknown) > 0 {
+		line += fmt.Sprintf(", unknown %v", status.Unknown)
+	}
+	return line
+}
+
+// migrateTarget applies the pending migrations of the set of the target
+func migrateTarget(target migrationTarget) error {
+	if target.set == migration.SetRows {
+		return target.store.Migrations().Up(target.set, target.schema)
+	}
+	return MigrateUserTables(target.store, target.schema)
+}
+
+// vaultMigrationTargets returns the schemas of the users and the organizations,
+// organization vaults live in the store of their region. In row tenancy they
+// are the shared tables of the vaults schema of every store.
+func vaultMigrationTargets(s storage.Store) ([]migrationTarget, error) {
+	if tenancy.Rows() {
+		return rowMigrationTargets(s)
+	}
+	return vaultTargets(s)
+}
+
+// vaultTargets returns the vault schemas of the users and the organizations in
+// the stores they live in, in row tenancy too
+func vaultTargets(s storage.Store) ([]migrationTarget, error) {
+	users, err := s.Users().All()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find users for migration: %v", err)
+	}
+	targets := []migrationTarget{}
+	for _, user := range users {
+		if user.Schema == "" {
+			continue
+		}
+		targets = append(targets, migrationTarget{store: s, set: migration.SetVault, schema: user.Schema})
+	}
+
+	orgs, err := s.Organizations().All()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find organizations for migration: %v", err)
+	}
+	for _, org := range orgs {
+		vault, err := s.Region(org.Region)
+		if err != nil {
+			logger.Errorf("failed to find region of organization %d: %v", org.ID, err)
+			continue
+		}
+		targets = append(targets, migrationTarget{store: vault, set: migration.SetVault, schema: org.Schema})
+	}
+	return targets, nil
+}
+
+// rowMigrationTargets returns the vault and row migrations of the shared
+// tables of the store and its regions
+func rowMigrationTargets(s storage.Store) ([]migrationTarget, error) {
+	stores := []storage.Store{s}
+	for _, name := range s.RegionNames() {
+		region, err := s.Region(name)
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, region)
+	}
+
+	targets := []migrationTarget{}
+	for _, store := range stores {
+		if err := store.Users().CreateSchema(tenancy.Schema); err != nil {
+			return nil, fmt.Errorf("failed to create the schema of the vaults: %v", err)
+		}
+		targets = append(targets,
+			migrationTarget{store: store, set: migration.SetVault, schema: tenancy.Schema},
+			migrationTarget{store: store, set: migration.SetRows, schema: tenancy.Schema})
+	}
+	return targets, nil
+}
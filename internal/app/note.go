@@ -15,7 +15,7 @@ func FindAllNotes(s storage.Store, schema string) ([]model.Note, error) {
 
 	// Decrypt server side encrypted fields
 	for i := range list {
-		m, err := DecryptModel(&list[i])
+		m, err := DecryptModel(&list[i], schema)
 		if err != nil {
 			logger.Errorf("Error while decrypting credit card: %v", err)
 			continue
@@ -29,9 +29,12 @@ func FindAllNotes(s storage.Store, schema string) ([]model.Note, error) {
 // CreateNote creates a new note and saves it to the store
 func CreateNote(s storage.Store, dto *model.NoteDTO, schema string) (*model.Note, error) {
 	rawModel := model.ToNote(dto)
-	encModel := EncryptModel(rawModel)
+	seal, err := sealCreated(s, rawModel, schema)
+	if err != nil {
+		return nil, err
+	}
 
-	createdNote, err := s.Notes().Create(encModel.(*model.Note), schema)
+	createdNote, err := s.Notes().CreateSealed(rawModel, schema, seal)
 	if err != nil {
 		return nil, err
 	}
@@ -41,11 +44,21 @@ func CreateNote(s storage.Store, dto *model.NoteDTO, schema string) (*model.Note
 
 // UpdateNote updates the note with the dto and applies the changes in the store
 func UpdateNote(s storage.Store, note *model.Note, dto *model.NoteDTO, schema string) (*model.Note, error) {
+	if err := checkRevision(dto.Revision, note.Revision); err != nil {
+		return nil, err
+	}
+
 	rawModel := model.ToNote(dto)
-	encModel := EncryptModel(rawModel).(*model.Note)
+	rawModel.ID = note.ID
+	encrypted, err := EncryptModel(s, rawModel, schema)
+	if err != nil {
+		return nil, err
+	}
+	encModel := encrypted.(*model.Note)
 
 	note.Title = encModel.Title
 	note.Note = encModel.Note
+	note.FolderID = encModel.FolderID
 
 	updatedNote, err := s.Notes().Update(note, schema)
 	if err != nil {
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"time"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// Vault change actions
+const (
+	ChangeActionUpdated = "updated"
+	ChangeActionDeleted = "deleted"
+)
+
+// VaultChanges finds the items and folders changed since the given time. Only
+// the IDs are returned, clients get the changed items with the delta sync.
+func VaultChanges(s storage.Store, since time.Time, schema string) (*model.ChangeNotification, error) {
+	notification := &model.ChangeNotification{Revision: SyncRevision(time.Now()), Changes: []model.VaultChange{}}
+	updated := func(itemType string, id uint) {
+		notification.Changes = append(notification.Changes, model.VaultChange{ItemType: itemType, ItemID: id, Action: ChangeActionUpdated})
+	}
+	opts := &model.ListOptions{Sort: "id", Direction: "asc", UpdatedSince: &since}
+
+	folders, err := s.Folders().FindUpdatedSince(since, schema)
+	if err != nil {
+		return nil, err
+	}
+	for _, folder := range folders {
+		updated("folder", folder.ID)
+	}
+
+	logins, _, err := s.Logins().FindAll(opts, schema)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range logins {
+		updated(ItemTypeLogin, item.ID)
+	}
+	cards, _, err := s.CreditCards().FindAll(opts, schema)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range cards {
+		updated(ItemTypeCreditCard, item.ID)
+	}
+	accounts, _, err := s.BankAccounts().FindAll(opts, schema)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range accounts {
+		updated(ItemTypeBankAccount, item.ID)
+	}
+	notes, _, err := s.Notes().FindAll(opts, schema)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range notes {
+		updated(ItemTypeNote, item.ID)
+	}
+	emails, _, err := s.Emails().FindAll(opts, schema)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range emails {
+		updated(ItemTypeEmail, item.ID)
+	}
+	servers, _, err := s.Servers().FindAll(opts, schema)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range servers {
+		updated(ItemTypeServer, item.ID)
+	}
+
+	tombstones, err := s.Tombstones().FindSince(since, schema)
+	if err != nil {
+		return nil, err
+	}
+	for _, tombstone := range tombstones {
+		notification.Changes = append(notification.Changes, model.VaultChange{
+			ItemType: tombstone.ItemType,
+			ItemID:   tombstone.ItemID,
+			Action:   ChangeActionDeleted,
+		})
+	}
+
+	return notification, nil
+}
@@ -0,0 +1,128 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+var (
+	// ErrAlreadyMember represents message for inviting an existing member
+	ErrAlreadyMember = errors.New("user is already a member of the organization")
+	// ErrNoInvitation represents message for accepting a missing invitation
+	ErrNoInvitation = errors.New("invitation couldn't found")
+)
+
+// CreateOrganization creates an organization owned by the user with its own vault schema.
+// The vault schema is created in the store of the organization region.
+func CreateOrganization(s storage.Store, dto *model.OrganizationDTO, owner *model.User) (*model.Organization, error) {
+	vault, err := s.Region(dto.Region)
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := s.Organizations().Create(&model.Organization{
+		Name:    dto.Name,
+		OwnerID: owner.ID,
+		Region:  dto.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate schema name and update organization
+	org.Schema = fmt.Sprintf("org%d", org.ID)
+	org, err = s.Organizations().Update(org)
+	if err != nil {
+		logger.Errorf("Error while updating organization schema: %v", err)
+		return nil, ErrGenerateSchema
+	}
+
+	// Organization vault uses the same tables with user vaults
+	if err = vault.Users().CreateSchema(org.Schema); err != nil {
+		logger.Errorf("Error while creating schema: %v", err)
+		return nil, ErrCreateSchema
+	}
+	if err = MigrateUserTables(vault, org.Schema); err != nil {
+		logger.Errorf("Error while migrating organization tables: %v", err)
+		return nil, err
+	}
+
+	_, err = s.Organizations().SaveMember(&model.OrganizationMember{
+		OrganizationID: org.ID,
+		UserID:         owner.ID,
+		Email:          owner.Email,
+		Role:           model.OrganizationRoleOwner,
+		Status:         model.MemberStatusAccepted,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return org, nil
+}
+
+// DeleteOrganization marks the organization deleted, its members and its vault
+// schema are removed when it is purged
+func DeleteOrganization(s storage.Store, org *model.Organization) error {
+	return s.Organizations().Delete(org.ID)
+}
+
+// InviteMember creates an invitation for an existing user and notifies the user
+// by email, the organization must have a free seat
+func InviteMember(s storage.Store, org *model.Organization, dto *model.InvitationDTO) (*model.OrganizationMember, error) {
+	user, err := s.Users().FindByEmail(dto.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.Organizations().FindMember(org.ID, user.ID); err == nil {
+		return nil, ErrAlreadyMember
+	}
+	if err := CheckSeats(s, org); err != nil {
+		return nil, err
+	}
+
+	member, err := s.Organizations().SaveMember(&model.OrganizationMember{
+		OrganizationID: org.ID,
+		UserID:         user.ID,
+		Email:          user.Email,
+		Role:           dto.Role,
+		Status:         model.MemberStatusInvited,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := SendUserMail(s, user, EmailInvitation, map[string]interface{}{"Organization": org.Name}); err != nil {
+		logger.Errorf("can't send invitation email to %s error: %v", user.Email, err)
+	}
+
+	return member, nil
+}
+
+// AcceptInvitation makes the invited user an accepted member of the organization
+func AcceptInvitation(s storage.Store, orgID, userID uint) (*model.OrganizationMember, error) {
+	member, err := s.Organizations().FindMember(orgID, userID)
+	if err != nil || member.Status != model.MemberStatusInvited {
+		return nil, ErrNoInvitation
+	}
+
+	member.Status = model.MemberStatusAccepted
+	return s.Organizations().SaveMember(member)
+}
+
+// CanManageOrganization checks if the role is allowed to manage members and collections
+func CanManageOrganization(role string) bool {
+	return role == model.OrganizationRoleOwner || role == model.OrganizationRoleAdmin
+}
+
+// CanEditOrganizationVault checks if the role is allowed to change the items of
+// the organization vault and its collections, and to read every item of it.
+// Members only read the items of the collections they are given.
+func CanEditOrganizationVault(role string) bool {
+	return CanManageOrganization(role) || role == model.OrganizationRoleEditor
+}
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	_ "embed"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/passwall/passwall-server/model"
+)
+
+const (
+	defaultPassphraseWords     = 6
+	defaultPassphraseSeparator = "-"
+)
+
+// effLargeWordlist is the EFF large wordlist for diceware passphrases
+// https://www.eff.org/deeplinks/2016/07/new-wordlists-random-passphrases
+//
+//go:embed assets/eff_large_wordlist.txt
+var effLargeWordlist []byte
+
+var passphraseWords = parseWordlist(effLargeWordlist)
+
+// parseWordlist parses "<dice rolls>\t<word>" formatted wordlists
+func parseWordlist(data []byte) []string {
+	var words []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			words = append(words, fields[1])
+		}
+	}
+	return words
+}
+
+// GeneratePassphrase generates a diceware passphrase according to the options
+func GeneratePassphrase(dto *model.PassphraseDTO) (string, error) {
+	count := dto.Words
+	if count == 0 {
+		count = defaultPassphraseWords
+	}
+
+	separator := dto.Separator
+	if separator == "" {
+		separator = defaultPassphraseSeparator
+	}
+
+	words := make([]string, count)
+	for i := range words {
+		n, err := randomInt(len(passphraseWords))
+		if err != nil {
+			return "", err
+		}
+
+		word := passphraseWords[n]
+		if dto.Capitalize {
+			word = strings.ToUpper(word[:1]) + word[1:]
+		}
+		words[i] = word
+	}
+
+	// Append a random digit to a random word
+	if dto.IncludeNumber {
+		i, err := randomInt(count)
+		if err != nil {
+			return "", err
+		}
+		digit, err := randomInt(10)
+		if err != nil {
+			return "", err
+		}
+		words[i] += strconv.Itoa(digit)
+	}
+
+	return strings.Join(words, separator), nil
+}
+
+// randomInt returns a uniform random number in [0, max) using crypto/rand
+func randomInt(max int) (int, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
+	if err != nil {
+		return 0, err
+	}
+	return int(n.Int64()), nil
+}
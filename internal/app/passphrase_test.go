@@ -0,0 +1,60 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWordlist(t *testing.T) {
+	assert.Equal(t, 7776, len(passphraseWords))
+	assert.Equal(t, "abacus", passphraseWords[0])
+	assert.Equal(t, "zoom", passphraseWords[len(passphraseWords)-1])
+}
+
+func TestGeneratePassphraseDefaultSeparator(t *testing.T) {
+	passphrase, err := GeneratePassphrase(&model.PassphraseDTO{})
+	if err != nil {
+		t.Errorf("GeneratePassphrase() error = %v", err)
+		return
+	}
+	assert.GreaterOrEqual(t, strings.Count(passphrase, defaultPassphraseSeparator), defaultPassphraseWords-1)
+}
+
+func TestGeneratePassphrase(t *testing.T) {
+	tests := []struct {
+		name          string
+		dto           model.PassphraseDTO
+		expectedWords int
+		separator     string
+	}{
+		{name: "Default word count", dto: model.PassphraseDTO{Separator: "_"}, expectedWords: 6, separator: "_"},
+		{name: "Custom word count and separator", dto: model.PassphraseDTO{Words: 4, Separator: "."}, expectedWords: 4, separator: "."},
+		{name: "Capitalized with number", dto: model.PassphraseDTO{Words: 5, Separator: " ", Capitalize: true, IncludeNumber: true}, expectedWords: 5, separator: " "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			passphrase, err := GeneratePassphrase(&tt.dto)
+			if err != nil {
+				t.Errorf("GeneratePassphrase() error = %v", err)
+				return
+			}
+
+			words := strings.Split(passphrase, tt.separator)
+			assert.Equal(t, tt.expectedWords, len(words))
+
+			digits := 0
+			for _, word := range words {
+				if tt.dto.Capitalize {
+					assert.True(t, unicode.IsUpper(rune(word[0])), "word %q should be capitalized", word)
+				}
+				digits += strings.IndexFunc(word, unicode.IsDigit) + 1
+			}
+			assert.Equal(t, tt.dto.IncludeNumber, digits > 0)
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package app
+
+import (
+	"time"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+// FlagExpiredPasswords flags the logins whose passwords are older than their
+// max password age and notifies the owners about the newly expired ones.
+// Organization logins also get the policies of the collections they are in.
+func FlagExpiredPasswords(s storage.Store) {
+	users, err := s.Users().All()
+	if err != nil {
+		logger.Errorf("Error while finding users for password expiry: %v", err)
+		return
+	}
+
+	for i := range users {
+		user := &users[i]
+		if user.Schema == "" || user.DeletionScheduledAt != nil {
+			continue
+		}
+
+		expired, err := flagExpiredLogins(s, user.Schema, nil)
+		if err != nil {
+			logger.Errorf("Error while flagging expired passwords of schema %s: %v", user.Schema, err)
+			continue
+		}
+		notifyExpiredPasswords(s, user, expired)
+	}
+
+	orgs, err := s.Organizations().All()
+	if err != nil {
+		logger.Errorf("Error while finding organizations for password expiry: %v", err)
+		return
+	}
+
+	for i := range orgs {
+		org := &orgs[i]
+		vault, err := s.Region(org.Region)
+		if err != nil {
+			logger.Errorf("Error while finding region of organization %d: %v", org.ID, err)
+			continue
+		}
+
+		expired, err := flagExpiredLogins(vault, org.Schema, collectionPasswordPolicies(s, org.ID))
+		if err != nil {
+			logger.Errorf("Error while flagging expired passwords of schema %s: %v", org.Schema, err)
+			continue
+		}
+
+		owner, err := s.Users().FindByID(org.OwnerID)
+		if err != nil {
+			continue
+		}
+		notifyExpiredPasswords(s, owner, expired)
+	}
+}
+
+// collectionPasswordPolicies returns the strictest max password age of the
+// collections each login of the organization is in
+func collectionPasswordPolicies(s storage.Store, orgID uint) map[uint]int {
+	policies := map[uint]int{}
+
+	collections, err := s.Collections().All(orgID)
+	if err != nil {
+		return policies
+	}
+
+	for _, collection := range collections {
+		if collection.MaxPasswordAge <= 0 {
+			continue
+		}
+		items, err := s.Collections().Items(collection.ID)
+		if err != nil {
+			continue
+		}
+		for _, item := range items {
+			if item.ItemType != ItemTypeLogin {
+				continue
+			}
+			if age, ok := policies[item.ItemID]; !ok || collection.MaxPasswordAge < age {
+				policies[item.ItemID] = collection.MaxPasswordAge
+			}
+		}
+	}
+
+	return policies
+}
+
+// flagExpiredLogins updates the expired flags of the logins in the schema
+// and returns the logins which are expired since the last check
+func flagExpiredLogins(s storage.Store, schema string, policies map[uint]int) ([]model.Login, error) {
+	loginList, err := s.Logins().All(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	newlyExpired := []model.Login{}
+	for i := range loginList {
+		login := &loginList[i]
+
+		expired := passwordExpired(login, effectiveMaxPasswordAge(login, policies), now)
+		if expired == login.Expired {
+			continue
+		}
+
+		login.Expired = expired
+		if _, err := s.Logins().Update(login, schema); err != nil {
+			return nil, err
+		}
+		if expired {
+			newlyExpired = append(newlyExpired, *login)
+		}
+	}
+
+	return newlyExpired, nil
+}
+
+// effectiveMaxPasswordAge returns the strictest of the item and collection policies
+func effectiveMaxPasswordAge(login *model.Login, policies map[uint]int) int {
+	maxAge := login.MaxPasswordAge
+	if age, ok := policies[login.ID]; ok && (maxAge <= 0 || age < maxAge) {
+		maxAge = age
+	}
+	return maxAge
+}
+
+// passwordExpired checks if the password of the login is older than maxAgeDays.
+// Zero max age means the password never expires.
+func passwordExpired(login *model.Login, maxAgeDays int, now time.Time) bool {
+	if maxAgeDays <= 0 {
+		return false
+	}
+
+	changedAt := login.CreatedAt
+	if login.PasswordChangedAt != nil {
+		changedAt = *login.PasswordChangedAt
+	}
+
+	return now.Sub(changedAt) >= time.Duration(maxAgeDays)*24*time.Hour
+}
+
+func notifyExpiredPasswords(s storage.Store, user *model.User, logins []model.Login) {
+	if len(logins) == 0 {
+		return
+	}
+
+	titles := make([]string, len(logins))
+	for i, login := range logins {
+		titles[i] = login.Title
+	}
+
+	if err := SendUserMail(s, user, EmailExpiredPasswords, map[string]interface{}{"Titles": titles}); err != nil {
+		logger.Errorf("can't send email to %s error: %v\n", user.Email, err)
+	}
+}
@@ -0,0 +1,41 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordExpired(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	changedAt := now.AddDate(0, 0, -30)
+
+	tests := []struct {
+		name     string
+		login    model.Login
+		maxAge   int
+		expected bool
+	}{
+		{name: "No policy", login: model.Login{CreatedAt: now.AddDate(-5, 0, 0)}, maxAge: 0, expected: false},
+		{name: "Changed recently", login: model.Login{CreatedAt: now.AddDate(-1, 0, 0), PasswordChangedAt: &changedAt}, maxAge: 90, expected: false},
+		{name: "Changed long ago", login: model.Login{CreatedAt: now.AddDate(-1, 0, 0), PasswordChangedAt: &changedAt}, maxAge: 30, expected: true},
+		{name: "Never changed", login: model.Login{CreatedAt: now.AddDate(0, 0, -100)}, maxAge: 90, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, passwordExpired(&tt.login, tt.maxAge, now))
+		})
+	}
+}
+
+func TestEffectiveMaxPasswordAge(t *testing.T) {
+	policies := map[uint]int{1: 60, 2: 180}
+
+	assert.Equal(t, 60, effectiveMaxPasswordAge(&model.Login{ID: 1}, policies))
+	assert.Equal(t, 90, effectiveMaxPasswordAge(&model.Login{ID: 2, MaxPasswordAge: 90}, policies))
+	assert.Equal(t, 30, effectiveMaxPasswordAge(&model.Login{ID: 3, MaxPasswordAge: 30}, policies))
+	assert.Equal(t, 0, effectiveMaxPasswordAge(&model.Login{ID: 4}, nil))
+}
@@ -0,0 +1,148 @@
+package app
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// Settings keys of the security policy
+const (
+	PolicyMinPasswordLength   = "policy.min_password_length"
+	PolicyMinPasswordStrength = "policy.min_password_strength"
+	PolicyRequireTwoFactor    = "policy.require_two_factor"
+	PolicySessionMaxLifetime  = "policy.session_max_lifetime"
+	PolicySessionIdleTimeout  = "policy.session_idle_timeout"
+	PolicyExportDisabled      = "policy.export_disabled"
+	PolicyKDFCost             = "policy.kdf_cost"
+)
+
+var (
+	// ErrPasswordTooShort is returned for the master passwords shorter than the policy allows
+	ErrPasswordTooShort = errors.New("master password is shorter than the security policy allows")
+	// ErrPasswordTooWeak is returned for the master passwords weaker than the policy allows
+	ErrPasswordTooWeak = errors.New("master password is weaker than the security policy allows, use a longer one with more kinds of characters")
+	// ErrExportDisabled is returned for the exports when the policy disables them
+	ErrExportDisabled = errors.New("exports are disabled by the security policy")
+	// ErrSessionExpired is returned for the sessions older than the policy allows
+	ErrSessionExpired = errors.New("session is expired, sign in again")
+	// ErrSessionLifetimeRequired is returned for the idle timeouts without a max lifetime ending the sessions
+	ErrSessionLifetimeRequired = errors.New("session_idle_timeout needs a session_max_lifetime")
+	// ErrTwoFactorRequired is returned for the signins without the code the policy requires
+	ErrTwoFactorRequired = errors.New("two-factor code is required, it is sent to you")
+	// ErrInvalidTwoFactorCode is returned for the signins with a wrong code
+	ErrInvalidTwoFactorCode = errors.New("two-factor code is invalid or expired")
+)
+
+// FindSecurityPolicy returns the security policy of the instance, it is read
+// from the settings at most once a minute
+func FindSecurityPolicy(s storage.Store) (model.SecurityPolicy, error) {
+	values, err := findSettings(s)
+	if err != nil {
+		return model.SecurityPolicy{}, err
+	}
+	return policyFromSettings(values), nil
+}
+
+// UpdateSecurityPolicy saves the security policy, the instance uses it right away
+func UpdateSecurityPolicy(s storage.Store, policy model.SecurityPolicy) error {
+	return saveSettings(s, policySettings(policy))
+}
+
+// policyFromSettings returns the security policy of the setting values, the
+// missing ones are disabled
+func policyFromSettings(values map[string]string) model.SecurityPolicy {
+	number := func(key string) int {
+		n, _ := strconv.Atoi(values[key])
+		return n
+	}
+	flag := func(key string) bool {
+		b, _ := strconv.ParseBool(values[key])
+		return b
+	}
+
+	return model.SecurityPolicy{
+		MinPasswordLength:   number(PolicyMinPasswordLength),
+		MinPasswordStrength: number(PolicyMinPasswordStrength),
+		RequireTwoFactor:    flag(PolicyRequireTwoFactor),
+		SessionMaxLifetime:  number(PolicySessionMaxLifetime),
+		SessionIdleTimeout:  number(PolicySessionIdleTimeout),
+		ExportDisabled:      flag(PolicyExportDisabled),
+		KDFCost:             number(PolicyKDFCost),
+	}
+}
+
+// policySettings returns the settings of the security policy
+func policySettings(policy model.SecurityPolicy) []model.Setting {
+	return []model.Setting{
+		{Key: PolicyMinPasswordLength, Value: strconv.Itoa(policy.MinPasswordLength)},
+		{Key: PolicyMinPasswordStrength, Value: strconv.Itoa(policy.MinPasswordStrength)},
+		{Key: PolicyRequireTwoFactor, Value: strconv.FormatBool(policy.RequireTwoFactor)},
+		{Key: PolicySessionMaxLifetime, Value: strconv.Itoa(policy.SessionMaxLifetime)},
+		{Key: PolicySessionIdleTimeout, Value: strconv.Itoa(policy.SessionIdleTimeout)},
+		{Key: PolicyExportDisabled, Value: strconv.FormatBool(policy.ExportDisabled)},
+		{Key: PolicyKDFCost, Value: strconv.Itoa(policy.KDFCost)},
+	}
+}
+
+// CheckMasterPasswordPolicy checks if the new master password is long and
+// strong enough for the policy
+func CheckMasterPasswordPolicy(policy model.SecurityPolicy, masterPassword string) error {
+	if len([]rune(masterPassword)) < policy.MinPasswordLength {
+		return ErrPasswordTooShort
+	}
+	if policy.MinPasswordStrength > 0 && PasswordStrength(masterPassword) < policy.MinPasswordStrength {
+		return ErrPasswordTooWeak
+	}
+	return nil
+}
+
+// SessionExpired checks if the session of the token claims is older than the
+// policy allows. Tokens issued before the sessions were tracked are expired.
+func SessionExpired(policy model.SecurityPolicy, claims jwt.MapClaims, now time.Time) bool {
+	if policy.SessionMaxLifetime <= 0 {
+		return false
+	}
+	authTime, ok := SessionStartedAt(claims)
+	if !ok {
+		return true
+	}
+	return !now.Before(authTime.Add(time.Duration(policy.SessionMaxLifetime) * time.Hour))
+}
+
+// SessionExpiry returns the expiry times of the access and refresh tokens
+// issued now for the session the client signed in at the auth time, they
+// start from the lifetimes of the client type. With an idle timeout
+// the refresh token expires when the session isn't used for that long, so
+// every refresh extends it. Neither outlives the max lifetime of the session.
+func SessionExpiry(policy model.SecurityPolicy, client string, authTime, now time.Time) (accessExp, refreshExp time.Time) {
+	accessDuration, refreshDuration := clientTokenDurations(client)
+	accessExp, refreshExp = now.Add(accessDuration), now.Add(refreshDuration)
+
+	if policy.SessionIdleTimeout > 0 {
+		refreshExp = now.Add(time.Duration(policy.SessionIdleTimeout) * time.Minute)
+	}
+	if policy.SessionMaxLifetime > 0 {
+		if end := authTime.Add(time.Duration(policy.SessionMaxLifetime) * time.Hour); refreshExp.After(end) {
+			refreshExp = end
+		}
+	}
+	if accessExp.After(refreshExp) {
+		accessExp = refreshExp
+	}
+	return accessExp, refreshExp
+}
+
+// SessionStartedAt returns the signin time of the session of the token claims
+func SessionStartedAt(claims jwt.MapClaims) (time.Time, bool) {
+	seconds, ok := claims["auth_time"].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
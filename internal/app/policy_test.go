@@ -0,0 +1,102 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/passwall/passwall-server/model"
+)
+
+func TestPolicySettings(t *testing.T) {
+	policy := model.SecurityPolicy{
+		MinPasswordLength:   12,
+		MinPasswordStrength: 3,
+		RequireTwoFactor:    true,
+		SessionMaxLifetime:  24,
+		SessionIdleTimeout:  30,
+		ExportDisabled:      true,
+		KDFCost:             12,
+	}
+	assert.Equal(t, policy, policyFromSettings(settingValues(policySettings(policy))))
+
+	// Missing settings disable the policies
+	assert.Equal(t, model.SecurityPolicy{}, policyFromSettings(nil))
+}
+
+func TestCheckMasterPasswordPolicy(t *testing.T) {
+	assert.NoError(t, CheckMasterPasswordPolicy(model.SecurityPolicy{}, "123456"))
+
+	policy := model.SecurityPolicy{MinPasswordLength: 10}
+	assert.Equal(t, ErrPasswordTooShort, CheckMasterPasswordPolicy(policy, "ğüşiöçıĞÜ"))
+	assert.NoError(t, CheckMasterPasswordPolicy(policy, "ğüşiöçıĞÜŞ"))
+
+	policy = model.SecurityPolicy{MinPasswordStrength: 3}
+	assert.Equal(t, ErrPasswordTooWeak, CheckMasterPasswordPolicy(policy, "aaaaaaaaaaaa"))
+	assert.NoError(t, CheckMasterPasswordPolicy(policy, "Correct-Horse-Battery-9"))
+}
+
+func TestSessionExpired(t *testing.T) {
+	now := time.Now()
+	claims := jwt.MapClaims{"auth_time": float64(now.Add(-2 * time.Hour).Unix())}
+
+	assert.False(t, SessionExpired(model.SecurityPolicy{}, claims, now))
+	assert.False(t, SessionExpired(model.SecurityPolicy{SessionMaxLifetime: 3}, claims, now))
+	assert.True(t, SessionExpired(model.SecurityPolicy{SessionMaxLifetime: 2}, claims, now))
+
+	// Tokens without the signin time can't be checked
+	assert.True(t, SessionExpired(model.SecurityPolicy{SessionMaxLifetime: 2}, jwt.MapClaims{}, now))
+}
+
+func TestSessionExpiry(t *testing.T) {
+	defer viper.Set("server.accessTokenExpireDuration", viper.GetString("server.accessTokenExpireDuration"))
+	defer viper.Set("server.refreshTokenExpireDuration", viper.GetString("server.refreshTokenExpireDuration"))
+	viper.Set("server.accessTokenExpireDuration", "30m")
+	viper.Set("server.refreshTokenExpireDuration", "15d")
+
+	now := time.Now()
+	authTime := now.Add(-2 * time.Hour)
+
+	// Fixed expiry without an idle timeout
+	accessExp, refreshExp := SessionExpiry(model.SecurityPolicy{}, "", authTime, now)
+	assert.Equal(t, now.Add(30*time.Minute), accessExp)
+	assert.Equal(t, now.Add(15*24*time.Hour), refreshExp)
+
+	// Every refresh moves the idle window, the access token doesn't outlive it
+	policy := model.SecurityPolicy{SessionIdleTimeout: 10, SessionMaxLifetime: 8}
+	accessExp, refreshExp = SessionExpiry(policy, "", authTime, now)
+	assert.Equal(t, now.Add(10*time.Minute), accessExp)
+	assert.Equal(t, now.Add(10*time.Minute), refreshExp)
+	_, refreshExp = SessionExpiry(policy, "", authTime, now.Add(time.Hour))
+	assert.Equal(t, now.Add(70*time.Minute), refreshExp)
+
+	// Up to the max lifetime of the session
+	accessExp, refreshExp = SessionExpiry(policy, "", authTime, authTime.Add(8*time.Hour-5*time.Minute))
+	assert.Equal(t, authTime.Add(8*time.Hour), refreshExp)
+	assert.Equal(t, refreshExp, accessExp)
+}
+
+func TestSessionExpiryClient(t *testing.T) {
+	defer viper.Set("server.accessTokenExpireDuration", viper.GetString("server.accessTokenExpireDuration"))
+	defer viper.Set("server.refreshTokenExpireDuration", viper.GetString("server.refreshTokenExpireDuration"))
+	defer viper.Set("tokens.mobile.refreshTokenExpireDuration", viper.GetString("tokens.mobile.refreshTokenExpireDuration"))
+	viper.Set("server.accessTokenExpireDuration", "30m")
+	viper.Set("server.refreshTokenExpireDuration", "15d")
+	viper.Set("tokens.mobile.refreshTokenExpireDuration", "90d")
+
+	now := time.Now()
+	accessExp, refreshExp := SessionExpiry(model.SecurityPolicy{}, ClientMobile, now, now)
+	assert.Equal(t, now.Add(30*time.Minute), accessExp)
+	assert.Equal(t, now.Add(90*24*time.Hour), refreshExp)
+
+	// Clients without their own lifetimes use the ones of the server
+	_, refreshExp = SessionExpiry(model.SecurityPolicy{}, ClientWeb, now, now)
+	assert.Equal(t, now.Add(15*24*time.Hour), refreshExp)
+
+	// The max lifetime of the policy still ends the mobile sessions
+	_, refreshExp = SessionExpiry(model.SecurityPolicy{SessionMaxLifetime: 24 * 30}, ClientMobile, now, now)
+	assert.Equal(t, now.Add(30*24*time.Hour), refreshExp)
+}
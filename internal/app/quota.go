@@ -0,0 +1,164 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/config"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// Resources limited by the plans
+const (
+	QuotaItems  = "items"
+	QuotaShares = "shares"
+)
+
+// QuotaError is returned when the plan of the user doesn't allow more of the resource
+type QuotaError struct {
+	Resource string
+	Limit    int
+	// Upgradable is true when subscribing to pro allows more
+	Upgradable bool
+}
+
+func (e *QuotaError) Error() string {
+	if e.Upgradable {
+		return fmt.Sprintf("your plan allows %d %s, upgrade to add more", e.Limit, e.Resource)
+	}
+	return fmt.Sprintf("your plan allows %d %s", e.Limit, e.Resource)
+}
+
+// FindPlan returns the name and the quotas of the plan of the user, past due
+// users keep the pro plan in their grace period
+func FindPlan(user *model.User, now time.Time) (string, config.PlanConfiguration) {
+	name := SubscriptionAccess(user, now)
+	if name == SubscriptionTypePastDue {
+		name = SubscriptionTypePro
+	}
+	return name, config.Current().Plans[name]
+}
+
+// quotaLimit returns the limit of the resource in the plan, zero is unlimited
+func quotaLimit(plan config.PlanConfiguration, resource string) int {
+	switch resource {
+	case QuotaItems:
+		return plan.MaxItems
+	case QuotaShares:
+		return plan.MaxShares
+	}
+	return 0
+}
+
+// userQuotaLimit returns the limit of the resource for the user, the quota
+// admins set for the user is used instead of the one of the plan. Custom is
+// true for the quotas of the user.
+func userQuotaLimit(user *model.User, plan config.PlanConfiguration, resource string) (limit int, custom bool) {
+	if resource == QuotaItems && user.MaxItems != nil {
+		return *user.MaxItems, true
+	}
+	return quotaLimit(plan, resource), false
+}
+
+// CheckQuota checks if the plan of the owner allows one more of the resource
+// when used of them exist
+func CheckQuota(owner *model.User, resource string, used int) error {
+	name, plan := FindPlan(owner, time.Now())
+	limit, custom := userQuotaLimit(owner, plan, resource)
+	if limit == 0 || used < limit {
+		return nil
+	}
+
+	// Subscribing doesn't change the quotas admins set
+	pro := quotaLimit(config.Current().Plans[SubscriptionTypePro], resource)
+	return &QuotaError{
+		Resource:   resource,
+		Limit:      limit,
+		Upgradable: !custom && name != SubscriptionTypePro && (pro == 0 || pro > limit),
+	}
+}
+
+// SetUserQuota changes the item quota of the user, nil goes back to the quota
+// of the plan
+func SetUserQuota(s storage.Store, user *model.User, maxItems *int) (*model.User, error) {
+	user.MaxItems = maxItems
+	return s.Users().Update(user)
+}
+
+// CountItems counts the items of every type in the vault schema
+func CountItems(s storage.Store, schema string) (int, error) {
+	opts := &model.ListOptions{Limit: 1, Sort: "id", Direction: "asc", Filters: map[string]interface{}{}}
+	counts := []func() (int64, error){
+		func() (int64, error) { _, total, err := s.Logins().FindAll(opts, schema); return total, err },
+		func() (int64, error) { _, total, err := s.CreditCards().FindAll(opts, schema); return total, err },
+		func() (int64, error) { _, total, err := s.BankAccounts().FindAll(opts, schema); return total, err },
+		func() (int64, error) { _, total, err := s.Notes().FindAll(opts, schema); return total, err },
+		func() (int64, error) { _, total, err := s.Emails().FindAll(opts, schema); return total, err },
+		func() (int64, error) { _, total, err := s.Servers().FindAll(opts, schema); return total, err },
+	}
+
+	count := 0
+	for _, countType := range counts {
+		total, err := countType()
+		if err != nil {
+			return 0, err
+		}
+		count += int(total)
+	}
+	return count, nil
+}
+
+// CountShares counts the items in the collections of the organization
+func CountShares(s storage.Store, orgID uint) (int, error) {
+	collections, err := s.Collections().All(orgID)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, collection := range collections {
+		items, err := s.Collections().Items(collection.ID)
+		if err != nil {
+			return 0, err
+		}
+		count += len(items)
+	}
+	return count, nil
+}
+
+// FindUsage returns the items in the vault of the user and the most items
+// shared in one of the organizations the user owns with the limits of the plan
+func FindUsage(s storage.Store, user *model.User) (*model.Usage, error) {
+	name, plan := FindPlan(user, time.Now())
+
+	items, err := CountItems(s, user.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	orgs, err := s.Organizations().FindByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	shares := 0
+	for _, org := range orgs {
+		if org.OwnerID != user.ID {
+			continue
+		}
+		count, err := CountShares(s, org.ID)
+		if err != nil {
+			return nil, err
+		}
+		if count > shares {
+			shares = count
+		}
+	}
+
+	itemLimit, _ := userQuotaLimit(user, plan, QuotaItems)
+	return &model.Usage{
+		Plan:   name,
+		Items:  model.QuotaUsage{Used: items, Limit: itemLimit},
+		Shares: model.QuotaUsage{Used: shares, Limit: plan.MaxShares},
+	}, nil
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/passwall/passwall-server/internal/config"
+	"github.com/passwall/passwall-server/model"
+)
+
+func TestQuotaLimit(t *testing.T) {
+	plan := config.PlanConfiguration{MaxItems: 50, MaxShares: 10}
+	assert.Equal(t, 50, quotaLimit(plan, QuotaItems))
+	assert.Equal(t, 10, quotaLimit(plan, QuotaShares))
+	assert.Equal(t, 0, quotaLimit(plan, "attachments"))
+}
+
+func TestUserQuotaLimit(t *testing.T) {
+	plan := config.PlanConfiguration{MaxItems: 50, MaxShares: 10}
+	user := &model.User{}
+
+	limit, custom := userQuotaLimit(user, plan, QuotaItems)
+	assert.Equal(t, 50, limit)
+	assert.False(t, custom)
+
+	// Quotas of the user are used instead of the plan, zero is unlimited
+	for _, maxItems := range []int{500, 0} {
+		maxItems := maxItems
+		user.MaxItems = &maxItems
+		limit, custom = userQuotaLimit(user, plan, QuotaItems)
+		assert.Equal(t, maxItems, limit)
+		assert.True(t, custom)
+	}
+
+	limit, custom = userQuotaLimit(user, plan, QuotaShares)
+	assert.Equal(t, 10, limit)
+	assert.False(t, custom)
+}
+
+func TestCheckQuotaWithoutPlans(t *testing.T) {
+	// Subscription types without a plan are unlimited
+	user := &model.User{SubscriptionType: SubscriptionTypeFree}
+	assert.NoError(t, CheckQuota(user, QuotaItems, 1000000))
+	assert.NoError(t, CheckQuota(user, QuotaShares, 1000000))
+
+	// Quotas admins set can't be lifted by subscribing
+	maxItems := 5
+	user.MaxItems = &maxItems
+	err := CheckQuota(user, QuotaItems, 5)
+	if assert.Error(t, err) {
+		assert.False(t, err.(*QuotaError).Upgradable)
+	}
+}
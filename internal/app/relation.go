@@ -0,0 +1,71 @@
+package app
+
+import (
+	"errors"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+var (
+	// ErrSelfRelation represents message for linking an item to itself
+	ErrSelfRelation = errors.New("an item can't be linked to itself")
+)
+
+// CreateRelation links two existing items of the vault
+func CreateRelation(s storage.Store, dto *model.ItemRelationDTO, schema string) (*model.ItemRelation, error) {
+	if dto.SourceType == dto.TargetType && dto.SourceID == dto.TargetID {
+		return nil, ErrSelfRelation
+	}
+	if err := ItemExists(s, dto.SourceType, dto.SourceID, schema); err != nil {
+		return nil, err
+	}
+	if err := ItemExists(s, dto.TargetType, dto.TargetID, schema); err != nil {
+		return nil, err
+	}
+
+	return s.Relations().Create(model.ToItemRelation(dto), schema)
+}
+
+// FindRelatedItems finds the items linked to the item in both directions
+func FindRelatedItems(s storage.Store, itemType string, itemID uint, schema string) ([]model.RelatedItem, error) {
+	relations, err := s.Relations().FindByItem(itemType, itemID, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]model.RelatedItem, len(relations))
+	ids := map[string][]uint{}
+	for i, relation := range relations {
+		item := model.RelatedItem{RelationID: relation.ID, ItemType: relation.TargetType, ItemID: relation.TargetID}
+		if relation.TargetType == itemType && relation.TargetID == itemID {
+			item.ItemType = relation.SourceType
+			item.ItemID = relation.SourceID
+		}
+		items[i] = item
+		ids[item.ItemType] = append(ids[item.ItemType], item.ItemID)
+	}
+
+	// Titles are found with a query per item type, not per item
+	titles := map[string]map[uint]string{}
+	for t, typeIDs := range ids {
+		found, err := ItemTitles(s, t, typeIDs, schema)
+		if err != nil {
+			return nil, err
+		}
+		titles[t] = found
+	}
+
+	related := []model.RelatedItem{}
+	for _, item := range items {
+		// Skip the links of items which don't exist anymore
+		title, ok := titles[item.ItemType][item.ItemID]
+		if !ok {
+			continue
+		}
+		item.Title = title
+		related = append(related, item)
+	}
+
+	return related, nil
+}
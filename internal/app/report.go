@@ -0,0 +1,143 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// FindReusedPasswords finds the logins sharing the same password by comparing
+// HMACs of the decrypted passwords
+func FindReusedPasswords(s storage.Store, schema string) ([]model.ReusedPasswordGroup, error) {
+	loginList, err := FindAllLogins(s, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprints := make(map[uint]string, len(loginList))
+	for i := range loginList {
+		if loginList[i].Password == "" {
+			continue
+		}
+		fingerprints[loginList[i].ID] = passwordFingerprint(loginList[i].Password)
+	}
+
+	return groupReusedPasswords(loginList, fingerprints), nil
+}
+
+// FindReusedPasswordsByHashes finds the logins sharing the same password by
+// comparing the hashes computed by the client
+func FindReusedPasswordsByHashes(s storage.Store, dto *model.PasswordHashesDTO, schema string) ([]model.ReusedPasswordGroup, error) {
+	loginList, err := FindAllLogins(s, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprints := make(map[uint]string, len(dto.Hashes))
+	for _, hash := range dto.Hashes {
+		fingerprints[hash.ID] = hash.Hash
+	}
+
+	return groupReusedPasswords(loginList, fingerprints), nil
+}
+
+// passwordFingerprint returns an HMAC of the password keyed with the server secret
+func passwordFingerprint(password string) string {
+	mac := hmac.New(sha256.New, []byte(viper.GetString("server.secret")))
+	mac.Write([]byte(password))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// groupReusedPasswords groups the logins with the same fingerprint.
+// Logins without a fingerprint and passwords used only once are left out.
+func groupReusedPasswords(logins []model.Login, fingerprints map[uint]string) []model.ReusedPasswordGroup {
+	groups := make(map[string][]model.ReportItem)
+	order := []string{}
+
+	for i := range logins {
+		fingerprint, ok := fingerprints[logins[i].ID]
+		if !ok {
+			continue
+		}
+		if _, exists := groups[fingerprint]; !exists {
+			order = append(order, fingerprint)
+		}
+		groups[fingerprint] = append(groups[fingerprint], model.ToReportItem(&logins[i]))
+	}
+
+	result := []model.ReusedPasswordGroup{}
+	for _, fingerprint := range order {
+		items := groups[fingerprint]
+		if len(items) < 2 {
+			continue
+		}
+		result = append(result, model.ReusedPasswordGroup{Count: len(items), Items: items})
+	}
+
+	// Most reused passwords first
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Count > result[j].Count
+	})
+
+	return result
+}
+
+// FindPasswordReport finds the logins with weak passwords or passwords older than maxAgeDays
+func FindPasswordReport(s storage.Store, schema string, maxAgeDays int) (*model.PasswordReport, error) {
+	loginList, err := FindAllLogins(s, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildPasswordReport(loginList, maxAgeDays, time.Now()), nil
+}
+
+// buildPasswordReport checks the strength and age of the login passwords.
+// Logins created before password changes were tracked are aged by their creation time.
+func buildPasswordReport(logins []model.Login, maxAgeDays int, now time.Time) *model.PasswordReport {
+	report := &model.PasswordReport{
+		MaxAgeDays: maxAgeDays,
+		Items:      []model.PasswordReportItem{},
+	}
+
+	for i := range logins {
+		login := &logins[i]
+		if login.Password == "" {
+			continue
+		}
+
+		changedAt := login.CreatedAt
+		if login.PasswordChangedAt != nil {
+			changedAt = *login.PasswordChangedAt
+		}
+
+		item := model.PasswordReportItem{
+			ReportItem:        model.ToReportItem(login),
+			Strength:          PasswordStrength(login.Password),
+			PasswordChangedAt: login.PasswordChangedAt,
+			AgeDays:           int(now.Sub(changedAt).Hours() / 24),
+		}
+		item.Weak = item.Strength <= WeakPasswordScore
+		item.Old = maxAgeDays > 0 && item.AgeDays >= maxAgeDays
+
+		if !item.Weak && !item.Old {
+			continue
+		}
+		if item.Weak {
+			report.WeakCount++
+		}
+		if item.Old {
+			report.OldCount++
+		}
+		report.Items = append(report.Items, item)
+	}
+
+	return report
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupReusedPasswords(t *testing.T) {
+	logins := []model.Login{
+		{ID: 1, Title: "Github"},
+		{ID: 2, Title: "Gitlab"},
+		{ID: 3, Title: "Bitbucket"},
+		{ID: 4, Title: "Mail"},
+		{ID: 5, Title: "Bank"},
+		{ID: 6, Title: "Forum"},
+	}
+
+	tests := []struct {
+		name         string
+		fingerprints map[uint]string
+		expected     [][]uint
+	}{
+		{name: "No reuse", fingerprints: map[uint]string{1: "a", 2: "b", 3: "c"}, expected: [][]uint{}},
+		{name: "Single group", fingerprints: map[uint]string{1: "a", 2: "a", 3: "b"}, expected: [][]uint{{1, 2}}},
+		{name: "Most reused first", fingerprints: map[uint]string{1: "a", 2: "a", 3: "b", 4: "b", 5: "b", 6: "c"}, expected: [][]uint{{3, 4, 5}, {1, 2}}},
+		{name: "Missing fingerprints", fingerprints: map[uint]string{4: "a", 6: "a"}, expected: [][]uint{{4, 6}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups := groupReusedPasswords(logins, tt.fingerprints)
+			got := [][]uint{}
+			for _, group := range groups {
+				ids := []uint{}
+				for _, item := range group.Items {
+					ids = append(ids, item.ID)
+				}
+				assert.Equal(t, len(ids), group.Count)
+				got = append(got, ids)
+			}
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestPasswordFingerprint(t *testing.T) {
+	assert.Equal(t, passwordFingerprint("password"), passwordFingerprint("password"))
+	assert.NotEqual(t, passwordFingerprint("password"), passwordFingerprint("Password"))
+}
+
+func TestBuildPasswordReport(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	changedRecently := now.AddDate(0, 0, -10)
+
+	logins := []model.Login{
+		{ID: 1, Password: "secret", CreatedAt: now, PasswordChangedAt: &changedRecently},
+		{ID: 2, Password: "x7#Kq9!mZ2@vLp5$", CreatedAt: now.AddDate(-2, 0, 0)},
+		{ID: 3, Password: "x7#Kq9!mZ2@vLp5$", CreatedAt: now.AddDate(-2, 0, 0), PasswordChangedAt: &changedRecently},
+		{ID: 4, Password: "", CreatedAt: now.AddDate(-2, 0, 0)},
+	}
+
+	report := buildPasswordReport(logins, 365, now)
+	assert.Equal(t, 1, report.WeakCount)
+	assert.Equal(t, 1, report.OldCount)
+	assert.Len(t, report.Items, 2)
+	assert.Equal(t, uint(1), report.Items[0].ID)
+	assert.True(t, report.Items[0].Weak)
+	assert.Equal(t, 10, report.Items[0].AgeDays)
+	assert.Equal(t, uint(2), report.Items[1].ID)
+	assert.True(t, report.Items[1].Old)
+
+	report = buildPasswordReport(logins, 0, now)
+	assert.Equal(t, 0, report.OldCount)
+	assert.Len(t, report.Items, 1)
+}
@@ -0,0 +1,46 @@
+package app
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+// RevokeAllSessions increases the token epoch of the user, so every access and
+// refresh token issued before is rejected on every instance right away, and
+// forgets the trusted devices so they ask the two-factor code again. The
+// session of the claims asking it gets new tokens of the new epoch and stays
+// signed in. The user is told by email.
+func RevokeAllSessions(s storage.Store, user *model.User, policy model.SecurityPolicy, claims jwt.MapClaims, ip string, now time.Time) (*model.TokenDetailsDTO, error) {
+	user.TokenEpoch++
+	user, err := s.Users().Update(user)
+	if err != nil {
+		return nil, err
+	}
+	// Saved tokens of the older epoch can't be used anymore
+	s.Tokens().Delete(int(user.ID))
+	if err := s.TrustedDevices().DeleteByUserID(user.ID); err != nil {
+		return nil, err
+	}
+
+	authTime, ok := SessionStartedAt(claims)
+	if !ok {
+		authTime = now
+	}
+	td, err := CreateSessionToken(user, policy, SessionClient(claims), authTime)
+	if err != nil {
+		return nil, err
+	}
+	s.Tokens().Create(int(user.ID), td.AtUUID, td.AccessToken, td.AtExpiresTime)
+	s.Tokens().Create(int(user.ID), td.RtUUID, td.RefreshToken, td.RtExpiresTime)
+
+	data := map[string]interface{}{"IP": ip, "Time": now.UTC().Format("2006-01-02 15:04 UTC")}
+	if err := SendUserMail(s, user, EmailSessionsRevoked, data); err != nil {
+		logger.Errorf("can't send email to %s error: %v\n", user.Email, err)
+	}
+	return td, nil
+}
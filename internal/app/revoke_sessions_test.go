@@ -0,0 +1,76 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	uuid "github.com/satori/go.uuid"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/internal/storage/migration"
+	"github.com/passwall/passwall-server/internal/storage/sqlite"
+	"github.com/passwall/passwall-server/model"
+)
+
+func TestRevokeAllSessions(t *testing.T) {
+	defer viper.Set("server.accessTokenExpireDuration", viper.GetString("server.accessTokenExpireDuration"))
+	defer viper.Set("server.refreshTokenExpireDuration", viper.GetString("server.refreshTokenExpireDuration"))
+	viper.Set("server.accessTokenExpireDuration", "30m")
+	viper.Set("server.refreshTokenExpireDuration", "15d")
+
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "passwall.db"), &gorm.Config{})
+	require.NoError(t, err)
+	s := storage.New(db)
+	require.NoError(t, s.Migrations().Up(migration.SetSystem, ""))
+
+	user, err := s.Users().Create(&model.User{UUID: uuid.NewV4(), Email: "panic@passwall.io", Role: "Member"})
+	require.NoError(t, err)
+
+	claimsOf := func(tokenString string) jwt.MapClaims {
+		token, err := TokenValid(tokenString)
+		require.NoError(t, err)
+		return token.Claims.(jwt.MapClaims)
+	}
+	authTime := time.Now().Add(-time.Hour)
+	current, err := CreateSessionToken(user, model.SecurityPolicy{}, ClientMobile, authTime)
+	require.NoError(t, err)
+	other, err := CreateSessionToken(user, model.SecurityPolicy{}, ClientWeb, authTime)
+	require.NoError(t, err)
+
+	_, deviceToken, err := TrustDevice(s, user, "phone-1", "PassWall iOS", time.Now())
+	require.NoError(t, err)
+
+	td, err := RevokeAllSessions(s, user, model.SecurityPolicy{}, claimsOf(current.AccessToken), "81.2.69.160", time.Now())
+	require.NoError(t, err)
+
+	revoked, err := s.Users().FindByUUID(user.UUID.String())
+	require.NoError(t, err)
+	assert.Equal(t, 1, revoked.TokenEpoch)
+
+	// Tokens of the older epoch are rejected, the session asking it goes on
+	// with its new tokens
+	assert.False(t, TokenEpochValid(revoked, claimsOf(other.AccessToken)))
+	assert.False(t, TokenEpochValid(revoked, claimsOf(other.RefreshToken)))
+	assert.False(t, TokenEpochValid(revoked, claimsOf(current.AccessToken)))
+	claims := claimsOf(td.RefreshToken)
+	assert.True(t, TokenEpochValid(revoked, claims))
+	assert.Equal(t, ClientMobile, SessionClient(claims))
+	startedAt, _ := SessionStartedAt(claims)
+	assert.Equal(t, authTime.Unix(), startedAt.Unix())
+
+	// Trusted devices ask the two-factor code again
+	assert.False(t, TrustedDeviceValid(s, revoked, "phone-1", deviceToken, time.Now()))
+
+	// Tokens issued before the epoch was claimed are of epoch 0
+	assert.True(t, TokenEpochValid(&model.User{}, jwt.MapClaims{}))
+
+	pending, err := s.Outbox().Count(EmailStatusPending, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pending)
+}
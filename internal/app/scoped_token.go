@@ -0,0 +1,59 @@
+package app
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// ErrScopeNotGranted represents message for scoped tokens asking a scope the session doesn't have
+var ErrScopeNotGranted = errors.New("scoped tokens can't have scopes the session doesn't have")
+
+// CreateScopedToken creates an access token of the user limited to the scopes
+// for the clients which shouldn't use the whole account, like a monitoring
+// script reading the vault. The scopes must be a subset of the scopes of the
+// session creating it. Scoped tokens have no refresh token and can't be
+// refreshed, they are saved like the other tokens so signing out everywhere
+// revokes them.
+func CreateScopedToken(s storage.Store, user *model.User, sessionScopes []string, dto model.ScopedTokenDTO, now time.Time) (*model.TokenDetailsDTO, error) {
+	for _, scope := range dto.Scopes {
+		if !HasScope(sessionScopes, scope) {
+			return nil, ErrScopeNotGranted
+		}
+	}
+
+	td := &model.TokenDetailsDTO{
+		AtExpiresTime: now.Add(time.Duration(dto.ExpiresIn) * time.Hour),
+		AtUUID:        NewUUID(),
+	}
+
+	claims := jwt.MapClaims{}
+	claims["authorized"] = false
+	claims["user_uuid"] = user.UUID.String()
+	claims["exp"] = td.AtExpiresTime.Unix()
+	claims["uuid"] = td.AtUUID.String()
+	claims["scopes"] = dto.Scopes
+	claims["auth_time"] = now.Unix()
+	claims["epoch"] = user.TokenEpoch
+	claims["client"] = ClientAPI
+	claims["scoped"] = true
+
+	var err error
+	td.AccessToken, err = signToken(claims)
+	if err != nil {
+		return nil, err
+	}
+	s.Tokens().Create(int(user.ID), td.AtUUID, td.AccessToken, td.AtExpiresTime)
+	return td, nil
+}
+
+// ScopedToken checks if the token claims are of a scoped token, only they
+// are limited to their scopes claim
+func ScopedToken(claims jwt.MapClaims) bool {
+	scoped, _ := claims["scoped"].(bool)
+	return scoped
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/internal/storage/migration"
+	"github.com/passwall/passwall-server/internal/storage/sqlite"
+	"github.com/passwall/passwall-server/model"
+)
+
+func TestCreateScopedToken(t *testing.T) {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "passwall.db"), &gorm.Config{})
+	require.NoError(t, err)
+	s := storage.New(db)
+	require.NoError(t, s.Migrations().Up(migration.SetSystem, ""))
+
+	user := &model.User{ID: 1, UUID: uuid.NewV4(), Role: "Member"}
+	now := time.Now()
+	readOnly := model.ScopedTokenDTO{Scopes: []string{ScopeVaultRead}, ExpiresIn: 24}
+
+	td, err := CreateScopedToken(s, user, DefaultScopes(user), readOnly, now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(24*time.Hour), td.AtExpiresTime)
+
+	token, err := TokenValid(td.AccessToken)
+	require.NoError(t, err)
+	claims := token.Claims.(jwt.MapClaims)
+	assert.True(t, ScopedToken(claims))
+	scopes, ok := ScopesFromClaims(claims)
+	assert.True(t, ok)
+	assert.Equal(t, []string{ScopeVaultRead}, scopes)
+	assert.False(t, HasScope(scopes, ScopeVaultExport))
+
+	// Saved like the other tokens, so signing out everywhere revokes it
+	_, err = s.Tokens().FindByUUID(td.AtUUID.String())
+	assert.NoError(t, err)
+
+	// Scoped tokens can't widen the scopes of the session creating them
+	_, err = CreateScopedToken(s, user, []string{ScopeVaultRead}, model.ScopedTokenDTO{Scopes: []string{ScopeVaultWrite}, ExpiresIn: 1}, now)
+	assert.Equal(t, ErrScopeNotGranted, err)
+
+	// Regular session tokens aren't scoped
+	assert.False(t, ScopedToken(jwt.MapClaims{"scopes": []interface{}{ScopeVaultRead}}))
+}
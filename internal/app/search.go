@@ -0,0 +1,204 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/viper"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+// blindIndexLength is the length of the hex encoded token in the search index
+const blindIndexLength = 16
+
+// SettingIndexKeyID is the setting of the ID of the index key the stored
+// indexes are built with
+const SettingIndexKeyID = "search.index_key_id"
+
+// SearchLogins finds the logins whose title, domain or username contain all
+// the words of the query. Words are matched as a whole because the index only
+// keeps their HMACs.
+func SearchLogins(s storage.Store, query, schema string) ([]model.Login, error) {
+	tokens := BlindIndexTokens(query)
+	if len(tokens) == 0 {
+		return []model.Login{}, nil
+	}
+
+	loginList, err := s.Logins().Search(tokens, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decrypt server side encrypted fields
+	for i := range loginList {
+		if _, err := DecryptModel(&loginList[i], schema); err != nil {
+			logger.Errorf("Error while decrypting login: %v", err)
+		}
+	}
+
+	return loginList, nil
+}
+
+// SetLoginSearchIndex updates the search index and the blind indexes of the
+// login from its decrypted title, URL and username. Usernames encrypted by the
+// clients in zero-knowledge mode aren't indexed.
+func SetLoginSearchIndex(login *model.Login) {
+	username := login.Username
+	if IsClientCiphertext(username) {
+		username = ""
+	}
+
+	domain := loginDomain(login.URL)
+	login.SearchIndex = strings.Join(BlindIndexTokens(login.Title+" "+domain+" "+username), " ")
+	login.DomainIndex, login.UsernameIndex = "", ""
+	if domain != "" {
+		login.DomainIndex = blindIndex(domain)
+	}
+	if username = strings.ToLower(strings.TrimSpace(username)); username != "" {
+		login.UsernameIndex = blindIndex(username)
+	}
+}
+
+// setStoredLoginSearchIndex updates the indexes of the stored login of the
+// vault of the schema from a decrypted copy and reports whether they changed
+func setStoredLoginSearchIndex(login *model.Login, schema string) (bool, error) {
+	decrypted := *login
+	if _, err := DecryptModel(&decrypted, schema); err != nil {
+		return false, err
+	}
+	SetLoginSearchIndex(&decrypted)
+
+	changed := login.SearchIndex != decrypted.SearchIndex || login.DomainIndex != decrypted.DomainIndex ||
+		login.UsernameIndex != decrypted.UsernameIndex
+	login.SearchIndex, login.DomainIndex, login.UsernameIndex = decrypted.SearchIndex, decrypted.DomainIndex, decrypted.UsernameIndex
+	return changed, nil
+}
+
+// IndexLogins builds the indexes of the logins which don't have one yet
+func IndexLogins(s storage.Store, schema string) error {
+	return indexLogins(s, schema, false)
+}
+
+// indexLogins builds the indexes of the logins without one, or of all the
+// logins when they were built with another index key
+func indexLogins(s storage.Store, schema string, all bool) error {
+	loginList, err := s.Logins().All(schema)
+	if err != nil {
+		return err
+	}
+
+	for i := range loginList {
+		if loginList[i].SearchIndex != "" && !all {
+			continue
+		}
+		// Logins which can't be decrypted keep their indexes
+		changed, err := setStoredLoginSearchIndex(&loginList[i], schema)
+		if err != nil {
+			logger.Errorf("Error while indexing login %d of %s: %v", loginList[i].ID, schema, err)
+			continue
+		}
+		if !changed {
+			continue
+		}
+		if _, err := s.Logins().Update(&loginList[i], schema); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RebuildSearchIndexes builds the indexes of every vault again when they were
+// built with another index key, like before the index key was set
+func RebuildSearchIndexes(s storage.Store) error {
+	values, err := findSettings(s)
+	if err != nil {
+		return err
+	}
+	keyID := indexKeyID()
+	if values[SettingIndexKeyID] == keyID {
+		return nil
+	}
+
+	targets, err := vaultTargets(s)
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		if err := indexLogins(target.store, target.schema, true); err != nil {
+			return fmt.Errorf("schema %s: %w", target.schema, err)
+		}
+	}
+	return saveIndexKeyID(s)
+}
+
+// saveIndexKeyID saves the ID of the index key in use as the one the stored
+// indexes are built with
+func saveIndexKeyID(s storage.Store) error {
+	return saveSettings(s, []model.Setting{{Key: SettingIndexKeyID, Value: indexKeyID()}})
+}
+
+// BlindIndexTokens splits the text into lower case words and returns the unique
+// HMACs of them, so the search index doesn't reveal the content
+func BlindIndexTokens(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	seen := map[string]bool{}
+	tokens := []string{}
+	for _, word := range words {
+		token := blindIndex(word)
+		if seen[token] {
+			continue
+		}
+		seen[token] = true
+		tokens = append(tokens, token)
+	}
+
+	return tokens
+}
+
+func blindIndex(word string) string {
+	mac := hmac.New(sha256.New, indexKey())
+	mac.Write([]byte(word))
+	return hex.EncodeToString(mac.Sum(nil))[:blindIndexLength]
+}
+
+// indexKey returns the dedicated key of the blind indexes, servers without
+// one derive it from the passphrase like the older versions
+func indexKey() []byte {
+	if key := viper.GetString("server.indexKey"); key != "" {
+		return []byte(key)
+	}
+	return []byte("search:" + viper.GetString("server.passphrase"))
+}
+
+// indexKeyID identifies the index key without revealing it
+func indexKeyID() string {
+	return blindIndex("passwall index key id")
+}
+
+// loginDomain returns the lower case host of the URL without www
+func loginDomain(rawURL string) string {
+	rawURL = strings.TrimSpace(strings.ToLower(rawURL))
+	if rawURL == "" {
+		return ""
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
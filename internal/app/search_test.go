@@ -0,0 +1,98 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/internal/storage/migration"
+	"github.com/passwall/passwall-server/internal/storage/sqlite"
+	"github.com/passwall/passwall-server/model"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestBlindIndexTokens(t *testing.T) {
+	tokens := BlindIndexTokens("GitHub https://github.com/passwall")
+	assert.Len(t, tokens, 4)
+	for _, token := range tokens {
+		assert.Len(t, token, blindIndexLength)
+	}
+
+	// Case and punctuation don't change the tokens
+	assert.Equal(t, BlindIndexTokens("github passwall"), BlindIndexTokens("GitHub, PassWall!"))
+	assert.Empty(t, BlindIndexTokens(" -/ "))
+}
+
+func TestSetLoginSearchIndex(t *testing.T) {
+	login := &model.Login{Title: "Mail", URL: "mail.example.com"}
+	SetLoginSearchIndex(login)
+	assert.Contains(t, login.SearchIndex, BlindIndexTokens("example")[0])
+	assert.NotContains(t, login.SearchIndex, "example")
+}
+
+func TestSetLoginSearchIndexBlindIndexes(t *testing.T) {
+	login := &model.Login{Title: "Mail", URL: "https://www.Example.com/login", Username: " John "}
+	SetLoginSearchIndex(login)
+	assert.Contains(t, login.SearchIndex, BlindIndexTokens("john")[0])
+	assert.Equal(t, blindIndex("example.com"), login.DomainIndex)
+	assert.Equal(t, blindIndex("john"), login.UsernameIndex)
+
+	// A dedicated index key changes every index
+	defer viper.Set("server.indexKey", viper.GetString("server.indexKey"))
+	viper.Set("server.indexKey", "dedicated-index-key")
+	keyed := *login
+	SetLoginSearchIndex(&keyed)
+	assert.NotEqual(t, login.DomainIndex, keyed.DomainIndex)
+	assert.NotEqual(t, login.SearchIndex, keyed.SearchIndex)
+
+	// Usernames encrypted by the clients can't be indexed
+	zk := &model.Login{URL: "example.com", Username: clientCiphertext(12, 32)}
+	SetLoginSearchIndex(zk)
+	assert.Empty(t, zk.UsernameIndex)
+	assert.NotEmpty(t, zk.DomainIndex)
+}
+
+func TestRebuildSearchIndexes(t *testing.T) {
+	defer viper.Set("server.passphrase", viper.GetString("server.passphrase"))
+	defer viper.Set("server.indexKey", viper.GetString("server.indexKey"))
+	defer SetDataKeyStore(nil)
+	viper.Set("server.passphrase", "index-passphrase")
+	viper.Set("server.indexKey", "")
+
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "passwall.db"), &gorm.Config{})
+	require.NoError(t, err)
+	s := storage.New(db)
+	SetDataKeyStore(s)
+	require.NoError(t, s.Migrations().Up(migration.SetSystem, ""))
+	_, err = s.Users().Create(&model.User{Email: "index@passwall.io", Schema: "user_index"})
+	require.NoError(t, err)
+	require.NoError(t, s.Users().CreateSchema("user_index"))
+	require.NoError(t, s.Migrations().Up(migration.SetVault, "user_index"))
+
+	login, err := CreateLogin(s, &model.LoginDTO{Title: "Mail", URL: "https://mail.example.com", Username: "john"}, "user_index")
+	require.NoError(t, err)
+	require.NoError(t, RebuildSearchIndexes(s))
+
+	// Indexes are built with the dedicated key once it is set
+	viper.Set("server.indexKey", "dedicated-index-key")
+	found, err := SearchLogins(s, "john", "user_index")
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	require.NoError(t, RebuildSearchIndexes(s))
+	found, err = SearchLogins(s, "john", "user_index")
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, login.ID, found[0].ID)
+	assert.Equal(t, "john", found[0].Username)
+
+	stored, err := s.Logins().FindByID(login.ID, "user_index")
+	require.NoError(t, err)
+	assert.Equal(t, blindIndex("john"), stored.UsernameIndex)
+	values, err := findSettings(s)
+	require.NoError(t, err)
+	assert.Equal(t, indexKeyID(), values[SettingIndexKeyID])
+}
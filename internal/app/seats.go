@@ -0,0 +1,93 @@
+package app
+
+import (
+	"errors"
+
+	"github.com/passwall/passwall-server/internal/billing"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+var (
+	// ErrNoSeats is returned for the invitations beyond the seats of the organization
+	ErrNoSeats = errors.New("all seats of the organization are taken, add seats to invite more members")
+	// ErrTooFewSeats is returned for the seats fewer than the members and invitations
+	ErrTooFewSeats = errors.New("seats can't be fewer than the members and invitations of the organization")
+	// ErrNoTeamSubscription is returned for changing the seats of organizations without a subscription
+	ErrNoTeamSubscription = errors.New("organization doesn't have a team subscription")
+)
+
+// HasFreeSeat checks if the organization has a seat for one more member when
+// used of them are taken, organizations without seats are unlimited
+func HasFreeSeat(org *model.Organization, used int) bool {
+	return org.Seats == 0 || used < org.Seats
+}
+
+// CheckSeats checks if the organization has a seat for one more member,
+// invitations take a seat until they are accepted or removed
+func CheckSeats(s storage.Store, org *model.Organization) error {
+	if org.Seats == 0 {
+		return nil
+	}
+
+	members, err := s.Organizations().Members(org.ID)
+	if err != nil {
+		return err
+	}
+	if !HasFreeSeat(org, len(members)) {
+		return ErrNoSeats
+	}
+	return nil
+}
+
+// UpdateSeats changes the seats of the team subscription at the billing
+// provider, which prorates the change, and then in the organization
+func UpdateSeats(s storage.Store, provider billing.Provider, org *model.Organization, seats int) (*model.Organization, error) {
+	if provider == nil || org.SubscriptionID == "" {
+		return nil, ErrNoTeamSubscription
+	}
+
+	members, err := s.Organizations().Members(org.ID)
+	if err != nil {
+		return nil, err
+	}
+	if seats < len(members) {
+		return nil, ErrTooFewSeats
+	}
+
+	if err := provider.UpdateSeats(org.SubscriptionID, org.SubscriptionItem, seats); err != nil {
+		logger.Errorf("can't update seats of organization %d error: %v", org.ID, err)
+		return nil, err
+	}
+
+	org.Seats = seats
+	return s.Organizations().Update(org)
+}
+
+// UpdateTeamSubscription updates the seats of the organization of the team
+// subscription event
+func UpdateTeamSubscription(s storage.Store, event *billing.Event) error {
+	org, err := s.Organizations().FindByID(event.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	org.Seats = TeamSeats(event)
+	org.SubscriptionID, org.SubscriptionItem = "", ""
+	if event.Active || event.PastDue {
+		org.SubscriptionID, org.SubscriptionItem = event.SubscriptionID, event.SubscriptionItem
+	}
+	_, err = s.Organizations().Update(org)
+	return err
+}
+
+// TeamSeats returns the seats of the team subscription event. Ended
+// subscriptions leave the seat of the owner, the members keep their access but
+// no one else can be invited.
+func TeamSeats(event *billing.Event) int {
+	if (event.Active || event.PastDue) && event.Quantity > 0 {
+		return event.Quantity
+	}
+	return 1
+}
@@ -0,0 +1,29 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/passwall/passwall-server/internal/billing"
+	"github.com/passwall/passwall-server/model"
+)
+
+func TestHasFreeSeat(t *testing.T) {
+	assert.True(t, HasFreeSeat(&model.Organization{}, 100), "organizations without seats are unlimited")
+	assert.True(t, HasFreeSeat(&model.Organization{Seats: 3}, 2))
+	assert.False(t, HasFreeSeat(&model.Organization{Seats: 3}, 3))
+}
+
+func TestTeamSeats(t *testing.T) {
+	assert.Equal(t, 5, TeamSeats(&billing.Event{Active: true, Quantity: 5}))
+	assert.Equal(t, 5, TeamSeats(&billing.Event{PastDue: true, Quantity: 5}))
+	// Ended subscriptions leave the seat of the owner
+	assert.Equal(t, 1, TeamSeats(&billing.Event{Quantity: 5}))
+	assert.Equal(t, 1, TeamSeats(&billing.Event{Active: true}))
+}
+
+func TestUpdateSeatsWithoutSubscription(t *testing.T) {
+	_, err := UpdateSeats(nil, nil, &model.Organization{SubscriptionID: "sub_01h"}, 5)
+	assert.Equal(t, ErrNoTeamSubscription, err)
+}
@@ -0,0 +1,139 @@
+package app
+
+import (
+	"time"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+const (
+	// digestPeriod is the time between two security digests of a user
+	digestPeriod = 7 * 24 * time.Hour
+	// digestSignins is the number of the latest signins listed in a digest
+	digestSignins = 10
+)
+
+// SecurityDigest is the summary of the account security since the last digest
+type SecurityDigest struct {
+	Since time.Time
+	// Signins are the latest signins of the period, SigninCount counts them all
+	Signins     []model.AuditEvent
+	SigninCount int64
+	WeakCount   int
+	ReusedCount int
+	// ExpiringCount counts the passwords expiring before the next digest
+	ExpiringCount int
+	ExpiredCount  int
+	BreachedCount int
+	// Breaches are the email breaches found in the period
+	Breaches []model.EmailBreach
+}
+
+// SendSecurityDigests emails the weekly security digest to the users who opted
+// in, each user gets it a week after the previous one
+func SendSecurityDigests(s storage.Store) {
+	users, err := s.Users().All()
+	if err != nil {
+		logger.Errorf("Error while finding users for security digests: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for i := range users {
+		user := &users[i]
+		if !user.SecurityDigest || user.Schema == "" || user.DeletionScheduledAt != nil || user.DisabledAt != nil {
+			continue
+		}
+		since := now.Add(-digestPeriod)
+		if user.DigestSentAt != nil {
+			if now.Sub(*user.DigestSentAt) < digestPeriod {
+				continue
+			}
+			since = *user.DigestSentAt
+		}
+
+		digest, err := BuildSecurityDigest(s, user, since, now)
+		if err != nil {
+			logger.Errorf("Error while building security digest of user %d: %v", user.ID, err)
+			continue
+		}
+		if err := SendUserMail(s, user, EmailSecurityDigest, map[string]interface{}{"Digest": digest}); err != nil {
+			logger.Errorf("can't send email to %s error: %v\n", user.Email, err)
+			continue
+		}
+
+		user.DigestSentAt = &now
+		if _, err := s.Users().Update(user); err != nil {
+			logger.Errorf("Error while saving security digest of user %d: %v", user.ID, err)
+		}
+	}
+}
+
+// BuildSecurityDigest summarizes the signins and the breaches of the user in
+// the period and the state of the passwords in the vault of the user
+func BuildSecurityDigest(s storage.Store, user *model.User, since, until time.Time) (*SecurityDigest, error) {
+	opts := &model.ListOptions{
+		Limit:     digestSignins,
+		Sort:      "id",
+		Direction: "desc",
+		Filters:   map[string]interface{}{"user_id": user.ID, "action": AuditActionSignin},
+	}
+	signins, total, err := s.AuditEvents().FindAll(opts, model.AuditPeriod{From: &since, To: &until})
+	if err != nil {
+		return nil, err
+	}
+
+	logins, err := FindAllLogins(s, user.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	breaches, err := s.Breaches().FindByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := summarizeLogins(logins, until)
+	digest.Since = since
+	digest.Signins = signins
+	digest.SigninCount = total
+	for _, breach := range breaches {
+		if !breach.CreatedAt.Before(since) {
+			digest.Breaches = append(digest.Breaches, breach)
+		}
+	}
+	return digest, nil
+}
+
+// summarizeLogins counts the weak, reused, expiring, expired and breached
+// passwords of the decrypted logins
+func summarizeLogins(logins []model.Login, now time.Time) *SecurityDigest {
+	digest := &SecurityDigest{
+		WeakCount: buildPasswordReport(logins, 0, now).WeakCount,
+	}
+
+	fingerprints := make(map[uint]string, len(logins))
+	for i := range logins {
+		login := &logins[i]
+		if login.Password != "" {
+			fingerprints[login.ID] = passwordFingerprint(login.Password)
+		}
+
+		switch {
+		case login.Expired:
+			digest.ExpiredCount++
+		case passwordExpired(login, login.MaxPasswordAge, now.Add(digestPeriod)):
+			digest.ExpiringCount++
+		}
+		if login.Breached {
+			digest.BreachedCount++
+		}
+	}
+	for _, group := range groupReusedPasswords(logins, fingerprints) {
+		digest.ReusedCount += group.Count
+	}
+
+	return digest
+}
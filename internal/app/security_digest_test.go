@@ -0,0 +1,33 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/passwall/passwall-server/model"
+)
+
+func TestSummarizeLogins(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) *time.Time {
+		at := now.AddDate(0, 0, -days)
+		return &at
+	}
+
+	logins := []model.Login{
+		{ID: 1, Password: "123456", Breached: true},
+		{ID: 2, Password: "123456"},
+		{ID: 3, Password: "correct horse battery staple 42!", MaxPasswordAge: 90, PasswordChangedAt: daysAgo(85)},
+		{ID: 4, Password: "Tr0ub4dour&3-and-more", MaxPasswordAge: 90, PasswordChangedAt: daysAgo(95), Expired: true},
+		{ID: 5, Password: "an0ther L0ng passphrase!", MaxPasswordAge: 90, PasswordChangedAt: daysAgo(10)},
+	}
+
+	digest := summarizeLogins(logins, now)
+	assert.Equal(t, 2, digest.WeakCount)
+	assert.Equal(t, 2, digest.ReusedCount)
+	assert.Equal(t, 1, digest.ExpiringCount)
+	assert.Equal(t, 1, digest.ExpiredCount)
+	assert.Equal(t, 1, digest.BreachedCount)
+}
@@ -1,28 +1,212 @@
 package app
 
 import (
-	"github.com/spf13/viper"
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+
 	"gopkg.in/gomail.v2"
 
+	"github.com/passwall/passwall-server/internal/config"
+	"github.com/passwall/passwall-server/internal/i18n"
+	"github.com/passwall/passwall-server/internal/mail"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
 	"github.com/passwall/passwall-server/pkg/logger"
 )
 
-// SendMail is an helper to send mail all over the project
-func SendMail(toName, toEmail string, subject, bodyHTML string) error {
+// Email templates, each has a .txt file with the subject and the text body and
+// a .html file with the content of the HTML layout
+const (
+	EmailVerification      = "verification"
+	EmailDeletionCode      = "deletion_code"
+	EmailNewUser           = "new_user"
+	EmailDeletionScheduled = "deletion_scheduled"
+	EmailDeletionReminder  = "deletion_reminder"
+	EmailInvitation        = "invitation"
+	EmailExpiredPasswords  = "expired_passwords"
+	EmailImportCompleted   = "import_completed"
+	EmailImportFailed      = "import_failed"
+	EmailBreachAlert       = "breach_alert"
+	EmailSecurityAlert     = "security_alert"
+	EmailSecurityDigest    = "security_digest"
+	EmailTrialExpiring     = "trial_expiring"
+	EmailPaymentFailed     = "payment_failed"
+	EmailSubscriptionEnded = "subscription_ended"
+	EmailSessionsRevoked   = "sessions_revoked"
+	EmailChangeCode        = "email_change_code"
+	EmailChangeApproval    = "email_change_approval"
+	EmailChanged           = "email_changed"
+)
+
+// emailLayout is the HTML around the content of every email, operators can
+// override it to brand the emails
+const emailLayout = "layout.html"
+
+//go:embed assets/email
+var emailTemplates embed.FS
+
+// Email is a rendered email with its text and HTML alternatives
+type Email struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// SendMail is an helper to send mail all over the project, the email is
+// rendered from the template of the locale with the data and queued in the
+// outbox. The email worker sends it in the background, so SMTP failures aren't
+// returned.
+func SendMail(s storage.Store, locale, toName, toEmail, template string, data map[string]interface{}) error {
+	email, err := RenderEmail(locale, template, data)
+	if err != nil {
+		logger.Errorf("Failed to render email %s to '%s' error: %v", template, toEmail, err)
+		return err
+	}
+	return QueueEmail(s, toName, toEmail, template, email)
+}
+
+// SendUserMail sends the email to the user in the language of the user
+func SendUserMail(s storage.Store, user *model.User, template string, data map[string]interface{}) error {
+	return SendMail(s, user.Locale, user.Name, user.Email, template, data)
+}
+
+// smtpPool is the connection pool of the email configuration in use, it is
+// replaced when the configuration is reloaded
+var smtpPool struct {
+	sync.Mutex
+	pool *mail.Pool
+}
+
+// sendSMTP sends the email with the SMTP server. Email settings are read for
+// every mail so a reloaded configuration is used right away.
+func sendSMTP(email *model.OutboxEmail) error {
+	cfg := config.Current().Email
+
 	m := gomail.NewMessage()
-	m.SetHeader("From", m.FormatAddress(viper.GetString("email.fromemail"), viper.GetString("email.fromname")))
-	m.SetHeader("To", m.FormatAddress(toEmail, toName))
-	m.SetHeader("Subject", subject)
-	m.SetBody("text/html", bodyHTML)
-	d := gomail.NewDialer(
-		viper.GetString("email.host"),
-		viper.GetInt("email.port"),
-		viper.GetString("email.username"),
-		viper.GetString("email.password"),
-	)
-	err := d.DialAndSend(m)
+	m.SetHeader("From", m.FormatAddress(cfg.FromEmail, cfg.FromName))
+	m.SetHeader("To", m.FormatAddress(email.ToEmail, email.ToName))
+	m.SetHeader("Subject", email.Subject)
+	m.SetBody("text/plain", email.Text)
+	m.AddAlternative("text/html", email.HTML)
+	pool, err := emailPool(cfg)
+	if err != nil {
+		return err
+	}
+	return pool.Send(cfg.FromEmail, []string{email.ToEmail}, m)
+}
+
+// emailPool returns the connection pool of the configuration, the connections
+// of a previous configuration are closed
+func emailPool(cfg config.EmailConfiguration) (*mail.Pool, error) {
+	smtpPool.Lock()
+	defer smtpPool.Unlock()
+
+	if smtpPool.pool == nil || smtpPool.pool.Config() != cfg {
+		pool, err := mail.NewPool(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if smtpPool.pool != nil {
+			smtpPool.pool.Close()
+		}
+		smtpPool.pool = pool
+	}
+	return smtpPool.pool, nil
+}
+
+// RenderEmail renders the subject and the bodies of the template. The sender
+// name, the server domain and the locale are added to the data as FromName,
+// Domain and Locale. Files in the email.templatesDir directory are used
+// instead of the built in ones with the same name, the translations of a
+// locale are in its subdirectory.
+func RenderEmail(locale, template string, data map[string]interface{}) (*Email, error) {
+	if !i18n.Supported(locale) {
+		locale = i18n.DefaultLocale
+	}
+	cfg := config.Current()
+	values := map[string]interface{}{
+		"FromName": cfg.Email.FromName,
+		"Domain":   cfg.Server.Domain,
+		"Locale":   locale,
+	}
+	for key, value := range data {
+		values[key] = value
+	}
+	dir := cfg.Email.TemplatesDir
+
+	textSource, err := readEmailTemplate(dir, locale, template+".txt")
+	if err != nil {
+		return nil, err
+	}
+	text, err := texttemplate.New(template).Option("missingkey=error").Parse(textSource)
+	if err != nil {
+		return nil, fmt.Errorf("email template %s.txt: %w", template, err)
+	}
+
+	layoutSource, err := readEmailTemplate(dir, locale, emailLayout)
+	if err != nil {
+		return nil, err
+	}
+	htmlSource, err := readEmailTemplate(dir, locale, template+".html")
+	if err != nil {
+		return nil, err
+	}
+	html, err := htmltemplate.New(template).Option("missingkey=error").Parse(layoutSource)
+	if err == nil {
+		_, err = html.Parse(htmlSource)
+	}
 	if err != nil {
-		logger.Errorf("Failed to send email to '%s' error: %v", toEmail, err)
+		return nil, fmt.Errorf("email template %s.html: %w", template, err)
+	}
+
+	var subject, body, htmlBody bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", values); err != nil {
+		return nil, err
+	}
+	if err := text.Execute(&body, values); err != nil {
+		return nil, err
+	}
+	if err := html.ExecuteTemplate(&htmlBody, "layout", values); err != nil {
+		return nil, err
+	}
+
+	return &Email{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    body.String(),
+		HTML:    htmlBody.String(),
+	}, nil
+}
+
+// readEmailTemplate reads the template file of the locale from the directory,
+// or the built in one when the directory doesn't have it. Templates which
+// aren't translated to the locale are the default ones.
+func readEmailTemplate(dir, locale, name string) (string, error) {
+	names := []string{name}
+	if locale != i18n.DefaultLocale {
+		names = []string{path.Join(locale, name), name}
+	}
+
+	for _, name := range names {
+		if dir != "" {
+			data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+			if err == nil {
+				return string(data), nil
+			}
+			if !os.IsNotExist(err) {
+				return "", err
+			}
+		}
+		if data, err := emailTemplates.ReadFile("assets/email/" + name); err == nil {
+			return string(data), nil
+		}
 	}
-	return err
+	return "", fmt.Errorf("email template %s doesn't exist", name)
 }
@@ -0,0 +1,118 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderEmail(t *testing.T) {
+	email, err := RenderEmail("en", EmailVerification, map[string]interface{}{"Code": "<123456>"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Passwall Email Verification", email.Subject)
+		assert.Contains(t, email.Text, "<123456>")
+		assert.Contains(t, email.HTML, "&lt;123456&gt;")
+		assert.NotContains(t, email.HTML, "<123456>")
+	}
+
+	_, err = RenderEmail("en", EmailVerification, nil)
+	assert.Error(t, err, "missing data")
+
+	_, err = RenderEmail("en", "unknown", nil)
+	assert.Error(t, err)
+}
+
+func TestRenderEveryEmail(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		EmailVerification:      {"Code": "123456"},
+		EmailDeletionCode:      {"Code": "123456"},
+		EmailNewUser:           {"Name": "Jane", "Email": "jane@passwall.io"},
+		EmailDeletionScheduled: {"Date": "2026-01-02"},
+		EmailDeletionReminder:  {"Date": "2026-01-02"},
+		EmailInvitation:        {"Organization": "Passwall"},
+		EmailExpiredPasswords:  {"Titles": []string{"GitHub"}},
+		EmailImportCompleted:   {"Summary": &model.ImportSummary{Created: 3}},
+		EmailImportFailed:      {"Error": "invalid file"},
+		EmailBreachAlert:       {"Breaches": []model.EmailBreach{{Title: "Adobe", Email: "jane@passwall.io"}}},
+		EmailSecurityAlert:     {"Details": "12 exports in 10 minutes", "SessionsRevoked": true},
+		EmailSecurityDigest: {"Digest": &SecurityDigest{
+			Signins:     []model.AuditEvent{{IP: "81.2.69.160", Country: "GB", City: "London"}},
+			SigninCount: 1,
+			Breaches:    []model.EmailBreach{{Title: "Adobe", Email: "jane@passwall.io"}},
+		}},
+		EmailTrialExpiring:     {"Date": "2026-01-02", "Days": 3},
+		EmailPaymentFailed:     {"Date": "2026-01-02"},
+		EmailSubscriptionEnded: {},
+		EmailSessionsRevoked:   {"IP": "81.2.69.160", "Time": "2026-01-02 10:00 UTC"},
+		EmailChangeCode:        {"Code": "123456"},
+		EmailChangeApproval:    {"Email": "new@passwall.io", "Token": "abc123"},
+		EmailChanged:           {"OldEmail": "old@passwall.io", "Email": "new@passwall.io", "Time": "2026-01-02 10:00 UTC"},
+	}
+	for template, data := range tests {
+		for _, locale := range []string{"en", "tr"} {
+			email, err := RenderEmail(locale, template, data)
+			if assert.NoError(t, err, template) {
+				assert.NotEmpty(t, email.Subject, template)
+				assert.NotEmpty(t, email.Text, template)
+				assert.Contains(t, email.HTML, "<html", template)
+			}
+		}
+	}
+
+	_, err := RenderEmail("en", EmailImportCompleted, map[string]interface{}{"Summary": (*model.ImportSummary)(nil)})
+	assert.NoError(t, err, "import without summary")
+}
+
+func TestRenderLocalizedEmail(t *testing.T) {
+	email, err := RenderEmail("tr", EmailVerification, map[string]interface{}{"Code": "123456"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Passwall E-posta Doğrulama", email.Subject)
+		assert.Contains(t, email.Text, "doğrulama kodunuz 123456")
+		assert.Contains(t, email.HTML, `<html lang="tr">`)
+		assert.Contains(t, email.HTML, "gönderildi")
+	}
+
+	// Emails which aren't translated are in English
+	email, err = RenderEmail("tr", EmailInvitation, map[string]interface{}{"Organization": "Passwall"})
+	if assert.NoError(t, err) {
+		assert.Contains(t, email.HTML, "This email was sent by")
+	}
+
+	// Unknown locales are the default one
+	email, err = RenderEmail("../tr", EmailVerification, map[string]interface{}{"Code": "123456"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Passwall Email Verification", email.Subject)
+	}
+}
+
+func TestReadEmailTemplate(t *testing.T) {
+	dir := t.TempDir()
+	override := `{{define "subject"}}Welcome{{end}}`
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "verification.txt"), []byte(override), 0600))
+
+	source, err := readEmailTemplate(dir, "en", "verification.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, override, source)
+
+	// Templates missing in the directory are the built in ones
+	source, err = readEmailTemplate(dir, "en", "verification.html")
+	assert.NoError(t, err)
+	assert.Contains(t, source, `{{define "content"}}`)
+
+	_, err = readEmailTemplate(dir, "en", "unknown.txt")
+	assert.Error(t, err)
+
+	// Translations in the directory are used before the built in ones
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "tr"), 0700))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "tr", "verification.txt"), []byte("Merhaba"), 0600))
+	source, err = readEmailTemplate(dir, "tr", "verification.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "Merhaba", source)
+
+	source, err = readEmailTemplate(dir, "tr", "verification.html")
+	assert.NoError(t, err)
+	assert.Contains(t, source, "doğrulama kodunuz")
+}
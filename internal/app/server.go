@@ -15,7 +15,7 @@ func FindAllServers(s storage.Store, schema string) ([]model.Server, error) {
 
 	// Decrypt server side encrypted fields
 	for i := range list {
-		m, err := DecryptModel(&list[i])
+		m, err := DecryptModel(&list[i], schema)
 		if err != nil {
 			logger.Errorf("Error while decrypting credit card: %v", err)
 			continue
@@ -29,9 +29,12 @@ func FindAllServers(s storage.Store, schema string) ([]model.Server, error) {
 // CreateServer creates a server and saves it to the store
 func CreateServer(s storage.Store, dto *model.ServerDTO, schema string) (*model.Server, error) {
 	rawModel := model.ToServer(dto)
-	encModel := EncryptModel(rawModel)
+	seal, err := sealCreated(s, rawModel, schema)
+	if err != nil {
+		return nil, err
+	}
 
-	createdServer, err := s.Servers().Create(encModel.(*model.Server), schema)
+	createdServer, err := s.Servers().CreateSealed(rawModel, schema, seal)
 	if err != nil {
 		return nil, err
 	}
@@ -41,8 +44,17 @@ func CreateServer(s storage.Store, dto *model.ServerDTO, schema string) (*model.
 
 // UpdateServer updates the server with the dto and applies the changes in the store
 func UpdateServer(s storage.Store, server *model.Server, dto *model.ServerDTO, schema string) (*model.Server, error) {
+	if err := checkRevision(dto.Revision, server.Revision); err != nil {
+		return nil, err
+	}
+
 	rawModel := model.ToServer(dto)
-	encModel := EncryptModel(rawModel).(*model.Server)
+	rawModel.ID = server.ID
+	encrypted, err := EncryptModel(s, rawModel, schema)
+	if err != nil {
+		return nil, err
+	}
+	encModel := encrypted.(*model.Server)
 
 	server.Title = encModel.Title
 	server.IP = encModel.IP
@@ -54,6 +66,7 @@ func UpdateServer(s storage.Store, server *model.Server, dto *model.ServerDTO, s
 	server.AdminUsername = encModel.AdminUsername
 	server.AdminPassword = encModel.AdminPassword
 	server.Extra = encModel.Extra
+	server.FolderID = encModel.FolderID
 
 	updatedServer, err := s.Servers().Update(server, schema)
 	if err != nil {
@@ -0,0 +1,60 @@
+package app
+
+import (
+	"sync"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// settingsCacheTTL is how long an instance uses the settings it read, the
+// changes of the other instances are seen after it
+const settingsCacheTTL = time.Minute
+
+// settingsCache are the settings the instance read last by their keys
+var settingsCache struct {
+	sync.Mutex
+	values map[string]string
+	readAt time.Time
+}
+
+// findSettings returns the values of the instance settings by their keys, they
+// are read from the database at most once a minute
+func findSettings(s storage.Store) (map[string]string, error) {
+	settingsCache.Lock()
+	defer settingsCache.Unlock()
+
+	if settingsCache.values != nil && time.Since(settingsCache.readAt) < settingsCacheTTL {
+		return settingsCache.values, nil
+	}
+	settings, err := s.Settings().All()
+	if err != nil {
+		return nil, err
+	}
+	settingsCache.values = settingValues(settings)
+	settingsCache.readAt = time.Now()
+	return settingsCache.values, nil
+}
+
+// saveSettings stores the settings, the instance uses them right away
+func saveSettings(s storage.Store, settings []model.Setting) error {
+	if err := s.Settings().Save(settings); err != nil {
+		return err
+	}
+
+	settingsCache.Lock()
+	defer settingsCache.Unlock()
+	// Settings of the other instances are read again with the next lookup
+	settingsCache.values = nil
+	return nil
+}
+
+// settingValues returns the values of the settings by their keys
+func settingValues(settings []model.Setting) map[string]string {
+	values := make(map[string]string, len(settings))
+	for _, setting := range settings {
+		values[setting.Key] = setting.Value
+	}
+	return values
+}
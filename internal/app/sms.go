@@ -0,0 +1,69 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/passwall/passwall-server/internal/i18n"
+)
+
+// Verification code channels
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+)
+
+// smsCodeMessage is the text of the code messages, it is translated like the
+// server messages
+const smsCodeMessage = "Your Passwall verification code is %s"
+
+var (
+	// ErrSMSDisabled is returned when a code is requested by SMS but no SMS
+	// provider is configured
+	ErrSMSDisabled = errors.New("SMS is not enabled")
+	// ErrInvalidPhone is returned for the phone numbers which aren't in E.164 format
+	ErrInvalidPhone = errors.New("phone number must be in E.164 format like +905551112233")
+	// ErrPhoneNotVerified is returned when a code is requested by SMS for a user
+	// without a verified phone number
+	ErrPhoneNotVerified = errors.New("phone number is not verified")
+)
+
+// phonePattern matches the E.164 numbers, a plus sign and up to 15 digits
+var phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// SMSSender sends the text messages with an SMS provider
+type SMSSender interface {
+	// Send sends the text to the phone number in E.164 format
+	Send(to, text string) error
+}
+
+// smsSender sends the verification codes by SMS, nil sends them only by email
+var smsSender SMSSender
+
+// SetSMSSender sets the sender of the text messages, it is set at startup when
+// an SMS provider is configured
+func SetSMSSender(sender SMSSender) {
+	smsSender = sender
+}
+
+// SMSEnabled checks if the codes can be sent by SMS
+func SMSEnabled() bool {
+	return smsSender != nil
+}
+
+// ValidPhone checks if the phone number is in E.164 format
+func ValidPhone(phone string) bool {
+	return phonePattern.MatchString(phone)
+}
+
+// SendSMSCode sends the verification code to the phone in the language of the locale
+func SendSMSCode(locale, phone, code string) error {
+	if smsSender == nil {
+		return ErrSMSDisabled
+	}
+	if !ValidPhone(phone) {
+		return ErrInvalidPhone
+	}
+	return smsSender.Send(phone, fmt.Sprintf(i18n.Translate(locale, smsCodeMessage), code))
+}
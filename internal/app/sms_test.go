@@ -0,0 +1,44 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSMSSender struct {
+	to, text string
+}
+
+func (f *fakeSMSSender) Send(to, text string) error {
+	f.to, f.text = to, text
+	return nil
+}
+
+func TestValidPhone(t *testing.T) {
+	assert.True(t, ValidPhone("+905551112233"))
+	assert.True(t, ValidPhone("+15005550006"))
+	assert.False(t, ValidPhone("905551112233"))
+	assert.False(t, ValidPhone("+0905551112233"))
+	assert.False(t, ValidPhone("+90 555 111 22 33"))
+	assert.False(t, ValidPhone("+1234567890123456"))
+}
+
+func TestSendSMSCode(t *testing.T) {
+	defer SetSMSSender(nil)
+
+	assert.False(t, SMSEnabled())
+	assert.Equal(t, ErrSMSDisabled, SendSMSCode("en", "+905551112233", "123456"))
+
+	sender := &fakeSMSSender{}
+	SetSMSSender(sender)
+	assert.True(t, SMSEnabled())
+	assert.Equal(t, ErrInvalidPhone, SendSMSCode("en", "5551112233", "123456"))
+
+	assert.NoError(t, SendSMSCode("en", "+905551112233", "123456"))
+	assert.Equal(t, "+905551112233", sender.to)
+	assert.Equal(t, "Your Passwall verification code is 123456", sender.text)
+
+	assert.NoError(t, SendSMSCode("tr", "+905551112233", "123456"))
+	assert.Equal(t, "Passwall doğrulama kodunuz 123456", sender.text)
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"time"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+// StatsMaxDays is the most days of signups the statistics return
+const StatsMaxDays = 365
+
+// itemTables are the vault tables counted as items in the statistics
+var itemTables = map[string]bool{
+	"logins":        true,
+	"credit_cards":  true,
+	"bank_accounts": true,
+	"notes":         true,
+	"emails":        true,
+	"servers":       true,
+}
+
+// FindInstanceStats returns the statistics of the instance with the signups
+// of the last days until now
+func FindInstanceStats(s storage.Store, now time.Time, days int) (*model.InstanceStats, error) {
+	users, err := s.Users().All()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &model.InstanceStats{
+		Users:   CountUsers(users, now),
+		Items:   map[string]int64{},
+		Signups: DailySignups(users, now, days),
+	}
+	for table := range itemTables {
+		stats.Items[table] = 0
+	}
+	// Vaults which can't be read are left out, so one broken schema doesn't
+	// hide the statistics of the others
+	for _, user := range users {
+		states, err := s.Vaults().State(user.Schema)
+		if err != nil {
+			logger.Errorf("can't count items of user %d error: %v", user.ID, err)
+			continue
+		}
+		for _, state := range states {
+			if itemTables[state.Name] {
+				stats.Items[state.Name] += state.Count
+			}
+		}
+	}
+
+	if stats.Sessions.Tokens, stats.Sessions.Users, err = s.Tokens().CountActive(now); err != nil {
+		return nil, err
+	}
+	if stats.Storage.DatabaseBytes, err = s.Size(); err != nil {
+		return nil, err
+	}
+	if stats.Emails.Pending, err = s.Outbox().Count(EmailStatusPending, 0); err != nil {
+		return nil, err
+	}
+	if stats.Emails.Retrying, err = s.Outbox().Count(EmailStatusPending, 1); err != nil {
+		return nil, err
+	}
+	if stats.Emails.Dead, err = s.Outbox().Count(EmailStatusDead, 0); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// CountUsers counts the users by their status and the plan they have access to
+func CountUsers(users []model.User, now time.Time) model.UserStats {
+	stats := model.UserStats{Total: len(users), Plans: map[string]int{}}
+	for i := range users {
+		user := &users[i]
+		switch {
+		case user.DisabledAt != nil:
+			stats.Disabled++
+		case user.DeletionScheduledAt != nil:
+			stats.Scheduled++
+		default:
+			stats.Active++
+		}
+		stats.Plans[SubscriptionAccess(user, now)]++
+	}
+	return stats
+}
+
+// DailySignups counts the users created on each of the last days until now,
+// days without signups are included with zero
+func DailySignups(users []model.User, now time.Time, days int) []model.DailyCount {
+	today := now.UTC().Truncate(24 * time.Hour)
+	first := today.AddDate(0, 0, 1-days)
+
+	signups := make([]model.DailyCount, days)
+	for i := range signups {
+		signups[i].Date = first.AddDate(0, 0, i).Format("2006-01-02")
+	}
+	for _, user := range users {
+		day := int(user.CreatedAt.UTC().Sub(first).Hours() / 24)
+		if user.CreatedAt.Before(first) || day >= days {
+			continue
+		}
+		signups[day].Count++
+	}
+	return signups
+}
@@ -0,0 +1,43 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/passwall/passwall-server/model"
+)
+
+func TestCountUsers(t *testing.T) {
+	now := time.Now()
+	users := []model.User{
+		{SubscriptionType: SubscriptionTypeFree},
+		{SubscriptionType: SubscriptionTypePro},
+		{SubscriptionType: SubscriptionTypeFree, DisabledAt: &now},
+		{SubscriptionType: SubscriptionTypeFree, DeletionScheduledAt: &now},
+	}
+
+	stats := CountUsers(users, now)
+	assert.Equal(t, 4, stats.Total)
+	assert.Equal(t, 2, stats.Active)
+	assert.Equal(t, 1, stats.Disabled)
+	assert.Equal(t, 1, stats.Scheduled)
+	assert.Equal(t, map[string]int{SubscriptionTypeFree: 3, SubscriptionTypePro: 1}, stats.Plans)
+}
+
+func TestDailySignups(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC)
+	users := []model.User{
+		{CreatedAt: now},
+		{CreatedAt: now.Add(-time.Hour)},
+		{CreatedAt: time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)},
+		{CreatedAt: time.Date(2026, 3, 7, 23, 59, 0, 0, time.UTC)},
+	}
+
+	assert.Equal(t, []model.DailyCount{
+		{Date: "2026-03-08", Count: 1},
+		{Date: "2026-03-09", Count: 0},
+		{Date: "2026-03-10", Count: 2},
+	}, DailySignups(users, now, 3))
+}
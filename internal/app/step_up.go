@@ -0,0 +1,46 @@
+package app
+
+import (
+	"errors"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/spf13/viper"
+)
+
+// ErrReauthenticationRequired represents message for sensitive operations without a recent re-authentication
+var ErrReauthenticationRequired = errors.New("master password must be confirmed for this operation")
+
+// Reauthenticate confirms the master password of the user and starts the
+// step-up window in which sensitive operations like plaintext exports are allowed
+func Reauthenticate(s storage.Store, userID uint, masterPassword string) (*model.User, error) {
+	user, err := s.Users().FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err = s.Users().FindByCredentials(user.Email, masterPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user.ReauthenticatedAt = &now
+	return s.Users().Update(user)
+}
+
+// IsRecentlyAuthenticated checks if the user confirmed the master password
+// within the step-up window before the given time
+func IsRecentlyAuthenticated(user *model.User, window time.Duration, now time.Time) bool {
+	if user.ReauthenticatedAt == nil {
+		return false
+	}
+	elapsed := now.Sub(*user.ReauthenticatedAt)
+	return elapsed >= 0 && elapsed <= window
+}
+
+// StepUpWindow returns how long a re-authentication is valid for sensitive operations
+func StepUpWindow() time.Duration {
+	return time.Duration(viper.GetInt("server.stepUpWindow")) * time.Minute
+}
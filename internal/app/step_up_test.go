@@ -0,0 +1,35 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRecentlyAuthenticated(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	tests := []struct {
+		name     string
+		user     model.User
+		expected bool
+	}{
+		{name: "Never confirmed", user: model.User{}, expected: false},
+		{name: "Within window", user: model.User{ReauthenticatedAt: at(-2 * time.Minute)}, expected: true},
+		{name: "Window end", user: model.User{ReauthenticatedAt: at(-5 * time.Minute)}, expected: true},
+		{name: "Expired", user: model.User{ReauthenticatedAt: at(-6 * time.Minute)}, expected: false},
+		{name: "In the future", user: model.User{ReauthenticatedAt: at(time.Minute)}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, IsRecentlyAuthenticated(&tt.user, 5*time.Minute, now))
+		})
+	}
+}
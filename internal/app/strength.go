@@ -0,0 +1,72 @@
+package app
+
+import (
+	"math"
+	"unicode"
+)
+
+// WeakPasswordScore is the highest strength score counted as weak
+const WeakPasswordScore = 2
+
+// PasswordStrength scores the password from 0 (very weak) to 4 (very strong)
+// regarding to its estimated entropy
+func PasswordStrength(password string) int {
+	bits := passwordEntropy(password)
+
+	switch {
+	case bits < 28:
+		return 0
+	case bits < 36:
+		return 1
+	case bits < 60:
+		return 2
+	case bits < 128:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// passwordEntropy estimates the entropy bits of the password by the size of
+// the character pool it uses. Repeated characters don't add entropy.
+func passwordEntropy(password string) float64 {
+	var lower, upper, digit, symbol bool
+	seen := make(map[rune]bool)
+	unique := 0
+
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+		if !seen[r] {
+			seen[r] = true
+			unique++
+		}
+	}
+
+	pool := 0
+	if lower {
+		pool += 26
+	}
+	if upper {
+		pool += 26
+	}
+	if digit {
+		pool += 10
+	}
+	if symbol {
+		pool += 33
+	}
+	if pool == 0 {
+		return 0
+	}
+
+	return float64(unique) * math.Log2(float64(pool))
+}
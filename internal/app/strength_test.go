@@ -0,0 +1,28 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordStrength(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		expected int
+	}{
+		{name: "Empty password", password: "", expected: 0},
+		{name: "Repeated characters", password: "aaaaaaaaaaaa", expected: 0},
+		{name: "Short lowercase", password: "secret", expected: 0},
+		{name: "Lowercase and digits", password: "secret123", expected: 2},
+		{name: "Mixed characters", password: "S3cr3t!Passw0rd", expected: 3},
+		{name: "Long random", password: "x7#Kq9!mZ2@vLp5$Wn8&Rt4*Yb6^Hc3%", expected: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, PasswordStrength(tt.password))
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/config"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+// Subscription types of the users, trial users have the pro features until
+// their trial ends and past due ones until their grace period ends
+const (
+	SubscriptionTypeFree    = "free"
+	SubscriptionTypePro     = "pro"
+	SubscriptionTypeTrial   = "trial"
+	SubscriptionTypePastDue = "past_due"
+)
+
+var (
+	// ErrAlreadyGranted is returned for granting pro to a user who has a grant
+	ErrAlreadyGranted = errors.New("user has a pro grant already, extend it instead")
+	// ErrNotGranted is returned for changing the grant of a user without one
+	ErrNotGranted = errors.New("user doesn't have a pro grant")
+	// ErrGrantEnded is returned for the grants ending before now
+	ErrGrantEnded = errors.New("grant must end in the future")
+)
+
+// SubscriptionAccess returns the subscription type the clients check, users
+// in trial have the pro features and the ones whose trial ended the free ones.
+// Every user has the pro features on servers with a license, and the users
+// admins granted pro until their grant ends.
+func SubscriptionAccess(user *model.User, now time.Time) string {
+	switch {
+	case Licensed(now), Granted(user, now):
+		return SubscriptionTypePro
+	case InTrial(user, now):
+		return SubscriptionTypePro
+	case user.SubscriptionType == SubscriptionTypePro:
+		return SubscriptionTypePro
+	case user.SubscriptionType == SubscriptionTypePastDue && !graceEnded(user, now):
+		return SubscriptionTypePastDue
+	default:
+		return SubscriptionTypeFree
+	}
+}
+
+// LapsedSubscriptionType returns the type of a paying user whose subscription
+// couldn't be confirmed. The user is past due for billing.graceDays after the
+// first failure and free after that.
+func LapsedSubscriptionType(user *model.User, now time.Time) string {
+	if user.SubscriptionType != SubscriptionTypePro && user.SubscriptionType != SubscriptionTypePastDue {
+		return SubscriptionTypeFree
+	}
+	if config.Current().Billing.GraceDays <= 0 || graceEnded(user, now) {
+		return SubscriptionTypeFree
+	}
+	return SubscriptionTypePastDue
+}
+
+// graceEnded checks if the grace period of the past due user is over, it
+// starts when the subscription lapses
+func graceEnded(user *model.User, now time.Time) bool {
+	if user.SubscriptionLapsedAt == nil {
+		return false
+	}
+	grace := time.Duration(config.Current().Billing.GraceDays) * 24 * time.Hour
+	return !now.Before(user.SubscriptionLapsedAt.Add(grace))
+}
+
+// ExpireGracePeriods downgrades the past due users whose grace period ended
+func ExpireGracePeriods(s storage.Store) {
+	users, err := s.Users().All()
+	if err != nil {
+		logger.Errorf("Error while finding users for grace periods: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for i := range users {
+		user := &users[i]
+		if user.SubscriptionType == SubscriptionTypePastDue && graceEnded(user, now) {
+			UpdateSubscription(s, user, SubscriptionTypeFree)
+		}
+	}
+}
+
+// trackLapse starts the grace period of the users becoming past due and ends
+// it when they pay or are downgraded
+func trackLapse(user *model.User, subscriptionType string) {
+	switch {
+	case subscriptionType == SubscriptionTypePastDue && user.SubscriptionLapsedAt == nil:
+		now := time.Now()
+		user.SubscriptionLapsedAt = &now
+	case subscriptionType != SubscriptionTypePastDue:
+		user.SubscriptionLapsedAt = nil
+	}
+}
+
+// notifyDunning emails the user when the payment of the subscription fails
+// and when the subscription ends after the grace period
+func notifyDunning(s storage.Store, user *model.User, previous, subscriptionType string) {
+	template := ""
+	data := map[string]interface{}{}
+	switch {
+	case subscriptionType == SubscriptionTypePastDue:
+		template = EmailPaymentFailed
+		grace := time.Duration(config.Current().Billing.GraceDays) * 24 * time.Hour
+		data["Date"] = user.SubscriptionLapsedAt.Add(grace).Format("2006-01-02")
+	case previous == SubscriptionTypePastDue && subscriptionType == SubscriptionTypeFree:
+		template = EmailSubscriptionEnded
+	default:
+		return
+	}
+
+	if err := SendUserMail(s, user, template, data); err != nil {
+		logger.Errorf("can't send email to %s error: %v\n", user.Email, err)
+	}
+}
+
+// Granted checks if the user has a pro grant which didn't end at the time
+func Granted(user *model.User, now time.Time) bool {
+	if user.SubscriptionGrantedAt == nil {
+		return false
+	}
+	return user.SubscriptionGrantedUntil == nil || now.Before(*user.SubscriptionGrantedUntil)
+}
+
+// GrantSubscription grants the pro features to the user until the time, or
+// until it is revoked when until is nil. Grants don't change the subscription
+// of the user at the payment provider.
+func GrantSubscription(s storage.Store, email string, until *time.Time, now time.Time) (*model.User, error) {
+	user, err := s.Users().FindByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("user %s: %w", email, err)
+	}
+	if Granted(user, now) {
+		return nil, ErrAlreadyGranted
+	}
+	if until != nil && !now.Before(*until) {
+		return nil, ErrGrantEnded
+	}
+
+	user.SubscriptionGrantedAt = &now
+	user.SubscriptionGrantedUntil = until
+	return s.Users().Update(user)
+}
+
+// ExtendSubscription changes the end of the pro grant of the user, nil makes
+// it last until it is revoked
+func ExtendSubscription(s storage.Store, email string, until *time.Time, now time.Time) (*model.User, error) {
+	user, err := s.Users().FindByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("user %s: %w", email, err)
+	}
+	if !Granted(user, now) {
+		return nil, ErrNotGranted
+	}
+	if until != nil && !now.Before(*until) {
+		return nil, ErrGrantEnded
+	}
+
+	user.SubscriptionGrantedUntil = until
+	return s.Users().Update(user)
+}
+
+// RevokeSubscription ends the pro grant of the user, paying users keep the
+// pro features of their subscription
+func RevokeSubscription(s storage.Store, email string) (*model.User, error) {
+	user, err := s.Users().FindByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("user %s: %w", email, err)
+	}
+	if user.SubscriptionGrantedAt == nil {
+		return nil, ErrNotGranted
+	}
+
+	user.SubscriptionGrantedAt = nil
+	user.SubscriptionGrantedUntil = nil
+	return s.Users().Update(user)
+}
@@ -0,0 +1,60 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/passwall/passwall-server/model"
+)
+
+func TestTrackLapse(t *testing.T) {
+	user := &model.User{SubscriptionType: SubscriptionTypePro}
+
+	trackLapse(user, SubscriptionTypePastDue)
+	if assert.NotNil(t, user.SubscriptionLapsedAt) {
+		lapsedAt := *user.SubscriptionLapsedAt
+
+		// The grace period starts at the first failure
+		trackLapse(user, SubscriptionTypePastDue)
+		assert.Equal(t, lapsedAt, *user.SubscriptionLapsedAt)
+	}
+
+	trackLapse(user, SubscriptionTypePro)
+	assert.Nil(t, user.SubscriptionLapsedAt)
+}
+
+func TestLapsedSubscriptionType(t *testing.T) {
+	now := time.Now()
+
+	// Grace periods are disabled in the empty configuration
+	assert.Equal(t, SubscriptionTypeFree, LapsedSubscriptionType(&model.User{SubscriptionType: SubscriptionTypePro}, now))
+	assert.Equal(t, SubscriptionTypeFree, LapsedSubscriptionType(&model.User{SubscriptionType: SubscriptionTypeTrial}, now))
+
+	lapsedAt := now.Add(-time.Hour)
+	user := &model.User{SubscriptionType: SubscriptionTypePastDue, SubscriptionLapsedAt: &lapsedAt}
+	assert.True(t, graceEnded(user, now))
+	assert.Equal(t, SubscriptionTypeFree, SubscriptionAccess(user, now))
+
+	user.SubscriptionLapsedAt = nil
+	assert.Equal(t, SubscriptionTypePastDue, SubscriptionAccess(user, now))
+}
+
+func TestGranted(t *testing.T) {
+	now := time.Now()
+	user := &model.User{SubscriptionType: SubscriptionTypeFree}
+	assert.False(t, Granted(user, now))
+
+	// Grants without an end last until they are revoked
+	grantedAt := now.Add(-time.Hour)
+	user.SubscriptionGrantedAt = &grantedAt
+	assert.True(t, Granted(user, now))
+	assert.Equal(t, SubscriptionTypePro, SubscriptionAccess(user, now))
+
+	until := now.Add(time.Hour)
+	user.SubscriptionGrantedUntil = &until
+	assert.True(t, Granted(user, now))
+	assert.False(t, Granted(user, until))
+	assert.Equal(t, SubscriptionTypeFree, SubscriptionAccess(user, until))
+}
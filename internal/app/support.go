@@ -0,0 +1,156 @@
+package app
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/cache"
+)
+
+const (
+	// DefaultSupportConsent is how long the consent of a user lasts when the user doesn't say
+	DefaultSupportConsent = time.Hour
+	// MaxSupportConsent is the longest consent a user can give
+	MaxSupportConsent = 24 * time.Hour
+)
+
+var (
+	// ErrNoSupportConsent is returned for impersonating a user without a valid consent token
+	ErrNoSupportConsent = errors.New("user hasn't given consent to support or it is expired")
+	// ErrSupportSession is returned for the requests of support sessions the impersonation doesn't allow
+	ErrSupportSession = errors.New("support sessions can't access the vault or change anything")
+)
+
+// GrantSupportConsent gives consent to the admins to impersonate the user for
+// the duration, it replaces the earlier consent of the user. The token of the
+// consent is only returned here, the user gives it to the admin.
+func GrantSupportConsent(c cache.Cache, user *model.User, duration time.Duration, now time.Time) (*model.SupportConsent, error) {
+	if duration <= 0 {
+		duration = DefaultSupportConsent
+	}
+	if duration > MaxSupportConsent {
+		duration = MaxSupportConsent
+	}
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	consent := &model.SupportConsent{
+		Token:     hex.EncodeToString(random),
+		ExpiresAt: now.Add(duration).Truncate(time.Second),
+	}
+	value := consent.Token + ":" + strconv.FormatInt(consent.ExpiresAt.Unix(), 10)
+	if err := c.Set(supportConsentKey(user.ID), value, duration); err != nil {
+		return nil, err
+	}
+	return consent, nil
+}
+
+// RevokeSupportConsent takes the consent of the user back, the support
+// sessions of it end right away
+func RevokeSupportConsent(c cache.Cache, userID uint) error {
+	return c.Delete(supportConsentKey(userID))
+}
+
+// FindSupportConsent returns the token and the expiry of the consent of the
+// user, found is false when the user hasn't given one or it is expired
+func FindSupportConsent(c cache.Cache, userID uint, now time.Time) (token string, expiresAt time.Time, found bool, err error) {
+	value, found, err := c.Get(supportConsentKey(userID))
+	if err != nil || !found {
+		return "", time.Time{}, false, err
+	}
+	token, expiresAt, ok := parseSupportConsent(value)
+	if !ok || !now.Before(expiresAt) {
+		return "", time.Time{}, false, nil
+	}
+	return token, expiresAt, true, nil
+}
+
+// Impersonate creates the token of the support session of the admin as the
+// user of the email. The consent token of the user is required and the
+// session expires with the consent.
+func Impersonate(s storage.Store, c cache.Cache, admin *model.User, email, consentToken string, now time.Time) (*model.User, *model.TokenDetailsDTO, error) {
+	user, err := s.Users().FindByEmail(email)
+	if err != nil {
+		return nil, nil, ErrNoSupportConsent
+	}
+	token, expiresAt, found, err := FindSupportConsent(c, user.ID, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !found || subtle.ConstantTimeCompare([]byte(token), []byte(consentToken)) != 1 {
+		return nil, nil, ErrNoSupportConsent
+	}
+
+	td, err := CreateSupportToken(user, admin, expiresAt, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.Tokens().Create(int(user.ID), td.AtUUID, td.AccessToken, td.AtExpiresTime)
+	return user, td, nil
+}
+
+// CreateSupportToken creates the access token of the admin impersonating the
+// user, its only scope is support and the impersonator_id claim has the admin.
+// Support sessions have no refresh token.
+func CreateSupportToken(user, admin *model.User, expiresAt, now time.Time) (*model.TokenDetailsDTO, error) {
+	td := &model.TokenDetailsDTO{
+		AtExpiresTime: expiresAt,
+		AtUUID:        NewUUID(),
+	}
+
+	claims := jwt.MapClaims{}
+	claims["authorized"] = false
+	claims["user_uuid"] = user.UUID.String()
+	claims["exp"] = expiresAt.Unix()
+	claims["uuid"] = td.AtUUID.String()
+	claims["scopes"] = []string{ScopeSupport}
+	claims["auth_time"] = now.Unix()
+	claims["epoch"] = user.TokenEpoch
+	claims["impersonator_id"] = admin.ID
+
+	var err error
+	td.AccessToken, err = signToken(claims)
+	if err != nil {
+		return nil, err
+	}
+	return td, nil
+}
+
+// ImpersonatorFromClaims returns the admin of the support session of the
+// token claims, ok is false for the sessions of the users themselves
+func ImpersonatorFromClaims(claims jwt.MapClaims) (adminID uint, ok bool) {
+	id, ok := claims["impersonator_id"].(float64)
+	if !ok || id <= 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// parseSupportConsent parses the token and the expiry of the consent saved in the cache
+func parseSupportConsent(value string) (token string, expiresAt time.Time, ok bool) {
+	token, expiry, ok := strings.Cut(value, ":")
+	if !ok || token == "" {
+		return "", time.Time{}, false
+	}
+	seconds, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return token, time.Unix(seconds, 0), true
+}
+
+func supportConsentKey(userID uint) string {
+	return fmt.Sprintf("support-consent:%d", userID)
+}
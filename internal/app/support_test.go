@@ -0,0 +1,74 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	uuid "github.com/satori/go.uuid"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/cache"
+)
+
+func TestSupportConsent(t *testing.T) {
+	c := cache.NewMemory()
+	user := &model.User{ID: 7}
+	now := time.Now()
+
+	consent, err := GrantSupportConsent(c, user, 0, now)
+	require.NoError(t, err)
+	assert.Len(t, consent.Token, 32)
+	assert.WithinDuration(t, now.Add(DefaultSupportConsent), consent.ExpiresAt, time.Second)
+
+	token, expiresAt, found, err := FindSupportConsent(c, user.ID, now)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, consent.Token, token)
+	assert.Equal(t, consent.ExpiresAt.Unix(), expiresAt.Unix())
+
+	// Consent lasts at most a day
+	consent, err = GrantSupportConsent(c, user, 48*time.Hour, now)
+	require.NoError(t, err)
+	assert.WithinDuration(t, now.Add(MaxSupportConsent), consent.ExpiresAt, time.Second)
+
+	_, _, found, err = FindSupportConsent(c, user.ID, consent.ExpiresAt)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, RevokeSupportConsent(c, user.ID))
+	_, _, found, err = FindSupportConsent(c, user.ID, now)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestCreateSupportToken(t *testing.T) {
+	viper.Set("server.secret", "support-test-secret")
+	user := &model.User{ID: 7, UUID: uuid.NewV4()}
+	admin := &model.User{ID: 1, Role: "Admin"}
+	now := time.Now()
+
+	td, err := CreateSupportToken(user, admin, now.Add(time.Hour), now)
+	require.NoError(t, err)
+	assert.Empty(t, td.RefreshToken)
+
+	token, err := TokenValid(td.AccessToken)
+	require.NoError(t, err)
+	claims := token.Claims.(jwt.MapClaims)
+
+	adminID, ok := ImpersonatorFromClaims(claims)
+	assert.True(t, ok)
+	assert.Equal(t, admin.ID, adminID)
+	assert.Equal(t, false, claims["authorized"])
+	assert.Equal(t, user.UUID.String(), claims["user_uuid"])
+
+	scopes, _ := ScopesFromClaims(claims)
+	assert.Equal(t, []string{ScopeSupport}, scopes)
+
+	// Sessions of the users themselves have no impersonator
+	_, ok = ImpersonatorFromClaims(jwt.MapClaims{"user_uuid": user.UUID.String()})
+	assert.False(t, ok)
+}
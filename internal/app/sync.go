@@ -0,0 +1,136 @@
+package app
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// ErrInvalidSyncSince represents message for sync points which are neither revisions nor timestamps
+var ErrInvalidSyncSince = errors.New("since must be a revision or an RFC 3339 timestamp")
+
+// ParseSyncSince parses the sync point of the client. Revisions are the unix
+// milliseconds returned by the previous sync, timestamps are in RFC 3339 format.
+func ParseSyncSince(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if revision, err := strconv.ParseInt(value, 10, 64); err == nil && revision >= 0 {
+		return RevisionTime(revision), nil
+	}
+	since, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, ErrInvalidSyncSince
+	}
+	return since, nil
+}
+
+// SyncRevision converts the time to the revision returned to sync clients
+func SyncRevision(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// RevisionTime converts the revision of a sync client back to time
+func RevisionTime(revision int64) time.Time {
+	return time.Unix(0, revision*int64(time.Millisecond))
+}
+
+// Sync finds the decrypted items and folders created or updated and the items
+// deleted since the given time. Items changed at the since time are sent again,
+// so changes made during a sync aren't missed.
+func Sync(s storage.Store, since time.Time, schema string) (*model.SyncResponse, error) {
+	// Revision is taken before reading, changes made meanwhile come in the next
+	// sync. It is moved back by the lag of the replica the syncs read from, so
+	// the changes not replicated yet come in the next sync too.
+	response := &model.SyncResponse{Revision: SyncRevision(time.Now().Add(-s.ReadLag()))}
+
+	var err error
+	if response.Folders, err = s.Folders().FindUpdatedSince(since, schema); err != nil {
+		return nil, err
+	}
+	if err := findVaultItems(s, &since, schema, &response.VaultItems); err != nil {
+		return nil, err
+	}
+	if response.Deleted, err = s.Tombstones().FindSince(since, schema); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// FullSync returns the profile of the user with all the folders and decrypted
+// items of the vault
+func FullSync(s storage.Store, user *model.User, schema string) (*model.FullSyncResponse, error) {
+	response := &model.FullSyncResponse{Revision: SyncRevision(time.Now().Add(-s.ReadLag())), User: model.ToUserDTO(user)}
+
+	var err error
+	if response.Folders, err = s.Folders().All(schema); err != nil {
+		return nil, err
+	}
+	if err := findVaultItems(s, nil, schema, &response.VaultItems); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// findVaultItems finds the decrypted items updated since the given time, or
+// all the items without it
+func findVaultItems(s storage.Store, since *time.Time, schema string, items *model.VaultItems) error {
+	opts := &model.ListOptions{Sort: "id", Direction: "asc", UpdatedSince: since}
+
+	var err error
+	if items.Logins, _, err = s.Logins().FindAll(opts, schema); err != nil {
+		return err
+	}
+	for i := range items.Logins {
+		if _, err := DecryptModel(&items.Logins[i], schema); err != nil {
+			return err
+		}
+	}
+	if items.CreditCards, _, err = s.CreditCards().FindAll(opts, schema); err != nil {
+		return err
+	}
+	for i := range items.CreditCards {
+		if _, err := DecryptModel(&items.CreditCards[i], schema); err != nil {
+			return err
+		}
+	}
+	if items.BankAccounts, _, err = s.BankAccounts().FindAll(opts, schema); err != nil {
+		return err
+	}
+	for i := range items.BankAccounts {
+		if _, err := DecryptModel(&items.BankAccounts[i], schema); err != nil {
+			return err
+		}
+	}
+	if items.Notes, _, err = s.Notes().FindAll(opts, schema); err != nil {
+		return err
+	}
+	for i := range items.Notes {
+		if _, err := DecryptModel(&items.Notes[i], schema); err != nil {
+			return err
+		}
+	}
+	if items.Emails, _, err = s.Emails().FindAll(opts, schema); err != nil {
+		return err
+	}
+	for i := range items.Emails {
+		if _, err := DecryptModel(&items.Emails[i], schema); err != nil {
+			return err
+		}
+	}
+	if items.Servers, _, err = s.Servers().FindAll(opts, schema); err != nil {
+		return err
+	}
+	for i := range items.Servers {
+		if _, err := DecryptModel(&items.Servers[i], schema); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSyncSince(t *testing.T) {
+	revisionTime := time.Date(2021, 6, 1, 12, 30, 0, 250*int(time.Millisecond), time.UTC)
+
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Time
+		wantErr  bool
+	}{
+		{name: "Empty", value: "", expected: time.Time{}},
+		{name: "Revision", value: "1622550600250", expected: revisionTime},
+		{name: "Timestamp", value: "2021-06-01T12:30:00Z", expected: time.Date(2021, 6, 1, 12, 30, 0, 0, time.UTC)},
+		{name: "Negative revision", value: "-5", wantErr: true},
+		{name: "Invalid", value: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			since, err := ParseSyncSince(tt.value)
+			if tt.wantErr {
+				assert.Equal(t, ErrInvalidSyncSince, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, tt.expected.Equal(since), "expected %v got %v", tt.expected, since)
+		})
+	}
+}
+
+func TestSyncRevisionRoundTrip(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 30, 0, 123456789, time.UTC)
+
+	since, err := ParseSyncSince(strconv.FormatInt(SyncRevision(now), 10))
+
+	assert.NoError(t, err)
+	assert.True(t, since.Equal(now.Truncate(time.Millisecond)))
+}
+
+func TestFullSyncResponseJSON(t *testing.T) {
+	response := model.FullSyncResponse{
+		Revision:   1,
+		User:       &model.UserDTO{Email: mockEmail},
+		VaultItems: model.VaultItems{Logins: []model.Login{{Title: "Example"}}},
+	}
+
+	data, err := json.Marshal(response)
+	assert.NoError(t, err)
+
+	var fields map[string]json.RawMessage
+	assert.NoError(t, json.Unmarshal(data, &fields))
+	for _, key := range []string{"revision", "user", "folders", "logins", "credit_cards", "servers"} {
+		assert.Contains(t, fields, key)
+	}
+	assert.NotContains(t, fields, "deleted")
+}
@@ -0,0 +1,41 @@
+package app
+
+import (
+	"github.com/passwall/passwall-server/internal/storage"
+)
+
+// CopyVaultsToRows copies the vaults of the users and the organizations from
+// their schemas to the shared tables of row tenancy of their store, and
+// returns the number of the vaults copied. The server keeps serving the
+// schemas meanwhile, so it is run until nothing changes, the last time in
+// maintenance mode, before the server is restarted in row tenancy.
+func CopyVaultsToRows(s storage.Store) (int, error) {
+	targets, err := vaultTargets(s)
+	if err != nil {
+		return 0, err
+	}
+
+	// Every store is copied, so vaults deleted since the last copy are removed
+	stores := []storage.Store{s}
+	for _, name := range s.RegionNames() {
+		region, err := s.Region(name)
+		if err != nil {
+			return 0, err
+		}
+		stores = append(stores, region)
+	}
+	schemas := map[storage.Store][]string{}
+	for _, target := range targets {
+		schemas[target.store] = append(schemas[target.store], target.schema)
+	}
+
+	copied := 0
+	for _, store := range stores {
+		n, err := store.CopyToRows(schemas[store])
+		copied += n
+		if err != nil {
+			return copied, err
+		}
+	}
+	return copied, nil
+}
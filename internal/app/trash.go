@@ -0,0 +1,190 @@
+package app
+
+import (
+	"errors"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/internal/storage/trash"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+	"github.com/spf13/viper"
+)
+
+var (
+	// ErrDeletedRecordNotFound represents message for restoring records which aren't deleted
+	ErrDeletedRecordNotFound = errors.New("deleted record not found")
+	// ErrVaultRequired represents message for the vault tables looked up without a vault
+	ErrVaultRequired = errors.New("email or organization of the vault is required")
+	// ErrVaultNotFound represents message for the vaults of unknown users and organizations
+	ErrVaultNotFound = errors.New("vault not found")
+	// ErrEmailTaken represents message for restoring users whose email is used again
+	ErrEmailTaken = errors.New("email is used by another user")
+)
+
+// TrashVault is the vault the deleted records of the vault tables are looked up
+// in, the one of the user with the email or the one of the organization
+type TrashVault struct {
+	Email          string
+	OrganizationID uint
+}
+
+// RetentionDays returns the days the deleted records of the table are kept
+// before they are purged, purge.tables overrides purge.retentionDays per table
+func RetentionDays(table string) int {
+	key := "purge.tables." + table
+	if viper.IsSet(key) {
+		return viper.GetInt(key)
+	}
+	return viper.GetInt("purge.retentionDays")
+}
+
+// FindDeletedRecords returns the deleted records of the table which aren't purged yet
+func FindDeletedRecords(s storage.Store, table string, vault TrashVault) ([]model.DeletedRecord, error) {
+	store, schema, err := trashSchema(s, table, vault)
+	if err != nil {
+		return nil, err
+	}
+	return store.Trash().FindDeleted(table, schema, time.Now())
+}
+
+// RestoreDeletedRecord brings the deleted record of the table back. Restored
+// users and organizations get the migrations of their vault applied since they
+// were deleted.
+func RestoreDeletedRecord(s storage.Store, table string, id uint, vault TrashVault) error {
+	store, schema, err := trashSchema(s, table, vault)
+	if err != nil {
+		return err
+	}
+
+	var deleted *model.DeletedRecord
+	if table == "users" || table == "organizations" {
+		records, err := store.Trash().FindDeleted(table, schema, time.Now())
+		if err != nil {
+			return err
+		}
+		for i := range records {
+			if records[i].ID == id {
+				deleted = &records[i]
+			}
+		}
+		if deleted == nil {
+			return ErrDeletedRecordNotFound
+		}
+	}
+	if table == "users" {
+		if _, err := s.Users().FindByEmail(deleted.Name); err == nil {
+			return ErrEmailTaken
+		}
+	}
+
+	err = store.Trash().Restore(table, schema, id)
+	if err == trash.ErrNotDeleted {
+		return ErrDeletedRecordNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	switch table {
+	case "users":
+		return MigrateUserTables(s, deleted.Schema)
+	case "organizations":
+		region, err := s.Region(deleted.Region)
+		if err != nil {
+			return err
+		}
+		return MigrateUserTables(region, deleted.Schema)
+	}
+	return nil
+}
+
+// PurgeDeletedRecords removes the records deleted before their retention for
+// good. The vaults of the purged users and organizations are dropped with them.
+func PurgeDeletedRecords(s storage.Store, now time.Time) {
+	var targets []migrationTarget
+	for _, table := range trash.Tables() {
+		before := now.AddDate(0, 0, -RetentionDays(table))
+		if !trash.IsVault(table) {
+			purgeDeleted(s, table, "", before)
+			continue
+		}
+
+		if targets == nil {
+			var err error
+			if targets, err = vaultTargets(s); err != nil {
+				logger.Errorf("Error while purging deleted %s: %v", table, err)
+				continue
+			}
+		}
+		for _, target := range targets {
+			purgeDeleted(target.store, table, target.schema, before)
+		}
+	}
+}
+
+// purgeDeleted purges the records of the table of the store deleted before the time
+func purgeDeleted(s storage.Store, table, schema string, before time.Time) {
+	records, err := s.Trash().FindDeleted(table, schema, before)
+	if err != nil {
+		return
+	}
+
+	ids := []uint{}
+	for _, record := range records {
+		switch table {
+		case "users":
+			if err = s.Users().DropSchema(record.Schema); err == nil {
+				err = ForgetDataKey(s, record.Schema)
+			}
+		case "organizations":
+			var region storage.Store
+			if region, err = s.Region(record.Region); err == nil {
+				if err = region.Users().DropSchema(record.Schema); err == nil {
+					err = ForgetDataKey(region, record.Schema)
+				}
+			}
+		}
+		if err != nil {
+			logger.Errorf("Error while purging %s %d: %v", table, record.ID, err)
+			continue
+		}
+		ids = append(ids, record.ID)
+	}
+
+	if err := s.Trash().Purge(table, schema, ids); err != nil {
+		logger.Errorf("Error while purging deleted %s: %v", table, err)
+		return
+	}
+	if len(ids) > 0 {
+		logger.Infof("%d deleted %s purged after retention", len(ids), table)
+	}
+}
+
+// trashSchema returns the store and the schema of the deleted records of the
+// table, the vault tables need the vault
+func trashSchema(s storage.Store, table string, vault TrashVault) (storage.Store, string, error) {
+	if !trash.IsVault(table) {
+		return s, "", nil
+	}
+
+	if vault.Email != "" {
+		user, err := s.Users().FindByEmail(vault.Email)
+		if err != nil {
+			return nil, "", ErrVaultNotFound
+		}
+		return s, user.Schema, nil
+	}
+	if vault.OrganizationID != 0 {
+		org, err := s.Organizations().FindByID(vault.OrganizationID)
+		if err != nil {
+			return nil, "", ErrVaultNotFound
+		}
+		region, err := s.Region(org.Region)
+		if err != nil {
+			return nil, "", err
+		}
+		return region, org.Schema, nil
+	}
+	return nil, "", ErrVaultRequired
+}
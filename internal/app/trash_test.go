@@ -0,0 +1,18 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetentionDays(t *testing.T) {
+	viper.Set("purge.retentionDays", 30)
+	viper.Set("purge.tables", map[string]interface{}{"logins": 90, "announcements": 0})
+	defer viper.Set("purge.tables", nil)
+
+	assert.Equal(t, 90, RetentionDays("logins"))
+	assert.Equal(t, 0, RetentionDays("announcements"))
+	assert.Equal(t, 30, RetentionDays("notes"))
+}
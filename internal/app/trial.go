@@ -0,0 +1,86 @@
+package app
+
+import (
+	"math"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/config"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+// StartTrial gives the new user the pro features for billing.trialDays, it
+// does nothing when trials are disabled or the user already had one
+func StartTrial(s storage.Store, user *model.User) (*model.User, error) {
+	days := config.Current().Billing.TrialDays
+	if days <= 0 || user.TrialStartedAt != nil {
+		return user, nil
+	}
+
+	now := time.Now()
+	endsAt := now.AddDate(0, 0, days)
+	user.TrialStartedAt = &now
+	user.TrialEndsAt = &endsAt
+	user.SubscriptionType = SubscriptionTypeTrial
+	return s.Users().Update(user)
+}
+
+// InTrial checks if the trial of the user hasn't ended yet
+func InTrial(user *model.User, now time.Time) bool {
+	return user.SubscriptionType == SubscriptionTypeTrial && user.TrialEndsAt != nil && now.Before(*user.TrialEndsAt)
+}
+
+// FindTrialStatus returns the trial of the user, nil when the user isn't in trial
+func FindTrialStatus(user *model.User, now time.Time) *model.TrialStatus {
+	if !InTrial(user, now) {
+		return nil
+	}
+	return &model.TrialStatus{
+		StartedAt: user.TrialStartedAt,
+		EndsAt:    *user.TrialEndsAt,
+		DaysLeft:  trialDaysLeft(user, now),
+	}
+}
+
+// trialDaysLeft counts the started days left in the trial of the user
+func trialDaysLeft(user *model.User, now time.Time) int {
+	return int(math.Ceil(user.TrialEndsAt.Sub(now).Hours() / 24))
+}
+
+// ExpireTrials downgrades the users whose trial ended to the free features
+// and reminds the ones whose trial ends in billing.trialReminderDays
+func ExpireTrials(s storage.Store) {
+	users, err := s.Users().All()
+	if err != nil {
+		logger.Errorf("Error while finding users for trial expiry: %v", err)
+		return
+	}
+
+	reminder := time.Duration(config.Current().Billing.TrialReminderDays) * 24 * time.Hour
+	now := time.Now()
+	for i := range users {
+		user := &users[i]
+		if user.SubscriptionType != SubscriptionTypeTrial || user.TrialEndsAt == nil {
+			continue
+		}
+
+		if !InTrial(user, now) {
+			UpdateSubscription(s, user, SubscriptionTypeFree)
+			continue
+		}
+		if user.TrialReminderSentAt != nil || reminder <= 0 || user.TrialEndsAt.Sub(now) > reminder {
+			continue
+		}
+
+		data := map[string]interface{}{"Date": user.TrialEndsAt.Format("2006-01-02"), "Days": trialDaysLeft(user, now)}
+		if err := SendUserMail(s, user, EmailTrialExpiring, data); err != nil {
+			logger.Errorf("can't send email to %s error: %v\n", user.Email, err)
+			continue
+		}
+		user.TrialReminderSentAt = &now
+		if _, err := s.Users().Update(user); err != nil {
+			logger.Errorf("Error while saving trial reminder of user %d: %v", user.ID, err)
+		}
+	}
+}
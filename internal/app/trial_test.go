@@ -0,0 +1,39 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/passwall/passwall-server/model"
+)
+
+func TestTrialStatus(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	startedAt := now.AddDate(0, 0, -10)
+	endsAt := now.Add(52 * time.Hour)
+	user := &model.User{SubscriptionType: SubscriptionTypeTrial, TrialStartedAt: &startedAt, TrialEndsAt: &endsAt}
+
+	assert.True(t, InTrial(user, now))
+	assert.Equal(t, SubscriptionTypePro, SubscriptionAccess(user, now))
+	status := FindTrialStatus(user, now)
+	if assert.NotNil(t, status) {
+		assert.Equal(t, endsAt, status.EndsAt)
+		assert.Equal(t, 3, status.DaysLeft)
+	}
+
+	// The trial ended but the users weren't downgraded yet
+	later := endsAt.Add(time.Minute)
+	assert.False(t, InTrial(user, later))
+	assert.Equal(t, SubscriptionTypeFree, SubscriptionAccess(user, later))
+	assert.Nil(t, FindTrialStatus(user, later))
+
+	// Subscribing ends the trial
+	user.SubscriptionType = SubscriptionTypePro
+	assert.False(t, InTrial(user, now))
+	assert.Equal(t, SubscriptionTypePro, SubscriptionAccess(user, now))
+	assert.Nil(t, FindTrialStatus(user, now))
+
+	assert.Equal(t, SubscriptionTypeFree, SubscriptionAccess(&model.User{}, now))
+}
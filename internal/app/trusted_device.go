@@ -0,0 +1,85 @@
+package app
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+// trustedDeviceTokenLen is the number of random bytes of the device tokens
+const trustedDeviceTokenLen = 32
+
+// TrustDevice remembers the device of the identifier for the user and returns
+// it with its token, which skips the two-factor code at the next signins of the
+// device. A device trusted again gets a new token and the older one stops working.
+func TrustDevice(s storage.Store, user *model.User, deviceID, name string, now time.Time) (*model.TrustedDevice, string, error) {
+	secret := make([]byte, trustedDeviceTokenLen)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	deviceHash := trustedDeviceHash(deviceID)
+	if err := s.TrustedDevices().DeleteStale(user.ID, deviceHash, now); err != nil {
+		return nil, "", err
+	}
+	device, err := s.TrustedDevices().Create(&model.TrustedDevice{
+		UUID:       NewUUID(),
+		UserID:     user.ID,
+		DeviceHash: deviceHash,
+		TokenHash:  trustedDeviceHash(token),
+		Name:       name,
+		ExpiresAt:  now.Add(trustedDeviceDuration()),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return device, token, nil
+}
+
+// TrustedDeviceValid checks if the token is a device token of the user which
+// isn't expired or revoked and is sent by the device it was issued to. It only
+// stands for the two-factor code, the master password is checked before.
+func TrustedDeviceValid(s storage.Store, user *model.User, deviceID, token string, now time.Time) bool {
+	if deviceID == "" || token == "" {
+		return false
+	}
+	device, err := s.TrustedDevices().FindByTokenHash(trustedDeviceHash(token), now)
+	if err != nil || device.UserID != user.ID {
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(device.DeviceHash), []byte(trustedDeviceHash(deviceID))) != 1 {
+		return false
+	}
+
+	device.LastUsedAt = &now
+	if err := s.TrustedDevices().Touch(device); err != nil {
+		logger.Errorf("Error while saving last use of trusted device %s: %v", device.UUID, err)
+	}
+	return true
+}
+
+// trustedDeviceHash returns the hex SHA-256 the device identifiers and tokens
+// are stored as
+func trustedDeviceHash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// trustedDeviceDuration returns how long the devices stay trusted, 30 days
+// when it isn't configured
+func trustedDeviceDuration() time.Duration {
+	if value := viper.GetString("tokens.trustedDeviceExpireDuration"); value != "" {
+		return resolveTokenExpireDuration(value)
+	}
+	return 30 * 24 * time.Hour
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/internal/storage/migration"
+	"github.com/passwall/passwall-server/internal/storage/sqlite"
+	"github.com/passwall/passwall-server/model"
+)
+
+func TestTrustedDevice(t *testing.T) {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "passwall.db"), &gorm.Config{})
+	require.NoError(t, err)
+	s := storage.New(db)
+	require.NoError(t, s.Migrations().Up(migration.SetSystem, ""))
+
+	user, err := s.Users().Create(&model.User{UUID: uuid.NewV4(), Email: "device@passwall.io", Role: "Member"})
+	require.NoError(t, err)
+	other, err := s.Users().Create(&model.User{UUID: uuid.NewV4(), Email: "other@passwall.io", Role: "Member"})
+	require.NoError(t, err)
+
+	now := time.Now()
+	device, token, err := TrustDevice(s, user, "laptop-1", "Firefox", now)
+	require.NoError(t, err)
+	assert.NotEqual(t, token, device.TokenHash)
+
+	// The token only works for the user and the device it is issued to
+	assert.True(t, TrustedDeviceValid(s, user, "laptop-1", token, now))
+	assert.False(t, TrustedDeviceValid(s, user, "laptop-2", token, now))
+	assert.False(t, TrustedDeviceValid(s, other, "laptop-1", token, now))
+	assert.False(t, TrustedDeviceValid(s, user, "laptop-1", "", now))
+	assert.False(t, TrustedDeviceValid(s, user, "laptop-1", token, now.Add(31*24*time.Hour)))
+
+	devices, err := s.TrustedDevices().FindByUserID(user.ID, now)
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+	assert.NotNil(t, devices[0].LastUsedAt)
+
+	// Trusting the device again replaces its token
+	_, renewed, err := TrustDevice(s, user, "laptop-1", "Firefox", now)
+	require.NoError(t, err)
+	assert.False(t, TrustedDeviceValid(s, user, "laptop-1", token, now))
+	assert.True(t, TrustedDeviceValid(s, user, "laptop-1", renewed, now))
+
+	devices, err = s.TrustedDevices().FindByUserID(user.ID, now)
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+	_, err = s.TrustedDevices().Delete(other.ID, devices[0].UUID.String())
+	assert.Error(t, err)
+	_, err = s.TrustedDevices().Delete(user.ID, devices[0].UUID.String())
+	require.NoError(t, err)
+	assert.False(t, TrustedDeviceValid(s, user, "laptop-1", renewed, now))
+}
@@ -4,13 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/spf13/viper"
 
+	"github.com/passwall/passwall-server/internal/i18n"
 	"github.com/passwall/passwall-server/internal/storage"
 	"github.com/passwall/passwall-server/model"
 	"github.com/passwall/passwall-server/pkg/logger"
-	uuid "github.com/satori/go.uuid"
 )
 
 var (
@@ -18,6 +19,8 @@ var (
 	ErrGenerateSchema = errors.New("an error occured while genarating schema")
 	// ErrCreateSchema represents message for creating schema
 	ErrCreateSchema = errors.New("an error occured while creating the schema and tables")
+	// ErrUnsupportedLocale represents message for locales the messages aren't translated to
+	ErrUnsupportedLocale = errors.New("locale is not supported")
 )
 
 // CreateUser creates a user and saves it to the store
@@ -31,9 +34,6 @@ func CreateUser(s storage.Store, userDTO *model.UserDTO) (*model.User, error) {
 		return nil, err
 	}
 
-	// Hashing the master password with Bcrypt
-	userDTO.MasterPassword = NewBcrypt([]byte(userDTO.MasterPassword))
-
 	passwordLength, err := strconv.Atoi(viper.GetString("server.generatedPasswordLength"))
 	if err != nil {
 		logger.Errorf("Error while converting passwordLength: %v", err)
@@ -49,38 +49,59 @@ func CreateUser(s storage.Store, userDTO *model.UserDTO) (*model.User, error) {
 	// New user's role is Member (not Admin)
 	userDTO.Role = "Member"
 
-	// Generate new UUID for user
-	userDTO.UUID = uuid.NewV4()
-
-	userDTO.IsMigrated = true
-
-	createdUser, err := s.Users().Create(model.ToUser(userDTO))
-	if err != nil {
-		logger.Errorf("Error while creating user: %v", err)
-		return nil, err
+	// Unknown locales fall back to the Accept-Language of the requests
+	if !i18n.Supported(userDTO.Locale) {
+		userDTO.Locale = ""
 	}
 
-	confirmationCode := RandomMD5Hash()
-	createdUser.ConfirmationCode = confirmationCode
+	// Generate new time ordered UUID for user
+	userDTO.UUID = NewUUID()
 
-	// Generate schema name and update user
-	updatedUser, err := GenerateSchema(s, createdUser)
-	if err != nil {
-		logger.Errorf("Error while generating schema: %v", err)
-		return nil, ErrGenerateSchema
-	}
+	userDTO.IsMigrated = true
 
-	// Create user schema and tables
-	err = s.Users().CreateSchema(updatedUser.Schema)
+	// Hashing the master password with the cost of the security policy
+	newUser := model.ToUser(userDTO)
+	err = setMasterPassword(newUser, userDTO.MasterPassword, policyKDFCost(s))
 	if err != nil {
-		logger.Errorf("Error while creating schema: %v", err)
-		return nil, ErrCreateSchema
+		logger.Errorf("Error while hashing master password: %v", err)
+		return nil, err
 	}
 
-	// Create user tables in user schema
-	err = MigrateUserTables(s, updatedUser.Schema)
+	// Users without their vault aren't left behind when a step fails
+	var createdUser *model.User
+	err = s.WithTransaction(func(tx storage.Store) error {
+		createdUser, err = tx.Users().Create(newUser)
+		if err != nil {
+			logger.Errorf("Error while creating user: %v", err)
+			return err
+		}
+
+		confirmationCode := RandomMD5Hash()
+		createdUser.ConfirmationCode = confirmationCode
+
+		// Generate schema name and update user
+		updatedUser, err := GenerateSchema(tx, createdUser)
+		if err != nil {
+			logger.Errorf("Error while generating schema: %v", err)
+			return ErrGenerateSchema
+		}
+
+		// Create user schema and tables
+		err = tx.Users().CreateSchema(updatedUser.Schema)
+		if err != nil {
+			logger.Errorf("Error while creating schema: %v", err)
+			return ErrCreateSchema
+		}
+
+		// Create user tables in user schema
+		err = MigrateUserTables(tx, updatedUser.Schema)
+		if err != nil {
+			logger.Errorf("Error while migrating user tables: %v", err)
+			return err
+		}
+		return nil
+	})
 	if err != nil {
-		logger.Errorf("Error while migrating user tables: %v", err)
 		return nil, err
 	}
 
@@ -90,16 +111,19 @@ func CreateUser(s storage.Store, userDTO *model.UserDTO) (*model.User, error) {
 // UpdateUser updates the user with the dto and applies the changes in the store
 func UpdateUser(s storage.Store, user *model.User, userDTO *model.UserDTO, isAuthorized bool) (*model.User, error) {
 
+	if userDTO.Locale != "" && !i18n.Supported(userDTO.Locale) {
+		return nil, ErrUnsupportedLocale
+	}
+
 	// TODO: Refactor the contents of updated user with a logical way
-	if userDTO.MasterPassword != "" && NewBcrypt([]byte(userDTO.MasterPassword)) != user.MasterPassword {
-		userDTO.MasterPassword = NewBcrypt([]byte(userDTO.MasterPassword))
-	} else {
-		userDTO.MasterPassword = user.MasterPassword
+	if userDTO.MasterPassword != "" {
+		if err := setMasterPassword(user, userDTO.MasterPassword, policyKDFCost(s)); err != nil {
+			return nil, err
+		}
 	}
 
 	user.Name = userDTO.Name
 	user.Email = userDTO.Email
-	user.MasterPassword = userDTO.MasterPassword
 	user.EmailVerifiedAt = userDTO.EmailVerifiedAt
 	// This never changes
 	user.Schema = fmt.Sprintf("user%d", user.ID)
@@ -110,6 +134,9 @@ func UpdateUser(s storage.Store, user *model.User, userDTO *model.UserDTO, isAut
 	}
 
 	user.IsMigrated = userDTO.IsMigrated
+	user.BreachScan = userDTO.BreachScan
+	user.SecurityDigest = userDTO.SecurityDigest
+	user.Locale = userDTO.Locale
 
 	updatedUser, err := s.Users().Update(user)
 	if err != nil {
@@ -120,7 +147,10 @@ func UpdateUser(s storage.Store, user *model.User, userDTO *model.UserDTO, isAut
 
 // ChangeMasterPassword updates the user with the new master password
 func ChangeMasterPassword(s storage.Store, user *model.User, newMasterPassword string) (*model.User, error) {
-	user.MasterPassword = NewBcrypt([]byte(newMasterPassword))
+	if err := setMasterPassword(user, newMasterPassword, policyKDFCost(s)); err != nil {
+		return nil, err
+	}
+	user.MasterPasswordReset = false
 	updatedUser, err := s.Users().Update(user)
 	if err != nil {
 		return nil, err
@@ -128,6 +158,17 @@ func ChangeMasterPassword(s storage.Store, user *model.User, newMasterPassword s
 	return updatedUser, nil
 }
 
+// SetUserPhone saves the phone number the user verified, an empty phone removes it
+func SetUserPhone(s storage.Store, user *model.User, phone string) (*model.User, error) {
+	user.Phone = phone
+	user.PhoneVerifiedAt = nil
+	if phone != "" {
+		now := time.Now()
+		user.PhoneVerifiedAt = &now
+	}
+	return s.Users().Update(user)
+}
+
 // GenerateSchema creates user schema and tables
 func GenerateSchema(s storage.Store, user *model.User) (*model.User, error) {
 	user.Schema = fmt.Sprintf("user%d", user.ID)
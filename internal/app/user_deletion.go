@@ -0,0 +1,103 @@
+package app
+
+import (
+	"errors"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+var (
+	// ErrDeletionNotScheduled represents message for cancelling a deletion which is not scheduled
+	ErrDeletionNotScheduled = errors.New("user deletion is not scheduled")
+)
+
+// ScheduleUserDeletion deactivates the user and schedules the purge after the grace period
+func ScheduleUserDeletion(s storage.Store, user *model.User) error {
+	scheduledAt := time.Now().AddDate(0, 0, viper.GetInt("server.deletionGracePeriod"))
+	user.DeletionScheduledAt = &scheduledAt
+	user.DeletionReminderSentAt = nil
+
+	if _, err := s.Users().Update(user); err != nil {
+		logger.Errorf("Error while scheduling deletion of user %d: %v", user.ID, err)
+		return err
+	}
+
+	// Deactivated users can't use their existing tokens
+	s.Tokens().Delete(int(user.ID))
+
+	data := map[string]interface{}{"Date": scheduledAt.Format("2006-01-02")}
+	if err := SendUserMail(s, user, EmailDeletionScheduled, data); err != nil {
+		logger.Errorf("can't send email to %s error: %v\n", user.Email, err)
+	}
+
+	return nil
+}
+
+// CancelUserDeletion reactivates a user whose deletion is scheduled
+func CancelUserDeletion(s storage.Store, user *model.User) error {
+	if user.DeletionScheduledAt == nil {
+		return ErrDeletionNotScheduled
+	}
+
+	user.DeletionScheduledAt = nil
+	user.DeletionReminderSentAt = nil
+
+	if _, err := s.Users().Update(user); err != nil {
+		logger.Errorf("Error while cancelling deletion of user %d: %v", user.ID, err)
+		return err
+	}
+
+	return nil
+}
+
+// PurgeDeletedUsers sends reminders to the users whose deletion is near
+// and deletes the users whose grace period is over
+func PurgeDeletedUsers(s storage.Store) {
+	now := time.Now()
+	reminderUntil := now.AddDate(0, 0, viper.GetInt("server.deletionReminderDays"))
+
+	users, err := s.Users().FindScheduledForDeletion(reminderUntil)
+	if err != nil {
+		return
+	}
+
+	for i := range users {
+		user := &users[i]
+
+		if !user.DeletionScheduledAt.After(now) {
+			if err := s.Users().Delete(user.ID, user.Schema); err != nil {
+				logger.Errorf("Error while purging user %d: %v", user.ID, err)
+				continue
+			}
+			s.Tokens().Delete(int(user.ID))
+			s.Breaches().DeleteByUserID(user.ID)
+			s.Webhooks().DeleteByUserID(user.ID)
+			s.Alerts().DeleteByUserID(user.ID)
+			s.TrustedDevices().DeleteByUserID(user.ID)
+			RecordAuditEvent(s, &model.AuditEvent{UserID: user.ID, Action: AuditActionPurge, ItemType: "users", ItemID: user.ID})
+			logger.Infof("user %d purged after deletion grace period", user.ID)
+			continue
+		}
+
+		if user.DeletionReminderSentAt != nil {
+			continue
+		}
+
+		data := map[string]interface{}{"Date": user.DeletionScheduledAt.Format("2006-01-02")}
+		if err := SendUserMail(s, user, EmailDeletionReminder, data); err != nil {
+			logger.Errorf("can't send email to %s error: %v\n", user.Email, err)
+			continue
+		}
+
+		sentAt := time.Now()
+		user.DeletionReminderSentAt = &sentAt
+		if _, err := s.Users().Update(user); err != nil {
+			logger.Errorf("Error while updating deletion reminder of user %d: %v", user.ID, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// UUIDGenerator generates the UUIDs of the new users and tokens
+type UUIDGenerator interface {
+	// NewUUID returns a new UUID
+	NewUUID() uuid.UUID
+}
+
+// uuidGenerator generates the UUIDs, time ordered UUIDv7 by default
+var uuidGenerator UUIDGenerator = &UUIDv7Generator{}
+
+// SetUUIDGenerator sets the generator of the UUIDs, nil sets the UUIDv7 one back
+func SetUUIDGenerator(generator UUIDGenerator) {
+	if generator == nil {
+		generator = &UUIDv7Generator{}
+	}
+	uuidGenerator = generator
+}
+
+// NewUUID returns a new UUID of the generator. The records created before
+// UUIDv7 keep their random UUIDv4, both are looked up the same way.
+func NewUUID() uuid.UUID {
+	return uuidGenerator.NewUUID()
+}
+
+// UUIDv7Generator generates the UUIDv7 of RFC 9562, they start with the Unix
+// time in milliseconds so the new ones are near each other in the indexes. A
+// 12 bit counter keeps the UUIDs of the same millisecond in order.
+type UUIDv7Generator struct {
+	mu     sync.Mutex
+	millis int64
+	seq    uint16
+}
+
+// NewUUID ...
+func (g *UUIDv7Generator) NewUUID() uuid.UUID {
+	var u uuid.UUID
+	if _, err := rand.Read(u[:]); err != nil {
+		// The system random source never fails on the supported platforms
+		return uuid.NewV4()
+	}
+
+	g.mu.Lock()
+	if now := time.Now().UnixMilli(); now > g.millis {
+		g.millis = now
+		// The counter starts randomly low, so it rarely overflows
+		g.seq = binary.BigEndian.Uint16(u[6:8]) & 0x7ff
+	} else {
+		// Same millisecond or the clock went back
+		g.seq++
+		if g.seq > 0xfff {
+			g.millis++
+			g.seq = 0
+		}
+	}
+	millis, seq := g.millis, g.seq
+	g.mu.Unlock()
+
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(millis))
+	copy(u[0:6], ts[2:8])
+	u[6] = 0x70 | byte(seq>>8)
+	u[7] = byte(seq)
+	u.SetVariant(uuid.VariantRFC4122)
+	return u
+}
+
+// UUIDTime returns the creation time of the UUIDv7, ok is false for the other versions
+func UUIDTime(u uuid.UUID) (t time.Time, ok bool) {
+	if u.Version() != 7 {
+		return time.Time{}, false
+	}
+	var ts [8]byte
+	copy(ts[2:8], u[0:6])
+	return time.UnixMilli(int64(binary.BigEndian.Uint64(ts[:]))), true
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUUIDv7Generator(t *testing.T) {
+	g := &UUIDv7Generator{}
+	before := time.Now().Truncate(time.Millisecond)
+
+	prev := g.NewUUID()
+	for i := 0; i < 10000; i++ {
+		u := g.NewUUID()
+		assert.Equal(t, byte(7), u.Version())
+		assert.Equal(t, uuid.VariantRFC4122, u.Variant())
+		require.Equal(t, 1, bytes.Compare(u.Bytes(), prev.Bytes()), "UUIDs must be in order")
+		prev = u
+	}
+
+	created, ok := UUIDTime(prev)
+	assert.True(t, ok)
+	assert.False(t, created.Before(before))
+	assert.WithinDuration(t, time.Now(), created, time.Second)
+
+	// Random UUIDs of the older records have no time
+	_, ok = UUIDTime(uuid.NewV4())
+	assert.False(t, ok)
+}
+
+type fixedUUIDs struct{ u uuid.UUID }
+
+func (f fixedUUIDs) NewUUID() uuid.UUID { return f.u }
+
+func TestSetUUIDGenerator(t *testing.T) {
+	fixed := uuid.NewV4()
+	SetUUIDGenerator(fixedUUIDs{fixed})
+	defer SetUUIDGenerator(nil)
+
+	assert.Equal(t, fixed, NewUUID())
+	SetUUIDGenerator(nil)
+	assert.Equal(t, byte(7), NewUUID().Version())
+}
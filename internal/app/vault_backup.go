@@ -0,0 +1,414 @@
+package app
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	vaultBackupFormat  = "passwall-backup"
+	vaultBackupVersion = 2
+	vaultBackupKDF     = "argon2id"
+)
+
+var (
+	// ErrInvalidBackup represents message for files which aren't Passwall backups
+	ErrInvalidBackup = errors.New("file is not a Passwall backup")
+	// ErrWrongBackupPassword represents message for backups which can't be decrypted
+	ErrWrongBackupPassword = errors.New("backup password is wrong or the file is damaged")
+	// ErrShortBackupPassword represents message for weak backup passwords
+	ErrShortBackupPassword = fmt.Errorf("backup password must be at least %d characters", minSecureKeyLength)
+)
+
+// BuildVaultBackup collects all the decrypted items, folders, login histories
+// and relations of the vault
+func BuildVaultBackup(s storage.Store, schema string) (*model.VaultBackup, error) {
+	var err error
+	backup := &model.VaultBackup{Version: vaultBackupVersion, CreatedAt: time.Now()}
+
+	if backup.Folders, err = s.Folders().All(schema); err != nil {
+		return nil, err
+	}
+	if backup.Logins, err = FindAllLogins(s, schema); err != nil {
+		return nil, err
+	}
+	for _, login := range backup.Logins {
+		histories, err := FindLoginHistories(s, login.ID, schema)
+		if err != nil {
+			return nil, err
+		}
+		backup.LoginHistories = append(backup.LoginHistories, histories...)
+	}
+	if backup.CreditCards, err = FindAllCreditCards(s, schema); err != nil {
+		return nil, err
+	}
+	if backup.BankAccounts, err = FindAllBankAccounts(s, schema); err != nil {
+		return nil, err
+	}
+	if backup.Notes, err = FindAllNotes(s, schema); err != nil {
+		return nil, err
+	}
+	if backup.Emails, err = FindAllEmails(s, schema); err != nil {
+		return nil, err
+	}
+	if backup.Servers, err = FindAllServers(s, schema); err != nil {
+		return nil, err
+	}
+	if backup.Relations, err = s.Relations().All(schema); err != nil {
+		return nil, err
+	}
+
+	return backup, nil
+}
+
+// EncryptVaultBackup encrypts the backup with a key derived from the password
+func EncryptVaultBackup(backup *model.VaultBackup, password string) ([]byte, error) {
+	if len(password) < minSecureKeyLength {
+		return nil, ErrShortBackupPassword
+	}
+
+	plain, err := json.Marshal(backup)
+	if err != nil {
+		return nil, err
+	}
+
+	file := model.VaultBackupFile{
+		Format:  vaultBackupFormat,
+		Version: vaultBackupVersion,
+		KDF:     vaultBackupKDF,
+		Time:    3,
+		Memory:  64 * 1024,
+		Threads: 4,
+		Salt:    make([]byte, 16),
+	}
+	if _, err := io.ReadFull(rand.Reader, file.Salt); err != nil {
+		return nil, err
+	}
+
+	gcm, err := vaultBackupCipher(&file, password)
+	if err != nil {
+		return nil, err
+	}
+	file.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, file.Nonce); err != nil {
+		return nil, err
+	}
+	// Header is authenticated too, so KDF params can't be changed
+	file.Data = gcm.Seal(nil, file.Nonce, plain, vaultBackupHeader(&file))
+
+	// Ciphertext is signed, so the restore checks it before the slow KDF
+	if file.Manifest, file.Signature, err = SignExport(ExportTypeBackup, "", VaultBackupItems(backup), file.Data); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(file)
+}
+
+// DecryptVaultBackup opens the backup file encrypted with the password
+func DecryptVaultBackup(data []byte, password string) (*model.VaultBackup, error) {
+	var file model.VaultBackupFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Format != vaultBackupFormat {
+		return nil, ErrInvalidBackup
+	}
+	if file.Version > vaultBackupVersion || file.KDF != vaultBackupKDF {
+		return nil, fmt.Errorf("unsupported backup version %d", file.Version)
+	}
+	// Version 1 files have no manifest, they are only protected by AES-GCM
+	var manifest *model.ExportManifest
+	if file.Version >= 2 {
+		var err error
+		if manifest, err = VerifyExport(file.Manifest, file.Signature, file.Data); err != nil {
+			return nil, err
+		}
+		if manifest.Type != ExportTypeBackup {
+			return nil, ErrInvalidBackup
+		}
+	}
+
+	gcm, err := vaultBackupCipher(&file, password)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Nonce) != gcm.NonceSize() {
+		return nil, ErrInvalidBackup
+	}
+	plain, err := gcm.Open(nil, file.Nonce, file.Data, vaultBackupHeader(&file))
+	if err != nil {
+		return nil, ErrWrongBackupPassword
+	}
+
+	backup := new(model.VaultBackup)
+	if err := json.Unmarshal(plain, backup); err != nil {
+		return nil, ErrInvalidBackup
+	}
+	if manifest != nil && manifest.Items != VaultBackupItems(backup) {
+		return nil, ErrExportTampered
+	}
+	return backup, nil
+}
+
+// VaultBackupItems counts the items of the backup, folders and histories aren't items
+func VaultBackupItems(backup *model.VaultBackup) int {
+	return len(backup.Logins) + len(backup.CreditCards) + len(backup.BankAccounts) +
+		len(backup.Notes) + len(backup.Emails) + len(backup.Servers)
+}
+
+func vaultBackupCipher(file *model.VaultBackupFile, password string) (cipher.AEAD, error) {
+	key := argon2.IDKey([]byte(password), file.Salt, file.Time, file.Memory, file.Threads, 32)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func vaultBackupHeader(file *model.VaultBackupFile) []byte {
+	return []byte(fmt.Sprintf("%s:%d:%s:%d:%d:%d", file.Format, file.Version, file.KDF, file.Time, file.Memory, file.Threads))
+}
+
+// RestoreVaultBackup adds the content of the backup to the vault. Items get new
+// IDs and their folders, histories and relations are linked to the new IDs.
+func RestoreVaultBackup(s storage.Store, backup *model.VaultBackup, schema string) (*model.ImportSummary, error) {
+	summary := &model.ImportSummary{Types: map[string]int{}}
+
+	folderIDs, err := restoreFolders(s, backup.Folders, schema)
+	if err != nil {
+		return nil, err
+	}
+	folderID := func(old *uint) *uint {
+		if old == nil {
+			return nil
+		}
+		if id, ok := folderIDs[*old]; ok {
+			return &id
+		}
+		return nil
+	}
+
+	// New IDs of the items by type and old ID
+	itemIDs := map[string]map[uint]uint{}
+	created := func(itemType string, oldID, newID uint) {
+		if itemIDs[itemType] == nil {
+			itemIDs[itemType] = map[uint]uint{}
+		}
+		itemIDs[itemType][oldID] = newID
+		summary.Types[itemType]++
+		summary.Created++
+	}
+	fail := func(itemType string, oldID uint, err error) {
+		summary.Failed++
+		summary.Errors = append(summary.Errors, fmt.Sprintf("%s %d: %v", itemType, oldID, err))
+	}
+
+	for i := range backup.Logins {
+		item := backup.Logins[i]
+		oldID := item.ID
+		item.ID, item.FolderID = 0, folderID(item.FolderID)
+		SetLoginSearchIndex(&item)
+		seal, err := sealCreated(s, &item, schema)
+		if err != nil {
+			fail(ItemTypeLogin, oldID, err)
+			continue
+		}
+		createdItem, err := s.Logins().CreateSealed(&item, schema, seal)
+		if err != nil {
+			fail(ItemTypeLogin, oldID, err)
+			continue
+		}
+		created(ItemTypeLogin, oldID, createdItem.ID)
+	}
+	for i := range backup.LoginHistories {
+		history := backup.LoginHistories[i]
+		oldID := history.ID
+		loginID, ok := itemIDs[ItemTypeLogin][history.LoginID]
+		if !ok {
+			continue
+		}
+		history.ID, history.LoginID = 0, loginID
+		seal, err := sealCreated(s, &history, schema)
+		if err != nil {
+			fail("login_history", oldID, err)
+			continue
+		}
+		if _, err := s.Logins().CreateHistorySealed(&history, schema, seal); err != nil {
+			fail("login_history", oldID, err)
+		}
+	}
+	for i := range backup.CreditCards {
+		item := backup.CreditCards[i]
+		oldID := item.ID
+		item.ID, item.FolderID = 0, folderID(item.FolderID)
+		seal, err := sealCreated(s, &item, schema)
+		if err != nil {
+			fail(ItemTypeCreditCard, oldID, err)
+			continue
+		}
+		createdItem, err := s.CreditCards().CreateSealed(&item, schema, seal)
+		if err != nil {
+			fail(ItemTypeCreditCard, oldID, err)
+			continue
+		}
+		created(ItemTypeCreditCard, oldID, createdItem.ID)
+	}
+	for i := range backup.BankAccounts {
+		item := backup.BankAccounts[i]
+		oldID := item.ID
+		item.ID, item.FolderID = 0, folderID(item.FolderID)
+		seal, err := sealCreated(s, &item, schema)
+		if err != nil {
+			fail(ItemTypeBankAccount, oldID, err)
+			continue
+		}
+		createdItem, err := s.BankAccounts().CreateSealed(&item, schema, seal)
+		if err != nil {
+			fail(ItemTypeBankAccount, oldID, err)
+			continue
+		}
+		created(ItemTypeBankAccount, oldID, createdItem.ID)
+	}
+	for i := range backup.Notes {
+		item := backup.Notes[i]
+		oldID := item.ID
+		item.ID, item.FolderID = 0, folderID(item.FolderID)
+		seal, err := sealCreated(s, &item, schema)
+		if err != nil {
+			fail(ItemTypeNote, oldID, err)
+			continue
+		}
+		createdItem, err := s.Notes().CreateSealed(&item, schema, seal)
+		if err != nil {
+			fail(ItemTypeNote, oldID, err)
+			continue
+		}
+		created(ItemTypeNote, oldID, createdItem.ID)
+	}
+	for i := range backup.Emails {
+		item := backup.Emails[i]
+		oldID := item.ID
+		item.ID, item.FolderID = 0, folderID(item.FolderID)
+		seal, err := sealCreated(s, &item, schema)
+		if err != nil {
+			fail(ItemTypeEmail, oldID, err)
+			continue
+		}
+		createdItem, err := s.Emails().CreateSealed(&item, schema, seal)
+		if err != nil {
+			fail(ItemTypeEmail, oldID, err)
+			continue
+		}
+		created(ItemTypeEmail, oldID, createdItem.ID)
+	}
+	for i := range backup.Servers {
+		item := backup.Servers[i]
+		oldID := item.ID
+		item.ID, item.FolderID = 0, folderID(item.FolderID)
+		seal, err := sealCreated(s, &item, schema)
+		if err != nil {
+			fail(ItemTypeServer, oldID, err)
+			continue
+		}
+		createdItem, err := s.Servers().CreateSealed(&item, schema, seal)
+		if err != nil {
+			fail(ItemTypeServer, oldID, err)
+			continue
+		}
+		created(ItemTypeServer, oldID, createdItem.ID)
+	}
+
+	for _, relation := range restoredRelations(backup.Relations, itemIDs) {
+		relation := relation
+		if _, err := s.Relations().Create(&relation, schema); err != nil {
+			fail("relation", 0, err)
+		}
+	}
+
+	return summary, nil
+}
+
+// restoreFolders creates the folders parents first and returns the new IDs by old ID
+func restoreFolders(s storage.Store, folders []model.Folder, schema string) (map[uint]uint, error) {
+	folderIDs := map[uint]uint{}
+	for _, folder := range sortFoldersByParent(folders) {
+		oldID := folder.ID
+		folder.ID = 0
+		if folder.ParentID != nil {
+			parentID := folderIDs[*folder.ParentID]
+			folder.ParentID = &parentID
+		}
+		created, err := s.Folders().Create(&folder, schema)
+		if err != nil {
+			return nil, err
+		}
+		folderIDs[oldID] = created.ID
+	}
+	return folderIDs, nil
+}
+
+// sortFoldersByParent orders the folders so every parent comes before its
+// children. Folders whose parent isn't in the list become top level folders.
+func sortFoldersByParent(folders []model.Folder) []model.Folder {
+	known := map[uint]bool{}
+	for _, folder := range folders {
+		known[folder.ID] = true
+	}
+
+	sorted := []model.Folder{}
+	added := map[uint]bool{}
+	for len(sorted) < len(folders) {
+		progress := false
+		for _, folder := range folders {
+			if added[folder.ID] {
+				continue
+			}
+			if folder.ParentID != nil && !known[*folder.ParentID] {
+				folder.ParentID = nil
+			}
+			if folder.ParentID == nil || added[*folder.ParentID] {
+				sorted = append(sorted, folder)
+				added[folder.ID] = true
+				progress = true
+			}
+		}
+		// Folders in a cycle are moved to the top level
+		if !progress {
+			for _, folder := range folders {
+				if !added[folder.ID] {
+					folder.ParentID = nil
+					sorted = append(sorted, folder)
+					added[folder.ID] = true
+					break
+				}
+			}
+		}
+	}
+	return sorted
+}
+
+// restoredRelations links the relations to the new item IDs, relations of
+// the items which couldn't be restored are left out
+func restoredRelations(relations []model.ItemRelation, itemIDs map[string]map[uint]uint) []model.ItemRelation {
+	restored := []model.ItemRelation{}
+	for _, relation := range relations {
+		sourceID, ok := itemIDs[relation.SourceType][relation.SourceID]
+		if !ok {
+			continue
+		}
+		targetID, ok := itemIDs[relation.TargetType][relation.TargetID]
+		if !ok {
+			continue
+		}
+		relation.ID, relation.SourceID, relation.TargetID = 0, sourceID, targetID
+		restored = append(restored, relation)
+	}
+	return restored
+}
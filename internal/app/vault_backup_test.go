@@ -0,0 +1,93 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptVaultBackup(t *testing.T) {
+	backup := &model.VaultBackup{
+		Version: vaultBackupVersion,
+		Logins:  []model.Login{{ID: 7, Title: "Mail", Password: "secret"}},
+	}
+
+	_, err := EncryptVaultBackup(backup, "short")
+	assert.Equal(t, ErrShortBackupPassword, err)
+
+	data, err := EncryptVaultBackup(backup, "correct horse")
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "secret")
+
+	restored, err := DecryptVaultBackup(data, "correct horse")
+	assert.NoError(t, err)
+	assert.Equal(t, backup.Logins, restored.Logins)
+
+	_, err = DecryptVaultBackup(data, "wrong password")
+	assert.Equal(t, ErrWrongBackupPassword, err)
+
+	_, err = DecryptVaultBackup([]byte(`{"format":"other"}`), "correct horse")
+	assert.Equal(t, ErrInvalidBackup, err)
+
+	// Changing the KDF params breaks the authentication of the data
+	var file model.VaultBackupFile
+	assert.NoError(t, json.Unmarshal(data, &file))
+	file.Time = 1
+	tampered, _ := json.Marshal(file)
+	_, err = DecryptVaultBackup(tampered, "correct horse")
+	assert.Equal(t, ErrWrongBackupPassword, err)
+
+	// Truncated data and missing signatures are found before decrypting
+	assert.NoError(t, json.Unmarshal(data, &file))
+	file.Data = file.Data[:len(file.Data)-1]
+	tampered, _ = json.Marshal(file)
+	_, err = DecryptVaultBackup(tampered, "correct horse")
+	assert.Equal(t, ErrExportTampered, err)
+
+	assert.NoError(t, json.Unmarshal(data, &file))
+	file.Signature = nil
+	tampered, _ = json.Marshal(file)
+	_, err = DecryptVaultBackup(tampered, "correct horse")
+	assert.Equal(t, ErrExportSignature, err)
+}
+
+func TestSortFoldersByParent(t *testing.T) {
+	id := func(v uint) *uint { return &v }
+	folders := []model.Folder{
+		{ID: 3, ParentID: id(2)},
+		{ID: 2, ParentID: id(1)},
+		{ID: 1},
+		{ID: 4, ParentID: id(99)},
+		{ID: 5, ParentID: id(6)},
+		{ID: 6, ParentID: id(5)},
+	}
+
+	sorted := sortFoldersByParent(folders)
+	assert.Len(t, sorted, len(folders))
+
+	added := map[uint]bool{}
+	for _, folder := range sorted {
+		if folder.ParentID != nil {
+			assert.True(t, added[*folder.ParentID], "parent of %d", folder.ID)
+		}
+		added[folder.ID] = true
+	}
+}
+
+func TestRestoredRelations(t *testing.T) {
+	relations := []model.ItemRelation{
+		{ID: 1, SourceType: ItemTypeLogin, SourceID: 1, TargetType: ItemTypeNote, TargetID: 2},
+		{ID: 2, SourceType: ItemTypeLogin, SourceID: 1, TargetType: ItemTypeNote, TargetID: 3},
+	}
+	itemIDs := map[string]map[uint]uint{
+		ItemTypeLogin: {1: 10},
+		ItemTypeNote:  {2: 20},
+	}
+
+	restored := restoredRelations(relations, itemIDs)
+	assert.Equal(t, []model.ItemRelation{
+		{SourceType: ItemTypeLogin, SourceID: 10, TargetType: ItemTypeNote, TargetID: 20},
+	}, restored)
+}
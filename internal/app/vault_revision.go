@@ -0,0 +1,43 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+)
+
+// VaultRevision returns a hash which changes whenever an item or folder of the
+// vault is created, updated or deleted
+func VaultRevision(s storage.Store, schema string) (string, error) {
+	states, err := s.Vaults().State(schema)
+	if err != nil {
+		return "", err
+	}
+	return hashVaultState(schema, states), nil
+}
+
+func hashVaultState(schema string, states []model.VaultTableState) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n", schema)
+	for _, state := range states {
+		var updatedAt int64
+		if state.UpdatedAt != nil {
+			updatedAt = state.UpdatedAt.UnixNano()
+		}
+		fmt.Fprintf(hash, "%s:%d:%d\n", state.Name, state.Count, updatedAt)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:32]
+}
+
+// FindVaultRevision returns the revision counter of the vault
+func FindVaultRevision(s storage.Store, schema string) (*model.VaultRevision, error) {
+	return s.Vaults().Revision(schema)
+}
+
+// BumpVaultRevision increases the revision counter of the vault after a mutation
+func BumpVaultRevision(s storage.Store, schema string) (uint64, error) {
+	return s.Vaults().BumpRevision(schema)
+}
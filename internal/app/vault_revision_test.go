@@ -0,0 +1,31 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashVaultState(t *testing.T) {
+	updatedAt := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	later := updatedAt.Add(time.Second)
+	states := []model.VaultTableState{
+		{Name: "logins", Count: 2, UpdatedAt: &updatedAt},
+		{Name: "notes", Count: 0},
+	}
+
+	revision := hashVaultState("user1", states)
+	assert.Len(t, revision, 32)
+	assert.Equal(t, revision, hashVaultState("user1", states))
+
+	// Every kind of change gives a new revision
+	assert.NotEqual(t, revision, hashVaultState("user2", states))
+	assert.NotEqual(t, revision, hashVaultState("user1", []model.VaultTableState{
+		{Name: "logins", Count: 1, UpdatedAt: &updatedAt}, {Name: "notes", Count: 0},
+	}))
+	assert.NotEqual(t, revision, hashVaultState("user1", []model.VaultTableState{
+		{Name: "logins", Count: 2, UpdatedAt: &later}, {Name: "notes", Count: 0},
+	}))
+}
@@ -0,0 +1,377 @@
+package app
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/config"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+// Webhook events
+const (
+	WebhookEventItemCreated         = "item.created"
+	WebhookEventNewDeviceSignin     = "signin.new_device"
+	WebhookEventUserSignedUp        = "user.signed_up"
+	WebhookEventSubscriptionChanged = "subscription.changed"
+	WebhookEventSecurityAlert       = "security.alert"
+	WebhookEventEmailChanged        = "user.email_changed"
+)
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+const (
+	// webhookTimeout limits an attempt so a slow endpoint doesn't hold the retries
+	webhookTimeout = 10 * time.Second
+	// webhookMaxAttempts is the number of attempts before a delivery fails
+	webhookMaxAttempts = 8
+	// webhookFirstRetry is the wait after the first failed attempt, it doubles
+	// after every attempt
+	webhookFirstRetry = time.Minute
+	// webhookRetryBatch is the number of deliveries retried at once
+	webhookRetryBatch = 100
+	// webhookSecretLen is the number of random bytes of the signing secrets
+	webhookSecretLen = 32
+)
+
+var (
+	// ErrWebhookNotFound represents message for webhooks which don't exist or belong to another user
+	ErrWebhookNotFound = errors.New("webhook not found")
+	// ErrWebhookURL represents message for webhook URLs which can't be delivered to
+	ErrWebhookURL = errors.New("webhook URL must be an absolute https URL")
+	// ErrWebhookAddress represents message for webhook URLs of the local network
+	ErrWebhookAddress = errors.New("webhook URL must resolve to a public address")
+	// ErrWebhookEvent represents message for webhook events which can't be subscribed to
+	ErrWebhookEvent = errors.New("webhook event is not supported")
+)
+
+// userWebhookEvents are the events users can subscribe to, admin webhooks
+// can also receive the signups
+var userWebhookEvents = []string{WebhookEventItemCreated, WebhookEventNewDeviceSignin, WebhookEventSubscriptionChanged, WebhookEventSecurityAlert, WebhookEventEmailChanged}
+
+// webhookClient checks the dialed addresses too since the host may resolve to
+// another address when delivering, and returns the redirects as failures so
+// endpoints can't redirect deliveries to the local network
+var webhookClient = &http.Client{
+	Timeout: webhookTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: webhookTimeout,
+			Control: controlWebhookDial,
+		}).DialContext,
+		TLSHandshakeTimeout: webhookTimeout,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// webhookLookupIP resolves the hosts of the webhook URLs
+var webhookLookupIP = net.LookupIP
+
+// WebhookPayload is the body of the deliveries
+type WebhookPayload struct {
+	Event     string      `json:"event"`
+	UserID    uint        `json:"user_id"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// CreateWebhook creates the webhook of the user or an admin webhook, the
+// returned webhook has its signing secret
+func CreateWebhook(s storage.Store, dto *model.WebhookDTO, userID uint, admin bool) (*model.Webhook, error) {
+	if err := validateWebhook(dto, admin); err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, webhookSecretLen)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	webhook := &model.Webhook{
+		UserID: userID,
+		Admin:  admin,
+		URL:    dto.URL,
+		Secret: hex.EncodeToString(secret),
+		Events: strings.Join(dto.Events, ","),
+		Active: dto.Active == nil || *dto.Active,
+	}
+	return s.Webhooks().Create(webhook)
+}
+
+// FindWebhook finds the webhook of the user, admin webhooks are found only for admins
+func FindWebhook(s storage.Store, id, userID uint, admin bool) (*model.Webhook, error) {
+	webhook, err := s.Webhooks().FindByID(id)
+	if err != nil || webhook.Admin != admin || (!admin && webhook.UserID != userID) {
+		return nil, ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+// UpdateWebhook changes the URL, the events and the state of the webhook
+func UpdateWebhook(s storage.Store, webhook *model.Webhook, dto *model.WebhookDTO) (*model.Webhook, error) {
+	if err := validateWebhook(dto, webhook.Admin); err != nil {
+		return nil, err
+	}
+
+	webhook.URL = dto.URL
+	webhook.Events = strings.Join(dto.Events, ",")
+	if dto.Active != nil {
+		webhook.Active = *dto.Active
+	}
+	return s.Webhooks().Update(webhook)
+}
+
+// validateWebhook checks the URL and the events of the webhook, http URLs are
+// allowed only in the dev environment. The host must resolve to public
+// addresses only, webhooks can't reach the services of the local network.
+func validateWebhook(dto *model.WebhookDTO, admin bool) error {
+	u, err := url.Parse(dto.URL)
+	if err != nil || u.Hostname() == "" || (u.Scheme != "https" && (u.Scheme != "http" || config.Current().Server.Env != "dev")) {
+		return ErrWebhookURL
+	}
+
+	ips := []net.IP{net.ParseIP(u.Hostname())}
+	if ips[0] == nil {
+		if ips, err = webhookLookupIP(u.Hostname()); err != nil || len(ips) == 0 {
+			return ErrWebhookAddress
+		}
+	}
+	for _, ip := range ips {
+		if !publicWebhookIP(ip) {
+			return ErrWebhookAddress
+		}
+	}
+
+	allowed := userWebhookEvents
+	if admin {
+		allowed = append([]string{WebhookEventUserSignedUp}, userWebhookEvents...)
+	}
+	for _, event := range dto.Events {
+		if FindIndex(allowed, event) < 0 {
+			return fmt.Errorf("%w: %s", ErrWebhookEvent, event)
+		}
+	}
+	return nil
+}
+
+// DispatchWebhookEvent sends the event of the user to the webhooks of the user
+// and to the admin webhooks in the background. Deliveries are stored first, the
+// failed ones are retried by RetryWebhookDeliveries.
+func DispatchWebhookEvent(s storage.Store, event string, userID uint, data interface{}) {
+	webhooks, err := s.Webhooks().FindActive(userID)
+	if err != nil || len(webhooks) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(WebhookPayload{Event: event, UserID: userID, CreatedAt: time.Now().UTC(), Data: data})
+	if err != nil {
+		logger.Errorf("can't encode webhook event %s error: %v", event, err)
+		return
+	}
+
+	for i := range webhooks {
+		webhook := &webhooks[i]
+		if !webhook.Subscribed(event) {
+			continue
+		}
+		// The first attempt is done at once, the next attempt time keeps the
+		// retries away from it until it is surely over
+		next := time.Now().Add(2 * webhookTimeout)
+		delivery, err := s.Webhooks().CreateDelivery(&model.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			Event:         event,
+			Payload:       string(payload),
+			Status:        WebhookDeliveryPending,
+			NextAttemptAt: &next,
+		})
+		if err != nil {
+			continue
+		}
+		go deliverWebhook(s, webhook, delivery)
+	}
+}
+
+// RetryWebhookDeliveries attempts the pending deliveries whose retry is due
+func RetryWebhookDeliveries(s storage.Store) {
+	deliveries, err := s.Webhooks().FindDueDeliveries(time.Now(), WebhookDeliveryPending, webhookRetryBatch)
+	if err != nil {
+		return
+	}
+
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		webhook, err := s.Webhooks().FindByID(delivery.WebhookID)
+		if err != nil {
+			continue
+		}
+		if !webhook.Active {
+			delivery.Status = WebhookDeliveryFailed
+			delivery.Error = "webhook is inactive"
+			delivery.NextAttemptAt = nil
+			s.Webhooks().UpdateDelivery(delivery)
+			continue
+		}
+		deliverWebhook(s, webhook, delivery)
+	}
+}
+
+// deliverWebhook makes an attempt of the delivery and stores its result
+func deliverWebhook(s storage.Store, webhook *model.Webhook, delivery *model.WebhookDelivery) {
+	now := time.Now()
+	delivery.Attempts++
+	delivery.ResponseCode, delivery.Error = 0, ""
+
+	code, err := postWebhook(webhook, delivery, now)
+	delivery.ResponseCode = code
+	switch {
+	case err == nil:
+		delivery.Status = WebhookDeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+	case delivery.Attempts >= webhookMaxAttempts:
+		delivery.Status = WebhookDeliveryFailed
+		delivery.Error = err.Error()
+		delivery.NextAttemptAt = nil
+	default:
+		delivery.Error = err.Error()
+		next := now.Add(WebhookRetryDelay(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	}
+
+	if _, err := s.Webhooks().UpdateDelivery(delivery); err != nil {
+		logger.Errorf("can't update webhook delivery %d error: %v", delivery.ID, err)
+	}
+}
+
+// postWebhook posts the payload of the delivery signed with the secret of the
+// webhook and returns the response status
+func postWebhook(webhook *model.Webhook, delivery *model.WebhookDelivery, now time.Time) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Passwall-Webhook")
+	req.Header.Set("X-Passwall-Event", delivery.Event)
+	req.Header.Set("X-Passwall-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Passwall-Timestamp", timestamp)
+	req.Header.Set("X-Passwall-Signature", SignWebhook(webhook.Secret, timestamp, []byte(delivery.Payload)))
+
+	res, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("endpoint returned %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+// controlWebhookDial refuses the connections of the deliveries to the local
+// network, the address is the resolved one so DNS rebinding can't bypass it
+func controlWebhookDial(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicWebhookIP(ip) {
+		return ErrWebhookAddress
+	}
+	return nil
+}
+
+// publicWebhookIP checks if the address isn't a loopback, private, link-local
+// or unspecified one
+func publicWebhookIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsUnspecified()
+}
+
+// SignWebhook returns the X-Passwall-Signature header of the payload, the HMAC
+// SHA-256 of the timestamp and the payload joined with a dot. Receivers should
+// compare it in constant time and reject old timestamps to stop replays.
+func SignWebhook(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookRetryDelay is the wait before the next attempt after the given number
+// of failed attempts
+func WebhookRetryDelay(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	return webhookFirstRetry << (attempts - 1)
+}
+
+// IsNewDevice checks if the user never signed in before with the user agent,
+// it must be called before the signin is recorded
+func IsNewDevice(s storage.Store, userID uint, userAgent string) bool {
+	opts := &model.ListOptions{
+		Limit:     1,
+		Sort:      "id",
+		Direction: "desc",
+		Filters:   map[string]interface{}{"user_id": userID, "action": AuditActionSignin, "user_agent": userAgent},
+	}
+	_, total, err := s.AuditEvents().FindAll(opts, model.AuditPeriod{})
+	return err == nil && total == 0
+}
+
+// UpdateSubscription stores the subscription type seen at signin or sent by
+// the payment provider and sends subscription.changed when it is different
+// from the previous one. Users becoming past due and downgraded after it get
+// the dunning emails.
+func UpdateSubscription(s storage.Store, user *model.User, subscriptionType string) {
+	previous := user.SubscriptionType
+	if previous == subscriptionType {
+		return
+	}
+
+	user.SubscriptionType = subscriptionType
+	trackLapse(user, subscriptionType)
+	if _, err := s.Users().Update(user); err != nil {
+		return
+	}
+	notifyDunning(s, user, previous, subscriptionType)
+	// The first signin after the upgrade only records the type
+	if previous != "" {
+		RecordAuditEvent(s, &model.AuditEvent{UserID: user.ID, Action: AuditActionSubscription, ItemType: "users", ItemID: user.ID})
+		DispatchWebhookEvent(s, WebhookEventSubscriptionChanged, user.ID, map[string]string{
+			"from": previous,
+			"to":   subscriptionType,
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignWebhook(t *testing.T) {
+	payload := []byte(`{"event":"item.created"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1622548800." + string(payload)))
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	assert.Equal(t, expected, SignWebhook("secret", "1622548800", payload))
+	assert.NotEqual(t, expected, SignWebhook("other", "1622548800", payload))
+	assert.NotEqual(t, expected, SignWebhook("secret", "1622548801", payload))
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{attempts: 0, expected: time.Minute},
+		{attempts: 1, expected: time.Minute},
+		{attempts: 2, expected: 2 * time.Minute},
+		{attempts: 4, expected: 8 * time.Minute},
+		{attempts: 7, expected: 64 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, WebhookRetryDelay(tt.attempts))
+	}
+}
+
+func TestValidateWebhook(t *testing.T) {
+	defer func(lookup func(string) ([]net.IP, error)) { webhookLookupIP = lookup }(webhookLookupIP)
+	webhookLookupIP = func(host string) ([]net.IP, error) {
+		switch host {
+		case "example.com":
+			return []net.IP{net.ParseIP("93.184.216.34")}, nil
+		case "internal.example.com":
+			return []net.IP{net.ParseIP("93.184.216.34"), net.ParseIP("10.1.2.3")}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	tests := []struct {
+		name     string
+		dto      model.WebhookDTO
+		admin    bool
+		expected error
+	}{
+		{name: "Valid", dto: model.WebhookDTO{URL: "https://example.com/hook", Events: []string{WebhookEventItemCreated}}},
+		{name: "Plain http", dto: model.WebhookDTO{URL: "http://example.com/hook", Events: []string{WebhookEventItemCreated}}, expected: ErrWebhookURL},
+		{name: "Relative URL", dto: model.WebhookDTO{URL: "/hook", Events: []string{WebhookEventItemCreated}}, expected: ErrWebhookURL},
+		{name: "Unknown event", dto: model.WebhookDTO{URL: "https://example.com/hook", Events: []string{"item.deleted"}}, expected: ErrWebhookEvent},
+		{name: "Signups for users", dto: model.WebhookDTO{URL: "https://example.com/hook", Events: []string{WebhookEventUserSignedUp}}, expected: ErrWebhookEvent},
+		{name: "Signups for admins", dto: model.WebhookDTO{URL: "https://example.com/hook", Events: []string{WebhookEventUserSignedUp}}, admin: true},
+		{name: "Public address", dto: model.WebhookDTO{URL: "https://93.184.216.34/hook", Events: []string{WebhookEventItemCreated}}},
+		{name: "Loopback", dto: model.WebhookDTO{URL: "https://127.0.0.1/hook", Events: []string{WebhookEventItemCreated}}, expected: ErrWebhookAddress},
+		{name: "IPv6 loopback", dto: model.WebhookDTO{URL: "https://[::1]/hook", Events: []string{WebhookEventItemCreated}}, expected: ErrWebhookAddress},
+		{name: "Private", dto: model.WebhookDTO{URL: "https://192.168.1.10/hook", Events: []string{WebhookEventItemCreated}}, expected: ErrWebhookAddress},
+		{name: "Link-local", dto: model.WebhookDTO{URL: "https://169.254.169.254/latest/meta-data", Events: []string{WebhookEventItemCreated}}, expected: ErrWebhookAddress},
+		{name: "Unspecified", dto: model.WebhookDTO{URL: "https://0.0.0.0/hook", Events: []string{WebhookEventItemCreated}}, expected: ErrWebhookAddress},
+		{name: "Resolves to private", dto: model.WebhookDTO{URL: "https://internal.example.com/hook", Events: []string{WebhookEventItemCreated}}, expected: ErrWebhookAddress},
+		{name: "Unresolved", dto: model.WebhookDTO{URL: "https://unknown.example.com/hook", Events: []string{WebhookEventItemCreated}}, expected: ErrWebhookAddress},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateWebhook(&tt.dto, tt.admin)
+			if tt.expected == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, tt.expected), err)
+		})
+	}
+}
+
+func TestWebhookSubscribed(t *testing.T) {
+	webhook := &model.Webhook{Events: WebhookEventItemCreated + "," + WebhookEventSubscriptionChanged}
+
+	assert.True(t, webhook.Subscribed(WebhookEventItemCreated))
+	assert.True(t, webhook.Subscribed(WebhookEventSubscriptionChanged))
+	assert.False(t, webhook.Subscribed(WebhookEventNewDeviceSignin))
+}
+
+func TestPostWebhookLocalAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// The host may resolve to the local network after the validation
+	webhook := &model.Webhook{URL: server.URL, Secret: "secret"}
+	_, err := postWebhook(webhook, &model.WebhookDelivery{Event: WebhookEventItemCreated, Payload: "{}"}, time.Now())
+	assert.True(t, errors.Is(err, ErrWebhookAddress), err)
+}
+
+func TestPostWebhookRedirect(t *testing.T) {
+	redirected := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/internal" {
+			redirected = true
+			return
+		}
+		http.Redirect(w, r, "/internal", http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	// Dial the test server, the redirect must still be returned as is
+	defer func(transport http.RoundTripper) { webhookClient.Transport = transport }(webhookClient.Transport)
+	webhookClient.Transport = server.Client().Transport
+
+	webhook := &model.Webhook{URL: server.URL + "/hook", Secret: "secret"}
+	status, err := postWebhook(webhook, &model.WebhookDelivery{Event: WebhookEventItemCreated, Payload: "{}"}, time.Now())
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusTemporaryRedirect, status)
+	assert.False(t, redirected)
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// ClientCiphertextFormat is the version of the fields the clients encrypt
+	// in zero-knowledge mode, zk1.<base64 nonce>.<base64 ciphertext>
+	ClientCiphertextFormat = "zk1"
+	// clientNonceLength and clientTagLength are the nonce and tag lengths of AES-GCM
+	clientNonceLength = 12
+	clientTagLength   = 16
+)
+
+var (
+	// ErrNotClientEncrypted represents message for item fields not encrypted by the client in zero-knowledge mode
+	ErrNotClientEncrypted = errors.New("item fields must be encrypted by the client in zero-knowledge mode")
+	// ErrZeroKnowledge represents message for features needing the plaintext items in zero-knowledge mode
+	ErrZeroKnowledge = errors.New("not available in zero-knowledge mode, the server can't read the items")
+)
+
+// ZeroKnowledge reports whether the server runs in zero-knowledge mode, the
+// clients encrypt the item fields and the server stores them as they are
+func ZeroKnowledge() bool {
+	return viper.GetBool("server.zeroKnowledge")
+}
+
+// IsClientCiphertext checks if the field has the structure of the fields the
+// clients encrypt, the server has no key to check more
+func IsClientCiphertext(value string) bool {
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 || parts[0] != ClientCiphertextFormat {
+		return false
+	}
+	nonce, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil || len(nonce) != clientNonceLength {
+		return false
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(parts[2])
+	return err == nil && len(ciphertext) >= clientTagLength
+}
+
+// checkClientEncrypted checks if the fields of the struct pointer the server
+// would encrypt are empty or encrypted by the client
+func checkClientEncrypted(rawModel interface{}) error {
+	value := reflect.ValueOf(rawModel).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Tag.Get("encrypt") != "true" || value.Field(i).String() == "" {
+			continue
+		}
+		if !IsClientCiphertext(value.Field(i).String()) {
+			return fmt.Errorf("%w: %s", ErrNotClientEncrypted, field.Tag.Get("json"))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/passwall/passwall-server/model"
+)
+
+func clientCiphertext(nonceLength, ciphertextLength int) string {
+	return strings.Join([]string{
+		ClientCiphertextFormat,
+		base64.StdEncoding.EncodeToString(make([]byte, nonceLength)),
+		base64.StdEncoding.EncodeToString(make([]byte, ciphertextLength)),
+	}, ".")
+}
+
+func TestIsClientCiphertext(t *testing.T) {
+	assert.True(t, IsClientCiphertext(clientCiphertext(12, 16)))
+	assert.True(t, IsClientCiphertext(clientCiphertext(12, 40)))
+
+	assert.False(t, IsClientCiphertext("secret note"))
+	assert.False(t, IsClientCiphertext(clientCiphertext(8, 40)))
+	assert.False(t, IsClientCiphertext(clientCiphertext(12, 8)))
+	assert.False(t, IsClientCiphertext("zk2"+strings.TrimPrefix(clientCiphertext(12, 40), ClientCiphertextFormat)))
+	assert.False(t, IsClientCiphertext("zk1.not-base64.not-base64"))
+}
+
+func TestZeroKnowledgeModel(t *testing.T) {
+	defer viper.Set("server.zeroKnowledge", viper.GetBool("server.zeroKnowledge"))
+	viper.Set("server.zeroKnowledge", true)
+
+	ciphertext := clientCiphertext(12, 32)
+	note := &model.Note{Title: "Wifi", Note: ciphertext}
+	_, err := EncryptModel(nil, note, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, ciphertext, note.Note)
+
+	_, err = DecryptModel(note, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, ciphertext, note.Note)
+
+	// Empty fields are kept, plaintext ones are rejected
+	_, err = EncryptModel(nil, &model.Login{Password: ciphertext}, "user1")
+	assert.NoError(t, err)
+	_, err = EncryptModel(nil, &model.Login{Password: ciphertext, Username: "john"}, "user1")
+	assert.ErrorIs(t, err, ErrNotClientEncrypted)
+}
@@ -0,0 +1,100 @@
+package billing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/config"
+)
+
+// Billing providers
+const (
+	ProviderRevenueCat   = "revenuecat"
+	ProviderPaddle       = "paddle"
+	ProviderLemonSqueezy = "lemonsqueezy"
+)
+
+// apiTimeout limits a call to the API of the provider so a slow provider
+// doesn't hold the request
+const apiTimeout = 10 * time.Second
+
+var (
+	// ErrInvalidSignature is returned for the webhooks not signed with the secret
+	ErrInvalidSignature = errors.New("webhook signature is invalid")
+	// ErrIgnoredEvent is returned for the webhooks which aren't about subscriptions
+	ErrIgnoredEvent = errors.New("webhook event is ignored")
+)
+
+// Event is a subscription change of a customer. Checkouts pass the UUID of
+// the user as the user_uuid custom data, the email of the customer is used
+// when it is missing.
+type Event struct {
+	Name     string
+	UserUUID string
+	Email    string
+	// Active is true while the customer has access to the subscription
+	Active bool
+	// PastDue is true when the renewal payment failed and is retried
+	PastDue bool
+	// OrganizationID is the organization_id custom data of team checkouts
+	OrganizationID uint
+	// SubscriptionID and SubscriptionItem identify the seats of the
+	// subscription at the provider, Quantity is the number of seats
+	SubscriptionID   string
+	SubscriptionItem string
+	Quantity         int
+}
+
+// Provider verifies and parses the webhooks of a payment provider
+type Provider interface {
+	// Parse verifies the signature of the webhook and returns its subscription change
+	Parse(header http.Header, body []byte) (*Event, error)
+	// UpdateSeats changes the quantity of the subscription item to the seats
+	UpdateSeats(subscriptionID, item string, seats int) error
+}
+
+// New creates the webhook provider of the configuration, it is nil for
+// RevenueCat which is checked at signin instead
+func New(cfg config.BillingConfiguration) (Provider, error) {
+	switch cfg.Provider {
+	case ProviderRevenueCat:
+		return nil, nil
+	case ProviderPaddle:
+		return NewPaddle(cfg.PaddleWebhookSecret, cfg.PaddleAPIKey), nil
+	case ProviderLemonSqueezy:
+		return NewLemonSqueezy(cfg.LemonSqueezyWebhookSecret, cfg.LemonSqueezyAPIKey), nil
+	default:
+		return nil, fmt.Errorf("unknown billing provider %q, use revenuecat, paddle or lemonsqueezy", cfg.Provider)
+	}
+}
+
+// send sends the request to the API of the provider, responses out of the 2xx
+// range are errors
+func send(client *http.Client, req *http.Request) error {
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+		return fmt.Errorf("provider returned %s: %s", res.Status, body)
+	}
+	return nil
+}
+
+// organizationID parses the organization_id custom data, checkouts pass it as
+// a number or a string. It is zero for the checkouts of users.
+func organizationID(n json.Number) uint {
+	id, err := strconv.ParseUint(n.String(), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return uint(id)
+}
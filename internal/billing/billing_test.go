@@ -0,0 +1,159 @@
+package billing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/passwall/passwall-server/internal/config"
+)
+
+func sign(secret, message string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestNew(t *testing.T) {
+	provider, err := New(config.BillingConfiguration{Provider: ProviderRevenueCat})
+	assert.NoError(t, err)
+	assert.Nil(t, provider)
+
+	provider, err = New(config.BillingConfiguration{Provider: ProviderPaddle})
+	assert.NoError(t, err)
+	assert.IsType(t, &Paddle{}, provider)
+
+	_, err = New(config.BillingConfiguration{Provider: "stripe"})
+	assert.Error(t, err)
+}
+
+func TestPaddleParse(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	paddle := NewPaddle("pdl_secret", "pdl_apikey")
+	paddle.now = func() time.Time { return now }
+
+	body := `{"event_type": "subscription.activated", "data": {"status": "active", "custom_data": {"user_uuid": "f3b6c1e4"}}}`
+	header := func(ts int64, secret string) http.Header {
+		signature := sign(secret, fmt.Sprintf("%d:%s", ts, body))
+		return http.Header{"Paddle-Signature": {fmt.Sprintf("ts=%d;h1=%s", ts, signature)}}
+	}
+
+	event, err := paddle.Parse(header(now.Unix(), "pdl_secret"), []byte(body))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "subscription.activated", event.Name)
+		assert.Equal(t, "f3b6c1e4", event.UserUUID)
+		assert.True(t, event.Active)
+	}
+
+	_, err = paddle.Parse(header(now.Unix(), "other"), []byte(body))
+	assert.Equal(t, ErrInvalidSignature, err)
+
+	_, err = paddle.Parse(header(now.Add(-time.Hour).Unix(), "pdl_secret"), []byte(body))
+	assert.Equal(t, ErrInvalidSignature, err, "replayed signature")
+
+	_, err = paddle.Parse(http.Header{}, []byte(body))
+	assert.Equal(t, ErrInvalidSignature, err)
+
+	body = `{"event_type": "subscription.canceled", "data": {"status": "canceled", "custom_data": {"email": "jane@passwall.io"}}}`
+	event, err = paddle.Parse(header(now.Unix(), "pdl_secret"), []byte(body))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "jane@passwall.io", event.Email)
+		assert.False(t, event.Active)
+	}
+
+	body = `{"event_type": "subscription.past_due", "data": {"status": "past_due", "custom_data": {"user_uuid": "f3b6c1e4"}}}`
+	event, err = paddle.Parse(header(now.Unix(), "pdl_secret"), []byte(body))
+	if assert.NoError(t, err) {
+		assert.False(t, event.Active)
+		assert.True(t, event.PastDue)
+	}
+
+	body = `{"event_type": "subscription.updated", "data": {"id": "sub_01h", "status": "active",
+		"custom_data": {"user_uuid": "f3b6c1e4", "organization_id": "7"}, "items": [{"quantity": 5, "price": {"id": "pri_01h"}}]}}`
+	event, err = paddle.Parse(header(now.Unix(), "pdl_secret"), []byte(body))
+	if assert.NoError(t, err) {
+		assert.Equal(t, uint(7), event.OrganizationID)
+		assert.Equal(t, "sub_01h", event.SubscriptionID)
+		assert.Equal(t, "pri_01h", event.SubscriptionItem)
+		assert.Equal(t, 5, event.Quantity)
+	}
+
+	body = `{"event_type": "transaction.completed", "data": {}}`
+	_, err = paddle.Parse(header(now.Unix(), "pdl_secret"), []byte(body))
+	assert.Equal(t, ErrIgnoredEvent, err)
+}
+
+func TestLemonSqueezyParse(t *testing.T) {
+	lemon := NewLemonSqueezy("ls_secret", "ls_apikey")
+
+	body := `{"meta": {"event_name": "subscription_cancelled", "custom_data": {"user_uuid": "f3b6c1e4"}},
+		"data": {"attributes": {"status": "cancelled", "user_email": "jane@passwall.io"}}}`
+	event, err := lemon.Parse(http.Header{"X-Signature": {sign("ls_secret", body)}}, []byte(body))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "f3b6c1e4", event.UserUUID)
+		assert.Equal(t, "jane@passwall.io", event.Email)
+		assert.True(t, event.Active, "cancelled subscriptions last until they expire")
+	}
+
+	_, err = lemon.Parse(http.Header{"X-Signature": {sign("other", body)}}, []byte(body))
+	assert.Equal(t, ErrInvalidSignature, err)
+
+	body = `{"meta": {"event_name": "subscription_expired"}, "data": {"attributes": {"status": "expired", "user_email": "jane@passwall.io"}}}`
+	event, err = lemon.Parse(http.Header{"X-Signature": {sign("ls_secret", body)}}, []byte(body))
+	if assert.NoError(t, err) {
+		assert.False(t, event.Active)
+	}
+
+	body = `{"meta": {"event_name": "subscription_updated", "custom_data": {"organization_id": 7}},
+		"data": {"id": "1234", "attributes": {"status": "active", "first_subscription_item": {"id": 5678, "quantity": 3}}}}`
+	event, err = lemon.Parse(http.Header{"X-Signature": {sign("ls_secret", body)}}, []byte(body))
+	if assert.NoError(t, err) {
+		assert.Equal(t, uint(7), event.OrganizationID)
+		assert.Equal(t, "1234", event.SubscriptionID)
+		assert.Equal(t, "5678", event.SubscriptionItem)
+		assert.Equal(t, 3, event.Quantity)
+	}
+
+	body = `{"meta": {"event_name": "subscription_payment_success"}, "data": {}}`
+	_, err = lemon.Parse(http.Header{"X-Signature": {sign("ls_secret", body)}}, []byte(body))
+	assert.Equal(t, ErrIgnoredEvent, err)
+}
+
+func TestUpdateSeats(t *testing.T) {
+	var method, path, authorization string
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path, authorization = r.Method, r.URL.Path, r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&body)
+		if strings.HasSuffix(r.URL.Path, "/missing") {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	paddle := NewPaddle("pdl_secret", "pdl_apikey")
+	paddle.baseURL = server.URL
+	assert.NoError(t, paddle.UpdateSeats("sub_01h", "pri_01h", 8))
+	assert.Equal(t, http.MethodPatch, method)
+	assert.Equal(t, "/subscriptions/sub_01h", path)
+	assert.Equal(t, "Bearer pdl_apikey", authorization)
+	assert.Equal(t, []interface{}{map[string]interface{}{"price_id": "pri_01h", "quantity": float64(8)}}, body["items"])
+	assert.Error(t, paddle.UpdateSeats("missing", "pri_01h", 8))
+
+	lemon := NewLemonSqueezy("ls_secret", "ls_apikey")
+	lemon.baseURL = server.URL
+	assert.NoError(t, lemon.UpdateSeats("1234", "5678", 8))
+	assert.Equal(t, "/v1/subscription-items/5678", path)
+	assert.Equal(t, "Bearer ls_apikey", authorization)
+	assert.Equal(t, map[string]interface{}{"quantity": float64(8)}, body["data"].(map[string]interface{})["attributes"])
+	assert.Error(t, lemon.UpdateSeats("1234", "not-an-id", 8))
+}
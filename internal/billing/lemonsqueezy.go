@@ -0,0 +1,132 @@
+package billing
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// lemonSqueezyURL is the base URL of the Lemon Squeezy API
+const lemonSqueezyURL = "https://api.lemonsqueezy.com"
+
+// lemonSqueezyActive are the subscription statuses giving access, cancelled
+// ones are in their grace period until they expire
+var lemonSqueezyActive = map[string]bool{"on_trial": true, "active": true, "cancelled": true}
+
+// lemonSqueezyPastDue are the statuses of the subscriptions whose payment failed,
+// unpaid ones aren't retried anymore
+var lemonSqueezyPastDue = map[string]bool{"past_due": true, "unpaid": true}
+
+// LemonSqueezy parses the webhooks of Lemon Squeezy
+type LemonSqueezy struct {
+	baseURL string
+	secret  string
+	apiKey  string
+	client  *http.Client
+}
+
+// lemonSqueezyWebhook is the part of the webhook a subscription change needs
+type lemonSqueezyWebhook struct {
+	Meta struct {
+		EventName  string `json:"event_name"`
+		CustomData struct {
+			UserUUID       string      `json:"user_uuid"`
+			OrganizationID json.Number `json:"organization_id"`
+		} `json:"custom_data"`
+	} `json:"meta"`
+	Data struct {
+		ID         string `json:"id"`
+		Attributes struct {
+			Status                string `json:"status"`
+			UserEmail             string `json:"user_email"`
+			FirstSubscriptionItem *struct {
+				ID       int `json:"id"`
+				Quantity int `json:"quantity"`
+			} `json:"first_subscription_item"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// NewLemonSqueezy creates the provider verifying the webhooks with the signing
+// secret, the API key updates the seats
+func NewLemonSqueezy(secret, apiKey string) *LemonSqueezy {
+	return &LemonSqueezy{
+		baseURL: lemonSqueezyURL,
+		secret:  secret,
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: apiTimeout},
+	}
+}
+
+// Parse ...
+func (l *LemonSqueezy) Parse(header http.Header, body []byte) (*Event, error) {
+	signature, err := hex.DecodeString(header.Get("X-Signature"))
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	mac := hmac.New(sha256.New, []byte(l.secret))
+	mac.Write(body)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var webhook lemonSqueezyWebhook
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(webhook.Meta.EventName, "subscription_") ||
+		strings.HasPrefix(webhook.Meta.EventName, "subscription_payment_") {
+		return nil, ErrIgnoredEvent
+	}
+
+	event := &Event{
+		Name:           webhook.Meta.EventName,
+		UserUUID:       webhook.Meta.CustomData.UserUUID,
+		Email:          webhook.Data.Attributes.UserEmail,
+		Active:         lemonSqueezyActive[webhook.Data.Attributes.Status],
+		PastDue:        lemonSqueezyPastDue[webhook.Data.Attributes.Status],
+		OrganizationID: organizationID(webhook.Meta.CustomData.OrganizationID),
+		SubscriptionID: webhook.Data.ID,
+	}
+	// Seats are the quantity of the subscription item, not of the subscription
+	if item := webhook.Data.Attributes.FirstSubscriptionItem; item != nil {
+		event.SubscriptionItem = strconv.Itoa(item.ID)
+		event.Quantity = item.Quantity
+	}
+	return event, nil
+}
+
+// UpdateSeats changes the quantity of the subscription item, Lemon Squeezy
+// prorates it on the next invoice
+func (l *LemonSqueezy) UpdateSeats(subscriptionID, item string, seats int) error {
+	if _, err := strconv.Atoi(item); err != nil {
+		return fmt.Errorf("invalid subscription item %q of subscription %s", item, subscriptionID)
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"data": map[string]interface{}{
+			"type":       "subscription-items",
+			"id":         item,
+			"attributes": map[string]interface{}{"quantity": seats},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint := l.baseURL + "/v1/subscription-items/" + url.PathEscape(item)
+	req, err := http.NewRequest(http.MethodPatch, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.api+json")
+	req.Header.Set("Content-Type", "application/vnd.api+json")
+	req.Header.Set("Authorization", "Bearer "+l.apiKey)
+	return send(l.client, req)
+}
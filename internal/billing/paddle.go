@@ -0,0 +1,151 @@
+package billing
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// paddleURL is the base URL of the Paddle Billing API
+const paddleURL = "https://api.paddle.com"
+
+// paddleTolerance is how old a Paddle signature can be, older ones are replays
+const paddleTolerance = 5 * time.Minute
+
+// paddleActive are the subscription statuses giving access, canceled ones
+// are canceled at the end of the billing period
+var paddleActive = map[string]bool{"active": true, "trialing": true}
+
+// Paddle parses the notifications of Paddle Billing
+type Paddle struct {
+	baseURL string
+	secret  string
+	apiKey  string
+	now     func() time.Time
+	client  *http.Client
+}
+
+// paddleNotification is the part of the notification a subscription change needs
+type paddleNotification struct {
+	EventType string `json:"event_type"`
+	Data      struct {
+		ID         string `json:"id"`
+		Status     string `json:"status"`
+		CustomData struct {
+			UserUUID       string      `json:"user_uuid"`
+			Email          string      `json:"email"`
+			OrganizationID json.Number `json:"organization_id"`
+		} `json:"custom_data"`
+		Items []struct {
+			Quantity int `json:"quantity"`
+			Price    struct {
+				ID string `json:"id"`
+			} `json:"price"`
+		} `json:"items"`
+	} `json:"data"`
+}
+
+// NewPaddle creates the provider verifying the notifications with the secret
+// key of the notification destination, the API key updates the seats
+func NewPaddle(secret, apiKey string) *Paddle {
+	return &Paddle{
+		baseURL: paddleURL,
+		secret:  secret,
+		apiKey:  apiKey,
+		now:     time.Now,
+		client:  &http.Client{Timeout: apiTimeout},
+	}
+}
+
+// Parse ...
+func (p *Paddle) Parse(header http.Header, body []byte) (*Event, error) {
+	if err := p.verify(header.Get("Paddle-Signature"), body); err != nil {
+		return nil, err
+	}
+
+	var notification paddleNotification
+	if err := json.Unmarshal(body, &notification); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(notification.EventType, "subscription.") {
+		return nil, ErrIgnoredEvent
+	}
+
+	event := &Event{
+		Name:           notification.EventType,
+		UserUUID:       notification.Data.CustomData.UserUUID,
+		Email:          notification.Data.CustomData.Email,
+		Active:         paddleActive[notification.Data.Status],
+		PastDue:        notification.Data.Status == "past_due",
+		OrganizationID: organizationID(notification.Data.CustomData.OrganizationID),
+		SubscriptionID: notification.Data.ID,
+	}
+	// Team subscriptions have a single price whose quantity is the seats
+	if len(notification.Data.Items) > 0 {
+		event.SubscriptionItem = notification.Data.Items[0].Price.ID
+		event.Quantity = notification.Data.Items[0].Quantity
+	}
+	return event, nil
+}
+
+// UpdateSeats replaces the items of the subscription with the price of the
+// seats, the change is prorated right away
+func (p *Paddle) UpdateSeats(subscriptionID, item string, seats int) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"items":                  []map[string]interface{}{{"price_id": item, "quantity": seats}},
+		"proration_billing_mode": "prorated_immediately",
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint := p.baseURL + "/subscriptions/" + url.PathEscape(subscriptionID)
+	req, err := http.NewRequest(http.MethodPatch, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	return send(p.client, req)
+}
+
+// verify checks the ts=...;h1=... signature, an HMAC of the timestamp and the body
+func (p *Paddle) verify(signature string, body []byte) error {
+	var timestamp string
+	var hashes []string
+	for _, part := range strings.Split(signature, ";") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "ts":
+			timestamp = value
+		case "h1":
+			hashes = append(hashes, value)
+		}
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(hashes) == 0 {
+		return ErrInvalidSignature
+	}
+	if age := p.now().Sub(time.Unix(seconds, 0)); age > paddleTolerance || age < -paddleTolerance {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(p.secret))
+	mac.Write([]byte(timestamp + ":"))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+	for _, hash := range hashes {
+		if decoded, err := hex.DecodeString(hash); err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
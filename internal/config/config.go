@@ -5,6 +5,8 @@ import (
 	"encoding/base64"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/spf13/viper"
 )
@@ -13,32 +15,95 @@ var (
 	configuration *Configuration
 	configFileExt = ".yml"
 	configType    = "yaml"
+
+	// keys are the configuration keys in the order of their defaults
+	keys []string
+
+	// camelBoundary matches the word boundaries of the camel case keys
+	camelBoundary = regexp.MustCompile(`([a-z0-9])([A-Z])`)
 )
 
+// envPrefix is the prefix of the environment variables of the configuration
+const envPrefix = "PW"
+
+// envSections are the short names of the sections in the environment variables
+var envSections = map[string]string{
+	"database": "DB",
+}
+
+// envAliases are the older names of the environment variables, they are still
+// accepted when the prefixed name isn't set
+var envAliases = map[string][]string{
+	"server.env":       {"PW_ENV"},
+	"server.port":      {"PORT"},
+	"server.domain":    {"DOMAIN"},
+	"database.logmode": {"PW_DB_LOG_MODE"},
+	"database.sslmode": {"PW_DB_SSL_MODE"},
+}
+
 // Configuration ...
 type Configuration struct {
 	Server   ServerConfiguration
 	Database DatabaseConfiguration
 	Email    EmailConfiguration
+	HIBP     HIBPConfiguration
+	Cache    CacheConfiguration
+	Audit    AuditConfiguration
+	GeoIP    GeoIPConfiguration
+	Anomaly  AnomalyConfiguration
+	SMS      SMSConfiguration
+	Billing  BillingConfiguration
+	License  LicenseConfiguration
+	Purge    PurgeConfiguration
+	KMS      KMSConfiguration
+	HSM      HSMConfiguration
+	Tokens   TokensConfiguration
+	// Plans are the quotas of the subscription types, types without a plan are unlimited
+	Plans map[string]PlanConfiguration
+	// Regions are the databases organizations can be pinned to for data residency
+	Regions map[string]DatabaseConfiguration
 }
 
 // ServerConfiguration is the required parameters to set up a server
 type ServerConfiguration struct {
-	Env                        string `default:"dev"` // dev, prod
-	Port                       string `default:"3625"`
-	Domain                     string `default:"https://vault.passwall.io"`
-	Dir                        string `default:"/app/config"`
-	Passphrase                 string `default:"passphrase-for-encrypting-passwords-do-not-forget"`
-	Secret                     string `default:"secret-key-for-JWT-TOKEN"`
-	Timeout                    int    `default:"24"`
-	GeneratedPasswordLength    int    `default:"16"`
-	AccessTokenExpireDuration  string `default:"30m"`
-	RefreshTokenExpireDuration string `default:"15d"`
-	APIKey                     string `default:"my-secret-api-key"`
+	Env                        string   `default:"dev"` // dev, prod
+	Port                       string   `default:"3625"`
+	Domain                     string   `default:"https://vault.passwall.io"`
+	Dir                        string   `default:"/app/config"`
+	Passphrase                 string   `default:"passphrase-for-encrypting-passwords-do-not-forget"`
+	Secret                     string   `default:"secret-key-for-JWT-TOKEN"`
+	IndexKey                   string   // key of the blind search indexes, derived from the passphrase when empty
+	ExportSigningKey           string   // base64 Ed25519 seed signing the exports, derived from the secret when empty
+	ExportTrustedKeys          []string // base64 Ed25519 public keys of the other exports the restores accept
+	Timeout                    int      `default:"24"`
+	GeneratedPasswordLength    int      `default:"16"`
+	AccessTokenExpireDuration  string   `default:"30m"`
+	RefreshTokenExpireDuration string   `default:"15d"`
+	APIKey                     string   `default:"my-secret-api-key"`
+	DeletionGracePeriod        int      `default:"30"`
+	DeletionReminderDays       int      `default:"3"`
+	PasswordMaxAge             int      `default:"365"`
+	BatchLimit                 int      `default:"500"`
+	StepUpWindow               int      `default:"5"`
+	LogLevel                   string   `default:"info"` // debug, info, warn, error
+	RateLimit                  int      `default:"5"`    // requests per second of an IP
+	CORSOrigins                []string // empty allows every origin
+	ReadHeaderTimeout          int      `default:"5"`        // seconds
+	IdleTimeout                int      `default:"60"`       // seconds
+	MaxBodySize                int64    `default:"1048576"`  // bytes of the JSON bodies
+	MaxImportSize              int64    `default:"33554432"` // bytes of the imported files
+	CompressMinSize            int      `default:"1024"`     // bytes of the smallest gzipped response
+	DebugAddr                  string   // address of the pprof listener, empty disables it
+	WebClient                  bool     `default:"true"` // serve the embedded web client from /
+	WebAPIURL                  string   // API URL of the web client, empty uses its origin
+	Maintenance                bool     // reject the changes with 503 while backing up or migrating
+	ZeroKnowledge              bool     // accept only the item fields encrypted by the clients
 }
 
 // DatabaseConfiguration is the required parameters to set up a DB instance
 type DatabaseConfiguration struct {
+	Driver   string `default:"postgres"` // postgres, sqlite
+	Path     string `default:"store/passwall.db"`
 	Name     string `default:"passwall"`
 	Username string `default:"user"`
 	Password string `default:"password"`
@@ -46,16 +111,169 @@ type DatabaseConfiguration struct {
 	Port     string `default:"5432"`
 	LogMode  bool   `default:"false"`
 	SSLMode  string `default:"disable"`
+	Tenancy  string `default:"schema"` // schema, row (Postgres only), regions use the one of the main database
+
+	// Connection pool of Postgres, zero keeps the default of database/sql
+	MaxOpenConns     int `default:"20"` // connections open at once, more queries wait for one
+	MaxIdleConns     int `default:"10"` // connections kept open between the queries
+	ConnMaxLifetime  int `default:"30"` // minutes a connection is used before it is reopened
+	ConnMaxIdleTime  int `default:"5"`  // minutes an idle connection is kept
+	StatementTimeout int `default:"0"`  // seconds a statement can run before Postgres cancels it
+
+	// Read-only replica of Postgres serving the list, search and sync queries
+	Replica    string // DSN of the replica, empty reads from the primary
+	ReplicaLag int    `default:"5"` // seconds the replica may be behind the primary
+}
+
+// CacheConfiguration is the required parameters to connect to the cache
+type CacheConfiguration struct {
+	Driver   string `default:"memory"` // memory, redis
+	Address  string `default:"localhost:6379"`
+	Password string `default:""`
+	DB       int    `default:"0"`
+	UserTTL  int    `default:"30"` // seconds the users of the tokens are cached, 0 finds them in the database on every request
 }
 
 // EmailConfiguration is the required parameters to send emails
 type EmailConfiguration struct {
-	Host     string `default:"smtp.passwall.io"`
-	Port     string `default:"25"`
-	Username string `default:"hello@passwall.io"`
-	Password string `default:"password"`
-	From     string `default:"hello@passwall.io"`
-	Admin    string `default:"hello@passwall.io"`
+	Host         string `default:"smtp.passwall.io"`
+	Port         string `default:"25"`
+	Username     string `default:"hello@passwall.io"`
+	Password     string `default:"password"`
+	FromName     string `default:"Passwall"`
+	FromEmail    string `default:"hello@passwall.io"`
+	Admin        string `default:"hello@passwall.io"`
+	APIKey       string `default:"apiKey"`
+	TemplatesDir string `default:""`     // templates overriding the built in ones with the same name
+	Encryption   string `default:"auto"` // auto, starttls, tls or none
+	Auth         string `default:"auto"` // auto, plain, login or cram-md5
+	HeloName     string `default:""`
+	PoolSize     int    `default:"2"` // idle connections kept open, 0 opens one for every email
+	DKIMKeyFile  string `default:""`  // PEM encoded RSA or Ed25519 key, emails aren't signed when empty
+	DKIMSelector string `default:""`
+	DKIMDomain   string `default:""` // domain of fromEmail when empty
+}
+
+// AuditConfiguration is the required parameters to stream the audit events to a SIEM
+type AuditConfiguration struct {
+	Sinks              []string // syslog, file, https
+	SyslogNetwork      string   `default:"udp"` // udp, tcp
+	SyslogAddress      string   `default:""`
+	FilePath           string   `default:"audit.jsonl"`
+	HTTPSURL           string   `default:""`
+	HTTPSAuthorization string   `default:""` // Authorization header like "Splunk <token>"
+}
+
+// GeoIPConfiguration is the required parameters to locate the IPs of the security events
+type GeoIPConfiguration struct {
+	DBPath string `default:""` // MaxMind or GeoLite2 City database, empty disables GeoIP
+}
+
+// AnomalyConfiguration is the required parameters to find the suspicious patterns in the audit events
+type AnomalyConfiguration struct {
+	Enabled        bool `default:"true"`
+	Window         int  `default:"10"`    // minutes the exports and deletions are counted in
+	MaxExports     int  `default:"5"`     // exports in the window raising an alert
+	MaxDeletions   int  `default:"50"`    // deletions in the window raising an alert
+	TravelSpeed    int  `default:"900"`   // km/h between two signins raising an alert, needs GeoIP
+	RevokeSessions bool `default:"false"` // sign the user out of every session on an alert
+	ForceChange    bool `default:"false"` // ask the user to change the master password on an alert
+	FailedSignins  int  `default:"20"`    // failed signins in an hour shown as a spike in the activity feed
+}
+
+// SMSConfiguration is the required parameters to send the verification codes by SMS
+type SMSConfiguration struct {
+	Provider         string `default:""` // twilio or vonage, empty disables SMS
+	From             string `default:""` // sender number or alphanumeric sender ID
+	TwilioAccountSID string `default:""`
+	TwilioAuthToken  string `default:""`
+	VonageAPIKey     string `default:""`
+	VonageAPISecret  string `default:""`
+}
+
+// BillingConfiguration is the required parameters to follow the subscriptions of the users
+type BillingConfiguration struct {
+	Provider                  string `default:"revenuecat"` // revenuecat, paddle or lemonsqueezy
+	PaddleWebhookSecret       string `default:""`
+	PaddleAPIKey              string `default:""` // updates the seats of team subscriptions
+	LemonSqueezyWebhookSecret string `default:""`
+	LemonSqueezyAPIKey        string `default:""`  // updates the seats of team subscriptions
+	TrialDays                 int    `default:"0"` // days of pro features new users get, 0 disables trials
+	TrialReminderDays         int    `default:"3"` // days before the end of a trial the reminder is sent
+	GraceDays                 int    `default:"7"` // days lapsed subscriptions keep the pro features as past due
+}
+
+// LicenseConfiguration is the offline license of self-hosted servers, the key
+// is read from the file when it isn't given
+type LicenseConfiguration struct {
+	Key  string `default:""`
+	File string `default:""`
+}
+
+// KMSConfiguration is the key management service the passphrase is unwrapped
+// with at startup instead of being configured in plaintext
+type KMSConfiguration struct {
+	Provider           string `default:""` // aws, gcp, azure, vault or pkcs11, empty uses server.passphrase
+	KeyID              string `default:""` // AWS key ARN, GCP key name, Azure key URL, Vault transit key name or HSM key label
+	WrappedFile        string `default:"passphrase.kms"`
+	RewrapInterval     int    `default:"24"` // hours between the checks of the key rotations, 0 only checks at startup
+	AWSRegion          string `default:""`
+	AWSAccessKeyID     string `default:""`
+	AWSSecretAccessKey string `default:""`
+	AWSSessionToken    string `default:""`
+	GCPCredentialsFile string `default:""` // service account key, empty uses the service account of the instance
+	AzureTenantID      string `default:""`
+	AzureClientID      string `default:""`
+	AzureClientSecret  string `default:""`
+	VaultAddress       string `default:""`
+	VaultToken         string `default:""`
+	VaultMount         string `default:"transit"`
+}
+
+// HSMConfiguration is the PKCS#11 token keeping the JWT signing key and the
+// key of the pkcs11 KMS provider, the keys never leave it
+type HSMConfiguration struct {
+	Library    string `default:""` // path of the PKCS#11 library, empty doesn't use a token
+	TokenLabel string `default:""`
+	PIN        string `default:""`
+	SigningKey string `default:""` // label of the P-256 key pair signing the JWTs, empty uses server.secret
+}
+
+// TokensConfiguration is the token lifetimes of the client types the users
+// sign in with, like longer ones for the mobile apps than for the web
+type TokensConfiguration struct {
+	Extension ClientTokenConfiguration
+	Web       ClientTokenConfiguration
+	Mobile    ClientTokenConfiguration
+	API       ClientTokenConfiguration
+	// TrustedDeviceExpireDuration is how long the devices remembered at a
+	// signin skip the two-factor code
+	TrustedDeviceExpireDuration string `default:"30d"`
+}
+
+// ClientTokenConfiguration is the token lifetimes of a client type, empty ones
+// use the lifetimes of the server section
+type ClientTokenConfiguration struct {
+	AccessTokenExpireDuration  string `default:""`
+	RefreshTokenExpireDuration string `default:""`
+}
+
+// PurgeConfiguration is the days the deleted records are kept for the admins
+// to restore before they are purged
+type PurgeConfiguration struct {
+	RetentionDays int            `default:"30"`
+	Tables        map[string]int // retention days of the tables overriding retentionDays
+}
+
+// PlanConfiguration is the quotas of a subscription type, zero is unlimited
+type PlanConfiguration struct {
+	MaxItems  int `default:"0"` // items in a vault
+	MaxShares int `default:"0"` // items in the collections of an organization
+}
+
+// HIBPConfiguration is the required parameters to use Have I Been Pwned APIs
+type HIBPConfiguration struct {
+	APIKey string `default:""`
 }
 
 // Init initializes the configuration manager
@@ -63,35 +281,44 @@ func Init(configPath, configName string) (*Configuration, error) {
 
 	configFilePath := filepath.Join(configPath, configName) + configFileExt
 
-	// initialize viper configuration
-	initializeConfig(configPath, configName)
+	v := viper.GetViper()
 
-	// Bind environment variables
-	bindEnvs()
+	// initialize viper configuration
+	initializeConfig(v, configPath, configName)
 
 	// Set default values
-	setDefaults()
+	setDefaults(v)
+
+	// Bind environment variables
+	bindEnvs(v)
 
 	// Read or create configuration file
-	if err := readConfiguration(configFilePath); err != nil {
+	if err := readConfiguration(v, configFilePath); err != nil {
 		return nil, err
 	}
 
 	// Auto read env variables
-	viper.AutomaticEnv()
+	v.AutomaticEnv()
 
 	// Unmarshal config file to struct
-	if err := viper.Unmarshal(&configuration); err != nil {
+	if err := v.Unmarshal(&configuration); err != nil {
+		return nil, err
+	}
+
+	// Refuse to start with a configuration which can't work
+	if err := Validate(configuration); err != nil {
 		return nil, err
 	}
 
+	configDir, configFile = configPath, configName
+	current.Store(configuration)
 	return configuration, nil
 }
 
 // read configuration from file
-func readConfiguration(configFilePath string) error {
-	err := viper.ReadInConfig() // Find and read the config file
-	if err != nil {             // Handle errors reading the config file
+func readConfiguration(v *viper.Viper, configFilePath string) error {
+	err := v.ReadInConfig() // Find and read the config file
+	if err != nil {         // Handle errors reading the config file
 		// if file does not exist, simply create one
 		if _, err := os.Stat(configFilePath); os.IsNotExist(err) {
 			os.Create(configFilePath)
@@ -99,7 +326,7 @@ func readConfiguration(configFilePath string) error {
 			return err
 		}
 		// let's write defaults
-		if err := viper.WriteConfig(); err != nil {
+		if err := v.WriteConfig(); err != nil {
 			return err
 		}
 	}
@@ -107,78 +334,212 @@ func readConfiguration(configFilePath string) error {
 }
 
 // initialize the configuration manager
-func initializeConfig(configPath, configName string) {
-	viper.AddConfigPath(configPath)
-	viper.SetConfigName(configName)
-	viper.SetConfigType(configType)
+func initializeConfig(v *viper.Viper, configPath, configName string) {
+	v.AddConfigPath(configPath)
+	v.SetConfigName(configName)
+	v.SetConfigType(configType)
 }
 
-func bindEnvs() {
-	viper.BindEnv("server.env", "PW_ENV")
-	viper.BindEnv("server.port", "PORT")
-	viper.BindEnv("server.domain", "DOMAIN")
-	viper.BindEnv("server.passphrase", "PW_SERVER_PASSPHRASE")
-	viper.BindEnv("server.secret", "PW_SERVER_SECRET")
-	viper.BindEnv("server.timeout", "PW_SERVER_TIMEOUT")
-
-	viper.BindEnv("server.generatedPasswordLength", "PW_SERVER_GENERATED_PASSWORD_LENGTH")
-	viper.BindEnv("server.accessTokenExpireDuration", "PW_SERVER_ACCESS_TOKEN_EXPIRE_DURATION")
-	viper.BindEnv("server.refreshTokenExpireDuration", "PW_SERVER_REFRESH_TOKEN_EXPIRE_DURATION")
-
-	viper.BindEnv("server.apiKey", "PW_SERVER_API_KEY")
-
-	viper.BindEnv("database.name", "PW_DB_NAME")
-	viper.BindEnv("database.username", "PW_DB_USERNAME")
-	viper.BindEnv("database.password", "PW_DB_PASSWORD")
-	viper.BindEnv("database.host", "PW_DB_HOST")
-	viper.BindEnv("database.port", "PW_DB_PORT")
-	viper.BindEnv("database.logmode", "PW_DB_LOG_MODE")
+// bindEnvs binds every key to its environment variable and the older names of it
+func bindEnvs(v *viper.Viper) {
+	for _, key := range keys {
+		v.BindEnv(append([]string{key}, EnvNames(key)...)...)
+	}
+}
 
-	// "require", "verify-full", "verify-ca", "disable" supported for postgres
-	viper.BindEnv("database.sslmode", "PW_DB_SSL_MODE")
+// EnvNames returns the environment variables of the key, the first one set is used
+func EnvNames(key string) []string {
+	return append([]string{EnvName(key)}, envAliases[key]...)
+}
 
-	viper.BindEnv("email.host", "PW_EMAIL_HOST")
-	viper.BindEnv("email.port", "PW_EMAIL_PORT")
-	viper.BindEnv("email.username", "PW_EMAIL_USERNAME")
-	viper.BindEnv("email.password", "PW_EMAIL_PASSWORD")
-	viper.BindEnv("email.fromEmail", "PW_EMAIL_FROM_EMAIL")
-	viper.BindEnv("email.fromName", "PW_EMAIL_FROM_NAME")
-	viper.BindEnv("email.apiKey", "PW_EMAIL_API_KEY")
+// EnvName returns the environment variable of the key, the prefix and the
+// path of the key in snake case like PW_SERVER_LOG_LEVEL for server.logLevel
+func EnvName(key string) string {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		if short, ok := envSections[part]; ok && i == 0 {
+			parts[i] = short
+			continue
+		}
+		parts[i] = strings.ToUpper(camelBoundary.ReplaceAllString(part, "${1}_${2}"))
+	}
+	return envPrefix + "_" + strings.Join(parts, "_")
 }
 
-func setDefaults() {
+func setDefaults(v *viper.Viper) {
+	keys = nil
 
 	// Server defaults
-	viper.SetDefault("server.env", "prod")
-	viper.SetDefault("server.port", "3625")
-	viper.SetDefault("server.domain", "https://vault.passwall.io")
-	viper.SetDefault("server.passphrase", generateKey())
-	viper.SetDefault("server.secret", generateKey())
-	viper.SetDefault("server.timeout", 24)
-	viper.SetDefault("server.generatedPasswordLength", 16)
-	viper.SetDefault("server.accessTokenExpireDuration", "30m")
-	viper.SetDefault("server.refreshTokenExpireDuration", "15d")
-	viper.SetDefault("server.apiKey", generateKey())
+	setDefault(v, "server.env", "prod")
+	setDefault(v, "server.port", "3625")
+	setDefault(v, "server.domain", "https://vault.passwall.io")
+	setDefault(v, "server.passphrase", generateKey())
+	setDefault(v, "server.secret", generateKey())
+	setDefault(v, "server.indexKey", "")
+	setDefault(v, "server.exportSigningKey", "")
+	setDefault(v, "server.exportTrustedKeys", []string{})
+	setDefault(v, "server.timeout", 24)
+	setDefault(v, "server.generatedPasswordLength", 16)
+	setDefault(v, "server.accessTokenExpireDuration", "30m")
+	setDefault(v, "server.refreshTokenExpireDuration", "15d")
+	setDefault(v, "server.apiKey", generateKey())
+	setDefault(v, "server.deletionGracePeriod", 30)
+	setDefault(v, "server.deletionReminderDays", 3)
+	setDefault(v, "server.passwordMaxAge", 365)
+	setDefault(v, "server.batchLimit", 500)
+	setDefault(v, "server.stepUpWindow", 5)
+	setDefault(v, "server.logLevel", "info")
+	setDefault(v, "server.rateLimit", 5)
+	setDefault(v, "server.corsOrigins", []string{})
+	setDefault(v, "server.readHeaderTimeout", 5)
+	setDefault(v, "server.idleTimeout", 60)
+	setDefault(v, "server.maxBodySize", 1<<20)
+	setDefault(v, "server.maxImportSize", 32<<20)
+	setDefault(v, "server.compressMinSize", 1024)
+	setDefault(v, "server.debugAddr", "")
+	setDefault(v, "server.webClient", true)
+	setDefault(v, "server.webApiUrl", "")
+	setDefault(v, "server.maintenance", false)
+	setDefault(v, "server.zeroKnowledge", false)
 
 	// Database defaults
-	viper.SetDefault("database.name", "passwall")
-	viper.SetDefault("database.username", "postgres")
-	viper.SetDefault("database.password", "password")
-	viper.SetDefault("database.host", "localhost")
-	viper.SetDefault("database.port", "5432")
-	viper.SetDefault("database.logmode", false)
+	setDefault(v, "database.driver", "postgres")
+	setDefault(v, "database.path", "store/passwall.db")
+	setDefault(v, "database.name", "passwall")
+	setDefault(v, "database.username", "postgres")
+	setDefault(v, "database.password", "password")
+	setDefault(v, "database.host", "localhost")
+	setDefault(v, "database.port", "5432")
+	setDefault(v, "database.logmode", false)
 
 	// "require", "verify-full", "verify-ca", "disable" supported for postgres
-	viper.SetDefault("database.sslmode", "disable")
+	setDefault(v, "database.sslmode", "disable")
+
+	// Vaults are Postgres schemas or rows of shared tables, see the -copy-to-rows flag
+	setDefault(v, "database.tenancy", "schema")
+
+	// Connection pool defaults, a connection is reopened every half an hour so
+	// managed Postgres can move it
+	setDefault(v, "database.maxOpenConns", 20)
+	setDefault(v, "database.maxIdleConns", 10)
+	setDefault(v, "database.connMaxLifetime", 30)
+	setDefault(v, "database.connMaxIdleTime", 5)
+	setDefault(v, "database.statementTimeout", 0)
+	setDefault(v, "database.replicaLag", 5)
 
 	// Email defaults
-	viper.SetDefault("email.host", "smtp.passwall.io")
-	viper.SetDefault("email.port", "25")
-	viper.SetDefault("email.username", "hello@passwall.io")
-	viper.SetDefault("email.password", "password")
-	viper.SetDefault("email.fromName", "Passwall")
-	viper.SetDefault("email.fromEmail", "hello@passwall.io")
-	viper.SetDefault("email.apiKey", "apiKey")
+	setDefault(v, "email.host", "smtp.passwall.io")
+	setDefault(v, "email.port", "25")
+	setDefault(v, "email.username", "hello@passwall.io")
+	setDefault(v, "email.password", "password")
+	setDefault(v, "email.fromName", "Passwall")
+	setDefault(v, "email.fromEmail", "hello@passwall.io")
+	setDefault(v, "email.apiKey", "apiKey")
+	setDefault(v, "email.templatesDir", "")
+	setDefault(v, "email.encryption", "auto")
+	setDefault(v, "email.auth", "auto")
+	setDefault(v, "email.heloName", "")
+	setDefault(v, "email.poolSize", 2)
+	setDefault(v, "email.dkimKeyFile", "")
+	setDefault(v, "email.dkimSelector", "")
+	setDefault(v, "email.dkimDomain", "")
+
+	// HIBP defaults, breach monitoring is disabled without an API key
+	setDefault(v, "hibp.apiKey", "")
+
+	// Cache defaults, memory cache works for a single server instance only
+	setDefault(v, "cache.driver", "memory")
+	setDefault(v, "cache.address", "localhost:6379")
+	setDefault(v, "cache.password", "")
+	setDefault(v, "cache.db", 0)
+	setDefault(v, "cache.userTTL", 30)
+
+	// Audit defaults, events are only kept in the database without sinks
+	setDefault(v, "audit.sinks", []string{})
+	setDefault(v, "audit.syslogNetwork", "udp")
+	setDefault(v, "audit.syslogAddress", "")
+	setDefault(v, "audit.filePath", "audit.jsonl")
+	setDefault(v, "audit.httpsUrl", "")
+	setDefault(v, "audit.httpsAuthorization", "")
+
+	// GeoIP defaults, events aren't located without a database
+	setDefault(v, "geoip.dbPath", "")
+
+	// Anomaly defaults, alerts are raised but sessions are kept
+	setDefault(v, "anomaly.enabled", true)
+	setDefault(v, "anomaly.window", 10)
+	setDefault(v, "anomaly.maxExports", 5)
+	setDefault(v, "anomaly.maxDeletions", 50)
+	setDefault(v, "anomaly.travelSpeed", 900)
+	setDefault(v, "anomaly.revokeSessions", false)
+	setDefault(v, "anomaly.forceChange", false)
+	setDefault(v, "anomaly.failedSignins", 20)
+
+	// SMS defaults, codes are only sent by email without a provider
+	setDefault(v, "sms.provider", "")
+	setDefault(v, "sms.from", "")
+	setDefault(v, "sms.twilioAccountSid", "")
+	setDefault(v, "sms.twilioAuthToken", "")
+	setDefault(v, "sms.vonageApiKey", "")
+	setDefault(v, "sms.vonageApiSecret", "")
+
+	// Billing defaults, subscriptions are checked with RevenueCat at signin
+	setDefault(v, "billing.provider", "revenuecat")
+	setDefault(v, "billing.paddleWebhookSecret", "")
+	setDefault(v, "billing.paddleApiKey", "")
+	setDefault(v, "billing.lemonSqueezyWebhookSecret", "")
+	setDefault(v, "billing.lemonSqueezyApiKey", "")
+	setDefault(v, "billing.trialDays", 0)
+	setDefault(v, "billing.trialReminderDays", 3)
+	setDefault(v, "billing.graceDays", 7)
+
+	// License defaults, servers without a license check the subscriptions
+	setDefault(v, "license.key", "")
+	setDefault(v, "license.file", "")
+
+	// Purge defaults, deleted records are kept for a month
+	setDefault(v, "purge.retentionDays", 30)
+
+	// KMS defaults, the passphrase is configured in plaintext without a provider
+	setDefault(v, "kms.provider", "")
+	setDefault(v, "kms.keyId", "")
+	setDefault(v, "kms.wrappedFile", "passphrase.kms")
+	setDefault(v, "kms.rewrapInterval", 24)
+	setDefault(v, "kms.awsRegion", "")
+	setDefault(v, "kms.awsAccessKeyId", "")
+	setDefault(v, "kms.awsSecretAccessKey", "")
+	setDefault(v, "kms.awsSessionToken", "")
+	setDefault(v, "kms.gcpCredentialsFile", "")
+	setDefault(v, "kms.azureTenantId", "")
+	setDefault(v, "kms.azureClientId", "")
+	setDefault(v, "kms.azureClientSecret", "")
+	setDefault(v, "kms.vaultAddress", "")
+	setDefault(v, "kms.vaultToken", "")
+	setDefault(v, "kms.vaultMount", "transit")
+
+	// HSM defaults, no token is used without a library
+	setDefault(v, "hsm.library", "")
+	setDefault(v, "hsm.tokenLabel", "")
+	setDefault(v, "hsm.pin", "")
+	setDefault(v, "hsm.signingKey", "")
+
+	// Token defaults, the client types use the lifetimes of the server section
+	setDefault(v, "tokens.extension.accessTokenExpireDuration", "")
+	setDefault(v, "tokens.extension.refreshTokenExpireDuration", "")
+	setDefault(v, "tokens.web.accessTokenExpireDuration", "")
+	setDefault(v, "tokens.web.refreshTokenExpireDuration", "")
+	setDefault(v, "tokens.mobile.accessTokenExpireDuration", "")
+	setDefault(v, "tokens.mobile.refreshTokenExpireDuration", "")
+	setDefault(v, "tokens.api.accessTokenExpireDuration", "")
+	setDefault(v, "tokens.api.refreshTokenExpireDuration", "")
+	setDefault(v, "tokens.trustedDeviceExpireDuration", "30d")
+}
+
+// setDefault sets the default value of the key and registers the key for the
+// environment variables and the report
+func setDefault(v *viper.Viper, key string, value interface{}) {
+	keys = append(keys, key)
+	v.SetDefault(key, value)
 }
 
 func generateKey() string {
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+var (
+	// current is the configuration in use, Reload replaces it
+	current atomic.Pointer[Configuration]
+
+	// configDir and configFile are the location of the file given to Init
+	configDir, configFile string
+)
+
+// Current returns the configuration in use. Settings which can be reloaded
+// should be read from it instead of viper, viper isn't safe to change while
+// the requests read it.
+func Current() *Configuration {
+	if cfg := current.Load(); cfg != nil {
+		return cfg
+	}
+	return &Configuration{}
+}
+
+// Reload reads the file and the environment variables again and applies the
+// settings which are safe to change while running: log level, rate limit, CORS
+// origins, maintenance mode and email. The other settings need a restart.
+func Reload() (*Configuration, error) {
+	v := viper.New()
+	initializeConfig(v, configDir, configFile)
+	setDefaults(v)
+	bindEnvs(v)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	v.AutomaticEnv()
+
+	var loaded *Configuration
+	if err := v.Unmarshal(&loaded); err != nil {
+		return nil, err
+	}
+	if err := Validate(loaded); err != nil {
+		return nil, err
+	}
+
+	next := *Current()
+	next.Server.LogLevel = loaded.Server.LogLevel
+	next.Server.RateLimit = loaded.Server.RateLimit
+	next.Server.CORSOrigins = loaded.Server.CORSOrigins
+	next.Server.Maintenance = loaded.Server.Maintenance
+	next.Email = loaded.Email
+	current.Store(&next)
+	return &next, nil
+}
+
+// Watch reloads the configuration when the file changes or the process gets
+// SIGHUP. Results are given to onReload, errors keep the current configuration.
+func Watch(onReload func(*Configuration, error)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	// Editors replace the file instead of writing it, the directory is watched
+	// so the new file is seen too
+	if err := watcher.Add(configDir); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	file := filepath.Clean(filepath.Join(configDir, configFile) + configFileExt)
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != file || !(event.Has(fsnotify.Write) || event.Has(fsnotify.Create)) {
+					continue
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				onReload(nil, err)
+				continue
+			case <-hup:
+			}
+			onReload(Reload())
+		}
+	}()
+	return nil
+}
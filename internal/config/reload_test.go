@@ -0,0 +1,40 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yml")
+	write := func(content string) {
+		require.NoError(t, os.WriteFile(file, []byte(content), 0600))
+	}
+
+	write("server:\n  port: \"3625\"\n  logLevel: info\n  rateLimit: 5\n")
+	_, err := Init(dir, "config")
+	require.NoError(t, err)
+
+	write("server:\n  port: \"4000\"\n  logLevel: debug\n  rateLimit: 20\n  maintenance: true\n  corsOrigins:\n    - https://vault.passwall.io\n")
+	cfg, err := Reload()
+	require.NoError(t, err)
+
+	assert.Equal(t, "debug", cfg.Server.LogLevel)
+	assert.Equal(t, 20, cfg.Server.RateLimit)
+	assert.Equal(t, []string{"https://vault.passwall.io"}, cfg.Server.CORSOrigins)
+	assert.True(t, cfg.Server.Maintenance)
+	// Port needs a restart
+	assert.Equal(t, "3625", cfg.Server.Port)
+	assert.Equal(t, cfg, Current())
+
+	// Invalid files keep the current configuration
+	write("server:\n  logLevel: loud\n")
+	_, err = Reload()
+	assert.Error(t, err)
+	assert.Equal(t, "debug", Current().Server.LogLevel)
+}
@@ -0,0 +1,326 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// redacted replaces the values of the secret keys in the report
+const redacted = "[REDACTED]"
+
+// tokenDuration matches the token durations like 30m and 15d
+var tokenDuration = regexp.MustCompile(`^[0-9]+[smhd]$`)
+
+// secretKeys are the last parts of the keys having secret values
+var secretKeys = map[string]bool{
+	"secret":     true,
+	"passphrase": true,
+	"indexkey":   true,
+	// Seed of the key signing the exports
+	"exportsigningkey": true,
+	"password":         true,
+	"apikey":           true,
+	// Authorization headers have the tokens of the SIEM endpoints
+	"httpsauthorization": true,
+	"twilioauthtoken":    true,
+	"vonageapisecret":    true,
+	// Billing webhooks are signed with these
+	"paddlewebhooksecret":       true,
+	"lemonsqueezywebhooksecret": true,
+	"paddleapikey":              true,
+	"lemonsqueezyapikey":        true,
+	// License keys are bound to their licensee
+	"key": true,
+	// Credentials of the key management services
+	"awssecretaccesskey": true,
+	"awssessiontoken":    true,
+	"azureclientsecret":  true,
+	"vaulttoken":         true,
+	// PIN of the PKCS#11 token
+	"pin": true,
+}
+
+// Validate checks the configuration and returns all the problems of it at once
+func Validate(cfg *Configuration) error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	server := cfg.Server
+	check(server.Secret != "", "server.secret is required, set %s", EnvName("server.secret"))
+	check(server.Passphrase != "", "server.passphrase is required, set %s", EnvName("server.passphrase"))
+	port, err := strconv.Atoi(server.Port)
+	check(err == nil && port > 0 && port < 65536, "server.port %q is not a valid port", server.Port)
+	check(tokenDuration.MatchString(server.AccessTokenExpireDuration),
+		"server.accessTokenExpireDuration %q is invalid, use a number with s, m, h or d like 30m", server.AccessTokenExpireDuration)
+	check(tokenDuration.MatchString(server.RefreshTokenExpireDuration),
+		"server.refreshTokenExpireDuration %q is invalid, use a number with s, m, h or d like 15d", server.RefreshTokenExpireDuration)
+	clients := map[string]ClientTokenConfiguration{
+		"extension": cfg.Tokens.Extension, "web": cfg.Tokens.Web, "mobile": cfg.Tokens.Mobile, "api": cfg.Tokens.API,
+	}
+	for name, client := range clients {
+		check(client.AccessTokenExpireDuration == "" || tokenDuration.MatchString(client.AccessTokenExpireDuration),
+			"tokens.%s.accessTokenExpireDuration %q is invalid, use a number with s, m, h or d like 30m", name, client.AccessTokenExpireDuration)
+		check(client.RefreshTokenExpireDuration == "" || tokenDuration.MatchString(client.RefreshTokenExpireDuration),
+			"tokens.%s.refreshTokenExpireDuration %q is invalid, use a number with s, m, h or d like 15d", name, client.RefreshTokenExpireDuration)
+	}
+	check(tokenDuration.MatchString(cfg.Tokens.TrustedDeviceExpireDuration),
+		"tokens.trustedDeviceExpireDuration %q is invalid, use a number with s, m, h or d like 30d", cfg.Tokens.TrustedDeviceExpireDuration)
+	check(validLogLevel(server.LogLevel), "server.logLevel %q is invalid, use debug, info, warn or error", server.LogLevel)
+	check(server.Timeout > 0, "server.timeout must be greater than 0")
+	check(server.ReadHeaderTimeout > 0, "server.readHeaderTimeout must be greater than 0")
+	check(server.IdleTimeout > 0, "server.idleTimeout must be greater than 0")
+	check(server.MaxBodySize > 0, "server.maxBodySize must be greater than 0")
+	check(server.MaxImportSize >= server.MaxBodySize, "server.maxImportSize must be at least server.maxBodySize")
+	check(server.GeneratedPasswordLength > 0, "server.generatedPasswordLength must be greater than 0")
+	check(server.BatchLimit > 0, "server.batchLimit must be greater than 0")
+	check(server.RateLimit > 0, "server.rateLimit must be greater than 0")
+	if server.ExportSigningKey != "" {
+		seed, err := base64.StdEncoding.DecodeString(server.ExportSigningKey)
+		check(err == nil && len(seed) == ed25519.SeedSize, "server.exportSigningKey must be a base64 %d bytes seed", ed25519.SeedSize)
+	}
+	for _, key := range server.ExportTrustedKeys {
+		public, err := base64.StdEncoding.DecodeString(key)
+		check(err == nil && len(public) == ed25519.PublicKeySize, "server.exportTrustedKeys has invalid key %q, use base64 Ed25519 public keys", key)
+	}
+
+	errs = append(errs, validateDatabase("database", cfg.Database)...)
+	for name, region := range cfg.Regions {
+		errs = append(errs, validateDatabase("regions."+name, region)...)
+	}
+
+	switch cfg.Cache.Driver {
+	case "memory":
+	case "redis":
+		check(cfg.Cache.Address != "", "cache.address is required for the redis cache")
+	default:
+		check(false, "cache.driver %q is invalid, use memory or redis", cfg.Cache.Driver)
+	}
+	check(cfg.Cache.UserTTL >= 0, "cache.userTTL must not be negative")
+
+	for _, sink := range cfg.Audit.Sinks {
+		switch sink {
+		case "syslog":
+			check(cfg.Audit.SyslogAddress != "", "audit.syslogAddress is required for the syslog sink")
+			check(cfg.Audit.SyslogNetwork == "udp" || cfg.Audit.SyslogNetwork == "tcp",
+				"audit.syslogNetwork %q is invalid, use udp or tcp", cfg.Audit.SyslogNetwork)
+		case "file":
+			check(cfg.Audit.FilePath != "", "audit.filePath is required for the file sink")
+		case "https":
+			check(strings.HasPrefix(cfg.Audit.HTTPSURL, "https://"), "audit.httpsUrl must be an https URL for the https sink")
+		default:
+			check(false, "audit.sinks has unknown sink %q, use syslog, file or https", sink)
+		}
+	}
+
+	if anomaly := cfg.Anomaly; anomaly.Enabled {
+		check(anomaly.Window > 0, "anomaly.window must be greater than 0")
+		check(anomaly.MaxExports > 0, "anomaly.maxExports must be greater than 0")
+		check(anomaly.MaxDeletions > 0, "anomaly.maxDeletions must be greater than 0")
+		check(anomaly.TravelSpeed > 0, "anomaly.travelSpeed must be greater than 0")
+		check(anomaly.FailedSignins > 0, "anomaly.failedSignins must be greater than 0")
+	}
+
+	email := cfg.Email
+	port, err = strconv.Atoi(email.Port)
+	check(err == nil && port > 0 && port < 65536, "email.port %q is not a valid port", email.Port)
+	check(oneOf(email.Encryption, "auto", "starttls", "tls", "none"),
+		"email.encryption %q is invalid, use auto, starttls, tls or none", email.Encryption)
+	check(oneOf(email.Auth, "auto", "plain", "login", "cram-md5"),
+		"email.auth %q is invalid, use auto, plain, login or cram-md5", email.Auth)
+	check(email.PoolSize >= 0, "email.poolSize can't be negative")
+	if email.DKIMKeyFile != "" {
+		_, err := os.Stat(email.DKIMKeyFile)
+		check(err == nil, "email.dkimKeyFile %q can't be read: %v", email.DKIMKeyFile, err)
+		check(email.DKIMSelector != "", "email.dkimSelector is required for DKIM signing")
+	}
+
+	if dir := cfg.Email.TemplatesDir; dir != "" {
+		info, err := os.Stat(dir)
+		check(err == nil && info.IsDir(), "email.templatesDir %q isn't a directory", dir)
+	}
+
+	switch sms := cfg.SMS; sms.Provider {
+	case "":
+	case "twilio":
+		check(sms.TwilioAccountSID != "" && sms.TwilioAuthToken != "", "sms.twilioAccountSid and sms.twilioAuthToken are required for twilio")
+		check(sms.From != "", "sms.from is required for SMS")
+	case "vonage":
+		check(sms.VonageAPIKey != "" && sms.VonageAPISecret != "", "sms.vonageApiKey and sms.vonageApiSecret are required for vonage")
+		check(sms.From != "", "sms.from is required for SMS")
+	default:
+		check(false, "sms.provider %q is invalid, use twilio or vonage", sms.Provider)
+	}
+
+	switch billing := cfg.Billing; billing.Provider {
+	case "revenuecat":
+	case "paddle":
+		check(billing.PaddleWebhookSecret != "", "billing.paddleWebhookSecret is required for paddle")
+	case "lemonsqueezy":
+		check(billing.LemonSqueezyWebhookSecret != "", "billing.lemonSqueezyWebhookSecret is required for lemonsqueezy")
+	default:
+		check(false, "billing.provider %q is invalid, use revenuecat, paddle or lemonsqueezy", billing.Provider)
+	}
+	check(cfg.Billing.TrialDays >= 0, "billing.trialDays must not be negative")
+	check(cfg.Billing.TrialReminderDays >= 0, "billing.trialReminderDays must not be negative")
+	check(cfg.Billing.GraceDays >= 0, "billing.graceDays must not be negative")
+
+	check(cfg.Purge.RetentionDays >= 0, "purge.retentionDays must not be negative")
+	for table, days := range cfg.Purge.Tables {
+		check(days >= 0, "purge.tables.%s must not be negative", table)
+	}
+
+	switch kms := cfg.KMS; kms.Provider {
+	case "":
+	case "aws":
+		check(kms.AWSRegion != "", "kms.awsRegion is required for aws")
+		check(kms.AWSAccessKeyID != "" && kms.AWSSecretAccessKey != "", "kms.awsAccessKeyId and kms.awsSecretAccessKey are required for aws")
+	case "gcp":
+	case "azure":
+		check(strings.HasPrefix(kms.KeyID, "https://"), "kms.keyId must be the URL of the key for azure")
+		check(kms.AzureTenantID != "" && kms.AzureClientID != "" && kms.AzureClientSecret != "",
+			"kms.azureTenantId, kms.azureClientId and kms.azureClientSecret are required for azure")
+	case "vault":
+		check(kms.VaultAddress != "" && kms.VaultToken != "", "kms.vaultAddress and kms.vaultToken are required for vault")
+	case "pkcs11":
+		check(cfg.HSM.Library != "", "hsm.library is required for pkcs11")
+	default:
+		check(false, "kms.provider %q is invalid, use aws, gcp, azure, vault or pkcs11", kms.Provider)
+	}
+	if cfg.HSM.Library != "" {
+		check(cfg.HSM.TokenLabel != "", "hsm.tokenLabel is required with hsm.library")
+	}
+	if cfg.HSM.SigningKey != "" {
+		check(cfg.HSM.Library != "", "hsm.library is required for hsm.signingKey")
+	}
+	if cfg.KMS.Provider != "" {
+		check(cfg.KMS.KeyID != "", "kms.keyId is required for %s", cfg.KMS.Provider)
+		check(cfg.KMS.WrappedFile != "", "kms.wrappedFile is required for %s", cfg.KMS.Provider)
+		check(cfg.KMS.RewrapInterval >= 0, "kms.rewrapInterval must not be negative")
+	}
+
+	for name, plan := range cfg.Plans {
+		check(plan.MaxItems >= 0 && plan.MaxShares >= 0, "plans.%s quotas must not be negative", name)
+	}
+
+	if cfg.GeoIP.DBPath != "" {
+		_, err := os.Stat(cfg.GeoIP.DBPath)
+		check(err == nil, "geoip.dbPath %q can't be read: %v", cfg.GeoIP.DBPath, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateDatabase checks the database configuration of the key
+func validateDatabase(key string, db DatabaseConfiguration) []error {
+	var errs []error
+	switch db.Driver {
+	case "", "postgres":
+		if db.Host == "" {
+			errs = append(errs, fmt.Errorf("%s.host is required for postgres", key))
+		}
+		if db.Name == "" {
+			errs = append(errs, fmt.Errorf("%s.name is required for postgres", key))
+		}
+	case "sqlite":
+		if db.Path == "" {
+			errs = append(errs, fmt.Errorf("%s.path is required for sqlite", key))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%s.driver %q is invalid, use postgres or sqlite", key, db.Driver))
+	}
+	if db.MaxOpenConns < 0 || db.MaxIdleConns < 0 || db.ConnMaxLifetime < 0 || db.ConnMaxIdleTime < 0 || db.StatementTimeout < 0 {
+		errs = append(errs, fmt.Errorf("%s connection pool settings must not be negative", key))
+	}
+	if db.MaxOpenConns > 0 && db.MaxIdleConns > db.MaxOpenConns {
+		errs = append(errs, fmt.Errorf("%s.maxIdleConns can't be more than %s.maxOpenConns", key, key))
+	}
+	if db.Replica != "" && db.Driver != "postgres" {
+		errs = append(errs, fmt.Errorf("%s.replica needs postgres", key))
+	}
+	if db.ReplicaLag < 0 {
+		errs = append(errs, fmt.Errorf("%s.replicaLag must not be negative", key))
+	}
+	return errs
+}
+
+func validLogLevel(level string) bool {
+	switch strings.ToLower(level) {
+	case "debug", "info", "warn", "warning", "error":
+		return true
+	}
+	return false
+}
+
+// oneOf checks if the value is one of the allowed values
+func oneOf(value string, allowed ...string) bool {
+	for _, v := range allowed {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Report returns the effective configuration, one line per key with its value
+// and where the value comes from. Values of the secret keys are redacted.
+func Report() []string {
+	lines := []string{}
+	known := map[string]bool{}
+	for _, key := range keys {
+		known[strings.ToLower(key)] = true
+		lines = append(lines, reportLine(key, source(key, EnvNames(key))))
+	}
+
+	// Keys only in the file like the regions have no environment variables
+	extra := []string{}
+	for _, key := range viper.AllKeys() {
+		if !known[key] {
+			extra = append(extra, key)
+		}
+	}
+	sort.Strings(extra)
+	for _, key := range extra {
+		lines = append(lines, reportLine(key, source(key, nil)))
+	}
+	return lines
+}
+
+func reportLine(key, source string) string {
+	return fmt.Sprintf("%s = %s (%s)", key, reportValue(key, fmt.Sprint(viper.Get(key))), source)
+}
+
+// reportValue redacts the value of the secret keys, empty values are shown
+// since a missing secret is worth seeing
+func reportValue(key, value string) string {
+	parts := strings.Split(key, ".")
+	if value != "" && secretKeys[strings.ToLower(parts[len(parts)-1])] {
+		return redacted
+	}
+	return strconv.Quote(value)
+}
+
+// source returns where the value of the key comes from
+func source(key string, envNames []string) string {
+	for _, name := range envNames {
+		if _, ok := os.LookupEnv(name); ok {
+			return "env " + name
+		}
+	}
+	if viper.InConfig(key) {
+		return "file"
+	}
+	return "default"
+}
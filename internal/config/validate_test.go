@@ -0,0 +1,160 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validConfiguration() *Configuration {
+	return &Configuration{
+		Server: ServerConfiguration{
+			Port:                       "3625",
+			Passphrase:                 "passphrase",
+			Secret:                     "secret",
+			Timeout:                    24,
+			GeneratedPasswordLength:    16,
+			AccessTokenExpireDuration:  "30m",
+			RefreshTokenExpireDuration: "15d",
+			BatchLimit:                 500,
+			RateLimit:                  5,
+			LogLevel:                   "info",
+			ReadHeaderTimeout:          5,
+			IdleTimeout:                60,
+			MaxBodySize:                1 << 20,
+			MaxImportSize:              32 << 20,
+		},
+		Database: DatabaseConfiguration{Driver: "postgres", Host: "localhost", Name: "passwall"},
+		Cache:    CacheConfiguration{Driver: "memory"},
+		Email:    EmailConfiguration{Port: "25", Encryption: "auto", Auth: "auto", PoolSize: 2},
+		Billing:  BillingConfiguration{Provider: "revenuecat"},
+		Tokens:   TokensConfiguration{TrustedDeviceExpireDuration: "30d"},
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{"server.secret", "PW_SERVER_SECRET"},
+		{"server.logLevel", "PW_SERVER_LOG_LEVEL"},
+		{"server.apiKey", "PW_SERVER_API_KEY"},
+		{"server.accessTokenExpireDuration", "PW_SERVER_ACCESS_TOKEN_EXPIRE_DURATION"},
+		{"database.name", "PW_DB_NAME"},
+		{"database.sslmode", "PW_DB_SSLMODE"},
+		{"database.connMaxLifetime", "PW_DB_CONN_MAX_LIFETIME"},
+		{"database.replicaLag", "PW_DB_REPLICA_LAG"},
+		{"email.fromEmail", "PW_EMAIL_FROM_EMAIL"},
+		{"hibp.apiKey", "PW_HIBP_API_KEY"},
+		{"geoip.dbPath", "PW_GEOIP_DB_PATH"},
+		{"kms.awsAccessKeyId", "PW_KMS_AWS_ACCESS_KEY_ID"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, EnvName(test.key), test.key)
+	}
+}
+
+func TestEnvNames(t *testing.T) {
+	assert.Equal(t, []string{"PW_SERVER_PORT", "PORT"}, EnvNames("server.port"))
+	assert.Equal(t, []string{"PW_DB_LOGMODE", "PW_DB_LOG_MODE"}, EnvNames("database.logmode"))
+	assert.Equal(t, []string{"PW_SERVER_SECRET"}, EnvNames("server.secret"))
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(validConfiguration()))
+
+	tests := []struct {
+		name     string
+		change   func(cfg *Configuration)
+		expected string
+	}{
+		{"empty secret", func(cfg *Configuration) { cfg.Server.Secret = "" }, "server.secret is required"},
+		{"empty passphrase", func(cfg *Configuration) { cfg.Server.Passphrase = "" }, "server.passphrase is required"},
+		{"invalid port", func(cfg *Configuration) { cfg.Server.Port = "70000" }, "server.port"},
+		{"invalid duration", func(cfg *Configuration) { cfg.Server.AccessTokenExpireDuration = "30" }, "server.accessTokenExpireDuration"},
+		{"invalid client token duration", func(cfg *Configuration) { cfg.Tokens.Mobile.RefreshTokenExpireDuration = "90" }, "tokens.mobile.refreshTokenExpireDuration"},
+		{"invalid trusted device duration", func(cfg *Configuration) { cfg.Tokens.TrustedDeviceExpireDuration = "" }, "tokens.trustedDeviceExpireDuration"},
+		{"invalid log level", func(cfg *Configuration) { cfg.Server.LogLevel = "loud" }, "server.logLevel"},
+		{"short export signing key", func(cfg *Configuration) { cfg.Server.ExportSigningKey = "c2VlZA==" }, "server.exportSigningKey"},
+		{"invalid trusted export key", func(cfg *Configuration) { cfg.Server.ExportTrustedKeys = []string{"not-a-key"} }, "server.exportTrustedKeys"},
+		{"small import size", func(cfg *Configuration) { cfg.Server.MaxImportSize = 1024 }, "server.maxImportSize"},
+		{"unknown driver", func(cfg *Configuration) { cfg.Database.Driver = "mysql" }, "database.driver"},
+		{"sqlite without path", func(cfg *Configuration) { cfg.Database = DatabaseConfiguration{Driver: "sqlite"} }, "database.path"},
+		{"region without host", func(cfg *Configuration) {
+			cfg.Regions = map[string]DatabaseConfiguration{"eu": {Name: "passwall"}}
+		}, "regions.eu.host"},
+		{"unknown audit sink", func(cfg *Configuration) { cfg.Audit.Sinks = []string{"kafka"} }, "audit.sinks"},
+		{"audit endpoint without https", func(cfg *Configuration) {
+			cfg.Audit = AuditConfiguration{Sinks: []string{"https"}, HTTPSURL: "http://siem"}
+		}, "audit.httpsUrl"},
+		{"invalid email port", func(cfg *Configuration) { cfg.Email.Port = "smtp" }, "email.port"},
+		{"unknown email encryption", func(cfg *Configuration) { cfg.Email.Encryption = "ssl" }, "email.encryption"},
+		{"unknown email auth", func(cfg *Configuration) { cfg.Email.Auth = "xoauth2" }, "email.auth"},
+		{"dkim without selector", func(cfg *Configuration) { cfg.Email.DKIMKeyFile = "validate_test.go" }, "email.dkimSelector"},
+		{"missing email templates", func(cfg *Configuration) { cfg.Email.TemplatesDir = "missing/templates" }, "email.templatesDir"},
+		{"unknown sms provider", func(cfg *Configuration) { cfg.SMS.Provider = "sns" }, "sms.provider"},
+		{"twilio without token", func(cfg *Configuration) {
+			cfg.SMS = SMSConfiguration{Provider: "twilio", From: "+15005550006", TwilioAccountSID: "AC123"}
+		}, "sms.twilioAuthToken"},
+		{"unknown billing provider", func(cfg *Configuration) { cfg.Billing.Provider = "stripe" }, "billing.provider"},
+		{"paddle without secret", func(cfg *Configuration) { cfg.Billing.Provider = "paddle" }, "billing.paddleWebhookSecret"},
+		{"negative trial", func(cfg *Configuration) { cfg.Billing.TrialDays = -1 }, "billing.trialDays"},
+		{"negative plan quota", func(cfg *Configuration) {
+			cfg.Plans = map[string]PlanConfiguration{"free": {MaxItems: -1}}
+		}, "plans.free"},
+		{"idle connections over the open ones", func(cfg *Configuration) {
+			cfg.Database.MaxOpenConns, cfg.Database.MaxIdleConns = 5, 10
+		}, "database.maxIdleConns"},
+		{"sqlite replica", func(cfg *Configuration) {
+			cfg.Database.Driver, cfg.Database.Path, cfg.Database.Replica = "sqlite", "passwall.db", "host=replica"
+		}, "database.replica"},
+		{"negative table retention", func(cfg *Configuration) { cfg.Purge.Tables = map[string]int{"logins": -1} }, "purge.tables.logins"},
+		{"missing geoip database", func(cfg *Configuration) { cfg.GeoIP.DBPath = "missing/GeoLite2-City.mmdb" }, "geoip.dbPath"},
+		{"anomaly without window", func(cfg *Configuration) { cfg.Anomaly = AnomalyConfiguration{Enabled: true} }, "anomaly.window"},
+		{"redis without address", func(cfg *Configuration) { cfg.Cache.Driver = "redis" }, "cache.address"},
+		{"unknown kms provider", func(cfg *Configuration) { cfg.KMS = KMSConfiguration{Provider: "hsm", KeyID: "key"} }, "kms.provider"},
+		{"kms without key", func(cfg *Configuration) {
+			cfg.KMS = KMSConfiguration{Provider: "vault", VaultAddress: "https://vault:8200", VaultToken: "token", WrappedFile: "passphrase.kms"}
+		}, "kms.keyId"},
+		{"azure key without url", func(cfg *Configuration) {
+			cfg.KMS = KMSConfiguration{Provider: "azure", KeyID: "passwall", WrappedFile: "passphrase.kms", AzureTenantID: "t", AzureClientID: "c", AzureClientSecret: "s"}
+		}, "kms.keyId must be the URL"},
+		{"pkcs11 without library", func(cfg *Configuration) {
+			cfg.KMS = KMSConfiguration{Provider: "pkcs11", KeyID: "passphrase", WrappedFile: "passphrase.kms"}
+		}, "hsm.library is required for pkcs11"},
+		{"hsm without token label", func(cfg *Configuration) { cfg.HSM = HSMConfiguration{Library: "/usr/lib/softhsm/libsofthsm2.so"} }, "hsm.tokenLabel"},
+		{"signing key without library", func(cfg *Configuration) { cfg.HSM = HSMConfiguration{SigningKey: "jwt"} }, "hsm.library is required for hsm.signingKey"},
+		{"negative user cache ttl", func(cfg *Configuration) { cfg.Cache.UserTTL = -1 }, "cache.userTTL"},
+	}
+	for _, test := range tests {
+		cfg := validConfiguration()
+		test.change(cfg)
+		err := Validate(cfg)
+		if assert.Error(t, err, test.name) {
+			assert.Contains(t, err.Error(), test.expected, test.name)
+		}
+	}
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	cfg := validConfiguration()
+	cfg.Server.Secret = ""
+	cfg.Cache.Driver = "disk"
+
+	err := Validate(cfg)
+	assert.Contains(t, err.Error(), "server.secret")
+	assert.Contains(t, err.Error(), "cache.driver")
+}
+
+func TestReportValue(t *testing.T) {
+	assert.Equal(t, redacted, reportValue("server.secret", "s3cr3t"))
+	assert.Equal(t, redacted, reportValue("database.password", "s3cr3t"))
+	assert.Equal(t, redacted, reportValue("hibp.apiKey", "s3cr3t"))
+	assert.Equal(t, redacted, reportValue("regions.eu.password", "s3cr3t"))
+	assert.Equal(t, redacted, reportValue("sms.twilioAuthToken", "s3cr3t"))
+	assert.Equal(t, redacted, reportValue("kms.vaultToken", "s3cr3t"))
+	assert.Equal(t, redacted, reportValue("hsm.pin", "1234"))
+	assert.Equal(t, `""`, reportValue("cache.password", ""))
+	assert.Equal(t, `"3625"`, reportValue("server.port", "3625"))
+}
@@ -0,0 +1,56 @@
+package geoip
+
+import (
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// Reader finds the location of the IP addresses in a MaxMind or GeoLite2 City database
+type Reader struct {
+	db *geoip2.Reader
+}
+
+// Open opens the database file, it is read once and kept in memory
+func Open(path string) (*Reader, error) {
+	db, err := geoip2.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{db: db}, nil
+}
+
+// Locate returns the ISO country code and the English city name of the IP,
+// they are empty for private addresses and the ones missing in the database
+func (r *Reader) Locate(ip string) (country, city string) {
+	addr := net.ParseIP(ip)
+	if addr == nil || addr.IsLoopback() || addr.IsPrivate() {
+		return "", ""
+	}
+
+	record, err := r.db.City(addr)
+	if err != nil {
+		return "", ""
+	}
+	return record.Country.IsoCode, record.City.Names["en"]
+}
+
+// Coordinates returns the latitude and the longitude of the IP, ok is false
+// when the database doesn't know where it is
+func (r *Reader) Coordinates(ip string) (latitude, longitude float64, ok bool) {
+	addr := net.ParseIP(ip)
+	if addr == nil || addr.IsLoopback() || addr.IsPrivate() {
+		return 0, 0, false
+	}
+
+	record, err := r.db.City(addr)
+	if err != nil || record.Location.AccuracyRadius == 0 {
+		return 0, 0, false
+	}
+	return record.Location.Latitude, record.Location.Longitude, true
+}
+
+// Close releases the database
+func (r *Reader) Close() error {
+	return r.db.Close()
+}
@@ -0,0 +1,229 @@
+// Package hsm keeps the JWT signing key and the key wrapping the passphrase in
+// a hardware security module or a smartcard, used through its PKCS#11 library.
+// The keys never leave the token, the signatures and the encryptions are done
+// by it. PKCS#11 needs cgo, servers are built with the pkcs11 tag for it.
+package hsm
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/passwall/passwall-server/internal/config"
+)
+
+// gcmNonceLength is the length of the AES-GCM nonces the ciphertexts start with
+const gcmNonceLength = 12
+
+var (
+	// ErrNotSupported represents message for servers built without PKCS#11
+	ErrNotSupported = errors.New("server is built without PKCS#11 support, build it with -tags pkcs11")
+	// ErrKeyNotFound represents message for keys which aren't on the token
+	ErrKeyNotFound = errors.New("key is not found on the token")
+	// ErrInvalidCiphertext represents message for ciphertexts which are too short
+	ErrInvalidCiphertext = errors.New("ciphertext is invalid")
+)
+
+// Object classes of the keys
+const (
+	classPublicKey  = 2
+	classPrivateKey = 3
+	classSecretKey  = 4
+)
+
+// device is a logged in session of the token, its calls are serialized by the Token
+type device interface {
+	// find returns the handle of the object of the class with the label
+	find(class uint, label string) (uint, error)
+	// ecPoint returns the DER encoded CKA_EC_POINT of the public key
+	ecPoint(key uint) ([]byte, error)
+	// signECDSA signs the digest with the private key, the signature is r || s
+	signECDSA(key uint, digest []byte) ([]byte, error)
+	// encryptGCM and decryptGCM encrypt and decrypt with the secret AES key
+	encryptGCM(key uint, nonce, plaintext []byte) ([]byte, error)
+	decryptGCM(key uint, nonce, ciphertext []byte) ([]byte, error)
+}
+
+// Token is a logged in token of a PKCS#11 library
+type Token struct {
+	mu     sync.Mutex
+	device device
+}
+
+// tokens are the opened tokens by library and label, a library is initialized once
+var tokens = struct {
+	sync.Mutex
+	opened map[string]*Token
+}{opened: map[string]*Token{}}
+
+// Open logs in to the configured token, it is nil when no library is
+// configured. The token is opened once and shared by its users.
+func Open(cfg config.HSMConfiguration) (*Token, error) {
+	if cfg.Library == "" {
+		return nil, nil
+	}
+
+	tokens.Lock()
+	defer tokens.Unlock()
+	name := cfg.Library + "\x00" + cfg.TokenLabel
+	if token, ok := tokens.opened[name]; ok {
+		return token, nil
+	}
+	device, err := openDevice(cfg.Library, cfg.TokenLabel, cfg.PIN)
+	if err != nil {
+		return nil, fmt.Errorf("pkcs11 %s: %w", cfg.Library, err)
+	}
+	token := &Token{device: device}
+	tokens.opened[name] = token
+	return token, nil
+}
+
+// SigningKey finds the P-256 key pair of the label signing the JWTs
+func (t *Token) SigningKey(label string) (*SigningKey, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	private, err := t.device.find(classPrivateKey, label)
+	if err != nil {
+		return nil, fmt.Errorf("private key %s: %w", label, err)
+	}
+	public, err := t.device.find(classPublicKey, label)
+	if err != nil {
+		return nil, fmt.Errorf("public key %s: %w", label, err)
+	}
+	point, err := t.device.ecPoint(public)
+	if err != nil {
+		return nil, fmt.Errorf("public key %s: %w", label, err)
+	}
+	publicKey, err := parseECPoint(point)
+	if err != nil {
+		return nil, fmt.Errorf("public key %s: %w", label, err)
+	}
+	return &SigningKey{token: t, handle: private, public: publicKey}, nil
+}
+
+// EncryptionKey finds the AES key of the label wrapping the passphrase
+func (t *Token) EncryptionKey(label string) (*EncryptionKey, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	handle, err := t.device.find(classSecretKey, label)
+	if err != nil {
+		return nil, fmt.Errorf("secret key %s: %w", label, err)
+	}
+	return &EncryptionKey{token: t, handle: handle}, nil
+}
+
+// parseECPoint parses the uncompressed P-256 point of CKA_EC_POINT, which is
+// an OCTET STRING, some libraries return it without one
+func parseECPoint(point []byte) (*ecdsa.PublicKey, error) {
+	var raw []byte
+	if rest, err := asn1.Unmarshal(point, &raw); err != nil || len(rest) > 0 {
+		raw = point
+	}
+	x, y := elliptic.Unmarshal(elliptic.P256(), raw)
+	if x == nil {
+		return nil, errors.New("only uncompressed P-256 keys are supported")
+	}
+	return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+}
+
+// SigningKey is the private key of the token signing the JWTs with ES256
+type SigningKey struct {
+	token  *Token
+	handle uint
+	public *ecdsa.PublicKey
+}
+
+// Public returns the public key verifying the signatures
+func (k *SigningKey) Public() *ecdsa.PublicKey {
+	return k.public
+}
+
+// sign signs the SHA-256 of the message on the token
+func (k *SigningKey) sign(message []byte) ([]byte, error) {
+	digest := sha256.Sum256(message)
+	k.token.mu.Lock()
+	defer k.token.mu.Unlock()
+	return k.token.device.signECDSA(k.handle, digest[:])
+}
+
+// SigningMethodES256 signs the JWTs with a SigningKey of a token, the
+// signatures are verified with its public key like other ES256 tokens
+var SigningMethodES256 = &signingMethod{}
+
+type signingMethod struct{}
+
+// Alg ...
+func (m *signingMethod) Alg() string {
+	return jwt.SigningMethodES256.Alg()
+}
+
+// Sign ...
+func (m *signingMethod) Sign(signingString string, key interface{}) (string, error) {
+	signingKey, ok := key.(*SigningKey)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+	signature, err := signingKey.sign([]byte(signingString))
+	if err != nil {
+		return "", err
+	}
+	return jwt.EncodeSegment(signature), nil
+}
+
+// Verify ...
+func (m *signingMethod) Verify(signingString, signature string, key interface{}) error {
+	if signingKey, ok := key.(*SigningKey); ok {
+		key = signingKey.public
+	}
+	return jwt.SigningMethodES256.Verify(signingString, signature, key)
+}
+
+// EncryptionKey is the AES key of the token wrapping the passphrase, it is a
+// key management service of a key without versions
+type EncryptionKey struct {
+	token  *Token
+	handle uint
+}
+
+// Encrypt encrypts with AES-GCM on the token, the ciphertext starts with the nonce
+func (k *EncryptionKey) Encrypt(plaintext []byte) ([]byte, string, error) {
+	nonce := make([]byte, gcmNonceLength)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, "", err
+	}
+
+	k.token.mu.Lock()
+	defer k.token.mu.Unlock()
+	ciphertext, err := k.token.device.encryptGCM(k.handle, nonce, plaintext)
+	if err != nil {
+		return nil, "", err
+	}
+	return append(nonce, ciphertext...), "1", nil
+}
+
+// Decrypt ...
+func (k *EncryptionKey) Decrypt(ciphertext []byte, version string) ([]byte, error) {
+	if len(ciphertext) < gcmNonceLength {
+		return nil, ErrInvalidCiphertext
+	}
+
+	k.token.mu.Lock()
+	defer k.token.mu.Unlock()
+	return k.token.device.decryptGCM(k.handle, ciphertext[:gcmNonceLength], ciphertext[gcmNonceLength:])
+}
+
+// KeyVersion returns the only version of the key, keys of a token are
+// replaced with new labels instead of being rotated
+func (k *EncryptionKey) KeyVersion() (string, error) {
+	return "1", nil
+}
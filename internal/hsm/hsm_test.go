@@ -0,0 +1,144 @@
+package hsm
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/asn1"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/passwall/passwall-server/internal/config"
+)
+
+// fakeDevice does the operations of the token in software
+type fakeDevice struct {
+	ecKey  *ecdsa.PrivateKey
+	aesKey []byte
+}
+
+func newFakeDevice(t *testing.T) *fakeDevice {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	aesKey := make([]byte, 32)
+	_, err = rand.Read(aesKey)
+	require.NoError(t, err)
+	return &fakeDevice{ecKey: ecKey, aesKey: aesKey}
+}
+
+func (d *fakeDevice) find(class uint, label string) (uint, error) {
+	if label != "passwall" {
+		return 0, ErrKeyNotFound
+	}
+	return class, nil
+}
+
+func (d *fakeDevice) ecPoint(key uint) ([]byte, error) {
+	return asn1.Marshal(elliptic.Marshal(elliptic.P256(), d.ecKey.X, d.ecKey.Y))
+}
+
+func (d *fakeDevice) signECDSA(key uint, digest []byte) ([]byte, error) {
+	r, s, err := ecdsa.Sign(rand.Reader, d.ecKey, digest)
+	if err != nil {
+		return nil, err
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return signature, nil
+}
+
+func (d *fakeDevice) gcm() cipher.AEAD {
+	block, _ := aes.NewCipher(d.aesKey)
+	gcm, _ := cipher.NewGCM(block)
+	return gcm
+}
+
+func (d *fakeDevice) encryptGCM(key uint, nonce, plaintext []byte) ([]byte, error) {
+	return d.gcm().Seal(nil, nonce, plaintext, nil), nil
+}
+
+func (d *fakeDevice) decryptGCM(key uint, nonce, ciphertext []byte) ([]byte, error) {
+	return d.gcm().Open(nil, nonce, ciphertext, nil)
+}
+
+func TestOpen(t *testing.T) {
+	token, err := Open(config.HSMConfiguration{})
+	assert.NoError(t, err)
+	assert.Nil(t, token)
+
+	_, err = Open(config.HSMConfiguration{Library: "/nonexistent/libpkcs11.so", TokenLabel: "passwall"})
+	assert.Error(t, err)
+}
+
+func TestSigningKey(t *testing.T) {
+	device := newFakeDevice(t)
+	token := &Token{device: device}
+
+	_, err := token.SigningKey("another")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	key, err := token.SigningKey("passwall")
+	require.NoError(t, err)
+	assert.True(t, key.Public().Equal(&device.ecKey.PublicKey))
+
+	signed, err := jwt.NewWithClaims(SigningMethodES256, jwt.MapClaims{
+		"user_uuid": "uuid",
+		"exp":       time.Now().Add(time.Minute).Unix(),
+	}).SignedString(key)
+	require.NoError(t, err)
+
+	// Tokens are verified like other ES256 tokens with the public key
+	parsed, err := jwt.Parse(signed, func(token *jwt.Token) (interface{}, error) {
+		return key.Public(), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ES256", parsed.Method.Alg())
+	assert.Equal(t, "uuid", parsed.Claims.(jwt.MapClaims)["user_uuid"])
+
+	// Other keys don't sign with the method
+	_, err = jwt.NewWithClaims(SigningMethodES256, jwt.MapClaims{}).SignedString([]byte("secret"))
+	assert.ErrorIs(t, err, jwt.ErrInvalidKeyType)
+}
+
+func TestEncryptionKey(t *testing.T) {
+	token := &Token{device: newFakeDevice(t)}
+	key, err := token.EncryptionKey("passwall")
+	require.NoError(t, err)
+
+	ciphertext, version, err := key.Encrypt([]byte("passphrase"))
+	require.NoError(t, err)
+	assert.Equal(t, "1", version)
+	assert.NotContains(t, string(ciphertext), "passphrase")
+
+	plaintext, err := key.Decrypt(ciphertext, version)
+	require.NoError(t, err)
+	assert.Equal(t, "passphrase", string(plaintext))
+
+	_, err = key.Decrypt(ciphertext[:gcmNonceLength-1], version)
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+}
+
+func TestParseECPoint(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	raw := elliptic.Marshal(elliptic.P256(), ecKey.X, ecKey.Y)
+	wrapped, err := asn1.Marshal(raw)
+	require.NoError(t, err)
+
+	// Points are accepted with and without the OCTET STRING
+	for _, point := range [][]byte{wrapped, raw} {
+		public, err := parseECPoint(point)
+		require.NoError(t, err)
+		assert.True(t, public.Equal(&ecKey.PublicKey))
+	}
+
+	_, err = parseECPoint([]byte{0x04, 0x01})
+	assert.Error(t, err)
+}
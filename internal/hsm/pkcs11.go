@@ -0,0 +1,325 @@
+//go:build pkcs11 && cgo && unix
+
+package hsm
+
+/*
+#cgo linux LDFLAGS: -ldl
+#include <dlfcn.h>
+#include <stdlib.h>
+#include <string.h>
+
+// The types and functions of PKCS#11 v2.40 the server uses, the functions are
+// found in the library by their names
+
+typedef unsigned long CK_ULONG;
+typedef unsigned char CK_BYTE;
+typedef CK_ULONG CK_RV;
+
+typedef struct { CK_ULONG type; void *pValue; CK_ULONG ulValueLen; } CK_ATTRIBUTE;
+typedef struct { CK_ULONG mechanism; void *pParameter; CK_ULONG ulParameterLen; } CK_MECHANISM;
+typedef struct { CK_BYTE *pIv; CK_ULONG ulIvLen; CK_ULONG ulIvBits; CK_BYTE *pAAD; CK_ULONG ulAADLen; CK_ULONG ulTagBits; } CK_GCM_PARAMS;
+typedef struct { CK_BYTE major; CK_BYTE minor; } CK_VERSION;
+typedef struct {
+	CK_BYTE label[32];
+	CK_BYTE manufacturerID[32];
+	CK_BYTE model[16];
+	CK_BYTE serialNumber[16];
+	CK_ULONG flags;
+	CK_ULONG ulMaxSessionCount;
+	CK_ULONG ulSessionCount;
+	CK_ULONG ulMaxRwSessionCount;
+	CK_ULONG ulRwSessionCount;
+	CK_ULONG ulMaxPinLen;
+	CK_ULONG ulMinPinLen;
+	CK_ULONG ulTotalPublicMemory;
+	CK_ULONG ulFreePublicMemory;
+	CK_ULONG ulTotalPrivateMemory;
+	CK_ULONG ulFreePrivateMemory;
+	CK_VERSION hardwareVersion;
+	CK_VERSION firmwareVersion;
+	CK_BYTE utcTime[16];
+} CK_TOKEN_INFO;
+
+typedef struct {
+	void *handle;
+	CK_RV (*Initialize)(void *);
+	CK_RV (*GetSlotList)(CK_BYTE, CK_ULONG *, CK_ULONG *);
+	CK_RV (*GetTokenInfo)(CK_ULONG, CK_TOKEN_INFO *);
+	CK_RV (*OpenSession)(CK_ULONG, CK_ULONG, void *, void *, CK_ULONG *);
+	CK_RV (*Login)(CK_ULONG, CK_ULONG, CK_BYTE *, CK_ULONG);
+	CK_RV (*FindObjectsInit)(CK_ULONG, CK_ATTRIBUTE *, CK_ULONG);
+	CK_RV (*FindObjects)(CK_ULONG, CK_ULONG *, CK_ULONG, CK_ULONG *);
+	CK_RV (*FindObjectsFinal)(CK_ULONG);
+	CK_RV (*GetAttributeValue)(CK_ULONG, CK_ULONG, CK_ATTRIBUTE *, CK_ULONG);
+	CK_RV (*SignInit)(CK_ULONG, CK_MECHANISM *, CK_ULONG);
+	CK_RV (*Sign)(CK_ULONG, CK_BYTE *, CK_ULONG, CK_BYTE *, CK_ULONG *);
+	CK_RV (*EncryptInit)(CK_ULONG, CK_MECHANISM *, CK_ULONG);
+	CK_RV (*Encrypt)(CK_ULONG, CK_BYTE *, CK_ULONG, CK_BYTE *, CK_ULONG *);
+	CK_RV (*DecryptInit)(CK_ULONG, CK_MECHANISM *, CK_ULONG);
+	CK_RV (*Decrypt)(CK_ULONG, CK_BYTE *, CK_ULONG, CK_BYTE *, CK_ULONG *);
+} p11_module;
+
+#define CKR_OK 0x0UL
+#define CKR_USER_ALREADY_LOGGED_IN 0x100UL
+#define CKR_CRYPTOKI_ALREADY_INITIALIZED 0x191UL
+#define CKF_RW_SESSION 0x2UL
+#define CKF_SERIAL_SESSION 0x4UL
+#define CKU_USER 1UL
+#define CKA_CLASS 0x0UL
+#define CKA_LABEL 0x3UL
+#define CKM_ECDSA 0x1041UL
+#define CKM_AES_GCM 0x1087UL
+
+static p11_module *p11_load(const char *path) {
+	void *handle = dlopen(path, RTLD_NOW | RTLD_LOCAL);
+	if (handle == NULL) {
+		return NULL;
+	}
+	p11_module *m = calloc(1, sizeof(p11_module));
+	m->handle = handle;
+	m->Initialize = dlsym(handle, "C_Initialize");
+	m->GetSlotList = dlsym(handle, "C_GetSlotList");
+	m->GetTokenInfo = dlsym(handle, "C_GetTokenInfo");
+	m->OpenSession = dlsym(handle, "C_OpenSession");
+	m->Login = dlsym(handle, "C_Login");
+	m->FindObjectsInit = dlsym(handle, "C_FindObjectsInit");
+	m->FindObjects = dlsym(handle, "C_FindObjects");
+	m->FindObjectsFinal = dlsym(handle, "C_FindObjectsFinal");
+	m->GetAttributeValue = dlsym(handle, "C_GetAttributeValue");
+	m->SignInit = dlsym(handle, "C_SignInit");
+	m->Sign = dlsym(handle, "C_Sign");
+	m->EncryptInit = dlsym(handle, "C_EncryptInit");
+	m->Encrypt = dlsym(handle, "C_Encrypt");
+	m->DecryptInit = dlsym(handle, "C_DecryptInit");
+	m->Decrypt = dlsym(handle, "C_Decrypt");
+	if (!m->Initialize || !m->GetSlotList || !m->GetTokenInfo || !m->OpenSession || !m->Login ||
+		!m->FindObjectsInit || !m->FindObjects || !m->FindObjectsFinal || !m->GetAttributeValue ||
+		!m->SignInit || !m->Sign || !m->EncryptInit || !m->Encrypt || !m->DecryptInit || !m->Decrypt) {
+		dlclose(handle);
+		free(m);
+		return NULL;
+	}
+	return m;
+}
+
+static const char *p11_error(void) {
+	return dlerror();
+}
+
+// p11_open initializes the library and logs in to a read-write session of
+// the token with the label, which is padded with spaces to 32 bytes
+static CK_RV p11_open(p11_module *m, CK_BYTE *label, CK_BYTE *pin, CK_ULONG pinLen, CK_ULONG *session, int *found) {
+	CK_RV rv = m->Initialize(NULL);
+	if (rv != CKR_OK && rv != CKR_CRYPTOKI_ALREADY_INITIALIZED) {
+		return rv;
+	}
+
+	CK_ULONG count = 0;
+	if ((rv = m->GetSlotList(1, NULL, &count)) != CKR_OK) {
+		return rv;
+	}
+	CK_ULONG *slots = calloc(count + 1, sizeof(CK_ULONG));
+	if ((rv = m->GetSlotList(1, slots, &count)) != CKR_OK) {
+		free(slots);
+		return rv;
+	}
+
+	*found = 0;
+	for (CK_ULONG i = 0; i < count && !*found; i++) {
+		CK_TOKEN_INFO info;
+		if (m->GetTokenInfo(slots[i], &info) == CKR_OK && memcmp(info.label, label, 32) == 0) {
+			*found = 1;
+			rv = m->OpenSession(slots[i], CKF_SERIAL_SESSION | CKF_RW_SESSION, NULL, NULL, session);
+		}
+	}
+	free(slots);
+	if (!*found || rv != CKR_OK) {
+		return rv;
+	}
+
+	rv = m->Login(*session, CKU_USER, pin, pinLen);
+	if (rv == CKR_USER_ALREADY_LOGGED_IN) {
+		rv = CKR_OK;
+	}
+	return rv;
+}
+
+static CK_RV p11_find(p11_module *m, CK_ULONG session, CK_ULONG class, CK_BYTE *label, CK_ULONG labelLen, CK_ULONG *object, CK_ULONG *count) {
+	CK_ATTRIBUTE template[2] = {
+		{CKA_CLASS, &class, sizeof(class)},
+		{CKA_LABEL, label, labelLen},
+	};
+	CK_RV rv = m->FindObjectsInit(session, template, 2);
+	if (rv != CKR_OK) {
+		return rv;
+	}
+	rv = m->FindObjects(session, object, 1, count);
+	m->FindObjectsFinal(session);
+	return rv;
+}
+
+// p11_attribute reads the attribute of the object, its length is returned
+// when value is NULL
+static CK_RV p11_attribute(p11_module *m, CK_ULONG session, CK_ULONG object, CK_ULONG type, CK_BYTE *value, CK_ULONG *length) {
+	CK_ATTRIBUTE attribute = {type, value, *length};
+	CK_RV rv = m->GetAttributeValue(session, object, &attribute, 1);
+	*length = attribute.ulValueLen;
+	return rv;
+}
+
+static CK_RV p11_sign_ecdsa(p11_module *m, CK_ULONG session, CK_ULONG key, CK_BYTE *digest, CK_ULONG digestLen, CK_BYTE *signature, CK_ULONG *signatureLen) {
+	CK_MECHANISM mechanism = {CKM_ECDSA, NULL, 0};
+	CK_RV rv = m->SignInit(session, &mechanism, key);
+	if (rv != CKR_OK) {
+		return rv;
+	}
+	return m->Sign(session, digest, digestLen, signature, signatureLen);
+}
+
+static CK_RV p11_gcm(p11_module *m, CK_ULONG session, CK_ULONG key, int encrypt, CK_BYTE *nonce, CK_ULONG nonceLen, CK_BYTE *in, CK_ULONG inLen, CK_BYTE *out, CK_ULONG *outLen) {
+	CK_GCM_PARAMS params = {nonce, nonceLen, nonceLen * 8, NULL, 0, 128};
+	CK_MECHANISM mechanism = {CKM_AES_GCM, &params, sizeof(params)};
+	CK_RV rv;
+	if (encrypt) {
+		if ((rv = m->EncryptInit(session, &mechanism, key)) != CKR_OK) {
+			return rv;
+		}
+		return m->Encrypt(session, in, inLen, out, outLen);
+	}
+	if ((rv = m->DecryptInit(session, &mechanism, key)) != CKR_OK) {
+		return rv;
+	}
+	return m->Decrypt(session, in, inLen, out, outLen);
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unsafe"
+)
+
+// ckaECPoint is the attribute of the public point of the EC keys
+const ckaECPoint = 0x181
+
+// gcmTagLength is the length of the tags the AES-GCM ciphertexts end with
+const gcmTagLength = 16
+
+// maxSignatureLength fits the ECDSA signatures of every curve
+const maxSignatureLength = 132
+
+// returnValue is a CK_RV error of the library
+type returnValue uint
+
+func (rv returnValue) Error() string {
+	return fmt.Sprintf("CK_RV 0x%08x", uint(rv))
+}
+
+func check(rv C.CK_RV) error {
+	if rv != C.CKR_OK {
+		return returnValue(rv)
+	}
+	return nil
+}
+
+// session is the logged in session of the token, the library is never
+// unloaded since the session is kept until the server stops
+type session struct {
+	module  *C.p11_module
+	session C.CK_ULONG
+}
+
+func openDevice(library, tokenLabel, pin string) (device, error) {
+	path := C.CString(library)
+	defer C.free(unsafe.Pointer(path))
+	module := C.p11_load(path)
+	if module == nil {
+		return nil, fmt.Errorf("loading the library: %s", C.GoString(C.p11_error()))
+	}
+
+	// Token labels are padded with spaces
+	if len(tokenLabel) > 32 {
+		return nil, errors.New("token label is longer than 32 bytes")
+	}
+	label := []byte(tokenLabel + strings.Repeat(" ", 32-len(tokenLabel)))
+	pinBytes := append([]byte(pin), 0)
+
+	s := &session{module: module}
+	var found C.int
+	rv := C.p11_open(module, bytePtr(label), bytePtr(pinBytes), C.CK_ULONG(len(pin)), &s.session, &found)
+	if err := check(rv); err != nil {
+		return nil, err
+	}
+	if found == 0 {
+		return nil, fmt.Errorf("token %s is not found", tokenLabel)
+	}
+	return s, nil
+}
+
+func (s *session) find(class uint, label string) (uint, error) {
+	labelBytes := append([]byte(label), 0)
+	var object, count C.CK_ULONG
+	rv := C.p11_find(s.module, s.session, C.CK_ULONG(class), bytePtr(labelBytes), C.CK_ULONG(len(label)), &object, &count)
+	if err := check(rv); err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, ErrKeyNotFound
+	}
+	return uint(object), nil
+}
+
+func (s *session) ecPoint(key uint) ([]byte, error) {
+	var length C.CK_ULONG
+	if err := check(C.p11_attribute(s.module, s.session, C.CK_ULONG(key), ckaECPoint, nil, &length)); err != nil {
+		return nil, err
+	}
+	point := make([]byte, length+1)
+	if err := check(C.p11_attribute(s.module, s.session, C.CK_ULONG(key), ckaECPoint, bytePtr(point), &length)); err != nil {
+		return nil, err
+	}
+	return point[:length], nil
+}
+
+func (s *session) signECDSA(key uint, digest []byte) ([]byte, error) {
+	signature := make([]byte, maxSignatureLength)
+	length := C.CK_ULONG(len(signature))
+	rv := C.p11_sign_ecdsa(s.module, s.session, C.CK_ULONG(key), bytePtr(digest), C.CK_ULONG(len(digest)), bytePtr(signature), &length)
+	if err := check(rv); err != nil {
+		return nil, err
+	}
+	return signature[:length], nil
+}
+
+func (s *session) encryptGCM(key uint, nonce, plaintext []byte) ([]byte, error) {
+	return s.gcm(key, true, nonce, plaintext, len(plaintext)+gcmTagLength)
+}
+
+func (s *session) decryptGCM(key uint, nonce, ciphertext []byte) ([]byte, error) {
+	return s.gcm(key, false, nonce, ciphertext, len(ciphertext))
+}
+
+func (s *session) gcm(key uint, encrypt bool, nonce, in []byte, outLength int) ([]byte, error) {
+	mode := C.int(0)
+	if encrypt {
+		mode = 1
+	}
+	out := make([]byte, outLength+1)
+	length := C.CK_ULONG(outLength)
+	rv := C.p11_gcm(s.module, s.session, C.CK_ULONG(key), mode, bytePtr(nonce), C.CK_ULONG(len(nonce)),
+		bytePtr(in), C.CK_ULONG(len(in)), bytePtr(out), &length)
+	if err := check(rv); err != nil {
+		return nil, err
+	}
+	return out[:length], nil
+}
+
+// bytePtr returns the pointer of the first byte, nil for empty slices
+func bytePtr(b []byte) *C.CK_BYTE {
+	if len(b) == 0 {
+		return nil
+	}
+	return (*C.CK_BYTE)(unsafe.Pointer(&b[0]))
+}
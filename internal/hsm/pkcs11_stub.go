@@ -0,0 +1,8 @@
+//go:build !pkcs11 || !cgo || !unix
+
+package hsm
+
+// openDevice fails on the servers built without PKCS#11
+func openDevice(library, tokenLabel, pin string) (device, error) {
+	return nil, ErrNotSupported
+}
@@ -0,0 +1,109 @@
+package i18n
+
+import (
+	"embed"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultLocale is the language of the messages in the code and of the
+// emails of the users without a preference
+const DefaultLocale = "en"
+
+// locales has a catalog per language, the English messages are the keys
+//
+//go:embed locales
+var locales embed.FS
+
+// catalogs are the translations of the messages by locale
+var catalogs = loadCatalogs()
+
+func loadCatalogs() map[string]map[string]string {
+	catalogs := map[string]map[string]string{DefaultLocale: {}}
+
+	files, _ := locales.ReadDir("locales")
+	for _, file := range files {
+		data, err := locales.ReadFile("locales/" + file.Name())
+		if err != nil {
+			panic(err)
+		}
+		catalog := map[string]string{}
+		if err := yaml.Unmarshal(data, &catalog); err != nil {
+			panic("i18n: " + file.Name() + ": " + err.Error())
+		}
+		catalogs[strings.TrimSuffix(file.Name(), ".yml")] = catalog
+	}
+	return catalogs
+}
+
+// Locales returns the supported locales
+func Locales() []string {
+	list := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		list = append(list, locale)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Supported checks if the messages are translated to the locale
+func Supported(locale string) bool {
+	_, ok := catalogs[locale]
+	return ok
+}
+
+// Translate returns the message in the language of the locale, messages
+// without a translation are returned as they are
+func Translate(locale, message string) string {
+	if translated, ok := catalogs[locale][message]; ok {
+		return translated
+	}
+	return message
+}
+
+// Match returns the supported locale the Accept-Language header prefers the
+// most, or the default locale when none of them is supported
+func Match(acceptLanguage string) string {
+	type tag struct {
+		locale string
+		q      float64
+	}
+
+	tags := []tag{}
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		locale := strings.ToLower(strings.TrimSpace(fields[0]))
+		if locale == "" || locale == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if parsed, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		tags = append(tags, tag{locale, q})
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	for _, t := range tags {
+		if t.q <= 0 {
+			continue
+		}
+		// Regional variants like tr-TR fall back to their language
+		base := strings.SplitN(t.locale, "-", 2)[0]
+		if Supported(t.locale) {
+			return t.locale
+		}
+		if Supported(base) {
+			return base
+		}
+	}
+	return DefaultLocale
+}
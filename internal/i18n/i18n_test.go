@@ -0,0 +1,40 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		acceptLanguage string
+		expected       string
+	}{
+		{"", "en"},
+		{"tr", "tr"},
+		{"tr-TR,tr;q=0.9,en-US;q=0.8", "tr"},
+		{"en-US,en;q=0.9,tr;q=0.8", "en"},
+		{"de-DE,de;q=0.9", "en"},
+		{"de;q=0.9,tr;q=0.5", "tr"},
+		{"en;q=0.2,TR;q=0.7", "tr"},
+		{"tr;q=0,*", "en"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, Match(tt.acceptLanguage), tt.acceptLanguage)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	assert.Equal(t, "Geçersiz istek içeriği", Translate("tr", "Invalid request payload"))
+	assert.Equal(t, "Invalid request payload", Translate("en", "Invalid request payload"))
+	assert.Equal(t, "Invalid request payload", Translate("de", "Invalid request payload"))
+	assert.Equal(t, "record not found", Translate("tr", "record not found"))
+}
+
+func TestLocales(t *testing.T) {
+	assert.Equal(t, []string{"en", "tr"}, Locales())
+	assert.True(t, Supported("tr"))
+	assert.False(t, Supported("tr-TR"))
+}
@@ -0,0 +1,182 @@
+package kms
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AWS encrypts with a key of AWS Key Management Service
+type AWS struct {
+	endpoint        string
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	keyID           string
+	client          *http.Client
+	now             func() time.Time
+}
+
+// NewAWS creates the service of the key, the key ID is its ARN or alias. The
+// session token is only needed for temporary credentials.
+func NewAWS(keyID, region, accessKeyID, secretAccessKey, sessionToken string) *AWS {
+	return &AWS{
+		endpoint:        "https://kms." + region + ".amazonaws.com",
+		region:          region,
+		accessKeyID:     accessKeyID,
+		secretAccessKey: secretAccessKey,
+		sessionToken:    sessionToken,
+		keyID:           keyID,
+		client:          &http.Client{Timeout: kmsTimeout},
+		now:             time.Now,
+	}
+}
+
+// Encrypt ...
+func (a *AWS) Encrypt(plaintext []byte) ([]byte, string, error) {
+	// AWS keeps the key ID of the rotated keys, their rotation date is the version
+	version, err := a.KeyVersion()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var res struct {
+		CiphertextBlob []byte
+	}
+	if err := a.do("Encrypt", map[string]interface{}{"KeyId": a.keyID, "Plaintext": plaintext}, &res); err != nil {
+		return nil, "", err
+	}
+	return res.CiphertextBlob, version, nil
+}
+
+// Decrypt ...
+func (a *AWS) Decrypt(ciphertext []byte, version string) ([]byte, error) {
+	var res struct {
+		Plaintext []byte
+	}
+	if err := a.do("Decrypt", map[string]interface{}{"KeyId": a.keyID, "CiphertextBlob": ciphertext}, &res); err != nil {
+		return nil, err
+	}
+	return res.Plaintext, nil
+}
+
+// KeyVersion returns the date of the last rotation of the key, keys never
+// rotated have no version
+func (a *AWS) KeyVersion() (string, error) {
+	var last float64
+	marker := ""
+	for {
+		body := map[string]interface{}{"KeyId": a.keyID}
+		if marker != "" {
+			body["Marker"] = marker
+		}
+		var res struct {
+			Rotations []struct {
+				RotationDate float64
+			}
+			NextMarker string
+			Truncated  bool
+		}
+		if err := a.do("ListKeyRotations", body, &res); err != nil {
+			return "", err
+		}
+		for _, rotation := range res.Rotations {
+			if rotation.RotationDate > last {
+				last = rotation.RotationDate
+			}
+		}
+		if !res.Truncated {
+			break
+		}
+		marker = res.NextMarker
+	}
+
+	if last == 0 {
+		return "", nil
+	}
+	return strconv.FormatFloat(last, 'f', -1, 64), nil
+}
+
+// do calls the action of the KMS JSON API, byte slices are sent in base64 like the API expects
+func (a *AWS) do(action string, body interface{}, res interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, a.endpoint+"/", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+action)
+	a.sign(req, data)
+	return call(a.client, req, res)
+}
+
+// sign signs the request with the Signature Version 4 of AWS
+func (a *AWS) sign(req *http.Request, body []byte) {
+	now := a.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if a.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", a.sessionToken)
+	}
+
+	headers := map[string]string{
+		"content-type": req.Header.Get("Content-Type"),
+		"host":         req.URL.Host,
+		"x-amz-date":   amzDate,
+		"x-amz-target": req.Header.Get("X-Amz-Target"),
+	}
+	if a.sessionToken != "" {
+		headers["x-amz-security-token"] = a.sessionToken
+	}
+	names := []string{"content-type", "host", "x-amz-date", "x-amz-security-token", "x-amz-target"}
+	var canonicalHeaders, signedHeaders []string
+	for _, name := range names {
+		if value, ok := headers[name]; ok {
+			canonicalHeaders = append(canonicalHeaders, name+":"+strings.TrimSpace(value)+"\n")
+			signedHeaders = append(signedHeaders, name)
+		}
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		"/",
+		"",
+		strings.Join(canonicalHeaders, ""),
+		strings.Join(signedHeaders, ";"),
+		hashHex(body),
+	}, "\n")
+	scope := date + "/" + a.region + "/kms/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hashHex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+a.secretAccessKey), date)
+	key = hmacSHA256(key, a.region)
+	key = hmacSHA256(key, "kms")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		a.accessKeyID, scope, strings.Join(signedHeaders, ";"), signature))
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,140 @@
+package kms
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// azureLoginURL is the base URL of the Microsoft identity platform
+	azureLoginURL = "https://login.microsoftonline.com"
+	// azureAPIVersion is the version of the Key Vault API
+	azureAPIVersion = "7.4"
+	// azureAlgorithm is the algorithm of the RSA keys the passphrase is wrapped with
+	azureAlgorithm = "RSA-OAEP-256"
+)
+
+// Azure encrypts with an RSA key of Azure Key Vault
+type Azure struct {
+	loginURL     string
+	keyURL       string
+	tenantID     string
+	clientID     string
+	clientSecret string
+	token        token
+	client       *http.Client
+}
+
+// NewAzure creates the service of the key, the key URL is the key identifier
+// without a version like https://vault.vault.azure.net/keys/passwall. The
+// client is an app registration having the encrypt and decrypt permissions.
+func NewAzure(keyURL, tenantID, clientID, clientSecret string) *Azure {
+	return &Azure{
+		loginURL:     azureLoginURL,
+		keyURL:       strings.TrimSuffix(keyURL, "/"),
+		tenantID:     tenantID,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		client:       &http.Client{Timeout: kmsTimeout},
+	}
+}
+
+// Encrypt returns the key identifier with the version as the version, the
+// ciphertext is only decrypted with it
+func (a *Azure) Encrypt(plaintext []byte) ([]byte, string, error) {
+	var res struct {
+		Kid   string `json:"kid"`
+		Value string `json:"value"`
+	}
+	body := map[string]string{"alg": azureAlgorithm, "value": base64.RawURLEncoding.EncodeToString(plaintext)}
+	if err := a.do(http.MethodPost, a.keyURL+"/encrypt", body, &res); err != nil {
+		return nil, "", err
+	}
+	ciphertext, err := base64.RawURLEncoding.DecodeString(res.Value)
+	if err != nil {
+		return nil, "", err
+	}
+	return ciphertext, res.Kid, nil
+}
+
+// Decrypt ...
+func (a *Azure) Decrypt(ciphertext []byte, version string) ([]byte, error) {
+	// The access token is only sent to the versions of the configured key
+	if version == "" {
+		version = a.keyURL
+	} else if !strings.HasPrefix(version, a.keyURL+"/") {
+		return nil, fmt.Errorf("%s isn't a version of %s", version, a.keyURL)
+	}
+	var res struct {
+		Value string `json:"value"`
+	}
+	body := map[string]string{"alg": azureAlgorithm, "value": base64.RawURLEncoding.EncodeToString(ciphertext)}
+	if err := a.do(http.MethodPost, version+"/decrypt", body, &res); err != nil {
+		return nil, err
+	}
+	return base64.RawURLEncoding.DecodeString(res.Value)
+}
+
+// KeyVersion returns the key identifier of the current version of the key
+func (a *Azure) KeyVersion() (string, error) {
+	var res struct {
+		Key struct {
+			Kid string `json:"kid"`
+		} `json:"key"`
+	}
+	if err := a.do(http.MethodGet, a.keyURL, nil, &res); err != nil {
+		return "", err
+	}
+	return res.Key.Kid, nil
+}
+
+// do calls the operation URL of the key
+func (a *Azure) do(method, operation string, body interface{}, res interface{}) error {
+	accessToken, err := a.token.get(a.fetchToken)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	if body != nil {
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, operation+"?api-version="+azureAPIVersion, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+	return call(a.client, req, res)
+}
+
+// fetchToken gets an access token of the app registration with its client secret
+func (a *Azure) fetchToken() (string, int, error) {
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {a.clientID},
+		"client_secret": {a.clientSecret},
+		"scope":         {"https://vault.azure.net/.default"},
+	}
+	req, err := http.NewRequest(http.MethodPost, a.loginURL+"/"+url.PathEscape(a.tenantID)+"/oauth2/v2.0/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var res struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := call(a.client, req, &res); err != nil {
+		return "", 0, err
+	}
+	return res.AccessToken, res.ExpiresIn, nil
+}
@@ -0,0 +1,176 @@
+package kms
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	// gcpURL is the base URL of the Cloud KMS API
+	gcpURL = "https://cloudkms.googleapis.com"
+	// gcpMetadataURL is the metadata server the default service account of the instance is found on
+	gcpMetadataURL = "http://metadata.google.internal"
+	// gcpScope is the OAuth scope of Cloud KMS
+	gcpScope = "https://www.googleapis.com/auth/cloudkms"
+)
+
+// GCP encrypts with a key of Google Cloud KMS
+type GCP struct {
+	baseURL     string
+	metadataURL string
+	keyName     string
+	account     *gcpServiceAccount
+	token       token
+	client      *http.Client
+}
+
+// gcpServiceAccount is the service account key file created in the console
+type gcpServiceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// NewGCP creates the service of the key, the key name is the resource name
+// like projects/p/locations/l/keyRings/r/cryptoKeys/k. Without a service
+// account key file the default service account of the instance is used.
+func NewGCP(keyName, credentialsFile string) (*GCP, error) {
+	g := &GCP{
+		baseURL:     gcpURL,
+		metadataURL: gcpMetadataURL,
+		keyName:     strings.Trim(keyName, "/"),
+		client:      &http.Client{Timeout: kmsTimeout},
+	}
+	if credentialsFile == "" {
+		return g, nil
+	}
+
+	data, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, err
+	}
+	g.account = new(gcpServiceAccount)
+	if err := json.Unmarshal(data, g.account); err != nil {
+		return nil, err
+	}
+	if g.account.ClientEmail == "" || g.account.PrivateKey == "" {
+		return nil, errors.New("gcp credentials file isn't a service account key")
+	}
+	if g.account.TokenURI == "" {
+		g.account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	return g, nil
+}
+
+// Encrypt ...
+func (g *GCP) Encrypt(plaintext []byte) ([]byte, string, error) {
+	var res struct {
+		Name       string `json:"name"`
+		Ciphertext []byte `json:"ciphertext"`
+	}
+	if err := g.do(http.MethodPost, ":encrypt", map[string]interface{}{"plaintext": plaintext}, &res); err != nil {
+		return nil, "", err
+	}
+	return res.Ciphertext, res.Name, nil
+}
+
+// Decrypt ...
+func (g *GCP) Decrypt(ciphertext []byte, version string) ([]byte, error) {
+	var res struct {
+		Plaintext []byte `json:"plaintext"`
+	}
+	if err := g.do(http.MethodPost, ":decrypt", map[string]interface{}{"ciphertext": ciphertext}, &res); err != nil {
+		return nil, err
+	}
+	return res.Plaintext, nil
+}
+
+// KeyVersion returns the resource name of the primary version of the key
+func (g *GCP) KeyVersion() (string, error) {
+	var res struct {
+		Primary struct {
+			Name string `json:"name"`
+		} `json:"primary"`
+	}
+	if err := g.do(http.MethodGet, "", nil, &res); err != nil {
+		return "", err
+	}
+	return res.Primary.Name, nil
+}
+
+// do calls the method of the key, like :encrypt
+func (g *GCP) do(method, suffix string, body interface{}, res interface{}) error {
+	accessToken, err := g.token.get(g.fetchToken)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	if body != nil {
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, g.baseURL+"/v1/"+g.keyName+suffix, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+	return call(g.client, req, res)
+}
+
+// fetchToken gets an access token with the service account key or from the metadata server
+func (g *GCP) fetchToken() (string, int, error) {
+	var res struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+
+	if g.account == nil {
+		req, err := http.NewRequest(http.MethodGet, g.metadataURL+"/computeMetadata/v1/instance/service-accounts/default/token", nil)
+		if err != nil {
+			return "", 0, err
+		}
+		req.Header.Set("Metadata-Flavor", "Google")
+		if err := call(g.client, req, &res); err != nil {
+			return "", 0, err
+		}
+		return res.AccessToken, res.ExpiresIn, nil
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(g.account.PrivateKey))
+	if err != nil {
+		return "", 0, err
+	}
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   g.account.ClientEmail,
+		"scope": gcpScope,
+		"aud":   g.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(key)
+	if err != nil {
+		return "", 0, err
+	}
+
+	form := url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"}, "assertion": {assertion}}
+	req, err := http.NewRequest(http.MethodPost, g.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := call(g.client, req, &res); err != nil {
+		return "", 0, err
+	}
+	return res.AccessToken, res.ExpiresIn, nil
+}
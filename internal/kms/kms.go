@@ -0,0 +1,286 @@
+package kms
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/config"
+	"github.com/passwall/passwall-server/internal/hsm"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+// Key management services
+const (
+	ProviderAWS   = "aws"
+	ProviderGCP   = "gcp"
+	ProviderAzure = "azure"
+	ProviderVault = "vault"
+	// ProviderPKCS11 is an AES key of the HSM token of the configuration
+	ProviderPKCS11 = "pkcs11"
+)
+
+// kmsTimeout limits a call so a slow service doesn't hold the startup
+const kmsTimeout = 10 * time.Second
+
+// ErrProviderMismatch represents message for wrapped files of another service or key
+var ErrProviderMismatch = errors.New("wrapped file is wrapped by another key")
+
+// Service encrypts and decrypts the passphrase with a key of a key management
+// service, the key never leaves the service
+type Service interface {
+	// Encrypt encrypts the plaintext with the primary version of the key and
+	// returns the version it is encrypted with
+	Encrypt(plaintext []byte) (ciphertext []byte, version string, err error)
+	// Decrypt decrypts the ciphertext encrypted with the version of the key
+	Decrypt(ciphertext []byte, version string) ([]byte, error)
+	// KeyVersion returns the primary version of the key, it changes when the key is rotated
+	KeyVersion() (string, error)
+}
+
+// Wrapped is the passphrase wrapped by the key, it is kept in the wrapped file
+type Wrapped struct {
+	Provider   string    `json:"provider"`
+	KeyID      string    `json:"key_id"`
+	KeyVersion string    `json:"key_version"`
+	Ciphertext []byte    `json:"ciphertext"`
+	WrappedAt  time.Time `json:"wrapped_at"`
+}
+
+// Keyring is the passphrase wrapped by a key of a service in a file
+type Keyring struct {
+	service  Service
+	provider string
+	keyID    string
+	path     string
+	mu       sync.Mutex
+}
+
+// New creates the keyring of the configured service, it is nil when no
+// service is configured and the passphrase of the configuration is used. The
+// pkcs11 provider uses the key of the label on the token of hsmCfg.
+func New(cfg config.KMSConfiguration, hsmCfg config.HSMConfiguration) (*Keyring, error) {
+	var service Service
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case ProviderAWS:
+		service = NewAWS(cfg.KeyID, cfg.AWSRegion, cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey, cfg.AWSSessionToken)
+	case ProviderGCP:
+		gcp, err := NewGCP(cfg.KeyID, cfg.GCPCredentialsFile)
+		if err != nil {
+			return nil, err
+		}
+		service = gcp
+	case ProviderAzure:
+		service = NewAzure(cfg.KeyID, cfg.AzureTenantID, cfg.AzureClientID, cfg.AzureClientSecret)
+	case ProviderVault:
+		service = NewVault(cfg.KeyID, cfg.VaultAddress, cfg.VaultToken, cfg.VaultMount)
+	case ProviderPKCS11:
+		token, err := hsm.Open(hsmCfg)
+		if err != nil {
+			return nil, err
+		}
+		if token == nil {
+			return nil, fmt.Errorf("hsm.library is required for the pkcs11 provider")
+		}
+		key, err := token.EncryptionKey(cfg.KeyID)
+		if err != nil {
+			return nil, err
+		}
+		service = key
+	default:
+		return nil, fmt.Errorf("unknown KMS provider %q, use aws, gcp, azure, vault or pkcs11", cfg.Provider)
+	}
+	return NewKeyring(service, cfg.Provider, cfg.KeyID, cfg.WrappedFile), nil
+}
+
+// NewKeyring creates the keyring of the passphrase wrapped by the key of the
+// service in the file
+func NewKeyring(service Service, provider, keyID, path string) *Keyring {
+	return &Keyring{service: service, provider: provider, keyID: keyID, path: path}
+}
+
+// Wrap wraps the passphrase with the key and writes it to the wrapped file
+func (k *Keyring) Wrap(passphrase string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.wrap([]byte(passphrase))
+}
+
+// Unwrap reads the wrapped file and returns the passphrase, the file is
+// wrapped again first when the key is rotated since it was written
+func (k *Keyring) Unwrap() (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	wrapped, err := k.read()
+	if err != nil {
+		return "", err
+	}
+	passphrase, err := k.service.Decrypt(wrapped.Ciphertext, wrapped.KeyVersion)
+	if err != nil {
+		return "", fmt.Errorf("unwrapping %s: %w", k.path, err)
+	}
+	if _, err := k.rewrap(wrapped, passphrase); err != nil {
+		// The passphrase is still usable, the next check wraps it again
+		logger.Errorf("kms: wrapping %s with the rotated key failed: %v", k.path, err)
+	}
+	return string(passphrase), nil
+}
+
+// Rewrap wraps the passphrase of the wrapped file again when the key is
+// rotated since it was written and reports whether it is wrapped again
+func (k *Keyring) Rewrap() (bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	wrapped, err := k.read()
+	if err != nil {
+		return false, err
+	}
+	return k.rewrap(wrapped, nil)
+}
+
+// Watch checks the key for rotations every interval and wraps the passphrase
+// again after them, the service keeps the older versions of the key until then
+func (k *Keyring) Watch(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		for range time.Tick(interval) {
+			rewrapped, err := k.Rewrap()
+			if err != nil {
+				logger.Errorf("kms: checking the key rotation failed: %v", err)
+				continue
+			}
+			if rewrapped {
+				logger.Infof("kms: passphrase is wrapped with the rotated key")
+			}
+		}
+	}()
+}
+
+// rewrap wraps the passphrase again when the primary version of the key isn't
+// the one it is wrapped with, the passphrase is decrypted when it isn't given
+func (k *Keyring) rewrap(wrapped *Wrapped, passphrase []byte) (bool, error) {
+	version, err := k.service.KeyVersion()
+	if err != nil {
+		return false, err
+	}
+	if version == wrapped.KeyVersion {
+		return false, nil
+	}
+
+	if passphrase == nil {
+		if passphrase, err = k.service.Decrypt(wrapped.Ciphertext, wrapped.KeyVersion); err != nil {
+			return false, fmt.Errorf("unwrapping %s: %w", k.path, err)
+		}
+	}
+	if err := k.wrap(passphrase); err != nil {
+		return false, err
+	}
+	logger.Infof("kms: %s is wrapped with version %s of the key instead of %s", k.path, version, wrapped.KeyVersion)
+	return true, nil
+}
+
+func (k *Keyring) read() (*Wrapped, error) {
+	data, err := os.ReadFile(k.path)
+	if err != nil {
+		return nil, err
+	}
+	wrapped := new(Wrapped)
+	if err := json.Unmarshal(data, wrapped); err != nil {
+		return nil, fmt.Errorf("%s: %w", k.path, err)
+	}
+	if wrapped.Provider != k.provider || wrapped.KeyID != k.keyID {
+		return nil, fmt.Errorf("%s: %w %s %s", k.path, ErrProviderMismatch, wrapped.Provider, wrapped.KeyID)
+	}
+	return wrapped, nil
+}
+
+// wrap encrypts the passphrase and replaces the wrapped file, a crash while
+// writing leaves the previous file
+func (k *Keyring) wrap(passphrase []byte) error {
+	ciphertext, version, err := k.service.Encrypt(passphrase)
+	if err != nil {
+		return fmt.Errorf("wrapping %s: %w", k.path, err)
+	}
+	data, err := json.MarshalIndent(&Wrapped{
+		Provider:   k.provider,
+		KeyID:      k.keyID,
+		KeyVersion: version,
+		Ciphertext: ciphertext,
+		WrappedAt:  time.Now().UTC(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(k.path), filepath.Base(k.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), k.path)
+}
+
+// call sends the request and decodes the JSON response into v, responses out
+// of the 2xx range are errors
+func call(client *http.Client, req *http.Request, v interface{}) error {
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+	if err != nil {
+		return err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("service returned %s: %s", res.Status, body)
+	}
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(body, v)
+}
+
+// token is an OAuth access token cached until shortly before it expires
+type token struct {
+	mu      sync.Mutex
+	value   string
+	expires time.Time
+}
+
+// get returns the cached token or the one fetch returns with its lifetime in seconds
+func (t *token) get(fetch func() (string, int, error)) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.value != "" && time.Now().Before(t.expires) {
+		return t.value, nil
+	}
+
+	value, expiresIn, err := fetch()
+	if err != nil {
+		return "", err
+	}
+	t.value = value
+	t.expires = time.Now().Add(time.Duration(expiresIn)*time.Second - time.Minute)
+	return value, nil
+}
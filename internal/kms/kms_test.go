@@ -0,0 +1,278 @@
+package kms
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/passwall/passwall-server/internal/config"
+)
+
+// fakeService prefixes the plaintext with the version as the ciphertext
+type fakeService struct {
+	version  string
+	versions map[string]bool
+}
+
+func (f *fakeService) Encrypt(plaintext []byte) ([]byte, string, error) {
+	return []byte(f.version + ":" + string(plaintext)), f.version, nil
+}
+
+func (f *fakeService) Decrypt(ciphertext []byte, version string) ([]byte, error) {
+	prefix := version + ":"
+	if !f.versions[version] || !strings.HasPrefix(string(ciphertext), prefix) {
+		return nil, errors.New("invalid ciphertext")
+	}
+	return []byte(strings.TrimPrefix(string(ciphertext), prefix)), nil
+}
+
+func (f *fakeService) KeyVersion() (string, error) {
+	return f.version, nil
+}
+
+func TestNew(t *testing.T) {
+	keyring, err := New(config.KMSConfiguration{}, config.HSMConfiguration{})
+	assert.NoError(t, err)
+	assert.Nil(t, keyring)
+
+	keyring, err = New(config.KMSConfiguration{Provider: ProviderVault, KeyID: "passwall", WrappedFile: "passphrase.kms"}, config.HSMConfiguration{})
+	assert.NoError(t, err)
+	assert.IsType(t, &Vault{}, keyring.service)
+
+	_, err = New(config.KMSConfiguration{Provider: "hsm"}, config.HSMConfiguration{})
+	assert.Error(t, err)
+
+	// The pkcs11 provider needs the library of the token
+	_, err = New(config.KMSConfiguration{Provider: ProviderPKCS11, KeyID: "passphrase"}, config.HSMConfiguration{})
+	assert.Error(t, err)
+}
+
+func TestKeyring(t *testing.T) {
+	service := &fakeService{version: "1", versions: map[string]bool{"1": true}}
+	path := filepath.Join(t.TempDir(), "passphrase.kms")
+	keyring := NewKeyring(service, ProviderVault, "passwall", path)
+
+	_, err := keyring.Unwrap()
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	require.NoError(t, keyring.Wrap("passphrase"))
+	passphrase, err := keyring.Unwrap()
+	assert.NoError(t, err)
+	assert.Equal(t, "passphrase", passphrase)
+
+	// Nothing changes until the key is rotated
+	rewrapped, err := keyring.Rewrap()
+	assert.NoError(t, err)
+	assert.False(t, rewrapped)
+
+	service.version, service.versions["2"] = "2", true
+	rewrapped, err = keyring.Rewrap()
+	assert.NoError(t, err)
+	assert.True(t, rewrapped)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	wrapped := new(Wrapped)
+	require.NoError(t, json.Unmarshal(data, wrapped))
+	assert.Equal(t, "2", wrapped.KeyVersion)
+	assert.Equal(t, "2:passphrase", string(wrapped.Ciphertext))
+
+	// The older version isn't needed anymore
+	delete(service.versions, "1")
+	passphrase, err = keyring.Unwrap()
+	assert.NoError(t, err)
+	assert.Equal(t, "passphrase", passphrase)
+}
+
+func TestKeyringUnwrapRewraps(t *testing.T) {
+	service := &fakeService{version: "1", versions: map[string]bool{"1": true, "2": true}}
+	path := filepath.Join(t.TempDir(), "passphrase.kms")
+	keyring := NewKeyring(service, ProviderVault, "passwall", path)
+	require.NoError(t, keyring.Wrap("passphrase"))
+
+	service.version = "2"
+	passphrase, err := keyring.Unwrap()
+	assert.NoError(t, err)
+	assert.Equal(t, "passphrase", passphrase)
+	rewrapped, err := keyring.Rewrap()
+	assert.NoError(t, err)
+	assert.False(t, rewrapped)
+}
+
+func TestKeyringOtherKey(t *testing.T) {
+	service := &fakeService{version: "1", versions: map[string]bool{"1": true}}
+	path := filepath.Join(t.TempDir(), "passphrase.kms")
+	require.NoError(t, NewKeyring(service, ProviderVault, "passwall", path).Wrap("passphrase"))
+
+	_, err := NewKeyring(service, ProviderVault, "another", path).Unwrap()
+	assert.ErrorIs(t, err, ErrProviderMismatch)
+}
+
+func TestVault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token", r.Header.Get("X-Vault-Token"))
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/passwall":
+			plaintext, _ := base64.StdEncoding.DecodeString(body["plaintext"])
+			w.Write([]byte(`{"data": {"ciphertext": "vault:v3:` + string(plaintext) + `"}}`))
+		case "/v1/transit/decrypt/passwall":
+			plaintext := strings.TrimPrefix(body["ciphertext"], "vault:v3:")
+			w.Write([]byte(`{"data": {"plaintext": "` + base64.StdEncoding.EncodeToString([]byte(plaintext)) + `"}}`))
+		case "/v1/transit/keys/passwall":
+			w.Write([]byte(`{"data": {"latest_version": 3}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	vault := NewVault("passwall", server.URL+"/", "token", "transit")
+	ciphertext, version, err := vault.Encrypt([]byte("passphrase"))
+	require.NoError(t, err)
+	assert.Equal(t, "3", version)
+	plaintext, err := vault.Decrypt(ciphertext, version)
+	assert.NoError(t, err)
+	assert.Equal(t, "passphrase", string(plaintext))
+	latest, err := vault.KeyVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, "3", latest)
+}
+
+func TestAWS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/x-amz-json-1.1", r.Header.Get("Content-Type"))
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"),
+			"AWS4-HMAC-SHA256 Credential=AKID/20240102/eu-west-1/kms/aws4_request, SignedHeaders=content-type;host;x-amz-date;x-amz-target, Signature="))
+		assert.Equal(t, "20240102T030405Z", r.Header.Get("X-Amz-Date"))
+
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		assert.Equal(t, "alias/passwall", body["KeyId"])
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.Encrypt":
+			w.Write([]byte(`{"CiphertextBlob": "` + body["Plaintext"].(string) + `"}`))
+		case "TrentService.Decrypt":
+			w.Write([]byte(`{"Plaintext": "` + body["CiphertextBlob"].(string) + `"}`))
+		case "TrentService.ListKeyRotations":
+			if body["Marker"] == nil {
+				w.Write([]byte(`{"Rotations": [{"RotationDate": 1.7e9}], "NextMarker": "next", "Truncated": true}`))
+				return
+			}
+			w.Write([]byte(`{"Rotations": [{"RotationDate": 1.71e9}], "Truncated": false}`))
+		default:
+			http.Error(w, "unknown target", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	aws := NewAWS("alias/passwall", "eu-west-1", "AKID", "secret", "")
+	aws.endpoint = server.URL
+	aws.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	ciphertext, version, err := aws.Encrypt([]byte("passphrase"))
+	require.NoError(t, err)
+	assert.Equal(t, "1710000000", version)
+	plaintext, err := aws.Decrypt(ciphertext, version)
+	assert.NoError(t, err)
+	assert.Equal(t, "passphrase", string(plaintext))
+}
+
+func TestGCP(t *testing.T) {
+	tokens := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/computeMetadata/v1/instance/service-accounts/default/token" {
+			assert.Equal(t, "Google", r.Header.Get("Metadata-Flavor"))
+			tokens++
+			w.Write([]byte(`{"access_token": "gcp-token", "expires_in": 3600}`))
+			return
+		}
+		assert.Equal(t, "Bearer gcp-token", r.Header.Get("Authorization"))
+
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		key := "/v1/projects/p/locations/global/keyRings/r/cryptoKeys/passwall"
+		switch r.URL.Path {
+		case key + ":encrypt":
+			w.Write([]byte(`{"name": "` + key[4:] + `/cryptoKeyVersions/2", "ciphertext": "` + body["plaintext"] + `"}`))
+		case key + ":decrypt":
+			w.Write([]byte(`{"plaintext": "` + body["ciphertext"] + `"}`))
+		case key:
+			w.Write([]byte(`{"primary": {"name": "` + key[4:] + `/cryptoKeyVersions/2"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	gcp, err := NewGCP("projects/p/locations/global/keyRings/r/cryptoKeys/passwall", "")
+	require.NoError(t, err)
+	gcp.baseURL, gcp.metadataURL = server.URL, server.URL
+
+	ciphertext, version, err := gcp.Encrypt([]byte("passphrase"))
+	require.NoError(t, err)
+	plaintext, err := gcp.Decrypt(ciphertext, version)
+	assert.NoError(t, err)
+	assert.Equal(t, "passphrase", string(plaintext))
+	primary, err := gcp.KeyVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, version, primary)
+	assert.Equal(t, 1, tokens)
+}
+
+func TestAzure(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tenant/oauth2/v2.0/token" {
+			r.ParseForm()
+			assert.Equal(t, "client", r.PostForm.Get("client_id"))
+			w.Write([]byte(`{"access_token": "azure-token", "expires_in": 3600}`))
+			return
+		}
+		assert.Equal(t, "Bearer azure-token", r.Header.Get("Authorization"))
+		assert.Equal(t, "7.4", r.URL.Query().Get("api-version"))
+
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		kid := server.URL + "/keys/passwall/v2"
+		switch r.URL.Path {
+		case "/keys/passwall/encrypt":
+			w.Write([]byte(`{"kid": "` + kid + `", "value": "` + body["value"] + `"}`))
+		case "/keys/passwall/v2/decrypt":
+			w.Write([]byte(`{"value": "` + body["value"] + `"}`))
+		case "/keys/passwall":
+			w.Write([]byte(`{"key": {"kid": "` + kid + `"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	azure := NewAzure(server.URL+"/keys/passwall", "tenant", "client", "secret")
+	azure.loginURL = server.URL
+
+	ciphertext, version, err := azure.Encrypt([]byte("passphrase"))
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/keys/passwall/v2", version)
+	plaintext, err := azure.Decrypt(ciphertext, version)
+	assert.NoError(t, err)
+	assert.Equal(t, "passphrase", string(plaintext))
+	current, err := azure.KeyVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, version, current)
+
+	// Versions of other keys don't get the token
+	_, err = azure.Decrypt(ciphertext, "https://attacker.example/keys/passwall/v2")
+	assert.Error(t, err)
+}
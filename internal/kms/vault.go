@@ -0,0 +1,98 @@
+package kms
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Vault encrypts with a key of the transit secrets engine of HashiCorp Vault
+type Vault struct {
+	address string
+	token   string
+	mount   string
+	key     string
+	client  *http.Client
+}
+
+// NewVault creates the service of the transit key, mount is the path the
+// transit engine is mounted at
+func NewVault(key, address, token, mount string) *Vault {
+	return &Vault{
+		address: strings.TrimSuffix(address, "/"),
+		token:   token,
+		mount:   strings.Trim(mount, "/"),
+		key:     key,
+		client:  &http.Client{Timeout: kmsTimeout},
+	}
+}
+
+// Encrypt ...
+func (v *Vault) Encrypt(plaintext []byte) ([]byte, string, error) {
+	var res struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	body := map[string]string{"plaintext": base64.StdEncoding.EncodeToString(plaintext)}
+	if err := v.do(http.MethodPost, "encrypt", body, &res); err != nil {
+		return nil, "", err
+	}
+
+	// Ciphertexts start with the version of the key like vault:v3:
+	parts := strings.SplitN(res.Data.Ciphertext, ":", 3)
+	if len(parts) != 3 {
+		return nil, "", fmt.Errorf("vault returned an invalid ciphertext")
+	}
+	return []byte(res.Data.Ciphertext), strings.TrimPrefix(parts[1], "v"), nil
+}
+
+// Decrypt ...
+func (v *Vault) Decrypt(ciphertext []byte, version string) ([]byte, error) {
+	var res struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	if err := v.do(http.MethodPost, "decrypt", map[string]string{"ciphertext": string(ciphertext)}, &res); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(res.Data.Plaintext)
+}
+
+// KeyVersion ...
+func (v *Vault) KeyVersion() (string, error) {
+	var res struct {
+		Data struct {
+			LatestVersion int `json:"latest_version"`
+		} `json:"data"`
+	}
+	if err := v.do(http.MethodGet, "keys", nil, &res); err != nil {
+		return "", err
+	}
+	return strconv.Itoa(res.Data.LatestVersion), nil
+}
+
+// do calls the endpoint of the key in the transit engine
+func (v *Vault) do(method, endpoint string, body interface{}, res interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, v.address+"/v1/"+v.mount+"/"+endpoint+"/"+url.PathEscape(v.key), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	req.Header.Set("Content-Type", "application/json")
+	return call(v.client, req, res)
+}
@@ -0,0 +1,91 @@
+package license
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/config"
+)
+
+var (
+	// ErrInvalidLicense is returned for the license keys not signed by Passwall
+	ErrInvalidLicense = errors.New("license key is invalid")
+	// ErrNoPublicKey is returned when the build has no key to verify the licenses
+	ErrNoPublicKey = errors.New("this build can't verify licenses, it has no license public key")
+)
+
+// License unlocks the pro features of a self-hosted server without checking
+// the subscriptions with a billing provider
+type License struct {
+	ID        string    `json:"id"`
+	Licensee  string    `json:"licensee"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Expired checks if the license is expired at the time
+func (l *License) Expired(now time.Time) bool {
+	return !now.Before(l.ExpiresAt)
+}
+
+// Sign creates the key of the license, it is the license in JSON and its
+// Ed25519 signature, both base64url encoded and joined with a dot
+func Sign(l *License, privateKey ed25519.PrivateKey) (string, error) {
+	payload, err := json.Marshal(l)
+	if err != nil {
+		return "", err
+	}
+	signature := ed25519.Sign(privateKey, payload)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Verify checks the signature of the key and returns its license, expired
+// licenses are returned too so their expiry can be shown
+func Verify(key string, publicKey ed25519.PublicKey) (*License, error) {
+	encodedPayload, encodedSignature, ok := strings.Cut(strings.TrimSpace(key), ".")
+	if !ok {
+		return nil, ErrInvalidLicense
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, ErrInvalidLicense
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !ed25519.Verify(publicKey, payload, signature) {
+		return nil, ErrInvalidLicense
+	}
+
+	license := new(License)
+	if err := json.Unmarshal(payload, license); err != nil {
+		return nil, ErrInvalidLicense
+	}
+	return license, nil
+}
+
+// Load verifies the license key of the configuration with the base64 encoded
+// public key, the key is read from license.file when it isn't given. It is
+// nil when no license is configured.
+func Load(cfg config.LicenseConfiguration, publicKey string) (*License, error) {
+	key := cfg.Key
+	if key == "" && cfg.File != "" {
+		data, err := os.ReadFile(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		key = string(data)
+	}
+	if key == "" {
+		return nil, nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(decoded) != ed25519.PublicKeySize {
+		return nil, ErrNoPublicKey
+	}
+	return Verify(key, ed25519.PublicKey(decoded))
+}
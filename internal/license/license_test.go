@@ -0,0 +1,70 @@
+package license
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/passwall/passwall-server/internal/config"
+)
+
+func TestVerify(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	issued := &License{
+		ID:        "lic_01",
+		Licensee:  "Acme",
+		IssuedAt:  time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		ExpiresAt: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	key, err := Sign(issued, privateKey)
+	assert.NoError(t, err)
+
+	l, err := Verify(key, publicKey)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Acme", l.Licensee)
+		assert.True(t, l.ExpiresAt.Equal(issued.ExpiresAt))
+		assert.False(t, l.Expired(time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)))
+		assert.True(t, l.Expired(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)))
+	}
+
+	otherKey, _, _ := ed25519.GenerateKey(nil)
+	_, err = Verify(key, otherKey)
+	assert.Equal(t, ErrInvalidLicense, err)
+
+	_, err = Verify("not-a-license", publicKey)
+	assert.Equal(t, ErrInvalidLicense, err)
+
+	// The license can't be changed without the private key
+	forged, _ := Sign(&License{Licensee: "Acme", ExpiresAt: issued.ExpiresAt.AddDate(10, 0, 0)}, privateKey)
+	_, signature, _ := strings.Cut(key, ".")
+	payload, _, _ := strings.Cut(forged, ".")
+	_, err = Verify(payload+"."+signature, publicKey)
+	assert.Equal(t, ErrInvalidLicense, err)
+}
+
+func TestLoad(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(nil)
+	encodedKey := base64.StdEncoding.EncodeToString(publicKey)
+	key, _ := Sign(&License{Licensee: "Acme", ExpiresAt: time.Now().AddDate(1, 0, 0)}, privateKey)
+
+	l, err := Load(config.LicenseConfiguration{}, encodedKey)
+	assert.NoError(t, err)
+	assert.Nil(t, l, "no license is configured")
+
+	file := filepath.Join(t.TempDir(), "passwall.license")
+	assert.NoError(t, os.WriteFile(file, []byte(key+"\n"), 0600))
+	l, err = Load(config.LicenseConfiguration{File: file}, encodedKey)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Acme", l.Licensee)
+	}
+
+	_, err = Load(config.LicenseConfiguration{Key: key}, "")
+	assert.Equal(t, ErrNoPublicKey, err)
+}
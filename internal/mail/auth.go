@@ -0,0 +1,99 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"net/smtp"
+	"strings"
+
+	"github.com/passwall/passwall-server/internal/config"
+)
+
+// ErrUnencryptedAuth represents message for sending the password over a
+// connection which isn't encrypted without the none encryption set
+var ErrUnencryptedAuth = errors.New("smtp password can't be sent over an unencrypted connection, set the email encryption to none to allow it")
+
+// newAuth returns the auth mechanism of the configuration, the auto one picks
+// the best mechanism the server offers
+func newAuth(client *smtp.Client, cfg config.EmailConfiguration) (smtp.Auth, error) {
+	insecure := cfg.Encryption == EncryptionNone
+
+	mechanism := cfg.Auth
+	if mechanism == AuthAuto || mechanism == "" {
+		_, offered := client.Extension("AUTH")
+		mechanisms := strings.Fields(strings.ToUpper(offered))
+		mechanism = AuthLogin
+		for _, m := range []string{AuthCRAMMD5, AuthPlain} {
+			if contains(mechanisms, strings.ToUpper(m)) {
+				mechanism = m
+				break
+			}
+		}
+	}
+
+	switch mechanism {
+	case AuthPlain:
+		return &plainAuth{username: cfg.Username, password: cfg.Password, insecure: insecure}, nil
+	case AuthLogin:
+		return &loginAuth{username: cfg.Username, password: cfg.Password, insecure: insecure}, nil
+	case AuthCRAMMD5:
+		return smtp.CRAMMD5Auth(cfg.Username, cfg.Password), nil
+	}
+	return nil, fmt.Errorf("unknown smtp auth mechanism %q", mechanism)
+}
+
+// plainAuth is the PLAIN mechanism, unlike smtp.PlainAuth it sends the
+// password to remote servers without TLS when it is allowed
+type plainAuth struct {
+	username, password string
+	insecure           bool
+}
+
+func (a *plainAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !a.insecure {
+		return "", nil, ErrUnencryptedAuth
+	}
+	return "PLAIN", []byte("\x00" + a.username + "\x00" + a.password), nil
+}
+
+func (a *plainAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if more {
+		return nil, errors.New("unexpected smtp server challenge")
+	}
+	return nil, nil
+}
+
+// loginAuth is the LOGIN mechanism many relays still ask for
+type loginAuth struct {
+	username, password string
+	insecure           bool
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !a.insecure {
+		return "", nil, ErrUnencryptedAuth
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	}
+	return nil, fmt.Errorf("unexpected smtp server challenge %q", fromServer)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
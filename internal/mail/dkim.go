@@ -0,0 +1,179 @@
+package mail
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dkimHeaders are the headers signed when the message has them
+var dkimHeaders = []string{"from", "to", "subject", "date", "message-id", "mime-version", "content-type"}
+
+// whitespaces matches the runs of spaces and tabs the relaxed canonicalization
+// reduces to a space
+var whitespaces = regexp.MustCompile(`[ \t]+`)
+
+// DKIM signs the messages with the private key published in the DNS of the
+// domain under the selector, with the relaxed/relaxed canonicalization
+type DKIM struct {
+	domain    string
+	selector  string
+	algorithm string
+	signer    crypto.Signer
+}
+
+// LoadDKIM reads the PEM encoded RSA or Ed25519 private key of the domain
+func LoadDKIM(keyFile, domain, selector string) (*DKIM, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := parseDKIMKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("dkim key %s: %w", keyFile, err)
+	}
+	return NewDKIM(signer, domain, selector)
+}
+
+// NewDKIM creates the signer of the domain with an RSA or Ed25519 key
+func NewDKIM(signer crypto.Signer, domain, selector string) (*DKIM, error) {
+	if domain == "" || selector == "" {
+		return nil, errors.New("dkim domain and selector are required")
+	}
+
+	d := &DKIM{domain: domain, selector: selector, signer: signer}
+	switch signer.(type) {
+	case *rsa.PrivateKey:
+		d.algorithm = "rsa-sha256"
+	case ed25519.PrivateKey:
+		d.algorithm = "ed25519-sha256"
+	default:
+		return nil, errors.New("dkim key must be an RSA or Ed25519 key")
+	}
+	return d, nil
+}
+
+// Sign returns the message with its DKIM-Signature header
+func (d *DKIM) Sign(message []byte, now time.Time) ([]byte, error) {
+	header, body := message, []byte{}
+	if i := bytes.Index(message, []byte("\r\n\r\n")); i >= 0 {
+		header, body = message[:i+2], message[i+4:]
+	}
+	fields := splitHeader(string(header))
+
+	bodyHash := sha256.Sum256([]byte(relaxedBody(string(body))))
+	signed := []string{}
+	var data strings.Builder
+	for _, name := range dkimHeaders {
+		if field, ok := lastField(fields, name); ok {
+			signed = append(signed, name)
+			data.WriteString(relaxedHeader(field))
+		}
+	}
+
+	value := fmt.Sprintf("v=1; a=%s; c=relaxed/relaxed; d=%s; s=%s; t=%s; h=%s; bh=%s; b=",
+		d.algorithm, d.domain, d.selector, strconv.FormatInt(now.Unix(), 10),
+		strings.Join(signed, ":"), base64.StdEncoding.EncodeToString(bodyHash[:]))
+	data.WriteString(strings.TrimSuffix(relaxedHeader("DKIM-Signature: "+value), "\r\n"))
+
+	hash := sha256.Sum256([]byte(data.String()))
+	var signature []byte
+	var err error
+	if d.algorithm == "ed25519-sha256" {
+		signature, err = d.signer.Sign(rand.Reader, hash[:], crypto.Hash(0))
+	} else {
+		signature, err = d.signer.Sign(rand.Reader, hash[:], crypto.SHA256)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signedMessage := "DKIM-Signature: " + value + base64.StdEncoding.EncodeToString(signature) + "\r\n"
+	return append([]byte(signedMessage), message...), nil
+}
+
+// parseDKIMKey parses the PKCS #1 or PKCS #8 private key
+func parseDKIMKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported key type")
+	}
+	return signer, nil
+}
+
+// splitHeader splits the header block to its fields, folded lines stay in
+// their field
+func splitHeader(header string) []string {
+	fields := []string{}
+	for _, line := range strings.SplitAfter(header, "\r\n") {
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(fields) > 0 {
+			fields[len(fields)-1] += line
+			continue
+		}
+		fields = append(fields, line)
+	}
+	return fields
+}
+
+// lastField returns the last field with the name, it is the one receivers
+// verify when a header appears more than once
+func lastField(fields []string, name string) (string, bool) {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if colon := strings.Index(fields[i], ":"); colon > 0 &&
+			strings.EqualFold(strings.TrimSpace(fields[i][:colon]), name) {
+			return fields[i], true
+		}
+	}
+	return "", false
+}
+
+// relaxedHeader canonicalizes the header field with the relaxed algorithm of RFC 6376
+func relaxedHeader(field string) string {
+	colon := strings.Index(field, ":")
+	name := strings.ToLower(strings.TrimSpace(field[:colon]))
+	value := strings.NewReplacer("\r\n", "", "\n", "").Replace(field[colon+1:])
+	value = strings.TrimSpace(whitespaces.ReplaceAllString(value, " "))
+	return name + ":" + value + "\r\n"
+}
+
+// relaxedBody canonicalizes the body with the relaxed algorithm of RFC 6376
+func relaxedBody(body string) string {
+	lines := strings.Split(body, "\r\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(whitespaces.ReplaceAllString(line, " "), " ")
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
@@ -0,0 +1,110 @@
+package mail
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const dkimMessage = "From: Passwall <hello@passwall.io>\r\n" +
+	"To: jane@passwall.io\r\n" +
+	"Subject:  Passwall   Email\r\n" +
+	"\tVerification\r\n" +
+	"Mime-Version: 1.0\r\n" +
+	"\r\n" +
+	"Your code is  123456 \r\n" +
+	"\r\n\r\n"
+
+func TestRelaxedCanonicalization(t *testing.T) {
+	// The examples of RFC 6376 section 3.4.5
+	assert.Equal(t, "a:X\r\n", relaxedHeader("A: X\r\n"))
+	assert.Equal(t, "b:Y Z\r\n", relaxedHeader("B : Y\t\r\n\tZ  \r\n"))
+	assert.Equal(t, " C\r\nD E\r\n", relaxedBody(" C \r\nD \t E\r\n\r\n\r\n"))
+	assert.Equal(t, "", relaxedBody("\r\n\r\n"))
+}
+
+func TestDKIMSign(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	for _, signer := range []crypto.Signer{rsaKey, edKey} {
+		dkim, err := NewDKIM(signer, "passwall.io", "mail")
+		assert.NoError(t, err)
+
+		signed, err := dkim.Sign([]byte(dkimMessage), time.Unix(1622548800, 0))
+		assert.NoError(t, err)
+		assert.True(t, strings.HasSuffix(string(signed), dkimMessage))
+
+		field := strings.SplitN(string(signed), "\r\n", 2)[0]
+		tags := dkimTags(field)
+		assert.Equal(t, "passwall.io", tags["d"])
+		assert.Equal(t, "mail", tags["s"])
+		assert.Equal(t, "1622548800", tags["t"])
+		assert.Equal(t, "from:to:subject:mime-version", tags["h"])
+
+		bodyHash := sha256.Sum256([]byte("Your code is 123456\r\n"))
+		assert.Equal(t, base64.StdEncoding.EncodeToString(bodyHash[:]), tags["bh"])
+
+		// Verify the signature like a receiver does
+		data := "from:Passwall <hello@passwall.io>\r\n" +
+			"to:jane@passwall.io\r\n" +
+			"subject:Passwall Email Verification\r\n" +
+			"mime-version:1.0\r\n" +
+			strings.TrimSuffix(relaxedHeader(field[:strings.LastIndex(field, "b=")+2]), "\r\n")
+		hash := sha256.Sum256([]byte(data))
+		signature, err := base64.StdEncoding.DecodeString(tags["b"])
+		assert.NoError(t, err)
+
+		switch key := signer.(type) {
+		case *rsa.PrivateKey:
+			assert.Equal(t, "rsa-sha256", tags["a"])
+			assert.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], signature))
+		case ed25519.PrivateKey:
+			assert.Equal(t, "ed25519-sha256", tags["a"])
+			assert.True(t, ed25519.Verify(key.Public().(ed25519.PublicKey), hash[:], signature))
+		}
+	}
+}
+
+func TestLoadDKIM(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "dkim.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	assert.NoError(t, os.WriteFile(path, data, 0600))
+
+	_, err = LoadDKIM(path, "passwall.io", "mail")
+	assert.NoError(t, err)
+
+	_, err = LoadDKIM(path, "passwall.io", "")
+	assert.Error(t, err)
+
+	assert.NoError(t, os.WriteFile(path, []byte("not a key"), 0600))
+	_, err = LoadDKIM(path, "passwall.io", "mail")
+	assert.Error(t, err)
+}
+
+// dkimTags parses the tags of the DKIM-Signature header field
+func dkimTags(field string) map[string]string {
+	tags := map[string]string{}
+	for _, tag := range strings.Split(strings.TrimPrefix(field, "DKIM-Signature: "), ";") {
+		if parts := strings.SplitN(strings.TrimSpace(tag), "=", 2); len(parts) == 2 {
+			tags[parts[0]] = parts[1]
+		}
+	}
+	return tags
+}
@@ -0,0 +1,255 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/config"
+)
+
+// Connection encryptions
+const (
+	// EncryptionAuto uses implicit TLS on port 465 and STARTTLS on the other
+	// ports when the server offers it
+	EncryptionAuto     = "auto"
+	EncryptionSTARTTLS = "starttls"
+	EncryptionTLS      = "tls"
+	EncryptionNone     = "none"
+)
+
+// Auth mechanisms
+const (
+	// AuthAuto picks CRAM-MD5, PLAIN or LOGIN in this order of the ones the
+	// server offers
+	AuthAuto    = "auto"
+	AuthPlain   = "plain"
+	AuthLogin   = "login"
+	AuthCRAMMD5 = "cram-md5"
+)
+
+const (
+	// timeout limits dialing and every send on a connection
+	timeout = 30 * time.Second
+	// idleTimeout closes the pooled connections before the servers drop them
+	idleTimeout = 30 * time.Second
+)
+
+// ErrSTARTTLS represents message for servers which don't offer the required STARTTLS
+var ErrSTARTTLS = errors.New("smtp server doesn't support STARTTLS")
+
+// conn is an open SMTP session
+type conn struct {
+	net    net.Conn
+	client *smtp.Client
+	used   time.Time
+}
+
+// Pool sends the emails with the SMTP server of the configuration, it keeps
+// up to PoolSize idle connections open to reuse them for the next emails
+type Pool struct {
+	cfg  config.EmailConfiguration
+	dkim *DKIM
+
+	mu   sync.Mutex
+	idle []*conn
+}
+
+// NewPool creates the pool of the configuration, connections are opened when
+// they are needed. Emails are DKIM signed when a DKIM key is configured, the
+// domain of the sender is the signing domain unless another one is given.
+func NewPool(cfg config.EmailConfiguration) (*Pool, error) {
+	p := &Pool{cfg: cfg}
+	if cfg.DKIMKeyFile != "" {
+		domain := cfg.DKIMDomain
+		if domain == "" {
+			domain = cfg.FromEmail[strings.LastIndex(cfg.FromEmail, "@")+1:]
+		}
+		dkim, err := LoadDKIM(cfg.DKIMKeyFile, domain, cfg.DKIMSelector)
+		if err != nil {
+			return nil, err
+		}
+		p.dkim = dkim
+	}
+	return p, nil
+}
+
+// Config returns the configuration of the pool
+func (p *Pool) Config() config.EmailConfiguration {
+	return p.cfg
+}
+
+// Send sends the message from the address to the recipients, the message is
+// the whole email with its headers
+func (p *Pool) Send(from string, to []string, msg io.WriterTo) error {
+	if p.dkim != nil {
+		var buf bytes.Buffer
+		if _, err := msg.WriteTo(&buf); err != nil {
+			return err
+		}
+		signed, err := p.dkim.Sign(buf.Bytes(), time.Now())
+		if err != nil {
+			return err
+		}
+		msg = bytes.NewReader(signed)
+	}
+
+	c, err := p.get()
+	if err != nil {
+		return err
+	}
+
+	if err := send(c, from, to, msg); err != nil {
+		c.client.Close()
+		return err
+	}
+	p.put(c)
+	return nil
+}
+
+// Close closes the idle connections
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	for _, c := range idle {
+		c.client.Quit()
+	}
+	return nil
+}
+
+// get returns an idle connection still alive or opens a new one
+func (p *Pool) get() (*conn, error) {
+	for {
+		p.mu.Lock()
+		if len(p.idle) == 0 {
+			p.mu.Unlock()
+			return p.dial()
+		}
+		c := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		p.mu.Unlock()
+
+		if time.Since(c.used) > idleTimeout {
+			c.client.Close()
+			continue
+		}
+		c.net.SetDeadline(time.Now().Add(timeout))
+		if err := c.client.Noop(); err != nil {
+			c.client.Close()
+			continue
+		}
+		return c, nil
+	}
+}
+
+// put keeps the connection for the next emails or closes it when the pool is full
+func (p *Pool) put(c *conn) {
+	c.used = time.Now()
+
+	p.mu.Lock()
+	if len(p.idle) < p.cfg.PoolSize {
+		p.idle = append(p.idle, c)
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+	c.client.Quit()
+}
+
+// dial opens a session, secures it and authenticates as the configuration tells
+func (p *Pool) dial() (*conn, error) {
+	cfg := p.cfg
+	addr := net.JoinHostPort(cfg.Host, cfg.Port)
+	tlsConfig := &tls.Config{ServerName: cfg.Host, MinVersion: tls.VersionTLS12}
+	implicit := cfg.Encryption == EncryptionTLS || (cfg.Encryption == EncryptionAuto && cfg.Port == "465")
+
+	dialer := &net.Dialer{Timeout: timeout}
+	var nc net.Conn
+	var err error
+	if implicit {
+		nc, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		nc, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	nc.SetDeadline(time.Now().Add(timeout))
+
+	client, err := smtp.NewClient(nc, cfg.Host)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	if err := open(client, cfg, implicit, tlsConfig); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &conn{net: nc, client: client}, nil
+}
+
+// open greets the server, starts TLS on plain connections and authenticates
+func open(client *smtp.Client, cfg config.EmailConfiguration, implicit bool, tlsConfig *tls.Config) error {
+	if cfg.HeloName != "" {
+		if err := client.Hello(cfg.HeloName); err != nil {
+			return err
+		}
+	}
+
+	if !implicit && cfg.Encryption != EncryptionNone {
+		ok, _ := client.Extension("STARTTLS")
+		switch {
+		case ok:
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return err
+			}
+		case cfg.Encryption == EncryptionSTARTTLS:
+			return ErrSTARTTLS
+		}
+	}
+
+	if cfg.Username == "" {
+		return nil
+	}
+	auth, err := newAuth(client, cfg)
+	if err != nil {
+		return err
+	}
+	return client.Auth(auth)
+}
+
+// send sends a message in the session
+func send(c *conn, from string, to []string, msg io.WriterTo) error {
+	c.net.SetDeadline(time.Now().Add(timeout))
+	if err := c.client.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := c.client.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := msg.WriteTo(w); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp server rejected the message: %w", err)
+	}
+	return nil
+}
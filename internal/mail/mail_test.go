@@ -0,0 +1,179 @@
+package mail
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/passwall/passwall-server/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeServer is an SMTP server without TLS offering AUTH LOGIN and PLAIN
+type fakeServer struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	conns    int
+	helo     []string
+	auth     []string
+	messages []string
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			c, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns++
+			s.mu.Unlock()
+			go s.serve(c)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) config() config.EmailConfiguration {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return config.EmailConfiguration{
+		Host:       host,
+		Port:       port,
+		Username:   "jane",
+		Password:   "s3cr3t",
+		Encryption: EncryptionNone,
+		Auth:       AuthAuto,
+		PoolSize:   1,
+	}
+}
+
+func (s *fakeServer) serve(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+	reply := func(line string) { c.Write([]byte(line + "\r\n")) }
+	read := func() string {
+		line, _ := r.ReadString('\n')
+		return strings.TrimRight(line, "\r\n")
+	}
+	decode := func(line string) string {
+		data, _ := base64.StdEncoding.DecodeString(line)
+		return string(data)
+	}
+
+	reply("220 fake ESMTP")
+	for {
+		line := read()
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO", "HELO":
+			s.mu.Lock()
+			s.helo = append(s.helo, strings.TrimPrefix(line, verb+" "))
+			s.mu.Unlock()
+			reply("250-fake")
+			reply("250 AUTH LOGIN PLAIN")
+		case "AUTH":
+			fields := strings.Fields(line)
+			credentials := ""
+			if fields[1] == "PLAIN" {
+				credentials = strings.ReplaceAll(decode(fields[2]), "\x00", " ")
+			} else {
+				reply("334 " + base64.StdEncoding.EncodeToString([]byte("Username:")))
+				credentials = decode(read())
+				reply("334 " + base64.StdEncoding.EncodeToString([]byte("Password:")))
+				credentials += " " + decode(read())
+			}
+			s.mu.Lock()
+			s.auth = append(s.auth, fields[1]+" "+strings.TrimSpace(credentials))
+			s.mu.Unlock()
+			reply("235 authenticated")
+		case "DATA":
+			reply("354 go ahead")
+			var message strings.Builder
+			for line := read(); line != "."; line = read() {
+				message.WriteString(line + "\n")
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, message.String())
+			s.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		case "":
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+type message string
+
+func (m message) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write([]byte(m))
+	return int64(n), err
+}
+
+func TestPoolSend(t *testing.T) {
+	server := newFakeServer(t)
+	cfg := server.config()
+	cfg.HeloName = "mail.passwall.io"
+	pool, _ := NewPool(cfg)
+	defer pool.Close()
+
+	assert.NoError(t, pool.Send("hello@passwall.io", []string{"jane@passwall.io"}, message("Subject: one\r\n\r\nfirst")))
+	assert.NoError(t, pool.Send("hello@passwall.io", []string{"jane@passwall.io"}, message("Subject: two\r\n\r\nsecond")))
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	// The pooled connection is reused for the second email
+	assert.Equal(t, 1, server.conns)
+	assert.Equal(t, []string{"mail.passwall.io"}, server.helo)
+	assert.Equal(t, []string{"PLAIN jane s3cr3t"}, server.auth)
+	if assert.Len(t, server.messages, 2) {
+		assert.Contains(t, server.messages[1], "second")
+	}
+}
+
+func TestPoolAuth(t *testing.T) {
+	server := newFakeServer(t)
+	cfg := server.config()
+	cfg.Auth = AuthLogin
+	cfg.PoolSize = 0
+	pool, _ := NewPool(cfg)
+
+	assert.NoError(t, pool.Send("hello@passwall.io", []string{"jane@passwall.io"}, message("Subject: hi\r\n\r\nhi")))
+	server.mu.Lock()
+	assert.Equal(t, []string{"LOGIN jane s3cr3t"}, server.auth)
+	server.mu.Unlock()
+
+	// Passwords aren't sent in clear unless the encryption is none
+	cfg.Encryption = EncryptionAuto
+	pool, _ = NewPool(cfg)
+	err := pool.Send("hello@passwall.io", []string{"jane@passwall.io"}, message("hi"))
+	assert.True(t, errors.Is(err, ErrUnencryptedAuth), err)
+}
+
+func TestPoolRequiresSTARTTLS(t *testing.T) {
+	server := newFakeServer(t)
+	cfg := server.config()
+	cfg.Encryption = EncryptionSTARTTLS
+
+	pool, _ := NewPool(cfg)
+	err := pool.Send("hello@passwall.io", []string{"jane@passwall.io"}, message("hi"))
+	assert.Equal(t, ErrSTARTTLS, err)
+}
@@ -0,0 +1,217 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/passwall/passwall-server/internal/api"
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+)
+
+// apiPrefix matches the version prefix of the route templates
+var apiPrefix = regexp.MustCompile(`^/api(/v[0-9]+)?`)
+
+// auditSkipped are the routes using POST without changing anything, and the
+// ones recording their own audit events
+var auditSkipped = map[string]bool{
+	"/users/check-credentials":                   true,
+	"/users/reauthenticate":                      true,
+	"/reports/reused-passwords":                  true,
+	"/generate/passphrase":                       true,
+	"/tools/breach-check":                        true,
+	"/admin/subscriptions":                       true,
+	"/admin/users/force-password-change":         true,
+	"/users/support-consent":                     true,
+	"/admin/impersonate":                         true,
+	"/admin/deleted/{table}/{id:[0-9]+}/restore": true,
+}
+
+// auditViewed are the routes returning a decrypted vault item
+var auditViewed = map[string]bool{
+	"/logins/{id:[0-9]+}":        true,
+	"/bank-accounts/{id:[0-9]+}": true,
+	"/credit-cards/{id:[0-9]+}":  true,
+	"/notes/{id:[0-9]+}":         true,
+	"/emails/{id:[0-9]+}":        true,
+	"/servers/{id:[0-9]+}":       true,
+}
+
+// webhookItemTypes are the vault items whose creation is sent to the webhooks
+var webhookItemTypes = map[string]bool{
+	"logins":        true,
+	"bank-accounts": true,
+	"credit-cards":  true,
+	"notes":         true,
+	"emails":        true,
+	"servers":       true,
+}
+
+// maxCapturedBody is the size of the response kept to find the ID of the created item
+const maxCapturedBody = 64 << 10
+
+// auditSubActions are the last path segments naming the action instead of the
+// item, the item type is the segment before them
+var auditSubActions = map[string]string{
+	"bulk-update":            "bulk_update",
+	"batch":                  "batch",
+	"merge":                  "merge",
+	"migrate":                "migrate",
+	"resolve":                "resolve",
+	"cancel":                 "cancel",
+	"accept":                 "accept",
+	"change-master-password": "change_master_password",
+	"import":                 app.AuditActionImport,
+	"jobs":                   app.AuditActionImport,
+	"backup":                 app.AuditActionImport,
+}
+
+// Audit is a route level middleware that records the action of the request to
+// the audit log before running it. The request fails if the event can't be recorded.
+func Audit(s storage.Store, action string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		event := api.NewAuditEvent(r, action, mux.Vars(r)["type"], 0)
+		if err := app.RecordAuditEvent(s, event); err != nil {
+			api.RespondWithError(w, http.StatusInternalServerError, "Audit event couldn't be recorded")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// AuditRequests is a middleware of the API routers that records every
+// successful create, update and delete and the views of the vault items to the
+// audit log. The action and the item come from the route, POST creates, PUT
+// updates and DELETE deletes unless the path ends with an action like /logins/merge.
+// The reads of the admins impersonating a user are recorded as support access.
+func AuditRequests(s storage.Store) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			template, _ := mux.CurrentRoute(r).GetPathTemplate()
+			template = apiPrefix.ReplaceAllString(template, "")
+			safe := r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions
+			viewed := r.Method == http.MethodGet && auditViewed[template]
+			// Every request of the admins impersonating a user is recorded
+			_, impersonated := r.Context().Value("impersonator_id").(uint)
+			if !viewed && !impersonated && (safe || auditSkipped[template]) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			sw := &statusWriter{ResponseWriter: w, capture: r.Method == http.MethodPost}
+			next.ServeHTTP(sw, r)
+			if sw.status >= http.StatusBadRequest {
+				return
+			}
+
+			action, itemType, itemVar := auditRoute(r.Method, template)
+			if viewed {
+				action = app.AuditActionView
+			}
+			if impersonated && safe {
+				action = app.AuditActionSupportAccess
+			}
+			itemID, _ := strconv.ParseUint(mux.Vars(r)[itemVar], 10, 64)
+			// Created items have their ID only in the response
+			if itemID == 0 && action == app.AuditActionCreate {
+				itemID = uint64(createdID(sw.body))
+			}
+			event := api.NewAuditEvent(r, action, itemType, uint(itemID))
+			api.RecordAuditEvent(s, r, event)
+
+			if action == app.AuditActionCreate && webhookItemTypes[itemType] {
+				app.DispatchWebhookEvent(s, app.WebhookEventItemCreated, event.UserID, map[string]interface{}{
+					"item_type": itemType,
+					"item_id":   event.ItemID,
+				})
+			}
+		})
+	}
+}
+
+// auditRoute returns the action, the item type and the variable of the item ID
+// of the route template
+func auditRoute(method, template string) (action, itemType, itemVar string) {
+	switch method {
+	case http.MethodPost:
+		action = app.AuditActionCreate
+	case http.MethodDelete:
+		action = app.AuditActionDelete
+	default:
+		action = app.AuditActionUpdate
+	}
+
+	segments := strings.Split(strings.Trim(template, "/"), "/")
+	last := -1
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, "{") {
+			last = i
+		}
+	}
+	if last < 0 {
+		return action, "", ""
+	}
+
+	subAction, ok := auditSubActions[segments[last]]
+	// Items added to a collection are shared with its members
+	if segments[last] == "items" {
+		subAction, ok = app.AuditActionShare, true
+		if method == http.MethodDelete {
+			subAction = app.AuditActionUnshare
+		}
+	}
+	if ok && last > 0 {
+		action = subAction
+		last--
+		for last > 0 && strings.HasPrefix(segments[last], "{") {
+			last--
+		}
+	}
+
+	itemType = segments[last]
+	if last+1 < len(segments) && strings.HasPrefix(segments[last+1], "{") {
+		itemVar = strings.SplitN(strings.Trim(segments[last+1], "{}"), ":", 2)[0]
+	}
+	return action, itemType, itemVar
+}
+
+// createdID returns the ID of the item in the response body, 0 if it has none
+func createdID(body []byte) uint {
+	var created struct {
+		ID uint `json:"id"`
+	}
+	if err := json.Unmarshal(body, &created); err != nil {
+		return 0
+	}
+	return created.ID
+}
+
+// statusWriter keeps the status of the response and the beginning of its body
+// when capture is set
+type statusWriter struct {
+	http.ResponseWriter
+	status  int
+	capture bool
+	body    []byte
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if sw.status == 0 {
+		sw.status = status
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	if sw.capture && len(sw.body) < maxCapturedBody {
+		sw.body = append(sw.body, b...)
+	}
+	return sw.ResponseWriter.Write(b)
+}
@@ -3,16 +3,43 @@ package router
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/passwall/passwall-server/internal/api"
 	"github.com/passwall/passwall-server/internal/app"
 	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/pkg/cache"
+	"github.com/passwall/passwall-server/pkg/logger"
 	"github.com/passwall/passwall-server/pkg/token"
 	"github.com/urfave/negroni"
 )
 
+// passwordChangeRoutes are the routes users asked to change the master password
+// can use before they change it
+var passwordChangeRoutes = map[string]bool{
+	"/users/change-master-password": true,
+	"/users/check-credentials":      true,
+	"/users/reauthenticate":         true,
+	"/config":                       true,
+	"/announcements":                true,
+}
+
+// supportRoutes are the routes admins impersonating a user can read, none of
+// them returns vault items
+var supportRoutes = map[string]bool{
+	"/config":                true,
+	"/account/usage":         true,
+	"/announcements":         true,
+	"/audit":                 true,
+	"/alerts":                true,
+	"/webhooks":              true,
+	"/organizations":         true,
+	"/organizations/regions": true,
+}
+
 // Auth is a middleware that checks for a valid JWT token
-func Auth(s storage.Store) negroni.HandlerFunc {
+func Auth(s storage.Store, c cache.Cache) negroni.HandlerFunc {
 
 	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
 
@@ -30,6 +57,18 @@ func Auth(s storage.Store) negroni.HandlerFunc {
 		}
 		claims, _ := token.Claims.(jwt.MapClaims)
 
+		// Tokens revoked by signout are rejected on every instance
+		revoked, err := app.IsTokenRevoked(c, claims)
+		if err != nil {
+			logger.WithContext(r.Context()).Errorf("can't check revoked token error: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if revoked {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
 		// Get User UUID from claims
 		ctxUserUUID, ok := claims["user_uuid"].(string)
 		if !ok {
@@ -37,8 +76,9 @@ func Auth(s storage.Store) negroni.HandlerFunc {
 			return
 		}
 
-		// Get user details from db by User UUID
-		user, err := s.Users().FindByUUID(ctxUserUUID)
+		// Get user details by User UUID, from the cache for a while after the
+		// first request
+		user, err := s.Users().FindCachedByUUID(ctxUserUUID)
 		if err != nil {
 			w.WriteHeader(http.StatusUnauthorized)
 			return
@@ -51,6 +91,47 @@ func Auth(s storage.Store) negroni.HandlerFunc {
 			return
 		}
 
+		// Tokens issued before the user revoked all the sessions are rejected
+		if !app.TokenEpochValid(user, claims) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		// Users scheduled for deletion are deactivated until they cancel it
+		if user.DeletionScheduledAt != nil {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		// Users disabled by an administrator lose access with their current tokens too
+		if user.DisabledAt != nil {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		// Messages are in the language the user prefers
+		if user.Locale != "" {
+			api.SetLocale(w, user.Locale)
+		}
+
+		// Sessions older than the lifetime of the security policy sign in again
+		policy, err := app.FindSecurityPolicy(s)
+		if err != nil {
+			logger.WithContext(r.Context()).Errorf("can't find security policy error: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if app.SessionExpired(policy, claims, time.Now()) {
+			api.RespondWithError(w, http.StatusUnauthorized, app.ErrSessionExpired.Error())
+			return
+		}
+
+		// Users asked to change the master password can't use the vault until they do
+		if user.MasterPasswordReset && !passwordChangeRoutes[apiPrefix.ReplaceAllString(r.URL.Path, "")] {
+			api.RespondWithError(w, http.StatusForbidden, app.ErrPasswordChangeRequired.Error())
+			return
+		}
+
 		// Admin or Member
 		ctxAuthorized, ok := claims["authorized"].(bool)
 		if !ok {
@@ -58,16 +139,49 @@ func Auth(s storage.Store) negroni.HandlerFunc {
 			return
 		}
 
+		// Tokens issued before scopes were introduced get the default scopes,
+		// sessions signed in before the account scope too. Scoped tokens keep
+		// only their scopes.
+		ctxScopes, ok := app.ScopesFromClaims(claims)
+		if !ok || !app.ScopedToken(claims) && !app.HasScope(ctxScopes, app.ScopeAccount) {
+			ctxScopes = app.DefaultScopes(user)
+		}
+
+		// Admins impersonating the user only read the allowed routes while the consent lasts
+		ctxImpersonator, impersonated := app.ImpersonatorFromClaims(claims)
+		if impersonated {
+			_, _, consented, err := app.FindSupportConsent(c, user.ID, time.Now())
+			if err != nil {
+				logger.WithContext(r.Context()).Errorf("can't find support consent error: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if !consented {
+				api.RespondWithError(w, http.StatusUnauthorized, app.ErrNoSupportConsent.Error())
+				return
+			}
+			if r.Method != http.MethodGet || !supportRoutes[apiPrefix.ReplaceAllString(r.URL.Path, "")] {
+				api.RespondWithError(w, http.StatusForbidden, app.ErrSupportSession.Error())
+				return
+			}
+			ctxAuthorized, ctxScopes = false, []string{app.ScopeVaultRead, app.ScopeAccount}
+		}
+
 		ctxSchema := user.Schema
 
 		ctx := r.Context()
 		ctxWithUUID := context.WithValue(ctx, "uuid", ctxUserUUID)
 		ctxWithAuthorized := context.WithValue(ctxWithUUID, "authorized", ctxAuthorized)
 		ctxWithSchema := context.WithValue(ctxWithAuthorized, "schema", ctxSchema)
+		ctxWithScopes := context.WithValue(ctxWithSchema, "scopes", ctxScopes)
+		ctxWithUserID := context.WithValue(ctxWithScopes, "user_id", user.ID)
+		if impersonated {
+			ctxWithUserID = context.WithValue(ctxWithUserID, "impersonator_id", ctxImpersonator)
+		}
 		// These context variables can be accesable with
 		// ctxAuthorized := r.Context().Value("authorized").(bool)
 		// ctxID := r.Context().Value("id").(float64)
 
-		next(w, r.WithContext(ctxWithSchema))
+		next(w, r.WithContext(ctxWithUserID))
 	})
 }
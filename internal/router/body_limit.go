@@ -0,0 +1,53 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/passwall/passwall-server/internal/config"
+)
+
+const (
+	// defaultMaxBodySize is used when the configuration has no body limit
+	defaultMaxBodySize = 1 << 20
+	// defaultMaxImportSize is used when the configuration has no import limit
+	defaultMaxImportSize = 32 << 20
+)
+
+// uploadPaths are the endpoints receiving files, they have the import limit
+var uploadPaths = []string{"/system/import", "/import/jobs", "/import/backup"}
+
+// BodyLimit is a middleware that limits the size of the request bodies, so the
+// JSON decoders can't be made to read oversized payloads. Requests declaring a
+// larger body are rejected before reading it, the others fail when the limit
+// is reached.
+func BodyLimit(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	limit := bodyLimit(r.URL.Path)
+	if r.ContentLength > limit {
+		w.Header().Add("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		w.Write([]byte("Request body is too large."))
+		return
+	}
+	if r.Body != nil {
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+	}
+	next(w, r)
+}
+
+// bodyLimit is the size limit of the request body of the path in bytes
+func bodyLimit(path string) int64 {
+	server := config.Current().Server
+	for _, uploadPath := range uploadPaths {
+		if strings.HasSuffix(path, uploadPath) {
+			if server.MaxImportSize > 0 {
+				return server.MaxImportSize
+			}
+			return defaultMaxImportSize
+		}
+	}
+	if server.MaxBodySize > 0 {
+		return server.MaxBodySize
+	}
+	return defaultMaxBodySize
+}
@@ -0,0 +1,167 @@
+package router
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/passwall/passwall-server/internal/config"
+)
+
+// defaultCompressMinSize is used when the configuration has no compression threshold
+const defaultCompressMinSize = 1024
+
+// gzipWriters are reused between the responses, a gzip writer allocates a lot
+var gzipWriters = sync.Pool{New: func() interface{} { return gzip.NewWriter(nil) }}
+
+// Compress is a middleware that gzips the JSON responses of the clients
+// accepting gzip. Small responses are sent as they are, compressing them costs
+// more than it saves.
+func Compress(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	w.Header().Add("Vary", "Accept-Encoding")
+	if r.Method == http.MethodHead || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+		next(w, r)
+		return
+	}
+
+	cw := &compressWriter{ResponseWriter: w, minSize: compressMinSize()}
+	defer cw.Close()
+	next(cw, r)
+}
+
+// compressMinSize is the size of the smallest response compressed in bytes
+func compressMinSize() int {
+	if size := config.Current().Server.CompressMinSize; size > 0 {
+		return size
+	}
+	return defaultCompressMinSize
+}
+
+// acceptsGzip checks if the Accept-Encoding header allows gzip
+func acceptsGzip(header string) bool {
+	for _, value := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(value), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		q := strings.TrimPrefix(strings.TrimSpace(params), "q=")
+		if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// compressWriter keeps the response until it reaches the minimum size, then
+// decides whether to compress it
+type compressWriter struct {
+	http.ResponseWriter
+	minSize int
+	status  int
+	buf     []byte
+	gz      *gzip.Writer
+	started bool
+}
+
+// WriteHeader keeps the status until the body decides the encoding
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.status == 0 {
+		cw.status = status
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.gz != nil {
+		return cw.gz.Write(b)
+	}
+	if cw.started {
+		return cw.ResponseWriter.Write(b)
+	}
+	// Streams and files aren't compressed, they are sent without waiting
+	if cw.Header().Get("Content-Type") != "" && !cw.compressible() {
+		if err := cw.start(false); err != nil {
+			return 0, err
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) >= cw.minSize {
+		if err := cw.start(cw.compressible()); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// compressible checks if the response is JSON which isn't encoded already
+func (cw *compressWriter) compressible() bool {
+	header := cw.Header()
+	return strings.Contains(header.Get("Content-Type"), "json") && header.Get("Content-Encoding") == ""
+}
+
+// start writes the header and the kept body, gzipped or as it is
+func (cw *compressWriter) start(compress bool) error {
+	cw.started = true
+	if compress {
+		header := cw.Header()
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		// Gzipped body is a different representation, its ETag can only be weak
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+	}
+	if cw.status != 0 {
+		cw.ResponseWriter.WriteHeader(cw.status)
+	}
+
+	buf := cw.buf
+	cw.buf = nil
+	if !compress {
+		_, err := cw.ResponseWriter.Write(buf)
+		return err
+	}
+	cw.gz = gzipWriters.Get().(*gzip.Writer)
+	cw.gz.Reset(cw.ResponseWriter)
+	_, err := cw.gz.Write(buf)
+	return err
+}
+
+// Flush decides the encoding of the kept response and sends what is written
+// so far, the streaming handlers can't wait for the minimum size
+func (cw *compressWriter) Flush() {
+	if !cw.started {
+		if err := cw.start(cw.compressible()); err != nil {
+			return
+		}
+	}
+	if cw.gz != nil {
+		if err := cw.gz.Flush(); err != nil {
+			return
+		}
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close sends the responses smaller than the minimum size and finishes the gzip stream
+func (cw *compressWriter) Close() {
+	if !cw.started {
+		if cw.status == 0 && len(cw.buf) == 0 {
+			return
+		}
+		cw.start(false)
+		return
+	}
+	if cw.gz != nil {
+		cw.gz.Close()
+		gzipWriters.Put(cw.gz)
+		cw.gz = nil
+	}
+}
@@ -0,0 +1,46 @@
+package router
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressFlush(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/notifications/stream", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+
+	// Streams are sent as they are as soon as they are flushed
+	Compress(rec, req, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("retry: 5000\n\n"))
+		w.(http.Flusher).Flush()
+		assert.True(t, rec.Flushed)
+		assert.Equal(t, "retry: 5000\n\n", rec.Body.String())
+	})
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+
+	// Flushed JSON is gzipped without waiting for the minimum size
+	rec = httptest.NewRecorder()
+	Compress(rec, req, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":1}`))
+		w.(http.Flusher).Flush()
+		assert.True(t, rec.Flushed)
+		assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+
+		gz, err := gzip.NewReader(strings.NewReader(rec.Body.String()))
+		require.NoError(t, err)
+		body := make([]byte, len(`{"id":1}`))
+		_, err = io.ReadFull(gz, body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"id":1}`, string(body))
+	})
+}
@@ -2,13 +2,21 @@ package router
 
 import (
 	"net/http"
+
+	"github.com/passwall/passwall-server/internal/config"
 )
 
-// CORS ...
+// CORS allows the origins of the configuration, every origin is allowed when
+// it has none. Origins are read for every request so they can be reloaded.
 func CORS(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-	w.Header().Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
+	origin := r.Header.Get("Origin")
+	w.Header().Add("Vary", "Origin")
+	if allowedOrigin(config.Current().Server.CORSOrigins, origin) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
 	w.Header().Set("Access-Control-Allow-Credentials", "true")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Organization-ID, X-Request-ID")
+	w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, HEAD")
 	if r.Method == "OPTIONS" {
 		w.WriteHeader(204)
@@ -16,3 +24,16 @@ func CORS(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
 	}
 	next(w, r)
 }
+
+// allowedOrigin reports whether the origin is in the origins, * allows every origin
+func allowedOrigin(origins []string, origin string) bool {
+	if len(origins) == 0 {
+		return true
+	}
+	for _, o := range origins {
+		if o == "*" || o == origin {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package router
+
+import (
+	"database/sql"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	runtimepprof "runtime/pprof"
+	"sync"
+
+	"github.com/urfave/negroni"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/pkg/cache"
+)
+
+// publishPoolStats publishes the pool stats once, expvar names can't be reused
+var publishPoolStats sync.Once
+
+// NewDebug returns the handler of the debug listener: pprof profiles, expvar
+// variables and a goroutine dump. Every endpoint needs a token with the admin
+// scope, the listener should still be reachable only from the internal network.
+func NewDebug(s storage.Store, c cache.Cache) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", RequireScope(app.ScopeAdmin, pprof.Index))
+	mux.HandleFunc("/debug/pprof/cmdline", RequireScope(app.ScopeAdmin, pprof.Cmdline))
+	mux.HandleFunc("/debug/pprof/profile", RequireScope(app.ScopeAdmin, pprof.Profile))
+	mux.HandleFunc("/debug/pprof/symbol", RequireScope(app.ScopeAdmin, pprof.Symbol))
+	mux.HandleFunc("/debug/pprof/trace", RequireScope(app.ScopeAdmin, pprof.Trace))
+	publishPoolStats.Do(func() { expvar.Publish("database_pools", expvar.Func(poolStats(s))) })
+	mux.HandleFunc("/debug/vars", RequireScope(app.ScopeAdmin, expvar.Handler().ServeHTTP))
+	mux.HandleFunc("/debug/goroutines", RequireScope(app.ScopeAdmin, goroutineDump))
+
+	recovery := negroni.NewRecovery()
+	recovery.Logger = recoveryLogger{}
+	return negroni.New(negroni.HandlerFunc(RequestID), recovery, Auth(s, c), negroni.Wrap(mux))
+}
+
+// poolStats returns the stats of the connection pools of the database and its
+// regions, the main one is named main
+func poolStats(s storage.Store) func() interface{} {
+	return func() interface{} {
+		stats := map[string]sql.DBStats{}
+		if main, err := s.PoolStats(); err == nil {
+			stats["main"] = main
+		}
+		for _, name := range s.RegionNames() {
+			region, err := s.Region(name)
+			if err != nil {
+				continue
+			}
+			if regionStats, err := region.PoolStats(); err == nil {
+				stats[name] = regionStats
+			}
+		}
+		return stats
+	}
+}
+
+// goroutineDump writes the stack traces of all the goroutines
+func goroutineDump(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	runtimepprof.Lookup("goroutine").WriteTo(w, 2)
+}
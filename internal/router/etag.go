@@ -0,0 +1,48 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+// ETag wraps the list handlers of the vault to return the vault revision as
+// ETag. Requests whose If-None-Match has the current revision get 304 Not Modified.
+func ETag(handler func(storage.Store) http.HandlerFunc) func(storage.Store) http.HandlerFunc {
+	return func(s storage.Store) http.HandlerFunc {
+		next := handler(s)
+		return func(w http.ResponseWriter, r *http.Request) {
+			schema, _ := r.Context().Value("schema").(string)
+			revision, err := app.VaultRevision(s, schema)
+			if err != nil {
+				logger.Errorf("Error while computing vault revision of schema %s: %v", schema, err)
+				next(w, r)
+				return
+			}
+
+			etag := `"` + revision + `"`
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Cache-Control", "private, no-cache")
+			if etagMatches(r.Header.Get("If-None-Match"), etag) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+// etagMatches checks if the If-None-Match header has the ETag, weak
+// comparison is used as in RFC 7232
+func etagMatches(header, etag string) bool {
+	for _, value := range strings.Split(header, ",") {
+		value = strings.TrimPrefix(strings.TrimSpace(value), "W/")
+		if value == "*" || value == etag {
+			return true
+		}
+	}
+	return false
+}
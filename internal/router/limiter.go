@@ -1,24 +1,63 @@
 package router
 
 import (
+	"fmt"
 	"net/http"
+	"time"
 
-	"github.com/didip/tollbooth"
+	"github.com/passwall/passwall-server/internal/api"
+	"github.com/passwall/passwall-server/internal/config"
+	"github.com/passwall/passwall-server/pkg/cache"
+	"github.com/passwall/passwall-server/pkg/logger"
 	"github.com/urfave/negroni"
 )
 
-// LimitHandler ...
-func LimitHandler() negroni.HandlerFunc {
-	lmt := tollbooth.NewLimiter(5, nil)
+const (
+	// defaultRequestLimit is used when the configuration has no rate limit
+	defaultRequestLimit = 5
+	// limitWindow is the duration of a rate limit window
+	limitWindow = time.Second
+)
 
+// LimitHandler limits the requests by IP, counters are kept in the cache so
+// the limit is shared by all the server instances. Limit is read for every
+// request so reloading the configuration changes it.
+func LimitHandler(c cache.Cache) negroni.HandlerFunc {
 	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-		httpError := tollbooth.LimitByRequest(lmt, w, r)
-		if httpError != nil {
-			w.Header().Add("Content-Type", lmt.GetMessageContentType())
-			w.WriteHeader(httpError.StatusCode)
-			w.Write([]byte(httpError.Message))
+		count, err := c.Incr(rateLimitKey(api.RequestIP(r), time.Now()), limitWindow)
+		if err != nil {
+			// Requests aren't blocked while the cache is down
+			logger.WithContext(r.Context()).Errorf("can't count requests error: %v", err)
+		}
+		if count > requestLimit() {
+			w.Header().Add("Content-Type", "text/plain; charset=utf-8")
+			w.Header().Add("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte("You have reached maximum request limit."))
 			return
 		}
 		next(w, r)
 	})
 }
+
+// requestLimit is the number of the requests an IP can make in a rate limit window
+func requestLimit() int64 {
+	if limit := config.Current().Server.RateLimit; limit > 0 {
+		return int64(limit)
+	}
+	return defaultRequestLimit
+}
+
+// rateLimitKey is the counter key of the IP in the window of the time
+func rateLimitKey(ip string, now time.Time) string {
+	return fmt.Sprintf("rate-limit:%s:%d", ip, now.Truncate(limitWindow).Unix())
+}
+
+// Limit counts the requests of the handler in the rate limit of the IP, it is
+// used by the endpoints sending codes outside of the /auth routes
+func Limit(c cache.Cache, next http.HandlerFunc) http.HandlerFunc {
+	limiter := LimitHandler(c)
+	return func(w http.ResponseWriter, r *http.Request) {
+		limiter(w, r, next)
+	}
+}
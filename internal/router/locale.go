@@ -0,0 +1,17 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/passwall/passwall-server/internal/api"
+	"github.com/passwall/passwall-server/internal/i18n"
+)
+
+// Locale is a middleware that writes the messages of the response in the
+// language the Accept-Language header prefers. Auth replaces it with the
+// preference of the signed in user.
+func Locale(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	api.SetLocale(w, i18n.Match(r.Header.Get("Accept-Language")))
+	w.Header().Add("Vary", "Accept-Language")
+	next(w, r)
+}
@@ -0,0 +1,57 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/urfave/negroni"
+
+	"github.com/passwall/passwall-server/internal/api"
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+// maintenanceRetryAfter is the seconds clients are told to wait in maintenance
+const maintenanceRetryAfter = "300"
+
+// maintenanceAllowed are the routes changing data which work in maintenance:
+// sessions, the POST routes which only read and switching the mode itself
+var maintenanceAllowed = map[string]bool{
+	"/auth/signin":              true,
+	"/auth/signout":             true,
+	"/auth/refresh":             true,
+	"/auth/check":               true,
+	"/auth/sessions/revoke-all": true,
+	"/users/check-credentials":  true,
+	"/users/reauthenticate":     true,
+	"/reports/reused-passwords": true,
+	"/generate/passphrase":      true,
+	"/tools/breach-check":       true,
+	"/admin/maintenance":        true,
+}
+
+// Maintenance is a middleware that rejects the requests changing data with 503
+// while the server is in maintenance, reads keep working
+func Maintenance(s storage.Store) negroni.HandlerFunc {
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next(w, r)
+			return
+		}
+		if maintenanceAllowed[apiPrefix.ReplaceAllString(r.URL.Path, "")] {
+			next(w, r)
+			return
+		}
+
+		maintenance, err := app.FindMaintenance(s)
+		if err != nil {
+			logger.WithContext(r.Context()).Errorf("can't find maintenance mode error: %v", err)
+		} else if maintenance.Enabled {
+			w.Header().Set("Retry-After", maintenanceRetryAfter)
+			api.RespondWithError(w, http.StatusServiceUnavailable, app.ErrMaintenance.Error())
+			return
+		}
+		next(w, r)
+	})
+}
@@ -0,0 +1,137 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/passwall/passwall-server/internal/api"
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/model"
+	"github.com/urfave/negroni"
+)
+
+// OrganizationHeader selects the organization vault of the request
+const OrganizationHeader = "X-Organization-ID"
+
+// Organization is a middleware that switches the request to an organization vault
+// when OrganizationHeader is set. It must run after Auth middleware.
+func Organization(s storage.Store) negroni.HandlerFunc {
+
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+
+		orgHeader := r.Header.Get(OrganizationHeader)
+		if orgHeader == "" {
+			next(w, r)
+			return
+		}
+
+		orgID, err := strconv.Atoi(orgHeader)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		userID, ok := r.Context().Value("user_id").(uint)
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		// Only accepted members can access the organization vault
+		member, err := s.Organizations().FindMember(uint(orgID), userID)
+		if err != nil || member.Status != model.MemberStatusAccepted {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		org, err := s.Organizations().FindByID(uint(orgID))
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		// Route the request to the store of the organization region once
+		vault, err := s.Region(org.Region)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		ctx := r.Context()
+		ctxWithSchema := context.WithValue(ctx, "schema", org.Schema)
+		ctxWithOrgID := context.WithValue(ctxWithSchema, "organization_id", org.ID)
+		ctxWithOrgRole := context.WithValue(ctxWithOrgID, "organization_role", member.Role)
+		ctxWithStore := context.WithValue(ctxWithOrgRole, "store", vault)
+
+		next(w, r.WithContext(ctxWithStore))
+	})
+}
+
+// collectionItemRoutes are the routes returning a single vault item, with the
+// type of the item in the collections
+var collectionItemRoutes = map[string]string{
+	"/logins/{id:[0-9]+}":        "login",
+	"/bank-accounts/{id:[0-9]+}": "bank_account",
+	"/credit-cards/{id:[0-9]+}":  "credit_card",
+	"/notes/{id:[0-9]+}":         "note",
+	"/emails/{id:[0-9]+}":        "email",
+	"/servers/{id:[0-9]+}":       "server",
+}
+
+// organizationAccess checks the role of the member in the organization vault
+// the request is routed to. Owners, admins and editors change and read the
+// whole vault, members only read the items of the collections they are given
+// one by one; the lists, syncs and exports of the vault are rejected for them.
+func (r *Router) organizationAccess(req *http.Request) bool {
+	// Requests to the vault of the user have no organization role
+	role, ok := req.Context().Value("organization_role").(string)
+	if !ok || app.CanEditOrganizationVault(role) {
+		return true
+	}
+	if req.Method != http.MethodGet {
+		return false
+	}
+
+	template, _ := mux.CurrentRoute(req).GetPathTemplate()
+	itemType, ok := collectionItemRoutes[apiPrefix.ReplaceAllString(template, "")]
+	if !ok {
+		return false
+	}
+	itemID, err := strconv.ParseUint(mux.Vars(req)["id"], 10, 64)
+	if err != nil {
+		return false
+	}
+	orgID, _ := req.Context().Value("organization_id").(uint)
+	userID, _ := req.Context().Value("user_id").(uint)
+	given, err := r.store.Collections().HasMemberItem(orgID, userID, itemType, uint(itemID))
+	return err == nil && given
+}
+
+// vault builds the handler with the store the request is routed to by
+// Organization middleware, falling back to the primary store. Responses get
+// the revision counter of the vault in VaultRevisionHeader.
+func (r *Router) vault(handler func(storage.Store) http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if !r.organizationAccess(req) {
+			api.RespondWithError(w, http.StatusForbidden, "You are not allowed to access this item of the organization vault")
+			return
+		}
+
+		store, ok := req.Context().Value("store").(storage.Store)
+		if !ok {
+			store = r.store
+		}
+		// Vaults read the first time move their older fields to their data key
+		if schema, ok := req.Context().Value("schema").(string); ok && req.Method == http.MethodGet {
+			app.ReencryptVault(store, schema)
+		}
+		// Vault rows record the principal which created and changed them
+		if principal := api.RequestPrincipal(req); principal != "" {
+			store = store.As(principal)
+		}
+		handler(store)(newRevisionWriter(w, req, store), req)
+	}
+}
@@ -0,0 +1,80 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/internal/storage/migration"
+	"github.com/passwall/passwall-server/internal/storage/sqlite"
+	"github.com/passwall/passwall-server/model"
+)
+
+func TestOrganizationAccess(t *testing.T) {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "passwall.db"), &gorm.Config{})
+	require.NoError(t, err)
+	s := storage.New(db)
+	require.NoError(t, s.Migrations().Up(migration.SetSystem, ""))
+
+	// The member is given the collection with the login 1, the login 2 isn't shared
+	collection, err := s.Collections().Create(&model.Collection{OrganizationID: 1, Name: "Shared"})
+	require.NoError(t, err)
+	_, err = s.Collections().AddItem(&model.CollectionItem{CollectionID: collection.ID, ItemType: "login", ItemID: 1})
+	require.NoError(t, err)
+	_, err = s.Collections().AddMember(&model.CollectionMember{CollectionID: collection.ID, UserID: 3})
+	require.NoError(t, err)
+
+	r := &Router{store: s}
+	handler := func(storage.Store) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}
+	}
+	router := mux.NewRouter().PathPrefix("/api/v1").Subrouter()
+	router.HandleFunc("/logins", r.vault(handler)).Methods(http.MethodGet, http.MethodPost)
+	router.HandleFunc("/logins/{id:[0-9]+}", r.vault(handler)).Methods(http.MethodGet, http.MethodPut)
+
+	tests := []struct {
+		name     string
+		userID   uint
+		role     string
+		method   string
+		path     string
+		expected int
+	}{
+		{name: "Admin lists", userID: 1, role: model.OrganizationRoleAdmin, method: http.MethodGet, path: "/api/v1/logins", expected: http.StatusOK},
+		{name: "Admin reads unshared", userID: 1, role: model.OrganizationRoleAdmin, method: http.MethodGet, path: "/api/v1/logins/2", expected: http.StatusOK},
+		{name: "Admin creates", userID: 1, role: model.OrganizationRoleAdmin, method: http.MethodPost, path: "/api/v1/logins", expected: http.StatusOK},
+		{name: "Editor updates", userID: 2, role: model.OrganizationRoleEditor, method: http.MethodPut, path: "/api/v1/logins/2", expected: http.StatusOK},
+		{name: "Member reads shared", userID: 3, role: model.OrganizationRoleMember, method: http.MethodGet, path: "/api/v1/logins/1", expected: http.StatusOK},
+		{name: "Member reads unshared", userID: 3, role: model.OrganizationRoleMember, method: http.MethodGet, path: "/api/v1/logins/2", expected: http.StatusForbidden},
+		{name: "Member lists", userID: 3, role: model.OrganizationRoleMember, method: http.MethodGet, path: "/api/v1/logins", expected: http.StatusForbidden},
+		{name: "Member creates", userID: 3, role: model.OrganizationRoleMember, method: http.MethodPost, path: "/api/v1/logins", expected: http.StatusForbidden},
+		{name: "Member updates shared", userID: 3, role: model.OrganizationRoleMember, method: http.MethodPut, path: "/api/v1/logins/1", expected: http.StatusForbidden},
+		{name: "Other member reads shared", userID: 4, role: model.OrganizationRoleMember, method: http.MethodGet, path: "/api/v1/logins/1", expected: http.StatusForbidden},
+		{name: "Personal vault", userID: 3, method: http.MethodPost, path: "/api/v1/logins", expected: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), "user_id", tt.userID)
+			// Organization middleware sets the organization and the role of the member
+			if tt.role != "" {
+				ctx = context.WithValue(ctx, "organization_id", uint(1))
+				ctx = context.WithValue(ctx, "organization_role", tt.role)
+			}
+			req := httptest.NewRequest(tt.method, tt.path, nil).WithContext(ctx)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/passwall/passwall-server/internal/api"
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+// ExportPolicy is a route level middleware that rejects the exports when the
+// security policy disables them
+func ExportPolicy(s storage.Store, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		policy, err := app.FindSecurityPolicy(s)
+		if err != nil {
+			logger.WithContext(r.Context()).Errorf("can't find security policy error: %v", err)
+			api.RespondWithError(w, http.StatusInternalServerError, "Server error!")
+			return
+		}
+		if policy.ExportDisabled {
+			api.RespondWithError(w, http.StatusForbidden, app.ErrExportDisabled.Error())
+			return
+		}
+		next(w, r)
+	}
+}
+
+// ServerEncryption is a route level middleware that rejects the features
+// needing the plaintext items, like the reports and the plaintext exports, when
+// the server runs in zero-knowledge mode
+func ServerEncryption(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if app.ZeroKnowledge() {
+			api.RespondWithError(w, http.StatusForbidden, app.ErrZeroKnowledge.Error())
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,46 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/passwall/passwall-server/internal/api"
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+)
+
+// ItemQuota checks the plan allows one more item in the vault of the request
+// before creating it. Organization vaults are limited by the plan of their owner.
+func ItemQuota(s storage.Store, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ownerID, _ := r.Context().Value("user_id").(uint)
+		if orgID, ok := r.Context().Value("organization_id").(uint); ok {
+			org, err := s.Organizations().FindByID(orgID)
+			if err != nil {
+				api.RespondWithError(w, http.StatusNotFound, err.Error())
+				return
+			}
+			ownerID = org.OwnerID
+		}
+		owner, err := s.Users().FindByID(ownerID)
+		if err != nil {
+			api.RespondWithError(w, http.StatusUnauthorized, "Invalid user")
+			return
+		}
+
+		vault, ok := r.Context().Value("store").(storage.Store)
+		if !ok {
+			vault = s
+		}
+		schema, _ := r.Context().Value("schema").(string)
+		items, err := app.CountItems(vault, schema)
+		if err != nil {
+			api.RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if err := app.CheckQuota(owner, app.QuotaItems, items); err != nil {
+			api.RespondWithQuotaError(w, err)
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,76 @@
+package router
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/api"
+	"github.com/passwall/passwall-server/pkg/logger"
+	"github.com/urfave/negroni"
+)
+
+// RequestIDHeader has the correlation ID of the request in the requests and responses
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID limits the IDs sent by clients and proxies to safe log values
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID is a middleware that gives every request a correlation ID which is
+// returned in RequestIDHeader and added to the logs of the request. IDs set by
+// a proxy in front of the server are kept.
+func RequestID(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	requestID := r.Header.Get(RequestIDHeader)
+	if !validRequestID.MatchString(requestID) {
+		requestID = newRequestID()
+	}
+	w.Header().Set(RequestIDHeader, requestID)
+
+	start := time.Now()
+	ctx := logger.WithRequestID(r.Context(), requestID)
+	next(w, r.WithContext(ctx))
+
+	status := http.StatusOK
+	if res, ok := w.(negroni.ResponseWriter); ok {
+		status = res.Status()
+	}
+	logger.WithContext(ctx).WithFields(logger.Fields{
+		"method":      r.Method,
+		"path":        r.URL.Path,
+		"status":      status,
+		"duration_ms": time.Since(start).Milliseconds(),
+		"ip":          api.RequestIP(r),
+	}).Infof("%s %s %d", r.Method, r.URL.Path, status)
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// recoveryLogger writes the panics recovered by negroni to the server log
+type recoveryLogger struct{}
+
+// Println ...
+func (recoveryLogger) Println(v ...interface{}) {
+	logger.Errorf("%s", fmt.Sprintln(v...))
+}
+
+// Printf ...
+func (recoveryLogger) Printf(format string, v ...interface{}) {
+	logger.Errorf(format, v...)
+}
+
+// newMiddlewares returns the common middlewares of all the routes. RequestID
+// comes first so the requests ending with a panic are logged with their status too.
+func newMiddlewares() *negroni.Negroni {
+	recovery := negroni.NewRecovery()
+	recovery.Logger = recoveryLogger{}
+	return negroni.New(negroni.HandlerFunc(RequestID), recovery, negroni.HandlerFunc(Locale), negroni.NewStatic(http.Dir("public")))
+}
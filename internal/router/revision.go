@@ -0,0 +1,88 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+)
+
+// VaultRevisionHeader has the revision counter of the vault in the responses
+// of the vault endpoints
+const VaultRevisionHeader = "X-Vault-Revision"
+
+// revisionWriter sets VaultRevisionHeader just before the response is written.
+// Successful requests which may change the vault increase the revision first.
+type revisionWriter struct {
+	http.ResponseWriter
+	store       storage.Store
+	schema      string
+	mutation    bool
+	wroteHeader bool
+}
+
+func newRevisionWriter(w http.ResponseWriter, r *http.Request, s storage.Store) *revisionWriter {
+	schema, _ := r.Context().Value("schema").(string)
+	return &revisionWriter{
+		ResponseWriter: w,
+		store:          s,
+		schema:         schema,
+		mutation:       isMutation(r.Method),
+	}
+}
+
+// WriteHeader ...
+func (w *revisionWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.setRevision(status)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write ...
+func (w *revisionWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush keeps the streaming handlers working
+func (w *revisionWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *revisionWriter) setRevision(status int) {
+	if w.schema == "" {
+		return
+	}
+
+	var revision uint64
+	if w.mutation && status < http.StatusBadRequest {
+		bumped, err := app.BumpVaultRevision(w.store, w.schema)
+		if err != nil {
+			return
+		}
+		revision = bumped
+	} else {
+		current, err := app.FindVaultRevision(w.store, w.schema)
+		if err != nil {
+			return
+		}
+		revision = current.Revision
+	}
+	w.Header().Set(VaultRevisionHeader, strconv.FormatUint(revision, 10))
+}
+
+// isMutation checks if the requests with the method may change the vault
+func isMutation(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
@@ -7,21 +7,32 @@ import (
 	"github.com/urfave/negroni"
 
 	"github.com/passwall/passwall-server/internal/api"
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/billing"
+	"github.com/passwall/passwall-server/internal/config"
 	"github.com/passwall/passwall-server/internal/storage"
+	"github.com/passwall/passwall-server/pkg/cache"
+	"github.com/passwall/passwall-server/pkg/embedded"
 )
 
 // Router ...
 type Router struct {
 	router *mux.Router
 	store  storage.Store
+	cache  cache.Cache
+	// billing is the payment provider, it is nil for RevenueCat
+	billing billing.Provider
 }
 
 // New ...
-func New(s storage.Store) *Router {
+func New(s storage.Store, c cache.Cache) *Router {
 	r := &Router{
 		router: mux.NewRouter(),
 		store:  s,
+		cache:  c,
 	}
+	// The configuration is validated, so the provider is known
+	r.billing, _ = billing.New(config.Current().Billing)
 	r.initRoutes()
 	return r
 }
@@ -32,107 +43,336 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 }
 
 func (r *Router) initRoutes() {
-	// API Router Group
+	// API Router Groups, unversioned routes are the v1 routes of the older clients
+	apiV2Router := mux.NewRouter().PathPrefix("/api/v2").Subrouter()
+	r.apiV2Routes(apiV2Router)
+	apiV1Router := mux.NewRouter().PathPrefix("/api/v1").Subrouter()
+	r.apiV1Routes(apiV1Router)
 	apiRouter := mux.NewRouter().PathPrefix("/api").Subrouter()
-
-	// Login endpoints
-	apiRouter.HandleFunc("/login-test", api.TestLogin(r.store)).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/logins", api.FindAllLogins(r.store)).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/logins", api.CreateLogin(r.store)).Methods(http.MethodPost)
-	apiRouter.HandleFunc("/logins/{id:[0-9]+}", api.FindLoginsByID(r.store)).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/logins/{id:[0-9]+}", api.UpdateLogin(r.store)).Methods(http.MethodPut)
-	apiRouter.HandleFunc("/logins/{id:[0-9]+}", api.DeleteLogin(r.store)).Methods(http.MethodDelete)
-	apiRouter.HandleFunc("/logins/bulk-update", api.BulkUpdateLogins(r.store)).Methods(http.MethodPut)
-
-	// Bank Account endpoints
-	apiRouter.HandleFunc("/bank-accounts", api.FindAllBankAccounts(r.store)).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/bank-accounts", api.CreateBankAccount(r.store)).Methods(http.MethodPost)
-	apiRouter.HandleFunc("/bank-accounts/{id:[0-9]+}", api.FindBankAccountByID(r.store)).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/bank-accounts/{id:[0-9]+}", api.UpdateBankAccount(r.store)).Methods(http.MethodPut)
-	apiRouter.HandleFunc("/bank-accounts/{id:[0-9]+}", api.DeleteBankAccount(r.store)).Methods(http.MethodDelete)
-	apiRouter.HandleFunc("/bank-accounts/bulk-update", api.BulkUpdateBankAccounts(r.store)).Methods(http.MethodPut)
-
-	// Credit Card endpoints
-	apiRouter.HandleFunc("/credit-cards", api.FindAllCreditCards(r.store)).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/credit-cards", api.CreateCreditCard(r.store)).Methods(http.MethodPost)
-	apiRouter.HandleFunc("/credit-cards/{id:[0-9]+}", api.FindCreditCardByID(r.store)).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/credit-cards/{id:[0-9]+}", api.UpdateCreditCard(r.store)).Methods(http.MethodPut)
-	apiRouter.HandleFunc("/credit-cards/{id:[0-9]+}", api.DeleteCreditCard(r.store)).Methods(http.MethodDelete)
-	apiRouter.HandleFunc("/credit-cards/bulk-update", api.BulkUpdateCreditCards(r.store)).Methods(http.MethodPut)
-
-	// Note endpoints
-	apiRouter.HandleFunc("/notes", api.FindAllNotes(r.store)).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/notes", api.CreateNote(r.store)).Methods(http.MethodPost)
-	apiRouter.HandleFunc("/notes/{id:[0-9]+}", api.FindNoteByID(r.store)).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/notes/{id:[0-9]+}", api.UpdateNote(r.store)).Methods(http.MethodPut)
-	apiRouter.HandleFunc("/notes/{id:[0-9]+}", api.DeleteNote(r.store)).Methods(http.MethodDelete)
-	apiRouter.HandleFunc("/notes/bulk-update", api.BulkUpdateNotes(r.store)).Methods(http.MethodPut)
-
-	// Email endpoints
-	apiRouter.HandleFunc("/emails", api.FindAllEmails(r.store)).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/emails", api.CreateEmail(r.store)).Methods(http.MethodPost)
-	apiRouter.HandleFunc("/emails/{id:[0-9]+}", api.FindEmailByID(r.store)).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/emails/{id:[0-9]+}", api.UpdateEmail(r.store)).Methods(http.MethodPut)
-	apiRouter.HandleFunc("/emails/{id:[0-9]+}", api.DeleteEmail(r.store)).Methods(http.MethodDelete)
-	apiRouter.HandleFunc("/emails/bulk-update", api.BulkUpdateEmails(r.store)).Methods(http.MethodPut)
-
-	// Server endpoints
-	apiRouter.HandleFunc("/servers", api.FindAllServers(r.store)).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/servers", api.CreateServer(r.store)).Methods(http.MethodPost)
-	apiRouter.HandleFunc("/servers/{id:[0-9]+}", api.FindServerByID(r.store)).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/servers/{id:[0-9]+}", api.UpdateServer(r.store)).Methods(http.MethodPut)
-	apiRouter.HandleFunc("/servers/{id:[0-9]+}", api.DeleteServer(r.store)).Methods(http.MethodDelete)
-	apiRouter.HandleFunc("/servers/bulk-update", api.BulkUpdateServers(r.store)).Methods(http.MethodPut)
-
-	// User endpoints
-	apiRouter.HandleFunc("/users", api.FindAllUsers(r.store)).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/users", api.CreateUser(r.store)).Methods(http.MethodPost)
-	apiRouter.HandleFunc("/users/{id:[0-9]+}", api.FindUserByID(r.store)).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/users/{id:[0-9]+}", api.UpdateUser(r.store)).Methods(http.MethodPut)
-	apiRouter.HandleFunc("/users/{id:[0-9]+}", api.DeleteUser(r.store)).Methods(http.MethodDelete)
-	apiRouter.HandleFunc("/users/{id:[0-9]+}/migrate", api.Migrate(r.store)).Methods(http.MethodPut)
-
-	apiRouter.HandleFunc("/users/check-credentials", api.CheckCredentials(r.store)).Methods(http.MethodPost)
-	apiRouter.HandleFunc("/users/change-master-password", api.ChangeMasterPassword(r.store)).Methods(http.MethodPost)
-
-	apiRouter.HandleFunc("/system/import", api.Import(r.store)).Methods(http.MethodPost)
-	apiRouter.HandleFunc("/system/export", api.Export(r.store)).Methods(http.MethodGet)
+	r.apiV1Routes(apiRouter)
 
 	// Auth endpoints
 	authRouter := mux.NewRouter().PathPrefix("/auth").Subrouter()
-	authRouter.HandleFunc("/code", api.CreateCode(r.store)).Methods(http.MethodPost)
-	authRouter.HandleFunc("/verify/{code:[0-9]+}", api.VerifyCode()).Queries("email", "{email}").Methods(http.MethodGet)
-	authRouter.HandleFunc("/signup", api.Signup(r.store)).Methods(http.MethodPost)
-	authRouter.HandleFunc("/signin", api.Signin(r.store)).Methods(http.MethodPost)
-	authRouter.HandleFunc("/signout", api.Signout()).Methods(http.MethodPost)
-	authRouter.HandleFunc("/refresh", api.RefreshToken(r.store)).Methods(http.MethodPost)
+	authRouter.HandleFunc("/code", api.CreateCode(r.store, r.cache)).Methods(http.MethodPost)
+	authRouter.HandleFunc("/verify/{code:[0-9]+}", api.VerifyCode(r.cache)).Queries("email", "{email}").Methods(http.MethodGet)
+	authRouter.HandleFunc("/signup", api.Signup(r.store, r.cache)).Methods(http.MethodPost)
+	authRouter.HandleFunc("/signin", api.Signin(r.store, r.cache)).Methods(http.MethodPost)
+	authRouter.HandleFunc("/signout", api.Signout(r.store, r.cache)).Methods(http.MethodPost)
+	authRouter.HandleFunc("/refresh", api.RefreshToken(r.store, r.cache)).Methods(http.MethodPost)
 	authRouter.HandleFunc("/check", api.CheckToken(r.store)).Methods(http.MethodPost)
-	authRouter.HandleFunc("/delete-code", api.CreateDeleteCode(r.store)).Methods(http.MethodPost)
-	authRouter.HandleFunc("/recover-delete/{email}", api.RecoverDelete(r.store)).Methods(http.MethodDelete)
+	authRouter.HandleFunc("/sessions/revoke-all", api.RevokeAllSessions(r.store, r.cache)).Methods(http.MethodPost)
+	authRouter.HandleFunc("/email-change/approve", api.ApproveEmailChange(r.store, r.cache)).Methods(http.MethodPost)
+	authRouter.HandleFunc("/email-change/abort", api.AbortEmailChange(r.store, r.cache)).Methods(http.MethodPost)
+	authRouter.HandleFunc("/delete-code", api.CreateDeleteCode(r.store, r.cache)).Methods(http.MethodPost)
+	authRouter.HandleFunc("/recover-delete/{email}", api.RecoverDelete(r.store, r.cache)).Methods(http.MethodDelete)
+	authRouter.HandleFunc("/cancel-deletion", api.CancelDeletion(r.store)).Methods(http.MethodPost)
 
 	// Check Updated
 	webRouter := mux.NewRouter().PathPrefix("/web").Subrouter()
 	webRouter.HandleFunc("/check-update/{product:[0-9]+}", api.CheckUpdate).Methods(http.MethodGet)
 
-	n := negroni.Classic()
+	n := newMiddlewares()
 	n.Use(negroni.HandlerFunc(CORS))
 	n.Use(negroni.HandlerFunc(Secure))
+	n.Use(negroni.HandlerFunc(BodyLimit))
+	n.Use(negroni.HandlerFunc(Compress))
+	n.Use(Maintenance(r.store))
 
 	r.router.PathPrefix("/web").Handler(n.With(
-		LimitHandler(),
+		LimitHandler(r.cache),
 		negroni.Wrap(webRouter),
 	))
 
+	// v2 is registered first, /api prefix matches its paths too
+	r.router.PathPrefix("/api/v2").Handler(n.With(
+		Auth(r.store, r.cache),
+		Organization(r.store),
+		negroni.Wrap(apiV2Router),
+	))
+
+	r.router.PathPrefix("/api/v1").Handler(n.With(
+		Deprecated(apiV2Prefix),
+		Auth(r.store, r.cache),
+		Organization(r.store),
+		negroni.Wrap(apiV1Router),
+	))
+
 	r.router.PathPrefix("/api").Handler(n.With(
-		Auth(r.store),
+		Deprecated(apiV2Prefix),
+		Auth(r.store, r.cache),
+		Organization(r.store),
 		negroni.Wrap(apiRouter),
 	))
 
 	r.router.PathPrefix("/auth").Handler(n.With(
-		LimitHandler(),
+		LimitHandler(r.cache),
 		negroni.Wrap(authRouter),
 	))
 
 	// Insecure endpoints
 	r.router.HandleFunc("/health", api.HealthCheck(r.store)).Methods(http.MethodGet)
+	r.router.HandleFunc("/healthz", api.Liveness).Methods(http.MethodGet)
+	r.router.HandleFunc("/readyz", api.Readiness(r.store, r.cache)).Methods(http.MethodGet)
+
+	// Payment provider webhooks, RevenueCat subscriptions are checked at signin instead
+	if r.billing != nil {
+		r.router.HandleFunc("/billing/webhook", api.BillingWebhook(r.store, r.billing)).Methods(http.MethodPost)
+	}
+
+	// Web client gets the paths left, registered last so it doesn't hide the API
+	if server := config.Current().Server; server.WebClient {
+		recovery := negroni.NewRecovery()
+		recovery.Logger = recoveryLogger{}
+		r.router.PathPrefix("/").Handler(negroni.New(
+			negroni.HandlerFunc(RequestID),
+			recovery,
+			negroni.HandlerFunc(Secure),
+			negroni.Wrap(WebClient(embedded.Web(), server.WebAPIURL)),
+		))
+	}
+}
+
+// apiV1Routes adds the v1 endpoints to the router
+func (r *Router) apiV1Routes(apiRouter *mux.Router) {
+	apiRouter.Use(AuditRequests(r.store))
+
+	// Login endpoints
+	apiRouter.HandleFunc("/login-test", api.TestLogin(r.store)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/logins", RequireScope(app.ScopeVaultRead, r.vault(ETag(api.FindAllLogins)))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/logins", RequireScope(app.ScopeVaultWrite, ItemQuota(r.store, r.vault(api.CreateLogin)))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/logins/{id:[0-9]+}", RequireScope(app.ScopeVaultRead, r.vault(api.FindLoginsByID))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/logins/{id:[0-9]+}", RequireScope(app.ScopeVaultWrite, r.vault(api.UpdateLogin))).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/logins/{id:[0-9]+}", RequireScope(app.ScopeVaultWrite, r.vault(api.DeleteLogin))).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/logins/bulk-update", RequireScope(app.ScopeVaultWrite, r.vault(api.BulkUpdateLogins))).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/logins/batch", RequireScope(app.ScopeVaultWrite, ItemQuota(r.store, r.vault(api.BatchLogins)))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/logins/search", RequireScope(app.ScopeVaultRead, r.vault(api.SearchLogins))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/logins/duplicates", RequireScope(app.ScopeVaultRead, ServerEncryption(r.vault(api.FindDuplicateLogins)))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/logins/merge", RequireScope(app.ScopeVaultWrite, r.vault(api.MergeLogins))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/logins/{id:[0-9]+}/history", RequireScope(app.ScopeVaultRead, r.vault(api.FindLoginHistories))).Methods(http.MethodGet)
+
+	// Bank Account endpoints
+	apiRouter.HandleFunc("/bank-accounts", RequireScope(app.ScopeVaultRead, r.vault(ETag(api.FindAllBankAccounts)))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/bank-accounts", RequireScope(app.ScopeVaultWrite, ItemQuota(r.store, r.vault(api.CreateBankAccount)))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/bank-accounts/{id:[0-9]+}", RequireScope(app.ScopeVaultRead, r.vault(api.FindBankAccountByID))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/bank-accounts/{id:[0-9]+}", RequireScope(app.ScopeVaultWrite, r.vault(api.UpdateBankAccount))).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/bank-accounts/{id:[0-9]+}", RequireScope(app.ScopeVaultWrite, r.vault(api.DeleteBankAccount))).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/bank-accounts/bulk-update", RequireScope(app.ScopeVaultWrite, r.vault(api.BulkUpdateBankAccounts))).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/bank-accounts/batch", RequireScope(app.ScopeVaultWrite, ItemQuota(r.store, r.vault(api.BatchBankAccounts)))).Methods(http.MethodPost)
+
+	// Credit Card endpoints
+	apiRouter.HandleFunc("/credit-cards", RequireScope(app.ScopeVaultRead, r.vault(ETag(api.FindAllCreditCards)))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/credit-cards", RequireScope(app.ScopeVaultWrite, ItemQuota(r.store, r.vault(api.CreateCreditCard)))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/credit-cards/{id:[0-9]+}", RequireScope(app.ScopeVaultRead, r.vault(api.FindCreditCardByID))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/credit-cards/{id:[0-9]+}", RequireScope(app.ScopeVaultWrite, r.vault(api.UpdateCreditCard))).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/credit-cards/{id:[0-9]+}", RequireScope(app.ScopeVaultWrite, r.vault(api.DeleteCreditCard))).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/credit-cards/bulk-update", RequireScope(app.ScopeVaultWrite, r.vault(api.BulkUpdateCreditCards))).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/credit-cards/batch", RequireScope(app.ScopeVaultWrite, ItemQuota(r.store, r.vault(api.BatchCreditCards)))).Methods(http.MethodPost)
+
+	// Note endpoints
+	apiRouter.HandleFunc("/notes", RequireScope(app.ScopeVaultRead, r.vault(ETag(api.FindAllNotes)))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/notes", RequireScope(app.ScopeVaultWrite, ItemQuota(r.store, r.vault(api.CreateNote)))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/notes/{id:[0-9]+}", RequireScope(app.ScopeVaultRead, r.vault(api.FindNoteByID))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/notes/{id:[0-9]+}", RequireScope(app.ScopeVaultWrite, r.vault(api.UpdateNote))).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/notes/{id:[0-9]+}", RequireScope(app.ScopeVaultWrite, r.vault(api.DeleteNote))).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/notes/bulk-update", RequireScope(app.ScopeVaultWrite, r.vault(api.BulkUpdateNotes))).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/notes/batch", RequireScope(app.ScopeVaultWrite, ItemQuota(r.store, r.vault(api.BatchNotes)))).Methods(http.MethodPost)
+
+	// Email endpoints
+	apiRouter.HandleFunc("/emails", RequireScope(app.ScopeVaultRead, r.vault(ETag(api.FindAllEmails)))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/emails", RequireScope(app.ScopeVaultWrite, ItemQuota(r.store, r.vault(api.CreateEmail)))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/emails/{id:[0-9]+}", RequireScope(app.ScopeVaultRead, r.vault(api.FindEmailByID))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/emails/{id:[0-9]+}", RequireScope(app.ScopeVaultWrite, r.vault(api.UpdateEmail))).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/emails/{id:[0-9]+}", RequireScope(app.ScopeVaultWrite, r.vault(api.DeleteEmail))).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/emails/bulk-update", RequireScope(app.ScopeVaultWrite, r.vault(api.BulkUpdateEmails))).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/emails/batch", RequireScope(app.ScopeVaultWrite, ItemQuota(r.store, r.vault(api.BatchEmails)))).Methods(http.MethodPost)
+
+	// Server endpoints
+	apiRouter.HandleFunc("/servers", RequireScope(app.ScopeVaultRead, r.vault(ETag(api.FindAllServers)))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/servers", RequireScope(app.ScopeVaultWrite, ItemQuota(r.store, r.vault(api.CreateServer)))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/servers/{id:[0-9]+}", RequireScope(app.ScopeVaultRead, r.vault(api.FindServerByID))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/servers/{id:[0-9]+}", RequireScope(app.ScopeVaultWrite, r.vault(api.UpdateServer))).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/servers/{id:[0-9]+}", RequireScope(app.ScopeVaultWrite, r.vault(api.DeleteServer))).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/servers/bulk-update", RequireScope(app.ScopeVaultWrite, r.vault(api.BulkUpdateServers))).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/servers/batch", RequireScope(app.ScopeVaultWrite, ItemQuota(r.store, r.vault(api.BatchServers)))).Methods(http.MethodPost)
+
+	// User endpoints
+	apiRouter.HandleFunc("/users", RequireScope(app.ScopeAdmin, api.FindAllUsers(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/users", RequireScope(app.ScopeAdmin, api.CreateUser(r.store))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/users/{id:[0-9]+}", RequireScope(app.ScopeAccount, api.FindUserByID(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/users/{id:[0-9]+}", RequireScope(app.ScopeAccount, api.UpdateUser(r.store))).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/users/{id:[0-9]+}", RequireScope(app.ScopeAdmin, api.DeleteUser(r.store))).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/users/{id:[0-9]+}/migrate", RequireScope(app.ScopeAccount, api.Migrate(r.store))).Methods(http.MethodPut)
+
+	apiRouter.HandleFunc("/users/check-credentials", RequireScope(app.ScopeVaultRead, api.CheckCredentials(r.store))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/users/reauthenticate", RequireScope(app.ScopeVaultRead, api.Reauthenticate(r.store))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/users/change-master-password", RequireScope(app.ScopeAccount, api.ChangeMasterPassword(r.store))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/users/phone", RequireScope(app.ScopeAccount, Limit(r.cache, api.CreatePhoneCode(r.store, r.cache)))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/users/phone/verify", RequireScope(app.ScopeAccount, Limit(r.cache, api.VerifyPhone(r.store, r.cache)))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/users/phone", RequireScope(app.ScopeAccount, api.DeletePhone(r.store))).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/users/email", RequireScope(app.ScopeAccount, RequireRecentAuth(r.store, Limit(r.cache, api.RequestEmailChange(r.store, r.cache))))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/users/email/verify", RequireScope(app.ScopeAccount, Limit(r.cache, api.ConfirmEmailChange(r.store, r.cache)))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/config", RequireScope(app.ScopeVaultRead, api.FindClientConfig(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/account/usage", RequireScope(app.ScopeAccount, api.FindUsage(r.store))).Methods(http.MethodGet)
+
+	apiRouter.HandleFunc("/system/import", RequireScope(app.ScopeVaultWrite, ServerEncryption(r.vault(api.Import)))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/import/jobs", RequireScope(app.ScopeVaultWrite, ServerEncryption(r.vault(api.ImportJob(r.store))))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/system/export", RequireScope(app.ScopeVaultExport, ServerEncryption(ExportPolicy(r.store, Audit(r.store, app.AuditActionExport, r.vault(api.Export)))))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/export/backup", RequireScope(app.ScopeVaultExport, ExportPolicy(r.store, Audit(r.store, app.AuditActionExportBackup, r.vault(api.ExportBackup))))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/export/keepass", RequireScope(app.ScopeVaultExport, ServerEncryption(ExportPolicy(r.store, Audit(r.store, app.AuditActionExportKeePass, r.vault(api.ExportKeePass)))))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/export/csv/{type:login|credit_card|bank_account|note|email|server}", RequireScope(app.ScopeVaultExport, ServerEncryption(ExportPolicy(r.store, RequireRecentAuth(r.store, Audit(r.store, app.AuditActionExportCSV, r.vault(api.ExportCSV))))))).Methods(http.MethodGet)
+
+	// Audit log endpoints
+	apiRouter.HandleFunc("/audit", RequireScope(app.ScopeAccount, api.FindAuditEvents(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/admin/audit", RequireScope(app.ScopeAdmin, api.FindAllAuditEvents(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/admin/stats", RequireScope(app.ScopeAdmin, api.FindInstanceStats(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/admin/events", RequireScope(app.ScopeAdmin, api.FindActivity(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/import/backup", RequireScope(app.ScopeVaultWrite, r.vault(api.RestoreBackup))).Methods(http.MethodPost)
+
+	// Security alert endpoints
+	apiRouter.HandleFunc("/alerts", RequireScope(app.ScopeAccount, api.FindAlerts(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/admin/alerts", RequireScope(app.ScopeAdmin, api.FindAllAlerts(r.store))).Methods(http.MethodGet)
+
+	// Email outbox endpoints
+	apiRouter.HandleFunc("/admin/emails", RequireScope(app.ScopeAdmin, api.FindOutboxEmails(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/admin/emails/{id:[0-9]+}/retry", RequireScope(app.ScopeAdmin, api.RetryOutboxEmail(r.store))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/admin/emails/{id:[0-9]+}", RequireScope(app.ScopeAdmin, api.DeleteOutboxEmail(r.store))).Methods(http.MethodDelete)
+
+	// Deleted records kept until their retention ends
+	apiRouter.HandleFunc("/admin/deleted/{table}", RequireScope(app.ScopeAdmin, api.FindDeletedRecords(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/admin/deleted/{table}/{id:[0-9]+}/restore", RequireScope(app.ScopeAdmin, api.RestoreDeletedRecord(r.store))).Methods(http.MethodPost)
+
+	// Item quotas admins set for the users instead of the ones of their plans
+	apiRouter.HandleFunc("/admin/users/{id:[0-9]+}/quota", RequireScope(app.ScopeAdmin, api.UpdateUserQuota(r.store))).Methods(http.MethodPut)
+
+	// Master password changes admins ask the users for
+	apiRouter.HandleFunc("/admin/users/force-password-change", RequireScope(app.ScopeAdmin, api.ForcePasswordChange(r.store))).Methods(http.MethodPut)
+
+	// Announcements admins publish to every user
+	apiRouter.HandleFunc("/announcements", RequireScope(app.ScopeVaultRead, api.FindActiveAnnouncements(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/admin/announcements", RequireScope(app.ScopeAdmin, api.FindAllAnnouncements(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/admin/announcements", RequireScope(app.ScopeAdmin, api.CreateAnnouncement(r.store))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/admin/announcements/{id:[0-9]+}", RequireScope(app.ScopeAdmin, api.UpdateAnnouncement(r.store))).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/admin/announcements/{id:[0-9]+}", RequireScope(app.ScopeAdmin, api.DeleteAnnouncement(r.store))).Methods(http.MethodDelete)
+
+	// Support sessions of the admins impersonating the users who consent to it
+	apiRouter.HandleFunc("/users/support-consent", RequireScope(app.ScopeAccount, api.GrantSupportConsent(r.store, r.cache))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/users/support-consent", RequireScope(app.ScopeAccount, api.RevokeSupportConsent(r.store, r.cache))).Methods(http.MethodDelete)
+
+	// Devices trusted at signin to skip the two-factor code
+	apiRouter.HandleFunc("/sessions/devices", RequireScope(app.ScopeAccount, api.FindTrustedDevices(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/sessions/devices/{uuid}", RequireScope(app.ScopeAccount, api.RevokeTrustedDevice(r.store))).Methods(http.MethodDelete)
+
+	// Scoped tokens of the limited clients like read-only integrations
+	apiRouter.HandleFunc("/tokens", RequireScope(app.ScopeAccount, RequireRecentAuth(r.store, api.CreateScopedToken(r.store)))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/admin/impersonate", RequireScope(app.ScopeAdmin, api.Impersonate(r.store, r.cache))).Methods(http.MethodPost)
+
+	// Maintenance mode rejecting the changes during backups and migrations
+	apiRouter.HandleFunc("/admin/maintenance", RequireScope(app.ScopeAdmin, api.FindMaintenance(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/admin/maintenance", RequireScope(app.ScopeAdmin, api.UpdateMaintenance(r.store))).Methods(http.MethodPut)
+
+	// Key rotations encrypting the vaults with new data keys, followed with the jobs API
+	apiRouter.HandleFunc("/admin/key-rotation", RequireScope(app.ScopeAdmin, api.StartKeyRotation(r.store))).Methods(http.MethodPost)
+
+	// Security policy of the instance
+	apiRouter.HandleFunc("/admin/policies", RequireScope(app.ScopeAdmin, api.FindSecurityPolicy(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/admin/policies", RequireScope(app.ScopeAdmin, api.UpdateSecurityPolicy(r.store))).Methods(http.MethodPut)
+
+	// Pro grants of the users, besides their subscriptions
+	apiRouter.HandleFunc("/admin/subscriptions", RequireScope(app.ScopeAdmin, api.GrantSubscription(r.store))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/admin/subscriptions", RequireScope(app.ScopeAdmin, api.ExtendSubscription(r.store))).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/admin/subscriptions", RequireScope(app.ScopeAdmin, api.RevokeSubscription(r.store))).Methods(http.MethodDelete)
+
+	// Webhook endpoints
+	apiRouter.HandleFunc("/webhooks", RequireScope(app.ScopeAccount, api.FindAllWebhooks(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/webhooks", RequireScope(app.ScopeAccount, api.CreateWebhook(r.store))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/webhooks/{id:[0-9]+}", RequireScope(app.ScopeAccount, api.UpdateWebhook(r.store))).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/webhooks/{id:[0-9]+}", RequireScope(app.ScopeAccount, api.DeleteWebhook(r.store))).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/webhooks/{id:[0-9]+}/deliveries", RequireScope(app.ScopeAccount, api.FindWebhookDeliveries(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/admin/webhooks", RequireScope(app.ScopeAdmin, api.FindAllAdminWebhooks(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/admin/webhooks", RequireScope(app.ScopeAdmin, api.CreateAdminWebhook(r.store))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/admin/webhooks/{id:[0-9]+}", RequireScope(app.ScopeAdmin, api.UpdateAdminWebhook(r.store))).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/admin/webhooks/{id:[0-9]+}", RequireScope(app.ScopeAdmin, api.DeleteAdminWebhook(r.store))).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/admin/webhooks/{id:[0-9]+}/deliveries", RequireScope(app.ScopeAdmin, api.FindAdminWebhookDeliveries(r.store))).Methods(http.MethodGet)
+
+	// Sync endpoints
+	apiRouter.HandleFunc("/sync", RequireScope(app.ScopeVaultRead, r.vault(api.Sync))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/sync/revision", RequireScope(app.ScopeVaultRead, r.vault(api.FindVaultRevision))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/sync/full", RequireScope(app.ScopeVaultRead, r.vault(api.FullSync(r.store)))).Methods(http.MethodGet)
+
+	// Conflict endpoints
+	apiRouter.HandleFunc("/conflicts", RequireScope(app.ScopeVaultRead, r.vault(api.FindAllConflicts))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/conflicts/{id:[0-9]+}/resolve", RequireScope(app.ScopeVaultWrite, r.vault(api.ResolveConflict))).Methods(http.MethodPost)
+
+	// Notification endpoints
+	apiRouter.HandleFunc("/notifications/stream", RequireScope(app.ScopeVaultRead, r.vault(api.NotificationStream))).Methods(http.MethodGet)
+
+	// Job endpoints
+	apiRouter.HandleFunc("/jobs/{id:[0-9]+}", RequireScope(app.ScopeVaultRead, api.FindJobByID(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/jobs/{id:[0-9]+}/cancel", RequireScope(app.ScopeVaultWrite, api.CancelJob(r.store))).Methods(http.MethodPost)
+
+	// Relation endpoints
+	apiRouter.HandleFunc("/relations", RequireScope(app.ScopeVaultRead, r.vault(api.FindRelatedItems))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/relations", RequireScope(app.ScopeVaultWrite, r.vault(api.CreateRelation))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/relations/{id:[0-9]+}", RequireScope(app.ScopeVaultWrite, r.vault(api.DeleteRelation))).Methods(http.MethodDelete)
+
+	// Folder endpoints
+	apiRouter.HandleFunc("/folders", RequireScope(app.ScopeVaultRead, r.vault(ETag(api.FindAllFolders)))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/folders", RequireScope(app.ScopeVaultWrite, r.vault(api.CreateFolder))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/folders/{id:[0-9]+}", RequireScope(app.ScopeVaultWrite, r.vault(api.UpdateFolder))).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/folders/{id:[0-9]+}", RequireScope(app.ScopeVaultWrite, r.vault(api.DeleteFolder))).Methods(http.MethodDelete)
+
+	// Report endpoints
+	apiRouter.HandleFunc("/reports/reused-passwords", RequireScope(app.ScopeVaultRead, ServerEncryption(r.vault(api.FindReusedPasswords)))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/reports/reused-passwords", RequireScope(app.ScopeVaultRead, r.vault(api.FindReusedPasswordsByHashes))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/reports/passwords", RequireScope(app.ScopeVaultRead, ServerEncryption(r.vault(api.FindPasswordReport)))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/reports/breaches", RequireScope(app.ScopeVaultRead, api.FindAllBreaches(r.store))).Methods(http.MethodGet)
+
+	// Generator endpoints
+	apiRouter.HandleFunc("/generate/passphrase", api.GeneratePassphrase).Methods(http.MethodPost)
+
+	// Tool endpoints
+	apiRouter.HandleFunc("/tools/breach-check", api.CheckBreachedPassword).Methods(http.MethodPost)
+
+	// Organization endpoints
+	apiRouter.HandleFunc("/organizations", RequireScope(app.ScopeAccount, api.FindAllOrganizations(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/organizations", RequireScope(app.ScopeAccount, api.CreateOrganization(r.store))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/organizations/regions", RequireScope(app.ScopeAccount, api.FindAllRegions(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/organizations/{id:[0-9]+}", RequireScope(app.ScopeAccount, api.FindOrganizationByID(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/organizations/{id:[0-9]+}", RequireScope(app.ScopeAccount, api.UpdateOrganization(r.store))).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/organizations/{id:[0-9]+}", RequireScope(app.ScopeAccount, api.DeleteOrganization(r.store))).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/organizations/{id:[0-9]+}/seats", RequireScope(app.ScopeAccount, api.UpdateOrganizationSeats(r.store, r.billing))).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/organizations/{id:[0-9]+}/members", RequireScope(app.ScopeAccount, api.FindOrganizationMembers(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/organizations/{id:[0-9]+}/members/{user_id:[0-9]+}", RequireScope(app.ScopeAccount, api.DeleteMember(r.store))).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/organizations/{id:[0-9]+}/invitations", RequireScope(app.ScopeAccount, api.InviteMember(r.store))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/organizations/{id:[0-9]+}/invitations/accept", RequireScope(app.ScopeAccount, api.AcceptInvitation(r.store))).Methods(http.MethodPost)
+
+	// Collection endpoints
+	apiRouter.HandleFunc("/organizations/{id:[0-9]+}/collections", RequireScope(app.ScopeVaultRead, api.FindAllCollections(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/organizations/{id:[0-9]+}/collections", RequireScope(app.ScopeVaultWrite, api.CreateCollection(r.store))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/organizations/{id:[0-9]+}/collections/{collection_id:[0-9]+}", RequireScope(app.ScopeVaultWrite, api.UpdateCollection(r.store))).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/organizations/{id:[0-9]+}/collections/{collection_id:[0-9]+}", RequireScope(app.ScopeVaultWrite, api.DeleteCollection(r.store))).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/organizations/{id:[0-9]+}/collections/{collection_id:[0-9]+}/items", RequireScope(app.ScopeVaultRead, api.FindCollectionItems(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/organizations/{id:[0-9]+}/collections/{collection_id:[0-9]+}/items", RequireScope(app.ScopeVaultWrite, api.AddCollectionItem(r.store))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/organizations/{id:[0-9]+}/collections/{collection_id:[0-9]+}/items/{item_type}/{item_id:[0-9]+}", RequireScope(app.ScopeVaultWrite, api.RemoveCollectionItem(r.store))).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/organizations/{id:[0-9]+}/collections/{collection_id:[0-9]+}/members", RequireScope(app.ScopeAccount, api.FindCollectionMembers(r.store))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/organizations/{id:[0-9]+}/collections/{collection_id:[0-9]+}/members", RequireScope(app.ScopeAccount, api.AddCollectionMember(r.store))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/organizations/{id:[0-9]+}/collections/{collection_id:[0-9]+}/members/{user_id:[0-9]+}", RequireScope(app.ScopeAccount, api.RemoveCollectionMember(r.store))).Methods(http.MethodDelete)
+}
+
+// apiV2Routes adds the redesigned endpoints to the router, they use problem
+// details errors and cursor pagination
+func (r *Router) apiV2Routes(apiRouter *mux.Router) {
+	apiRouter.NotFoundHandler = http.HandlerFunc(api.ProblemNotFound)
+
+	// Item list endpoints
+	apiRouter.HandleFunc("/logins", RequireScope(app.ScopeVaultRead, r.vault(ETag(api.ListLoginsV2)))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/bank-accounts", RequireScope(app.ScopeVaultRead, r.vault(ETag(api.ListBankAccountsV2)))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/credit-cards", RequireScope(app.ScopeVaultRead, r.vault(ETag(api.ListCreditCardsV2)))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/notes", RequireScope(app.ScopeVaultRead, r.vault(ETag(api.ListNotesV2)))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/emails", RequireScope(app.ScopeVaultRead, r.vault(ETag(api.ListEmailsV2)))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/servers", RequireScope(app.ScopeVaultRead, r.vault(ETag(api.ListServersV2)))).Methods(http.MethodGet)
+
+	// Sync endpoints
+	apiRouter.HandleFunc("/sync", RequireScope(app.ScopeVaultRead, r.vault(api.SyncV2))).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/sync/revision", RequireScope(app.ScopeVaultRead, r.vault(api.FindVaultRevisionV2))).Methods(http.MethodGet)
 }
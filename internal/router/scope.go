@@ -0,0 +1,20 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/passwall/passwall-server/internal/app"
+)
+
+// RequireScope is a route level middleware that checks if the token
+// of the request has the given scope
+func RequireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scopes, _ := r.Context().Value("scopes").([]string)
+		if !app.HasScope(scopes, scope) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
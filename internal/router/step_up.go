@@ -0,0 +1,29 @@
+package router
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/api"
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/internal/storage"
+)
+
+// RequireRecentAuth is a route level middleware that allows the request only if
+// the user confirmed the master password within the step-up window
+func RequireRecentAuth(s storage.Store, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := r.Context().Value("user_id").(uint)
+		user, err := s.Users().FindByID(userID)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if !app.IsRecentlyAuthenticated(user, app.StepUpWindow(), time.Now()) {
+			api.RespondWithError(w, http.StatusForbidden, app.ErrReauthenticationRequired.Error())
+			return
+		}
+		next(w, r)
+	}
+}
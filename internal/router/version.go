@@ -0,0 +1,20 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/urfave/negroni"
+)
+
+// apiV2Prefix is the path prefix of the current API version
+const apiV2Prefix = "/api/v2"
+
+// Deprecated is a middleware that marks the responses of a deprecated API
+// version and links to the version replacing it
+func Deprecated(successor string) negroni.HandlerFunc {
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Add("Link", "<"+successor+">; rel=\"successor-version\"")
+		next(w, r)
+	})
+}
@@ -0,0 +1,69 @@
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// webIndex is the page of the web client routes
+	webIndex = "index.html"
+	// apiURLMeta is the meta tag telling the web client where the API is, a
+	// script would be blocked by the content security policy
+	apiURLMeta = `<meta name="passwall-api-url" content="%s">`
+)
+
+// hashedAsset matches the file names having the content hash of the build,
+// they never change and are cached forever
+var hashedAsset = regexp.MustCompile(`[.-][0-9a-f]{8,}\.[a-z0-9]+$`)
+
+// WebClient serves the files of the web client. Paths which aren't files get
+// index.html so the routes of the client can be opened directly. The API URL is
+// injected to index.html, clients use their own origin when it is empty.
+func WebClient(files fs.FS, apiURL string) http.HandlerFunc {
+	index, _ := fs.ReadFile(files, webIndex)
+	meta := fmt.Sprintf(apiURLMeta, html.EscapeString(apiURL))
+	index = bytes.Replace(index, []byte("</head>"), []byte(meta+"\n</head>"), 1)
+	fileServer := http.FileServer(http.FS(files))
+	started := time.Now()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+		if name != "" && name != webIndex {
+			if info, err := fs.Stat(files, name); err == nil && !info.IsDir() {
+				w.Header().Set("Cache-Control", assetCacheControl(name))
+				fileServer.ServeHTTP(w, r)
+				return
+			}
+			// Missing assets are errors, other paths are the routes of the client
+			if path.Ext(name) != "" {
+				http.NotFound(w, r)
+				return
+			}
+		}
+
+		// index.html is checked on every visit so new releases are seen at once
+		w.Header().Set("Cache-Control", "no-cache")
+		http.ServeContent(w, r, webIndex, started, bytes.NewReader(index))
+	}
+}
+
+// assetCacheControl returns the Cache-Control header of the file of the client
+func assetCacheControl(name string) string {
+	if hashedAsset.MatchString(name) {
+		return "public, max-age=31536000, immutable"
+	}
+	return "public, max-age=3600"
+}
@@ -0,0 +1,47 @@
+package siem
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/passwall/passwall-server/model"
+)
+
+// File appends the audit events to a file as JSON lines, log shippers like
+// Filebeat or the Splunk forwarder follow it
+type File struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFile opens the file to append the events to, it is created if missing
+func NewFile(path string) (*File, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &File{file: file}, nil
+}
+
+// Send ...
+func (f *File) Send(events []model.AuditEvent) error {
+	data := []byte{}
+	for i := range events {
+		line, err := json.Marshal(&events[i])
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, err := f.file.Write(data)
+	return err
+}
+
+// Close ...
+func (f *File) Close() error {
+	return f.file.Close()
+}
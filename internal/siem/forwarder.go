@@ -0,0 +1,95 @@
+package siem
+
+import (
+	"sync"
+	"time"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+const (
+	// queueSize is the number of the events waiting to be sent, events are
+	// dropped when the sinks can't keep up
+	queueSize = 10000
+	// batchSize is the maximum number of the events sent at once
+	batchSize = 100
+	// flushInterval is the longest time an event waits for its batch
+	flushInterval = time.Second
+)
+
+// Forwarder sends the recorded audit events to the sinks in the background,
+// so a slow SIEM doesn't slow down the requests
+type Forwarder struct {
+	sinks  []Sink
+	events chan model.AuditEvent
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewForwarder starts forwarding to the sinks
+func NewForwarder(sinks []Sink) *Forwarder {
+	f := &Forwarder{
+		sinks:  sinks,
+		events: make(chan model.AuditEvent, queueSize),
+		done:   make(chan struct{}),
+	}
+	go f.run()
+	return f
+}
+
+// Forward queues the event to be sent, it never blocks
+func (f *Forwarder) Forward(event model.AuditEvent) {
+	select {
+	case f.events <- event:
+	default:
+		logger.Errorf("audit event %d is dropped, the sinks can't keep up", event.ID)
+	}
+}
+
+// Close sends the queued events and closes the sinks
+func (f *Forwarder) Close() {
+	f.once.Do(func() {
+		close(f.events)
+		<-f.done
+		for _, sink := range f.sinks {
+			sink.Close()
+		}
+	})
+}
+
+func (f *Forwarder) run() {
+	defer close(f.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]model.AuditEvent, 0, batchSize)
+	for {
+		select {
+		case event, ok := <-f.events:
+			if !ok {
+				f.send(batch)
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) < batchSize {
+				continue
+			}
+		case <-ticker.C:
+		}
+		f.send(batch)
+		batch = batch[:0]
+	}
+}
+
+// send delivers the batch to every sink, a failing sink doesn't stop the others
+func (f *Forwarder) send(batch []model.AuditEvent) {
+	if len(batch) == 0 {
+		return
+	}
+	for _, sink := range f.sinks {
+		if err := sink.Send(batch); err != nil {
+			logger.Errorf("can't send %d audit events to %T error: %v", len(batch), sink, err)
+		}
+	}
+}
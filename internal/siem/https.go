@@ -0,0 +1,74 @@
+package siem
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/passwall/passwall-server/model"
+)
+
+// httpsTimeout limits a delivery so a slow endpoint doesn't hold the batches
+const httpsTimeout = 10 * time.Second
+
+// HTTPS posts the audit events to an HTTPS endpoint as JSON lines, like the
+// Splunk HTTP Event Collector raw endpoint or an Elastic ingest pipeline
+type HTTPS struct {
+	url           string
+	authorization string
+	client        *http.Client
+}
+
+// NewHTTPS creates the sink posting to the URL, the authorization is sent as
+// the Authorization header when it isn't empty
+func NewHTTPS(url, authorization string) (*HTTPS, error) {
+	if url == "" {
+		return nil, fmt.Errorf("URL is required")
+	}
+	return &HTTPS{
+		url:           url,
+		authorization: authorization,
+		client:        &http.Client{Timeout: httpsTimeout},
+	}, nil
+}
+
+// Send ...
+func (h *HTTPS) Send(events []model.AuditEvent) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for i := range events {
+		if err := encoder.Encode(&events[i]); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, h.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if h.authorization != "" {
+		req.Header.Set("Authorization", h.authorization)
+	}
+
+	res, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("endpoint returned %s", res.Status)
+	}
+	return nil
+}
+
+// Close ...
+func (h *HTTPS) Close() error {
+	h.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,50 @@
+package siem
+
+import (
+	"fmt"
+
+	"github.com/passwall/passwall-server/internal/config"
+	"github.com/passwall/passwall-server/model"
+)
+
+// Sinks of the audit events
+const (
+	SinkSyslog = "syslog"
+	SinkFile   = "file"
+	SinkHTTPS  = "https"
+)
+
+// Sink receives the audit events to pass them to a SIEM like Splunk or Elastic
+type Sink interface {
+	// Send delivers the events, in the order they are recorded
+	Send(events []model.AuditEvent) error
+	// Close releases the connection or the file of the sink
+	Close() error
+}
+
+// New creates the sinks of the configuration
+func New(cfg config.AuditConfiguration) ([]Sink, error) {
+	sinks := []Sink{}
+	for _, name := range cfg.Sinks {
+		var sink Sink
+		var err error
+		switch name {
+		case SinkSyslog:
+			sink, err = NewSyslog(cfg.SyslogNetwork, cfg.SyslogAddress)
+		case SinkFile:
+			sink, err = NewFile(cfg.FilePath)
+		case SinkHTTPS:
+			sink, err = NewHTTPS(cfg.HTTPSURL, cfg.HTTPSAuthorization)
+		default:
+			err = fmt.Errorf("unknown audit sink %q, use syslog, file or https", name)
+		}
+		if err != nil {
+			for _, sink := range sinks {
+				sink.Close()
+			}
+			return nil, fmt.Errorf("audit sink %s: %w", name, err)
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, nil
+}
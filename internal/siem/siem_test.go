@@ -0,0 +1,133 @@
+package siem
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func testEvent() model.AuditEvent {
+	return model.AuditEvent{
+		ID:        7,
+		CreatedAt: time.Date(2026, 3, 4, 5, 6, 7, 8000, time.UTC),
+		UserID:    1,
+		Action:    "signin_failed",
+		ItemType:  "users",
+		ItemID:    1,
+		IP:        "10.0.0.1",
+		UserAgent: `agent "x" [1]`,
+	}
+}
+
+func TestFormatSyslog(t *testing.T) {
+	event := testEvent()
+	message := formatSyslog(&event, "vault", 42)
+
+	assert.Equal(t, `<84>1 2026-03-04T05:06:07.000008Z vault passwall-server 42 signin_failed `+
+		`[passwall@32473 user_id="1" action="signin_failed" item_type="users" item_id="1" ip="10.0.0.1" user_agent="agent \"x\" [1\]"] `+
+		`{"id":7,"created_at":"2026-03-04T05:06:07.000008Z","user_id":1,"action":"signin_failed","item_type":"users","item_id":1,"ip":"10.0.0.1","user_agent":"agent \"x\" [1]"}`,
+		message)
+
+	event.Action = "create"
+	assert.Contains(t, formatSyslog(&event, "", 42), "<85>1 2026-03-04T05:06:07.000008Z - passwall-server 42 create ")
+
+	event.Country, event.City = "DE", "Berlin"
+	assert.Contains(t, formatSyslog(&event, "", 42), `user_agent="agent \"x\" [1\]" country="DE" city="Berlin"]`)
+}
+
+func TestMsgID(t *testing.T) {
+	assert.Equal(t, "-", msgID(""))
+	assert.Equal(t, "export_csv", msgID("export_csv"))
+	assert.Equal(t, "a_b", msgID("a b"))
+	assert.Len(t, msgID("a_very_long_action_name_over_the_limit"), 32)
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := NewFile(path)
+	assert.NoError(t, err)
+	assert.NoError(t, sink.Send([]model.AuditEvent{testEvent(), testEvent()}))
+	assert.NoError(t, sink.Close())
+
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event model.AuditEvent
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		assert.Equal(t, testEvent(), event)
+		lines++
+	}
+	assert.Equal(t, 2, lines)
+}
+
+func TestHTTPS(t *testing.T) {
+	var authorization string
+	var events []model.AuditEvent
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		decoder := json.NewDecoder(r.Body)
+		for decoder.More() {
+			var event model.AuditEvent
+			assert.NoError(t, decoder.Decode(&event))
+			events = append(events, event)
+		}
+	}))
+	defer server.Close()
+
+	sink, err := NewHTTPS(server.URL, "Splunk token")
+	assert.NoError(t, err)
+	sink.client = server.Client()
+	assert.NoError(t, sink.Send([]model.AuditEvent{testEvent(), testEvent()}))
+	assert.Equal(t, "Splunk token", authorization)
+	assert.Len(t, events, 2)
+
+	failing := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer failing.Close()
+	sink, _ = NewHTTPS(failing.URL, "")
+	sink.client = failing.Client()
+	assert.Error(t, sink.Send([]model.AuditEvent{testEvent()}))
+}
+
+type memorySink struct {
+	mu     sync.Mutex
+	events []model.AuditEvent
+	closed bool
+}
+
+func (m *memorySink) Send(events []model.AuditEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, events...)
+	return nil
+}
+
+func (m *memorySink) Close() error {
+	m.closed = true
+	return nil
+}
+
+func TestForwarder(t *testing.T) {
+	sink := &memorySink{}
+	forwarder := NewForwarder([]Sink{sink})
+	for i := 0; i < batchSize+5; i++ {
+		forwarder.Forward(testEvent())
+	}
+	forwarder.Close()
+
+	assert.Len(t, sink.events, batchSize+5)
+	assert.True(t, sink.closed)
+}
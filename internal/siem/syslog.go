@@ -0,0 +1,160 @@
+package siem
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/app"
+	"github.com/passwall/passwall-server/model"
+)
+
+const (
+	// syslogFacility is authpriv, the facility of the security messages
+	syslogFacility = 10
+	// syslogAppName is the APP-NAME of the messages
+	syslogAppName = "passwall-server"
+	// syslogSDID is the ID of the structured data element of the event
+	syslogSDID = "passwall@32473"
+	// syslogTimeout limits dialing and writing so a slow collector doesn't block the sink
+	syslogTimeout = 5 * time.Second
+)
+
+// Syslog sends the audit events to a syslog collector as RFC 5424 messages.
+// TCP messages are framed with their length as in RFC 6587.
+type Syslog struct {
+	mu       sync.Mutex
+	network  string
+	address  string
+	hostname string
+	conn     net.Conn
+}
+
+// NewSyslog connects to the collector on the address, network is udp or tcp
+func NewSyslog(network, address string) (*Syslog, error) {
+	hostname, _ := os.Hostname()
+	s := &Syslog{network: network, address: address, hostname: hostname}
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Syslog) connect() error {
+	conn, err := net.DialTimeout(s.network, s.address, syslogTimeout)
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	return nil
+}
+
+// Send ...
+func (s *Syslog) Send(events []model.AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range events {
+		message := formatSyslog(&events[i], s.hostname, os.Getpid())
+		if s.network == "tcp" {
+			message = strconv.Itoa(len(message)) + " " + message
+		}
+		if err := s.write(message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write writes the message, TCP connections closed by the collector are
+// opened again once
+func (s *Syslog) write(message string) error {
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return err
+		}
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(syslogTimeout))
+	_, err := s.conn.Write([]byte(message))
+	if err != nil && s.network == "tcp" {
+		s.conn.Close()
+		s.conn = nil
+		if err := s.connect(); err != nil {
+			return err
+		}
+		s.conn.SetWriteDeadline(time.Now().Add(syslogTimeout))
+		_, err = s.conn.Write([]byte(message))
+	}
+	return err
+}
+
+// Close ...
+func (s *Syslog) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+// formatSyslog returns the RFC 5424 message of the event. The fields are in the
+// structured data for the collectors parsing it and the message is the event as
+// JSON for the others.
+func formatSyslog(event *model.AuditEvent, hostname string, pid int) string {
+	severity := 5 // notice
+	if event.Action == app.AuditActionSigninFailed {
+		severity = 4 // warning
+	}
+	if hostname == "" {
+		hostname = "-"
+	}
+
+	params := []string{
+		sdParam("user_id", strconv.FormatUint(uint64(event.UserID), 10)),
+		sdParam("action", event.Action),
+		sdParam("item_type", event.ItemType),
+		sdParam("item_id", strconv.FormatUint(uint64(event.ItemID), 10)),
+		sdParam("ip", event.IP),
+		sdParam("user_agent", event.UserAgent),
+	}
+	// Location is only known when GeoIP is configured
+	if event.Country != "" {
+		params = append(params, sdParam("country", event.Country), sdParam("city", event.City))
+	}
+	message, _ := json.Marshal(event)
+
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s [%s %s] %s",
+		syslogFacility*8+severity,
+		event.CreatedAt.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		hostname, syslogAppName, pid, msgID(event.Action),
+		syslogSDID, strings.Join(params, " "), message)
+}
+
+// sdParam returns the structured data parameter, ", \ and ] are escaped
+func sdParam(name, value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+	return name + `="` + value + `"`
+}
+
+// msgID returns the action as MSGID, which is up to 32 printable ASCII characters
+func msgID(action string) string {
+	id := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, action)
+	if id == "" {
+		return "-"
+	}
+	if len(id) > 32 {
+		id = id[:32]
+	}
+	return id
+}
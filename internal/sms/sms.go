@@ -0,0 +1,59 @@
+package sms
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/config"
+)
+
+// SMS providers
+const (
+	ProviderTwilio = "twilio"
+	ProviderVonage = "vonage"
+)
+
+// smsTimeout limits a delivery so a slow provider doesn't hold the request
+const smsTimeout = 10 * time.Second
+
+// Sender sends the text messages with an SMS provider
+type Sender interface {
+	// Send sends the text to the phone number in E.164 format
+	Send(to, text string) error
+}
+
+// New creates the sender of the configured provider, it is nil when no
+// provider is configured
+func New(cfg config.SMSConfiguration) (Sender, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case ProviderTwilio:
+		return NewTwilio(cfg.TwilioAccountSID, cfg.TwilioAuthToken, cfg.From), nil
+	case ProviderVonage:
+		return NewVonage(cfg.VonageAPIKey, cfg.VonageAPISecret, cfg.From), nil
+	default:
+		return nil, fmt.Errorf("unknown SMS provider %q, use twilio or vonage", cfg.Provider)
+	}
+}
+
+// post sends the request and returns the response body, responses out of the
+// 2xx range are errors
+func post(client *http.Client, req *http.Request) ([]byte, error) {
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("provider returned %s: %s", res.Status, body)
+	}
+	return body, nil
+}
@@ -0,0 +1,79 @@
+package sms
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/passwall/passwall-server/internal/config"
+)
+
+func TestNew(t *testing.T) {
+	sender, err := New(config.SMSConfiguration{})
+	assert.NoError(t, err)
+	assert.Nil(t, sender)
+
+	sender, err = New(config.SMSConfiguration{Provider: ProviderTwilio})
+	assert.NoError(t, err)
+	assert.IsType(t, &Twilio{}, sender)
+
+	_, err = New(config.SMSConfiguration{Provider: "sns"})
+	assert.Error(t, err)
+}
+
+func TestTwilioSend(t *testing.T) {
+	var form map[string][]string
+	var user, password string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2010-04-01/Accounts/AC123/Messages.json", r.URL.Path)
+		user, password, _ = r.BasicAuth()
+		r.ParseForm()
+		form = r.PostForm
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	twilio := NewTwilio("AC123", "token", "+15005550006")
+	twilio.baseURL = server.URL
+	assert.NoError(t, twilio.Send("+905551112233", "Your code is 123456"))
+	assert.Equal(t, "AC123", user)
+	assert.Equal(t, "token", password)
+	assert.Equal(t, []string{"+905551112233"}, form["To"])
+	assert.Equal(t, []string{"+15005550006"}, form["From"])
+	assert.Equal(t, []string{"Your code is 123456"}, form["Body"])
+}
+
+func TestTwilioSendError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"code": 21211}`, http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	twilio := NewTwilio("AC123", "token", "+15005550006")
+	twilio.baseURL = server.URL
+	assert.Error(t, twilio.Send("+1", "Your code is 123456"))
+}
+
+func TestVonageSend(t *testing.T) {
+	status := "0"
+	var form map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/sms/json", r.URL.Path)
+		r.ParseForm()
+		form = r.PostForm
+		w.Write([]byte(`{"message-count": "1", "messages": [{"status": "` + status + `", "error-text": "Throttled"}]}`))
+	}))
+	defer server.Close()
+
+	vonage := NewVonage("key", "secret", "Passwall")
+	vonage.baseURL = server.URL
+	assert.NoError(t, vonage.Send("+905551112233", "Your code is 123456"))
+	assert.Equal(t, []string{"905551112233"}, form["to"])
+	assert.Equal(t, []string{"key"}, form["api_key"])
+	assert.Equal(t, []string{"Passwall"}, form["from"])
+
+	status = "1"
+	assert.EqualError(t, vonage.Send("+905551112233", "Your code is 123456"), "provider returned status 1: Throttled")
+}
@@ -0,0 +1,52 @@
+package sms
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// twilioURL is the base URL of the Twilio REST API
+const twilioURL = "https://api.twilio.com"
+
+// Twilio sends the messages with the Twilio Programmable Messaging API
+type Twilio struct {
+	baseURL    string
+	accountSID string
+	authToken  string
+	from       string
+	client     *http.Client
+}
+
+// NewTwilio creates the sender of the account, from is a Twilio number or a
+// messaging service SID
+func NewTwilio(accountSID, authToken, from string) *Twilio {
+	return &Twilio{
+		baseURL:    twilioURL,
+		accountSID: accountSID,
+		authToken:  authToken,
+		from:       from,
+		client:     &http.Client{Timeout: smsTimeout},
+	}
+}
+
+// Send ...
+func (t *Twilio) Send(to, text string) error {
+	form := url.Values{"To": {to}, "Body": {text}}
+	if strings.HasPrefix(t.from, "MG") {
+		form.Set("MessagingServiceSid", t.from)
+	} else {
+		form.Set("From", t.from)
+	}
+
+	endpoint := t.baseURL + "/2010-04-01/Accounts/" + url.PathEscape(t.accountSID) + "/Messages.json"
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(t.accountSID, t.authToken)
+
+	_, err = post(t.client, req)
+	return err
+}
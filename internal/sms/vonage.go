@@ -0,0 +1,80 @@
+package sms
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// vonageURL is the base URL of the Vonage SMS API
+const vonageURL = "https://rest.nexmo.com"
+
+// Vonage sends the messages with the Vonage SMS API
+type Vonage struct {
+	baseURL   string
+	apiKey    string
+	apiSecret string
+	from      string
+	client    *http.Client
+}
+
+// vonageResponse is the result of the message parts, status 0 is a success
+type vonageResponse struct {
+	Messages []struct {
+		Status    string `json:"status"`
+		ErrorText string `json:"error-text"`
+	} `json:"messages"`
+}
+
+// NewVonage creates the sender of the API key, from is a Vonage number or an
+// alphanumeric sender ID
+func NewVonage(apiKey, apiSecret, from string) *Vonage {
+	return &Vonage{
+		baseURL:   vonageURL,
+		apiKey:    apiKey,
+		apiSecret: apiSecret,
+		from:      from,
+		client:    &http.Client{Timeout: smsTimeout},
+	}
+}
+
+// Send ...
+func (v *Vonage) Send(to, text string) error {
+	form := url.Values{
+		"api_key":    {v.apiKey},
+		"api_secret": {v.apiSecret},
+		"from":       {v.from},
+		// Vonage takes the number without the plus sign
+		"to":   {strings.TrimPrefix(to, "+")},
+		"text": {text},
+		"type": {"unicode"},
+	}
+
+	req, err := http.NewRequest(http.MethodPost, v.baseURL+"/sms/json", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	body, err := post(v.client, req)
+	if err != nil {
+		return err
+	}
+
+	// Vonage returns 200 for the rejected messages too
+	var res vonageResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return err
+	}
+	if len(res.Messages) == 0 {
+		return fmt.Errorf("provider returned no messages")
+	}
+	for _, message := range res.Messages {
+		if message.Status != "0" {
+			return fmt.Errorf("provider returned status %s: %s", message.Status, message.ErrorText)
+		}
+	}
+	return nil
+}
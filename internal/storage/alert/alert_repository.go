@@ -0,0 +1,61 @@
+package alert
+
+import (
+	"time"
+
+	"github.com/passwall/passwall-server/internal/storage/query"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Repository ...
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository ...
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// FindAll ...
+func (p *Repository) FindAll(opts *model.ListOptions) ([]model.SecurityAlert, int64, error) {
+	db := p.db.Model(&model.SecurityAlert{})
+
+	alerts := []model.SecurityAlert{}
+	total, err := query.List(db, opts, nil, &alerts)
+	if err != nil {
+		logger.Errorf("Error listing security alerts error %v", err)
+		return nil, 0, err
+	}
+	return alerts, total, nil
+}
+
+// ExistsSince ...
+func (p *Repository) ExistsSince(userID uint, alertType string, since time.Time) (bool, error) {
+	var count int64
+	err := p.db.Model(&model.SecurityAlert{}).
+		Where("user_id = ? AND type = ? AND created_at >= ?", userID, alertType, since).
+		Count(&count).Error
+	if err != nil {
+		logger.Errorf("Error checking %v alerts of user %v error %v", alertType, userID, err)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Create ...
+func (p *Repository) Create(alert *model.SecurityAlert) (*model.SecurityAlert, error) {
+	err := p.db.Create(&alert).Error
+	if err != nil {
+		logger.Errorf("Error creating security alert %v error %v", alert, err)
+		return nil, err
+	}
+	return alert, nil
+}
+
+// DeleteByUserID ...
+func (p *Repository) DeleteByUserID(userID uint) error {
+	return p.db.Delete(model.SecurityAlert{}, "user_id = ?", userID).Error
+}
@@ -0,0 +1,78 @@
+package announcement
+
+import (
+	"time"
+
+	"github.com/passwall/passwall-server/internal/storage/query"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Repository ...
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository ...
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// All ...
+func (p *Repository) All() ([]model.Announcement, error) {
+	announcements := []model.Announcement{}
+	err := p.db.Order("starts_at desc").Find(&announcements).Error
+	if err != nil {
+		logger.Errorf("Error getting announcements error %v", err)
+		return nil, err
+	}
+	return announcements, nil
+}
+
+// FindActive ...
+func (p *Repository) FindActive(now time.Time) ([]model.Announcement, error) {
+	announcements := []model.Announcement{}
+	err := p.db.Where("starts_at <= ? AND (ends_at IS NULL OR ends_at > ?)", now, now).
+		Order("starts_at desc").Find(&announcements).Error
+	if err != nil {
+		logger.Errorf("Error getting active announcements error %v", err)
+		return nil, err
+	}
+	return announcements, nil
+}
+
+// FindByID ...
+func (p *Repository) FindByID(id uint) (*model.Announcement, error) {
+	announcement := new(model.Announcement)
+	err := p.db.Where("id = ?", id).First(&announcement).Error
+	if err != nil {
+		return nil, err
+	}
+	return announcement, nil
+}
+
+// Create ...
+func (p *Repository) Create(announcement *model.Announcement) (*model.Announcement, error) {
+	err := p.db.Create(&announcement).Error
+	if err != nil {
+		logger.Errorf("Error creating announcement error %v", err)
+		return nil, err
+	}
+	return announcement, nil
+}
+
+// Update saves the announcement if its version is still the one it was read with
+func (p *Repository) Update(announcement *model.Announcement) (*model.Announcement, error) {
+	err := query.SaveVersion(p.db, announcement, announcement.ID, &announcement.Version)
+	if err != nil {
+		logger.Errorf("Error updating announcement %v error %v", announcement.ID, err)
+		return nil, err
+	}
+	return announcement, nil
+}
+
+// Delete ...
+func (p *Repository) Delete(id uint) error {
+	return p.db.Delete(&model.Announcement{ID: id}).Error
+}
@@ -0,0 +1,41 @@
+package attribution
+
+import (
+	"gorm.io/gorm"
+)
+
+// principalKey is the statement setting of the principal changing the rows
+const principalKey = "attribution:principal"
+
+// With returns the query whose created and updated rows are attributed to the
+// principal, the queries built on it keep the principal
+func With(db *gorm.DB, principal string) *gorm.DB {
+	return db.Set(principalKey, principal).Session(&gorm.Session{})
+}
+
+// Register makes the creates and the updates of the queries of With set the
+// CreatedBy and the UpdatedBy fields of the models having them. It is called
+// once for every connection of the server.
+func Register(db *gorm.DB) error {
+	err := db.Callback().Create().Before("gorm:create").Register("attribution:create", attribute(true))
+	if err != nil {
+		return err
+	}
+	return db.Callback().Update().Before("gorm:update").Register("attribution:update", attribute(false))
+}
+
+// attribute returns the callback setting the principal of the statement
+func attribute(create bool) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		principal, ok := db.Statement.Get(principalKey)
+		if !ok || db.Statement.Schema == nil {
+			return
+		}
+		if create && db.Statement.Schema.LookUpField("CreatedBy") != nil {
+			db.Statement.SetColumn("CreatedBy", principal, true)
+		}
+		if db.Statement.Schema.LookUpField("UpdatedBy") != nil {
+			db.Statement.SetColumn("UpdatedBy", principal, true)
+		}
+	}
+}
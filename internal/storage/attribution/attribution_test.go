@@ -0,0 +1,41 @@
+package attribution
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/passwall/passwall-server/internal/storage/sqlite"
+	"github.com/passwall/passwall-server/model"
+)
+
+func TestRegister(t *testing.T) {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "passwall.db"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, Register(db))
+	require.NoError(t, db.Exec(`CREATE TABLE notes (
+		id integer PRIMARY KEY AUTOINCREMENT, created_at datetime, updated_at datetime, deleted_at datetime,
+		title text, note text, folder_id bigint, revision bigint NOT NULL DEFAULT 1,
+		created_by text NOT NULL DEFAULT '', updated_by text NOT NULL DEFAULT '')`).Error)
+
+	note := &model.Note{Title: "Wifi"}
+	require.NoError(t, With(db, "user:1").Create(note).Error)
+	assert.Equal(t, "user:1", note.CreatedBy)
+	assert.Equal(t, "user:1", note.UpdatedBy)
+
+	// Updates change only the last principal
+	note.Title = "Home wifi"
+	require.NoError(t, With(db, "import:3").Where("id = ?", note.ID).Select("*").Updates(note).Error)
+	found := &model.Note{}
+	require.NoError(t, db.First(found, note.ID).Error)
+	assert.Equal(t, "user:1", found.CreatedBy)
+	assert.Equal(t, "import:3", found.UpdatedBy)
+
+	// Queries without a principal leave the rows as they are
+	require.NoError(t, db.Model(found).Update("title", "Office wifi").Error)
+	require.NoError(t, db.First(found, note.ID).Error)
+	assert.Equal(t, "import:3", found.UpdatedBy)
+}
@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"github.com/passwall/passwall-server/internal/storage/query"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Repository ...
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository ...
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Create ...
+func (p *Repository) Create(event *model.AuditEvent) (*model.AuditEvent, error) {
+	err := p.db.Create(&event).Error
+	if err != nil {
+		logger.Errorf("Error creating audit event %v error %v", event, err)
+		return nil, err
+	}
+
+	return event, nil
+}
+
+// FindAll ...
+func (p *Repository) FindAll(opts *model.ListOptions, period model.AuditPeriod) ([]model.AuditEvent, int64, error) {
+	db := inPeriod(p.db.Model(&model.AuditEvent{}), period)
+
+	events := []model.AuditEvent{}
+	total, err := query.List(db, opts, nil, &events)
+	if err != nil {
+		logger.Errorf("Error listing audit events error %v", err)
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+// FindByAction ...
+func (p *Repository) FindByAction(action string, period model.AuditPeriod) ([]model.AuditEvent, error) {
+	db := inPeriod(p.db.Model(&model.AuditEvent{}), period)
+
+	events := []model.AuditEvent{}
+	err := db.Select("created_at", "user_id", "ip").Where("action = ?", action).Order("created_at asc").Find(&events).Error
+	if err != nil {
+		logger.Errorf("Error finding %v audit events error %v", action, err)
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// inPeriod limits the query to the events created in the period
+func inPeriod(db *gorm.DB, period model.AuditPeriod) *gorm.DB {
+	if period.From != nil {
+		db = db.Where("created_at >= ?", *period.From)
+	}
+	if period.To != nil {
+		db = db.Where("created_at < ?", *period.To)
+	}
+	return db
+}
@@ -0,0 +1,57 @@
+package breach
+
+import (
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Repository ...
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository ...
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// FindByUserID ...
+func (p *Repository) FindByUserID(userID uint) ([]model.EmailBreach, error) {
+	breaches := []model.EmailBreach{}
+	err := p.db.Where("user_id = ?", userID).Order("added_date desc").Find(&breaches).Error
+	if err != nil {
+		logger.Errorf("Error getting breaches of user %v error %v", userID, err)
+		return nil, err
+	}
+	return breaches, err
+}
+
+// Exists ...
+func (p *Repository) Exists(userID uint, email, name string) (bool, error) {
+	var count int64
+	err := p.db.Model(&model.EmailBreach{}).
+		Where("user_id = ? AND email = ? AND name = ?", userID, email, name).
+		Count(&count).Error
+	if err != nil {
+		logger.Errorf("Error checking breach %v of %v error %v", name, email, err)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Create ...
+func (p *Repository) Create(breach *model.EmailBreach) (*model.EmailBreach, error) {
+	err := p.db.Create(&breach).Error
+	if err != nil {
+		logger.Errorf("Error creating breach %v error %v", breach, err)
+		return nil, err
+	}
+
+	return breach, nil
+}
+
+// DeleteByUserID ...
+func (p *Repository) DeleteByUserID(userID uint) error {
+	return p.db.Delete(model.EmailBreach{}, "user_id = ?", userID).Error
+}
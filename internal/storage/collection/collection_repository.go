@@ -0,0 +1,155 @@
+package collection
+
+import (
+	"github.com/passwall/passwall-server/internal/storage/query"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Repository ...
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository ...
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// All ...
+func (p *Repository) All(orgID uint) ([]model.Collection, error) {
+	collections := []model.Collection{}
+	err := p.db.Where("organization_id = ?", orgID).Find(&collections).Error
+	if err != nil {
+		logger.Errorf("Error getting collections of organization %v error %v", orgID, err)
+		return nil, err
+	}
+	return collections, err
+}
+
+// FindByMember returns the collections of the organization the user is a member of
+func (p *Repository) FindByMember(orgID, userID uint) ([]model.Collection, error) {
+	collections := []model.Collection{}
+	err := p.db.Where("organization_id = ? AND id IN (?)", orgID,
+		p.db.Model(&model.CollectionMember{}).Select("collection_id").Where("user_id = ?", userID)).
+		Find(&collections).Error
+	if err != nil {
+		logger.Errorf("Error getting collections of member %v of organization %v error %v", userID, orgID, err)
+		return nil, err
+	}
+	return collections, err
+}
+
+// FindByID ...
+func (p *Repository) FindByID(id, orgID uint) (*model.Collection, error) {
+	collection := new(model.Collection)
+	err := p.db.Where("id = ? AND organization_id = ?", id, orgID).First(&collection).Error
+	if err != nil {
+		logger.Errorf("Error finding collection %v error %v", id, err)
+		return nil, err
+	}
+	return collection, err
+}
+
+// Create ...
+func (p *Repository) Create(collection *model.Collection) (*model.Collection, error) {
+	err := p.db.Create(&collection).Error
+	if err != nil {
+		logger.Errorf("Error creating collection %v error %v", collection, err)
+		return nil, err
+	}
+
+	return collection, nil
+}
+
+// Update saves the collection if its version is still the one it was read with
+func (p *Repository) Update(collection *model.Collection) (*model.Collection, error) {
+	err := query.SaveVersion(p.db, collection, collection.ID, &collection.Version)
+	if err != nil {
+		logger.Errorf("Error updating collection %v error %v", collection, err)
+		return nil, err
+	}
+
+	return collection, nil
+}
+
+// Delete ...
+func (p *Repository) Delete(id uint) error {
+	// Items are kept for restoring the collection until it is purged
+	return p.db.Delete(&model.Collection{ID: id}).Error
+}
+
+// Items ...
+func (p *Repository) Items(collectionID uint) ([]model.CollectionItem, error) {
+	items := []model.CollectionItem{}
+	err := p.db.Where("collection_id = ?", collectionID).Find(&items).Error
+	if err != nil {
+		logger.Errorf("Error getting items of collection %v error %v", collectionID, err)
+		return nil, err
+	}
+	return items, err
+}
+
+// AddItem ...
+func (p *Repository) AddItem(item *model.CollectionItem) (*model.CollectionItem, error) {
+	err := p.db.Create(&item).Error
+	if err != nil {
+		logger.Errorf("Error adding collection item %v error %v", item, err)
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// RemoveItem ...
+func (p *Repository) RemoveItem(collectionID uint, itemType string, itemID uint) error {
+	return p.db.Delete(model.CollectionItem{}, "collection_id = ? AND item_type = ? AND item_id = ?", collectionID, itemType, itemID).Error
+}
+
+// Members ...
+func (p *Repository) Members(collectionID uint) ([]model.CollectionMember, error) {
+	members := []model.CollectionMember{}
+	err := p.db.Where("collection_id = ?", collectionID).Find(&members).Error
+	if err != nil {
+		logger.Errorf("Error getting members of collection %v error %v", collectionID, err)
+		return nil, err
+	}
+	return members, err
+}
+
+// IsMember checks if the user is a member of the collection
+func (p *Repository) IsMember(collectionID, userID uint) (bool, error) {
+	var count int64
+	err := p.db.Model(&model.CollectionMember{}).Where("collection_id = ? AND user_id = ?", collectionID, userID).Count(&count).Error
+	return count > 0, err
+}
+
+// AddMember ...
+func (p *Repository) AddMember(member *model.CollectionMember) (*model.CollectionMember, error) {
+	err := p.db.Create(&member).Error
+	if err != nil {
+		logger.Errorf("Error adding collection member %v error %v", member, err)
+		return nil, err
+	}
+
+	return member, nil
+}
+
+// RemoveMember ...
+func (p *Repository) RemoveMember(collectionID, userID uint) error {
+	return p.db.Delete(model.CollectionMember{}, "collection_id = ? AND user_id = ?", collectionID, userID).Error
+}
+
+// HasMemberItem checks if the item is in a collection of the organization the
+// user is a member of
+func (p *Repository) HasMemberItem(orgID, userID uint, itemType string, itemID uint) (bool, error) {
+	var count int64
+	err := p.db.Model(&model.CollectionItem{}).
+		Joins("JOIN collection_members ON collection_members.collection_id = collection_items.collection_id").
+		Joins("JOIN collections ON collections.id = collection_items.collection_id").
+		Where("collections.organization_id = ? AND collections.deleted_at IS NULL", orgID).
+		Where("collection_members.user_id = ? AND collection_items.item_type = ? AND collection_items.item_id = ?", userID, itemType, itemID).
+		Count(&count).Error
+	return count > 0, err
+}
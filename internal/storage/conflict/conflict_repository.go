@@ -0,0 +1,57 @@
+package conflict
+
+import (
+	"github.com/passwall/passwall-server/internal/storage/tenancy"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Repository ...
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository ...
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// All ...
+func (p *Repository) All(schema string) ([]model.Conflict, error) {
+	conflicts := []model.Conflict{}
+	err := tenancy.Table(p.db, schema, "conflicts").Order("id asc").Find(&conflicts).Error
+	if err != nil {
+		logger.Errorf("Error getting conflicts error %v", err)
+		return nil, err
+	}
+
+	return conflicts, err
+}
+
+// FindByID ...
+func (p *Repository) FindByID(id uint, schema string) (*model.Conflict, error) {
+	conflict := new(model.Conflict)
+	err := tenancy.Table(p.db, schema, "conflicts").Where(`id = ?`, id).First(&conflict).Error
+	if err != nil {
+		logger.Errorf("Error finding conflict %v error %v", id, err)
+		return nil, err
+	}
+	return conflict, err
+}
+
+// Create ...
+func (p *Repository) Create(conflict *model.Conflict, schema string) (*model.Conflict, error) {
+	err := tenancy.Table(p.db, schema, "conflicts").Create(&conflict).Error
+	if err != nil {
+		logger.Errorf("Error creating conflict %v error %v", conflict, err)
+		return nil, err
+	}
+
+	return conflict, nil
+}
+
+// Delete ...
+func (p *Repository) Delete(id uint, schema string) error {
+	return tenancy.Table(p.db, schema, "conflicts").Delete(&model.Conflict{ID: id}).Error
+}
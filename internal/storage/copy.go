@@ -0,0 +1,286 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/passwall/passwall-server/internal/storage/migration"
+	"github.com/passwall/passwall-server/internal/storage/sqlite"
+	"github.com/passwall/passwall-server/internal/storage/tenancy"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// copyBatchSize is the number of rows copied at once
+const copyBatchSize = 500
+
+// ErrCopyTargetNotEmpty represents message for copying to a database which already has users
+var ErrCopyTargetNotEmpty = errors.New("target database already has users")
+
+// copyTable is a table and the model its rows are read into
+type copyTable struct {
+	name string
+	rows func() interface{}
+}
+
+var systemCopyTables = []copyTable{
+	{"users", func() interface{} { return &[]model.User{} }},
+	{"tokens", func() interface{} { return &[]model.Token{} }},
+	{"organizations", func() interface{} { return &[]model.Organization{} }},
+	{"organization_members", func() interface{} { return &[]model.OrganizationMember{} }},
+	{"collections", func() interface{} { return &[]model.Collection{} }},
+	{"collection_items", func() interface{} { return &[]model.CollectionItem{} }},
+	{"collection_members", func() interface{} { return &[]model.CollectionMember{} }},
+	{"email_breaches", func() interface{} { return &[]model.EmailBreach{} }},
+	{"audit_events", func() interface{} { return &[]model.AuditEvent{} }},
+	{"jobs", func() interface{} { return &[]model.Job{} }},
+	{"webhooks", func() interface{} { return &[]model.Webhook{} }},
+	{"webhook_deliveries", func() interface{} { return &[]model.WebhookDelivery{} }},
+	{"security_alerts", func() interface{} { return &[]model.SecurityAlert{} }},
+	{"outbox_emails", func() interface{} { return &[]model.OutboxEmail{} }},
+	{"settings", func() interface{} { return &[]model.Setting{} }},
+	{"announcements", func() interface{} { return &[]model.Announcement{} }},
+	{"data_keys", func() interface{} { return &[]model.DataKey{} }},
+}
+
+var vaultCopyTables = []copyTable{
+	{"folders", func() interface{} { return &[]model.Folder{} }},
+	{"logins", func() interface{} { return &[]model.Login{} }},
+	{"login_histories", func() interface{} { return &[]model.LoginHistory{} }},
+	{"credit_cards", func() interface{} { return &[]model.CreditCard{} }},
+	{"bank_accounts", func() interface{} { return &[]model.BankAccount{} }},
+	{"notes", func() interface{} { return &[]model.Note{} }},
+	{"emails", func() interface{} { return &[]model.Email{} }},
+	{"servers", func() interface{} { return &[]model.Server{} }},
+	{"item_relations", func() interface{} { return &[]model.ItemRelation{} }},
+	{"tombstones", func() interface{} { return &[]model.Tombstone{} }},
+	{"conflicts", func() interface{} { return &[]model.Conflict{} }},
+	{"vault_revisions", func() interface{} { return &[]model.VaultRevision{} }},
+}
+
+// CopyToPostgres copies the system tables and all the vaults of the SQLite
+// database to the Postgres database. Target is migrated first and must have no
+// users. Rows keep their IDs, so tokens, relations and sync clients stay valid.
+// Encrypted fields are copied as they are, both servers must use the same passphrase.
+func (db *Database) CopyToPostgres(dst *Database) error {
+	if !sqlite.Is(db.db) || sqlite.Is(dst.db) {
+		return errors.New("database can only be copied from SQLite to Postgres")
+	}
+	if tenancy.Rows() {
+		return fmt.Errorf("database can only be copied in %s tenancy, copy to rows afterwards", tenancy.ModeSchema)
+	}
+
+	var users int64
+	if err := dst.migrats.Up(migration.SetSystem, ""); err != nil {
+		return err
+	}
+	if err := dst.db.Table("users").Count(&users).Error; err != nil {
+		return err
+	}
+	if users > 0 {
+		return ErrCopyTargetNotEmpty
+	}
+
+	for _, table := range systemCopyTables {
+		if err := copyRows(db.db, dst.db, table, table.name); err != nil {
+			return err
+		}
+	}
+
+	schemas, err := sqlite.Schemas(db.db)
+	if err != nil {
+		return err
+	}
+	for _, schema := range schemas {
+		if err := dst.users.CreateSchema(schema); err != nil {
+			return err
+		}
+		if err := dst.migrats.Up(migration.SetVault, schema); err != nil {
+			return err
+		}
+		for _, table := range vaultCopyTables {
+			if err := copyRows(db.db, dst.db, table, schema+"."+table.name); err != nil {
+				return err
+			}
+		}
+		logger.Infof("Copied vault of schema %s", schema)
+	}
+
+	return nil
+}
+
+// copyRows copies the rows of the table in batches and moves the ID sequence
+// of the target table after the copied IDs
+func copyRows(src, dst *gorm.DB, table copyTable, name string) error {
+	rows := table.rows()
+	err := src.Table(name).Unscoped().FindInBatches(rows, copyBatchSize, func(tx *gorm.DB, batch int) error {
+		return dst.Table(name).Create(rows).Error
+	}).Error
+	if err != nil {
+		return fmt.Errorf("copying %s: %w", name, err)
+	}
+
+	return resetSequence(dst, name)
+}
+
+// resetSequence moves the ID sequence of the table after the IDs of its rows
+func resetSequence(db *gorm.DB, name string) error {
+	err := db.Exec(fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), COALESCE(MAX(id), 1), MAX(id) IS NOT NULL) FROM %[1]s", name)).Error
+	if err != nil {
+		return fmt.Errorf("resetting id sequence of %s: %w", name, err)
+	}
+	return nil
+}
+
+// CopyToRows copies the vaults of the schemas to the shared tables of row
+// tenancy, which are created in the vaults schema first. It runs while the
+// server still serves the schemas: every vault is copied in a transaction
+// holding off its writes, and vaults unchanged since their last copy are
+// skipped, so running it again copies only what changed in the meantime.
+// Vaults copied before but not in the schemas any more are removed. Returns
+// the number of the copied vaults.
+func (db *Database) CopyToRows(schemas []string) (int, error) {
+	if sqlite.Is(db.db) {
+		return 0, fmt.Errorf("%s tenancy needs postgres", tenancy.ModeRow)
+	}
+	if tenancy.Rows() {
+		return 0, errors.New("vaults are already in row tenancy")
+	}
+
+	if err := db.users.CreateSchema(tenancy.Schema); err != nil {
+		return 0, err
+	}
+	if err := db.migrats.Up(migration.SetVault, tenancy.Schema); err != nil {
+		return 0, err
+	}
+	if err := db.migrats.Up(migration.SetRows, tenancy.Schema); err != nil {
+		return 0, err
+	}
+
+	copied := 0
+	for _, schema := range schemas {
+		ok, err := copyVaultToRows(db.db, schema)
+		if err != nil {
+			return copied, fmt.Errorf("copying vault of schema %s: %w", schema, err)
+		}
+		if ok {
+			copied++
+			logger.Infof("Copied vault of schema %s", schema)
+		}
+	}
+
+	if err := removeStaleRows(db.db, schemas); err != nil {
+		return copied, err
+	}
+	for _, table := range tenancy.Tables {
+		if err := resetSequence(db.db, tenancy.Schema+"."+table); err != nil {
+			return copied, err
+		}
+	}
+	return copied, nil
+}
+
+// copyVaultToRows replaces the rows of the vault in the shared tables with the
+// ones of its schema, unless the vault didn't change since its last copy
+func copyVaultToRows(db *gorm.DB, schema string) (bool, error) {
+	copied := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		sources := make([]string, len(tenancy.Tables))
+		for i, table := range tenancy.Tables {
+			sources[i] = schema + "." + table
+		}
+		// Writes to the vault wait for the copy, it sees the vault at one point in time
+		if err := tx.Exec("LOCK TABLE " + strings.Join(sources, ", ") + " IN SHARE MODE").Error; err != nil {
+			return err
+		}
+
+		fingerprint, err := vaultFingerprint(tx, schema)
+		if err != nil {
+			return err
+		}
+		previous := []string{}
+		err = tx.Table(tenancy.Schema+".vault_copies").Where("vault = ?", schema).Pluck("fingerprint", &previous).Error
+		if err != nil {
+			return err
+		}
+		if len(previous) > 0 && previous[0] == fingerprint {
+			return nil
+		}
+
+		if err := tenancy.DeleteVault(tx, schema); err != nil {
+			return err
+		}
+		for _, table := range tenancy.Tables {
+			columns := []string{}
+			err := tx.Raw("SELECT column_name FROM information_schema.columns WHERE table_schema = ? AND table_name = ? ORDER BY ordinal_position",
+				schema, table).Scan(&columns).Error
+			if err != nil {
+				return err
+			}
+			list := strings.Join(columns, ", ")
+			err = tx.Exec(fmt.Sprintf("INSERT INTO %s.%s (%s, %s) SELECT %s, ? FROM %s.%s",
+				tenancy.Schema, table, list, tenancy.Column, list, schema, table), schema).Error
+			if err != nil {
+				return fmt.Errorf("copying %s: %w", table, err)
+			}
+		}
+
+		copied = true
+		return tx.Exec(`INSERT INTO `+tenancy.Schema+`.vault_copies (vault, fingerprint, copied_at) VALUES (?, ?, CURRENT_TIMESTAMP)
+			ON CONFLICT (vault) DO UPDATE SET fingerprint = EXCLUDED.fingerprint, copied_at = EXCLUDED.copied_at`, schema, fingerprint).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	return copied, nil
+}
+
+// vaultFingerprint returns the hash of all the rows of the vault of the
+// schema, any change of the vault changes it
+func vaultFingerprint(tx *gorm.DB, schema string) (string, error) {
+	hash := sha256.New()
+	for _, table := range tenancy.Tables {
+		var sum string
+		err := tx.Raw(fmt.Sprintf("SELECT COALESCE(md5(string_agg(md5(t::text), '' ORDER BY t.id)), '') FROM %s.%s t", schema, table)).
+			Scan(&sum).Error
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s:%s\n", table, sum)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// removeStaleRows removes the copied vaults which aren't one of the schemas,
+// their users or organizations were deleted since they were copied
+func removeStaleRows(db *gorm.DB, schemas []string) error {
+	current := map[string]bool{}
+	for _, schema := range schemas {
+		current[schema] = true
+	}
+
+	copies := []string{}
+	if err := db.Table(tenancy.Schema+".vault_copies").Pluck("vault", &copies).Error; err != nil {
+		return err
+	}
+	for _, schema := range copies {
+		if current[schema] {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tenancy.DeleteVault(tx, schema); err != nil {
+				return err
+			}
+			return tx.Exec("DELETE FROM "+tenancy.Schema+".vault_copies WHERE vault = ?", schema).Error
+		})
+		if err != nil {
+			return fmt.Errorf("removing vault of schema %s: %w", schema, err)
+		}
+		logger.Infof("Removed copied vault of schema %s", schema)
+	}
+	return nil
+}
@@ -1,21 +1,44 @@
 package storage
 
 import (
+	"database/sql"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"sort"
 	"time"
 
 	"github.com/passwall/passwall-server/internal/config"
-	"github.com/passwall/passwall-server/internal/storage/bankaccount"
-	"github.com/passwall/passwall-server/internal/storage/creditcard"
-	"github.com/passwall/passwall-server/internal/storage/email"
+	"github.com/passwall/passwall-server/internal/storage/alert"
+	"github.com/passwall/passwall-server/internal/storage/announcement"
+	"github.com/passwall/passwall-server/internal/storage/attribution"
+	"github.com/passwall/passwall-server/internal/storage/audit"
+	"github.com/passwall/passwall-server/internal/storage/breach"
+	"github.com/passwall/passwall-server/internal/storage/collection"
+	"github.com/passwall/passwall-server/internal/storage/conflict"
+	"github.com/passwall/passwall-server/internal/storage/datakey"
+	"github.com/passwall/passwall-server/internal/storage/device"
+	"github.com/passwall/passwall-server/internal/storage/folder"
+	"github.com/passwall/passwall-server/internal/storage/item"
+	"github.com/passwall/passwall-server/internal/storage/job"
 	"github.com/passwall/passwall-server/internal/storage/login"
-	"github.com/passwall/passwall-server/internal/storage/note"
-	"github.com/passwall/passwall-server/internal/storage/server"
+	"github.com/passwall/passwall-server/internal/storage/migration"
+	"github.com/passwall/passwall-server/internal/storage/organization"
+	"github.com/passwall/passwall-server/internal/storage/outbox"
+	"github.com/passwall/passwall-server/internal/storage/query"
+	"github.com/passwall/passwall-server/internal/storage/relation"
+	"github.com/passwall/passwall-server/internal/storage/setting"
+	"github.com/passwall/passwall-server/internal/storage/sqlite"
+	"github.com/passwall/passwall-server/internal/storage/tenancy"
 	"github.com/passwall/passwall-server/internal/storage/token"
+	"github.com/passwall/passwall-server/internal/storage/tombstone"
+	"github.com/passwall/passwall-server/internal/storage/trash"
 	"github.com/passwall/passwall-server/internal/storage/user"
+	"github.com/passwall/passwall-server/internal/storage/vault"
+	"github.com/passwall/passwall-server/internal/storage/webhook"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/cache"
 	"github.com/spf13/viper"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -33,6 +56,32 @@ type Database struct {
 	tokens   TokenRepository
 	users    UserRepository
 	servers  ServerRepository
+	orgs     OrganizationRepository
+	colls    CollectionRepository
+	breaches BreachRepository
+	rels     RelationRepository
+	folders  FolderRepository
+	audits   AuditRepository
+	jobs     JobRepository
+	hooks    WebhookRepository
+	alerts   AlertRepository
+	devices  TrustedDeviceRepository
+	settings SettingRepository
+	notices  AnnouncementRepository
+	outbox   OutboxRepository
+	tombs    TombstoneRepository
+	vaults   VaultRepository
+	confls   ConflictRepository
+	migrats  MigrationRepository
+	trash    TrashRepository
+	dataKeys DataKeyRepository
+	regions  map[string]Store
+
+	// userCache keeps the users of the auth lookups for the userTTL
+	userCache cache.Cache
+	userTTL   time.Duration
+	// evictions are the users changed in the transaction of the store
+	evictions *user.Evictions
 }
 
 // DBConn databese connection
@@ -56,13 +105,73 @@ func DBConn(cfg *config.DatabaseConfiguration) (*gorm.DB, error) {
 		},
 	)
 
+	if cfg.Driver == sqlite.Dialect {
+		if tenancy.Rows() {
+			return nil, fmt.Errorf("%s tenancy needs postgres, sqlite vaults are files", tenancy.ModeRow)
+		}
+		db, err = sqlite.Open(cfg.Path, &gorm.Config{Logger: newDBLogger})
+		if err != nil {
+			return nil, fmt.Errorf("could not open sqlite database %s: %v", cfg.Path, err)
+		}
+		return db, attribution.Register(db)
+	}
+
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s", cfg.Host, cfg.Username, cfg.Password, cfg.Name, cfg.Port, cfg.SSLMode)
+	// Postgres cancels the statements of the connections running longer
+	if cfg.StatementTimeout > 0 {
+		dsn += fmt.Sprintf(" statement_timeout=%d", cfg.StatementTimeout*1000)
+	}
 	db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: newDBLogger})
 	if err != nil {
 		return nil, fmt.Errorf("could not open postgresql connection: %v", err)
 	}
+	if err := configurePool(db, cfg); err != nil {
+		return nil, fmt.Errorf("could not configure postgresql connection pool: %v", err)
+	}
+	tenancy.Register(db)
+	if err := attribution.Register(db); err != nil {
+		return nil, err
+	}
+	if cfg.Replica == "" {
+		return db, nil
+	}
+
+	// The lists, the searches and the syncs read from the replica, the writes
+	// and the auth stay on the primary
+	replicaDSN := cfg.Replica
+	if cfg.StatementTimeout > 0 {
+		replicaDSN += fmt.Sprintf(" statement_timeout=%d", cfg.StatementTimeout*1000)
+	}
+	replica, err := gorm.Open(postgres.Open(replicaDSN), &gorm.Config{Logger: newDBLogger})
+	if err != nil {
+		return nil, fmt.Errorf("could not open postgresql replica connection: %v", err)
+	}
+	if err := configurePool(replica, cfg); err != nil {
+		return nil, fmt.Errorf("could not configure postgresql replica connection pool: %v", err)
+	}
+	return query.WithReplica(db, replica.ConnPool, time.Duration(cfg.ReplicaLag)*time.Second), nil
+}
 
-	return db, err
+// configurePool sets the limits of the connection pool, zero values keep the
+// defaults of database/sql
+func configurePool(db *gorm.DB, cfg *config.DatabaseConfiguration) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	if cfg.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime) * time.Minute)
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(time.Duration(cfg.ConnMaxIdleTime) * time.Minute)
+	}
+	return nil
 }
 
 // New opens a database according to configuration.
@@ -70,13 +179,32 @@ func New(db *gorm.DB) *Database {
 	return &Database{
 		db:       db,
 		logins:   login.NewRepository(db),
-		cards:    creditcard.NewRepository(db),
-		accounts: bankaccount.NewRepository(db),
-		notes:    note.NewRepository(db),
-		emails:   email.NewRepository(db),
+		cards:    item.NewRepository[model.CreditCard](db, "credit_cards", "credit_card", "card_name"),
+		accounts: item.NewRepository[model.BankAccount](db, "bank_accounts", "bank_account", "bank_name", "bank_code"),
+		notes:    item.NewRepository[model.Note](db, "notes", "note", "title"),
+		emails:   item.NewRepository[model.Email](db, "emails", "email", "title"),
 		tokens:   token.NewRepository(db),
 		users:    user.NewRepository(db),
-		servers:  server.NewRepository(db),
+		servers:  item.NewRepository[model.Server](db, "servers", "server", "title", "url"),
+		orgs:     organization.NewRepository(db),
+		colls:    collection.NewRepository(db),
+		breaches: breach.NewRepository(db),
+		rels:     relation.NewRepository(db),
+		folders:  folder.NewRepository(db),
+		audits:   audit.NewRepository(db),
+		jobs:     job.NewRepository(db),
+		hooks:    webhook.NewRepository(db),
+		alerts:   alert.NewRepository(db),
+		devices:  device.NewRepository(db),
+		settings: setting.NewRepository(db),
+		notices:  announcement.NewRepository(db),
+		outbox:   outbox.NewRepository(db),
+		tombs:    tombstone.NewRepository(db),
+		vaults:   vault.NewRepository(db),
+		confls:   conflict.NewRepository(db),
+		migrats:  migration.NewRepository(db),
+		trash:    trash.NewRepository(db),
+		dataKeys: datakey.NewRepository(db),
 	}
 }
 
@@ -95,12 +223,12 @@ func (db *Database) BankAccounts() BankAccountRepository {
 	return db.accounts
 }
 
-// Notes returns the BankAccountRepository.
+// Notes returns the NoteRepository.
 func (db *Database) Notes() NoteRepository {
 	return db.notes
 }
 
-// Emails returns the BankAccountRepository.
+// Emails returns the EmailRepository.
 func (db *Database) Emails() EmailRepository {
 	return db.emails
 }
@@ -120,6 +248,203 @@ func (db *Database) Servers() ServerRepository {
 	return db.servers
 }
 
+// Organizations returns the OrganizationRepository.
+func (db *Database) Organizations() OrganizationRepository {
+	return db.orgs
+}
+
+// Collections returns the CollectionRepository.
+func (db *Database) Collections() CollectionRepository {
+	return db.colls
+}
+
+// Breaches returns the BreachRepository.
+func (db *Database) Breaches() BreachRepository {
+	return db.breaches
+}
+
+// Relations returns the RelationRepository.
+func (db *Database) Relations() RelationRepository {
+	return db.rels
+}
+
+// Folders returns the FolderRepository.
+func (db *Database) Folders() FolderRepository {
+	return db.folders
+}
+
+// AuditEvents returns the AuditRepository.
+func (db *Database) AuditEvents() AuditRepository {
+	return db.audits
+}
+
+// Jobs returns the JobRepository.
+func (db *Database) Jobs() JobRepository {
+	return db.jobs
+}
+
+// Webhooks returns the WebhookRepository.
+func (db *Database) Webhooks() WebhookRepository {
+	return db.hooks
+}
+
+// Alerts returns the AlertRepository.
+func (db *Database) Alerts() AlertRepository {
+	return db.alerts
+}
+
+// TrustedDevices returns the TrustedDeviceRepository.
+func (db *Database) TrustedDevices() TrustedDeviceRepository {
+	return db.devices
+}
+
+// Settings returns the SettingRepository.
+func (db *Database) Settings() SettingRepository {
+	return db.settings
+}
+
+// Announcements returns the AnnouncementRepository.
+func (db *Database) Announcements() AnnouncementRepository {
+	return db.notices
+}
+
+// Outbox returns the OutboxRepository.
+func (db *Database) Outbox() OutboxRepository {
+	return db.outbox
+}
+
+// Tombstones returns the TombstoneRepository.
+func (db *Database) Tombstones() TombstoneRepository {
+	return db.tombs
+}
+
+// Vaults returns the VaultRepository.
+func (db *Database) Vaults() VaultRepository {
+	return db.vaults
+}
+
+// Conflicts returns the ConflictRepository.
+func (db *Database) Conflicts() ConflictRepository {
+	return db.confls
+}
+
+// Migrations returns the MigrationRepository.
+func (db *Database) Migrations() MigrationRepository {
+	return db.migrats
+}
+
+// Trash returns the TrashRepository.
+func (db *Database) Trash() TrashRepository {
+	return db.trash
+}
+
+// DataKeys returns the DataKeyRepository.
+func (db *Database) DataKeys() DataKeyRepository {
+	return db.dataKeys
+}
+
+// AddRegion registers the store of a data residency region.
+func (db *Database) AddRegion(name string, store Store) {
+	if db.regions == nil {
+		db.regions = map[string]Store{}
+	}
+	db.regions[name] = store
+}
+
+// Region returns the store of the data residency region.
+// Empty name returns the primary store.
+func (db *Database) Region(name string) (Store, error) {
+	if name == "" {
+		return db, nil
+	}
+	store, ok := db.regions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown region %q", name)
+	}
+	return store, nil
+}
+
+// RegionNames returns the names of the registered regions.
+func (db *Database) RegionNames() []string {
+	names := []string{}
+	for name := range db.regions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Size returns the bytes the database takes on disk
+func (db *Database) Size() (int64, error) {
+	if sqlite.Is(db.db) {
+		return sqlite.Size(db.db)
+	}
+
+	var size int64
+	err := db.db.Raw("SELECT pg_database_size(current_database())").Scan(&size).Error
+	return size, err
+}
+
+// WithTransaction runs fn with a store whose repositories use one database
+// transaction, it is committed when fn returns nil and rolled back otherwise.
+// Transactions of the store of fn are savepoints of the outer one. Regions are
+// other databases, their stores aren't in the transaction. SQLite has a single
+// connection, so fn must use only its store until it returns.
+func (db *Database) WithTransaction(fn func(Store) error) error {
+	// Changed users are removed from the cache once the outermost transaction commits
+	evictions := db.evictions
+	if evictions == nil {
+		evictions = &user.Evictions{}
+	}
+	err := db.db.Transaction(func(tx *gorm.DB) error {
+		store := New(tx)
+		store.regions = db.regions
+		store.evictions = evictions
+		store.CacheUsers(db.userCache, db.userTTL)
+		return fn(store)
+	})
+	if err == nil && db.evictions == nil && db.userCache != nil {
+		evictions.Forget(db.userCache)
+	}
+	return err
+}
+
+// As returns a store whose created and updated vault rows are attributed to
+// the principal. The stores of the regions aren't, As is called on them.
+func (db *Database) As(principal string) Store {
+	store := New(attribution.With(db.db, principal))
+	store.regions = db.regions
+	store.evictions = db.evictions
+	store.CacheUsers(db.userCache, db.userTTL)
+	return store
+}
+
+// CacheUsers makes the auth lookups of the users use the cache for the ttl,
+// the changes of the users remove them from it. Zero ttl or nil cache finds
+// them in the database every time.
+func (db *Database) CacheUsers(c cache.Cache, ttl time.Duration) {
+	if c == nil || ttl <= 0 {
+		return
+	}
+	db.userCache, db.userTTL = c, ttl
+	db.users = user.NewCachedRepository(user.NewRepository(db.db), c, ttl).DeferEvictions(db.evictions)
+}
+
+// ReadLag returns how far behind the primary the list, search and sync reads
+// may be, zero without a replica
+func (db *Database) ReadLag() time.Duration {
+	return query.ReadLag(db.db)
+}
+
+// PoolStats returns the stats of the connection pool of the database
+func (db *Database) PoolStats() (sql.DBStats, error) {
+	sqlDB, err := db.db.DB()
+	if err != nil {
+		return sql.DBStats{}, err
+	}
+	return sqlDB.Stats(), nil
+}
+
 // Ping checks if database is up
 func (db *Database) Ping() error {
 	sqlDB, err := db.db.DB()
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/passwall/passwall-server/internal/storage/migration"
+	"github.com/passwall/passwall-server/internal/storage/query"
+	"github.com/passwall/passwall-server/internal/storage/sqlite"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/cache"
+)
+
+func TestWithTransaction(t *testing.T) {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "passwall.db"), &gorm.Config{})
+	require.NoError(t, err)
+	s := New(db)
+	require.NoError(t, s.Migrations().Up(migration.SetSystem, ""))
+
+	// Failing work leaves nothing behind, the vault schema neither
+	failed := errors.New("failed")
+	err = s.WithTransaction(func(tx Store) error {
+		if _, err := tx.Users().Create(&model.User{Email: "jane@passwall.io", Schema: "user1"}); err != nil {
+			return err
+		}
+		if err := tx.Users().CreateSchema("user1"); err != nil {
+			return err
+		}
+		if err := tx.Migrations().Up(migration.SetVault, "user1"); err != nil {
+			return err
+		}
+		return failed
+	})
+	assert.ErrorIs(t, err, failed)
+	_, err = s.Users().FindByEmail("jane@passwall.io")
+	assert.Error(t, err)
+	status, err := s.Migrations().Status(migration.SetVault, "user1")
+	require.NoError(t, err)
+	assert.Zero(t, status.Version)
+
+	err = s.WithTransaction(func(tx Store) error {
+		_, err := tx.Users().Create(&model.User{Email: "jane@passwall.io"})
+		return err
+	})
+	require.NoError(t, err)
+	_, err = s.Users().FindByEmail("jane@passwall.io")
+	assert.NoError(t, err)
+}
+
+func TestUpdateChecksVersion(t *testing.T) {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "passwall.db"), &gorm.Config{})
+	require.NoError(t, err)
+	s := New(db)
+	require.NoError(t, s.Migrations().Up(migration.SetSystem, ""))
+
+	created, err := s.Users().Create(&model.User{Email: "jane@passwall.io"})
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), created.Version)
+
+	first, err := s.Users().FindByID(created.ID)
+	require.NoError(t, err)
+	second, err := s.Users().FindByID(created.ID)
+	require.NoError(t, err)
+
+	first.Name = "Jane"
+	updated, err := s.Users().Update(first)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), updated.Version)
+
+	// Updates of the version read before the last update are rejected
+	second.Name = "Jane Doe"
+	_, err = s.Users().Update(second)
+	var conflict *query.VersionConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, "users", conflict.Table)
+	assert.Equal(t, uint(1), conflict.Version)
+	assert.Equal(t, uint(1), second.Version)
+
+	found, err := s.Users().FindByID(created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Jane", found.Name)
+}
+
+func TestCacheUsers(t *testing.T) {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "passwall.db"), &gorm.Config{})
+	require.NoError(t, err)
+	s := New(db)
+	require.NoError(t, s.Migrations().Up(migration.SetSystem, ""))
+	c := cache.NewMemory()
+	s.CacheUsers(c, time.Minute)
+
+	created, err := s.Users().Create(&model.User{UUID: uuid.NewV4(), Email: "jane@passwall.io", MasterPassword: "hash"})
+	require.NoError(t, err)
+	found, err := s.Users().FindCachedByUUID(created.UUID.String())
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, found.ID)
+	assert.Empty(t, found.MasterPassword)
+
+	// Changes made around the repository are seen after the TTL only
+	require.NoError(t, db.Model(&model.User{}).Where("id = ?", created.ID).Update("name", "Jane").Error)
+	found, err = s.Users().FindCachedByUUID(created.UUID.String())
+	require.NoError(t, err)
+	assert.Empty(t, found.Name)
+
+	// Changes of the repository remove the user from the cache, in transactions
+	// too once they commit so the lookups meanwhile don't cache the old user again
+	err = s.WithTransaction(func(tx Store) error {
+		user, err := tx.Users().FindByID(created.ID)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		user.DisabledAt = &now
+		user.TokenEpoch++
+		if _, err = tx.Users().Update(user); err != nil {
+			return err
+		}
+		_, cached, err := c.Get("auth-user:" + created.UUID.String())
+		assert.True(t, cached)
+		return err
+	})
+	require.NoError(t, err)
+	_, cached, err := c.Get("auth-user:" + created.UUID.String())
+	require.NoError(t, err)
+	assert.False(t, cached)
+	found, err = s.Users().FindCachedByUUID(created.UUID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "Jane", found.Name)
+	assert.NotNil(t, found.DisabledAt)
+	assert.Equal(t, 1, found.TokenEpoch)
+
+	// The cached users keep the token epoch the JSON of the users hides
+	found, err = s.Users().FindCachedByUUID(created.UUID.String())
+	require.NoError(t, err)
+	assert.Equal(t, 1, found.TokenEpoch)
+
+	require.NoError(t, s.Users().Delete(created.ID, ""))
+	_, err = s.Users().FindCachedByUUID(created.UUID.String())
+	assert.Error(t, err)
+}
+
+func TestDataKeyVersionsDown(t *testing.T) {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "passwall.db"), &gorm.Config{})
+	require.NoError(t, err)
+	s := New(db)
+	require.NoError(t, s.Migrations().Up(migration.SetSystem, ""))
+	status, err := s.Migrations().Status(migration.SetSystem, "")
+	require.NoError(t, err)
+	// Steps reverting the migrations down to the data key versions
+	steps := int(status.Latest) - 25
+
+	_, err = s.DataKeys().Create(&model.DataKey{KeyID: "old", Schema: "user1", WrappedKey: "old", Version: 1})
+	require.NoError(t, err)
+	_, err = s.DataKeys().Create(&model.DataKey{KeyID: "new", Schema: "user1", WrappedKey: "new", Version: 2})
+	require.NoError(t, err)
+
+	// Older keys of an unfinished rotation aren't deleted, the migrations
+	// after the data key versions are reverted only
+	assert.Error(t, s.Migrations().Down(migration.SetSystem, "", steps))
+	var keys int64
+	require.NoError(t, db.Table("data_keys").Count(&keys).Error)
+	assert.Equal(t, int64(2), keys)
+	status, err = s.Migrations().Status(migration.SetSystem, "")
+	require.NoError(t, err)
+	assert.Equal(t, uint(26), status.Version)
+
+	require.NoError(t, db.Exec("DELETE FROM data_keys WHERE key_id = ?", "old").Error)
+	require.NoError(t, s.Migrations().Down(migration.SetSystem, "", 1))
+	require.NoError(t, db.Table("data_keys").Count(&keys).Error)
+	assert.Equal(t, int64(1), keys)
+}
@@ -0,0 +1,92 @@
+package datakey
+
+import (
+	"time"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Repository ...
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository ...
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// All ...
+func (p *Repository) All() ([]model.DataKey, error) {
+	keys := []model.DataKey{}
+	err := p.db.Order("id").Find(&keys).Error
+	if err != nil {
+		logger.Errorf("Error getting data keys error %v", err)
+		return nil, err
+	}
+	return keys, nil
+}
+
+// FindBySchema ...
+func (p *Repository) FindBySchema(schema string) (*model.DataKey, error) {
+	key := new(model.DataKey)
+	err := p.db.Where("schema = ?", schema).Order("version desc").First(key).Error
+	return key, err
+}
+
+// FindByKeyID ...
+func (p *Repository) FindByKeyID(keyID string) (*model.DataKey, error) {
+	key := new(model.DataKey)
+	err := p.db.Where("key_id = ?", keyID).First(key).Error
+	return key, err
+}
+
+// Create ...
+func (p *Repository) Create(key *model.DataKey) (*model.DataKey, error) {
+	err := p.db.Create(key).Error
+	if err != nil {
+		logger.Errorf("Error creating data key of schema %v error %v", key.Schema, err)
+		return nil, err
+	}
+	return key, nil
+}
+
+// Rewrap saves the key wrapped by another master key
+func (p *Repository) Rewrap(key *model.DataKey) error {
+	err := p.db.Model(key).Update("wrapped_key", key.WrappedKey).Error
+	if err != nil {
+		logger.Errorf("Error rewrapping data key %v error %v", key.KeyID, err)
+	}
+	return err
+}
+
+// MarkReencrypted records the fields of the vault of the schema are moved to its key
+func (p *Repository) MarkReencrypted(schema string) error {
+	err := p.db.Model(&model.DataKey{}).Where("schema = ?", schema).Update("reencrypted_at", time.Now()).Error
+	if err != nil {
+		logger.Errorf("Error marking data key of schema %v reencrypted error %v", schema, err)
+	}
+	return err
+}
+
+// Delete removes the keys of the schema, the fields they encrypted can't be
+// decrypted anymore
+func (p *Repository) Delete(schema string) error {
+	err := p.db.Where("schema = ?", schema).Delete(&model.DataKey{}).Error
+	if err != nil {
+		logger.Errorf("Error deleting data key of schema %v error %v", schema, err)
+	}
+	return err
+}
+
+// DeleteBefore removes the keys of the schema older than the version, once a
+// key rotation moved their fields to the newer key
+func (p *Repository) DeleteBefore(schema string, version int) error {
+	err := p.db.Where("schema = ? AND version < ?", schema, version).Delete(&model.DataKey{}).Error
+	if err != nil {
+		logger.Errorf("Error deleting data keys of schema %v before version %v error %v", schema, version, err)
+	}
+	return err
+}
@@ -0,0 +1,71 @@
+package device
+
+import (
+	"time"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Repository ...
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository ...
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// FindByUserID ...
+func (p *Repository) FindByUserID(userID uint, now time.Time) ([]model.TrustedDevice, error) {
+	devices := []model.TrustedDevice{}
+	err := p.db.Where("user_id = ? AND expires_at > ?", userID, now).Order("created_at desc").Find(&devices).Error
+	if err != nil {
+		logger.Errorf("Error getting trusted devices of user %v error %v", userID, err)
+		return nil, err
+	}
+	return devices, nil
+}
+
+// FindByTokenHash ...
+func (p *Repository) FindByTokenHash(tokenHash string, now time.Time) (*model.TrustedDevice, error) {
+	device := new(model.TrustedDevice)
+	err := p.db.Where("token_hash = ? AND expires_at > ?", tokenHash, now).First(device).Error
+	return device, err
+}
+
+// Create ...
+func (p *Repository) Create(device *model.TrustedDevice) (*model.TrustedDevice, error) {
+	err := p.db.Create(device).Error
+	if err != nil {
+		logger.Errorf("Error creating trusted device of user %v error %v", device.UserID, err)
+		return nil, err
+	}
+	return device, nil
+}
+
+// Touch saves the last use of the device
+func (p *Repository) Touch(device *model.TrustedDevice) error {
+	return p.db.Model(device).Update("last_used_at", device.LastUsedAt).Error
+}
+
+// Delete ...
+func (p *Repository) Delete(userID uint, uuid string) (*model.TrustedDevice, error) {
+	device := new(model.TrustedDevice)
+	if err := p.db.Where("user_id = ? AND uuid = ?", userID, uuid).First(device).Error; err != nil {
+		return nil, err
+	}
+	return device, p.db.Delete(device).Error
+}
+
+// DeleteStale removes the expired devices of the user and the ones of the device
+func (p *Repository) DeleteStale(userID uint, deviceHash string, now time.Time) error {
+	return p.db.Delete(model.TrustedDevice{}, "user_id = ? AND (expires_at <= ? OR device_hash = ?)", userID, now, deviceHash).Error
+}
+
+// DeleteByUserID ...
+func (p *Repository) DeleteByUserID(userID uint) error {
+	return p.db.Delete(model.TrustedDevice{}, "user_id = ?", userID).Error
+}
@@ -0,0 +1,135 @@
+package folder
+
+import (
+	"time"
+
+	"github.com/passwall/passwall-server/internal/storage/query"
+	"github.com/passwall/passwall-server/internal/storage/tenancy"
+	"github.com/passwall/passwall-server/internal/storage/tombstone"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// itemTables are the vault tables having folder_id column
+var itemTables = []string{"logins", "credit_cards", "bank_accounts", "notes", "emails", "servers"}
+
+// Repository ...
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository ...
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// All ...
+func (p *Repository) All(schema string) ([]model.Folder, error) {
+	folders := []model.Folder{}
+	err := tenancy.Table(query.Reads(p.db), schema, "folders").Order("name").Find(&folders).Error
+	if err != nil {
+		logger.Errorf("Error getting folders error %v", err)
+		return nil, err
+	}
+
+	return folders, err
+}
+
+// FindUpdatedSince ...
+func (p *Repository) FindUpdatedSince(since time.Time, schema string) ([]model.Folder, error) {
+	folders := []model.Folder{}
+	err := tenancy.Table(query.Reads(p.db), schema, "folders").Where("updated_at >= ?", since).Order("id").Find(&folders).Error
+	if err != nil {
+		logger.Errorf("Error getting folders updated since %v error %v", since, err)
+		return nil, err
+	}
+
+	return folders, err
+}
+
+// FindByID ...
+func (p *Repository) FindByID(id uint, schema string) (*model.Folder, error) {
+	folder := new(model.Folder)
+	err := tenancy.Table(p.db, schema, "folders").Where(`id = ?`, id).First(&folder).Error
+	if err != nil {
+		logger.Errorf("Error finding folder %v error %v", id, err)
+		return nil, err
+	}
+	return folder, err
+}
+
+// FindByName ...
+func (p *Repository) FindByName(name string, parentID *uint, schema string) (*model.Folder, error) {
+	folder := new(model.Folder)
+	query := tenancy.Table(p.db, schema, "folders").Where(`name = ?`, name)
+	if parentID == nil {
+		query = query.Where(`parent_id IS NULL`)
+	} else {
+		query = query.Where(`parent_id = ?`, *parentID)
+	}
+	err := query.First(&folder).Error
+	if err != nil {
+		return nil, err
+	}
+	return folder, err
+}
+
+// Create ...
+func (p *Repository) Create(folder *model.Folder, schema string) (*model.Folder, error) {
+	err := tenancy.Table(p.db, schema, "folders").Create(&folder).Error
+	if err != nil {
+		logger.Errorf("Error creating folder %v error %v", folder, err)
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+// Update ...
+func (p *Repository) Update(folder *model.Folder, schema string) (*model.Folder, error) {
+	err := tenancy.Table(p.db, schema, "folders").Save(&folder).Error
+	if err != nil {
+		logger.Errorf("Error updating folder %v error %v", folder, err)
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+// Delete removes the folder, its items and subfolders are moved to the parent folder
+func (p *Repository) Delete(id uint, schema string) error {
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		folder := new(model.Folder)
+		if err := tenancy.Table(tx, schema, "folders").Where(`id = ?`, id).First(&folder).Error; err != nil {
+			return err
+		}
+
+		// Moved items are marked as updated so sync clients get their new folder
+		now := time.Now()
+		for _, table := range itemTables {
+			err := tenancy.Table(tx, schema, table).Where(`folder_id = ?`, id).
+				Updates(map[string]interface{}{
+					"folder_id":  folder.ParentID,
+					"updated_at": now,
+					"revision":   gorm.Expr("revision + 1"),
+				}).Error
+			if err != nil {
+				logger.Errorf("Error moving %v of folder %v error %v", table, id, err)
+				return err
+			}
+		}
+
+		err := tenancy.Table(tx, schema, "folders").Where(`parent_id = ?`, id).
+			Updates(map[string]interface{}{"parent_id": folder.ParentID, "updated_at": now}).Error
+		if err != nil {
+			logger.Errorf("Error moving subfolders of folder %v error %v", id, err)
+			return err
+		}
+
+		if err := tenancy.Table(tx, schema, "folders").Delete(&model.Folder{ID: id}).Error; err != nil {
+			return err
+		}
+		return tombstone.Record(tx, schema, "folder", id)
+	})
+}
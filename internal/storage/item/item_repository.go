@@ -0,0 +1,151 @@
+package item
+
+import (
+	"github.com/passwall/passwall-server/internal/storage/query"
+	"github.com/passwall/passwall-server/internal/storage/tenancy"
+	"github.com/passwall/passwall-server/internal/storage/tombstone"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Entity is the pointer to a vault item the repository stores, the item tells
+// its ID and revision to be updated
+type Entity[T any] interface {
+	*T
+	model.Item
+}
+
+// Repository stores the vault items of a type in their table of the vault
+// schema. Every item type uses it, a new type only needs a table.
+type Repository[T any, P Entity[T]] struct {
+	db            *gorm.DB
+	table         string
+	itemType      string
+	searchColumns []string
+	listOmits     []string
+}
+
+// NewRepository returns the repository of the items in the table, the item
+// type is the one of their tombstones and the search columns are the ones
+// searched when listing them
+func NewRepository[T any, P Entity[T]](db *gorm.DB, table, itemType string, searchColumns ...string) *Repository[T, P] {
+	return &Repository[T, P]{db: db, table: table, itemType: itemType, searchColumns: searchColumns}
+}
+
+// OmitFromLists leaves the columns out of the lists, like the ones the
+// clients never get. Items of the lists aren't saved back, All keeps them.
+func (p *Repository[T, P]) OmitFromLists(columns ...string) *Repository[T, P] {
+	p.listOmits = columns
+	return p
+}
+
+// All ...
+func (p *Repository[T, P]) All(schema string) ([]T, error) {
+	items := []T{}
+	err := tenancy.Table(query.Reads(p.db), schema, p.table).Find(&items).Error
+	if err != nil {
+		logger.Errorf("Error getting all %s error %v", p.table, err)
+		return nil, err
+	}
+	return items, nil
+}
+
+// FindAll ...
+func (p *Repository[T, P]) FindAll(opts *model.ListOptions, schema string) ([]T, int64, error) {
+	items := []T{}
+	db := tenancy.Table(query.Reads(p.db), schema, p.table)
+	if len(p.listOmits) > 0 {
+		db = db.Omit(p.listOmits...)
+	}
+	total, err := query.List(db, opts, p.searchColumns, &items)
+	if err != nil {
+		logger.Errorf("Error listing %s error %v", p.table, err)
+		return nil, 0, err
+	}
+
+	return items, total, nil
+}
+
+// FindByID ...
+func (p *Repository[T, P]) FindByID(id uint, schema string) (*T, error) {
+	item := new(T)
+	err := tenancy.Table(p.db, schema, p.table).Where(`id = ?`, id).First(item).Error
+	if err != nil {
+		logger.Errorf("Error finding %s %v error %v", p.itemType, id, err)
+		return nil, err
+	}
+	return item, nil
+}
+
+// FindByIDs finds the items of the IDs at once, the missing ones are skipped
+func (p *Repository[T, P]) FindByIDs(ids []uint, schema string) ([]T, error) {
+	items := []T{}
+	if len(ids) == 0 {
+		return items, nil
+	}
+	err := tenancy.Table(query.Reads(p.db), schema, p.table).Where("id IN ?", ids).Find(&items).Error
+	if err != nil {
+		logger.Errorf("Error finding %s %v error %v", p.table, ids, err)
+		return nil, err
+	}
+	return items, nil
+}
+
+// Update saves the item if its revision is still the one it was read with
+func (p *Repository[T, P]) Update(item *T, schema string) (*T, error) {
+	entity := P(item)
+	err := query.SaveRevision(tenancy.Table(p.db, schema, p.table), item, entity.ItemID(), entity.ItemRevision())
+	if err != nil {
+		logger.Errorf("Error updating %s %v error %v", p.itemType, entity.ItemID(), err)
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// Create ...
+func (p *Repository[T, P]) Create(item *T, schema string) (*T, error) {
+	err := tenancy.Table(p.db, schema, p.table).Create(item).Error
+	if err != nil {
+		logger.Errorf("Error creating %s error %v", p.itemType, err)
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// CreateSealed creates the item and saves the columns the function seals once
+// the item has its ID, in the same transaction. Without the function the item
+// is created as it is.
+func (p *Repository[T, P]) CreateSealed(item *T, schema string, seal func(*T) (map[string]interface{}, error)) (*T, error) {
+	err := p.db.Transaction(func(tx *gorm.DB) error {
+		if err := tenancy.Table(tx, schema, p.table).Create(item).Error; err != nil {
+			return err
+		}
+		if seal == nil {
+			return nil
+		}
+		columns, err := seal(item)
+		if err != nil {
+			return err
+		}
+		return tenancy.Table(tx, schema, p.table).Where("id = ?", P(item).ItemID()).UpdateColumns(columns).Error
+	})
+	if err != nil {
+		logger.Errorf("Error creating %s error %v", p.itemType, err)
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// Delete removes the item and leaves a tombstone for the sync clients
+func (p *Repository[T, P]) Delete(id uint, schema string) error {
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		if err := tenancy.Table(tx, schema, p.table).Where("id = ?", id).Delete(new(T)).Error; err != nil {
+			return err
+		}
+		return tombstone.Record(tx, schema, p.itemType, id)
+	})
+}
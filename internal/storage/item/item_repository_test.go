@@ -0,0 +1,113 @@
+package item
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/passwall/passwall-server/internal/storage/query"
+	"github.com/passwall/passwall-server/internal/storage/sqlite"
+	"github.com/passwall/passwall-server/model"
+)
+
+func openVault(t *testing.T) *gorm.DB {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "passwall.db"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, sqlite.Attach(db, "user1"))
+	require.NoError(t, db.Exec(`CREATE TABLE user1.notes (
+		id integer PRIMARY KEY AUTOINCREMENT, created_at datetime, updated_at datetime, deleted_at datetime,
+		title text, note text, folder_id bigint, revision bigint NOT NULL DEFAULT 1,
+		created_by text NOT NULL DEFAULT '', updated_by text NOT NULL DEFAULT '')`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE user1.tombstones (
+		id integer PRIMARY KEY AUTOINCREMENT, created_at datetime, item_type text, item_id bigint)`).Error)
+	return db
+}
+
+func TestRepository(t *testing.T) {
+	db := openVault(t)
+	notes := NewRepository[model.Note](db, "notes", "note", "title")
+
+	created, err := notes.Create(&model.Note{Title: "Wifi", Note: "secret"}, "user1")
+	require.NoError(t, err)
+	assert.NotZero(t, created.ID)
+	_, err = notes.Create(&model.Note{Title: "Alarm", Note: "1234"}, "user1")
+	require.NoError(t, err)
+
+	found, err := notes.FindByID(created.ID, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, "Wifi", found.Title)
+	assert.Equal(t, uint(1), found.Revision)
+
+	all, err := notes.All("user1")
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	listed, total, err := notes.FindAll(&model.ListOptions{Search: "wif", Sort: "id", Direction: "asc", Filters: map[string]interface{}{}}, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, created.ID, listed[0].ID)
+
+	// Missing items are skipped
+	byIDs, err := notes.FindByIDs([]uint{created.ID, 1000}, "user1")
+	require.NoError(t, err)
+	require.Len(t, byIDs, 1)
+	assert.Equal(t, "Wifi", byIDs[0].Title)
+
+	// Omitted columns are left out of the lists only
+	omitted := NewRepository[model.Note](db, "notes", "note", "title").OmitFromLists("note")
+	listed, _, err = omitted.FindAll(&model.ListOptions{Sort: "id", Direction: "asc", Filters: map[string]interface{}{}}, "user1")
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+	assert.Equal(t, "Wifi", listed[0].Title)
+	assert.Empty(t, listed[0].Note)
+
+	// Updates of an older revision conflict
+	found.Title = "Home wifi"
+	updated, err := notes.Update(found, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), updated.Revision)
+	stale := *found
+	stale.Revision = 1
+	_, err = notes.Update(&stale, "user1")
+	assert.ErrorIs(t, err, query.ErrRevisionConflict)
+
+	require.NoError(t, notes.Delete(created.ID, "user1"))
+	_, err = notes.FindByID(created.ID, "user1")
+	assert.Error(t, err)
+
+	var tombstones []model.Tombstone
+	require.NoError(t, db.Table("user1.tombstones").Find(&tombstones).Error)
+	require.Len(t, tombstones, 1)
+	assert.Equal(t, "note", tombstones[0].ItemType)
+	assert.Equal(t, created.ID, tombstones[0].ItemID)
+}
+
+func TestCreateSealed(t *testing.T) {
+	db := openVault(t)
+	notes := NewRepository[model.Note](db, "notes", "note", "title")
+
+	// Columns are sealed with the ID of the created item
+	created, err := notes.CreateSealed(&model.Note{Title: "Wifi"}, "user1", func(note *model.Note) (map[string]interface{}, error) {
+		note.Note = fmt.Sprintf("sealed %d", note.ID)
+		return map[string]interface{}{"note": note.Note}, nil
+	})
+	require.NoError(t, err)
+	found, err := notes.FindByID(created.ID, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("sealed %d", created.ID), found.Note)
+	assert.Equal(t, uint(1), found.Revision)
+
+	// Items whose columns can't be sealed aren't created
+	_, err = notes.CreateSealed(&model.Note{Title: "Alarm"}, "user1", func(*model.Note) (map[string]interface{}, error) {
+		return nil, errors.New("no data key")
+	})
+	assert.Error(t, err)
+	all, err := notes.All("user1")
+	require.NoError(t, err)
+	assert.Len(t, all, 1)
+}
@@ -0,0 +1,50 @@
+package job
+
+import (
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Repository ...
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository ...
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// FindByID ...
+func (p *Repository) FindByID(id uint) (*model.Job, error) {
+	job := new(model.Job)
+	err := p.db.Where(`id = ?`, id).First(&job).Error
+	if err != nil {
+		logger.Errorf("Error getting job %v error %v", id, err)
+		return nil, err
+	}
+	return job, nil
+}
+
+// Create ...
+func (p *Repository) Create(job *model.Job) (*model.Job, error) {
+	err := p.db.Create(&job).Error
+	if err != nil {
+		logger.Errorf("Error creating job %v error %v", job, err)
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// Update ...
+func (p *Repository) Update(job *model.Job) (*model.Job, error) {
+	err := p.db.Save(&job).Error
+	if err != nil {
+		logger.Errorf("Error updating job %v error %v", job, err)
+		return nil, err
+	}
+
+	return job, nil
+}
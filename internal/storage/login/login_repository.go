@@ -1,73 +1,104 @@
 package login
 
 import (
+	"strings"
+
+	"github.com/passwall/passwall-server/internal/storage/item"
+	"github.com/passwall/passwall-server/internal/storage/query"
+	"github.com/passwall/passwall-server/internal/storage/sqlite"
+	"github.com/passwall/passwall-server/internal/storage/tenancy"
 	"github.com/passwall/passwall-server/model"
 	"github.com/passwall/passwall-server/pkg/logger"
 	"gorm.io/gorm"
 )
 
-// Repository ...
+// Repository stores the logins like the other items, with their histories
+// and search index
 type Repository struct {
+	*item.Repository[model.Login, *model.Login]
 	db *gorm.DB
 }
 
 // NewRepository ...
 func NewRepository(db *gorm.DB) *Repository {
-	return &Repository{db: db}
+	return &Repository{Repository: item.NewRepository[model.Login](db, "logins", "login", "title", "url").OmitFromLists("search_index"), db: db}
 }
 
-// All ...
-func (p *Repository) All(schema string) ([]model.Login, error) {
-	logins := []model.Login{}
-	err := p.db.Table(schema + ".logins").Find(&logins).Error
+// Histories ...
+func (p *Repository) Histories(loginID uint, schema string) ([]model.LoginHistory, error) {
+	histories := []model.LoginHistory{}
+	err := tenancy.Table(p.db, schema, "login_histories").Where("login_id = ?", loginID).Order("created_at desc").Find(&histories).Error
 	if err != nil {
-		logger.Errorf("Error getting all logins error %v", err)
+		logger.Errorf("Error getting histories of login %v error %v", loginID, err)
 		return nil, err
 	}
 
-	return logins, err
+	return histories, err
 }
 
-// FindByID ...
-func (p *Repository) FindByID(id uint, schema string) (*model.Login, error) {
-	login := new(model.Login)
-	err := p.db.Table(schema+".logins").Where(`id = ?`, id).First(&login).Error
+// CreateHistory ...
+func (p *Repository) CreateHistory(history *model.LoginHistory, schema string) (*model.LoginHistory, error) {
+	err := tenancy.Table(p.db, schema, "login_histories").Create(&history).Error
 	if err != nil {
-		logger.Errorf("Error finding login %v error %v", id, err)
+		logger.Errorf("Error creating login history %v error %v", history, err)
 		return nil, err
 	}
-	return login, err
+
+	return history, nil
 }
 
-// Update ...
-func (p *Repository) Update(login *model.Login, schema string) (*model.Login, error) {
-	err := p.db.Table(schema + ".logins").Save(&login).Error
+// CreateHistorySealed creates the history and saves the columns the function
+// seals once the history has its ID, in the same transaction
+func (p *Repository) CreateHistorySealed(history *model.LoginHistory, schema string, seal func(*model.LoginHistory) (map[string]interface{}, error)) (*model.LoginHistory, error) {
+	err := p.db.Transaction(func(tx *gorm.DB) error {
+		if err := tenancy.Table(tx, schema, "login_histories").Create(history).Error; err != nil {
+			return err
+		}
+		if seal == nil {
+			return nil
+		}
+		columns, err := seal(history)
+		if err != nil {
+			return err
+		}
+		return tenancy.Table(tx, schema, "login_histories").Where("id = ?", history.ID).UpdateColumns(columns).Error
+	})
 	if err != nil {
-		logger.Errorf("Error updating login %v error %v", login, err)
+		logger.Errorf("Error creating login history %v error %v", history, err)
 		return nil, err
 	}
 
-	return login, nil
+	return history, nil
 }
 
-// Create ...
-func (p *Repository) Create(login *model.Login, schema string) (*model.Login, error) {
-	err := p.db.Table(schema + ".logins").Create(&login).Error
+// UpdateHistory ...
+func (p *Repository) UpdateHistory(history *model.LoginHistory, schema string) (*model.LoginHistory, error) {
+	err := tenancy.Table(p.db, schema, "login_histories").Save(&history).Error
 	if err != nil {
-		logger.Errorf("Error creating login %v error %v", login, err)
+		logger.Errorf("Error updating login history %v error %v", history, err)
 		return nil, err
 	}
 
-	return login, nil
+	return history, nil
 }
 
-// Delete ...
-func (p *Repository) Delete(id uint, schema string) error {
-	err := p.db.Table(schema + ".logins").Delete(&model.Login{ID: id}).Error
-	return err
-}
+// Search ...
+func (p *Repository) Search(tokens []string, schema string) ([]model.Login, error) {
+	logins := []model.Login{}
+	db := tenancy.Table(query.Reads(p.db), schema, "logins")
+	if sqlite.Is(p.db) {
+		// Search index is a space separated text on SQLite
+		for _, token := range tokens {
+			db = db.Where("' ' || search_index || ' ' LIKE ?", "% "+token+" %")
+		}
+	} else {
+		db = db.Where("search_index @@ to_tsquery('simple', ?)", strings.Join(tokens, " & "))
+	}
+	err := db.Find(&logins).Error
+	if err != nil {
+		logger.Errorf("Error searching logins error %v", err)
+		return nil, err
+	}
 
-// Migrate ...
-func (p *Repository) Migrate(schema string) error {
-	return p.db.Table(schema + ".logins").AutoMigrate(&model.Login{})
+	return logins, err
 }
@@ -0,0 +1,261 @@
+package migration
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Migration sets, system migrations run on the public schema and vault
+// migrations on every user and organization schema. Row migrations turn the
+// vault tables of the vaults schema into the shared tables of row tenancy,
+// they are only for Postgres.
+const (
+	SetSystem = "system"
+	SetVault  = "vault"
+	SetRows   = "rows"
+)
+
+// schemaPlaceholder is replaced with the schema the vault migrations run on
+const schemaPlaceholder = "{schema}"
+
+// ErrUnknownVersion represents message for databases migrated by a newer version of the server
+var ErrUnknownVersion = errors.New("database has migrations unknown to this version of the server")
+
+// Migration files are kept by database dialect, sql/<dialect>/<set>
+//
+//go:embed sql
+var files embed.FS
+
+// fileName matches the migration files like 0001_baseline.up.sql
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type migration struct {
+	version uint
+	name    string
+	up      string
+	down    string
+}
+
+// Repository ...
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository ...
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Up ...
+func (p *Repository) Up(set, schema string) error {
+	migrations, applied, err := p.prepare(set, schema)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+		err := p.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(withSchema(m.up, schema)).Error; err != nil {
+				return err
+			}
+			return tx.Exec("INSERT INTO "+versionTable(set, schema)+" (version, name) VALUES (?, ?)", m.version, m.name).Error
+		})
+		if err != nil {
+			logger.Errorf("Error applying %v migration %v of schema %v error %v", set, m.version, schema, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// Down ...
+func (p *Repository) Down(set, schema string, steps int) error {
+	migrations, applied, err := p.prepare(set, schema)
+	if err != nil {
+		return err
+	}
+
+	for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+		m := migrations[i]
+		if !applied[m.version] {
+			continue
+		}
+		err := p.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(withSchema(m.down, schema)).Error; err != nil {
+				return err
+			}
+			return tx.Exec("DELETE FROM "+versionTable(set, schema)+" WHERE version = ?", m.version).Error
+		})
+		if err != nil {
+			logger.Errorf("Error reverting %v migration %v of schema %v error %v", set, m.version, schema, err)
+			return err
+		}
+		steps--
+	}
+	return nil
+}
+
+// Status ...
+func (p *Repository) Status(set, schema string) (*model.MigrationStatus, error) {
+	migrations, err := load(p.db.Dialector.Name(), set)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := p.applied(set, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &model.MigrationStatus{Set: set, Schema: schema}
+	known := map[uint]bool{}
+	for _, m := range migrations {
+		known[m.version] = true
+		status.Latest = m.version
+		if !applied[m.version] {
+			status.Pending = append(status.Pending, m.version)
+		}
+	}
+	for version := range applied {
+		if version > status.Version {
+			status.Version = version
+		}
+		if !known[version] {
+			status.Unknown = append(status.Unknown, version)
+		}
+	}
+	sort.Slice(status.Unknown, func(i, j int) bool { return status.Unknown[i] < status.Unknown[j] })
+	return status, nil
+}
+
+// prepare loads the migrations of the set and the versions applied to the
+// schema. Schemas having versions this server doesn't know are left untouched.
+func (p *Repository) prepare(set, schema string) ([]migration, map[uint]bool, error) {
+	migrations, err := load(p.db.Dialector.Name(), set)
+	if err != nil {
+		return nil, nil, err
+	}
+	applied, err := p.applied(set, schema)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkVersions(migrations, applied); err != nil {
+		return nil, nil, fmt.Errorf("%s migrations of schema %q: %w", set, schema, err)
+	}
+	return migrations, applied, nil
+}
+
+// applied returns the versions of the set applied to the schema, the version
+// table is created when it doesn't exist
+func (p *Repository) applied(set, schema string) (map[uint]bool, error) {
+	table := versionTable(set, schema)
+	err := p.db.Exec("CREATE TABLE IF NOT EXISTS " + table + ` (
+		version bigint PRIMARY KEY,
+		name text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`).Error
+	if err != nil {
+		logger.Errorf("Error creating migration table of schema %v error %v", schema, err)
+		return nil, err
+	}
+
+	versions := []uint{}
+	if err := p.db.Raw("SELECT version FROM " + table).Scan(&versions).Error; err != nil {
+		logger.Errorf("Error getting migrations of schema %v error %v", schema, err)
+		return nil, err
+	}
+
+	applied := map[uint]bool{}
+	for _, version := range versions {
+		applied[version] = true
+	}
+	return applied, nil
+}
+
+// load reads the embedded migrations of the dialect and set ordered by version
+func load(dialect, set string) ([]migration, error) {
+	dir := path.Join("sql", dialect, set)
+	entries, err := files.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unknown migration set %q of %s", set, dialect)
+	}
+
+	byVersion := map[uint]*migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %s", entry.Name())
+		}
+		version, _ := strconv.ParseUint(match[1], 10, 32)
+		content, err := files.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[uint(version)]
+		if !ok {
+			m = &migration{version: uint(version), name: match[2]}
+			byVersion[uint(version)] = m
+		}
+		if m.name != match[2] {
+			return nil, fmt.Errorf("migration %d has different names %s and %s", version, m.name, match[2])
+		}
+		if match[3] == "up" {
+			m.up = string(content)
+		} else {
+			m.down = string(content)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %d of %s set must have up and down files", m.version, set)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return migrations, nil
+}
+
+// checkVersions fails when the schema has a version which isn't one of the migrations
+func checkVersions(migrations []migration, applied map[uint]bool) error {
+	known := map[uint]bool{}
+	for _, m := range migrations {
+		known[m.version] = true
+	}
+	for version := range applied {
+		if !known[version] {
+			return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+		}
+	}
+	return nil
+}
+
+// versionTable returns the table of the applied versions of the set, row
+// migrations run on the vaults schema next to its vault migrations
+func versionTable(set, schema string) string {
+	if schema == "" {
+		return "schema_migrations"
+	}
+	if set == SetRows {
+		return schema + ".row_migrations"
+	}
+	return schema + ".schema_migrations"
+}
+
+func withSchema(sql, schema string) string {
+	return strings.ReplaceAll(sql, schemaPlaceholder, schema)
+}
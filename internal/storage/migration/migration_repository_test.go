@@ -0,0 +1,102 @@
+package migration
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/passwall/passwall-server/internal/storage/tenancy"
+)
+
+var dialects = []string{"postgres", "sqlite"}
+
+func TestLoad(t *testing.T) {
+	for _, dialect := range dialects {
+		for _, set := range []string{SetSystem, SetVault} {
+			migrations, err := load(dialect, set)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, migrations)
+
+			for i, m := range migrations {
+				assert.NotEmpty(t, m.up)
+				assert.NotEmpty(t, m.down)
+				if i > 0 {
+					assert.Greater(t, m.version, migrations[i-1].version)
+				}
+			}
+		}
+	}
+
+	_, err := load("postgres", "unknown")
+	assert.Error(t, err)
+	_, err = load("mysql", SetSystem)
+	assert.Error(t, err)
+}
+
+func TestDialectsHaveSameVersions(t *testing.T) {
+	// Databases are copied from SQLite to Postgres at the same version
+	for _, set := range []string{SetSystem, SetVault} {
+		postgres, err := load("postgres", set)
+		assert.NoError(t, err)
+		sqlite, err := load("sqlite", set)
+		assert.NoError(t, err)
+
+		assert.Equal(t, len(postgres), len(sqlite))
+		for i := range postgres {
+			if i < len(sqlite) {
+				assert.Equal(t, postgres[i].version, sqlite[i].version)
+				assert.Equal(t, postgres[i].name, sqlite[i].name)
+			}
+		}
+	}
+}
+
+func TestVaultMigrationsUseSchema(t *testing.T) {
+	for _, dialect := range dialects {
+		migrations, err := load(dialect, SetVault)
+		assert.NoError(t, err)
+
+		// Every vault table and index must be created in the schema of the vault
+		for _, m := range migrations {
+			for _, line := range strings.Split(m.up, "\n") {
+				switch {
+				case strings.HasPrefix(line, "CREATE TABLE"), strings.HasPrefix(line, "ALTER TABLE"):
+					assert.Contains(t, line, schemaPlaceholder+".")
+				case dialect == "sqlite" && strings.HasPrefix(line, "CREATE INDEX"):
+					assert.Contains(t, line, "EXISTS "+schemaPlaceholder+".")
+				}
+			}
+		}
+	}
+	assert.Equal(t, "CREATE TABLE user1.logins", withSchema("CREATE TABLE {schema}.logins", "user1"))
+}
+
+func TestRowMigrations(t *testing.T) {
+	migrations, err := load("postgres", SetRows)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, migrations)
+	_, err = load("sqlite", SetRows)
+	assert.Error(t, err)
+
+	// Every vault table gets the vault column
+	for _, table := range tenancy.Tables {
+		assert.Contains(t, migrations[0].up, "ALTER TABLE {schema}."+table+" ADD COLUMN "+tenancy.Column+" ")
+	}
+
+	// Row migrations are versioned apart from the vault migrations of the schema
+	assert.Equal(t, "vaults.schema_migrations", versionTable(SetVault, tenancy.Schema))
+	assert.Equal(t, "vaults.row_migrations", versionTable(SetRows, tenancy.Schema))
+	assert.Equal(t, "schema_migrations", versionTable(SetSystem, ""))
+}
+
+func TestCheckVersions(t *testing.T) {
+	migrations := []migration{{version: 1}, {version: 2}}
+
+	assert.NoError(t, checkVersions(migrations, map[uint]bool{}))
+	assert.NoError(t, checkVersions(migrations, map[uint]bool{1: true, 2: true}))
+
+	err := checkVersions(migrations, map[uint]bool{1: true, 3: true})
+	assert.True(t, errors.Is(err, ErrUnknownVersion))
+}
@@ -0,0 +1,37 @@
+DROP TABLE IF EXISTS {schema}.vault_copies;
+
+ALTER TABLE {schema}.folders DROP CONSTRAINT folders_pkey, ADD PRIMARY KEY (id);
+ALTER TABLE {schema}.folders DROP COLUMN vault;
+
+ALTER TABLE {schema}.logins DROP CONSTRAINT logins_pkey, ADD PRIMARY KEY (id);
+ALTER TABLE {schema}.logins DROP COLUMN vault;
+
+ALTER TABLE {schema}.login_histories DROP CONSTRAINT login_histories_pkey, ADD PRIMARY KEY (id);
+ALTER TABLE {schema}.login_histories DROP COLUMN vault;
+
+ALTER TABLE {schema}.credit_cards DROP CONSTRAINT credit_cards_pkey, ADD PRIMARY KEY (id);
+ALTER TABLE {schema}.credit_cards DROP COLUMN vault;
+
+ALTER TABLE {schema}.bank_accounts DROP CONSTRAINT bank_accounts_pkey, ADD PRIMARY KEY (id);
+ALTER TABLE {schema}.bank_accounts DROP COLUMN vault;
+
+ALTER TABLE {schema}.notes DROP CONSTRAINT notes_pkey, ADD PRIMARY KEY (id);
+ALTER TABLE {schema}.notes DROP COLUMN vault;
+
+ALTER TABLE {schema}.emails DROP CONSTRAINT emails_pkey, ADD PRIMARY KEY (id);
+ALTER TABLE {schema}.emails DROP COLUMN vault;
+
+ALTER TABLE {schema}.servers DROP CONSTRAINT servers_pkey, ADD PRIMARY KEY (id);
+ALTER TABLE {schema}.servers DROP COLUMN vault;
+
+ALTER TABLE {schema}.item_relations DROP CONSTRAINT item_relations_pkey, ADD PRIMARY KEY (id);
+ALTER TABLE {schema}.item_relations DROP COLUMN vault;
+
+ALTER TABLE {schema}.tombstones DROP CONSTRAINT tombstones_pkey, ADD PRIMARY KEY (id);
+ALTER TABLE {schema}.tombstones DROP COLUMN vault;
+
+ALTER TABLE {schema}.conflicts DROP CONSTRAINT conflicts_pkey, ADD PRIMARY KEY (id);
+ALTER TABLE {schema}.conflicts DROP COLUMN vault;
+
+ALTER TABLE {schema}.vault_revisions DROP CONSTRAINT vault_revisions_pkey, ADD PRIMARY KEY (id);
+ALTER TABLE {schema}.vault_revisions DROP COLUMN vault;
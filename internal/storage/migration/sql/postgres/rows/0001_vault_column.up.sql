@@ -0,0 +1,45 @@
+-- Vaults share the tables in row tenancy, the vault column has the schema name
+-- of the vault of the row and IDs are unique in the vault
+ALTER TABLE {schema}.folders ADD COLUMN vault text NOT NULL DEFAULT '';
+ALTER TABLE {schema}.folders DROP CONSTRAINT folders_pkey, ADD PRIMARY KEY (vault, id);
+
+ALTER TABLE {schema}.logins ADD COLUMN vault text NOT NULL DEFAULT '';
+ALTER TABLE {schema}.logins DROP CONSTRAINT logins_pkey, ADD PRIMARY KEY (vault, id);
+
+ALTER TABLE {schema}.login_histories ADD COLUMN vault text NOT NULL DEFAULT '';
+ALTER TABLE {schema}.login_histories DROP CONSTRAINT login_histories_pkey, ADD PRIMARY KEY (vault, id);
+
+ALTER TABLE {schema}.credit_cards ADD COLUMN vault text NOT NULL DEFAULT '';
+ALTER TABLE {schema}.credit_cards DROP CONSTRAINT credit_cards_pkey, ADD PRIMARY KEY (vault, id);
+
+ALTER TABLE {schema}.bank_accounts ADD COLUMN vault text NOT NULL DEFAULT '';
+ALTER TABLE {schema}.bank_accounts DROP CONSTRAINT bank_accounts_pkey, ADD PRIMARY KEY (vault, id);
+
+ALTER TABLE {schema}.notes ADD COLUMN vault text NOT NULL DEFAULT '';
+ALTER TABLE {schema}.notes DROP CONSTRAINT notes_pkey, ADD PRIMARY KEY (vault, id);
+
+ALTER TABLE {schema}.emails ADD COLUMN vault text NOT NULL DEFAULT '';
+ALTER TABLE {schema}.emails DROP CONSTRAINT emails_pkey, ADD PRIMARY KEY (vault, id);
+
+ALTER TABLE {schema}.servers ADD COLUMN vault text NOT NULL DEFAULT '';
+ALTER TABLE {schema}.servers DROP CONSTRAINT servers_pkey, ADD PRIMARY KEY (vault, id);
+
+ALTER TABLE {schema}.item_relations ADD COLUMN vault text NOT NULL DEFAULT '';
+ALTER TABLE {schema}.item_relations DROP CONSTRAINT item_relations_pkey, ADD PRIMARY KEY (vault, id);
+
+ALTER TABLE {schema}.tombstones ADD COLUMN vault text NOT NULL DEFAULT '';
+ALTER TABLE {schema}.tombstones DROP CONSTRAINT tombstones_pkey, ADD PRIMARY KEY (vault, id);
+
+ALTER TABLE {schema}.conflicts ADD COLUMN vault text NOT NULL DEFAULT '';
+ALTER TABLE {schema}.conflicts DROP CONSTRAINT conflicts_pkey, ADD PRIMARY KEY (vault, id);
+
+ALTER TABLE {schema}.vault_revisions ADD COLUMN vault text NOT NULL DEFAULT '';
+ALTER TABLE {schema}.vault_revisions DROP CONSTRAINT vault_revisions_pkey, ADD PRIMARY KEY (vault, id);
+
+-- Vaults copied from their schemas and the fingerprint of their rows at the time
+CREATE TABLE IF NOT EXISTS {schema}.vault_copies (
+    vault text NOT NULL,
+    fingerprint text NOT NULL,
+    copied_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (vault)
+);
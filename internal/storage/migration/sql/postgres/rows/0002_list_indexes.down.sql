@@ -0,0 +1,27 @@
+DROP INDEX IF EXISTS {schema}.idx_tombstones_vault_created_at;
+CREATE INDEX IF NOT EXISTS idx_folders_updated_at ON {schema}.folders (updated_at);
+DROP INDEX IF EXISTS {schema}.idx_folders_vault_updated_at;
+CREATE INDEX IF NOT EXISTS idx_servers_folder_id ON {schema}.servers (folder_id);
+CREATE INDEX IF NOT EXISTS idx_servers_updated_at ON {schema}.servers (updated_at);
+DROP INDEX IF EXISTS {schema}.idx_servers_vault_folder_id;
+DROP INDEX IF EXISTS {schema}.idx_servers_vault_updated_at;
+CREATE INDEX IF NOT EXISTS idx_emails_folder_id ON {schema}.emails (folder_id);
+CREATE INDEX IF NOT EXISTS idx_emails_updated_at ON {schema}.emails (updated_at);
+DROP INDEX IF EXISTS {schema}.idx_emails_vault_folder_id;
+DROP INDEX IF EXISTS {schema}.idx_emails_vault_updated_at;
+CREATE INDEX IF NOT EXISTS idx_notes_folder_id ON {schema}.notes (folder_id);
+CREATE INDEX IF NOT EXISTS idx_notes_updated_at ON {schema}.notes (updated_at);
+DROP INDEX IF EXISTS {schema}.idx_notes_vault_folder_id;
+DROP INDEX IF EXISTS {schema}.idx_notes_vault_updated_at;
+CREATE INDEX IF NOT EXISTS idx_bank_accounts_folder_id ON {schema}.bank_accounts (folder_id);
+CREATE INDEX IF NOT EXISTS idx_bank_accounts_updated_at ON {schema}.bank_accounts (updated_at);
+DROP INDEX IF EXISTS {schema}.idx_bank_accounts_vault_folder_id;
+DROP INDEX IF EXISTS {schema}.idx_bank_accounts_vault_updated_at;
+CREATE INDEX IF NOT EXISTS idx_credit_cards_folder_id ON {schema}.credit_cards (folder_id);
+CREATE INDEX IF NOT EXISTS idx_credit_cards_updated_at ON {schema}.credit_cards (updated_at);
+DROP INDEX IF EXISTS {schema}.idx_credit_cards_vault_folder_id;
+DROP INDEX IF EXISTS {schema}.idx_credit_cards_vault_updated_at;
+CREATE INDEX IF NOT EXISTS idx_logins_folder_id ON {schema}.logins (folder_id);
+CREATE INDEX IF NOT EXISTS idx_logins_updated_at ON {schema}.logins (updated_at);
+DROP INDEX IF EXISTS {schema}.idx_logins_vault_folder_id;
+DROP INDEX IF EXISTS {schema}.idx_logins_vault_updated_at;
@@ -0,0 +1,30 @@
+-- The shared tables are listed by vault, the change time and folder indexes
+-- of the vault migrations are replaced by ones starting with the vault.
+
+CREATE INDEX IF NOT EXISTS idx_logins_vault_updated_at ON {schema}.logins (vault, updated_at);
+CREATE INDEX IF NOT EXISTS idx_logins_vault_folder_id ON {schema}.logins (vault, folder_id);
+DROP INDEX IF EXISTS {schema}.idx_logins_updated_at;
+DROP INDEX IF EXISTS {schema}.idx_logins_folder_id;
+CREATE INDEX IF NOT EXISTS idx_credit_cards_vault_updated_at ON {schema}.credit_cards (vault, updated_at);
+CREATE INDEX IF NOT EXISTS idx_credit_cards_vault_folder_id ON {schema}.credit_cards (vault, folder_id);
+DROP INDEX IF EXISTS {schema}.idx_credit_cards_updated_at;
+DROP INDEX IF EXISTS {schema}.idx_credit_cards_folder_id;
+CREATE INDEX IF NOT EXISTS idx_bank_accounts_vault_updated_at ON {schema}.bank_accounts (vault, updated_at);
+CREATE INDEX IF NOT EXISTS idx_bank_accounts_vault_folder_id ON {schema}.bank_accounts (vault, folder_id);
+DROP INDEX IF EXISTS {schema}.idx_bank_accounts_updated_at;
+DROP INDEX IF EXISTS {schema}.idx_bank_accounts_folder_id;
+CREATE INDEX IF NOT EXISTS idx_notes_vault_updated_at ON {schema}.notes (vault, updated_at);
+CREATE INDEX IF NOT EXISTS idx_notes_vault_folder_id ON {schema}.notes (vault, folder_id);
+DROP INDEX IF EXISTS {schema}.idx_notes_updated_at;
+DROP INDEX IF EXISTS {schema}.idx_notes_folder_id;
+CREATE INDEX IF NOT EXISTS idx_emails_vault_updated_at ON {schema}.emails (vault, updated_at);
+CREATE INDEX IF NOT EXISTS idx_emails_vault_folder_id ON {schema}.emails (vault, folder_id);
+DROP INDEX IF EXISTS {schema}.idx_emails_updated_at;
+DROP INDEX IF EXISTS {schema}.idx_emails_folder_id;
+CREATE INDEX IF NOT EXISTS idx_servers_vault_updated_at ON {schema}.servers (vault, updated_at);
+CREATE INDEX IF NOT EXISTS idx_servers_vault_folder_id ON {schema}.servers (vault, folder_id);
+DROP INDEX IF EXISTS {schema}.idx_servers_updated_at;
+DROP INDEX IF EXISTS {schema}.idx_servers_folder_id;
+CREATE INDEX IF NOT EXISTS idx_folders_vault_updated_at ON {schema}.folders (vault, updated_at);
+DROP INDEX IF EXISTS {schema}.idx_folders_updated_at;
+CREATE INDEX IF NOT EXISTS idx_tombstones_vault_created_at ON {schema}.tombstones (vault, created_at);
@@ -0,0 +1,204 @@
+-- Baseline of the system tables. Databases created by the AutoMigrate of
+-- older versions already have the tables, missing columns are added to them.
+
+CREATE TABLE IF NOT EXISTS tokens (
+    id bigserial,
+    user_id bigint,
+    uuid varchar(100),
+    token text,
+    expiry_time timestamptz,
+    PRIMARY KEY (id)
+);
+ALTER TABLE tokens
+    ADD COLUMN IF NOT EXISTS user_id bigint,
+    ADD COLUMN IF NOT EXISTS uuid varchar(100),
+    ADD COLUMN IF NOT EXISTS token text,
+    ADD COLUMN IF NOT EXISTS expiry_time timestamptz;
+
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial,
+    uuid varchar(100),
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    name text,
+    email text,
+    master_password text,
+    secret text,
+    schema text,
+    role text,
+    confirmation_code text,
+    email_verified_at timestamptz,
+    is_migrated boolean,
+    deletion_scheduled_at timestamptz,
+    deletion_reminder_sent_at timestamptz,
+    breach_scan boolean,
+    reauthenticated_at timestamptz,
+    PRIMARY KEY (id)
+);
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS uuid varchar(100),
+    ADD COLUMN IF NOT EXISTS created_at timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at timestamptz,
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz,
+    ADD COLUMN IF NOT EXISTS name text,
+    ADD COLUMN IF NOT EXISTS email text,
+    ADD COLUMN IF NOT EXISTS master_password text,
+    ADD COLUMN IF NOT EXISTS secret text,
+    ADD COLUMN IF NOT EXISTS schema text,
+    ADD COLUMN IF NOT EXISTS role text,
+    ADD COLUMN IF NOT EXISTS confirmation_code text,
+    ADD COLUMN IF NOT EXISTS email_verified_at timestamptz,
+    ADD COLUMN IF NOT EXISTS is_migrated boolean,
+    ADD COLUMN IF NOT EXISTS deletion_scheduled_at timestamptz,
+    ADD COLUMN IF NOT EXISTS deletion_reminder_sent_at timestamptz,
+    ADD COLUMN IF NOT EXISTS breach_scan boolean,
+    ADD COLUMN IF NOT EXISTS reauthenticated_at timestamptz;
+
+CREATE TABLE IF NOT EXISTS organizations (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    name text,
+    owner_id bigint,
+    schema text,
+    region text,
+    PRIMARY KEY (id)
+);
+ALTER TABLE organizations
+    ADD COLUMN IF NOT EXISTS created_at timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at timestamptz,
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz,
+    ADD COLUMN IF NOT EXISTS name text,
+    ADD COLUMN IF NOT EXISTS owner_id bigint,
+    ADD COLUMN IF NOT EXISTS schema text,
+    ADD COLUMN IF NOT EXISTS region text;
+
+CREATE TABLE IF NOT EXISTS organization_members (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    organization_id bigint,
+    user_id bigint,
+    email text,
+    role text,
+    status text,
+    PRIMARY KEY (id)
+);
+ALTER TABLE organization_members
+    ADD COLUMN IF NOT EXISTS created_at timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at timestamptz,
+    ADD COLUMN IF NOT EXISTS organization_id bigint,
+    ADD COLUMN IF NOT EXISTS user_id bigint,
+    ADD COLUMN IF NOT EXISTS email text,
+    ADD COLUMN IF NOT EXISTS role text,
+    ADD COLUMN IF NOT EXISTS status text;
+CREATE INDEX IF NOT EXISTS idx_organization_members_user_id ON organization_members (user_id);
+CREATE INDEX IF NOT EXISTS idx_organization_members_organization_id ON organization_members (organization_id);
+
+CREATE TABLE IF NOT EXISTS collections (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    organization_id bigint,
+    name text,
+    max_password_age bigint,
+    PRIMARY KEY (id)
+);
+ALTER TABLE collections
+    ADD COLUMN IF NOT EXISTS created_at timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at timestamptz,
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz,
+    ADD COLUMN IF NOT EXISTS organization_id bigint,
+    ADD COLUMN IF NOT EXISTS name text,
+    ADD COLUMN IF NOT EXISTS max_password_age bigint;
+CREATE INDEX IF NOT EXISTS idx_collections_organization_id ON collections (organization_id);
+
+CREATE TABLE IF NOT EXISTS collection_items (
+    id bigserial,
+    created_at timestamptz,
+    collection_id bigint,
+    item_type text,
+    item_id bigint,
+    PRIMARY KEY (id)
+);
+ALTER TABLE collection_items
+    ADD COLUMN IF NOT EXISTS created_at timestamptz,
+    ADD COLUMN IF NOT EXISTS collection_id bigint,
+    ADD COLUMN IF NOT EXISTS item_type text,
+    ADD COLUMN IF NOT EXISTS item_id bigint;
+CREATE INDEX IF NOT EXISTS idx_collection_items_collection_id ON collection_items (collection_id);
+
+CREATE TABLE IF NOT EXISTS email_breaches (
+    id bigserial,
+    created_at timestamptz,
+    user_id bigint,
+    email text,
+    name text,
+    title text,
+    domain text,
+    breach_date text,
+    added_date timestamptz,
+    data_classes text,
+    PRIMARY KEY (id)
+);
+ALTER TABLE email_breaches
+    ADD COLUMN IF NOT EXISTS created_at timestamptz,
+    ADD COLUMN IF NOT EXISTS user_id bigint,
+    ADD COLUMN IF NOT EXISTS email text,
+    ADD COLUMN IF NOT EXISTS name text,
+    ADD COLUMN IF NOT EXISTS title text,
+    ADD COLUMN IF NOT EXISTS domain text,
+    ADD COLUMN IF NOT EXISTS breach_date text,
+    ADD COLUMN IF NOT EXISTS added_date timestamptz,
+    ADD COLUMN IF NOT EXISTS data_classes text;
+CREATE INDEX IF NOT EXISTS idx_email_breaches_user_id ON email_breaches (user_id);
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    id bigserial,
+    created_at timestamptz,
+    user_id bigint,
+    action text,
+    item_type text,
+    ip text,
+    user_agent text,
+    PRIMARY KEY (id)
+);
+ALTER TABLE audit_events
+    ADD COLUMN IF NOT EXISTS created_at timestamptz,
+    ADD COLUMN IF NOT EXISTS user_id bigint,
+    ADD COLUMN IF NOT EXISTS action text,
+    ADD COLUMN IF NOT EXISTS item_type text,
+    ADD COLUMN IF NOT EXISTS ip text,
+    ADD COLUMN IF NOT EXISTS user_agent text;
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events (action);
+CREATE INDEX IF NOT EXISTS idx_audit_events_user_id ON audit_events (user_id);
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    user_id bigint,
+    type text,
+    status text,
+    total bigint,
+    processed bigint,
+    result text,
+    error text,
+    finished_at timestamptz,
+    PRIMARY KEY (id)
+);
+ALTER TABLE jobs
+    ADD COLUMN IF NOT EXISTS created_at timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at timestamptz,
+    ADD COLUMN IF NOT EXISTS user_id bigint,
+    ADD COLUMN IF NOT EXISTS type text,
+    ADD COLUMN IF NOT EXISTS status text,
+    ADD COLUMN IF NOT EXISTS total bigint,
+    ADD COLUMN IF NOT EXISTS processed bigint,
+    ADD COLUMN IF NOT EXISTS result text,
+    ADD COLUMN IF NOT EXISTS error text,
+    ADD COLUMN IF NOT EXISTS finished_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_jobs_user_id ON jobs (user_id);
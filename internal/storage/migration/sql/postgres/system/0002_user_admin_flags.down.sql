@@ -0,0 +1,3 @@
+ALTER TABLE users
+    DROP COLUMN IF EXISTS master_password_reset,
+    DROP COLUMN IF EXISTS disabled_at;
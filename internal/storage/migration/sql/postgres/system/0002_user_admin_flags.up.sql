@@ -0,0 +1,5 @@
+-- Operators can disable users and ask them to change the master password.
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS disabled_at timestamptz,
+    ADD COLUMN IF NOT EXISTS master_password_reset boolean NOT NULL DEFAULT false;
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS audit_events_immutable ON audit_events;
+DROP FUNCTION IF EXISTS audit_events_immutable();
+ALTER TABLE audit_events DROP COLUMN IF EXISTS item_id;
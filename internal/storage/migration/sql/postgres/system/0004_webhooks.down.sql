@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS subscription_type;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
@@ -0,0 +1,37 @@
+-- Webhooks receive the events of the users, their deliveries are kept for the
+-- delivery log and for retrying the failed ones.
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    user_id bigint,
+    admin boolean NOT NULL DEFAULT false,
+    url text,
+    secret text,
+    events text,
+    active boolean NOT NULL DEFAULT true,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_webhooks_user_id ON webhooks (user_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    webhook_id bigint,
+    event text,
+    payload text,
+    status text,
+    attempts bigint NOT NULL DEFAULT 0,
+    response_code bigint NOT NULL DEFAULT 0,
+    error text,
+    next_attempt_at timestamptz,
+    delivered_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries (status);
+
+-- The subscription type seen at the last signin tells when it changes.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS subscription_type text;
@@ -0,0 +1,3 @@
+ALTER TABLE audit_events
+    DROP COLUMN IF EXISTS city,
+    DROP COLUMN IF EXISTS country;
@@ -0,0 +1,5 @@
+-- Audit events have the country and the city of their IP when GeoIP is configured.
+
+ALTER TABLE audit_events
+    ADD COLUMN IF NOT EXISTS country text,
+    ADD COLUMN IF NOT EXISTS city text;
@@ -0,0 +1,5 @@
+-- Users register a phone number to receive the verification codes by SMS.
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS phone text,
+    ADD COLUMN IF NOT EXISTS phone_verified_at timestamptz;
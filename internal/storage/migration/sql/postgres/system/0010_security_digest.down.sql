@@ -0,0 +1,3 @@
+ALTER TABLE users
+    DROP COLUMN IF EXISTS digest_sent_at,
+    DROP COLUMN IF EXISTS security_digest;
@@ -0,0 +1,5 @@
+-- Users opt in to a weekly email summarizing the security of their account.
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS security_digest boolean NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS digest_sent_at timestamptz;
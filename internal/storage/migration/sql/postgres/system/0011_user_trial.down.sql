@@ -0,0 +1,4 @@
+ALTER TABLE users
+    DROP COLUMN IF EXISTS trial_reminder_sent_at,
+    DROP COLUMN IF EXISTS trial_ends_at,
+    DROP COLUMN IF EXISTS trial_started_at;
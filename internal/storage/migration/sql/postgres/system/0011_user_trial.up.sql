@@ -0,0 +1,6 @@
+-- New users can get the pro features for a trial period.
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS trial_started_at timestamptz,
+    ADD COLUMN IF NOT EXISTS trial_ends_at timestamptz,
+    ADD COLUMN IF NOT EXISTS trial_reminder_sent_at timestamptz;
@@ -0,0 +1,3 @@
+-- Lapsed subscriptions keep the pro features in a grace period.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS subscription_lapsed_at timestamptz;
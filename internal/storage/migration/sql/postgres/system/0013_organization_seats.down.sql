@@ -0,0 +1,3 @@
+ALTER TABLE organizations DROP COLUMN IF EXISTS subscription_item;
+ALTER TABLE organizations DROP COLUMN IF EXISTS subscription_id;
+ALTER TABLE organizations DROP COLUMN IF EXISTS seats;
@@ -0,0 +1,5 @@
+-- Team subscriptions limit the members of organizations to their seats.
+
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS seats bigint NOT NULL DEFAULT 0;
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS subscription_id text;
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS subscription_item text;
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS subscription_granted_until;
+ALTER TABLE users DROP COLUMN IF EXISTS subscription_granted_at;
@@ -0,0 +1,4 @@
+-- Admins grant the pro features to users besides their subscriptions.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS subscription_granted_at timestamptz;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS subscription_granted_until timestamptz;
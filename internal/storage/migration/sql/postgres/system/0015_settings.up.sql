@@ -0,0 +1,9 @@
+-- Settings are the instance settings admins change at runtime, like the
+-- security policies.
+
+CREATE TABLE IF NOT EXISTS settings (
+    key text,
+    value text,
+    updated_at timestamptz,
+    PRIMARY KEY (key)
+);
@@ -0,0 +1,14 @@
+-- Announcements are the notices admins publish to every user, like planned
+-- maintenances or policy changes.
+
+CREATE TABLE IF NOT EXISTS announcements (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    message text,
+    severity text,
+    starts_at timestamptz,
+    ends_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_announcements_starts_at ON announcements (starts_at);
@@ -0,0 +1,3 @@
+-- Audit events of admins impersonating a user for support have the admin.
+
+ALTER TABLE audit_events ADD COLUMN IF NOT EXISTS impersonator_id bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+ALTER TABLE users DROP COLUMN IF EXISTS version;
+ALTER TABLE organizations DROP COLUMN IF EXISTS version;
+ALTER TABLE collections DROP COLUMN IF EXISTS version;
+ALTER TABLE webhooks DROP COLUMN IF EXISTS version;
+ALTER TABLE announcements DROP COLUMN IF EXISTS version;
@@ -0,0 +1,7 @@
+-- Records edited through the API are versioned, updates of an outdated version are rejected.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE collections ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE announcements ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
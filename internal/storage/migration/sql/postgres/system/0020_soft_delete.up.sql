@@ -0,0 +1,11 @@
+-- Deleted records are kept for the admins to restore them until the purge job
+-- removes them after their retention.
+
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+ALTER TABLE announcements ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_organizations_deleted_at ON organizations (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_collections_deleted_at ON collections (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_webhooks_deleted_at ON webhooks (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_announcements_deleted_at ON announcements (deleted_at);
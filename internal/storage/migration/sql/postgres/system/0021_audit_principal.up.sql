@@ -0,0 +1,3 @@
+-- Audit events have the principal which did the action, like user:1 or import:3.
+
+ALTER TABLE audit_events ADD COLUMN IF NOT EXISTS principal varchar(100) NOT NULL DEFAULT '';
@@ -0,0 +1,7 @@
+-- The audit events and the alerts of a user are listed newest first, the
+-- user_id indexes are replaced by ones with the time.
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_user_id_created_at ON audit_events (user_id, created_at);
+DROP INDEX IF EXISTS idx_audit_events_user_id;
+CREATE INDEX IF NOT EXISTS idx_security_alerts_user_id_created_at ON security_alerts (user_id, created_at);
+DROP INDEX IF EXISTS idx_security_alerts_user_id;
@@ -0,0 +1,2 @@
+ALTER TABLE data_keys
+    DROP COLUMN IF EXISTS reencrypted_at;
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    DROP COLUMN IF EXISTS kdf_cost,
+    DROP COLUMN IF EXISTS kdf_algorithm;
@@ -0,0 +1,16 @@
+-- Fields may still be encrypted with the older keys of a vault, so they are
+-- kept and the migration waits for the key rotation to delete them.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM data_keys k
+        WHERE EXISTS (SELECT 1 FROM data_keys n WHERE n.schema = k.schema AND n.version > k.version)) THEN
+        RAISE EXCEPTION 'vaults have older data keys, finish the key rotation first';
+    END IF;
+END;
+$$;
+
+DROP INDEX IF EXISTS idx_data_keys_schema_version;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_data_keys_schema ON data_keys (schema);
+
+ALTER TABLE data_keys
+    DROP COLUMN IF EXISTS version;
@@ -0,0 +1,4 @@
+-- Tokens carry the epoch of their user, revoking all the sessions increases
+-- it so the tokens issued before are rejected.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS token_epoch integer NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_billing_email;
+
+ALTER TABLE users DROP COLUMN IF EXISTS billing_email;
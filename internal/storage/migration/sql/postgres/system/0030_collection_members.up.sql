@@ -0,0 +1,13 @@
+-- Members of an organization read only the items of the collections they are
+-- given, owners, admins and editors read the whole organization vault.
+
+CREATE TABLE IF NOT EXISTS collection_members (
+    id bigserial,
+    created_at timestamptz,
+    collection_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_collection_members_collection_user ON collection_members (collection_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_collection_members_user_id ON collection_members (user_id);
@@ -0,0 +1,275 @@
+-- Baseline of the vault tables of a user or organization schema. Databases created by the AutoMigrate of
+-- older versions already have the tables, missing columns are added to them.
+
+CREATE TABLE IF NOT EXISTS {schema}.logins (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    title text,
+    url text,
+    username text,
+    password text,
+    totp_secret text,
+    extra text,
+    password_changed_at timestamptz,
+    breached boolean,
+    breach_checked_at timestamptz,
+    max_password_age bigint,
+    expired boolean,
+    search_index tsvector,
+    folder_id bigint,
+    revision bigint NOT NULL DEFAULT 1,
+    PRIMARY KEY (id)
+);
+ALTER TABLE {schema}.logins
+    ADD COLUMN IF NOT EXISTS created_at timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at timestamptz,
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz,
+    ADD COLUMN IF NOT EXISTS title text,
+    ADD COLUMN IF NOT EXISTS url text,
+    ADD COLUMN IF NOT EXISTS username text,
+    ADD COLUMN IF NOT EXISTS password text,
+    ADD COLUMN IF NOT EXISTS totp_secret text,
+    ADD COLUMN IF NOT EXISTS extra text,
+    ADD COLUMN IF NOT EXISTS password_changed_at timestamptz,
+    ADD COLUMN IF NOT EXISTS breached boolean,
+    ADD COLUMN IF NOT EXISTS breach_checked_at timestamptz,
+    ADD COLUMN IF NOT EXISTS max_password_age bigint,
+    ADD COLUMN IF NOT EXISTS expired boolean,
+    ADD COLUMN IF NOT EXISTS search_index tsvector,
+    ADD COLUMN IF NOT EXISTS folder_id bigint,
+    ADD COLUMN IF NOT EXISTS revision bigint NOT NULL DEFAULT 1;
+CREATE INDEX IF NOT EXISTS logins_search_index_idx ON {schema}.logins USING GIN (search_index);
+
+CREATE TABLE IF NOT EXISTS {schema}.login_histories (
+    id bigserial,
+    created_at timestamptz,
+    login_id bigint,
+    title text,
+    url text,
+    username text,
+    password text,
+    totp_secret text,
+    extra text,
+    reason text,
+    PRIMARY KEY (id)
+);
+ALTER TABLE {schema}.login_histories
+    ADD COLUMN IF NOT EXISTS created_at timestamptz,
+    ADD COLUMN IF NOT EXISTS login_id bigint,
+    ADD COLUMN IF NOT EXISTS title text,
+    ADD COLUMN IF NOT EXISTS url text,
+    ADD COLUMN IF NOT EXISTS username text,
+    ADD COLUMN IF NOT EXISTS password text,
+    ADD COLUMN IF NOT EXISTS totp_secret text,
+    ADD COLUMN IF NOT EXISTS extra text,
+    ADD COLUMN IF NOT EXISTS reason text;
+CREATE INDEX IF NOT EXISTS idx_login_histories_login_id ON {schema}.login_histories (login_id);
+
+CREATE TABLE IF NOT EXISTS {schema}.credit_cards (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    card_name text,
+    cardholder_name text,
+    type text,
+    number text,
+    verification_number text,
+    expiry_date text,
+    folder_id bigint,
+    revision bigint NOT NULL DEFAULT 1,
+    PRIMARY KEY (id)
+);
+ALTER TABLE {schema}.credit_cards
+    ADD COLUMN IF NOT EXISTS created_at timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at timestamptz,
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz,
+    ADD COLUMN IF NOT EXISTS card_name text,
+    ADD COLUMN IF NOT EXISTS cardholder_name text,
+    ADD COLUMN IF NOT EXISTS type text,
+    ADD COLUMN IF NOT EXISTS number text,
+    ADD COLUMN IF NOT EXISTS verification_number text,
+    ADD COLUMN IF NOT EXISTS expiry_date text,
+    ADD COLUMN IF NOT EXISTS folder_id bigint,
+    ADD COLUMN IF NOT EXISTS revision bigint NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS {schema}.bank_accounts (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    bank_name text,
+    bank_code text,
+    account_name text,
+    account_number text,
+    iban text,
+    currency text,
+    password text,
+    folder_id bigint,
+    revision bigint NOT NULL DEFAULT 1,
+    PRIMARY KEY (id)
+);
+ALTER TABLE {schema}.bank_accounts
+    ADD COLUMN IF NOT EXISTS created_at timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at timestamptz,
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz,
+    ADD COLUMN IF NOT EXISTS bank_name text,
+    ADD COLUMN IF NOT EXISTS bank_code text,
+    ADD COLUMN IF NOT EXISTS account_name text,
+    ADD COLUMN IF NOT EXISTS account_number text,
+    ADD COLUMN IF NOT EXISTS iban text,
+    ADD COLUMN IF NOT EXISTS currency text,
+    ADD COLUMN IF NOT EXISTS password text,
+    ADD COLUMN IF NOT EXISTS folder_id bigint,
+    ADD COLUMN IF NOT EXISTS revision bigint NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS {schema}.notes (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    title text,
+    note text,
+    folder_id bigint,
+    revision bigint NOT NULL DEFAULT 1,
+    PRIMARY KEY (id)
+);
+ALTER TABLE {schema}.notes
+    ADD COLUMN IF NOT EXISTS created_at timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at timestamptz,
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz,
+    ADD COLUMN IF NOT EXISTS title text,
+    ADD COLUMN IF NOT EXISTS note text,
+    ADD COLUMN IF NOT EXISTS folder_id bigint,
+    ADD COLUMN IF NOT EXISTS revision bigint NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS {schema}.emails (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    title text,
+    email text,
+    password text,
+    folder_id bigint,
+    revision bigint NOT NULL DEFAULT 1,
+    PRIMARY KEY (id)
+);
+ALTER TABLE {schema}.emails
+    ADD COLUMN IF NOT EXISTS created_at timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at timestamptz,
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz,
+    ADD COLUMN IF NOT EXISTS title text,
+    ADD COLUMN IF NOT EXISTS email text,
+    ADD COLUMN IF NOT EXISTS password text,
+    ADD COLUMN IF NOT EXISTS folder_id bigint,
+    ADD COLUMN IF NOT EXISTS revision bigint NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS {schema}.servers (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    title text,
+    ip text,
+    username text,
+    password text,
+    url text,
+    hosting_username text,
+    hosting_password text,
+    admin_username text,
+    admin_password text,
+    extra text,
+    folder_id bigint,
+    revision bigint NOT NULL DEFAULT 1,
+    PRIMARY KEY (id)
+);
+ALTER TABLE {schema}.servers
+    ADD COLUMN IF NOT EXISTS created_at timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at timestamptz,
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz,
+    ADD COLUMN IF NOT EXISTS title text,
+    ADD COLUMN IF NOT EXISTS ip text,
+    ADD COLUMN IF NOT EXISTS username text,
+    ADD COLUMN IF NOT EXISTS password text,
+    ADD COLUMN IF NOT EXISTS url text,
+    ADD COLUMN IF NOT EXISTS hosting_username text,
+    ADD COLUMN IF NOT EXISTS hosting_password text,
+    ADD COLUMN IF NOT EXISTS admin_username text,
+    ADD COLUMN IF NOT EXISTS admin_password text,
+    ADD COLUMN IF NOT EXISTS extra text,
+    ADD COLUMN IF NOT EXISTS folder_id bigint,
+    ADD COLUMN IF NOT EXISTS revision bigint NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS {schema}.item_relations (
+    id bigserial,
+    created_at timestamptz,
+    source_type text,
+    source_id bigint,
+    target_type text,
+    target_id bigint,
+    PRIMARY KEY (id)
+);
+ALTER TABLE {schema}.item_relations
+    ADD COLUMN IF NOT EXISTS created_at timestamptz,
+    ADD COLUMN IF NOT EXISTS source_type text,
+    ADD COLUMN IF NOT EXISTS source_id bigint,
+    ADD COLUMN IF NOT EXISTS target_type text,
+    ADD COLUMN IF NOT EXISTS target_id bigint;
+CREATE INDEX IF NOT EXISTS idx_relation_target ON {schema}.item_relations (target_type,target_id);
+CREATE INDEX IF NOT EXISTS idx_relation_source ON {schema}.item_relations (source_type,source_id);
+
+CREATE TABLE IF NOT EXISTS {schema}.folders (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    name text,
+    parent_id bigint,
+    PRIMARY KEY (id)
+);
+ALTER TABLE {schema}.folders
+    ADD COLUMN IF NOT EXISTS created_at timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at timestamptz,
+    ADD COLUMN IF NOT EXISTS name text,
+    ADD COLUMN IF NOT EXISTS parent_id bigint;
+CREATE INDEX IF NOT EXISTS idx_folders_parent_id ON {schema}.folders (parent_id);
+
+CREATE TABLE IF NOT EXISTS {schema}.tombstones (
+    id bigserial,
+    created_at timestamptz,
+    item_type text,
+    item_id bigint,
+    PRIMARY KEY (id)
+);
+ALTER TABLE {schema}.tombstones
+    ADD COLUMN IF NOT EXISTS created_at timestamptz,
+    ADD COLUMN IF NOT EXISTS item_type text,
+    ADD COLUMN IF NOT EXISTS item_id bigint;
+CREATE INDEX IF NOT EXISTS idx_tombstones_created_at ON {schema}.tombstones (created_at);
+
+CREATE TABLE IF NOT EXISTS {schema}.conflicts (
+    id bigserial,
+    created_at timestamptz,
+    item_type text NOT NULL,
+    item_id bigint NOT NULL,
+    copy_id bigint NOT NULL,
+    PRIMARY KEY (id)
+);
+ALTER TABLE {schema}.conflicts
+    ADD COLUMN IF NOT EXISTS created_at timestamptz,
+    ADD COLUMN IF NOT EXISTS item_type text NOT NULL,
+    ADD COLUMN IF NOT EXISTS item_id bigint NOT NULL,
+    ADD COLUMN IF NOT EXISTS copy_id bigint NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_conflict_item ON {schema}.conflicts (item_type,item_id);
+
+CREATE TABLE IF NOT EXISTS {schema}.vault_revisions (
+    id bigserial,
+    updated_at timestamptz,
+    revision bigint NOT NULL DEFAULT 0,
+    PRIMARY KEY (id)
+);
+ALTER TABLE {schema}.vault_revisions
+    ADD COLUMN IF NOT EXISTS updated_at timestamptz,
+    ADD COLUMN IF NOT EXISTS revision bigint NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE {schema}.folders DROP COLUMN IF EXISTS deleted_at;
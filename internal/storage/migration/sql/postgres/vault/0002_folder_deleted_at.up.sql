@@ -0,0 +1,3 @@
+-- Deleted folders are kept for the admins to restore them like the items.
+
+ALTER TABLE {schema}.folders ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
@@ -0,0 +1,14 @@
+ALTER TABLE {schema}.folders DROP COLUMN IF EXISTS created_by;
+ALTER TABLE {schema}.folders DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE {schema}.logins DROP COLUMN IF EXISTS created_by;
+ALTER TABLE {schema}.logins DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE {schema}.credit_cards DROP COLUMN IF EXISTS created_by;
+ALTER TABLE {schema}.credit_cards DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE {schema}.bank_accounts DROP COLUMN IF EXISTS created_by;
+ALTER TABLE {schema}.bank_accounts DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE {schema}.notes DROP COLUMN IF EXISTS created_by;
+ALTER TABLE {schema}.notes DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE {schema}.emails DROP COLUMN IF EXISTS created_by;
+ALTER TABLE {schema}.emails DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE {schema}.servers DROP COLUMN IF EXISTS created_by;
+ALTER TABLE {schema}.servers DROP COLUMN IF EXISTS updated_by;
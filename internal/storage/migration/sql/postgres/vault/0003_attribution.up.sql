@@ -0,0 +1,16 @@
+-- Vault rows have the principal which created and the one which last changed them.
+
+ALTER TABLE {schema}.folders ADD COLUMN IF NOT EXISTS created_by varchar(100) NOT NULL DEFAULT '';
+ALTER TABLE {schema}.folders ADD COLUMN IF NOT EXISTS updated_by varchar(100) NOT NULL DEFAULT '';
+ALTER TABLE {schema}.logins ADD COLUMN IF NOT EXISTS created_by varchar(100) NOT NULL DEFAULT '';
+ALTER TABLE {schema}.logins ADD COLUMN IF NOT EXISTS updated_by varchar(100) NOT NULL DEFAULT '';
+ALTER TABLE {schema}.credit_cards ADD COLUMN IF NOT EXISTS created_by varchar(100) NOT NULL DEFAULT '';
+ALTER TABLE {schema}.credit_cards ADD COLUMN IF NOT EXISTS updated_by varchar(100) NOT NULL DEFAULT '';
+ALTER TABLE {schema}.bank_accounts ADD COLUMN IF NOT EXISTS created_by varchar(100) NOT NULL DEFAULT '';
+ALTER TABLE {schema}.bank_accounts ADD COLUMN IF NOT EXISTS updated_by varchar(100) NOT NULL DEFAULT '';
+ALTER TABLE {schema}.notes ADD COLUMN IF NOT EXISTS created_by varchar(100) NOT NULL DEFAULT '';
+ALTER TABLE {schema}.notes ADD COLUMN IF NOT EXISTS updated_by varchar(100) NOT NULL DEFAULT '';
+ALTER TABLE {schema}.emails ADD COLUMN IF NOT EXISTS created_by varchar(100) NOT NULL DEFAULT '';
+ALTER TABLE {schema}.emails ADD COLUMN IF NOT EXISTS updated_by varchar(100) NOT NULL DEFAULT '';
+ALTER TABLE {schema}.servers ADD COLUMN IF NOT EXISTS created_by varchar(100) NOT NULL DEFAULT '';
+ALTER TABLE {schema}.servers ADD COLUMN IF NOT EXISTS updated_by varchar(100) NOT NULL DEFAULT '';
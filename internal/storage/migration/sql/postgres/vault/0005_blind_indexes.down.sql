@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS {schema}.idx_logins_domain_index;
+ALTER TABLE {schema}.logins
+    DROP COLUMN IF EXISTS username_index,
+    DROP COLUMN IF EXISTS domain_index;
@@ -0,0 +1,7 @@
+-- Logins keep the blind indexes of their domain and username, so duplicates
+-- are found without decrypting the usernames.
+
+ALTER TABLE {schema}.logins
+    ADD COLUMN IF NOT EXISTS domain_index varchar(64),
+    ADD COLUMN IF NOT EXISTS username_index varchar(64);
+CREATE INDEX IF NOT EXISTS idx_logins_domain_index ON {schema}.logins (domain_index, username_index);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS jobs;
+DROP TABLE IF EXISTS audit_events;
+DROP TABLE IF EXISTS email_breaches;
+DROP TABLE IF EXISTS collection_items;
+DROP TABLE IF EXISTS collections;
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS tokens;
@@ -0,0 +1,115 @@
+-- Baseline of the system tables of SQLite databases.
+
+CREATE TABLE IF NOT EXISTS tokens (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    user_id bigint,
+    uuid varchar(100),
+    token text,
+    expiry_time datetime
+);
+
+CREATE TABLE IF NOT EXISTS users (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    uuid varchar(100),
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    name text,
+    email text,
+    master_password text,
+    secret text,
+    schema text,
+    role text,
+    confirmation_code text,
+    email_verified_at datetime,
+    is_migrated numeric,
+    deletion_scheduled_at datetime,
+    deletion_reminder_sent_at datetime,
+    breach_scan numeric,
+    reauthenticated_at datetime
+);
+
+CREATE TABLE IF NOT EXISTS organizations (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    name text,
+    owner_id bigint,
+    schema text,
+    region text
+);
+
+CREATE TABLE IF NOT EXISTS organization_members (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    organization_id bigint,
+    user_id bigint,
+    email text,
+    role text,
+    status text
+);
+CREATE INDEX IF NOT EXISTS idx_organization_members_user_id ON organization_members (user_id);
+CREATE INDEX IF NOT EXISTS idx_organization_members_organization_id ON organization_members (organization_id);
+
+CREATE TABLE IF NOT EXISTS collections (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    organization_id bigint,
+    name text,
+    max_password_age bigint
+);
+CREATE INDEX IF NOT EXISTS idx_collections_organization_id ON collections (organization_id);
+
+CREATE TABLE IF NOT EXISTS collection_items (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    collection_id bigint,
+    item_type text,
+    item_id bigint
+);
+CREATE INDEX IF NOT EXISTS idx_collection_items_collection_id ON collection_items (collection_id);
+
+CREATE TABLE IF NOT EXISTS email_breaches (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    user_id bigint,
+    email text,
+    name text,
+    title text,
+    domain text,
+    breach_date text,
+    added_date datetime,
+    data_classes text
+);
+CREATE INDEX IF NOT EXISTS idx_email_breaches_user_id ON email_breaches (user_id);
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    user_id bigint,
+    action text,
+    item_type text,
+    ip text,
+    user_agent text
+);
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events (action);
+CREATE INDEX IF NOT EXISTS idx_audit_events_user_id ON audit_events (user_id);
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    user_id bigint,
+    type text,
+    status text,
+    total bigint,
+    processed bigint,
+    result text,
+    error text,
+    finished_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_jobs_user_id ON jobs (user_id);
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN master_password_reset;
+ALTER TABLE users DROP COLUMN disabled_at;
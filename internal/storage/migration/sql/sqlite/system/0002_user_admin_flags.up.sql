@@ -0,0 +1,4 @@
+-- Operators can disable users and ask them to change the master password.
+
+ALTER TABLE users ADD COLUMN disabled_at datetime;
+ALTER TABLE users ADD COLUMN master_password_reset numeric NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS audit_events_no_delete;
+DROP TRIGGER IF EXISTS audit_events_no_update;
+ALTER TABLE audit_events DROP COLUMN item_id;
@@ -0,0 +1,35 @@
+-- Webhooks receive the events of the users, their deliveries are kept for the
+-- delivery log and for retrying the failed ones.
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    user_id bigint,
+    admin numeric NOT NULL DEFAULT 0,
+    url text,
+    secret text,
+    events text,
+    active numeric NOT NULL DEFAULT 1
+);
+CREATE INDEX IF NOT EXISTS idx_webhooks_user_id ON webhooks (user_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    webhook_id bigint,
+    event text,
+    payload text,
+    status text,
+    attempts bigint NOT NULL DEFAULT 0,
+    response_code bigint NOT NULL DEFAULT 0,
+    error text,
+    next_attempt_at datetime,
+    delivered_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries (status);
+
+-- The subscription type seen at the last signin tells when it changes.
+ALTER TABLE users ADD COLUMN subscription_type text;
@@ -0,0 +1,4 @@
+-- Audit events have the country and the city of their IP when GeoIP is configured.
+
+ALTER TABLE audit_events ADD COLUMN country text;
+ALTER TABLE audit_events ADD COLUMN city text;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS security_alerts;
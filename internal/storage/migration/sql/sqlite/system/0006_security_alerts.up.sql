@@ -0,0 +1,13 @@
+-- Security alerts are the suspicious patterns found in the audit events.
+
+CREATE TABLE IF NOT EXISTS security_alerts (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    user_id bigint,
+    type text,
+    details text,
+    event_id bigint,
+    sessions_revoked numeric NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_security_alerts_user_id ON security_alerts (user_id);
+CREATE INDEX IF NOT EXISTS idx_security_alerts_type ON security_alerts (type);
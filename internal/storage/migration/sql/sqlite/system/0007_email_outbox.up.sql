@@ -0,0 +1,19 @@
+-- Emails are queued in the outbox and sent by a worker, the ones failing every
+-- attempt stay dead until an admin retries them.
+
+CREATE TABLE IF NOT EXISTS outbox_emails (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    to_name text,
+    to_email text,
+    template text,
+    subject text,
+    text text,
+    html text,
+    status text,
+    attempts bigint NOT NULL DEFAULT 0,
+    error text,
+    next_attempt_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_emails_status ON outbox_emails (status);
@@ -0,0 +1,3 @@
+-- Users choose the language of their emails and of the server messages.
+
+ALTER TABLE users ADD COLUMN locale text;
@@ -0,0 +1,4 @@
+-- Users register a phone number to receive the verification codes by SMS.
+
+ALTER TABLE users ADD COLUMN phone text;
+ALTER TABLE users ADD COLUMN phone_verified_at datetime;
@@ -0,0 +1,4 @@
+-- Users opt in to a weekly email summarizing the security of their account.
+
+ALTER TABLE users ADD COLUMN security_digest numeric NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN digest_sent_at datetime;
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN trial_reminder_sent_at;
+ALTER TABLE users DROP COLUMN trial_ends_at;
+ALTER TABLE users DROP COLUMN trial_started_at;
@@ -0,0 +1,5 @@
+-- New users can get the pro features for a trial period.
+
+ALTER TABLE users ADD COLUMN trial_started_at datetime;
+ALTER TABLE users ADD COLUMN trial_ends_at datetime;
+ALTER TABLE users ADD COLUMN trial_reminder_sent_at datetime;
@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN subscription_lapsed_at;
@@ -0,0 +1,3 @@
+ALTER TABLE organizations DROP COLUMN subscription_item;
+ALTER TABLE organizations DROP COLUMN subscription_id;
+ALTER TABLE organizations DROP COLUMN seats;
@@ -0,0 +1,5 @@
+-- Team subscriptions limit the members of organizations to their seats.
+
+ALTER TABLE organizations ADD COLUMN seats integer NOT NULL DEFAULT 0;
+ALTER TABLE organizations ADD COLUMN subscription_id text;
+ALTER TABLE organizations ADD COLUMN subscription_item text;
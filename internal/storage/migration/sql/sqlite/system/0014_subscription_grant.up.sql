@@ -0,0 +1,4 @@
+-- Admins grant the pro features to users besides their subscriptions.
+
+ALTER TABLE users ADD COLUMN subscription_granted_at datetime;
+ALTER TABLE users ADD COLUMN subscription_granted_until datetime;
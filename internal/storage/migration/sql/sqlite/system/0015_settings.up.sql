@@ -0,0 +1,8 @@
+-- Settings are the instance settings admins change at runtime, like the
+-- security policies.
+
+CREATE TABLE IF NOT EXISTS settings (
+    key text PRIMARY KEY,
+    value text,
+    updated_at datetime
+);
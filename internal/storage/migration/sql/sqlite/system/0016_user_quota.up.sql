@@ -0,0 +1,3 @@
+-- Admins change the item quota of a user besides the quota of the plan.
+
+ALTER TABLE users ADD COLUMN max_items integer;
@@ -0,0 +1,13 @@
+-- Announcements are the notices admins publish to every user, like planned
+-- maintenances or policy changes.
+
+CREATE TABLE IF NOT EXISTS announcements (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    message text,
+    severity text,
+    starts_at datetime,
+    ends_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_announcements_starts_at ON announcements (starts_at);
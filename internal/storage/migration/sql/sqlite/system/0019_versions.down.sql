@@ -0,0 +1,5 @@
+ALTER TABLE users DROP COLUMN version;
+ALTER TABLE organizations DROP COLUMN version;
+ALTER TABLE collections DROP COLUMN version;
+ALTER TABLE webhooks DROP COLUMN version;
+ALTER TABLE announcements DROP COLUMN version;
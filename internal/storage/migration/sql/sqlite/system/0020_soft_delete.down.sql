@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_announcements_deleted_at;
+DROP INDEX IF EXISTS idx_webhooks_deleted_at;
+DROP INDEX IF EXISTS idx_collections_deleted_at;
+DROP INDEX IF EXISTS idx_organizations_deleted_at;
+DROP INDEX IF EXISTS idx_users_deleted_at;
+
+ALTER TABLE announcements DROP COLUMN deleted_at;
+ALTER TABLE webhooks DROP COLUMN deleted_at;
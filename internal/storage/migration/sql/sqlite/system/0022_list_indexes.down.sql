@@ -0,0 +1,4 @@
+CREATE INDEX IF NOT EXISTS idx_security_alerts_user_id ON security_alerts (user_id);
+DROP INDEX IF EXISTS idx_security_alerts_user_id_created_at;
+CREATE INDEX IF NOT EXISTS idx_audit_events_user_id ON audit_events (user_id);
+DROP INDEX IF EXISTS idx_audit_events_user_id_created_at;
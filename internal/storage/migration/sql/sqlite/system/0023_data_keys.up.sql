@@ -0,0 +1,14 @@
+-- Data keys encrypt the fields of the items of a vault, they are kept wrapped
+-- by the master key of the passphrase. The key ID is in every field it encrypts.
+
+CREATE TABLE IF NOT EXISTS data_keys (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    key_id varchar(100) NOT NULL,
+    schema varchar(100) NOT NULL,
+    wrapped_key text NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_data_keys_key_id ON data_keys (key_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_data_keys_schema ON data_keys (schema);
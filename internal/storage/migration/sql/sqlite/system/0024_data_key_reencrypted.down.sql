@@ -0,0 +1 @@
+ALTER TABLE data_keys DROP COLUMN reencrypted_at;
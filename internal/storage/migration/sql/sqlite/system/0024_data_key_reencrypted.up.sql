@@ -0,0 +1,4 @@
+-- Vaults move their fields still encrypted with the passphrase to their data
+-- key once, the time it is done is kept with the key.
+
+ALTER TABLE data_keys ADD COLUMN reencrypted_at datetime;
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN kdf_cost;
+ALTER TABLE users DROP COLUMN kdf_algorithm;
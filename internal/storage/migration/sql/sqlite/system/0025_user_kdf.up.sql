@@ -0,0 +1,5 @@
+-- Users keep the parameters their master password is hashed with, so the
+-- ones hashed with a lower cost than the policy asks are upgraded at signin.
+
+ALTER TABLE users ADD COLUMN kdf_algorithm varchar(20) NOT NULL DEFAULT 'bcrypt';
+ALTER TABLE users ADD COLUMN kdf_cost integer NOT NULL DEFAULT 10;
@@ -0,0 +1,17 @@
+-- Fields may still be encrypted with the older keys of a vault, so they are
+-- kept and the migration waits for the key rotation to delete them.
+CREATE TEMP TABLE data_key_versions_down (older integer);
+CREATE TEMP TRIGGER data_key_versions_down BEFORE INSERT ON data_key_versions_down
+    WHEN NEW.older > 0
+BEGIN
+    SELECT RAISE(ABORT, 'vaults have older data keys, finish the key rotation first');
+END;
+INSERT INTO data_key_versions_down
+    SELECT COUNT(*) FROM data_keys
+    WHERE EXISTS (SELECT 1 FROM data_keys n WHERE n.schema = data_keys.schema AND n.version > data_keys.version);
+DROP TABLE data_key_versions_down;
+
+DROP INDEX IF EXISTS idx_data_keys_schema_version;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_data_keys_schema ON data_keys (schema);
+
+ALTER TABLE data_keys DROP COLUMN version;
@@ -0,0 +1,7 @@
+-- Vaults keep a data key per version while a key rotation moves their fields
+-- to the new one, the key with the highest version encrypts the new fields.
+
+ALTER TABLE data_keys ADD COLUMN version integer NOT NULL DEFAULT 1;
+
+DROP INDEX IF EXISTS idx_data_keys_schema;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_data_keys_schema_version ON data_keys (schema, version);
@@ -0,0 +1,18 @@
+-- Trusted devices skip the two-factor code at signin with their token until
+-- they expire, only the hashes of the device identifier and the token are kept.
+
+CREATE TABLE IF NOT EXISTS trusted_devices (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    uuid varchar(100) NOT NULL,
+    created_at datetime,
+    user_id integer NOT NULL,
+    device_hash varchar(64) NOT NULL,
+    token_hash varchar(64) NOT NULL,
+    name text,
+    last_used_at datetime,
+    expires_at datetime NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_trusted_devices_uuid ON trusted_devices (uuid);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_trusted_devices_token_hash ON trusted_devices (token_hash);
+CREATE INDEX IF NOT EXISTS idx_trusted_devices_user_id ON trusted_devices (user_id);
@@ -0,0 +1,6 @@
+-- Users changing their email keep the one the billing providers know them by,
+-- the subscription events of the providers find them by it.
+
+ALTER TABLE users ADD COLUMN billing_email text;
+
+CREATE INDEX IF NOT EXISTS idx_users_billing_email ON users (billing_email);
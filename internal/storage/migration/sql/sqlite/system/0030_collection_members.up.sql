@@ -0,0 +1,12 @@
+-- Members of an organization read only the items of the collections they are
+-- given, owners, admins and editors read the whole organization vault.
+
+CREATE TABLE IF NOT EXISTS collection_members (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    collection_id integer NOT NULL,
+    user_id integer NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_collection_members_collection_user ON collection_members (collection_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_collection_members_user_id ON collection_members (user_id);
@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS {schema}.vault_revisions;
+DROP TABLE IF EXISTS {schema}.conflicts;
+DROP TABLE IF EXISTS {schema}.tombstones;
+DROP TABLE IF EXISTS {schema}.folders;
+DROP TABLE IF EXISTS {schema}.item_relations;
+DROP TABLE IF EXISTS {schema}.servers;
+DROP TABLE IF EXISTS {schema}.emails;
+DROP TABLE IF EXISTS {schema}.notes;
+DROP TABLE IF EXISTS {schema}.bank_accounts;
+DROP TABLE IF EXISTS {schema}.credit_cards;
+DROP TABLE IF EXISTS {schema}.login_histories;
+DROP TABLE IF EXISTS {schema}.logins;
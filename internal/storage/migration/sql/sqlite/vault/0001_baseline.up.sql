@@ -0,0 +1,153 @@
+-- Baseline of the vault tables of a user or organization database attached as
+-- the schema. Search index is kept as text since SQLite has no tsvector.
+
+CREATE TABLE IF NOT EXISTS {schema}.logins (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    title text,
+    url text,
+    username text,
+    password text,
+    totp_secret text,
+    extra text,
+    password_changed_at datetime,
+    breached numeric,
+    breach_checked_at datetime,
+    max_password_age bigint,
+    expired numeric,
+    search_index text,
+    folder_id bigint,
+    revision bigint NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS {schema}.login_histories (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    login_id bigint,
+    title text,
+    url text,
+    username text,
+    password text,
+    totp_secret text,
+    extra text,
+    reason text
+);
+CREATE INDEX IF NOT EXISTS {schema}.idx_login_histories_login_id ON login_histories (login_id);
+
+CREATE TABLE IF NOT EXISTS {schema}.credit_cards (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    card_name text,
+    cardholder_name text,
+    type text,
+    number text,
+    verification_number text,
+    expiry_date text,
+    folder_id bigint,
+    revision bigint NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS {schema}.bank_accounts (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    bank_name text,
+    bank_code text,
+    account_name text,
+    account_number text,
+    iban text,
+    currency text,
+    password text,
+    folder_id bigint,
+    revision bigint NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS {schema}.notes (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    title text,
+    note text,
+    folder_id bigint,
+    revision bigint NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS {schema}.emails (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    title text,
+    email text,
+    password text,
+    folder_id bigint,
+    revision bigint NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS {schema}.servers (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    title text,
+    ip text,
+    username text,
+    password text,
+    url text,
+    hosting_username text,
+    hosting_password text,
+    admin_username text,
+    admin_password text,
+    extra text,
+    folder_id bigint,
+    revision bigint NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS {schema}.item_relations (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    source_type text,
+    source_id bigint,
+    target_type text,
+    target_id bigint
+);
+CREATE INDEX IF NOT EXISTS {schema}.idx_relation_target ON item_relations (target_type,target_id);
+CREATE INDEX IF NOT EXISTS {schema}.idx_relation_source ON item_relations (source_type,source_id);
+
+CREATE TABLE IF NOT EXISTS {schema}.folders (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    name text,
+    parent_id bigint
+);
+CREATE INDEX IF NOT EXISTS {schema}.idx_folders_parent_id ON folders (parent_id);
+
+CREATE TABLE IF NOT EXISTS {schema}.tombstones (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    item_type text,
+    item_id bigint
+);
+CREATE INDEX IF NOT EXISTS {schema}.idx_tombstones_created_at ON tombstones (created_at);
+
+CREATE TABLE IF NOT EXISTS {schema}.conflicts (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    item_type text NOT NULL,
+    item_id bigint NOT NULL,
+    copy_id bigint NOT NULL
+);
+CREATE INDEX IF NOT EXISTS {schema}.idx_conflict_item ON conflicts (item_type,item_id);
+
+CREATE TABLE IF NOT EXISTS {schema}.vault_revisions (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    updated_at datetime,
+    revision bigint NOT NULL DEFAULT 0
+);
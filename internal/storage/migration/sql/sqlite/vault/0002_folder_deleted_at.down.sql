@@ -0,0 +1 @@
+ALTER TABLE {schema}.folders DROP COLUMN deleted_at;
@@ -0,0 +1,14 @@
+ALTER TABLE {schema}.folders DROP COLUMN created_by;
+ALTER TABLE {schema}.folders DROP COLUMN updated_by;
+ALTER TABLE {schema}.logins DROP COLUMN created_by;
+ALTER TABLE {schema}.logins DROP COLUMN updated_by;
+ALTER TABLE {schema}.credit_cards DROP COLUMN created_by;
+ALTER TABLE {schema}.credit_cards DROP COLUMN updated_by;
+ALTER TABLE {schema}.bank_accounts DROP COLUMN created_by;
+ALTER TABLE {schema}.bank_accounts DROP COLUMN updated_by;
+ALTER TABLE {schema}.notes DROP COLUMN created_by;
+ALTER TABLE {schema}.notes DROP COLUMN updated_by;
+ALTER TABLE {schema}.emails DROP COLUMN created_by;
+ALTER TABLE {schema}.emails DROP COLUMN updated_by;
+ALTER TABLE {schema}.servers DROP COLUMN created_by;
+ALTER TABLE {schema}.servers DROP COLUMN updated_by;
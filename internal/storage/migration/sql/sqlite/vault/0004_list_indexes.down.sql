@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS {schema}.idx_folders_updated_at;
+DROP INDEX IF EXISTS {schema}.idx_servers_folder_id;
+DROP INDEX IF EXISTS {schema}.idx_servers_updated_at;
+DROP INDEX IF EXISTS {schema}.idx_emails_folder_id;
+DROP INDEX IF EXISTS {schema}.idx_emails_updated_at;
+DROP INDEX IF EXISTS {schema}.idx_notes_folder_id;
+DROP INDEX IF EXISTS {schema}.idx_notes_updated_at;
+DROP INDEX IF EXISTS {schema}.idx_bank_accounts_folder_id;
+DROP INDEX IF EXISTS {schema}.idx_bank_accounts_updated_at;
+DROP INDEX IF EXISTS {schema}.idx_credit_cards_folder_id;
+DROP INDEX IF EXISTS {schema}.idx_credit_cards_updated_at;
+DROP INDEX IF EXISTS {schema}.idx_logins_folder_id;
+DROP INDEX IF EXISTS {schema}.idx_logins_updated_at;
@@ -0,0 +1,16 @@
+-- Lists and syncs find the items of a folder or changed since a time, sorted
+-- by their change time on large vaults.
+
+CREATE INDEX IF NOT EXISTS {schema}.idx_logins_updated_at ON logins (updated_at);
+CREATE INDEX IF NOT EXISTS {schema}.idx_logins_folder_id ON logins (folder_id);
+CREATE INDEX IF NOT EXISTS {schema}.idx_credit_cards_updated_at ON credit_cards (updated_at);
+CREATE INDEX IF NOT EXISTS {schema}.idx_credit_cards_folder_id ON credit_cards (folder_id);
+CREATE INDEX IF NOT EXISTS {schema}.idx_bank_accounts_updated_at ON bank_accounts (updated_at);
+CREATE INDEX IF NOT EXISTS {schema}.idx_bank_accounts_folder_id ON bank_accounts (folder_id);
+CREATE INDEX IF NOT EXISTS {schema}.idx_notes_updated_at ON notes (updated_at);
+CREATE INDEX IF NOT EXISTS {schema}.idx_notes_folder_id ON notes (folder_id);
+CREATE INDEX IF NOT EXISTS {schema}.idx_emails_updated_at ON emails (updated_at);
+CREATE INDEX IF NOT EXISTS {schema}.idx_emails_folder_id ON emails (folder_id);
+CREATE INDEX IF NOT EXISTS {schema}.idx_servers_updated_at ON servers (updated_at);
+CREATE INDEX IF NOT EXISTS {schema}.idx_servers_folder_id ON servers (folder_id);
+CREATE INDEX IF NOT EXISTS {schema}.idx_folders_updated_at ON folders (updated_at);
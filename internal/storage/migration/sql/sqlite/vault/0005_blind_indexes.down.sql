@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS {schema}.idx_logins_domain_index;
+ALTER TABLE {schema}.logins DROP COLUMN username_index;
+ALTER TABLE {schema}.logins DROP COLUMN domain_index;
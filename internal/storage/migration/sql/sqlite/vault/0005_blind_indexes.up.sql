@@ -0,0 +1,6 @@
+-- Logins keep the blind indexes of their domain and username, so duplicates
+-- are found without decrypting the usernames.
+
+ALTER TABLE {schema}.logins ADD COLUMN domain_index text;
+ALTER TABLE {schema}.logins ADD COLUMN username_index text;
+CREATE INDEX IF NOT EXISTS {schema}.idx_logins_domain_index ON logins (domain_index, username_index);
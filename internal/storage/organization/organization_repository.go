@@ -0,0 +1,118 @@
+package organization
+
+import (
+	"github.com/passwall/passwall-server/internal/storage/query"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Repository ...
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository ...
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// FindByID ...
+func (p *Repository) FindByID(id uint) (*model.Organization, error) {
+	org := new(model.Organization)
+	err := p.db.Where(`id = ?`, id).First(&org).Error
+	if err != nil {
+		logger.Errorf("Error finding organization %v error %v", id, err)
+		return nil, err
+	}
+	return org, err
+}
+
+// All returns all the organizations
+func (p *Repository) All() ([]model.Organization, error) {
+	orgs := []model.Organization{}
+	err := p.db.Find(&orgs).Error
+	return orgs, err
+}
+
+// FindByUserID returns the organizations the user is an accepted member of
+func (p *Repository) FindByUserID(userID uint) ([]model.Organization, error) {
+	orgs := []model.Organization{}
+	err := p.db.
+		Joins("JOIN organization_members ON organization_members.organization_id = organizations.id").
+		Where("organization_members.user_id = ? AND organization_members.status = ?", userID, model.MemberStatusAccepted).
+		Find(&orgs).Error
+	if err != nil {
+		logger.Errorf("Error finding organizations of user %v error %v", userID, err)
+		return nil, err
+	}
+	return orgs, err
+}
+
+// Create ...
+func (p *Repository) Create(org *model.Organization) (*model.Organization, error) {
+	err := p.db.Create(&org).Error
+	if err != nil {
+		logger.Errorf("Error creating organization %v error %v", org, err)
+		return nil, err
+	}
+
+	return org, nil
+}
+
+// Update saves the organization if its version is still the one it was read with
+func (p *Repository) Update(org *model.Organization) (*model.Organization, error) {
+	err := query.SaveVersion(p.db, org, org.ID, &org.Version)
+	if err != nil {
+		logger.Errorf("Error updating organization %v error %v", org, err)
+		return nil, err
+	}
+
+	return org, nil
+}
+
+// Delete ...
+func (p *Repository) Delete(id uint) error {
+	// Members are kept for restoring the organization until it is purged
+	return p.db.Delete(&model.Organization{ID: id}).Error
+}
+
+// Members ...
+func (p *Repository) Members(orgID uint) ([]model.OrganizationMember, error) {
+	members := []model.OrganizationMember{}
+	err := p.db.Where("organization_id = ?", orgID).Find(&members).Error
+	if err != nil {
+		logger.Errorf("Error getting members of organization %v error %v", orgID, err)
+		return nil, err
+	}
+	return members, err
+}
+
+// FindMember ...
+func (p *Repository) FindMember(orgID, userID uint) (*model.OrganizationMember, error) {
+	member := new(model.OrganizationMember)
+	err := p.db.Where("organization_id = ? AND user_id = ?", orgID, userID).First(&member).Error
+	return member, err
+}
+
+// SaveMember ...
+func (p *Repository) SaveMember(member *model.OrganizationMember) (*model.OrganizationMember, error) {
+	err := p.db.Save(&member).Error
+	if err != nil {
+		logger.Errorf("Error saving organization member %v error %v", member, err)
+		return nil, err
+	}
+
+	return member, nil
+}
+
+// DeleteMember removes the member with the collections given the member
+func (p *Repository) DeleteMember(orgID, userID uint) error {
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		collections := tx.Model(&model.Collection{}).Unscoped().Select("id").Where("organization_id = ?", orgID)
+		if err := tx.Delete(model.CollectionMember{}, "user_id = ? AND collection_id IN (?)", userID, collections).Error; err != nil {
+			return err
+		}
+		return tx.Delete(model.OrganizationMember{}, "organization_id = ? AND user_id = ?", orgID, userID).Error
+	})
+}
@@ -0,0 +1,92 @@
+package outbox
+
+import (
+	"time"
+
+	"github.com/passwall/passwall-server/internal/storage/query"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Repository ...
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository ...
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// FindByID ...
+func (p *Repository) FindByID(id uint) (*model.OutboxEmail, error) {
+	email := new(model.OutboxEmail)
+	err := p.db.Where(`id = ?`, id).First(&email).Error
+	if err != nil {
+		logger.Errorf("Error getting outbox email %v error %v", id, err)
+		return nil, err
+	}
+	return email, nil
+}
+
+// FindAll ...
+func (p *Repository) FindAll(opts *model.ListOptions) ([]model.OutboxEmail, int64, error) {
+	db := p.db.Model(&model.OutboxEmail{})
+
+	emails := []model.OutboxEmail{}
+	total, err := query.List(db, opts, nil, &emails)
+	if err != nil {
+		logger.Errorf("Error listing outbox emails error %v", err)
+		return nil, 0, err
+	}
+	return emails, total, nil
+}
+
+// FindDue ...
+func (p *Repository) FindDue(until time.Time, status string, limit int) ([]model.OutboxEmail, error) {
+	emails := []model.OutboxEmail{}
+	err := p.db.Where(`status = ? AND next_attempt_at <= ?`, status, until).
+		Order("next_attempt_at asc").Limit(limit).Find(&emails).Error
+	if err != nil {
+		logger.Errorf("Error getting due outbox emails error %v", err)
+		return nil, err
+	}
+	return emails, nil
+}
+
+// Create ...
+func (p *Repository) Create(email *model.OutboxEmail) (*model.OutboxEmail, error) {
+	err := p.db.Create(&email).Error
+	if err != nil {
+		logger.Errorf("Error creating outbox email %v to %v error %v", email.Template, email.ToEmail, err)
+		return nil, err
+	}
+	return email, nil
+}
+
+// Update ...
+func (p *Repository) Update(email *model.OutboxEmail) (*model.OutboxEmail, error) {
+	err := p.db.Save(&email).Error
+	if err != nil {
+		logger.Errorf("Error updating outbox email %v error %v", email.ID, err)
+		return nil, err
+	}
+	return email, nil
+}
+
+// Delete ...
+func (p *Repository) Delete(id uint) error {
+	return p.db.Delete(&model.OutboxEmail{ID: id}).Error
+}
+
+// Count ...
+func (p *Repository) Count(status string, minAttempts int) (int64, error) {
+	var count int64
+	err := p.db.Model(&model.OutboxEmail{}).Where(`status = ? AND attempts >= ?`, status, minAttempts).Count(&count).Error
+	if err != nil {
+		logger.Errorf("Error counting %v outbox emails error %v", status, err)
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,61 @@
+package query
+
+import (
+	"strings"
+
+	"github.com/passwall/passwall-server/internal/storage/sqlite"
+	"github.com/passwall/passwall-server/model"
+	"gorm.io/gorm"
+)
+
+// List applies the list options to the query, counts the matching rows and
+// finds the requested page into dest. Search is done on the given plain text
+// columns since the encrypted ones can't be queried.
+func List(db *gorm.DB, opts *model.ListOptions, searchColumns []string, dest interface{}) (int64, error) {
+	// Session makes the query reusable for both counting and finding
+	query := db.Session(&gorm.Session{})
+
+	for column, value := range opts.Filters {
+		query = query.Where(column+" = ?", value)
+	}
+
+	if opts.UpdatedSince != nil {
+		query = query.Where("updated_at >= ?", *opts.UpdatedSince)
+	}
+
+	if opts.Search != "" && len(searchColumns) > 0 {
+		// LIKE of SQLite is already case insensitive
+		operator := " ILIKE ?"
+		if sqlite.Is(db) {
+			operator = " LIKE ?"
+		}
+		conditions := make([]string, len(searchColumns))
+		values := make([]interface{}, len(searchColumns))
+		for i, column := range searchColumns {
+			conditions[i] = column + operator
+			values[i] = "%" + opts.Search + "%"
+		}
+		query = query.Where("("+strings.Join(conditions, " OR ")+")", values...)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return 0, err
+	}
+
+	// Cursor pagination walks the rows in id order
+	if opts.Cursor > 0 {
+		query = query.Where("id > ?", opts.Cursor).Order("id asc")
+	} else {
+		query = query.Order(opts.Sort + " " + opts.Direction)
+		if opts.Limit > 0 && opts.Offset > 0 {
+			query = query.Offset(opts.Offset)
+		}
+	}
+
+	if opts.Limit > 0 {
+		query = query.Limit(opts.Limit)
+	}
+
+	return total, query.Find(dest).Error
+}
@@ -0,0 +1,51 @@
+package query
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// replicaKey is the statement setting of the read replica of the database
+const replicaKey = "query:replica"
+
+// replica is the query sent to the read replica and how far behind the
+// primary the replica may be
+type replica struct {
+	db  *gorm.DB
+	lag time.Duration
+}
+
+// WithReplica returns the query whose Reads are sent to the connections of
+// the replica. The replica shares the callbacks of the primary, only the
+// connections of its statements differ.
+func WithReplica(db *gorm.DB, pool gorm.ConnPool, lag time.Duration) *gorm.DB {
+	// A context clones the statement, the one of the primary isn't changed
+	reads := db.Session(&gorm.Session{Context: context.Background()})
+	reads.Statement.ConnPool = pool
+	return db.Set(replicaKey, &replica{db: reads, lag: lag}).Session(&gorm.Session{})
+}
+
+// Reads returns the query of the list, search and sync reads, the replica
+// when the database has one. Transactions read from themselves so they see
+// their own changes.
+func Reads(db *gorm.DB) *gorm.DB {
+	r, ok := db.Get(replicaKey)
+	if !ok {
+		return db
+	}
+	if _, tx := db.Statement.ConnPool.(gorm.TxCommitter); tx {
+		return db
+	}
+	return r.(*replica).db
+}
+
+// ReadLag returns how far behind the primary the Reads may be, zero
+// without a replica
+func ReadLag(db *gorm.DB) time.Duration {
+	if r, ok := db.Get(replicaKey); ok {
+		return r.(*replica).lag
+	}
+	return 0
+}
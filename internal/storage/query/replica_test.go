@@ -0,0 +1,56 @@
+package query
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/passwall/passwall-server/internal/storage/sqlite"
+)
+
+type row struct {
+	ID   uint
+	Name string
+}
+
+func openDatabase(t *testing.T, name string) *gorm.DB {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "passwall.db"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Exec("CREATE TABLE rows (id integer PRIMARY KEY, name text)").Error)
+	require.NoError(t, db.Table("rows").Create(&row{ID: 1, Name: name}).Error)
+	return db
+}
+
+func TestReads(t *testing.T) {
+	primary := openDatabase(t, "primary")
+	replica := openDatabase(t, "replica")
+
+	// Without a replica the reads are from the database itself
+	found := row{}
+	require.NoError(t, Reads(primary).Table("rows").First(&found).Error)
+	assert.Equal(t, "primary", found.Name)
+	assert.Zero(t, ReadLag(primary))
+
+	db := WithReplica(primary, replica.ConnPool, 5*time.Second)
+	assert.Equal(t, 5*time.Second, ReadLag(db))
+	require.NoError(t, Reads(db).Table("rows").First(&found).Error)
+	assert.Equal(t, "replica", found.Name)
+
+	// The other queries and the primary itself aren't changed
+	require.NoError(t, db.Table("rows").First(&found).Error)
+	assert.Equal(t, "primary", found.Name)
+	require.NoError(t, primary.Table("rows").First(&found).Error)
+	assert.Equal(t, "primary", found.Name)
+
+	// Transactions read their own changes
+	err := db.Transaction(func(tx *gorm.DB) error {
+		require.NoError(t, tx.Table("rows").Where("id = ?", 1).Update("name", "changed").Error)
+		return Reads(tx).Table("rows").First(&found).Error
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "changed", found.Name)
+}
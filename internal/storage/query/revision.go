@@ -0,0 +1,28 @@
+package query
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrRevisionConflict is returned when the entity was changed after it was read
+var ErrRevisionConflict = errors.New("item was changed by another client")
+
+// SaveRevision updates all the fields of the entity if its revision in the table
+// is still the given one and increases the revision. Entities changed by another
+// client in the meantime aren't overwritten, ErrRevisionConflict is returned.
+func SaveRevision(db *gorm.DB, entity interface{}, id uint, revision *uint) error {
+	expected := *revision
+	*revision = expected + 1
+
+	result := db.Where("id = ? AND revision = ?", id, expected).Select("*").Updates(entity)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = ErrRevisionConflict
+	}
+	if result.Error != nil {
+		*revision = expected
+		return result.Error
+	}
+	return nil
+}
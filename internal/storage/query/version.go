@@ -0,0 +1,36 @@
+package query
+
+import (
+	"gorm.io/gorm"
+)
+
+// VersionConflictError is returned when the record was changed by another
+// request after it was read. The API responds with 409 Conflict.
+type VersionConflictError struct {
+	Table   string
+	ID      uint
+	Version uint
+}
+
+// Error ...
+func (e *VersionConflictError) Error() string {
+	return "record was changed by another request"
+}
+
+// SaveVersion updates all the fields of the record if its version in the
+// table is still the given one and increases the version. Records changed in
+// the meantime aren't overwritten, a *VersionConflictError is returned.
+func SaveVersion(db *gorm.DB, record interface{}, id uint, version *uint) error {
+	expected := *version
+	*version = expected + 1
+
+	result := db.Where("id = ? AND version = ?", id, expected).Select("*").Updates(record)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = &VersionConflictError{Table: result.Statement.Table, ID: id, Version: expected}
+	}
+	if result.Error != nil {
+		*version = expected
+		return result.Error
+	}
+	return nil
+}
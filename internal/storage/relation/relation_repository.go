@@ -0,0 +1,78 @@
+package relation
+
+import (
+	"github.com/passwall/passwall-server/internal/storage/tenancy"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Repository ...
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository ...
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// All ...
+func (p *Repository) All(schema string) ([]model.ItemRelation, error) {
+	relations := []model.ItemRelation{}
+	err := tenancy.Table(p.db, schema, "item_relations").Find(&relations).Error
+	if err != nil {
+		logger.Errorf("Error getting relations error %v", err)
+		return nil, err
+	}
+
+	return relations, err
+}
+
+// FindByItem ...
+func (p *Repository) FindByItem(itemType string, itemID uint, schema string) ([]model.ItemRelation, error) {
+	relations := []model.ItemRelation{}
+	err := tenancy.Table(p.db, schema, "item_relations").
+		Where("(source_type = ? AND source_id = ?) OR (target_type = ? AND target_id = ?)", itemType, itemID, itemType, itemID).
+		Find(&relations).Error
+	if err != nil {
+		logger.Errorf("Error getting relations of %v %v error %v", itemType, itemID, err)
+		return nil, err
+	}
+
+	return relations, err
+}
+
+// FindByID ...
+func (p *Repository) FindByID(id uint, schema string) (*model.ItemRelation, error) {
+	relation := new(model.ItemRelation)
+	err := tenancy.Table(p.db, schema, "item_relations").Where(`id = ?`, id).First(&relation).Error
+	if err != nil {
+		logger.Errorf("Error finding relation %v error %v", id, err)
+		return nil, err
+	}
+	return relation, err
+}
+
+// Create ...
+func (p *Repository) Create(relation *model.ItemRelation, schema string) (*model.ItemRelation, error) {
+	err := tenancy.Table(p.db, schema, "item_relations").Create(&relation).Error
+	if err != nil {
+		logger.Errorf("Error creating relation %v error %v", relation, err)
+		return nil, err
+	}
+
+	return relation, nil
+}
+
+// Delete ...
+func (p *Repository) Delete(id uint, schema string) error {
+	return tenancy.Table(p.db, schema, "item_relations").Delete(&model.ItemRelation{ID: id}).Error
+}
+
+// DeleteByItem ...
+func (p *Repository) DeleteByItem(itemType string, itemID uint, schema string) error {
+	return tenancy.Table(p.db, schema, "item_relations").
+		Where("(source_type = ? AND source_id = ?) OR (target_type = ? AND target_id = ?)", itemType, itemID, itemType, itemID).
+		Delete(&model.ItemRelation{}).Error
+}
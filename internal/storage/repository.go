@@ -7,103 +7,72 @@ import (
 	uuid "github.com/satori/go.uuid"
 )
 
-// LoginRepository interface is the common interface for a repository
-// Each method checks the entity type.
-type LoginRepository interface {
+// ItemRepository is the common interface of the repositories of the vault
+// items, each item type has one
+type ItemRepository[T any] interface {
 	// All returns all the data in the repository.
-	All(schema string) ([]model.Login, error)
+	All(schema string) ([]T, error)
+	// FindAll returns the entities matching the list options and the total count.
+	FindAll(opts *model.ListOptions, schema string) ([]T, int64, error)
 	// FindByID finds the entity regarding to its ID.
-	FindByID(id uint, schema string) (*model.Login, error)
+	FindByID(id uint, schema string) (*T, error)
+	// FindByIDs finds the entities of the IDs, the missing ones are skipped.
+	FindByIDs(ids []uint, schema string) ([]T, error)
 	// Update stores the entity to the repository
-	Update(login *model.Login, schema string) (*model.Login, error)
+	Update(item *T, schema string) (*T, error)
 	// Create stores the entity to the repository
-	Create(login *model.Login, schema string) (*model.Login, error)
+	Create(item *T, schema string) (*T, error)
+	// CreateSealed stores the entity and the columns sealed once it has its ID
+	CreateSealed(item *T, schema string, seal func(*T) (map[string]interface{}, error)) (*T, error)
 	// Delete removes the entity from the store
 	Delete(id uint, schema string) error
-	// Migrate migrates the repository
-	Migrate(schema string) error
 }
 
-// CreditCardRepository interface is the common interface for a repository
-// Each method checks the entity type.
-type CreditCardRepository interface {
-	// All returns all the data in the repository.
-	All(schema string) ([]model.CreditCard, error)
-	// FindByID finds the entity regarding to its ID.
-	FindByID(id uint, schema string) (*model.CreditCard, error)
-	// Update stores the entity to the repository
-	Update(card *model.CreditCard, schema string) (*model.CreditCard, error)
-	// Create stores the entity to the repository
-	Create(card *model.CreditCard, schema string) (*model.CreditCard, error)
-	// Delete removes the entity from the store
-	Delete(id uint, schema string) error
-	// Migrate migrates the repository
-	Migrate(schema string) error
+// LoginRepository is the repository of the logins, they have histories and a
+// search index besides the other items
+type LoginRepository interface {
+	ItemRepository[model.Login]
+	// Histories returns the previous versions of the login
+	Histories(loginID uint, schema string) ([]model.LoginHistory, error)
+	// CreateHistory stores a previous version of the login
+	CreateHistory(history *model.LoginHistory, schema string) (*model.LoginHistory, error)
+	// CreateHistorySealed stores a previous version and the columns sealed once it has its ID
+	CreateHistorySealed(history *model.LoginHistory, schema string, seal func(*model.LoginHistory) (map[string]interface{}, error)) (*model.LoginHistory, error)
+	// UpdateHistory stores the changes of a previous version of the login
+	UpdateHistory(history *model.LoginHistory, schema string) (*model.LoginHistory, error)
+	// Search finds the entities whose search index contains all the tokens
+	Search(tokens []string, schema string) ([]model.Login, error)
 }
 
-// BankAccountRepository interface is the common interface for a repository
-// Each method checks the entity type.
-type BankAccountRepository interface {
-	// All returns all the data in the repository.
-	All(schema string) ([]model.BankAccount, error)
-	// FindByID finds the entity regarding to its ID.
-	FindByID(id uint, schema string) (*model.BankAccount, error)
-	// Update stores the entity to the repository
-	Update(account *model.BankAccount, schema string) (*model.BankAccount, error)
-	// Create stores the entity to the repository
-	Create(account *model.BankAccount, schema string) (*model.BankAccount, error)
-	// Delete removes the entity from the store
-	Delete(id uint, schema string) error
-	// Migrate migrates the repository
-	Migrate(schema string) error
-}
+// CreditCardRepository is the repository of the credit cards
+type CreditCardRepository = ItemRepository[model.CreditCard]
 
-// NoteRepository interface is the common interface for a repository
-// Each method checks the entity type.
-type NoteRepository interface {
-	// All returns all the data in the repository.
-	All(schema string) ([]model.Note, error)
-	// FindByID finds the entity regarding to its ID.
-	FindByID(id uint, schema string) (*model.Note, error)
-	// Update stores the entity to the repository
-	Update(account *model.Note, schema string) (*model.Note, error)
-	// Create stores the entity to the repository
-	Create(account *model.Note, schema string) (*model.Note, error)
-	// Delete removes the entity from the store
-	Delete(id uint, schema string) error
-	// Migrate migrates the repository
-	Migrate(schema string) error
-}
+// BankAccountRepository is the repository of the bank accounts
+type BankAccountRepository = ItemRepository[model.BankAccount]
 
-// EmailRepository interface is the common interface for a repository
-// Each method checks the entity type.
-type EmailRepository interface {
-	// All returns all the data in the repository.
-	All(schema string) ([]model.Email, error)
-	// FindByID finds the entity regarding to its ID.
-	FindByID(id uint, schema string) (*model.Email, error)
-	// Update stores the entity to the repository
-	Update(account *model.Email, schema string) (*model.Email, error)
-	// Create stores the entity to the repository
-	Create(account *model.Email, schema string) (*model.Email, error)
-	// Delete removes the entity from the store
-	Delete(id uint, schema string) error
-	// Migrate migrates the repository
-	Migrate(schema string) error
-}
+// NoteRepository is the repository of the notes
+type NoteRepository = ItemRepository[model.Note]
+
+// EmailRepository is the repository of the emails
+type EmailRepository = ItemRepository[model.Email]
+
+// ServerRepository is the repository of the servers
+type ServerRepository = ItemRepository[model.Server]
 
 // TokenRepository ...
 type TokenRepository interface {
 	// FindByUUID finds the entity regarding to its UUID.
 	FindByUUID(uuid string) (model.Token, error)
+	// FindByUserID finds the tokens of the user
+	FindByUserID(userid int) ([]model.Token, error)
 	// Create stores the entity to the repository
 	Create(userid int, uuid uuid.UUID, tkn string, expriydate time.Time)
 	// Delete removes the entity regarding to its User ID
 	Delete(userid int)
 	// DeleteByUUID removes the entity regarding to its UUID
 	DeleteByUUID(uuid string)
-	// Migrate migrates the repository
-	Migrate() error
+	// CountActive counts the tokens which aren't expired at the time and their users
+	CountActive(now time.Time) (tokens, users int64, err error)
 }
 
 // UserRepository interface is the common interface for a repository
@@ -117,8 +86,15 @@ type UserRepository interface {
 	FindByID(id uint) (*model.User, error)
 	// FindByUUID finds the entity regarding to its UUID.
 	FindByUUID(uuid string) (*model.User, error)
+	// FindCachedByUUID finds the user of the auth lookups, the cached users
+	// have no master password, secret and confirmation code.
+	FindCachedByUUID(uuid string) (*model.User, error)
 	// FindByEmail finds the entity regarding to its Email.
 	FindByEmail(email string) (*model.User, error)
+	// FindByBillingEmail finds the entity regarding to the email the billing providers know it by.
+	FindByBillingEmail(email string) (*model.User, error)
+	// FindScheduledForDeletion finds the entities whose deletion is scheduled until the given time.
+	FindScheduledForDeletion(until time.Time) ([]model.User, error)
 	// FindByCredentials finds the entity regarding to its Email and Master Password.
 	FindByCredentials(email, masterPassword string) (*model.User, error)
 	// Update stores the entity to the repository
@@ -127,25 +103,314 @@ type UserRepository interface {
 	Create(login *model.User) (*model.User, error)
 	// Delete removes the entity from the store
 	Delete(id uint, schema string) error
-	// Migrate migrates the repository
-	Migrate() error
 	// CreateSchema creates schema for user
 	CreateSchema(schema string) error
+	// DropSchema removes the schema and its tables
+	DropSchema(schema string) error
 }
 
-// ServerRepository interface is the common interface for a repository
+// OrganizationRepository interface is the common interface for a repository
 // Each method checks the entity type.
-type ServerRepository interface {
+type OrganizationRepository interface {
 	// All returns all the data in the repository.
-	All(schema string) ([]model.Server, error)
+	All() ([]model.Organization, error)
+	// FindByID finds the entity regarding to its ID.
+	FindByID(id uint) (*model.Organization, error)
+	// FindByUserID finds the organizations the user is a member of.
+	FindByUserID(userID uint) ([]model.Organization, error)
+	// Create stores the entity to the repository
+	Create(org *model.Organization) (*model.Organization, error)
+	// Update stores the entity to the repository
+	Update(org *model.Organization) (*model.Organization, error)
+	// Delete removes the entity and its members from the store
+	Delete(id uint) error
+	// Members returns all members and invitations of the organization
+	Members(orgID uint) ([]model.OrganizationMember, error)
+	// FindMember finds the membership of the user in the organization
+	FindMember(orgID, userID uint) (*model.OrganizationMember, error)
+	// SaveMember stores the membership to the repository
+	SaveMember(member *model.OrganizationMember) (*model.OrganizationMember, error)
+	// DeleteMember removes the membership from the store
+	DeleteMember(orgID, userID uint) error
+}
+
+// CollectionRepository interface is the common interface for a repository
+// Each method checks the entity type.
+type CollectionRepository interface {
+	// All returns all the collections of the organization.
+	All(orgID uint) ([]model.Collection, error)
+	// FindByMember returns the collections of the organization the user is a member of
+	FindByMember(orgID, userID uint) ([]model.Collection, error)
+	// FindByID finds the entity regarding to its ID and organization.
+	FindByID(id, orgID uint) (*model.Collection, error)
+	// Create stores the entity to the repository
+	Create(collection *model.Collection) (*model.Collection, error)
+	// Update stores the entity to the repository
+	Update(collection *model.Collection) (*model.Collection, error)
+	// Delete removes the entity from the store
+	Delete(id uint) error
+	// Items returns the items of the collection
+	Items(collectionID uint) ([]model.CollectionItem, error)
+	// AddItem adds an item to the collection
+	AddItem(item *model.CollectionItem) (*model.CollectionItem, error)
+	// RemoveItem removes an item from the collection
+	RemoveItem(collectionID uint, itemType string, itemID uint) error
+	// Members returns the members given the collection
+	Members(collectionID uint) ([]model.CollectionMember, error)
+	// IsMember checks if the user is given the collection
+	IsMember(collectionID, userID uint) (bool, error)
+	// AddMember gives a member of the organization the collection
+	AddMember(member *model.CollectionMember) (*model.CollectionMember, error)
+	// RemoveMember takes the collection back from the member
+	RemoveMember(collectionID, userID uint) error
+	// HasMemberItem checks if the item is in a collection of the organization given the user
+	HasMemberItem(orgID, userID uint, itemType string, itemID uint) (bool, error)
+}
+
+// BreachRepository interface is the common interface for a repository
+// Each method checks the entity type.
+type BreachRepository interface {
+	// FindByUserID returns the breaches found for the user.
+	FindByUserID(userID uint) ([]model.EmailBreach, error)
+	// Exists checks if the breach is already stored for the email of the user
+	Exists(userID uint, email, name string) (bool, error)
+	// Create stores the entity to the repository
+	Create(breach *model.EmailBreach) (*model.EmailBreach, error)
+	// DeleteByUserID removes the breaches of the user from the store
+	DeleteByUserID(userID uint) error
+}
+
+// AuditRepository interface is the common interface for a repository
+// Each method checks the entity type.
+type AuditRepository interface {
+	// Create stores the entity to the repository
+	Create(event *model.AuditEvent) (*model.AuditEvent, error)
+	// FindAll finds the page of the events matching the filters and the total count
+	FindAll(opts *model.ListOptions, period model.AuditPeriod) ([]model.AuditEvent, int64, error)
+	// FindByAction finds the times, users and IPs of every event of the action in the period
+	FindByAction(action string, period model.AuditPeriod) ([]model.AuditEvent, error)
+}
+
+// TombstoneRepository interface is the common interface for a repository
+// Each method checks the entity type.
+type TombstoneRepository interface {
+	// FindSince returns the tombstones of the items deleted since the given time.
+	FindSince(since time.Time, schema string) ([]model.Tombstone, error)
+}
+
+// VaultRepository interface is the common interface for a repository
+// Each method checks the entity type.
+type VaultRepository interface {
+	// State returns the row counts and last change times of the vault tables.
+	State(schema string) ([]model.VaultTableState, error)
+	// Revision returns the revision counter of the vault.
+	Revision(schema string) (*model.VaultRevision, error)
+	// BumpRevision increases the revision counter of the vault and returns the new value.
+	BumpRevision(schema string) (uint64, error)
+}
+
+// JobRepository interface is the common interface for a repository
+// Each method checks the entity type.
+type JobRepository interface {
+	// FindByID finds the entity regarding to its ID.
+	FindByID(id uint) (*model.Job, error)
+	// Create stores the entity to the repository
+	Create(job *model.Job) (*model.Job, error)
+	// Update stores the entity to the repository
+	Update(job *model.Job) (*model.Job, error)
+}
+
+// WebhookRepository interface is the common interface for a repository
+// Each method checks the entity type.
+type WebhookRepository interface {
+	// FindByID finds the entity regarding to its ID.
+	FindByID(id uint) (*model.Webhook, error)
+	// FindAll returns the webhooks of the user or the admin webhooks.
+	FindAll(userID uint, admin bool) ([]model.Webhook, error)
+	// FindActive returns the active webhooks receiving the events of the user.
+	FindActive(userID uint) ([]model.Webhook, error)
+	// Create stores the entity to the repository
+	Create(webhook *model.Webhook) (*model.Webhook, error)
+	// Update stores the entity to the repository
+	Update(webhook *model.Webhook) (*model.Webhook, error)
+	// Delete removes the entity and its deliveries from the store
+	Delete(id uint) error
+	// DeleteByUserID removes the webhooks of the user and their deliveries from the store
+	DeleteByUserID(userID uint) error
+	// FindDeliveries finds the page of the deliveries of the webhook and the total count
+	FindDeliveries(webhookID uint, opts *model.ListOptions) ([]model.WebhookDelivery, int64, error)
+	// FindDueDeliveries finds the deliveries in the status whose next attempt is due
+	FindDueDeliveries(until time.Time, status string, limit int) ([]model.WebhookDelivery, error)
+	// CreateDelivery stores the delivery to the repository
+	CreateDelivery(delivery *model.WebhookDelivery) (*model.WebhookDelivery, error)
+	// UpdateDelivery stores the delivery to the repository
+	UpdateDelivery(delivery *model.WebhookDelivery) (*model.WebhookDelivery, error)
+}
+
+// AlertRepository interface is the common interface for a repository
+// Each method checks the entity type.
+type AlertRepository interface {
+	// FindAll finds the page of the alerts matching the filters and the total count
+	FindAll(opts *model.ListOptions) ([]model.SecurityAlert, int64, error)
+	// ExistsSince checks if the user had an alert of the type since the given time
+	ExistsSince(userID uint, alertType string, since time.Time) (bool, error)
+	// Create stores the entity to the repository
+	Create(alert *model.SecurityAlert) (*model.SecurityAlert, error)
+	// DeleteByUserID removes the alerts of the user from the store
+	DeleteByUserID(userID uint) error
+}
+
+// TrustedDeviceRepository interface is the common interface for a repository
+// Each method checks the entity type.
+type TrustedDeviceRepository interface {
+	// FindByUserID finds the devices of the user which aren't expired at the time, the latest first
+	FindByUserID(userID uint, now time.Time) ([]model.TrustedDevice, error)
+	// FindByTokenHash finds the device of the token hash which isn't expired at the time
+	FindByTokenHash(tokenHash string, now time.Time) (*model.TrustedDevice, error)
+	// Create stores the entity to the repository
+	Create(device *model.TrustedDevice) (*model.TrustedDevice, error)
+	// Touch stores the last use of the entity
+	Touch(device *model.TrustedDevice) error
+	// Delete removes the device of the user regarding to its UUID and returns it
+	Delete(userID uint, uuid string) (*model.TrustedDevice, error)
+	// DeleteStale removes the devices of the user expired at the time and the ones of the device hash
+	DeleteStale(userID uint, deviceHash string, now time.Time) error
+	// DeleteByUserID removes the devices of the user from the store
+	DeleteByUserID(userID uint) error
+}
+
+// SettingRepository interface is the common interface for a repository
+// Each method checks the entity type.
+type SettingRepository interface {
+	// All finds all the settings of the instance
+	All() ([]model.Setting, error)
+	// Save stores the settings to the repository at once
+	Save(settings []model.Setting) error
+}
+
+// AnnouncementRepository interface is the common interface for a repository
+// Each method checks the entity type.
+type AnnouncementRepository interface {
+	// All returns all the announcements, the latest first
+	All() ([]model.Announcement, error)
+	// FindActive returns the announcements shown at the time, the latest first
+	FindActive(now time.Time) ([]model.Announcement, error)
 	// FindByID finds the entity regarding to its ID.
-	FindByID(id uint, schema string) (*model.Server, error)
+	FindByID(id uint) (*model.Announcement, error)
+	// Create stores the entity to the repository
+	Create(announcement *model.Announcement) (*model.Announcement, error)
+	// Update stores the entity to the repository
+	Update(announcement *model.Announcement) (*model.Announcement, error)
+	// Delete removes the entity from the store
+	Delete(id uint) error
+}
+
+// OutboxRepository interface is the common interface for a repository
+// Each method checks the entity type.
+type OutboxRepository interface {
+	// FindByID finds the entity regardless of the user.
+	FindByID(id uint) (*model.OutboxEmail, error)
+	// FindAll finds the page of the emails matching the filters and the total count
+	FindAll(opts *model.ListOptions) ([]model.OutboxEmail, int64, error)
+	// FindDue returns the emails with the status whose next attempt is due
+	FindDue(until time.Time, status string, limit int) ([]model.OutboxEmail, error)
+	// Create stores the entity to the repository
+	Create(email *model.OutboxEmail) (*model.OutboxEmail, error)
 	// Update stores the entity to the repository
-	Update(server *model.Server, schema string) (*model.Server, error)
+	Update(email *model.OutboxEmail) (*model.OutboxEmail, error)
+	// Delete removes the entity from the store
+	Delete(id uint) error
+	// Count counts the emails with the status tried at least the given attempts
+	Count(status string, minAttempts int) (int64, error)
+}
+
+// RelationRepository interface is the common interface for a repository
+// Each method checks the entity type.
+type RelationRepository interface {
+	// All returns all the relations of the vault.
+	All(schema string) ([]model.ItemRelation, error)
+	// FindByItem returns the relations the item is a source or target of.
+	FindByItem(itemType string, itemID uint, schema string) ([]model.ItemRelation, error)
+	// FindByID finds the entity regarding to its ID.
+	FindByID(id uint, schema string) (*model.ItemRelation, error)
+	// Create stores the entity to the repository
+	Create(relation *model.ItemRelation, schema string) (*model.ItemRelation, error)
+	// Delete removes the entity from the store
+	Delete(id uint, schema string) error
+	// DeleteByItem removes the relations of the item from the store
+	DeleteByItem(itemType string, itemID uint, schema string) error
+}
+
+// ConflictRepository interface is the common interface for a repository
+// Each method checks the entity type.
+type ConflictRepository interface {
+	// All returns all the conflicts of the vault.
+	All(schema string) ([]model.Conflict, error)
+	// FindByID finds the entity regarding to its ID.
+	FindByID(id uint, schema string) (*model.Conflict, error)
+	// Create stores the entity to the repository
+	Create(conflict *model.Conflict, schema string) (*model.Conflict, error)
+	// Delete removes the entity from the store
+	Delete(id uint, schema string) error
+}
+
+// FolderRepository interface is the common interface for a repository
+// Each method checks the entity type.
+type FolderRepository interface {
+	// All returns all the folders of the vault.
+	All(schema string) ([]model.Folder, error)
+	// FindUpdatedSince returns the folders created or updated since the given time.
+	FindUpdatedSince(since time.Time, schema string) ([]model.Folder, error)
+	// FindByID finds the entity regarding to its ID.
+	FindByID(id uint, schema string) (*model.Folder, error)
+	// FindByName finds the folder with the name under the parent folder.
+	FindByName(name string, parentID *uint, schema string) (*model.Folder, error)
 	// Create stores the entity to the repository
-	Create(server *model.Server, schema string) (*model.Server, error)
+	Create(folder *model.Folder, schema string) (*model.Folder, error)
+	// Update stores the entity to the repository
+	Update(folder *model.Folder, schema string) (*model.Folder, error)
 	// Delete removes the entity from the store
 	Delete(id uint, schema string) error
-	// Migrate migrates the repository
-	Migrate(schema string) error
+}
+
+// MigrationRepository interface is the common interface for a repository
+// Each method checks the entity type.
+type MigrationRepository interface {
+	// Up applies the pending migrations of the set to the schema.
+	Up(set, schema string) error
+	// Down reverts the given number of the last migrations of the set applied to the schema.
+	Down(set, schema string, steps int) error
+	// Status returns the applied, pending and unknown versions of the set in the schema.
+	Status(set, schema string) (*model.MigrationStatus, error)
+}
+
+// TrashRepository is the repository of the deleted records kept until they are
+// purged. Schema is the vault of the vault tables and ignored for the others.
+type TrashRepository interface {
+	// FindDeleted returns the records of the table deleted before the time, the latest first.
+	FindDeleted(table, schema string, before time.Time) ([]model.DeletedRecord, error)
+	// Restore brings the deleted record of the table back.
+	Restore(table, schema string, id uint) error
+	// Purge removes the deleted records of the table for good.
+	Purge(table, schema string, ids []uint) error
+}
+
+// DataKeyRepository is the repository of the wrapped data keys of the vaults
+type DataKeyRepository interface {
+	// All returns all the data keys of the store.
+	All() ([]model.DataKey, error)
+	// FindBySchema finds the data key of the vault of the schema with the highest version.
+	FindBySchema(schema string) (*model.DataKey, error)
+	// FindByKeyID finds the data key regarding to its key ID.
+	FindByKeyID(keyID string) (*model.DataKey, error)
+	// Create stores the entity to the repository
+	Create(key *model.DataKey) (*model.DataKey, error)
+	// Rewrap stores the key wrapped by another master key.
+	Rewrap(key *model.DataKey) error
+	// MarkReencrypted records the fields of the vault of the schema are moved to its key.
+	MarkReencrypted(schema string) error
+	// Delete removes the data keys of the schema.
+	Delete(schema string) error
+	// DeleteBefore removes the data keys of the schema older than the version.
+	DeleteBefore(schema string, version int) error
 }
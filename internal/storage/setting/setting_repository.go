@@ -0,0 +1,41 @@
+package setting
+
+import (
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Repository ...
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository ...
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// All ...
+func (p *Repository) All() ([]model.Setting, error) {
+	settings := []model.Setting{}
+	err := p.db.Find(&settings).Error
+	if err != nil {
+		logger.Errorf("Error getting settings error %v", err)
+		return nil, err
+	}
+	return settings, nil
+}
+
+// Save ...
+func (p *Repository) Save(settings []model.Setting) error {
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		for i := range settings {
+			if err := tx.Save(&settings[i]).Error; err != nil {
+				logger.Errorf("Error saving setting %v error %v", settings[i].Key, err)
+				return err
+			}
+		}
+		return nil
+	})
+}
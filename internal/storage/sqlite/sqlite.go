@@ -0,0 +1,200 @@
+package sqlite
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// Dialect is the name of the gorm dialector of SQLite databases
+const Dialect = "sqlite"
+
+// timeLayouts are the formats of the times written by the driver and CURRENT_TIMESTAMP
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05",
+}
+
+// schemaName matches the names of the schemas, they are used as database names in SQL
+var schemaName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+type database struct {
+	Seq  int
+	Name string
+	File string
+}
+
+// Is reports whether the connection is a SQLite database
+func Is(db *gorm.DB) bool {
+	return db.Dialector.Name() == Dialect
+}
+
+// Open opens the SQLite database file and attaches the schema files next to it.
+// SQLite has no schemas, every user and organization schema is a separate
+// database file attached with the schema name, so schema.table queries work the
+// same as Postgres.
+func Open(path string, config *gorm.Config) (*gorm.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(sqlite.Open(path+"?_pragma=busy_timeout(5000)"), config)
+	if err != nil {
+		return nil, err
+	}
+
+	// INSERT builder of the driver drops the schema of schema.table names, so
+	// rows would go to the first attached database having the table. Default
+	// builder of gorm keeps it.
+	delete(db.ClauseBuilders, "INSERT")
+
+	// Attached databases belong to the connection, a single connection keeps
+	// them visible to all the queries. SQLite allows one writer anyway.
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	files, err := filepath.Glob(SchemaFile(path, "*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		schema, ok := FileSchema(path, file)
+		if !ok {
+			continue
+		}
+		if err := Attach(db, schema); err != nil {
+			return nil, err
+		}
+	}
+
+	return db, nil
+}
+
+// Attach creates the database file of the schema if it doesn't exist and attaches it
+func Attach(db *gorm.DB, schema string) error {
+	if !schemaName.MatchString(schema) {
+		return fmt.Errorf("invalid schema name %q", schema)
+	}
+
+	databases, err := list(db)
+	if err != nil {
+		return err
+	}
+	var mainFile string
+	for _, d := range databases {
+		if d.Name == schema {
+			return nil
+		}
+		if d.Name == "main" {
+			mainFile = d.File
+		}
+	}
+	if mainFile == "" {
+		return fmt.Errorf("schema %q can't be attached to an in-memory database", schema)
+	}
+
+	return db.Exec("ATTACH DATABASE ? AS "+schema, SchemaFile(mainFile, schema)).Error
+}
+
+// Detach detaches the database of the schema and removes its file
+func Detach(db *gorm.DB, schema string) error {
+	databases, err := list(db)
+	if err != nil {
+		return err
+	}
+	for _, d := range databases {
+		if d.Name != schema || schema == "main" || schema == "temp" {
+			continue
+		}
+		if err := db.Exec("DETACH DATABASE " + schema).Error; err != nil {
+			return err
+		}
+		if err := os.Remove(d.File); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Schemas returns the names of the attached schemas
+func Schemas(db *gorm.DB) ([]string, error) {
+	databases, err := list(db)
+	if err != nil {
+		return nil, err
+	}
+	schemas := []string{}
+	for _, d := range databases {
+		if d.Name != "main" && d.Name != "temp" {
+			schemas = append(schemas, d.Name)
+		}
+	}
+	return schemas, nil
+}
+
+// Size returns the bytes of the main database file and the files of the
+// attached schemas, in-memory databases have no size
+func Size(db *gorm.DB) (int64, error) {
+	databases, err := list(db)
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	for _, d := range databases {
+		if d.File == "" {
+			continue
+		}
+		info, err := os.Stat(d.File)
+		if err != nil {
+			return 0, err
+		}
+		size += info.Size()
+	}
+	return size, nil
+}
+
+// SchemaFile returns the file of the schema database, passwall.db keeps the
+// user1 schema in passwall-user1.db
+func SchemaFile(mainFile, schema string) string {
+	ext := filepath.Ext(mainFile)
+	return strings.TrimSuffix(mainFile, ext) + "-" + schema + ext
+}
+
+// FileSchema returns the schema of the database file next to the main database
+func FileSchema(mainFile, file string) (string, bool) {
+	ext := filepath.Ext(mainFile)
+	prefix := strings.TrimSuffix(mainFile, ext) + "-"
+	if !strings.HasPrefix(file, prefix) || !strings.HasSuffix(file, ext) {
+		return "", false
+	}
+	schema := strings.TrimSuffix(strings.TrimPrefix(file, prefix), ext)
+	return schema, schemaName.MatchString(schema)
+}
+
+// ParseTime parses the times SQLite returns as text, like the results of
+// aggregates which have no column type
+func ParseTime(value string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
+
+func list(db *gorm.DB) ([]database, error) {
+	databases := []database{}
+	err := db.Raw("PRAGMA database_list").Scan(&databases).Error
+	return databases, err
+}
@@ -0,0 +1,51 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaFile(t *testing.T) {
+	assert.Equal(t, "store/passwall-user1.db", SchemaFile("store/passwall.db", "user1"))
+	assert.Equal(t, "/data/vault-org2", SchemaFile("/data/vault", "org2"))
+
+	tests := []struct {
+		file   string
+		schema string
+		ok     bool
+	}{
+		{"store/passwall-user1.db", "user1", true},
+		{"store/passwall-org12.db", "org12", true},
+		{"store/passwall.db", "", false},
+		{"store/other-user1.db", "", false},
+		{"store/passwall-user1.db-journal", "", false},
+		{"store/passwall-user 1.db", "user 1", false},
+	}
+	for _, tt := range tests {
+		schema, ok := FileSchema("store/passwall.db", tt.file)
+		assert.Equal(t, tt.ok, ok, tt.file)
+		if tt.ok {
+			assert.Equal(t, tt.schema, schema)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	want := time.Date(2024, 3, 1, 10, 20, 30, 0, time.UTC)
+
+	for _, value := range []string{
+		"2024-03-01 10:20:30",
+		"2024-03-01 10:20:30+00:00",
+		"2024-03-01T10:20:30+00:00",
+		"2024-03-01 12:20:30+02:00",
+	} {
+		got, err := ParseTime(value)
+		assert.NoError(t, err, value)
+		assert.True(t, want.Equal(got), value)
+	}
+
+	_, err := ParseTime("yesterday")
+	assert.Error(t, err)
+}
@@ -1,5 +1,10 @@
 package storage
 
+import (
+	"database/sql"
+	"time"
+)
+
 // Store is the minimal interface for the various repositories
 type Store interface {
 	Logins() LoginRepository
@@ -10,5 +15,41 @@ type Store interface {
 	Tokens() TokenRepository
 	Users() UserRepository
 	Servers() ServerRepository
+	Organizations() OrganizationRepository
+	Collections() CollectionRepository
+	Breaches() BreachRepository
+	Relations() RelationRepository
+	Folders() FolderRepository
+	AuditEvents() AuditRepository
+	Jobs() JobRepository
+	Webhooks() WebhookRepository
+	Alerts() AlertRepository
+	TrustedDevices() TrustedDeviceRepository
+	Settings() SettingRepository
+	Announcements() AnnouncementRepository
+	Outbox() OutboxRepository
+	Tombstones() TombstoneRepository
+	Vaults() VaultRepository
+	Conflicts() ConflictRepository
+	Migrations() MigrationRepository
+	Trash() TrashRepository
+	DataKeys() DataKeyRepository
+	Region(name string) (Store, error)
+	RegionNames() []string
 	Ping() error
+	// PoolStats returns the stats of the connection pool of the database
+	PoolStats() (sql.DBStats, error)
+	// ReadLag returns how far behind the primary the list, search and sync
+	// reads may be, zero without a replica
+	ReadLag() time.Duration
+	Size() (int64, error)
+	// WithTransaction runs fn with a store whose repositories use one
+	// transaction, it is committed when fn returns nil and rolled back otherwise
+	WithTransaction(fn func(Store) error) error
+	// As returns the store whose created and updated vault rows are
+	// attributed to the principal, like user:1
+	As(principal string) Store
+	// CopyToRows copies the vaults of the schemas to the shared tables of row
+	// tenancy and returns the number of the vaults which changed since their last copy
+	CopyToRows(schemas []string) (int, error)
 }
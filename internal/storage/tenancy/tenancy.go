@@ -0,0 +1,118 @@
+package tenancy
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Modes of keeping the vaults apart
+const (
+	// ModeSchema keeps every vault in its own Postgres schema or SQLite file
+	ModeSchema = "schema"
+	// ModeRow keeps all the vaults in the shared tables of the vaults schema,
+	// rows have the schema name of their vault in the vault column
+	ModeRow = "row"
+)
+
+// Schema is the schema of the shared vault tables of row tenancy
+const Schema = "vaults"
+
+// Column is the column of the shared vault tables telling the vault of the row
+const Column = "vault"
+
+// vaultKey is the statement setting of the vault the created rows belong to
+const vaultKey = "tenancy:vault"
+
+// Tables are the tables of a vault
+var Tables = []string{
+	"folders",
+	"logins",
+	"login_histories",
+	"credit_cards",
+	"bank_accounts",
+	"notes",
+	"emails",
+	"servers",
+	"item_relations",
+	"tombstones",
+	"conflicts",
+	"vault_revisions",
+}
+
+// rows is true when the vaults are kept in row tenancy. It is set once at
+// startup, all the databases of the server use the same mode.
+var rows bool
+
+// SetMode sets the tenancy mode of the vaults, empty is schema tenancy
+func SetMode(mode string) error {
+	switch mode {
+	case "", ModeSchema:
+		rows = false
+	case ModeRow:
+		rows = true
+	default:
+		return fmt.Errorf("unknown tenancy mode %q, use %s or %s", mode, ModeSchema, ModeRow)
+	}
+	return nil
+}
+
+// Rows reports if the vaults are kept in row tenancy
+func Rows() bool {
+	return rows
+}
+
+// Table returns the query of the table of the vault of the schema. In row
+// tenancy it is the shared table limited to the rows of the vault, rows
+// created with it get the vault too.
+func Table(db *gorm.DB, schema, table string) *gorm.DB {
+	if !rows {
+		return db.Table(schema + "." + table)
+	}
+	return db.Table(Schema+"."+table).Set(vaultKey, schema).Where(Column+" = ?", schema)
+}
+
+// From returns the FROM clause of the raw queries on the table of the vault
+func From(schema, table string) string {
+	if !rows {
+		return schema + "." + table
+	}
+	return fmt.Sprintf("%s.%s WHERE %s = '%s'", Schema, table, Column, schema)
+}
+
+// DeleteVault removes the rows of the vault of the schema from the shared tables
+func DeleteVault(db *gorm.DB, schema string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range Tables {
+			if err := tx.Exec("DELETE FROM "+Schema+"."+table+" WHERE "+Column+" = ?", schema).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Register makes the inserts of the queries of Table set the vault of the
+// rows. It is called once for every connection of the server.
+func Register(db *gorm.DB) {
+	db.ClauseBuilders["VALUES"] = buildValues
+}
+
+// buildValues builds the VALUES clause with the vault column added when the
+// statement has a vault
+func buildValues(c clause.Clause, builder clause.Builder) {
+	if stmt, ok := builder.(*gorm.Statement); ok {
+		vault, hasVault := stmt.Get(vaultKey)
+		values, isValues := c.Expression.(clause.Values)
+		if hasVault && isValues && len(values.Columns) > 0 {
+			columns := append(append([]clause.Column{}, values.Columns...), clause.Column{Name: Column})
+			rows := make([][]interface{}, len(values.Values))
+			for i, row := range values.Values {
+				rows[i] = append(append([]interface{}{}, row...), vault)
+			}
+			c.Expression = clause.Values{Columns: columns, Values: rows}
+		}
+	}
+	c.Build(builder)
+}
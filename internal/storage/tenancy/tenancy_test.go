@@ -0,0 +1,47 @@
+package tenancy
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/passwall/passwall-server/internal/storage/sqlite"
+	"github.com/passwall/passwall-server/model"
+)
+
+func TestSetMode(t *testing.T) {
+	defer SetMode(ModeSchema)
+
+	require.NoError(t, SetMode(ModeRow))
+	assert.True(t, Rows())
+	require.NoError(t, SetMode(""))
+	assert.False(t, Rows())
+	assert.Error(t, SetMode("database"))
+}
+
+func TestTable(t *testing.T) {
+	defer SetMode(ModeSchema)
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "passwall.db"), &gorm.Config{DryRun: true})
+	require.NoError(t, err)
+	Register(db)
+
+	stmt := Table(db, "user1", "notes").Create(&model.Note{Title: "Wifi"}).Statement
+	assert.Contains(t, stmt.SQL.String(), "`user1`.`notes`")
+	assert.NotContains(t, stmt.SQL.String(), Column)
+	assert.Equal(t, "user1.tombstones", From("user1", "tombstones"))
+
+	// Rows of the shared tables are limited to the vault and created in it
+	require.NoError(t, SetMode(ModeRow))
+	stmt = Table(db, "user1", "notes").Create(&model.Note{Title: "Wifi"}).Statement
+	assert.Contains(t, stmt.SQL.String(), "`vaults`.`notes`")
+	assert.Contains(t, stmt.SQL.String(), "`vault`")
+	assert.Equal(t, "user1", stmt.Vars[len(stmt.Vars)-1])
+
+	stmt = Table(db, "user1", "notes").Where("id = ?", 1).Find(&[]model.Note{}).Statement
+	assert.Contains(t, stmt.SQL.String(), "vault = ?")
+	assert.Contains(t, stmt.Vars, "user1")
+	assert.Equal(t, "vaults.tombstones WHERE vault = 'user1'", From("user1", "tombstones"))
+}
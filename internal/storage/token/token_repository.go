@@ -25,6 +25,13 @@ func (p *Repository) FindByUUID(uuid string) (model.Token, error) {
 	return token, err
 }
 
+// FindByUserID finds the tokens of the user
+func (p *Repository) FindByUserID(userid int) ([]model.Token, error) {
+	tokens := []model.Token{}
+	err := p.db.Where("user_id = ?", userid).Find(&tokens).Error
+	return tokens, err
+}
+
 // Create creates model to database
 func (p *Repository) Create(userid int, uid uuid.UUID, tkn string, expriydate time.Time) {
 
@@ -48,7 +55,11 @@ func (p *Repository) DeleteByUUID(uuid string) {
 	p.db.Delete(model.Token{}, "uuid = ?", uuid)
 }
 
-// Migrate ...
-func (p *Repository) Migrate() error {
-	return p.db.AutoMigrate(&model.Token{})
+// CountActive counts the tokens which aren't expired and their users
+func (p *Repository) CountActive(now time.Time) (tokens, users int64, err error) {
+	if err = p.db.Model(&model.Token{}).Where("expiry_time > ?", now).Count(&tokens).Error; err != nil {
+		return 0, 0, err
+	}
+	err = p.db.Model(&model.Token{}).Where("expiry_time > ?", now).Distinct("user_id").Count(&users).Error
+	return tokens, users, err
 }
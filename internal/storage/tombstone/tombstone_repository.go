@@ -0,0 +1,42 @@
+package tombstone
+
+import (
+	"time"
+
+	"github.com/passwall/passwall-server/internal/storage/query"
+	"github.com/passwall/passwall-server/internal/storage/tenancy"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Repository ...
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository ...
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Record leaves a tombstone for the deleted item. It is called by the item
+// repositories in the transaction deleting the item.
+func Record(tx *gorm.DB, schema, itemType string, itemID uint) error {
+	err := tenancy.Table(tx, schema, "tombstones").Create(&model.Tombstone{ItemType: itemType, ItemID: itemID}).Error
+	if err != nil {
+		logger.Errorf("Error recording tombstone of %v %v error %v", itemType, itemID, err)
+	}
+	return err
+}
+
+// FindSince ...
+func (p *Repository) FindSince(since time.Time, schema string) ([]model.Tombstone, error) {
+	tombstones := []model.Tombstone{}
+	err := tenancy.Table(query.Reads(p.db), schema, "tombstones").Where("created_at >= ?", since).Order("id asc").Find(&tombstones).Error
+	if err != nil {
+		logger.Errorf("Error getting tombstones since %v error %v", since, err)
+		return nil, err
+	}
+	return tombstones, nil
+}
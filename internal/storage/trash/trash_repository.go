@@ -0,0 +1,172 @@
+package trash
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/passwall/passwall-server/internal/storage/tenancy"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUnknownTable represents message for the tables without deleted records
+	ErrUnknownTable = errors.New("table doesn't keep deleted records")
+	// ErrNotDeleted represents message for restoring records which aren't deleted
+	ErrNotDeleted = errors.New("record isn't deleted")
+)
+
+// child is a table whose rows belong to the records of another one, they are
+// purged with them
+type child struct {
+	table, column string
+}
+
+// table is a table keeping its deleted records until they are purged
+type table struct {
+	model func() interface{}
+	// vault tables are in the schemas of the vaults
+	vault bool
+	// name is the column naming the records, vault records have none
+	name string
+	// columns are the ones the records are purged with
+	columns string
+	// itemType is the type of the tombstones of the vault items
+	itemType string
+	// revision is true for the vault items synced by their revision
+	revision bool
+	children []child
+}
+
+var tables = map[string]table{
+	"users":         {model: func() interface{} { return &model.User{} }, name: "email", columns: "schema"},
+	"organizations": {model: func() interface{} { return &model.Organization{} }, name: "name", columns: "schema, region", children: []child{{"organization_members", "organization_id"}}},
+	"collections":   {model: func() interface{} { return &model.Collection{} }, name: "name", children: []child{{"collection_items", "collection_id"}, {"collection_members", "collection_id"}}},
+	"webhooks":      {model: func() interface{} { return &model.Webhook{} }, name: "url"},
+	"announcements": {model: func() interface{} { return &model.Announcement{} }, name: "message"},
+	"folders":       {model: func() interface{} { return &model.Folder{} }, vault: true, itemType: "folder"},
+	"logins":        {model: func() interface{} { return &model.Login{} }, vault: true, itemType: "login", revision: true},
+	"credit_cards":  {model: func() interface{} { return &model.CreditCard{} }, vault: true, itemType: "credit_card", revision: true},
+	"bank_accounts": {model: func() interface{} { return &model.BankAccount{} }, vault: true, itemType: "bank_account", revision: true},
+	"notes":         {model: func() interface{} { return &model.Note{} }, vault: true, itemType: "note", revision: true},
+	"emails":        {model: func() interface{} { return &model.Email{} }, vault: true, itemType: "email", revision: true},
+	"servers":       {model: func() interface{} { return &model.Server{} }, vault: true, itemType: "server", revision: true},
+}
+
+// Tables returns the tables keeping their deleted records in order
+func Tables() []string {
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsVault reports if the table is in the schemas of the vaults
+func IsVault(name string) bool {
+	return tables[name].vault
+}
+
+// Repository ...
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository ...
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// FindDeleted ...
+func (p *Repository) FindDeleted(name, schema string, before time.Time) ([]model.DeletedRecord, error) {
+	db, t, err := query(p.db, name, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := "id, deleted_at"
+	if t.name != "" {
+		columns += ", " + t.name + " AS name"
+	}
+	if t.columns != "" {
+		columns += ", " + t.columns
+	}
+	records := []model.DeletedRecord{}
+	err = db.Select(columns).Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Order("deleted_at desc").Scan(&records).Error
+	if err != nil {
+		logger.Errorf("Error finding deleted %s error %v", name, err)
+		return nil, err
+	}
+	return records, nil
+}
+
+// Restore brings the deleted record back. Vault items come back as changed, so
+// the sync clients get them again, and their tombstones are removed.
+func (p *Repository) Restore(name, schema string, id uint) error {
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		db, t, err := query(tx, name, schema)
+		if err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{"deleted_at": nil, "updated_at": time.Now()}
+		if t.revision {
+			updates["revision"] = gorm.Expr("revision + 1")
+		}
+		result := db.Where("id = ? AND deleted_at IS NOT NULL", id).Updates(updates)
+		if result.Error != nil {
+			logger.Errorf("Error restoring %s %v error %v", name, id, result.Error)
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotDeleted
+		}
+
+		if t.itemType == "" {
+			return nil
+		}
+		return tenancy.Table(tx, schema, "tombstones").
+			Where("item_type = ? AND item_id = ?", t.itemType, id).Delete(&model.Tombstone{}).Error
+	})
+}
+
+// Purge removes the deleted records and the rows belonging to them for good
+func (p *Repository) Purge(name, schema string, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		db, t, err := query(tx, name, schema)
+		if err != nil {
+			return err
+		}
+
+		for _, c := range t.children {
+			if err := tx.Exec("DELETE FROM "+c.table+" WHERE "+c.column+" IN ?", ids).Error; err != nil {
+				logger.Errorf("Error purging %s of %s error %v", c.table, name, err)
+				return err
+			}
+		}
+		err = db.Unscoped().Where("id IN ? AND deleted_at IS NOT NULL", ids).Delete(t.model()).Error
+		if err != nil {
+			logger.Errorf("Error purging %s error %v", name, err)
+		}
+		return err
+	})
+}
+
+// query returns the query of the table, vault tables are the ones of the schema
+func query(db *gorm.DB, name, schema string) (*gorm.DB, table, error) {
+	t, ok := tables[name]
+	if !ok {
+		return nil, t, ErrUnknownTable
+	}
+	if t.vault {
+		return tenancy.Table(db, schema, name), t, nil
+	}
+	return db.Table(name), t, nil
+}
@@ -0,0 +1,92 @@
+package trash
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/passwall/passwall-server/internal/storage/sqlite"
+	"github.com/passwall/passwall-server/model"
+)
+
+func openStore(t *testing.T) *gorm.DB {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "passwall.db"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Exec(`CREATE TABLE collections (
+		id integer PRIMARY KEY AUTOINCREMENT, created_at datetime, updated_at datetime, deleted_at datetime,
+		name text, organization_id bigint, max_password_age integer, version bigint NOT NULL DEFAULT 1)`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE collection_items (
+		id integer PRIMARY KEY AUTOINCREMENT, created_at datetime, collection_id bigint, item_type text, item_id bigint)`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE collection_members (
+		id integer PRIMARY KEY AUTOINCREMENT, created_at datetime, collection_id bigint, user_id bigint)`).Error)
+	require.NoError(t, sqlite.Attach(db, "user1"))
+	require.NoError(t, db.Exec(`CREATE TABLE user1.notes (
+		id integer PRIMARY KEY AUTOINCREMENT, created_at datetime, updated_at datetime, deleted_at datetime,
+		title text, note text, folder_id bigint, revision bigint NOT NULL DEFAULT 1,
+		created_by text NOT NULL DEFAULT '', updated_by text NOT NULL DEFAULT '')`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE user1.tombstones (
+		id integer PRIMARY KEY AUTOINCREMENT, created_at datetime, item_type text, item_id bigint)`).Error)
+	return db
+}
+
+func TestRestore(t *testing.T) {
+	db := openStore(t)
+	repo := NewRepository(db)
+
+	note := &model.Note{Title: "Wifi"}
+	require.NoError(t, db.Table("user1.notes").Create(note).Error)
+	require.NoError(t, db.Table("user1.notes").Delete(note).Error)
+	require.NoError(t, db.Table("user1.tombstones").Create(&model.Tombstone{ItemType: "note", ItemID: note.ID}).Error)
+
+	// Deleted records are hidden from the queries but kept
+	var count int64
+	require.NoError(t, db.Table("user1.notes").Model(&model.Note{}).Count(&count).Error)
+	assert.Zero(t, count)
+	deleted, err := repo.FindDeleted("notes", "user1", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, deleted, 1)
+	assert.Equal(t, note.ID, deleted[0].ID)
+
+	require.NoError(t, repo.Restore("notes", "user1", note.ID))
+	restored := &model.Note{}
+	require.NoError(t, db.Table("user1.notes").First(restored, note.ID).Error)
+	assert.Equal(t, uint(2), restored.Revision)
+	require.NoError(t, db.Table("user1.tombstones").Count(&count).Error)
+	assert.Zero(t, count)
+
+	assert.ErrorIs(t, repo.Restore("notes", "user1", note.ID), ErrNotDeleted)
+	assert.ErrorIs(t, repo.Restore("tokens", "", 1), ErrUnknownTable)
+}
+
+func TestPurge(t *testing.T) {
+	db := openStore(t)
+	repo := NewRepository(db)
+
+	kept := &model.Collection{Name: "Team"}
+	deleted := &model.Collection{Name: "Old"}
+	require.NoError(t, db.Create(kept).Error)
+	require.NoError(t, db.Create(deleted).Error)
+	require.NoError(t, db.Create(&model.CollectionItem{CollectionID: deleted.ID, ItemType: "login", ItemID: 1}).Error)
+	require.NoError(t, db.Delete(deleted).Error)
+
+	// Records deleted after the retention start aren't found
+	records, err := repo.FindDeleted("collections", "", time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, records)
+	records, err = repo.FindDeleted("collections", "", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "Old", records[0].Name)
+
+	// Only deleted records are purged, with their children
+	require.NoError(t, repo.Purge("collections", "", []uint{kept.ID, deleted.ID}))
+	var count int64
+	require.NoError(t, db.Unscoped().Model(&model.Collection{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+	require.NoError(t, db.Model(&model.CollectionItem{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,130 @@
+package user
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/cache"
+	"github.com/passwall/passwall-server/pkg/logger"
+)
+
+// CachedRepository keeps the users of the auth lookups in the cache for the
+// TTL, so every request doesn't find its user in the database. The changes
+// made through it remove the changed user from the cache.
+type CachedRepository struct {
+	*Repository
+	cache     cache.Cache
+	ttl       time.Duration
+	evictions *Evictions
+}
+
+// NewCachedRepository ...
+func NewCachedRepository(repo *Repository, c cache.Cache, ttl time.Duration) *CachedRepository {
+	return &CachedRepository{Repository: repo, cache: c, ttl: ttl}
+}
+
+// Evictions are the users changed in a transaction. They are removed from the
+// cache once it commits, the lookups before that would cache them again.
+type Evictions struct {
+	mu    sync.Mutex
+	uuids []string
+}
+
+// Forget removes the users changed in the transaction from the cache
+func (e *Evictions) Forget(c cache.Cache) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, uuid := range e.uuids {
+		if err := c.Delete(cacheKey(uuid)); err != nil {
+			logger.Errorf("Error removing cached user %v error %v", uuid, err)
+		}
+	}
+	e.uuids = nil
+}
+
+// DeferEvictions makes the changes of the repository collect the users in the
+// evictions instead of removing them from the cache right away
+func (p *CachedRepository) DeferEvictions(e *Evictions) *CachedRepository {
+	p.evictions = e
+	return p
+}
+
+// cachedUser is the user kept in the cache with the fields the JSON of the
+// user hides but the auth lookups check
+type cachedUser struct {
+	*model.User
+	TokenEpoch int `json:"token_epoch"`
+}
+
+// cacheKey is the key of the cached user of the UUID
+func cacheKey(uuid string) string {
+	return "auth-user:" + uuid
+}
+
+// FindCachedByUUID finds the user in the cache first. Cached users are kept
+// without their master password, secret and confirmation code, they are only
+// for checking the access of the requests and never saved back.
+func (p *CachedRepository) FindCachedByUUID(uuid string) (*model.User, error) {
+	// The database is still there when the cache isn't reachable
+	if value, found, err := p.cache.Get(cacheKey(uuid)); err == nil && found {
+		cached := cachedUser{User: &model.User{}}
+		if err := json.Unmarshal([]byte(value), &cached); err == nil {
+			cached.User.TokenEpoch = cached.TokenEpoch
+			return cached.User, nil
+		}
+	}
+
+	user, err := p.Repository.FindByUUID(uuid)
+	if err != nil {
+		return nil, err
+	}
+	user.MasterPassword, user.Secret, user.ConfirmationCode = "", "", ""
+	value, err := json.Marshal(cachedUser{User: user, TokenEpoch: user.TokenEpoch})
+	if err == nil {
+		err = p.cache.Set(cacheKey(uuid), string(value), p.ttl)
+	}
+	if err != nil {
+		logger.Errorf("Error caching user %v error %v", uuid, err)
+	}
+	return user, nil
+}
+
+// Update ...
+func (p *CachedRepository) Update(user *model.User) (*model.User, error) {
+	user, err := p.Repository.Update(user)
+	if err != nil {
+		return nil, err
+	}
+	p.forget(user.UUID.String())
+	return user, nil
+}
+
+// Delete ...
+func (p *CachedRepository) Delete(id uint, schema string) error {
+	user, err := p.Repository.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if err := p.Repository.Delete(id, schema); err != nil {
+		return err
+	}
+	p.forget(user.UUID.String())
+	return nil
+}
+
+// forget removes the user from the cache, it is found in the database again
+// when the cache can't remove it until the TTL passes. In a transaction it is
+// removed once the transaction commits.
+func (p *CachedRepository) forget(uuid string) {
+	if p.evictions != nil {
+		p.evictions.mu.Lock()
+		p.evictions.uuids = append(p.evictions.uuids, uuid)
+		p.evictions.mu.Unlock()
+		return
+	}
+	if err := p.cache.Delete(cacheKey(uuid)); err != nil {
+		logger.Errorf("Error removing cached user %v error %v", uuid, err)
+	}
+}
@@ -1,6 +1,11 @@
 package user
 
 import (
+	"time"
+
+	"github.com/passwall/passwall-server/internal/storage/query"
+	"github.com/passwall/passwall-server/internal/storage/sqlite"
+	"github.com/passwall/passwall-server/internal/storage/tenancy"
 	"github.com/passwall/passwall-server/model"
 	"github.com/passwall/passwall-server/pkg/logger"
 	"golang.org/x/crypto/bcrypt"
@@ -71,6 +76,11 @@ func (p *Repository) FindByUUID(uuid string) (*model.User, error) {
 	return user, err
 }
 
+// FindCachedByUUID finds the user in the database, CachedRepository caches it
+func (p *Repository) FindCachedByUUID(uuid string) (*model.User, error) {
+	return p.FindByUUID(uuid)
+}
+
 // FindByEmail ...
 func (p *Repository) FindByEmail(email string) (*model.User, error) {
 	user := new(model.User)
@@ -78,6 +88,24 @@ func (p *Repository) FindByEmail(email string) (*model.User, error) {
 	return user, err
 }
 
+// FindByBillingEmail ...
+func (p *Repository) FindByBillingEmail(email string) (*model.User, error) {
+	user := new(model.User)
+	err := p.db.Where(`billing_email = ?`, email).First(&user).Error
+	return user, err
+}
+
+// FindScheduledForDeletion finds the users whose deletion is scheduled until the given time
+func (p *Repository) FindScheduledForDeletion(until time.Time) ([]model.User, error) {
+	users := []model.User{}
+	err := p.db.Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", until).Find(&users).Error
+	if err != nil {
+		logger.Errorf("Error finding users scheduled for deletion error %v", err)
+		return nil, err
+	}
+	return users, err
+}
+
 // FindByCredentials ...
 func (p *Repository) FindByCredentials(email, masterPassword string) (*model.User, error) {
 	user := new(model.User)
@@ -106,9 +134,9 @@ func (p *Repository) Save(user *model.User) (*model.User, error) {
 	return user, nil
 }
 
-// Update ...
+// Update saves the user if its version is still the one it was read with
 func (p *Repository) Update(user *model.User) (*model.User, error) {
-	err := p.db.Save(&user).Error
+	err := query.SaveVersion(p.db, user, user.ID, &user.Version)
 	if err != nil {
 		logger.Errorf("Error saving user %v error %v", user, err)
 		return nil, err
@@ -128,31 +156,49 @@ func (p *Repository) Create(user *model.User) (*model.User, error) {
 	return user, nil
 }
 
-// Delete ...
+// Delete marks the user deleted, the vault of the schema is kept for the admins
+// to restore the user until the purge job drops it
 func (p *Repository) Delete(id uint, schema string) error {
-
-	err := p.db.Exec("DROP SCHEMA " + schema + " CASCADE").Error
+	err := p.db.Delete(&model.User{ID: id}).Error
 	if err != nil {
-		logger.Errorf("Error deleting schema %s error %v", schema, err)
+		logger.Errorf("Error deleting user %v of schema %s error %v", id, schema, err)
 	}
-
-	err = p.db.Delete(&model.User{ID: id}).Error
 	return err
 }
 
-// Migrate ...
-func (p *Repository) Migrate() error {
-	return p.db.AutoMigrate(&model.User{})
-}
-
 // CreateSchema ...
 func (p *Repository) CreateSchema(schema string) error {
+	// Vaults are the rows of the shared tables in row tenancy
+	if tenancy.Rows() && schema != tenancy.Schema {
+		return nil
+	}
+
 	var err error
 	if schema != "" && schema != "public" {
-		err := p.db.Exec("CREATE SCHEMA IF NOT EXISTS " + schema).Error
+		if sqlite.Is(p.db) {
+			err = sqlite.Attach(p.db, schema)
+		} else {
+			err = p.db.Exec("CREATE SCHEMA IF NOT EXISTS " + schema).Error
+		}
 		if err != nil {
 			logger.Errorf("Error creating schema %s error %v", schema, err)
 		}
 	}
 	return err
 }
+
+// DropSchema ...
+func (p *Repository) DropSchema(schema string) error {
+	var err error
+	if tenancy.Rows() {
+		err = tenancy.DeleteVault(p.db, schema)
+	} else if sqlite.Is(p.db) {
+		err = sqlite.Detach(p.db, schema)
+	} else {
+		err = p.db.Exec("DROP SCHEMA IF EXISTS " + schema + " CASCADE").Error
+	}
+	if err != nil {
+		logger.Errorf("Error deleting schema %s error %v", schema, err)
+	}
+	return err
+}
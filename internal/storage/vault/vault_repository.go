@@ -0,0 +1,127 @@
+package vault
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/passwall/passwall-server/internal/storage/query"
+	"github.com/passwall/passwall-server/internal/storage/sqlite"
+	"github.com/passwall/passwall-server/internal/storage/tenancy"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// vaultTables are the tables of a vault, their change time columns and if
+// their deleted rows are kept until they are purged
+var vaultTables = []struct {
+	name, column string
+	softDelete   bool
+}{
+	{"logins", "updated_at", true},
+	{"credit_cards", "updated_at", true},
+	{"bank_accounts", "updated_at", true},
+	{"notes", "updated_at", true},
+	{"emails", "updated_at", true},
+	{"servers", "updated_at", true},
+	{"folders", "updated_at", true},
+	{"tombstones", "created_at", false},
+}
+
+// Repository ...
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository ...
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// State ...
+func (p *Repository) State(schema string) ([]model.VaultTableState, error) {
+	queries := make([]string, len(vaultTables))
+	for i, table := range vaultTables {
+		count := "COUNT(*)"
+		if table.softDelete {
+			count = "COUNT(CASE WHEN deleted_at IS NULL THEN 1 END)"
+		}
+		queries[i] = fmt.Sprintf("SELECT '%s' AS name, %s AS count, MAX(%s) AS updated_at FROM %s",
+			table.name, count, table.column, tenancy.From(schema, table.name))
+	}
+
+	union := strings.Join(queries, " UNION ALL ")
+	if sqlite.Is(p.db) {
+		return p.sqliteState(union, schema)
+	}
+
+	states := []model.VaultTableState{}
+	// The ETags are of the state of the replica, like the lists they cache
+	err := query.Reads(p.db).Raw(union).Scan(&states).Error
+	if err != nil {
+		logger.Errorf("Error getting vault state of schema %v error %v", schema, err)
+		return nil, err
+	}
+	return states, nil
+}
+
+// sqliteState runs the state query on SQLite, which returns MAX of the times as text
+func (p *Repository) sqliteState(query, schema string) ([]model.VaultTableState, error) {
+	rows := []struct {
+		Name      string
+		Count     int64
+		UpdatedAt *string
+	}{}
+	if err := p.db.Raw(query).Scan(&rows).Error; err != nil {
+		logger.Errorf("Error getting vault state of schema %v error %v", schema, err)
+		return nil, err
+	}
+
+	states := make([]model.VaultTableState, len(rows))
+	for i, row := range rows {
+		states[i] = model.VaultTableState{Name: row.Name, Count: row.Count}
+		if row.UpdatedAt != nil {
+			updatedAt, err := sqlite.ParseTime(*row.UpdatedAt)
+			if err != nil {
+				logger.Errorf("Error getting vault state of schema %v error %v", schema, err)
+				return nil, err
+			}
+			states[i].UpdatedAt = &updatedAt
+		}
+	}
+	return states, nil
+}
+
+// Revision ...
+func (p *Repository) Revision(schema string) (*model.VaultRevision, error) {
+	revisions := []model.VaultRevision{}
+	err := tenancy.Table(p.db, schema, "vault_revisions").Where("id = ?", 1).Find(&revisions).Error
+	if err != nil {
+		logger.Errorf("Error getting vault revision of schema %v error %v", schema, err)
+		return nil, err
+	}
+	// Vaults without any mutation yet have no row
+	if len(revisions) == 0 {
+		return &model.VaultRevision{ID: 1}, nil
+	}
+	return &revisions[0], nil
+}
+
+// BumpRevision ...
+func (p *Repository) BumpRevision(schema string) (uint64, error) {
+	var revision uint64
+	query := fmt.Sprintf(`INSERT INTO %[1]s.vault_revisions (id, updated_at, revision) VALUES (1, CURRENT_TIMESTAMP, 1)
+		ON CONFLICT (id) DO UPDATE SET updated_at = CURRENT_TIMESTAMP, revision = %[1]s.vault_revisions.revision + 1
+		RETURNING revision`, schema)
+	if tenancy.Rows() {
+		query = fmt.Sprintf(`INSERT INTO %[1]s.vault_revisions (%[2]s, id, updated_at, revision) VALUES ('%[3]s', 1, CURRENT_TIMESTAMP, 1)
+		ON CONFLICT (%[2]s, id) DO UPDATE SET updated_at = CURRENT_TIMESTAMP, revision = %[1]s.vault_revisions.revision + 1
+		RETURNING revision`, tenancy.Schema, tenancy.Column, schema)
+	}
+	err := p.db.Raw(query).Scan(&revision).Error
+	if err != nil {
+		logger.Errorf("Error bumping vault revision of schema %v error %v", schema, err)
+		return 0, err
+	}
+	return revision, nil
+}
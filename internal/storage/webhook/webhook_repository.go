@@ -0,0 +1,143 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/passwall/passwall-server/internal/storage/query"
+	"github.com/passwall/passwall-server/model"
+	"github.com/passwall/passwall-server/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Repository ...
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository ...
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// FindByID ...
+func (p *Repository) FindByID(id uint) (*model.Webhook, error) {
+	webhook := new(model.Webhook)
+	err := p.db.Where(`id = ?`, id).First(&webhook).Error
+	if err != nil {
+		logger.Errorf("Error getting webhook %v error %v", id, err)
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// FindAll ...
+func (p *Repository) FindAll(userID uint, admin bool) ([]model.Webhook, error) {
+	webhooks := []model.Webhook{}
+	db := p.db.Where(`admin = ?`, admin)
+	if !admin {
+		db = db.Where(`user_id = ?`, userID)
+	}
+	err := db.Order("id asc").Find(&webhooks).Error
+	if err != nil {
+		logger.Errorf("Error getting webhooks of user %v error %v", userID, err)
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// FindActive ...
+func (p *Repository) FindActive(userID uint) ([]model.Webhook, error) {
+	webhooks := []model.Webhook{}
+	err := p.db.Where(`active = ? AND (admin = ? OR user_id = ?)`, true, true, userID).Find(&webhooks).Error
+	if err != nil {
+		logger.Errorf("Error getting active webhooks of user %v error %v", userID, err)
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// Create ...
+func (p *Repository) Create(webhook *model.Webhook) (*model.Webhook, error) {
+	err := p.db.Create(&webhook).Error
+	if err != nil {
+		logger.Errorf("Error creating webhook %v error %v", webhook.ID, err)
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// Update saves the webhook if its version is still the one it was read with
+func (p *Repository) Update(webhook *model.Webhook) (*model.Webhook, error) {
+	err := query.SaveVersion(p.db, webhook, webhook.ID, &webhook.Version)
+	if err != nil {
+		logger.Errorf("Error updating webhook %v error %v", webhook.ID, err)
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// Delete removes the webhook and its deliveries
+func (p *Repository) Delete(id uint) error {
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(`webhook_id = ?`, id).Delete(&model.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Webhook{ID: id}).Error
+	})
+}
+
+// DeleteByUserID removes the webhooks of the user and their deliveries
+func (p *Repository) DeleteByUserID(userID uint) error {
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		ids := tx.Model(&model.Webhook{}).Select("id").Where(`user_id = ? AND admin = ?`, userID, false)
+		if err := tx.Where(`webhook_id IN (?)`, ids).Delete(&model.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Where(`user_id = ? AND admin = ?`, userID, false).Delete(&model.Webhook{}).Error
+	})
+}
+
+// FindDeliveries ...
+func (p *Repository) FindDeliveries(webhookID uint, opts *model.ListOptions) ([]model.WebhookDelivery, int64, error) {
+	db := p.db.Model(&model.WebhookDelivery{}).Where(`webhook_id = ?`, webhookID)
+
+	deliveries := []model.WebhookDelivery{}
+	total, err := query.List(db, opts, nil, &deliveries)
+	if err != nil {
+		logger.Errorf("Error listing deliveries of webhook %v error %v", webhookID, err)
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
+
+// FindDueDeliveries ...
+func (p *Repository) FindDueDeliveries(until time.Time, status string, limit int) ([]model.WebhookDelivery, error) {
+	deliveries := []model.WebhookDelivery{}
+	err := p.db.Where(`status = ? AND next_attempt_at <= ?`, status, until).
+		Order("next_attempt_at asc").Limit(limit).Find(&deliveries).Error
+	if err != nil {
+		logger.Errorf("Error getting due webhook deliveries error %v", err)
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// CreateDelivery ...
+func (p *Repository) CreateDelivery(delivery *model.WebhookDelivery) (*model.WebhookDelivery, error) {
+	err := p.db.Create(&delivery).Error
+	if err != nil {
+		logger.Errorf("Error creating delivery of webhook %v error %v", delivery.WebhookID, err)
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// UpdateDelivery ...
+func (p *Repository) UpdateDelivery(delivery *model.WebhookDelivery) (*model.WebhookDelivery, error) {
+	err := p.db.Save(&delivery).Error
+	if err != nil {
+		logger.Errorf("Error updating delivery %v error %v", delivery.ID, err)
+		return nil, err
+	}
+	return delivery, nil
+}
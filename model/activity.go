@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// ActivityEvent is an instance level happening in the admin activity feed like
+// a signup or a spike of failed signins. UserID is the user the event is about.
+type ActivityEvent struct {
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    uint      `json:"user_id,omitempty"`
+	ActorID   uint      `json:"actor_id,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+	Country   string    `json:"country,omitempty"`
+	Count     int       `json:"count,omitempty"`
+	Details   string    `json:"details,omitempty"`
+}
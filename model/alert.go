@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// SecurityAlert is a suspicious pattern found in the audit events of a user,
+// like signins from two distant places or a sudden mass export
+type SecurityAlert struct {
+	ID              uint      `gorm:"primary_key" json:"id"`
+	CreatedAt       time.Time `json:"created_at"`
+	UserID          uint      `gorm:"index" json:"user_id"`
+	Type            string    `gorm:"index" json:"type"`
+	Details         string    `json:"details"`
+	EventID         uint      `json:"event_id"`
+	SessionsRevoked bool      `json:"sessions_revoked"`
+}
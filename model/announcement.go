@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Announcement is a notice admins publish to every user, like a planned
+// maintenance or a policy change. It is shown from its start until its end,
+// or until it is removed when it has no end.
+type Announcement struct {
+	ID        uint           `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at"`
+	Version   uint           `gorm:"not null;default:1" json:"version"`
+	Message   string         `json:"message"`
+	Severity  string         `json:"severity"`
+	StartsAt  time.Time      `gorm:"index" json:"starts_at"`
+	EndsAt    *time.Time     `json:"ends_at"`
+}
+
+// AnnouncementDTO object for the admin endpoints publishing announcements, it
+// starts right away without a start time
+type AnnouncementDTO struct {
+	Message  string     `json:"message" validate:"required,max=1000"`
+	Severity string     `json:"severity" validate:"required,oneof=info warning critical"`
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+}
@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// AuditPeriod limits the audit events to the ones created in it, nil ends are open
+type AuditPeriod struct {
+	From *time.Time
+	To   *time.Time
+}
+
+// AuditEvent is a record of a security sensitive action of a user. Events are
+// never changed or deleted, the database rejects it.
+type AuditEvent struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	Action    string    `gorm:"index" json:"action"`
+	ItemType  string    `json:"item_type"`
+	ItemID    uint      `json:"item_id,omitempty"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Country   string    `json:"country,omitempty"`
+	City      string    `json:"city,omitempty"`
+	// ImpersonatorID is the admin who did the action impersonating the user
+	ImpersonatorID uint `json:"impersonator_id,omitempty"`
+	// Principal is the one the action is attributed to, like user:1 or admin:2
+	Principal string `json:"principal,omitempty"`
+}
@@ -9,22 +9,74 @@ import (
 // AuthEmail ...
 type AuthEmail struct {
 	Email string `json:"email"`
+	// Channel is email or sms, the code is sent by email when it is empty
+	Channel string `json:"channel"`
+	// Phone receives the signup code sent by SMS
+	Phone string `json:"phone"`
+}
+
+// PhoneDTO is the phone number to verify
+type PhoneDTO struct {
+	Phone string `json:"phone" validate:"required"`
+}
+
+// PhoneCodeDTO is the code sent to the phone number
+type PhoneCodeDTO struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// EmailChangeDTO is the new email the user changes to
+type EmailChangeDTO struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// EmailChangeCodeDTO is the code sent to the new email
+type EmailChangeCodeDTO struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// EmailChangeTokenDTO is the token of the link sent to the current email
+type EmailChangeTokenDTO struct {
+	Token string `json:"token" validate:"required"`
 }
 
 // AuthLoginDTO ...
 type AuthLoginDTO struct {
 	Email          string `validate:"required" json:"email"`
 	MasterPassword string `validate:"required" json:"master_password"`
+	// Code is the signin code sent to the user when the security policy requires two-factor
+	Code string `json:"code"`
+	// Client is the type of the client signing in, the lifetimes of the
+	// tokens of the session are configured by the client type
+	Client string `validate:"omitempty,oneof=extension web mobile api" json:"client"`
+	// DeviceID identifies the device of the client, the trusted device tokens
+	// only work with the device they are issued to
+	DeviceID string `validate:"required_with=TrustDevice,max=200" json:"device_id"`
+	// TrustDevice asks a device token with the two-factor code, which skips
+	// the code at the next signins of the device
+	TrustDevice bool `json:"trust_device"`
+	// DeviceToken is the token of a trusted device sent instead of the code
+	DeviceToken string `json:"device_token"`
 }
 
 // AuthLoginResponse ...
 type AuthLoginResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	Type         string `json:"type"`
+	AccessToken  string       `json:"access_token"`
+	RefreshToken string       `json:"refresh_token"`
+	Type         string       `json:"type"`
+	Trial        *TrialStatus `json:"trial,omitempty"`
+	// DeviceToken is the token of the device trusted at the signin
+	DeviceToken string `json:"device_token,omitempty"`
 	*UserDTO
 }
 
+// TrialStatus is the trial of the user, clients show the days left in it
+type TrialStatus struct {
+	StartedAt *time.Time `json:"started_at"`
+	EndsAt    time.Time  `json:"ends_at"`
+	DaysLeft  int        `json:"days_left"`
+}
+
 // TokenDetailsDTO ...
 type TokenDetailsDTO struct {
 	AccessToken   string `json:"access_token"`
@@ -2,21 +2,40 @@ package model
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // BankAccount ...
 type BankAccount struct {
-	ID            uint       `gorm:"primary_key" json:"id"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
-	DeletedAt     *time.Time `json:"deleted_at"`
-	BankName      string     `json:"title"`
-	BankCode      string     `json:"bank_code"`
-	AccountName   string     `json:"account_name" encrypt:"true"`
-	AccountNumber string     `json:"account_number" encrypt:"true"`
-	IBAN          string     `json:"iban" encrypt:"true"`
-	Currency      string     `json:"currency" encrypt:"true"`
-	Password      string     `json:"password" encrypt:"true"`
+	ID            uint           `gorm:"primary_key" json:"id"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"deleted_at"`
+	BankName      string         `json:"title"`
+	BankCode      string         `json:"bank_code"`
+	AccountName   string         `json:"account_name" encrypt:"true"`
+	AccountNumber string         `json:"account_number" encrypt:"true"`
+	IBAN          string         `json:"iban" encrypt:"true"`
+	Currency      string         `json:"currency" encrypt:"true"`
+	Password      string         `json:"password" encrypt:"true"`
+
+	FolderID *uint `json:"folder_id"`
+	Revision uint  `gorm:"not null;default:1" json:"revision"`
+
+	// CreatedBy and UpdatedBy are the principals which created and last changed the item
+	CreatedBy string `json:"created_by"`
+	UpdatedBy string `json:"updated_by"`
+}
+
+// ItemID returns the ID of the bank account
+func (b *BankAccount) ItemID() uint {
+	return b.ID
+}
+
+// ItemRevision returns the revision of the bank account
+func (b *BankAccount) ItemRevision() *uint {
+	return &b.Revision
 }
 
 //BankAccountDTO DTO object for BankAccount type
@@ -29,6 +48,12 @@ type BankAccountDTO struct {
 	IBAN          string `json:"iban"`
 	Currency      string `json:"currency"`
 	Password      string `json:"password"`
+
+	FolderID *uint `json:"folder_id"`
+	Revision uint  `json:"revision"`
+
+	CreatedBy string `json:"created_by,omitempty"`
+	UpdatedBy string `json:"updated_by,omitempty"`
 }
 
 // ToBankAccount ...
@@ -41,6 +66,8 @@ func ToBankAccount(bankAccountDTO *BankAccountDTO) *BankAccount {
 		IBAN:          bankAccountDTO.IBAN,
 		Currency:      bankAccountDTO.Currency,
 		Password:      bankAccountDTO.Password,
+
+		FolderID: bankAccountDTO.FolderID,
 	}
 }
 
@@ -55,6 +82,12 @@ func ToBankAccountDTO(bankAccount *BankAccount) *BankAccountDTO {
 		IBAN:          bankAccount.IBAN,
 		Currency:      bankAccount.Currency,
 		Password:      bankAccount.Password,
+
+		FolderID: bankAccount.FolderID,
+		Revision: bankAccount.Revision,
+
+		CreatedBy: bankAccount.CreatedBy,
+		UpdatedBy: bankAccount.UpdatedBy,
 	}
 }
 
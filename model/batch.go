@@ -0,0 +1,41 @@
+package model
+
+import (
+	"encoding/json"
+)
+
+// Batch operation names
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// BatchRequest is the payload of batch endpoints. Create and update items are
+// the DTOs of the item type, update items must have their ids.
+type BatchRequest struct {
+	Create []json.RawMessage `json:"create"`
+	Update []json.RawMessage `json:"update"`
+	Delete []uint            `json:"delete"`
+}
+
+// BatchResult is the result of a single operation in a batch
+type BatchResult struct {
+	Operation string `json:"operation"`
+	Index     int    `json:"index"`
+	ID        uint   `json:"id,omitempty"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+// BatchResponse is the response of batch endpoints
+type BatchResponse struct {
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Results   []BatchResult `json:"results"`
+}
+
+// Size returns the number of operations in the batch
+func (b *BatchRequest) Size() int {
+	return len(b.Create) + len(b.Update) + len(b.Delete)
+}
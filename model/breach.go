@@ -0,0 +1,44 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// EmailBreach is a known breach involving one of the user's email addresses
+type EmailBreach struct {
+	ID          uint      `gorm:"primary_key" json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UserID      uint      `gorm:"index" json:"user_id"`
+	Email       string    `json:"email"`
+	Name        string    `json:"name"`
+	Title       string    `json:"title"`
+	Domain      string    `json:"domain"`
+	BreachDate  string    `json:"breach_date"`
+	AddedDate   time.Time `json:"added_date"`
+	DataClasses string    `json:"data_classes"`
+}
+
+// BreachDTO is the breach object returned by the HIBP breached account API
+type BreachDTO struct {
+	Name        string    `json:"Name"`
+	Title       string    `json:"Title"`
+	Domain      string    `json:"Domain"`
+	BreachDate  string    `json:"BreachDate"`
+	AddedDate   time.Time `json:"AddedDate"`
+	DataClasses []string  `json:"DataClasses"`
+}
+
+// ToEmailBreach ...
+func ToEmailBreach(userID uint, email string, dto *BreachDTO) *EmailBreach {
+	return &EmailBreach{
+		UserID:      userID,
+		Email:       email,
+		Name:        dto.Name,
+		Title:       dto.Title,
+		Domain:      dto.Domain,
+		BreachDate:  dto.BreachDate,
+		AddedDate:   dto.AddedDate,
+		DataClasses: strings.Join(dto.DataClasses, ", "),
+	}
+}
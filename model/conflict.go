@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+)
+
+// Conflict links an item to the conflicted copy made from an update which
+// was made on an outdated revision of the item
+type Conflict struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ItemType  string    `gorm:"not null;index:idx_conflict_item" json:"item_type"`
+	ItemID    uint      `gorm:"not null;index:idx_conflict_item" json:"item_id"`
+	CopyID    uint      `gorm:"not null" json:"copy_id"`
+}
+
+// ResolveConflictDTO selects the version of the item kept when resolving a conflict
+type ResolveConflictDTO struct {
+	Keep string `json:"keep" validate:"required,oneof=original copy both"`
+}
@@ -2,20 +2,39 @@ package model
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // CreditCard ...
 type CreditCard struct {
-	ID                 uint       `gorm:"primary_key" json:"id"`
-	CreatedAt          time.Time  `json:"created_at"`
-	UpdatedAt          time.Time  `json:"updated_at"`
-	DeletedAt          *time.Time `json:"deleted_at"`
-	CardName           string     `json:"title"`
-	CardholderName     string     `json:"cardholder_name" encrypt:"true"`
-	Type               string     `json:"type" encrypt:"true"`
-	Number             string     `json:"number" encrypt:"true"`
-	VerificationNumber string     `json:"verification_number" encrypt:"true"`
-	ExpiryDate         string     `json:"expiry_date" encrypt:"true"`
+	ID                 uint           `gorm:"primary_key" json:"id"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `json:"deleted_at"`
+	CardName           string         `json:"title"`
+	CardholderName     string         `json:"cardholder_name" encrypt:"true"`
+	Type               string         `json:"type" encrypt:"true"`
+	Number             string         `json:"number" encrypt:"true"`
+	VerificationNumber string         `json:"verification_number" encrypt:"true"`
+	ExpiryDate         string         `json:"expiry_date" encrypt:"true"`
+
+	FolderID *uint `json:"folder_id"`
+	Revision uint  `gorm:"not null;default:1" json:"revision"`
+
+	// CreatedBy and UpdatedBy are the principals which created and last changed the item
+	CreatedBy string `json:"created_by"`
+	UpdatedBy string `json:"updated_by"`
+}
+
+// ItemID returns the ID of the credit card
+func (c *CreditCard) ItemID() uint {
+	return c.ID
+}
+
+// ItemRevision returns the revision of the credit card
+func (c *CreditCard) ItemRevision() *uint {
+	return &c.Revision
 }
 
 //CreditCardDTO DTO object for CreditCard type
@@ -27,6 +46,12 @@ type CreditCardDTO struct {
 	Number             string `json:"number"`
 	VerificationNumber string `json:"verification_number"`
 	ExpiryDate         string `json:"expiry_date"`
+
+	FolderID *uint `json:"folder_id"`
+	Revision uint  `json:"revision"`
+
+	CreatedBy string `json:"created_by,omitempty"`
+	UpdatedBy string `json:"updated_by,omitempty"`
 }
 
 // ToCreditCard ...
@@ -38,6 +63,8 @@ func ToCreditCard(creditCardDTO *CreditCardDTO) *CreditCard {
 		Number:             creditCardDTO.Number,
 		VerificationNumber: creditCardDTO.VerificationNumber,
 		ExpiryDate:         creditCardDTO.ExpiryDate,
+
+		FolderID: creditCardDTO.FolderID,
 	}
 }
 
@@ -51,6 +78,12 @@ func ToCreditCardDTO(creditCard *CreditCard) *CreditCardDTO {
 		Number:             creditCard.Number,
 		VerificationNumber: creditCard.VerificationNumber,
 		ExpiryDate:         creditCard.ExpiryDate,
+
+		FolderID: creditCard.FolderID,
+		Revision: creditCard.Revision,
+
+		CreatedBy: creditCard.CreatedBy,
+		UpdatedBy: creditCard.UpdatedBy,
 	}
 }
 
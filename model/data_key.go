@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// DataKey is the key encrypting the fields of the items of a vault, it is
+// stored wrapped by the master key
+type DataKey struct {
+	ID         uint      `gorm:"primary_key" json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	KeyID      string    `json:"key_id"`
+	Schema     string    `json:"schema"`
+	WrappedKey string    `json:"-"`
+	// Version increases with every key rotation of the vault, the key with the
+	// highest version encrypts the new fields
+	Version int `gorm:"default:1" json:"version"`
+	// ReencryptedAt is when the fields of the vault still encrypted with the
+	// passphrase are moved to the key
+	ReencryptedAt *time.Time `json:"reencrypted_at"`
+}
@@ -2,17 +2,35 @@ package model
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // Email ...
 type Email struct {
-	ID        uint       `gorm:"primary_key" json:"id"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-	DeletedAt *time.Time `json:"deleted_at"`
-	Title     string     `json:"title"`
-	Email     string     `json:"email" encrypt:"true"`
-	Password  string     `json:"password" encrypt:"true"`
+	ID        uint           `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at"`
+	Title     string         `json:"title"`
+	Email     string         `json:"email" encrypt:"true"`
+	Password  string         `json:"password" encrypt:"true"`
+	FolderID  *uint          `json:"folder_id"`
+	Revision  uint           `gorm:"not null;default:1" json:"revision"`
+
+	// CreatedBy and UpdatedBy are the principals which created and last changed the item
+	CreatedBy string `json:"created_by"`
+	UpdatedBy string `json:"updated_by"`
+}
+
+// ItemID returns the ID of the email
+func (e *Email) ItemID() uint {
+	return e.ID
+}
+
+// ItemRevision returns the revision of the email
+func (e *Email) ItemRevision() *uint {
+	return &e.Revision
 }
 
 // EmailDTO ...
@@ -21,6 +39,11 @@ type EmailDTO struct {
 	Title    string `json:"title"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	FolderID *uint  `json:"folder_id"`
+	Revision uint   `json:"revision"`
+
+	CreatedBy string `json:"created_by,omitempty"`
+	UpdatedBy string `json:"updated_by,omitempty"`
 }
 
 // ToEmail ...
@@ -29,6 +52,7 @@ func ToEmail(emailDTO *EmailDTO) *Email {
 		Title:    emailDTO.Title,
 		Email:    emailDTO.Email,
 		Password: emailDTO.Password,
+		FolderID: emailDTO.FolderID,
 	}
 }
 
@@ -39,6 +63,11 @@ func ToEmailDTO(email *Email) *EmailDTO {
 		Title:    email.Title,
 		Email:    email.Email,
 		Password: email.Password,
+		FolderID: email.FolderID,
+		Revision: email.Revision,
+
+		CreatedBy: email.CreatedBy,
+		UpdatedBy: email.UpdatedBy,
 	}
 }
 
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Folder groups the items of a vault, folders can be nested
+type Folder struct {
+	ID        uint           `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at"`
+	Name      string         `json:"name"`
+	ParentID  *uint          `gorm:"index" json:"parent_id"`
+
+	// CreatedBy and UpdatedBy are the principals which created and last changed the folder
+	CreatedBy string `json:"created_by"`
+	UpdatedBy string `json:"updated_by"`
+}
+
+// FolderDTO DTO object for Folder type
+type FolderDTO struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name" validate:"required,max=255"`
+	ParentID *uint  `json:"parent_id"`
+
+	CreatedBy string `json:"created_by,omitempty"`
+	UpdatedBy string `json:"updated_by,omitempty"`
+}
+
+// ToFolder ...
+func ToFolder(dto *FolderDTO) *Folder {
+	return &Folder{
+		Name:     dto.Name,
+		ParentID: dto.ParentID,
+	}
+}
+
+// ToFolderDTO ...
+func ToFolderDTO(folder *Folder) *FolderDTO {
+	return &FolderDTO{
+		ID:       folder.ID,
+		Name:     folder.Name,
+		ParentID: folder.ParentID,
+
+		CreatedBy: folder.CreatedBy,
+		UpdatedBy: folder.UpdatedBy,
+	}
+}
@@ -0,0 +1,14 @@
+package model
+
+// ComponentStatus is the state of a dependency of the server
+type ComponentStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Readiness is the state of the server and its dependencies. Server is ready
+// to take requests when all the components are up.
+type Readiness struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components"`
+}
@@ -0,0 +1,38 @@
+package model
+
+// ImportItem is a vault item read from the export file of another password manager.
+// Only one of the item fields is set.
+type ImportItem struct {
+	Folder      string
+	Login       *LoginDTO
+	CreditCard  *CreditCardDTO
+	BankAccount *BankAccountDTO
+	Note        *NoteDTO
+	Email       *EmailDTO
+	Server      *ServerDTO
+}
+
+// ImportSummary is the result of an import. Dry runs report the items and
+// folders which would be created.
+type ImportSummary struct {
+	DryRun     bool           `json:"dry_run"`
+	Created    int            `json:"created"`
+	Skipped    int            `json:"skipped"`
+	Failed     int            `json:"failed"`
+	Duplicates int            `json:"duplicates"`
+	Types      map[string]int `json:"types"`
+	Folders    []string       `json:"folders,omitempty"`
+	Errors     []string       `json:"errors,omitempty"`
+}
+
+// ImportOptions are the options of an import
+type ImportOptions struct {
+	// Password opens the encrypted export files like KeePass databases
+	Password string
+	// SkipDuplicates skips the logins having the URL and username of an existing login
+	SkipDuplicates bool
+	// Mapping maps the login fields to the column names of generic CSV files
+	Mapping map[string]string
+	// DryRun parses and validates the items without saving them
+	DryRun bool
+}
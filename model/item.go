@@ -0,0 +1,9 @@
+package model
+
+// Item is a vault item stored by the generic item repository
+type Item interface {
+	// ItemID returns the ID of the item
+	ItemID() uint
+	// ItemRevision returns the revision of the item, it is increased by the updates
+	ItemRevision() *uint
+}
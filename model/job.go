@@ -0,0 +1,84 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Job is a long running operation of a user, like an import, running in the background
+type Job struct {
+	ID         uint       `gorm:"primary_key" json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	UserID     uint       `gorm:"index" json:"user_id"`
+	Type       string     `json:"type"`
+	Status     string     `json:"status"`
+	Total      int        `json:"total"`
+	Processed  int        `json:"processed"`
+	Result     string     `gorm:"type:text" json:"result"`
+	Error      string     `json:"error"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+// JobDTO is the status and progress of a job
+type JobDTO struct {
+	ID          uint                `json:"id"`
+	Type        string              `json:"type"`
+	Status      string              `json:"status"`
+	Total       int                 `json:"total"`
+	Processed   int                 `json:"processed"`
+	Progress    int                 `json:"progress"`
+	Summary     *ImportSummary      `json:"summary,omitempty"`
+	KeyRotation *KeyRotationSummary `json:"key_rotation,omitempty"`
+	Error       string              `json:"error,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+	FinishedAt  *time.Time          `json:"finished_at,omitempty"`
+}
+
+// ToJobDTO converts the job to its DTO, progress is the processed percentage
+func ToJobDTO(job *Job) *JobDTO {
+	dto := &JobDTO{
+		ID:         job.ID,
+		Type:       job.Type,
+		Status:     job.Status,
+		Total:      job.Total,
+		Processed:  job.Processed,
+		Error:      job.Error,
+		CreatedAt:  job.CreatedAt,
+		FinishedAt: job.FinishedAt,
+	}
+
+	if job.Total > 0 {
+		dto.Progress = job.Processed * 100 / job.Total
+	}
+	switch {
+	case job.Result == "":
+	case job.Type == "key_rotation":
+		rotation := new(KeyRotationSummary)
+		if err := json.Unmarshal([]byte(job.Result), rotation); err == nil {
+			dto.KeyRotation = rotation
+		}
+	default:
+		summary := new(ImportSummary)
+		if err := json.Unmarshal([]byte(job.Result), summary); err == nil {
+			dto.Summary = summary
+		}
+	}
+
+	return dto
+}
+
+// KeyRotationDTO starts a key rotation, ItemsPerSecond limits the records
+// encrypted again per second and the default of the server is used without it
+type KeyRotationDTO struct {
+	ItemsPerSecond int `json:"items_per_second" validate:"min=0,max=10000"`
+}
+
+// KeyRotationSummary is the state of a key rotation job, the vaults are its
+// total. CutoverAt is when the older keys were deleted.
+type KeyRotationSummary struct {
+	Version        int        `json:"version"`
+	ItemsPerSecond int        `json:"items_per_second"`
+	Reencrypted    int        `json:"reencrypted"`
+	CutoverAt      *time.Time `json:"cutover_at,omitempty"`
+}
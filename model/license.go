@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// ClientConfig is the configuration of the server the clients need
+type ClientConfig struct {
+	License LicenseStatus  `json:"license"`
+	Policy  SecurityPolicy `json:"policy"`
+	// Encryption tells the clients if they encrypt the items themselves
+	Encryption EncryptionMode `json:"encryption"`
+	// ExportPublicKey is the base64 Ed25519 key verifying the exports of the server
+	ExportPublicKey string `json:"export_public_key"`
+}
+
+// EncryptionMode is the mode of the item encryption of the server
+type EncryptionMode struct {
+	// ZeroKnowledge servers store the item fields encrypted by the clients and
+	// never hold their keys
+	ZeroKnowledge bool `json:"zero_knowledge"`
+	// CiphertextFormat is the version of the fields the clients encrypt
+	CiphertextFormat string `json:"ciphertext_format,omitempty"`
+}
+
+// LicenseStatus is the status of the offline license of the server
+type LicenseStatus struct {
+	Status    string     `json:"status"`
+	Licensee  string     `json:"licensee,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+)
+
+// ListOptions are the pagination, sorting and filtering options of list endpoints
+type ListOptions struct {
+	Limit        int
+	Offset       int
+	Cursor       uint
+	Sort         string
+	Direction    string
+	Search       string
+	UpdatedSince *time.Time
+	Filters      map[string]interface{}
+}
+
+// ListResponse is the response envelope of list endpoints
+type ListResponse struct {
+	Items      interface{} `json:"items"`
+	Total      int64       `json:"total"`
+	Limit      int         `json:"limit"`
+	Offset     int         `json:"offset"`
+	NextCursor uint        `json:"next_cursor,omitempty"`
+}
@@ -2,20 +2,49 @@ package model
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // Login ...
 type Login struct {
-	ID         uint       `gorm:"primary_key" json:"id"`
-	CreatedAt  time.Time  `json:"created_at"`
-	UpdatedAt  time.Time  `json:"updated_at"`
-	DeletedAt  *time.Time `json:"deleted_at"`
-	Title      string     `json:"title"`
-	URL        string     `json:"url"`
-	Username   string     `json:"username" encrypt:"true"`
-	Password   string     `json:"password" encrypt:"true"`
-	TOTPSecret string     `json:"totp_secret" encrypt:"true"`
-	Extra      string     `json:"extra" encrypt:"true"`
+	ID         uint           `gorm:"primary_key" json:"id"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"deleted_at"`
+	Title      string         `json:"title"`
+	URL        string         `json:"url"`
+	Username   string         `json:"username" encrypt:"true"`
+	Password   string         `json:"password" encrypt:"true"`
+	TOTPSecret string         `json:"totp_secret" encrypt:"true"`
+	Extra      string         `json:"extra" encrypt:"true"`
+
+	PasswordChangedAt *time.Time `json:"password_changed_at"`
+	Breached          bool       `json:"breached"`
+	BreachCheckedAt   *time.Time `json:"breach_checked_at"`
+	MaxPasswordAge    int        `json:"max_password_age"`
+	Expired           bool       `json:"expired"`
+	SearchIndex       string     `gorm:"type:tsvector" json:"-"`
+	// DomainIndex and UsernameIndex are the blind indexes duplicates are
+	// found by without decrypting the usernames
+	DomainIndex   string `json:"-"`
+	UsernameIndex string `json:"-"`
+	FolderID      *uint  `json:"folder_id"`
+	Revision      uint   `gorm:"not null;default:1" json:"revision"`
+
+	// CreatedBy and UpdatedBy are the principals which created and last changed the item
+	CreatedBy string `json:"created_by"`
+	UpdatedBy string `json:"updated_by"`
+}
+
+// ItemID returns the ID of the login
+func (l *Login) ItemID() uint {
+	return l.ID
+}
+
+// ItemRevision returns the revision of the login
+func (l *Login) ItemRevision() *uint {
+	return &l.Revision
 }
 
 // LoginDTO DTO object for Login type
@@ -27,6 +56,14 @@ type LoginDTO struct {
 	Password   string `json:"password"`
 	TOTPSecret string `json:"totp_secret" encrypt:"true"`
 	Extra      string `json:"extra"`
+
+	MaxPasswordAge int   `json:"max_password_age" validate:"min=0"`
+	Expired        bool  `json:"expired"`
+	FolderID       *uint `json:"folder_id"`
+	Revision       uint  `json:"revision"`
+
+	CreatedBy string `json:"created_by,omitempty"`
+	UpdatedBy string `json:"updated_by,omitempty"`
 }
 
 // ToLogin ...
@@ -38,6 +75,9 @@ func ToLogin(loginDTO *LoginDTO) *Login {
 		Password:   loginDTO.Password,
 		Extra:      loginDTO.Extra,
 		TOTPSecret: loginDTO.TOTPSecret,
+
+		MaxPasswordAge: loginDTO.MaxPasswordAge,
+		FolderID:       loginDTO.FolderID,
 	}
 }
 
@@ -51,6 +91,14 @@ func ToLoginDTO(login *Login) *LoginDTO {
 		Password:   login.Password,
 		Extra:      login.Extra,
 		TOTPSecret: login.TOTPSecret,
+
+		MaxPasswordAge: login.MaxPasswordAge,
+		Expired:        login.Expired,
+		FolderID:       login.FolderID,
+		Revision:       login.Revision,
+
+		CreatedBy: login.CreatedBy,
+		UpdatedBy: login.UpdatedBy,
 	}
 }
 
@@ -65,6 +113,47 @@ func ToLoginDTOs(logins []*Login) []*LoginDTO {
 	return loginDTOs
 }
 
+// LoginHistory is a previous version of a login kept when logins are merged
+type LoginHistory struct {
+	ID         uint      `gorm:"primary_key" json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LoginID    uint      `gorm:"index" json:"login_id"`
+	Title      string    `json:"title"`
+	URL        string    `json:"url"`
+	Username   string    `json:"username" encrypt:"true"`
+	Password   string    `json:"password" encrypt:"true"`
+	TOTPSecret string    `json:"totp_secret" encrypt:"true"`
+	Extra      string    `json:"extra" encrypt:"true"`
+	Reason     string    `json:"reason"`
+}
+
+// DuplicateLoginGroup groups the logins with the same domain and username
+type DuplicateLoginGroup struct {
+	URL      string       `json:"url"`
+	Username string       `json:"username"`
+	Items    []ReportItem `json:"items"`
+}
+
+// MergeLoginsDTO is the payload for merging duplicate logins into the target login
+type MergeLoginsDTO struct {
+	TargetID  uint   `json:"target_id" validate:"required"`
+	SourceIDs []uint `json:"source_ids" validate:"required,min=1,dive,required"`
+}
+
+// ToLoginHistory ...
+func ToLoginHistory(login *Login, targetID uint, reason string) *LoginHistory {
+	return &LoginHistory{
+		LoginID:    targetID,
+		Title:      login.Title,
+		URL:        login.URL,
+		Username:   login.Username,
+		Password:   login.Password,
+		TOTPSecret: login.TOTPSecret,
+		Extra:      login.Extra,
+		Reason:     reason,
+	}
+}
+
 // URLs ...
 type URLs struct {
 	Items []string `json:"urls"`
@@ -0,0 +1,13 @@
+package model
+
+// Maintenance is the maintenance mode of the server, Configured is true when
+// it is set in the configuration and can't be switched off with the API
+type Maintenance struct {
+	Enabled    bool `json:"enabled"`
+	Configured bool `json:"configured"`
+}
+
+// MaintenanceDTO object for the admin endpoint switching the maintenance mode
+type MaintenanceDTO struct {
+	Enabled bool `json:"enabled"`
+}
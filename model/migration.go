@@ -0,0 +1,12 @@
+package model
+
+// MigrationStatus is the state of a migration set in a schema
+type MigrationStatus struct {
+	Set     string `json:"set"`
+	Schema  string `json:"schema"`
+	Version uint   `json:"version"`
+	Latest  uint   `json:"latest"`
+	Pending []uint `json:"pending"`
+	// Unknown versions are applied by a newer version of the server
+	Unknown []uint `json:"unknown"`
+}
@@ -2,39 +2,68 @@ package model
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // Note ...
 type Note struct {
-	ID        uint       `gorm:"primary_key" json:"id"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-	DeletedAt *time.Time `json:"deleted_at"`
-	Title     string     `json:"title"`
-	Note      string     `json:"note" encrypt:"true"`
+	ID        uint           `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at"`
+	Title     string         `json:"title"`
+	Note      string         `json:"note" encrypt:"true"`
+	FolderID  *uint          `json:"folder_id"`
+	Revision  uint           `gorm:"not null;default:1" json:"revision"`
+
+	// CreatedBy and UpdatedBy are the principals which created and last changed the item
+	CreatedBy string `json:"created_by"`
+	UpdatedBy string `json:"updated_by"`
+}
+
+// ItemID returns the ID of the note
+func (n *Note) ItemID() uint {
+	return n.ID
+}
+
+// ItemRevision returns the revision of the note
+func (n *Note) ItemRevision() *uint {
+	return &n.Revision
 }
 
 // NoteDTO ...
 type NoteDTO struct {
-	ID    uint   `json:"id"`
-	Title string `json:"title"`
-	Note  string `json:"note"`
+	ID       uint   `json:"id"`
+	Title    string `json:"title"`
+	Note     string `json:"note"`
+	FolderID *uint  `json:"folder_id"`
+	Revision uint   `json:"revision"`
+
+	CreatedBy string `json:"created_by,omitempty"`
+	UpdatedBy string `json:"updated_by,omitempty"`
 }
 
 // ToNote ...
 func ToNote(noteDTO *NoteDTO) *Note {
 	return &Note{
-		Title: noteDTO.Title,
-		Note:  noteDTO.Note,
+		Title:    noteDTO.Title,
+		Note:     noteDTO.Note,
+		FolderID: noteDTO.FolderID,
 	}
 }
 
 // ToNoteDTO ...
 func ToNoteDTO(note *Note) *NoteDTO {
 	return &NoteDTO{
-		ID:    note.ID,
-		Title: note.Title,
-		Note:  note.Note,
+		ID:       note.ID,
+		Title:    note.Title,
+		Note:     note.Note,
+		FolderID: note.FolderID,
+		Revision: note.Revision,
+
+		CreatedBy: note.CreatedBy,
+		UpdatedBy: note.UpdatedBy,
 	}
 }
 
@@ -0,0 +1,15 @@
+package model
+
+// VaultChange is a created, updated or deleted item or folder of a vault
+type VaultChange struct {
+	ItemType string `json:"item_type"`
+	ItemID   uint   `json:"item_id"`
+	Action   string `json:"action"`
+}
+
+// ChangeNotification is sent to the clients listening to the changes of their vault.
+// Revision is the point to continue from with the delta sync.
+type ChangeNotification struct {
+	Revision int64         `json:"revision"`
+	Changes  []VaultChange `json:"changes"`
+}
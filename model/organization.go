@@ -0,0 +1,157 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Organization roles
+const (
+	OrganizationRoleOwner  = "owner"
+	OrganizationRoleAdmin  = "admin"
+	OrganizationRoleEditor = "editor"
+	OrganizationRoleMember = "member"
+)
+
+// Organization member statuses
+const (
+	MemberStatusInvited  = "invited"
+	MemberStatusAccepted = "accepted"
+)
+
+// Organization model
+type Organization struct {
+	ID        uint           `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	Version   uint           `gorm:"not null;default:1" json:"version"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at"`
+	Name      string         `json:"name"`
+	OwnerID   uint           `json:"owner_id"`
+	Schema    string         `json:"schema"`
+	Region    string         `json:"region"`
+	// Seats limits the members and invitations, zero is unlimited
+	Seats int `json:"seats"`
+	// SubscriptionID and SubscriptionItem are the seats of the team
+	// subscription at the billing provider
+	SubscriptionID   string `json:"-"`
+	SubscriptionItem string `json:"-"`
+}
+
+// OrganizationDTO DTO object for Organization type
+type OrganizationDTO struct {
+	ID      uint   `json:"id"`
+	Name    string `json:"name" validate:"required,max=100"`
+	OwnerID uint   `json:"owner_id"`
+	Region  string `json:"region"`
+	Seats   int    `json:"seats"`
+	Role    string `json:"role,omitempty"`
+}
+
+// SeatsDTO object for the seats endpoint of organizations
+type SeatsDTO struct {
+	Seats int `json:"seats" validate:"required,min=1"`
+}
+
+// OrganizationMember model
+type OrganizationMember struct {
+	ID             uint      `gorm:"primary_key" json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	OrganizationID uint      `gorm:"index" json:"organization_id"`
+	UserID         uint      `gorm:"index" json:"user_id"`
+	Email          string    `json:"email"`
+	Role           string    `json:"role"`
+	Status         string    `json:"status"`
+}
+
+// InvitationDTO object for Organization invitation endpoint
+type InvitationDTO struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required,oneof=admin editor member"`
+}
+
+// Collection groups shared items of an organization
+type Collection struct {
+	ID             uint           `gorm:"primary_key" json:"id"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	Version        uint           `gorm:"not null;default:1" json:"version"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at"`
+	OrganizationID uint           `gorm:"index" json:"organization_id"`
+	Name           string         `json:"name"`
+	MaxPasswordAge int            `json:"max_password_age"`
+}
+
+// CollectionDTO DTO object for Collection type
+type CollectionDTO struct {
+	ID             uint   `json:"id"`
+	Name           string `json:"name" validate:"required,max=100"`
+	MaxPasswordAge int    `json:"max_password_age" validate:"min=0"`
+}
+
+// CollectionItem is an item of the organization vault added to a collection
+type CollectionItem struct {
+	ID           uint      `gorm:"primary_key" json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	CollectionID uint      `gorm:"index" json:"collection_id"`
+	ItemType     string    `json:"item_type"`
+	ItemID       uint      `json:"item_id"`
+}
+
+// CollectionMember gives a member of the organization access to the items of a collection
+type CollectionMember struct {
+	ID           uint      `gorm:"primary_key" json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	CollectionID uint      `gorm:"index" json:"collection_id"`
+	UserID       uint      `gorm:"index" json:"user_id"`
+}
+
+// CollectionMemberDTO DTO object for CollectionMember type
+type CollectionMemberDTO struct {
+	UserID uint `json:"user_id" validate:"required"`
+}
+
+// CollectionItemDTO DTO object for CollectionItem type
+type CollectionItemDTO struct {
+	ItemType string `json:"item_type" validate:"required,oneof=login credit_card bank_account note email server"`
+	ItemID   uint   `json:"item_id" validate:"required"`
+}
+
+// ToOrganizationDTO ...
+func ToOrganizationDTO(org *Organization, role string) *OrganizationDTO {
+	return &OrganizationDTO{
+		ID:      org.ID,
+		Name:    org.Name,
+		OwnerID: org.OwnerID,
+		Region:  org.Region,
+		Seats:   org.Seats,
+		Role:    role,
+	}
+}
+
+// ToCollection ...
+func ToCollection(dto *CollectionDTO) *Collection {
+	return &Collection{
+		Name:           dto.Name,
+		MaxPasswordAge: dto.MaxPasswordAge,
+	}
+}
+
+// ToCollectionDTO ...
+func ToCollectionDTO(collection *Collection) *CollectionDTO {
+	return &CollectionDTO{
+		ID:             collection.ID,
+		Name:           collection.Name,
+		MaxPasswordAge: collection.MaxPasswordAge,
+	}
+}
+
+// ToCollectionItem ...
+func ToCollectionItem(dto *CollectionItemDTO) *CollectionItem {
+	return &CollectionItem{
+		ItemType: dto.ItemType,
+		ItemID:   dto.ItemID,
+	}
+}
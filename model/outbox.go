@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// OutboxEmail is a rendered email waiting in the outbox to be sent. Sent
+// emails are removed, the ones failing every attempt are kept as dead.
+type OutboxEmail struct {
+	ID            uint       `gorm:"primary_key" json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	ToName        string     `json:"to_name"`
+	ToEmail       string     `json:"to_email"`
+	Template      string     `json:"template"`
+	Subject       string     `json:"subject"`
+	Text          string     `gorm:"type:text" json:"-"`
+	HTML          string     `gorm:"type:text" json:"-"`
+	Status        string     `gorm:"index" json:"status"`
+	Attempts      int        `json:"attempts"`
+	Error         string     `json:"error,omitempty"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+}
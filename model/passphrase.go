@@ -0,0 +1,9 @@
+package model
+
+// PassphraseDTO object for passphrase generator endpoint
+type PassphraseDTO struct {
+	Words         int    `json:"words" validate:"omitempty,min=3,max=20"`
+	Separator     string `json:"separator" validate:"max=5"`
+	Capitalize    bool   `json:"capitalize"`
+	IncludeNumber bool   `json:"include_number"`
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+)
+
+// ItemRelation links two items of a vault
+type ItemRelation struct {
+	ID         uint      `gorm:"primary_key" json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	SourceType string    `gorm:"index:idx_relation_source" json:"source_type"`
+	SourceID   uint      `gorm:"index:idx_relation_source" json:"source_id"`
+	TargetType string    `gorm:"index:idx_relation_target" json:"target_type"`
+	TargetID   uint      `gorm:"index:idx_relation_target" json:"target_id"`
+}
+
+// ItemRelationDTO DTO object for ItemRelation type
+type ItemRelationDTO struct {
+	SourceType string `json:"source_type" validate:"required,oneof=login credit_card bank_account note email server"`
+	SourceID   uint   `json:"source_id" validate:"required"`
+	TargetType string `json:"target_type" validate:"required,oneof=login credit_card bank_account note email server"`
+	TargetID   uint   `json:"target_id" validate:"required"`
+}
+
+// RelatedItem is an item linked to another item
+type RelatedItem struct {
+	RelationID uint   `json:"relation_id"`
+	ItemType   string `json:"item_type"`
+	ItemID     uint   `json:"item_id"`
+	Title      string `json:"title"`
+}
+
+// ToItemRelation ...
+func ToItemRelation(dto *ItemRelationDTO) *ItemRelation {
+	return &ItemRelation{
+		SourceType: dto.SourceType,
+		SourceID:   dto.SourceID,
+		TargetType: dto.TargetType,
+		TargetID:   dto.TargetID,
+	}
+}
@@ -0,0 +1,69 @@
+package model
+
+import "time"
+
+// ReportItem is the login metadata returned in vault health reports
+type ReportItem struct {
+	ID       uint   `json:"id"`
+	Title    string `json:"title"`
+	URL      string `json:"url"`
+	Username string `json:"username"`
+}
+
+// ReusedPasswordGroup groups the logins sharing the same password
+type ReusedPasswordGroup struct {
+	Count int          `json:"count"`
+	Items []ReportItem `json:"items"`
+}
+
+// PasswordReportItem is a login with its password health details
+type PasswordReportItem struct {
+	ReportItem
+	Strength          int        `json:"strength"`
+	PasswordChangedAt *time.Time `json:"password_changed_at"`
+	AgeDays           int        `json:"age_days"`
+	Weak              bool       `json:"weak"`
+	Old               bool       `json:"old"`
+}
+
+// PasswordReport lists the logins with weak or old passwords
+type PasswordReport struct {
+	MaxAgeDays int                  `json:"max_age_days"`
+	WeakCount  int                  `json:"weak_count"`
+	OldCount   int                  `json:"old_count"`
+	Items      []PasswordReportItem `json:"items"`
+}
+
+// PasswordHashDTO is a client computed hash of a login password
+type PasswordHashDTO struct {
+	ID   uint   `json:"id" validate:"required"`
+	Hash string `json:"hash" validate:"required"`
+}
+
+// PasswordHashesDTO is the payload for reused password report with client computed hashes
+type PasswordHashesDTO struct {
+	Hashes []PasswordHashDTO `json:"hashes" validate:"required,dive"`
+}
+
+// ToReportItem ...
+func ToReportItem(login *Login) ReportItem {
+	return ReportItem{
+		ID:       login.ID,
+		Title:    login.Title,
+		URL:      login.URL,
+		Username: login.Username,
+	}
+}
+
+// BreachCheckDTO is the payload for breached password check.
+// Either the password or its SHA-1 hash can be sent.
+type BreachCheckDTO struct {
+	Password string `json:"password" validate:"required_without=Hash"`
+	Hash     string `json:"hash" validate:"omitempty,len=40,hexadecimal"`
+}
+
+// BreachCheckResponse is the result of breached password check
+type BreachCheckResponse struct {
+	Breached bool `json:"breached"`
+	Count    int  `json:"count"`
+}
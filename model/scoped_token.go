@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// ScopedTokenDTO is the scopes and the lifetime in hours of a scoped token,
+// like ["vault:read"] for read-only clients
+type ScopedTokenDTO struct {
+	Scopes    []string `json:"scopes" validate:"required,min=1,dive,oneof=vault:read vault:write vault:export account"`
+	ExpiresIn int      `json:"expires_in" validate:"required,min=1,max=8760"`
+}
+
+// ScopedTokenResponse is the scoped token, it can't be refreshed
+type ScopedTokenResponse struct {
+	AccessToken string    `json:"access_token"`
+	Scopes      []string  `json:"scopes"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
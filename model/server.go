@@ -2,24 +2,43 @@ package model
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // Server ...
 type Server struct {
-	ID              uint       `gorm:"primary_key" json:"id"`
-	CreatedAt       time.Time  `json:"created_at"`
-	UpdatedAt       time.Time  `json:"updated_at"`
-	DeletedAt       *time.Time `json:"deleted_at"`
-	Title           string     `json:"title"`
-	IP              string     `json:"ip" encrypt:"true"`
-	Username        string     `json:"username" encrypt:"true"`
-	Password        string     `json:"password" encrypt:"true"`
-	URL             string     `json:"url"`
-	HostingUsername string     `json:"hosting_username" encrypt:"true"`
-	HostingPassword string     `json:"hosting_password" encrypt:"true"`
-	AdminUsername   string     `json:"admin_username" encrypt:"true"`
-	AdminPassword   string     `json:"admin_password" encrypt:"true"`
-	Extra           string     `json:"extra" encrypt:"true"`
+	ID              uint           `gorm:"primary_key" json:"id"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"deleted_at"`
+	Title           string         `json:"title"`
+	IP              string         `json:"ip" encrypt:"true"`
+	Username        string         `json:"username" encrypt:"true"`
+	Password        string         `json:"password" encrypt:"true"`
+	URL             string         `json:"url"`
+	HostingUsername string         `json:"hosting_username" encrypt:"true"`
+	HostingPassword string         `json:"hosting_password" encrypt:"true"`
+	AdminUsername   string         `json:"admin_username" encrypt:"true"`
+	AdminPassword   string         `json:"admin_password" encrypt:"true"`
+	Extra           string         `json:"extra" encrypt:"true"`
+
+	FolderID *uint `json:"folder_id"`
+	Revision uint  `gorm:"not null;default:1" json:"revision"`
+
+	// CreatedBy and UpdatedBy are the principals which created and last changed the item
+	CreatedBy string `json:"created_by"`
+	UpdatedBy string `json:"updated_by"`
+}
+
+// ItemID returns the ID of the server
+func (s *Server) ItemID() uint {
+	return s.ID
+}
+
+// ItemRevision returns the revision of the server
+func (s *Server) ItemRevision() *uint {
+	return &s.Revision
 }
 
 //ServerDTO DTO object for Server type
@@ -35,6 +54,12 @@ type ServerDTO struct {
 	AdminUsername   string `json:"admin_username"`
 	AdminPassword   string `json:"admin_password"`
 	Extra           string `json:"extra"`
+
+	FolderID *uint `json:"folder_id"`
+	Revision uint  `json:"revision"`
+
+	CreatedBy string `json:"created_by,omitempty"`
+	UpdatedBy string `json:"updated_by,omitempty"`
 }
 
 // ToServer ...
@@ -50,6 +75,8 @@ func ToServer(serverDTO *ServerDTO) *Server {
 		AdminUsername:   serverDTO.AdminUsername,
 		AdminPassword:   serverDTO.AdminPassword,
 		Extra:           serverDTO.Extra,
+
+		FolderID: serverDTO.FolderID,
 	}
 }
 
@@ -67,6 +94,12 @@ func ToServerDTO(server *Server) *ServerDTO {
 		AdminUsername:   server.AdminUsername,
 		AdminPassword:   server.AdminPassword,
 		Extra:           server.Extra,
+
+		FolderID: server.FolderID,
+		Revision: server.Revision,
+
+		CreatedBy: server.CreatedBy,
+		UpdatedBy: server.UpdatedBy,
 	}
 }
 
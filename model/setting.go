@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// Setting is an instance setting admins change at runtime, values are strings
+// parsed by the code using them
+type Setting struct {
+	Key       string    `gorm:"primary_key" json:"key"`
+	Value     string    `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SecurityPolicy is the instance-wide security policy, zero values disable the
+// policies
+type SecurityPolicy struct {
+	// MinPasswordLength and MinPasswordStrength limit the new master passwords,
+	// the strength is the score from 0 to 4
+	MinPasswordLength   int `json:"min_password_length" validate:"min=0,max=100"`
+	MinPasswordStrength int `json:"min_password_strength" validate:"min=0,max=4"`
+	// RequireTwoFactor asks a code sent by email or SMS at every signin
+	RequireTwoFactor bool `json:"require_two_factor"`
+	// SessionMaxLifetime is the hours a session lasts after the signin, the
+	// tokens can't be refreshed after it
+	SessionMaxLifetime int `json:"session_max_lifetime" validate:"min=0"`
+	// SessionIdleTimeout is the minutes a session lasts without activity, each
+	// refresh extends it up to the session max lifetime. Zero keeps the fixed
+	// refresh token lifetime of the server.
+	SessionIdleTimeout int `json:"session_idle_timeout" validate:"min=0"`
+	// ExportDisabled keeps the vaults in the server
+	ExportDisabled bool `json:"export_disabled"`
+	// KDFCost is the bcrypt cost of the master passwords, the ones hashed with
+	// a lower cost are upgraded at the next signin of their users. Costs
+	// below the default of bcrypt use the default.
+	KDFCost int `json:"kdf_cost" validate:"min=0,max=16"`
+}
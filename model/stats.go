@@ -0,0 +1,45 @@
+package model
+
+// InstanceStats are the statistics of the instance for the admin dashboard
+type InstanceStats struct {
+	Users    UserStats        `json:"users"`
+	Sessions SessionStats     `json:"sessions"`
+	Items    map[string]int64 `json:"items"`
+	Signups  []DailyCount     `json:"signups"`
+	Storage  StorageStats     `json:"storage"`
+	Emails   EmailStats       `json:"emails"`
+}
+
+// UserStats are the counts of the users, Plans counts them by the plan they
+// have access to
+type UserStats struct {
+	Total     int            `json:"total"`
+	Active    int            `json:"active"`
+	Disabled  int            `json:"disabled"`
+	Scheduled int            `json:"scheduled_for_deletion"`
+	Plans     map[string]int `json:"plans"`
+}
+
+// SessionStats are the tokens which aren't expired and the users signed in with them
+type SessionStats struct {
+	Tokens int64 `json:"tokens"`
+	Users  int64 `json:"users"`
+}
+
+// DailyCount is a count of a day, the date is YYYY-MM-DD in UTC
+type DailyCount struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+// StorageStats is the disk usage of the database
+type StorageStats struct {
+	DatabaseBytes int64 `json:"database_bytes"`
+}
+
+// EmailStats are the emails in the outbox, sent ones are removed from it
+type EmailStats struct {
+	Pending  int64 `json:"pending"`
+	Retrying int64 `json:"retrying"`
+	Dead     int64 `json:"dead"`
+}
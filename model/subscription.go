@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// SubscriptionGrantDTO object for the admin subscription endpoints, grants
+// without an end last until they are revoked
+type SubscriptionGrantDTO struct {
+	Email string     `json:"email" validate:"required,email"`
+	Until *time.Time `json:"until"`
+}
+
+// SubscriptionGrant is the pro grant of a user and the subscription type the
+// user has with it
+type SubscriptionGrant struct {
+	Email            string     `json:"email"`
+	SubscriptionType string     `json:"subscription_type"`
+	GrantedAt        *time.Time `json:"granted_at"`
+	GrantedUntil     *time.Time `json:"granted_until"`
+}
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// SupportConsent is the consent of a user to be impersonated by an admin
+// until it expires, the user gives the token to the admin
+type SupportConsent struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SupportConsentDTO object for the users consenting to support, the consent
+// lasts the minutes, an hour when they aren't given
+type SupportConsentDTO struct {
+	Minutes int `json:"minutes" validate:"omitempty,min=1,max=1440"`
+}
+
+// ImpersonateDTO object for the admins impersonating a user with the consent token of the user
+type ImpersonateDTO struct {
+	Email        string `json:"email" validate:"required,email"`
+	ConsentToken string `json:"consent_token" validate:"required"`
+}
+
+// ImpersonateResponse is the token of the support session of the admin, it
+// can't be refreshed and expires with the consent
+type ImpersonateResponse struct {
+	AccessToken string        `json:"access_token"`
+	ExpiresAt   time.Time     `json:"expires_at"`
+	User        *UserDTOTable `json:"user"`
+}
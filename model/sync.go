@@ -0,0 +1,29 @@
+package model
+
+// VaultItems are the decrypted folders and items of a vault
+type VaultItems struct {
+	Folders      []Folder      `json:"folders"`
+	Logins       []Login       `json:"logins"`
+	CreditCards  []CreditCard  `json:"credit_cards"`
+	BankAccounts []BankAccount `json:"bank_accounts"`
+	Notes        []Note        `json:"notes"`
+	Emails       []Email       `json:"emails"`
+	Servers      []Server      `json:"servers"`
+}
+
+// SyncResponse has the vault changes since the revision the client sent. The
+// revision of the response is sent back on the next sync.
+type SyncResponse struct {
+	Revision int64 `json:"revision"`
+	VaultItems
+	Deleted []Tombstone `json:"deleted"`
+}
+
+// FullSyncResponse has the profile of the user and the whole vault, so clients
+// can do their first sync in a single request and continue with delta syncs
+// using the revision.
+type FullSyncResponse struct {
+	Revision int64    `json:"revision"`
+	User     *UserDTO `json:"user"`
+	VaultItems
+}
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// Tombstone records a deleted vault item so sync clients can remove their copies
+type Tombstone struct {
+	ID        uint      `gorm:"primary_key" json:"-"`
+	CreatedAt time.Time `gorm:"index" json:"deleted_at"`
+	ItemType  string    `json:"item_type"`
+	ItemID    uint      `json:"item_id"`
+}
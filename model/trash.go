@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// DeletedRecord is a deleted record admins can restore until it is purged.
+// Records of the vaults have no name, admins don't see what is in them.
+type DeletedRecord struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	DeletedAt time.Time `json:"deleted_at"`
+	// Schema and Region are the vault of the deleted users and organizations
+	Schema string `json:"-"`
+	Region string `json:"-"`
+}
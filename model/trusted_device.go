@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// TrustedDevice is a device the user asked to remember at a signin with the
+// two-factor code, its token skips the code at the next signins of the device
+// until it expires. The master password is asked every time.
+type TrustedDevice struct {
+	ID        uint      `gorm:"primary_key" json:"-"`
+	UUID      uuid.UUID `gorm:"type:uuid;type:varchar(100);" json:"uuid"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    uint      `gorm:"index" json:"-"`
+	// DeviceHash and TokenHash are the SHA-256 of the device identifier the
+	// client sends and of the token, the token only works with the device
+	DeviceHash string     `json:"-"`
+	TokenHash  string     `json:"-"`
+	Name       string     `json:"name"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+}
@@ -0,0 +1,14 @@
+package model
+
+// Usage is the use of the plan quotas of a user
+type Usage struct {
+	Plan   string     `json:"plan"`
+	Items  QuotaUsage `json:"items"`
+	Shares QuotaUsage `json:"shares"`
+}
+
+// QuotaUsage is the use of a quota, zero limit is unlimited
+type QuotaUsage struct {
+	Used  int `json:"used"`
+	Limit int `json:"limit"`
+}
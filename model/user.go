@@ -4,6 +4,7 @@ import (
 	"time"
 
 	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
 )
 
 type ChangeMasterPasswordDTO struct {
@@ -12,22 +13,74 @@ type ChangeMasterPasswordDTO struct {
 	NewMasterPassword string `validate:"required" json:"new_master_password"`
 }
 
+// ReauthenticateDTO is the payload for confirming the master password before sensitive operations
+type ReauthenticateDTO struct {
+	MasterPassword string `validate:"required" json:"master_password"`
+}
+
 // User model
 type User struct {
-	ID               uint       `gorm:"primary_key" json:"id"`
-	UUID             uuid.UUID  `gorm:"type:uuid; type:varchar(100);"`
-	CreatedAt        time.Time  `json:"created_at"`
-	UpdatedAt        time.Time  `json:"updated_at"`
-	DeletedAt        *time.Time `json:"deleted_at"`
-	Name             string     `json:"name"`
-	Email            string     `json:"email"`
-	MasterPassword   string     `json:"master_password"`
-	Secret           string     `json:"secret"`
-	Schema           string     `json:"schema"`
-	Role             string     `json:"role"`
-	ConfirmationCode string     `json:"confirmation_code"`
-	EmailVerifiedAt  time.Time  `json:"email_verified_at"`
-	IsMigrated       bool       `json:"is_migrated"`
+	ID                     uint           `gorm:"primary_key" json:"id"`
+	UUID                   uuid.UUID      `gorm:"type:uuid; type:varchar(100);"`
+	CreatedAt              time.Time      `json:"created_at"`
+	UpdatedAt              time.Time      `json:"updated_at"`
+	Version                uint           `gorm:"not null;default:1" json:"version"`
+	DeletedAt              gorm.DeletedAt `json:"deleted_at"`
+	Name                   string         `json:"name"`
+	Email                  string         `json:"email"`
+	MasterPassword         string         `json:"master_password"`
+	Secret                 string         `json:"secret"`
+	Schema                 string         `json:"schema"`
+	Role                   string         `json:"role"`
+	ConfirmationCode       string         `json:"confirmation_code"`
+	EmailVerifiedAt        time.Time      `json:"email_verified_at"`
+	IsMigrated             bool           `json:"is_migrated"`
+	DeletionScheduledAt    *time.Time     `json:"deletion_scheduled_at"`
+	DeletionReminderSentAt *time.Time     `json:"deletion_reminder_sent_at"`
+	BreachScan             bool           `json:"breach_scan"`
+	ReauthenticatedAt      *time.Time     `json:"reauthenticated_at"`
+	DisabledAt             *time.Time     `json:"disabled_at"`
+	MasterPasswordReset    bool           `json:"master_password_reset"`
+	SubscriptionType       string         `json:"subscription_type"`
+	Locale                 string         `json:"locale"`
+	Phone                  string         `json:"phone"`
+	PhoneVerifiedAt        *time.Time     `json:"phone_verified_at"`
+	SecurityDigest         bool           `json:"security_digest"`
+	DigestSentAt           *time.Time     `json:"digest_sent_at"`
+	TrialStartedAt         *time.Time     `json:"trial_started_at"`
+	TrialEndsAt            *time.Time     `json:"trial_ends_at"`
+	TrialReminderSentAt    *time.Time     `json:"trial_reminder_sent_at"`
+	SubscriptionLapsedAt   *time.Time     `json:"subscription_lapsed_at"`
+	// Admins grant the pro features besides the subscription, until the end
+	// of the grant or without an end when it is nil
+	SubscriptionGrantedAt    *time.Time `json:"subscription_granted_at"`
+	SubscriptionGrantedUntil *time.Time `json:"subscription_granted_until"`
+	// MaxItems is the item quota admins set for the user instead of the one
+	// of the plan, zero is unlimited
+	MaxItems *int `json:"max_items"`
+	// KDFAlgorithm and KDFCost are the parameters the master password is
+	// hashed with, signins upgrade them to the ones of the security policy
+	KDFAlgorithm string `json:"kdf_algorithm"`
+	KDFCost      int    `json:"kdf_cost"`
+	// TokenEpoch is claimed in the tokens of the user, revoking all the
+	// sessions increases it and the tokens of an older epoch are rejected
+	TokenEpoch int `gorm:"not null;default:0" json:"-"`
+	// BillingEmail is the email the billing providers know the user by when
+	// it changed its email, the subscription events are found by it first
+	BillingEmail string `json:"billing_email"`
+}
+
+// UserQuotaDTO object for the admin endpoint changing the item quota of a
+// user, null goes back to the quota of the plan
+type UserQuotaDTO struct {
+	MaxItems *int `json:"max_items" validate:"omitempty,min=0"`
+}
+
+// ForcePasswordChangeDTO object for the admin endpoint asking a user to change
+// the master password, false clears the request
+type ForcePasswordChangeDTO struct {
+	Email               string `json:"email" validate:"required,email"`
+	ForcePasswordChange bool   `json:"force_password_change"`
 }
 
 // UserDTO DTO object for User type
@@ -42,6 +95,16 @@ type UserDTO struct {
 	Role            string    `json:"role"`
 	EmailVerifiedAt time.Time `json:"email_verified_at"`
 	IsMigrated      bool      `json:"is_migrated"`
+
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	BreachScan          bool       `json:"breach_scan"`
+	SecurityDigest      bool       `json:"security_digest"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	MasterPasswordReset bool       `json:"master_password_reset"`
+	Locale              string     `json:"locale"`
+	// Phone is changed with the phone verification endpoints
+	Phone           string     `json:"phone,omitempty"`
+	PhoneVerifiedAt *time.Time `json:"phone_verified_at,omitempty"`
 }
 
 // UserSignup object for Auth Signup endpoint
@@ -49,6 +112,7 @@ type UserSignup struct {
 	Name           string `json:"name" validate:"max=100"`
 	Email          string `json:"email" validate:"required,email"`
 	MasterPassword string `json:"master_password" validate:"required,max=100,min=6"`
+	Locale         string `json:"locale"`
 }
 
 // UserDTOTable ...
@@ -59,6 +123,8 @@ type UserDTOTable struct {
 	Email  string    `json:"email"`
 	Schema string    `json:"schema"`
 	Role   string    `json:"role"`
+	// Usage is filled in the admin user list
+	Usage *Usage `json:"usage,omitempty"`
 }
 
 // ConvertUserDTO converts UserSignup to UserDTO
@@ -67,6 +133,7 @@ func ConvertUserDTO(userSignup *UserSignup) *UserDTO {
 		Name:           userSignup.Name,
 		Email:          userSignup.Email,
 		MasterPassword: userSignup.MasterPassword,
+		Locale:         userSignup.Locale,
 	}
 }
 
@@ -83,6 +150,7 @@ func ToUser(userDTO *UserDTO) *User {
 		Role:            userDTO.Role,
 		EmailVerifiedAt: userDTO.EmailVerifiedAt,
 		IsMigrated:      userDTO.IsMigrated,
+		Locale:          userDTO.Locale,
 	}
 }
 
@@ -97,6 +165,15 @@ func ToUserDTO(user *User) *UserDTO {
 		Schema:     user.Schema,
 		Role:       user.Role,
 		IsMigrated: user.IsMigrated,
+
+		DeletionScheduledAt: user.DeletionScheduledAt,
+		BreachScan:          user.BreachScan,
+		SecurityDigest:      user.SecurityDigest,
+		DisabledAt:          user.DisabledAt,
+		MasterPasswordReset: user.MasterPasswordReset,
+		Locale:              user.Locale,
+		Phone:               user.Phone,
+		PhoneVerifiedAt:     user.PhoneVerifiedAt,
 	}
 }
 
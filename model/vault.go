@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// VaultTableState is the row count and the last change time of a vault table,
+// together they change whenever an item is created, updated or deleted
+type VaultTableState struct {
+	Name      string
+	Count     int64
+	UpdatedAt *time.Time
+}
+
+// VaultRevision is the counter every mutation of a vault increases, a vault
+// has a single row with ID 1
+type VaultRevision struct {
+	ID        uint      `gorm:"primary_key" json:"-"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Revision  uint64    `gorm:"not null;default:0" json:"revision"`
+}
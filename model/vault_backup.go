@@ -0,0 +1,54 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// VaultBackup is the content of an encrypted backup of a whole vault. Items keep
+// their IDs, so folders, histories and relations can be linked again on restore.
+type VaultBackup struct {
+	Version        int            `json:"version"`
+	CreatedAt      time.Time      `json:"created_at"`
+	Folders        []Folder       `json:"folders"`
+	Logins         []Login        `json:"logins"`
+	LoginHistories []LoginHistory `json:"login_histories"`
+	CreditCards    []CreditCard   `json:"credit_cards"`
+	BankAccounts   []BankAccount  `json:"bank_accounts"`
+	Notes          []Note         `json:"notes"`
+	Emails         []Email        `json:"emails"`
+	Servers        []Server       `json:"servers"`
+	Relations      []ItemRelation `json:"relations"`
+}
+
+// VaultBackupFile is the file format of the encrypted backups. Data is the
+// AES-GCM encrypted VaultBackup JSON with a key derived from the backup password.
+type VaultBackupFile struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+	// Manifest describes Data and is signed by the server, from version 2
+	Manifest  json.RawMessage `json:"manifest,omitempty"`
+	Signature []byte          `json:"signature,omitempty"`
+}
+
+// ExportManifest describes an export file by its size and SHA-256, the server
+// signs it so the restore detects a truncated or changed file before writing
+// anything to the vault
+type ExportManifest struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	Type      string    `json:"type"` // backup, csv or kdbx
+	ItemType  string    `json:"item_type,omitempty"`
+	Items     int       `json:"items,omitempty"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	KeyID     string    `json:"key_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
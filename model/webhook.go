@@ -0,0 +1,83 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Webhook is a URL receiving the events of a user, admin webhooks receive the
+// events of every user
+type Webhook struct {
+	ID        uint           `gorm:"primary_key" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at"`
+	Version   uint           `gorm:"not null;default:1" json:"version"`
+	UserID    uint           `gorm:"index" json:"user_id"`
+	Admin     bool           `json:"admin"`
+	URL       string         `json:"url"`
+	Secret    string         `json:"-"`
+	Events    string         `json:"events"`
+	Active    bool           `json:"active"`
+}
+
+// Subscribed checks if the webhook receives the event
+func (w *Webhook) Subscribed(event string) bool {
+	for _, e := range strings.Split(w.Events, ",") {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDTO is the payload creating and updating webhooks
+type WebhookDTO struct {
+	URL    string   `json:"url" validate:"required,url,max=2048"`
+	Events []string `json:"events" validate:"required,min=1"`
+	Active *bool    `json:"active"`
+}
+
+// WebhookResponse is a webhook without its secret, the secret is shown only
+// once when the webhook is created
+type WebhookResponse struct {
+	ID        uint      `json:"id"`
+	Admin     bool      `json:"admin"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ToWebhookResponse converts the webhook to its response without the secret
+func ToWebhookResponse(webhook *Webhook) *WebhookResponse {
+	return &WebhookResponse{
+		ID:        webhook.ID,
+		Admin:     webhook.Admin,
+		URL:       webhook.URL,
+		Events:    strings.Split(webhook.Events, ","),
+		Active:    webhook.Active,
+		CreatedAt: webhook.CreatedAt,
+		UpdatedAt: webhook.UpdatedAt,
+	}
+}
+
+// WebhookDelivery is a sending of an event to a webhook and its last attempt
+type WebhookDelivery struct {
+	ID            uint       `gorm:"primary_key" json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	WebhookID     uint       `gorm:"index" json:"webhook_id"`
+	Event         string     `json:"event"`
+	Payload       string     `gorm:"type:text" json:"payload"`
+	Status        string     `gorm:"index" json:"status"`
+	Attempts      int        `json:"attempts"`
+	ResponseCode  int        `json:"response_code"`
+	Error         string     `json:"error,omitempty"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+}
@@ -8,4 +8,7 @@ var (
 	CommitID = "0000000000000000000000000000000000000000"
 	// Do not change BuildTime, it is updated while releasing.
 	BuildTime = "2021-01-01T00:00:00"
+	// LicensePublicKey is the base64 encoded Ed25519 key verifying the offline
+	// licenses, it is set while releasing.
+	LicensePublicKey = ""
 )
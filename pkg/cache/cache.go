@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+)
+
+// Drivers of the cache
+const (
+	DriverMemory = "memory"
+	DriverRedis  = "redis"
+)
+
+// Cache keeps the short living values shared by the server instances like
+// verification codes, revoked tokens and rate limit counters
+type Cache interface {
+	// Get returns the value of the key, found is false for missing or expired keys
+	Get(key string) (value string, found bool, err error)
+	// Set saves the value of the key for the ttl
+	Set(key, value string, ttl time.Duration) error
+	// Delete removes the key
+	Delete(key string) error
+	// Incr increases the counter of the key and returns the new value. Counter
+	// expires ttl after its first increase.
+	Incr(key string, ttl time.Duration) (int64, error)
+	// Ping checks if the cache is reachable
+	Ping() error
+}
+
+// New creates the cache of the driver, address, password and db are used by Redis
+func New(driver, address, password string, db int) (Cache, error) {
+	switch driver {
+	case "", DriverMemory:
+		return NewMemory(), nil
+	case DriverRedis:
+		return NewRedis(address, password, db)
+	default:
+		return nil, fmt.Errorf("unknown cache driver %q, use memory or redis", driver)
+	}
+}
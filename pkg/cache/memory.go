@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	gocache "github.com/patrickmn/go-cache"
+)
+
+// Memory is the cache of a single server instance
+type Memory struct {
+	mu    sync.Mutex
+	items *gocache.Cache
+}
+
+// NewMemory creates an in-memory cache
+func NewMemory() *Memory {
+	return &Memory{items: gocache.New(5*time.Minute, 10*time.Minute)}
+}
+
+// Get ...
+func (m *Memory) Get(key string) (string, bool, error) {
+	value, found := m.items.Get(key)
+	if !found {
+		return "", false, nil
+	}
+	text, _ := value.(string)
+	return text, true, nil
+}
+
+// Set ...
+func (m *Memory) Set(key, value string, ttl time.Duration) error {
+	m.items.Set(key, value, ttl)
+	return nil
+}
+
+// Delete ...
+func (m *Memory) Delete(key string) error {
+	m.items.Delete(key)
+	return nil
+}
+
+// Incr ...
+func (m *Memory) Incr(key string, ttl time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Missing counters are added, Add fails when another call added it first
+	if err := m.items.Add(key, int64(1), ttl); err == nil {
+		return 1, nil
+	}
+	return m.items.IncrementInt64(key, 1)
+}
+
+// Ping ...
+func (m *Memory) Ping() error {
+	return nil
+}
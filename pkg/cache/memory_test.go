@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemory(t *testing.T) {
+	c := NewMemory()
+
+	_, found, err := c.Get("key")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	assert.NoError(t, c.Set("key", "value", time.Minute))
+	value, found, err := c.Get("key")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "value", value)
+
+	assert.NoError(t, c.Delete("key"))
+	_, found, _ = c.Get("key")
+	assert.False(t, found)
+
+	assert.NoError(t, c.Set("short", "value", time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	_, found, _ = c.Get("short")
+	assert.False(t, found)
+}
+
+func TestMemoryIncr(t *testing.T) {
+	c := NewMemory()
+
+	for i := int64(1); i <= 3; i++ {
+		count, err := c.Incr("counter", time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, i, count)
+	}
+
+	// Expired counters start again
+	_, err := c.Incr("window", time.Millisecond)
+	assert.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	count, err := c.Incr("window", time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestNew(t *testing.T) {
+	c, err := New(DriverMemory, "", "", 0)
+	assert.NoError(t, err)
+	assert.IsType(t, &Memory{}, c)
+
+	_, err = New("memcached", "", "", 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisTimeout limits the commands so a slow Redis doesn't hang the requests
+const redisTimeout = 3 * time.Second
+
+// incrScript increases the counter and sets its expiry only on the first
+// increase, so the window of a rate limit doesn't slide with every request
+var incrScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return count
+`)
+
+// Redis is the cache shared by all the server instances
+type Redis struct {
+	client *redis.Client
+}
+
+// NewRedis connects to the Redis server on the address
+func NewRedis(address, password string, db int) (*Redis, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     address,
+		Password: password,
+		DB:       db,
+	})
+
+	r := &Redis{client: client}
+	if err := r.Ping(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Get ...
+func (r *Redis) Get(key string) (string, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	value, err := r.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+// Set ...
+func (r *Redis) Set(key, value string, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	return r.client.Set(ctx, key, value, ttl).Err()
+}
+
+// Delete ...
+func (r *Redis) Delete(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	return r.client.Del(ctx, key).Err()
+}
+
+// Incr ...
+func (r *Redis) Incr(key string, ttl time.Duration) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	return incrScript.Run(ctx, r.client, []string{key}, ttl.Milliseconds()).Int64()
+}
+
+// Ping ...
+func (r *Redis) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	return r.client.Ping(ctx).Err()
+}
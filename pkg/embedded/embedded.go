@@ -0,0 +1,18 @@
+package embedded
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// web has the build of the Passwall web client, `make web` copies it from
+// WEB_DIST. Without it the placeholder index.html is served.
+//
+//go:embed all:web
+var web embed.FS
+
+// Web returns the files of the web client, index.html is at the root
+func Web() fs.FS {
+	sub, _ := fs.Sub(web, "web")
+	return sub
+}
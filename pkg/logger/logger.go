@@ -1,11 +1,10 @@
 package logger
 
 import (
-	"bytes"
+	"context"
 	"io"
 	"os"
 	"runtime"
-	"strconv"
 	"strings"
 	"time"
 
@@ -23,9 +22,13 @@ const (
 func init() {
 	logger.Out = getWriter()
 	logger.Level = logrus.InfoLevel
-	logger.Formatter = &formatter{}
-
-	logger.SetReportCaller(true)
+	logger.Formatter = &logrus.JSONFormatter{
+		TimestampFormat: time.RFC3339,
+		FieldMap: logrus.FieldMap{
+			logrus.FieldKeyTime: "time",
+			logrus.FieldKeyMsg:  "message",
+		},
+	}
 }
 
 // SetLogLevel sets log level
@@ -33,9 +36,91 @@ func SetLogLevel(level logrus.Level) {
 	logger.Level = level
 }
 
+// SetLevel sets log level by its name like debug, info or error
+func SetLevel(name string) error {
+	level, err := logrus.ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	SetLogLevel(level)
+	return nil
+}
+
 // Fields sets fields on the logger.
 type Fields logrus.Fields
 
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of the context carrying the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request ID of the context, empty if there is none
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// Entry logs messages with fields, like the request ID of a request
+type Entry struct {
+	fields Fields
+}
+
+// WithContext returns an entry logging the request ID of the context
+func WithContext(ctx context.Context) *Entry {
+	fields := Fields{}
+	if requestID := RequestID(ctx); requestID != "" {
+		fields["request_id"] = requestID
+	}
+	return &Entry{fields: fields}
+}
+
+// WithFields returns an entry logging the fields
+func WithFields(fields Fields) *Entry {
+	return (&Entry{fields: Fields{}}).WithFields(fields)
+}
+
+// WithFields returns a copy of the entry with the fields added
+func (e *Entry) WithFields(fields Fields) *Entry {
+	merged := Fields{}
+	for key, value := range e.fields {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+	return &Entry{fields: merged}
+}
+
+// Debugf logs a message with the fields of the entry at level Debug.
+func (e *Entry) Debugf(format string, args ...interface{}) {
+	if logger.Level >= logrus.DebugLevel {
+		newEntry().WithFields(logrus.Fields(e.fields)).Debugf(format, args...)
+	}
+}
+
+// Infof logs a message with the fields of the entry at level Info.
+func (e *Entry) Infof(format string, args ...interface{}) {
+	if logger.Level >= logrus.InfoLevel {
+		newEntry().WithFields(logrus.Fields(e.fields)).Infof(format, args...)
+	}
+}
+
+// Warnf logs a message with the fields of the entry at level Warn.
+func (e *Entry) Warnf(format string, args ...interface{}) {
+	if logger.Level >= logrus.WarnLevel {
+		newEntry().WithFields(logrus.Fields(e.fields)).Warnf(format, args...)
+	}
+}
+
+// Errorf logs a message with the fields of the entry at level Error.
+func (e *Entry) Errorf(format string, args ...interface{}) {
+	if logger.Level >= logrus.ErrorLevel {
+		newEntry().WithFields(logrus.Fields(e.fields)).Errorf(format, args...)
+	}
+}
+
 // Debugf logs a message at level Debug on the standard logger.
 func Debugf(format string, args ...interface{}) {
 	if logger.Level >= logrus.DebugLevel {
@@ -123,41 +208,3 @@ func getWriter() io.Writer {
 		return file
 	}
 }
-
-// Formatter implements logrus.Formatter interface.
-type formatter struct {
-	prefix string
-}
-
-// Format building log message.
-func (f *formatter) Format(entry *logrus.Entry) ([]byte, error) {
-	var sb bytes.Buffer
-	sb.WriteString(strings.ToUpper(entry.Level.String()))
-	sb.WriteString(" ")
-	sb.WriteString(entry.Time.Format(time.RFC3339))
-	sb.WriteString(" ")
-	sb.WriteString("1.1.2")
-	sb.WriteString(" ")
-	sb.WriteString(f.prefix)
-	sb.WriteString(entry.Message)
-	sb.WriteString(" ")
-	file, ok := entry.Data["file"].(string)
-	if ok {
-		sb.WriteString("file:")
-		sb.WriteString(file)
-	}
-	line, ok := entry.Data["line"].(int)
-	if ok {
-		sb.WriteString(":")
-		sb.WriteString(strconv.Itoa(line))
-	}
-	function, ok := entry.Data["function"].(string)
-	if ok {
-		sb.WriteString(" ")
-		sb.WriteString("func:")
-		sb.WriteString(function)
-	}
-	sb.WriteString("\n")
-
-	return sb.Bytes(), nil
-}